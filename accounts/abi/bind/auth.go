@@ -44,7 +44,11 @@ func NewKeyedTransactor(key *ecdsa.PrivateKey) *TransactOpts {
 			if err != nil {
 				return nil, err
 			}
-			return tx.WithSignature(signer, signature)
+			signed, err := tx.WithSignature(signer, signature)
+			if err != nil {
+				return nil, err
+			}
+			return signed.(*types.Transaction), nil
 		},
 	}
 }
@@ -72,6 +72,13 @@ type ContractTransactor interface {
 	SendTransaction(ctx context.Context, tx *types.Transaction) error
 }
 
+// ChainIDReader is implemented by the backends knowing the id of their chain.
+// Transactions are signed for it unless TransactOpts.ChainID is set.
+type ChainIDReader interface {
+	// ChainID retrieves the chain id transactions are signed for.
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
 // ContractFilterer defines the methods needed to access log events using one-off
 // queries or continuous event subscriptions.
 type ContractFilterer interface {
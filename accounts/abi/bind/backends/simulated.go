@@ -23,6 +23,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
 	"github.com/MatrixAINetwork/go-matrix/event"
 	"github.com/MatrixAINetwork/go-matrix/man/filters"
 	"github.com/MatrixAINetwork/go-matrix/mandb"
@@ -32,6 +33,7 @@ import (
 
 // This nil assignment ensures compile time that SimulatedBackend implements bind.ContractBackend.
 var _ bind.ContractBackend = (*SimulatedBackend)(nil)
+var _ bind.ChainIDReader = (*SimulatedBackend)(nil)
 
 var errBlockNumberUnsupported = errors.New("SimulatedBackend cannot access blocks other than the latest block")
 var errGasEstimationFailed = errors.New("gas required exceeds allowance or always failing transaction")
@@ -42,11 +44,13 @@ type SimulatedBackend struct {
 	database   mandb.Database   // In memory database to store our testing data
 	blockchain *core.BlockChain // Matrix blockchain to handle the consensus
 
-	mu           sync.Mutex
-	pendingBlock *types.Block   // Currently pending block that will be imported on request
-	pendingState *state.StateDB // Currently pending state that will be the active on on request
+	mu              sync.Mutex
+	pendingBlock    *types.Block   // Currently pending block that will be imported on request
+	pendingReceipts types.Receipts // Receipts of the transactions in the pending block
+	pendingState    *state.StateDB // Currently pending state that will be the active on on request
 
 	events *filters.EventSystem // Event system for filtering log events live
+	caller common.Address       // Sender of the calls made without one, like the node account used by man_call
 
 	config *params.ChainConfig
 }
@@ -55,14 +59,29 @@ type SimulatedBackend struct {
 // for testing purposes.
 func NewSimulatedBackend(alloc core.GenesisAlloc) *SimulatedBackend {
 	database := mandb.NewMemDatabase()
-	genesis := core.Genesis{Config: params.AllManashProtocolChanges, Alloc: alloc}
+	// MATRIX的创世状态需包含matrix状态, 借用开发链的创世配置, 由临时账户充当版本超级账户
+	key, _ := crypto.GenerateKey()
+	caller := crypto.PubkeyToAddress(key.PublicKey)
+	genesis := core.DeveloperGenesisBlock(0, caller)
+	versionSign, err := crypto.SignWithValidate(common.BytesToHash([]byte(genesis.Version)).Bytes(), true, key)
+	if err != nil {
+		panic(err)
+	}
+	genesis.VersionSignatures = []common.Signature{common.BytesToSignature(versionSign)}
+	for addr, account := range alloc {
+		genesis.Alloc[addr] = account
+	}
 	genesis.MustCommit(database)
-	blockchain, _ := core.NewBlockChain(database, nil, genesis.Config, manash.NewFaker(), vm.Config{})
+	blockchain, err := core.NewBlockChain(database, nil, genesis.Config, manash.NewFullFaker(), vm.Config{})
+	if err != nil {
+		panic(err)
+	}
 
 	backend := &SimulatedBackend{
 		database:   database,
 		blockchain: blockchain,
 		config:     genesis.Config,
+		caller:     caller,
 		events:     filters.NewEventSystem(new(event.TypeMux), &filterBackend{database, blockchain}, false),
 	}
 	backend.rollback()
@@ -75,9 +94,20 @@ func (b *SimulatedBackend) Commit() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if _, err := b.blockchain.InsertChain([]*types.Block{b.pendingBlock}); err != nil {
+	// 模拟区块没有DPOS签名, 不经过共识校验, 如同本地挖出的区块直接写链
+	statedb, _ := state.New(b.pendingBlock.Root(), state.NewDatabase(b.database))
+	if _, err := b.blockchain.WriteBlockWithState(b.pendingBlock, b.pendingReceipts, statedb); err != nil {
 		panic(err) // This cannot happen unless the simulator is wrong, fail in that case
 	}
+	var logs []*types.Log
+	for _, receipt := range b.pendingReceipts {
+		logs = append(logs, receipt.Logs...)
+	}
+	events := []interface{}{
+		core.ChainEvent{Block: b.pendingBlock, Hash: b.pendingBlock.Hash(), Logs: logs},
+		core.ChainHeadEvent{Block: b.pendingBlock},
+	}
+	b.blockchain.PostChainEvents(events, logs)
 	b.rollback()
 }
 
@@ -90,13 +120,18 @@ func (b *SimulatedBackend) Rollback() {
 }
 
 func (b *SimulatedBackend) rollback() {
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), manash.NewFaker(), b.database, 1, func(int, *core.BlockGen) {})
+	blocks, receipts := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), manash.NewFullFaker(), b.database, 1, func(int, *core.BlockGen) {})
 	statedb, _ := b.blockchain.State()
 
-	b.pendingBlock = blocks[0]
+	b.pendingBlock, b.pendingReceipts = blocks[0], receipts[0]
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
 }
 
+// ChainID returns the chain id of the simulated blockchain.
+func (b *SimulatedBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	return new(big.Int).Set(b.config.ChainId), nil
+}
+
 // CodeAt returns the code associated with a certain account in the blockchain.
 func (b *SimulatedBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
 	b.mu.Lock()
@@ -118,7 +153,7 @@ func (b *SimulatedBackend) BalanceAt(ctx context.Context, contract common.Addres
 		return nil, errBlockNumberUnsupported
 	}
 	statedb, _ := b.blockchain.State()
-	return statedb.GetBalance(contract), nil
+	return statedb.GetBalanceByType(contract, common.MainAccount), nil
 }
 
 // NonceAt returns the nonce of a certain account in the blockchain.
@@ -264,19 +299,28 @@ func (b *SimulatedBackend) callContract(ctx context.Context, call matrix.CallMsg
 	if call.Value == nil {
 		call.Value = new(big.Int)
 	}
+	if call.From == (common.Address{}) {
+		call.From = b.caller
+	}
 	// Set infinite balance to the fake caller account.
-	from := statedb.GetOrNewStateObject(call.From)
-	from.SetBalance(math.MaxBig256)
-	// Execute the call.
-	msg := callmsg{call}
+	statedb.SetBalance(common.MainAccount, call.From, math.MaxBig256)
+	// Execute the call as a MATRIX transaction paid in MAN, the same way man_call does.
+	var tx *types.Transaction
+	if call.To == nil {
+		tx = types.NewContractCreation(params.NonceAddOne, call.Value, call.Gas, call.GasPrice, call.Data, nil, nil, nil, 0, 0, "MAN", 0)
+	} else {
+		tx = types.NewTransaction(params.NonceAddOne, *call.To, call.Value, call.Gas, call.GasPrice, call.Data, nil, nil, nil, 0, 0, "MAN", 0)
+	}
+	msg := &types.TransactionCall{Transaction: tx}
+	msg.SetFromLoad(call.From)
 
-	evmContext := core.NewEVMContext(msg, block.Header(), b.blockchain, nil)
+	evmContext := core.NewEVMContext(call.From, call.GasPrice, block.Header(), b.blockchain, nil)
 	// Create a new environment which holds all relevant information
 	// about the transaction and calling mechanisms.
 	vmenv := vm.NewEVM(evmContext, statedb, b.config, vm.Config{})
 	gaspool := new(core.GasPool).AddGas(math.MaxUint64)
 
-	return core.NewStateTransition(vmenv, msg, gaspool).TransitionDb()
+	return core.ApplyMessage(vmenv, msg, gaspool)
 }
 
 // SendTransaction updates the pending block to include the given transaction.
@@ -285,7 +329,7 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	sender, err := types.Sender(types.HomesteadSigner{}, tx)
+	sender, err := types.Sender(types.NewEIP155Signer(b.config.ChainId), tx)
 	if err != nil {
 		panic(fmt.Errorf("invalid transaction: %v", err))
 	}
@@ -294,15 +338,15 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 		panic(fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce))
 	}
 
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), manash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
+	blocks, receipts := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), manash.NewFullFaker(), b.database, 1, func(number int, block *core.BlockGen) {
 		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTxWithChain(b.blockchain, tx)
+			block.AddTxWithChain(b.blockchain, tx.(*types.Transaction))
 		}
 		block.AddTxWithChain(b.blockchain, tx)
 	})
 	statedb, _ := b.blockchain.State()
 
-	b.pendingBlock = blocks[0]
+	b.pendingBlock, b.pendingReceipts = blocks[0], receipts[0]
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
 	return nil
 }
@@ -373,34 +417,20 @@ func (b *SimulatedBackend) SubscribeFilterLogs(ctx context.Context, query matrix
 func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), manash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
+	blocks, receipts := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), manash.NewFullFaker(), b.database, 1, func(number int, block *core.BlockGen) {
 		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTx(tx)
+			block.AddTx(tx.(*types.Transaction))
 		}
 		block.OffsetTime(int64(adjustment.Seconds()))
 	})
 	statedb, _ := b.blockchain.State()
 
-	b.pendingBlock = blocks[0]
+	b.pendingBlock, b.pendingReceipts = blocks[0], receipts[0]
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
 
 	return nil
 }
 
-// callmsg implements core.Message to allow passing it as a transaction simulator.
-type callmsg struct {
-	matrix.CallMsg
-}
-
-func (m callmsg) From() common.Address { return m.CallMsg.From }
-func (m callmsg) Nonce() uint64        { return 0 | params.NonceAddOne } //
-func (m callmsg) CheckNonce() bool     { return false }
-func (m callmsg) To() *common.Address  { return m.CallMsg.To }
-func (m callmsg) GasPrice() *big.Int   { return m.CallMsg.GasPrice }
-func (m callmsg) Gas() uint64          { return m.CallMsg.Gas }
-func (m callmsg) Value() *big.Int      { return m.CallMsg.Value }
-func (m callmsg) Data() []byte         { return m.CallMsg.Data }
-
 // filterBackend implements filters.Backend to support filtering for logs without
 // taking bloom-bits acceleration structures into account.
 type filterBackend struct {
@@ -442,7 +472,7 @@ func (fb *filterBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*ty
 	return logs, nil
 }
 
-func (fb *filterBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+func (fb *filterBackend) SubscribeNewTxsEvent(ch chan core.NewTxsEvent) event.Subscription {
 	return event.NewSubscription(func(quit <-chan struct{}) error {
 		<-quit
 		return nil
@@ -16,6 +16,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/crypto"
 	"github.com/MatrixAINetwork/go-matrix/event"
+	"github.com/MatrixAINetwork/go-matrix/params"
 )
 
 // SignerFn is a signer function callback when a contract requires a method to
@@ -41,6 +42,13 @@ type TransactOpts struct {
 	GasPrice *big.Int // Gas price to use for the transaction execution (nil = gas price oracle)
 	GasLimit uint64   // Gas limit to set for the transaction execution (0 = estimate)
 
+	Currency    string              // Currency the value and the gas are paid in ("" = MAN)
+	TxType      byte                // MATRIX transaction type (0 = common.ExtraNormalTxType)
+	CommitTime  uint64              // Creation or execution time of revocable and timed transactions
+	IsEntrustTx bool                // Whether the transaction is sent on behalf of an authorizer
+	ExtraTo     []*types.ExtraTo_tr // Additional recipients of a multi-recipient transaction
+	ChainID     *big.Int            // Chain id used for signing (nil = the one of the backend, or MATRIX main network)
+
 	Context context.Context // Network context to support cancellation and timeouts (nil = no timeout)
 }
 
@@ -213,16 +221,34 @@ func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, i
 		}
 	}
 	// Create the transaction, sign it and schedule it for execution
+	currency := opts.Currency
+	if currency == "" {
+		currency = "MAN"
+	}
+	var isEntrustTx byte
+	if opts.IsEntrustTx {
+		isEntrustTx = 1
+	}
 	var rawTx *types.Transaction
 	if contract == nil {
-		rawTx = types.NewContractCreation(nonce, value, gasLimit, gasPrice, input)
+		rawTx = types.NewContractCreation(nonce, value, gasLimit, gasPrice, input, nil, nil, nil, 0, isEntrustTx, currency, opts.CommitTime)
 	} else {
-		rawTx = types.NewTransaction(nonce, c.address, value, gasLimit, gasPrice, input)
+		rawTx = types.NewTransactions(nonce, c.address, value, gasLimit, gasPrice, input, nil, nil, nil, opts.ExtraTo, 0, opts.TxType, isEntrustTx, currency, opts.CommitTime)
 	}
 	if opts.Signer == nil {
 		return nil, errors.New("no signer to authorize the transaction with")
 	}
-	signedTx, err := opts.Signer(types.HomesteadSigner{}, opts.From, rawTx)
+	chainID := opts.ChainID
+	if chainID == nil {
+		if reader, ok := c.transactor.(ChainIDReader); ok {
+			if chainID, err = reader.ChainID(ensureContext(opts.Context)); err != nil {
+				return nil, fmt.Errorf("failed to retrieve chain id: %v", err)
+			}
+		} else {
+			chainID = params.MainnetChainConfig.ChainId
+		}
+	}
+	signedTx, err := opts.Signer(types.NewEIP155Signer(chainID), opts.From, rawTx)
 	if err != nil {
 		return nil, err
 	}
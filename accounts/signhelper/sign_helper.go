@@ -195,7 +195,7 @@ func (sh *SignHelper) getSignAccountAndPasswordAtSignHeight(reader AuthReader, b
 			return account, "", err
 		}
 	} else {
-		addrs = []common.Address{ca.GetSignAddress()}
+		addrs = ca.GetSignAddresses()
 	}
 
 	addr, password, err := reader.GetSignAccountPassword(addrs)
@@ -155,7 +155,11 @@ func (self *Random) newSubServer(name string, plugConfig string, support RandomC
 }
 
 func (self *Random) GetRandom(hash common.Hash, Type string) (*big.Int, error) {
-	return self.mapSubService[Type].CalcData(hash)
+	subService, ok := self.mapSubService[Type]
+	if ok == false || subService == nil {
+		return nil, fmt.Errorf("随机数子服务未开启 %v", Type)
+	}
+	return subService.CalcData(hash)
 }
 
 func getSubServicePlug(name string) (string, bool) {
@@ -284,12 +284,13 @@ func (p *Process) canGenBlock() bool {
 		return false
 	}
 	if p.bcInterval.IsBroadcastNumber(p.number + 1) {
-		if p.role != common.RoleBroadcast {
+		// 开发者模式下本节点同时是验证者和广播节点
+		if p.role != common.RoleBroadcast && !(p.pm.bc.Config().Dev != nil && p.role == common.RoleValidator) {
 			log.WARN(p.logExtraInfo(), "准备进行区块插入，广播区块前一个区块，由广播节点插入", p.role.String(), "高度", p.number)
 			return false
 		}
 	} else {
-		if p.role != common.RoleValidator {
+		if p.role != common.RoleValidator && !(p.pm.bc.Config().Dev != nil && p.role == common.RoleBroadcast) {
 			log.WARN(p.logExtraInfo(), "准备进行区块插入，身份错误", "当前身份不是验证者", "高度", p.number, "身份", p.role.String())
 			return false
 		}
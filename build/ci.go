@@ -60,6 +60,9 @@ var (
 	// Files that end up in the gman-alltools*.zip archive.
 	allToolsArchiveFiles = []string{
 		"COPYING",
+		executablePath("abigen"),
+		executablePath("bootnode"),
+		executablePath("evm"),
 		executablePath("gman"),
 	}
 
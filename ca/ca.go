@@ -18,6 +18,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/mc"
 	"github.com/MatrixAINetwork/go-matrix/p2p/discover"
+	"github.com/MatrixAINetwork/go-matrix/params"
 	"github.com/MatrixAINetwork/go-matrix/params/manparams"
 )
 
@@ -29,6 +30,7 @@ type TopologyGraphReader interface {
 	GetNextElectByHash(blockHash common.Hash) ([]common.Elect, error)
 	GetBroadcastAccounts(blockHash common.Hash) ([]common.Address, error)
 	GetInnerMinersAccount(blockHash common.Hash) ([]common.Address, error)
+	Config() *params.ChainConfig
 }
 
 // Identity stand for node's identity.
@@ -36,6 +38,8 @@ type Identity struct {
 	// self nodeId
 	self discover.NodeID
 	addr common.Address
+	// sign address from config, addr follows the sign address rotation of its deposit
+	baseAddr common.Address
 
 	// if in elected duration
 	duration      bool
@@ -97,6 +101,7 @@ func (ide *Identity) init(id discover.NodeID, path string, addr common.Address)
 		// check bootNode and set identity
 		ide.self = id
 		ide.addr = addr
+		ide.baseAddr = addr
 		ide.log = log.New()
 	})
 }
@@ -124,94 +129,109 @@ func Start(id discover.NodeID, path string, addr common.Address) {
 	log.INFO("CA", "订阅区块事件", "完成")
 	mc.PublishEvent(mc.CA_ReqCurrentBlock, struct{}{})
 
+	var next *types.Block // 开发者模式等待出块间隔时收到的新区块
 	for {
-		select {
-		case block := <-ide.blockChan:
-			header := block.Header()
-			hash := block.Hash()
-			ide.currentHeight = header.Number
-			ide.hash = block.Hash()
-
-			log.INFO("CA", "leader", header.Leader, "height", header.Number.Uint64(), "block hash", hash)
-
-			// init current height deposit
-			ide.deposit, _ = GetElectedByHeightWithdrawByHash(header.Hash())
-
-			// get broadcast interval
-			bcInterval, err := manparams.GetBCIntervalInfoByHash(hash)
-			if err != nil {
-				ide.log.Error("get broadcast interval", "error", err)
-				continue
+		block := next
+		next = nil
+		if block == nil {
+			select {
+			case block = <-ide.blockChan:
+			case <-ide.quit:
+				return
 			}
+		}
+		header := block.Header()
+		hash := block.Hash()
+		ide.currentHeight = header.Number
+		ide.hash = block.Hash()
+
+		log.INFO("CA", "leader", header.Leader, "height", header.Number.Uint64(), "block hash", hash)
+
+		// init current height deposit
+		ide.deposit, _ = GetElectedByHeightWithdrawByHash(header.Hash())
+		ide.updateSignAddress(header.Number.Uint64())
+
+		// get broadcast interval
+		bcInterval, err := manparams.GetBCIntervalInfoByHash(hash)
+		if err != nil {
+			ide.log.Error("get broadcast interval", "error", err)
+			continue
+		}
 
-			// do topology
-			tg, err := ide.topologyReader.GetTopologyGraphByHash(hash)
+		// do topology
+		tg, err := ide.topologyReader.GetTopologyGraphByHash(hash)
+		if err != nil {
+			ide.log.Error("get topology", "error", err)
+			continue
+		}
+		newTg := &mc.TopologyGraph{}
+		for _, value := range tg.NodeList {
+			sAddr, err := ConvertDepositToSignAddress(value.Account)
 			if err != nil {
-				ide.log.Error("get topology", "error", err)
+				log.Error("convert address failed", "error", err)
 				continue
 			}
-			newTg := &mc.TopologyGraph{}
-			for _, value := range tg.NodeList {
-				sAddr, err := ConvertDepositToSignAddress(value.Account)
-				if err != nil {
-					log.Error("convert address failed", "error", err)
-					continue
-				}
-				newTg.NodeList = append(newTg.NodeList, mc.TopologyNodeInfo{sAddr, value.Position, value.Type, value.NodeNumber})
-			}
-			newTg.CurNodeNumber = tg.CurNodeNumber
-			ide.topology = newTg
+			newTg.NodeList = append(newTg.NodeList, mc.TopologyNodeInfo{sAddr, value.Position, value.Type, value.NodeNumber})
+		}
+		newTg.CurNodeNumber = tg.CurNodeNumber
+		ide.topology = newTg
+
+		// get special accounts
+		broadcastAccounts, err := ide.topologyReader.GetBroadcastAccounts(hash)
+		if err != nil {
+			log.Error("ca", "get broadcast accounts err", err)
+			continue
+		}
+		ide.broadcastAccounts = broadcastAccounts
 
-			// get special accounts
-			broadcastAccounts, err := ide.topologyReader.GetBroadcastAccounts(hash)
+		innerMiners, err := ide.topologyReader.GetInnerMinersAccount(hash)
+		if err != nil {
+			log.Error("ca", "get inner miner accounts err", err)
+			continue
+		}
+		ide.innerMiners = innerMiners
+		// get elect
+		elect, err := ide.topologyReader.GetNextElectByHash(hash)
+		if err != nil {
+			ide.log.Error("get next elect", "error", err)
+			continue
+		}
+		newElect := make([]common.Elect, 0)
+		for _, val := range elect {
+			sAddr, err := ConvertDepositToSignAddress(val.Account)
 			if err != nil {
-				log.Error("ca", "get broadcast accounts err", err)
+				log.Error("convert address failed", "error", err)
 				continue
 			}
-			ide.broadcastAccounts = broadcastAccounts
-
-			innerMiners, err := ide.topologyReader.GetInnerMinersAccount(hash)
-			if err != nil {
-				log.Error("ca", "get inner miner accounts err", err)
-				continue
+			newElect = append(newElect, common.Elect{Account: sAddr, Stock: val.Stock, Type: val.Type, VIP: val.VIP})
+		}
+		ide.prevElect = newElect
+
+		// init topology
+		initCurrentTopology()
+		initNowTopologyResult()
+		if dev := ide.topologyReader.Config().Dev; dev != nil {
+			var ok bool
+			if next, ok = devRole(header, bcInterval, dev.Period); !ok {
+				return
 			}
-			ide.innerMiners = innerMiners
-			// get elect
-			elect, err := ide.topologyReader.GetNextElectByHash(hash)
-			if err != nil {
-				ide.log.Error("get next elect", "error", err)
+			if next != nil {
 				continue
 			}
-			newElect := make([]common.Elect, 0)
-			for _, val := range elect {
-				sAddr, err := ConvertDepositToSignAddress(val.Account)
-				if err != nil {
-					log.Error("convert address failed", "error", err)
-					continue
-				}
-				newElect = append(newElect, common.Elect{Account: sAddr, Stock: val.Stock, Type: val.Type, VIP: val.VIP})
-			}
-			ide.prevElect = newElect
-
-			// init topology
-			initCurrentTopology()
-			initNowTopologyResult()
+		}
 
-			// get nodes in buckets
-			nodesInBuckets := getNodesInBuckets(header.Hash())
+		// get nodes in buckets
+		nodesInBuckets := getNodesInBuckets(header.Hash())
 
-			// send role message to elect
-			mc.PublishEvent(mc.CA_RoleUpdated, &mc.RoleUpdatedMsg{Role: ide.currentRole, BlockNum: header.Number.Uint64(), BlockHash: hash, Leader: header.Leader, IsSuperBlock: header.IsSuperHeader()})
-			log.Info("ca publish identity", "data", mc.RoleUpdatedMsg{Role: ide.currentRole, BlockNum: header.Number.Uint64(), Leader: header.Leader})
-			// get nodes in buckets and send to buckets
-			mc.PublishEvent(mc.BlockToBuckets, mc.BlockToBucket{Ms: nodesInBuckets, Height: block.Header().Number, Role: ide.currentRole})
-			// send identity to linker
-			mc.PublishEvent(mc.BlockToLinkers, mc.BlockToLinker{Height: header.Number, BroadCastInterval: bcInterval, Role: ide.currentRole})
-			mc.PublishEvent(mc.SendSyncRole, mc.SyncIdEvent{Role: ide.currentRole}) //lb
-			mc.PublishEvent(mc.TxPoolManager, ide.currentRole)
-		case <-ide.quit:
-			return
-		}
+		// send role message to elect
+		mc.PublishEvent(mc.CA_RoleUpdated, &mc.RoleUpdatedMsg{Role: ide.currentRole, BlockNum: header.Number.Uint64(), BlockHash: hash, Leader: header.Leader, IsSuperBlock: header.IsSuperHeader()})
+		log.Info("ca publish identity", "data", mc.RoleUpdatedMsg{Role: ide.currentRole, BlockNum: header.Number.Uint64(), Leader: header.Leader})
+		// get nodes in buckets and send to buckets
+		mc.PublishEvent(mc.BlockToBuckets, mc.BlockToBucket{Ms: nodesInBuckets, Height: block.Header().Number, Role: ide.currentRole})
+		// send identity to linker
+		mc.PublishEvent(mc.BlockToLinkers, mc.BlockToLinker{Height: header.Number, BroadCastInterval: bcInterval, Role: ide.currentRole})
+		mc.PublishEvent(mc.SendSyncRole, mc.SyncIdEvent{Role: ide.currentRole}) //lb
+		mc.PublishEvent(mc.TxPoolManager, ide.currentRole)
 	}
 }
 
@@ -505,6 +525,43 @@ func GetSignAddress() common.Address {
 	return ide.addr
 }
 
+// GetSignAddresses returns the sign addresses this node may sign with, the one
+// effective at current height first. During a sign address rotation handover
+// the configured one is kept as a fallback.
+func GetSignAddresses() []common.Address {
+	ide.lock.RLock()
+	defer ide.lock.RUnlock()
+
+	if ide.addr == ide.baseAddr {
+		return []common.Address{ide.addr}
+	}
+	return []common.Address{ide.addr, ide.baseAddr}
+}
+
+// updateSignAddress switch self sign address when the deposit's sign address rotation takes effect.
+func (ide *Identity) updateSignAddress(height uint64) {
+	addr := ide.baseAddr
+	for _, node := range ide.deposit {
+		if node.HasSignAddress(ide.baseAddr) {
+			addr = node.SignAddressAt(height)
+			break
+		}
+	}
+	ide.lock.Lock()
+	if addr != ide.addr {
+		log.INFO("CA", "签名账户切换", addr.Hex(), "height", height)
+	}
+	ide.addr = addr
+	ide.lock.Unlock()
+}
+
+func currentHeight() uint64 {
+	if ide.currentHeight == nil {
+		return 0
+	}
+	return ide.currentHeight.Uint64()
+}
+
 // GetSelfDepositAddress
 func GetDepositAddress() common.Address {
 	ide.lock.RLock()
@@ -632,8 +689,9 @@ func GetAccountOriginalRole(account common.Address, hash common.Hash) (common.Ro
 
 // ConvertSignToDepositAddress
 func ConvertSignToDepositAddress(address common.Address) (addr common.Address, err error) {
+	height := currentHeight()
 	for _, node := range ide.deposit {
-		if node.SignAddress == address {
+		if node.IsSignAddressAt(address, height) {
 			return node.Address, nil
 		}
 	}
@@ -643,9 +701,10 @@ func ConvertSignToDepositAddress(address common.Address) (addr common.Address, e
 
 // ConvertDepositToSignAddress
 func ConvertDepositToSignAddress(address common.Address) (addr common.Address, err error) {
+	height := currentHeight()
 	for _, node := range ide.deposit {
 		if node.Address == address {
-			return node.SignAddress, nil
+			return node.SignAddressAt(height), nil
 		}
 	}
 
@@ -0,0 +1,45 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package ca
+
+import (
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+// devRole sets the role of a developer node for the block after header. The
+// node is the broadcast node at broadcast heights and the validator, leader and
+// miner otherwise. It holds the role messages back until the dev period since
+// the header has passed. If a new block arrives meanwhile, the role of header is
+// dropped and the new block is returned to be handled instead; it returns false
+// if the identity is stopped.
+func devRole(header *types.Header, bcInterval *mc.BCIntervalInfo, period uint64) (*types.Block, bool) {
+	ide.lock.Lock()
+	if bcInterval.IsBroadcastNumber(header.Number.Uint64() + 1) {
+		ide.currentRole = common.RoleBroadcast
+	} else {
+		ide.currentRole = common.RoleValidator
+	}
+	ide.lock.Unlock()
+
+	wait := time.Until(time.Unix(header.Time.Int64(), 0).Add(time.Duration(period) * time.Second))
+	if wait <= 0 {
+		return nil, true
+	}
+	log.Debug("CA", "开发者模式等待出块间隔", wait, "高度", header.Number.Uint64()+1)
+	select {
+	case <-time.After(wait):
+		return nil, true
+	case block := <-ide.blockChan:
+		log.Debug("CA", "开发者模式等待中收到新区块", block.Number(), "放弃高度", header.Number.Uint64()+1)
+		return block, true
+	case <-ide.quit:
+		return nil, false
+	}
+}
@@ -95,3 +95,31 @@ func GetRandomInfo(stateReader matrix.StateReader, hash common.Hash) (*mc.Random
 	}
 	return randomInfo, nil
 }
+
+func GetRandomWithhold(stateReader matrix.StateReader, hash common.Hash) (*mc.RandomWithholdInfo, error) {
+	st, err := stateReader.StateAtBlockHash(hash)
+	if err != nil {
+		log.Error(ModuleReadStateDB, "获取state失败", err)
+		return nil, err
+	}
+	info, err := matrixstate.GetRandomWithhold(st)
+	if err != nil {
+		log.Error(ModuleReadStateDB, "获取随机数未公开私钥统计阶段,从状态树获取失败,err", err)
+		return nil, err
+	}
+	return info, nil
+}
+
+func GetVersionProposals(stateReader matrix.StateReader, hash common.Hash) (*mc.VersionProposalInfo, error) {
+	st, err := stateReader.StateAtBlockHash(hash)
+	if err != nil {
+		log.Error(ModuleReadStateDB, "获取state失败", err)
+		return nil, err
+	}
+	info, err := matrixstate.GetVersionProposals(st)
+	if err != nil {
+		log.Error(ModuleReadStateDB, "获取版本升级提案阶段,从状态树获取失败,err", err)
+		return nil, err
+	}
+	return info, nil
+}
@@ -449,21 +449,27 @@ var (
 )
 
 const (
-	ExtraNormalTxType         byte = 0   //普通交易
-	ExtraBroadTxType          byte = 1   //广播交易(内部交易，钱包无用)
-	ExtraUnGasMinerTxType     byte = 2   //矿工奖励类型
-	ExtraRevocable            byte = 3   //可撤销的交易
-	ExtraRevertTxType         byte = 4   //撤销交易
-	ExtraAuthTx               byte = 5   //授权委托
-	ExtraCancelEntrust        byte = 6   //取消委托
-	ExtraTimeTxType           byte = 7   //定时交易
-	ExtraAItxType             byte = 8   //AI 交易
-	ExtraUnGasValidatorTxType byte = 10  //验证者奖励类型
-	ExtraUnGasInterestTxType  byte = 11  //利息奖励通过合约交易发放
-	ExtraUnGasTxsType         byte = 12  //交易费奖励类型
-	ExtraUnGasLotteryTxType   byte = 13  //彩票奖励类型
-	ExtraCreatCurrency        byte = 118 //创建币种交易
-	ExtraSuperBlockTx         byte = 120 //超级区块交易
+	ExtraNormalTxType          byte = 0   //普通交易
+	ExtraBroadTxType           byte = 1   //广播交易(内部交易，钱包无用)
+	ExtraUnGasMinerTxType      byte = 2   //矿工奖励类型
+	ExtraRevocable             byte = 3   //可撤销的交易
+	ExtraRevertTxType          byte = 4   //撤销交易
+	ExtraAuthTx                byte = 5   //授权委托
+	ExtraCancelEntrust         byte = 6   //取消委托
+	ExtraTimeTxType            byte = 7   //定时交易
+	ExtraAItxType              byte = 8   //AI 交易
+	ExtraUnGasValidatorTxType  byte = 10  //验证者奖励类型
+	ExtraUnGasInterestTxType   byte = 11  //利息奖励通过合约交易发放
+	ExtraUnGasTxsType          byte = 12  //交易费奖励类型
+	ExtraUnGasLotteryTxType    byte = 13  //彩票奖励类型
+	ExtraEscrowTxType          byte = 14  //仲裁担保交易
+	ExtraEscrowReleaseTxType   byte = 15  //担保交易提前放款(仲裁人或收款人)
+	ExtraEscrowRefundTxType    byte = 16  //担保交易退款(仲裁人)
+	ExtraSignRotationTxType    byte = 17  //签名账户(A1)轮换交易
+	ExtraVersionProposalTxType byte = 18  //版本升级提案交易(版本超级账户)
+	ExtraCurrencyFeeRateTxType byte = 19  //设置币种gas费率交易(多币种超级账户)
+	ExtraCreatCurrency         byte = 118 //创建币种交易
+	ExtraSuperBlockTx          byte = 120 //超级区块交易
 )
 
 var (
@@ -497,6 +503,59 @@ type RecorbleTx struct {
 	Typ  byte
 }
 
+//担保交易记录,与可撤销交易一起存放在可撤销交易的btree中
+type EscrowTx struct {
+	RecorbleTx
+	Arbiter Address //仲裁人
+}
+
+//是否为担保交易的参与方(发起人、仲裁人或收款人)
+func (et *EscrowTx) IsParticipant(addr Address) bool {
+	if et.From == addr || et.Arbiter == addr {
+		return true
+	}
+	return et.IsRecipient(addr)
+}
+
+func (et *EscrowTx) IsRecipient(addr Address) bool {
+	for _, aa := range et.Adam {
+		if aa.Addr == addr {
+			return true
+		}
+	}
+	return false
+}
+
+//提前放款只能由仲裁人或收款人发起，退款只能由仲裁人发起
+func (et *EscrowTx) CanSettle(typ byte, addr Address) bool {
+	switch typ {
+	case ExtraEscrowReleaseTxType:
+		return et.Arbiter == addr || et.IsRecipient(addr)
+	case ExtraEscrowRefundTxType:
+		return et.Arbiter == addr
+	default:
+		return false
+	}
+}
+
+//版本升级提案交易的数据,签名为版本超级账户对版本号的签名(同signversion)
+type VersionProposalTx struct {
+	Version      string
+	ActiveHeight uint64 //版本生效高度
+	Signature    Signature
+}
+
+//设置币种gas费率交易的数据,Rate为0时该币种的gas恢复用MAN支付
+type CurrencyFeeRateTx struct {
+	Currency string
+	Rate     uint64 //1 MAN 对应的该币种数量 * CurrencyFeeRateBase
+}
+
+const (
+	CurrencyFeeRateBase = 10000                         //币种gas费率的分母, 费率不低于该值, 币种gas不比MAN便宜
+	CurrencyFeeRateMax  = 1000000 * CurrencyFeeRateBase //币种gas费率上限
+)
+
 //地址为matrix地址
 type EntrustType struct {
 	//委托地址
@@ -511,6 +570,13 @@ type EntrustType struct {
 	EndHeight   uint64 //委托结束高度
 	StartTime   uint64
 	EndTime     uint64
+
+	//细粒度权限(为空表示不限制,对委托gas和委托签名的交易生效)
+	SpendLimit        *big.Int `json:",omitempty"` //每个周期内委托交易的转账金额与gas费用上限
+	SpendPeriod       uint64   `json:",omitempty"` //限额周期,按高度委托为块数,按时间委托为秒数,0表示整个委托期
+	AllowedTo         []string `json:",omitempty"` //允许的收款地址(base58)
+	AllowedCurrencies []string `json:",omitempty"` //允许的币种
+	AllowedTxTypes    []uint32 `json:",omitempty"` //允许的交易类型
 }
 
 type AuthType struct {
@@ -522,6 +588,65 @@ type AuthType struct {
 	EndHeight       uint64  //委托结束高度
 	StartTime       uint64
 	EndTime         uint64
+
+	SpendLimit        *big.Int  `json:",omitempty"`
+	SpendPeriod       uint64    `json:",omitempty"`
+	AllowedTo         []Address `json:",omitempty"`
+	AllowedCurrencies []string  `json:",omitempty"`
+	AllowedTxTypes    []uint32  `json:",omitempty"`
+}
+
+//判断委托是否允许该交易类型
+func (a *AuthType) AllowTxType(txType byte) bool {
+	if len(a.AllowedTxTypes) == 0 {
+		return true
+	}
+	for _, typ := range a.AllowedTxTypes {
+		if typ == uint32(txType) {
+			return true
+		}
+	}
+	return false
+}
+
+//判断委托是否允许该币种
+func (a *AuthType) AllowCurrency(currency string) bool {
+	if len(a.AllowedCurrencies) == 0 {
+		return true
+	}
+	for _, cur := range a.AllowedCurrencies {
+		if cur == currency {
+			return true
+		}
+	}
+	return false
+}
+
+//判断委托是否允许向该地址转账
+func (a *AuthType) AllowRecipient(to Address) bool {
+	if len(a.AllowedTo) == 0 {
+		return true
+	}
+	for _, addr := range a.AllowedTo {
+		if addr.Equal(to) {
+			return true
+		}
+	}
+	return false
+}
+
+//根据委托的起始高度(或时间)start和当前高度(或时间)now计算当前限额周期的序号
+func (a *AuthType) SpendPeriodIndex(start uint64, now uint64) uint64 {
+	if a.SpendPeriod == 0 || now < start {
+		return 0
+	}
+	return (now - start) / a.SpendPeriod
+}
+
+//被委托人在某个限额周期内委托交易已花费的转账金额与gas费用
+type EntrustSpend struct {
+	Period uint64
+	Spent  *big.Int
 }
 
 type BroadTxkey struct {
@@ -181,3 +181,97 @@ func TestMixedcaseAccount_Address(t *testing.T) {
 	}
 
 }
+
+func TestEscrowTxSettleRights(t *testing.T) {
+	var (
+		from      = HexToAddress("0x1000000000000000000000000000000000000001")
+		arbiter   = HexToAddress("0x1000000000000000000000000000000000000002")
+		recipient = HexToAddress("0x1000000000000000000000000000000000000003")
+		other     = HexToAddress("0x1000000000000000000000000000000000000004")
+	)
+	et := EscrowTx{
+		RecorbleTx: RecorbleTx{From: from, Adam: []AddrAmont{{Addr: recipient, Amont: big.NewInt(1)}}, Tim: 100, Typ: ExtraEscrowTxType},
+		Arbiter:    arbiter,
+	}
+	tests := []struct {
+		typ  byte
+		addr Address
+		exp  bool
+	}{
+		{ExtraEscrowReleaseTxType, arbiter, true},
+		{ExtraEscrowReleaseTxType, recipient, true},
+		{ExtraEscrowReleaseTxType, from, false},
+		{ExtraEscrowReleaseTxType, other, false},
+		{ExtraEscrowRefundTxType, arbiter, true},
+		{ExtraEscrowRefundTxType, recipient, false},
+		{ExtraEscrowRefundTxType, from, false},
+		{ExtraRevertTxType, arbiter, false},
+	}
+	for _, test := range tests {
+		if got := et.CanSettle(test.typ, test.addr); got != test.exp {
+			t.Errorf("CanSettle(%d, %x) = %v, want %v", test.typ, test.addr, got, test.exp)
+		}
+	}
+	if !et.IsParticipant(from) || !et.IsParticipant(arbiter) || !et.IsParticipant(recipient) || et.IsParticipant(other) {
+		t.Errorf("IsParticipant mismatch")
+	}
+
+	// Escrow records share the revocable btree, so they must decode as RecorbleTx.
+	b, err := json.Marshal(&et)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rt RecorbleTx
+	if err := json.Unmarshal(b, &rt); err != nil {
+		t.Fatal(err)
+	}
+	if rt.From != from || rt.Typ != ExtraEscrowTxType || rt.Tim != 100 || len(rt.Adam) != 1 {
+		t.Errorf("decoded RecorbleTx mismatch: %+v", rt)
+	}
+}
+
+func TestAuthTypePermission(t *testing.T) {
+	var (
+		allowed = HexToAddress("0x1000000000000000000000000000000000000001")
+		other   = HexToAddress("0x1000000000000000000000000000000000000002")
+	)
+	open := AuthType{}
+	if !open.AllowTxType(ExtraRevocable) || !open.AllowCurrency("MAN") || !open.AllowRecipient(other) {
+		t.Errorf("empty permission should not restrict")
+	}
+	auth := AuthType{
+		StartHeight:       100,
+		EndHeight:         1000,
+		SpendPeriod:       50,
+		AllowedTo:         []Address{allowed},
+		AllowedCurrencies: []string{"MAN"},
+		AllowedTxTypes:    []uint32{uint32(ExtraNormalTxType)},
+	}
+	if !auth.AllowTxType(ExtraNormalTxType) || auth.AllowTxType(ExtraRevocable) {
+		t.Errorf("AllowTxType mismatch")
+	}
+	if !auth.AllowCurrency("MAN") || auth.AllowCurrency("BTC") {
+		t.Errorf("AllowCurrency mismatch")
+	}
+	if !auth.AllowRecipient(allowed) || auth.AllowRecipient(other) {
+		t.Errorf("AllowRecipient mismatch")
+	}
+	for height, exp := range map[uint64]uint64{99: 0, 100: 0, 149: 0, 150: 1, 420: 6} {
+		if got := auth.SpendPeriodIndex(auth.StartHeight, height); got != exp {
+			t.Errorf("SpendPeriodIndex(%d) = %d, want %d", height, got, exp)
+		}
+	}
+	auth.StartTime = 1000
+	if got := auth.SpendPeriodIndex(auth.StartTime, 1120); got != 2 {
+		t.Errorf("SpendPeriodIndex by time = %d, want 2", got)
+	}
+
+	// Entrust data written before permissions existed must still decode.
+	var old []EntrustType
+	if err := json.Unmarshal([]byte(`[{"EntrustAddres":"MAN.x","IsEntrustGas":true,"EndHeight":10}]`), &old); err != nil {
+		t.Fatal(err)
+	}
+	if old[0].SpendLimit != nil || len(old[0].AllowedTo) != 0 {
+		t.Errorf("legacy entrust data decoded with permissions")
+	}
+}
@@ -136,11 +136,12 @@ func New(support BlKSupport) (*ManBlkManage, error) {
 	if err != nil {
 		return nil, err
 	}
-	obj.RegisterManBLkPlugs(CommonBlk, manparams.VersionAlpha, manCommonplug)
-
 	manBcplug, err := NewBCBlkPlug()
 
-	obj.RegisterManBLkPlugs(BroadcastBlk, manparams.VersionAlpha, manBcplug)
+	for _, version := range []string{manparams.VersionAlpha, manparams.VersionBeta} {
+		obj.RegisterManBLkPlugs(CommonBlk, version, manCommonplug)
+		obj.RegisterManBLkPlugs(BroadcastBlk, version, manBcplug)
+	}
 
 	return obj, nil
 }
@@ -150,20 +151,20 @@ func (bd *ManBlkManage) RegisterManBLkPlugs(types string, version string, plug M
 }
 
 func (bd *ManBlkManage) ProduceBlockVersion(num uint64, preVersion string) string {
-	//if num == manparams.VersionNumBeta {
-	//	return manparams.VersionBeta
-	//}
+	if num == manparams.VersionNumBeta {
+		return manparams.VersionBeta
+	}
 	return preVersion
 }
 
 func (bd *ManBlkManage) VerifyBlockVersion(num uint64, curVersion string, preVersion string) error {
-/*if num == manparams.VersionNumBeta {
+	if num == manparams.VersionNumBeta {
 		if curVersion != manparams.VersionBeta {
 			return errors.New("版本号异常")
 		} else {
 			return nil
 		}
-	} else*/ if curVersion != preVersion {
+	} else if curVersion != preVersion {
 		return errors.New("版本号异常,不等于父区块版本号")
 	}
 	return nil
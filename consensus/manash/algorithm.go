@@ -407,6 +407,13 @@ func hashimotoFull(dataset []uint32, hash []byte, nonce uint64) ([]byte, []byte)
 	return newPowHash(hash, nonce, uint64(len(dataset))*4, lookup)
 }
 
+// PowHash returns the mix digest and the proof-of-work value of the given header
+// hash (without nonce) and nonce, the same way VerifySeal computes them. It lets
+// external miners' shares be checked against an arbitrary target.
+func PowHash(hash []byte, nonce uint64) ([]byte, []byte) {
+	return hashimotoLight(32, nil, hash, nonce)
+}
+
 const maxEpoch = 2048
 
 // datasetSizes is a lookup table for the manash dataset size for the first 2048
@@ -296,6 +296,10 @@ func (manash *Manash) CalcDifficulty(chain consensus.ChainReader, time uint64, p
 // the difficulty that a new block should have when created at time
 // given the parent block's time and difficulty.
 func CalcDifficulty(config *params.ChainConfig, time uint64, parent *types.Header) *big.Int {
+	if config.Dev != nil {
+		// 开发链保持最低难度, 出块速度只受出块间隔限制
+		return new(big.Int).Set(params.MinimumDifficulty)
+	}
 	logger := log.New("CalcDifficulty block", parent.Number)
 	next := new(big.Int).Add(parent.Number, big1)
 	switch {
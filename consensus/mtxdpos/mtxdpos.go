@@ -121,6 +121,12 @@ func (md *MtxDPOS) VerifyVersionSigns(reader consensus.StateReader, header *type
 	return nil
 }
 
+// SuperNodeTarget returns the number of valid super account signatures a super
+// block or version needs out of totalCount super accounts.
+func (md *MtxDPOS) SuperNodeTarget(totalCount int) int {
+	return md.calcSuperNodeTarget(totalCount)
+}
+
 func (md *MtxDPOS) calcSuperNodeTarget(totalCount int) int {
 	targetCount := 0
 	if totalCount <= md.config.SuperNodeFullSignThreshold {
@@ -73,9 +73,9 @@ func genValueTx(nbytes int) func(int, *BlockGen) {
 	return func(i int, gen *BlockGen) {
 		toaddr := common.Address{}
 		data := make([]byte, nbytes)
-		gas, _ := IntrinsicGas(data, false, false)
-		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(benchRootAddr), toaddr, big.NewInt(1), gas, nil, data), types.HomesteadSigner{}, benchRootKey)
-		gen.AddTx(tx)
+		gas, _ := IntrinsicGas(data)
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(benchRootAddr), toaddr, big.NewInt(1), gas, nil, data, nil, nil, nil, 0, 0, "MAN", 0), types.NewEIP155Signer(params.TestChainConfig.ChainId), benchRootKey)
+		gen.AddTx(tx.(*types.Transaction))
 	}
 }
 
@@ -113,9 +113,11 @@ func genTxRing(naccounts int) func(int, *BlockGen) {
 				params.TxGas,
 				nil,
 				nil,
+				nil, nil, nil,
+				0, 0, "MAN", 0,
 			)
-			tx, _ = types.SignTx(tx, types.HomesteadSigner{}, ringKeys[from])
-			gen.AddTx(tx)
+			signed, _ := types.SignTx(tx, types.NewEIP155Signer(params.TestChainConfig.ChainId), ringKeys[from])
+			gen.AddTx(signed.(*types.Transaction))
 			from = to
 		}
 	}
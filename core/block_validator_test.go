@@ -9,19 +9,61 @@ import (
 	"testing"
 	"time"
 
+	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/consensus/manash"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/core/vm"
 	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
 	"github.com/MatrixAINetwork/go-matrix/params"
 )
 
+// pendingChain is a chain reader also knowing the states of blocks not inserted
+// yet, as verifying the coinbase of a header reads the state of its parent.
+type pendingChain struct {
+	*BlockChain
+	blocks map[common.Hash]*types.Block
+}
+
+func newPendingChain(chain *BlockChain, blocks []*types.Block) *pendingChain {
+	pending := &pendingChain{BlockChain: chain, blocks: make(map[common.Hash]*types.Block)}
+	for _, block := range blocks {
+		pending.blocks[block.Hash()] = block
+	}
+	return pending
+}
+
+func (c *pendingChain) StateAtBlockHash(hash common.Hash) (*state.StateDB, error) {
+	if block, ok := c.blocks[hash]; ok {
+		return c.StateAt(block.Root())
+	}
+	return c.BlockChain.StateAtBlockHash(hash)
+}
+
+func (c *pendingChain) GetGraphByHash(hash common.Hash) (*mc.TopologyGraph, *mc.ElectGraph, error) {
+	st, err := c.StateAtBlockHash(hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.GetGraphByState(st)
+}
+
+func (c *pendingChain) GetInnerMinerAccounts(hash common.Hash) ([]common.Address, error) {
+	st, err := c.StateAtBlockHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	return matrixstate.GetInnerMinerAccounts(st)
+}
+
 // Tests that simple header verification works, for both good and bad blocks.
 func TestHeaderVerification(t *testing.T) {
 	// Create a simple chain to verify
 	var (
 		testdb    = mandb.NewMemDatabase()
-		gspec     = &Genesis{Config: params.TestChainConfig}
+		gspec     = testGenesis(params.TestChainConfig, nil)
 		genesis   = gspec.MustCommit(testdb)
 		blocks, _ = GenerateChain(params.TestChainConfig, genesis, manash.NewFaker(), testdb, 8, nil)
 	)
@@ -73,7 +115,7 @@ func testHeaderConcurrentVerification(t *testing.T, threads int) {
 	// Create a simple chain to verify
 	var (
 		testdb    = mandb.NewMemDatabase()
-		gspec     = &Genesis{Config: params.TestChainConfig}
+		gspec     = testGenesis(params.TestChainConfig, nil)
 		genesis   = gspec.MustCommit(testdb)
 		blocks, _ = GenerateChain(params.TestChainConfig, genesis, manash.NewFaker(), testdb, 8, nil)
 	)
@@ -95,11 +137,11 @@ func testHeaderConcurrentVerification(t *testing.T, threads int) {
 
 		if valid {
 			chain, _ := NewBlockChain(testdb, nil, params.TestChainConfig, manash.NewFaker(), vm.Config{})
-			_, results = chain.engine.VerifyHeaders(chain, headers, seals)
+			_, results = chain.Engine(headers[0].Version).VerifyHeaders(newPendingChain(chain, blocks), headers, seals)
 			chain.Stop()
 		} else {
 			chain, _ := NewBlockChain(testdb, nil, params.TestChainConfig, manash.NewFakeFailer(uint64(len(headers)-1)), vm.Config{})
-			_, results = chain.engine.VerifyHeaders(chain, headers, seals)
+			_, results = chain.Engine(headers[0].Version).VerifyHeaders(newPendingChain(chain, blocks), headers, seals)
 			chain.Stop()
 		}
 		// Wait for all the verification results
@@ -145,7 +187,7 @@ func testHeaderConcurrentAbortion(t *testing.T, threads int) {
 	// Create a simple chain to verify
 	var (
 		testdb    = mandb.NewMemDatabase()
-		gspec     = &Genesis{Config: params.TestChainConfig}
+		gspec     = testGenesis(params.TestChainConfig, nil)
 		genesis   = gspec.MustCommit(testdb)
 		blocks, _ = GenerateChain(params.TestChainConfig, genesis, manash.NewFaker(), testdb, 1024, nil)
 	)
@@ -164,7 +206,7 @@ func testHeaderConcurrentAbortion(t *testing.T, threads int) {
 	chain, _ := NewBlockChain(testdb, nil, params.TestChainConfig, manash.NewFakeDelayer(time.Millisecond), vm.Config{})
 	defer chain.Stop()
 
-	abort, results := chain.engine.VerifyHeaders(chain, headers, seals)
+	abort, results := chain.Engine(headers[0].Version).VerifyHeaders(newPendingChain(chain, blocks), headers, seals)
 	close(abort)
 
 	// Deplete the results channel
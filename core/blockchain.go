@@ -24,6 +24,7 @@ import (
 
 	"github.com/MatrixAINetwork/go-matrix/ca"
 	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
 	"github.com/MatrixAINetwork/go-matrix/common/mclock"
 	"github.com/MatrixAINetwork/go-matrix/consensus"
 	"github.com/MatrixAINetwork/go-matrix/consensus/mtxdpos"
@@ -78,6 +79,14 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+	TriesInMemory uint64        // Number of recent block states kept in memory before pruning (0 = 128)
+}
+
+// gcRoot is a state root waiting in memory for garbage collection. Roots kept
+// for consensus are flushed to disk instead of being dropped.
+type gcRoot struct {
+	root common.Hash
+	keep bool
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -154,6 +163,8 @@ type BlockChain struct {
 
 	//bad block dump history
 	badDumpHistory []common.Hash
+
+	blackList *Blacklist // 禁止作为交易接收方的账户
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -191,16 +202,19 @@ func NewBlockChain(db mandb.Database, cacheConfig *CacheConfig, chainConfig *par
 		badBlocks:       badBlocks,
 		matrixProcessor: NewMatrixProcessor(),
 		badDumpHistory:  make([]common.Hash, 0),
+		blackList:       NewInitblacklist(),
 	}
 	bc.topologyStore = NewTopologyStore(bc)
 
 	validator := NewBlockValidator(chainConfig, bc, engine)
 	processor := NewStateProcessor(chainConfig, bc, engine)
-	bc.SetValidator(manparams.VersionAlpha, validator)
-	bc.SetProcessor(manparams.VersionAlpha, processor)
-	bc.engine[manparams.VersionAlpha] = engine
 	dpos := mtxdpos.NewMtxDPOS(chainConfig.SimpleMode)
-	bc.dposEngine[manparams.VersionAlpha] = dpos
+	for _, version := range []string{manparams.VersionAlpha, manparams.VersionBeta} {
+		bc.SetValidator(version, validator)
+		bc.SetProcessor(version, processor)
+		bc.engine[version] = engine
+		bc.dposEngine[version] = dpos
+	}
 
 	bc.defaultEngine, bc.defaultDposEngine, bc.defaultProcessor, bc.defaultValidator = engine, dpos, processor, validator
 
@@ -212,8 +226,10 @@ func NewBlockChain(db mandb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if err != nil {
 		return nil, err
 	}
-	bc.hc.SetEngine(manparams.VersionAlpha, engine)
-	bc.hc.SetDposEngine(manparams.VersionAlpha, dpos)
+	for _, version := range []string{manparams.VersionAlpha, manparams.VersionBeta} {
+		bc.hc.SetEngine(version, engine)
+		bc.hc.SetDposEngine(version, dpos)
+	}
 	bc.genesisBlock = bc.GetBlockByNumber(0)
 	if bc.genesisBlock == nil {
 		return nil, ErrNoGenesis
@@ -747,7 +763,7 @@ func (bc *BlockChain) Stop() {
 	if !bc.cacheConfig.Disabled {
 		triedb := bc.stateCache.TrieDB()
 
-		for _, offset := range []uint64{0, 1, triesInMemory - 1} {
+		for _, offset := range []uint64{0, 1, bc.triesInMemory() - 1} {
 			if number := bc.CurrentBlock().NumberU64(); number > offset {
 				recent := bc.GetBlockByNumber(number - offset)
 
@@ -758,7 +774,13 @@ func (bc *BlockChain) Stop() {
 			}
 		}
 		for !bc.triegc.Empty() {
-			triedb.Dereference(bc.triegc.PopItem().(common.Hash), common.Hash{})
+			item := bc.triegc.PopItem().(gcRoot)
+			if item.keep {
+				if err := triedb.Commit(item.root, false); err != nil {
+					log.Error("Failed to commit consensus state trie", "root", item.root, "err", err)
+				}
+			}
+			triedb.Dereference(item.root, common.Hash{})
 		}
 		if size := triedb.Size(); size != 0 {
 			log.Error("Dangling trie nodes after full cleanup")
@@ -1067,8 +1089,9 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	} else {
 		// Full but not archive node, do proper garbage collection
 		triedb.Reference(root, common.Hash{}) // metadata reference to keep trie alive
-		bc.triegc.Push(root, -float32(block.NumberU64()))
+		bc.triegc.Push(gcRoot{root: root, keep: bc.isConsensusRoot(block, state)}, -float32(block.NumberU64()))
 
+		triesInMemory := bc.triesInMemory()
 		if current := block.NumberU64(); current > triesInMemory {
 			// Find the next state trie we need to commit
 			header := bc.GetHeaderByNumber(current - triesInMemory)
@@ -1086,9 +1109,9 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 				if chosen < lastWrite+triesInMemory {
 					switch {
 					case size >= 2*limit:
-						log.Warn("State memory usage too high, committing", "size", size, "limit", limit, "optimum", float64(chosen-lastWrite)/triesInMemory)
+						log.Warn("State memory usage too high, committing", "size", size, "limit", limit, "optimum", float64(chosen-lastWrite)/float64(triesInMemory))
 					case bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit:
-						log.Info("State in memory for too long, committing", "time", bc.gcproc, "allowance", bc.cacheConfig.TrieTimeLimit, "optimum", float64(chosen-lastWrite)/triesInMemory)
+						log.Info("State in memory for too long, committing", "time", bc.gcproc, "allowance", bc.cacheConfig.TrieTimeLimit, "optimum", float64(chosen-lastWrite)/float64(triesInMemory))
 					}
 				}
 				// If optimum or critical limits reached, write to disk
@@ -1100,12 +1123,19 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 			}
 			// Garbage collect anything below our required write retention
 			for !bc.triegc.Empty() {
-				root, number := bc.triegc.Pop()
+				item, number := bc.triegc.Pop()
 				if uint64(-number) > chosen {
-					bc.triegc.Push(root, number)
+					bc.triegc.Push(item, number)
 					break
 				}
-				triedb.Dereference(root.(common.Hash), common.Hash{})
+				// 共识需要的历史状态写入磁盘, 其余的释放
+				root := item.(gcRoot)
+				if root.keep {
+					if err := triedb.Commit(root.root, false); err != nil {
+						return NonStatTy, err
+					}
+				}
+				triedb.Dereference(root.root, common.Hash{})
 			}
 		}
 	}
@@ -1712,15 +1742,17 @@ func (bc *BlockChain) update() {
 type BadBlockArgs struct {
 	Hash   common.Hash   `json:"hash"`
 	Header *types.Header `json:"header"`
+	RLP    string        `json:"rlp"`
 }
 
 // BadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
 func (bc *BlockChain) BadBlocks() ([]BadBlockArgs, error) {
 	headers := make([]BadBlockArgs, 0, bc.badBlocks.Len())
 	for _, hash := range bc.badBlocks.Keys() {
-		if hdr, exist := bc.badBlocks.Peek(hash); exist {
-			header := hdr.(*types.Header)
-			headers = append(headers, BadBlockArgs{header.Hash(), header})
+		if blk, exist := bc.badBlocks.Peek(hash); exist {
+			block := blk.(*types.Block)
+			blockRlp, _ := rlp.EncodeToBytes(block)
+			headers = append(headers, BadBlockArgs{block.Hash(), block.Header(), hexutil.Encode(blockRlp)})
 		}
 	}
 	return headers, nil
@@ -1728,7 +1760,7 @@ func (bc *BlockChain) BadBlocks() ([]BadBlockArgs, error) {
 
 // addBadBlock adds a bad block to the bad-block LRU cache
 func (bc *BlockChain) addBadBlock(block *types.Block) {
-	bc.badBlocks.Add(block.Header().Hash(), block.Header())
+	bc.badBlocks.Add(block.Hash(), block)
 }
 
 // reportBlock logs a bad block error.
@@ -2043,7 +2075,7 @@ func (bc *BlockChain) processSuperBlockState(block *types.Block, stateDB *state.
 
 	if err := mState.SetSuperBlkToState(stateDB, block.Header().Extra, block.Header().Number.Uint64()); err != nil {
 		log.Error("genesis", "设置matrix状态树错误", err)
-		return errors.Errorf("设置超级区块状态树错误", err)
+		return errors.Errorf("设置超级区块状态树错误: %v", err)
 	}
 	return nil
 }
@@ -2130,7 +2162,7 @@ func (bc *BlockChain) GetA1AccountFromA0Account(a0Account common.Address, blockH
 		return common.Address{}, errors.New("获取stateDB失败")
 	}
 
-	a1Account := depoistInfo.GetAuthAccount(st, a0Account)
+	a1Account := depoistInfo.GetAuthAccountAtHeight(st, a0Account, block.NumberU64())
 	if a1Account == (common.Address{}) {
 		log.Error(common.SignLog, "从A0账户获取A1账户", "失败", "不存在A1账户 a0Account", a0Account)
 		return common.Address{}, errors.New("不存在A1账户")
@@ -2147,6 +2179,20 @@ func (bc *BlockChain) GetA0AccountFromA1Account(a1Account common.Address, blockH
 		log.ERROR(common.SignLog, "从A1账户获取A0账户", "失败", "根据区块hash获取区块失败", "err")
 		return common.Address{}, errors.Errorf("获取区块(%s)失败", blockHash.TerminalString())
 	}
+	return bc.getA0AccountFromA1AccountAtHeight(a1Account, block, block.NumberU64())
+}
+
+//根据A1账户和签名高度得到A0账户
+func (bc *BlockChain) GetA0AccountFromA1AccountAtSignHeight(a1Account common.Address, blockHash common.Hash, signHeight uint64) (common.Address, error) {
+	block := bc.GetBlockByHash(blockHash)
+	if block == nil {
+		log.ERROR(common.SignLog, "从A1账户获取A0账户", "失败", "根据区块hash获取区块失败", "err")
+		return common.Address{}, errors.Errorf("获取区块(%s)失败", blockHash.TerminalString())
+	}
+	return bc.getA0AccountFromA1AccountAtHeight(a1Account, block, signHeight)
+}
+
+func (bc *BlockChain) getA0AccountFromA1AccountAtHeight(a1Account common.Address, block *types.Block, height uint64) (common.Address, error) {
 	//根据区块根得到区块链数据库
 	st, err := bc.StateAt(block.Root())
 	if err != nil {
@@ -2154,7 +2200,8 @@ func (bc *BlockChain) GetA0AccountFromA1Account(a1Account common.Address, blockH
 		return common.Address{}, errors.New("获取stateDB失败")
 	}
 
-	a0Account := depoistInfo.GetDepositAccount(st, a1Account)
+	//签名账户轮换交接期内新旧A1都能找到A0
+	a0Account := depoistInfo.GetDepositAccountAtHeight(st, a1Account, height)
 	if a0Account == (common.Address{}) {
 		log.Error(common.SignLog, "从A1账户获取A0账户", "失败", "不存在A0账户 a1Account", a1Account)
 		return common.Address{}, errors.New("不存在A0账户")
@@ -2178,15 +2225,31 @@ func (bc *BlockChain) GetA0AccountFromA2Account(a2Account common.Address, blockH
 
 //根据A0账户得到A2账户集合
 func (bc *BlockChain) GetA2AccountsFromA0Account(a0Account common.Address, blockHash common.Hash) ([]common.Address, error) {
-	a1Account, err := bc.GetA1AccountFromA0Account(a0Account, blockHash)
-	if err != nil {
-		return nil, err
+	block := bc.GetBlockByHash(blockHash)
+	if block == nil {
+		log.ERROR(common.SignLog, "从A0账户获取A2账户", "失败", "根据区块hash获取区块失败 hash", blockHash)
+		return nil, errors.Errorf("获取区块(%s)失败", blockHash.TerminalString())
 	}
-	a2Accounts, err := bc.GetA2AccountsFromA1Account(a1Account, blockHash)
+	return bc.getA2AccountsFromA0AccountAtHeight(a0Account, block, block.NumberU64())
+}
+
+//按高度得到A2账户集合,末尾附带可用的A1账户(签名账户轮换交接期内新旧A1都可签名)
+func (bc *BlockChain) getA2AccountsFromA0AccountAtHeight(a0Account common.Address, block *types.Block, height uint64) ([]common.Address, error) {
+	st, err := bc.StateAt(block.Root())
 	if err != nil {
-		return nil, err
+		log.ERROR(common.SignLog, "从A0账户获取A2账户", "失败", "根据区块root获取statedb失败 err", err)
+		return nil, errors.New("获取stateDB失败")
 	}
-	return a2Accounts, nil
+	a1Accounts := depoistInfo.GetAuthAccountsAtHeight(st, a0Account, height)
+	if len(a1Accounts) == 0 {
+		log.Error(common.SignLog, "从A0账户获取A2账户", "失败", "不存在A1账户 a0Account", a0Account)
+		return nil, errors.New("不存在A1账户")
+	}
+	a2Accounts := st.GetEntrustFrom(a1Accounts[0], height)
+	if len(a2Accounts) == 0 {
+		log.INFO(common.SignLog, "获得A2账户", "失败", "无委托交易,使用A1账户", a1Accounts[0].String(), "高度", height)
+	}
+	return append(a2Accounts, a1Accounts...), nil
 }
 
 //根据任意账户得到A0和A1账户
@@ -2214,15 +2277,12 @@ func (bc *BlockChain) GetA0AccountFromAnyAccount(account common.Address, blockHa
 
 //根据A0账户得到A2账户集合
 func (bc *BlockChain) GetA2AccountsFromA0AccountAtSignHeight(a0Account common.Address, blockHash common.Hash, signHeight uint64) ([]common.Address, error) {
-	a1Account, err := bc.GetA1AccountFromA0Account(a0Account, blockHash)
-	if err != nil {
-		return nil, err
-	}
-	a2Accounts, err := bc.GetA2AccountsFromA1AccountAtSignHeight(a1Account, blockHash, signHeight)
-	if err != nil {
-		return nil, err
+	block := bc.GetBlockByHash(blockHash)
+	if block == nil {
+		log.ERROR(common.SignLog, "从A0账户获取A2账户", "失败", "根据区块hash获取区块失败 hash", blockHash)
+		return nil, errors.Errorf("获取区块(%s)失败", blockHash.TerminalString())
 	}
-	return a2Accounts, nil
+	return bc.getA2AccountsFromA0AccountAtHeight(a0Account, block, signHeight)
 }
 
 func (bc *BlockChain) GetA2AccountsFromA1AccountAtSignHeight(a1Account common.Address, blockHash common.Hash, signHeight uint64) ([]common.Address, error) {
@@ -2253,7 +2313,7 @@ func (bc *BlockChain) GetA2AccountsFromA1AccountAtSignHeight(a1Account common.Ad
 //根据任意账户得到A0和A1账户
 func (bc *BlockChain) GetA0AccountFromAnyAccountAtSignHeight(account common.Address, blockHash common.Hash, signHeight uint64) (common.Address, common.Address, error) {
 	//假设传入的account为A1账户
-	a0Account, err := bc.GetA0AccountFromA1Account(account, blockHash)
+	a0Account, err := bc.GetA0AccountFromA1AccountAtSignHeight(account, blockHash, signHeight)
 	if err == nil {
 		//log.Debug(common.SignLog, "根据任意账户得到A0和A1账户", "输入为A1账户", "输入A1", account.Hex(), "输出A0", a0Account.Hex(), "签名高度", signHeight)
 		return a0Account, account, nil
@@ -2265,7 +2325,7 @@ func (bc *BlockChain) GetA0AccountFromAnyAccountAtSignHeight(account common.Addr
 		return common.Address{0}, common.Address{0}, err
 	}
 	//走到这，说明是A2账户
-	a0Account, err = bc.GetA0AccountFromA1Account(a1Account, blockHash)
+	a0Account, err = bc.GetA0AccountFromA1AccountAtSignHeight(a1Account, blockHash, signHeight)
 	if err != nil {
 		log.Error(common.SignLog, "根据任意账户得到A0和A1账户", "输入为A2账户", "输入A2", account.Hex(), "输出A1", a1Account.Hex(), "输出A0", "失败", "签名高度", signHeight)
 	}
@@ -0,0 +1,84 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/pkg/errors"
+)
+
+// ReplayResult is the outcome of re-executing a single block on top of its
+// parent state.
+type ReplayResult struct {
+	Number      uint64
+	Hash        common.Hash
+	ParentRoot  common.Hash // state root the replay started from
+	Root        common.Hash // state root claimed by the block header
+	ReplayRoot  common.Hash // state root produced by the replay
+	UsedGas     uint64
+	Receipts    types.Receipts
+	ValidateErr error          // error reported by the versioned validator, if any
+	State       *state.StateDB // replayed state, never committed to disk
+}
+
+// RootMatch reports whether the replayed state root equals the header root.
+func (r *ReplayResult) RootMatch() bool {
+	return r.Root == r.ReplayRoot
+}
+
+// ReplayBlock re-executes block through the versioned processor on top of the
+// parent state, exactly as insertChain would, without writing anything back to
+// the database. The block doesn't need to be part of the local chain, which
+// allows replaying bad blocks, but its parent must be.
+func (bc *BlockChain) ReplayBlock(block *types.Block) (*ReplayResult, error) {
+	parent := bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, errors.Errorf("parent block of #%d (%s) not found", block.NumberU64(), block.ParentHash().TerminalString())
+	}
+	statedb, err := state.New(parent.Root(), bc.stateCache)
+	if err != nil {
+		return nil, errors.Errorf("parent state %x unavailable: %v", parent.Root(), err)
+	}
+
+	header := block.Header()
+	result := &ReplayResult{
+		Number:     block.NumberU64(),
+		Hash:       block.Hash(),
+		ParentRoot: parent.Root(),
+		Root:       header.Root,
+		State:      statedb,
+	}
+	if block.IsSuperBlock() {
+		if err := bc.Processor(header.Version).ProcessSuperBlk(block, statedb); err != nil {
+			return nil, err
+		}
+	} else {
+		result.Receipts, _, result.UsedGas, err = bc.Processor(header.Version).Process(block, parent, statedb, bc.vmConfig)
+		if err != nil {
+			return nil, err
+		}
+		result.ValidateErr = bc.Validator(header.Version).ValidateState(block, parent, statedb, result.Receipts, result.UsedGas)
+	}
+	result.ReplayRoot = statedb.IntermediateRoot(bc.chainConfig.IsEIP158(header.Number))
+	return result, nil
+}
+
+// DiffReplay compares the replayed state with the state stored under the
+// block's header root. Bad blocks have no stored state, their replay is compared
+// with the dump of the parent state instead, listing everything the block
+// changed. base is the root of the state the diff was taken against.
+func (bc *BlockChain) DiffReplay(result *ReplayResult) (diffs []state.DumpDiff, base common.Hash, err error) {
+	base = result.Root
+	stored, err := state.New(base, bc.stateCache)
+	if err != nil {
+		base = result.ParentRoot
+		if stored, err = state.New(base, bc.stateCache); err != nil {
+			return nil, base, errors.Errorf("neither the state of block #%d nor its parent state %x is stored: %v", result.Number, base, err)
+		}
+	}
+	return state.DiffDump(stored.RawDump(), result.State.RawDump()), base, nil
+}
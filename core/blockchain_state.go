@@ -76,6 +76,11 @@ func (bc *BlockChain) GetTopologyStore() *TopologyStore {
 	return bc.topologyStore
 }
 
+// BlackList returns the accounts transactions can't be sent to.
+func (bc *BlockChain) BlackList() *Blacklist {
+	return bc.blackList
+}
+
 func (bc *BlockChain) GetBroadcastInterval() (*mc.BCIntervalInfo, error) {
 	st, err := bc.State()
 	if err != nil {
@@ -21,10 +21,10 @@ func TestReadSlashParacheckCfg(t *testing.T) {
 
 	_, ok, err := blockchain.slashCfgProc(nil, 0)
 	if err != ErrStatePtrIsNil {
-		t.Errorf("state 指针为空检查错误", err)
+		t.Error("state 指针为空检查错误", err)
 	}
 	if ok {
-		t.Errorf("返回状态错误", err, "status", ok)
+		t.Error("返回状态错误", err, "status", ok)
 	}
 }
 func Test_shouldBlockProduceStatsStartCase0(t *testing.T) {
@@ -36,18 +36,18 @@ func Test_shouldBlockProduceStatsStartCase0(t *testing.T) {
 
 	status, err := blockchain.shouldBlockProduceStatsStart(nil, common.Hash{}, &SlashCfg)
 	if status != false || err != ErrStatePtrIsNil {
-		t.Errorf("status", status, "error", err)
+		t.Error("status", status, "error", err)
 	}
 
 	status, err = blockchain.shouldBlockProduceStatsStart(state, common.Hash{}, nil)
 	if status != false || err != ErrSlashCfgPtrIsNil {
-		t.Errorf("status", status, "error", err)
+		t.Error("status", status, "error", err)
 	}
 
 	var slashCfg = mc.BlockProduceSlashCfg{Switcher: false, LowTHR: 1, ProhibitCycleNum: 2}
 	status, err = blockchain.shouldBlockProduceStatsStart(state, common.Hash{}, &slashCfg)
 	if status != false || err != nil {
-		t.Errorf("status", status, "error", err)
+		t.Error("status", status, "error", err)
 	}
 
 }
@@ -61,7 +61,7 @@ func Test_shouldBlockProduceStatsStartCase1(t *testing.T) {
 	matrixstate.SetBlockProduceStatsStatus(state, &mc.BlockProduceSlashStatsStatus{Number: 0})
 	status, err := blockchain.shouldBlockProduceStatsStart(state, common.Hash{}, &slashCfg)
 	if status != true || err != nil {
-		t.Errorf("status", status, "err", err)
+		t.Error("status", status, "err", err)
 	}
 }
 func Test_getSlashStatsList(t *testing.T) {
@@ -73,7 +73,7 @@ func Test_getSlashStatsList(t *testing.T) {
 	statsList, err := getSlashStatsList(nil)
 
 	if statsList != nil || err != ErrStatePtrIsNil {
-		t.Errorf("statsList", statsList, "err", err)
+		t.Error("statsList", statsList, "err", err)
 	}
 	var statList = mc.BlockProduceStats{}
 	for i := 0; i < 10; i++ {
@@ -81,17 +81,17 @@ func Test_getSlashStatsList(t *testing.T) {
 	}
 	err = matrixstate.SetBlockProduceStats(state, &statList)
 	if err != nil {
-		t.Errorf("write err", err)
+		t.Error("write err", err)
 	}
 	readStatsList, err := getSlashStatsList(state)
 	if len(statList.StatsList) != len(readStatsList.StatsList) {
-		t.Errorf("数据长度不一致", err)
+		t.Error("数据长度不一致", err)
 		fmt.Println(statList)
 		fmt.Println(readStatsList)
 	} else {
 		for i := 0; i < len(statList.StatsList); i++ {
 			if !statList.StatsList[i].Address.Equal(readStatsList.StatsList[i].Address) || statList.StatsList[i].ProduceNum != readStatsList.StatsList[i].ProduceNum {
-				t.Errorf("数据不一致", nil)
+				t.Error("数据不一致", nil)
 			}
 
 		}
@@ -121,7 +121,7 @@ func Test_getLatestInitStatsNum(t *testing.T) {
 		t.Errorf("状态树读不出错误")
 	}
 	if readVal != setNumber {
-		t.Errorf("数据读取错误", readVal)
+		t.Error("数据读取错误", readVal)
 	}
 }
 func Test_initStatsListCase0(t *testing.T) {
@@ -129,13 +129,13 @@ func Test_initStatsListCase0(t *testing.T) {
 	NewBlockChain(diskdb, nil, &params.ChainConfig{}, manash.NewFaker(), vm.Config{})
 
 	//空指针不处理
-	initStatsList(nil)
+	initStatsList(nil, 0)
 
 	//没有初选列表的情况下，应该设置空的统计列表
 	state, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
 	matrixstate.SetVersionInfo(state, manparams.VersionAlpha)
 
-	initStatsList(state)
+	initStatsList(state, 0)
 	statsList, err := matrixstate.GetBlockProduceStats(state)
 	if err != nil {
 		t.Errorf("读取统计列表错误, %s", err)
@@ -164,7 +164,7 @@ func Test_initStatsListCase1(t *testing.T) {
 		electList.ElectList = append(electList.ElectList, node)
 	}
 	matrixstate.SetElectGraph(state, &electList)
-	initStatsList(state)
+	initStatsList(state, 0)
 	statsList, err := matrixstate.GetBlockProduceStats(state)
 	if err != nil {
 		t.Errorf("读取统计列表错误, %s", err)
@@ -399,8 +399,8 @@ func Test_BlackListAddBlackListCase1(t *testing.T) {
 	var statsList []mc.UserBlockProduceNum
 	statsList = append(statsList, mc.UserBlockProduceNum{common.BigToAddress(big.NewInt(1)), 1})
 	handle.AddBlackList(statsList, &slashCfg)
-	//预期：已存在黑名单中的节点，重置禁止值
-	blackList.BlackList[1].ProhibitCycleCounter = 10
+	//预期：已存在黑名单中的节点，重置禁止值(当前周期计入禁止周期)
+	blackList.BlackList[1].ProhibitCycleCounter = slashCfg.ProhibitCycleNum - 1
 
 	for i := 0; i < 100; i++ {
 		if handle.blacklist[i].ProhibitCycleCounter != blackList.BlackList[i].ProhibitCycleCounter {
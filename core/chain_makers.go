@@ -5,6 +5,7 @@
 package core
 
 import (
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
 	"github.com/MatrixAINetwork/go-matrix/mandb"
 	"github.com/MatrixAINetwork/go-matrix/params"
 )
@@ -24,6 +26,54 @@ var (
 	forkSeed      = 2
 )
 
+// testValidatorKeys are the keys of the validators of the test genesis, which
+// sign every generated block. The first one is the leader and the faucet of the
+// chain, acting as its inner miner, broadcast node and every super account.
+var testValidatorKeys = func() []*ecdsa.PrivateKey {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i, hex := range []string{
+		"8417c59078f1e765f1c0a7365e006b0efba02b86f8676bbc15459be3757135b9",
+		"13c2d891c585e3c72af4d7c21fb0ee8645661387a3698ed6b3a3b12ebdf8fd15",
+		"e42b4de5d2312bfc7d61a5077fd4202526369974b18514c920f3ca1a80488340",
+	} {
+		keys[i], _ = crypto.HexToECDSA(hex)
+	}
+	return keys
+}()
+
+// testBCInterval is the broadcast interval of the test genesis, longer than the
+// chains the tests generate so that none of them reaches a broadcast block.
+const testBCInterval = 100000
+
+// testGenesis returns the genesis of a private network made of the test
+// validators with its matrix state, running the given chain config and holding
+// the given allocations on top of the faucet ones.
+func testGenesis(config *params.ChainConfig, alloc GenesisAlloc) *Genesis {
+	validators := make([]common.Address, len(testValidatorKeys))
+	for i, key := range testValidatorKeys {
+		validators[i] = crypto.PubkeyToAddress(key.PublicKey)
+	}
+	faucet := validators[0]
+	genesis := DevnetGenesisBlock(1337, validators, nil, validators[:1], faucet)
+	genesis.Config = config
+	// 生成的区块时间按父区块递增, 创世时间不能为当前时间
+	genesis.Timestamp = 0
+	for addr, account := range alloc {
+		genesis.Alloc[addr] = account
+	}
+	innerMiners := []GenesisAddress{GenesisAddress(faucet)}
+	genesis.MState.InnerMiners = &innerMiners
+	interval := *genesis.MState.BCICfg
+	interval.BCInterval = testBCInterval
+	genesis.MState.BCICfg = &interval
+	sign, err := crypto.SignWithValidate(common.BytesToHash([]byte(genesis.Version)).Bytes(), true, testValidatorKeys[0])
+	if err != nil {
+		panic(err)
+	}
+	genesis.VersionSignatures = []common.Signature{common.BytesToSignature(sign)}
+	return genesis
+}
+
 // BlockGen creates blocks for testing.
 // See GenerateChain for a detailed explanation.
 type BlockGen struct {
@@ -62,7 +112,7 @@ func (b *BlockGen) SetExtra(data []byte) {
 }
 
 // AddTx adds a transaction to the generated block. If no coinbase has
-// been set, the block's coinbase is set to the zero address.
+// been set, the block keeps the coinbase of its parent.
 //
 // AddTx panics if the transaction cannot be executed. In addition to
 // the protocol-imposed limitations (gas limit, etc.), there are some
@@ -74,7 +124,7 @@ func (b *BlockGen) AddTx(tx *types.Transaction) {
 }
 
 // AddTxWithChain adds a transaction to the generated block. If no coinbase has
-// been set, the block's coinbase is set to the zero address.
+// been set, the block keeps the coinbase of its parent.
 //
 // AddTxWithChain panics if the transaction cannot be executed. In addition to
 // the protocol-imposed limitations (gas limit, etc.), there are some
@@ -83,7 +133,7 @@ func (b *BlockGen) AddTx(tx *types.Transaction) {
 // the block in chain will be returned.
 func (b *BlockGen) AddTxWithChain(bc *BlockChain, tx *types.Transaction) {
 	if b.gasPool == nil {
-		b.SetCoinbase(common.Address{})
+		b.SetCoinbase(b.header.Coinbase)
 	}
 	b.statedb.Prepare(tx.Hash(), common.Hash{}, len(b.txs))
 	receipt, _, err := ApplyTransaction(b.config, bc, &b.header.Coinbase, b.gasPool, b.statedb, b.header, tx, &b.header.GasUsed, vm.Config{})
@@ -153,7 +203,7 @@ func (b *BlockGen) OffsetTime(seconds int64) {
 // The generator function is called with a new block generator for
 // every block. Any transactions and uncles added to the generator
 // become part of the block. If gen is nil, the blocks will be empty
-// and their coinbase will be the zero address.
+// and their coinbase will be the one of their parent.
 //
 // Blocks created by GenerateChain do not contain valid proof of work
 // values. Inserting them into BlockChain requires use of FakePow or
@@ -163,12 +213,22 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 		config = params.TestChainConfig
 	}
 	blocks, receipts := make(types.Blocks, n), make([]types.Receipts, n)
-	genblock := func(i int, parent *types.Block, statedb *state.StateDB) (*types.Block, types.Receipts) {
-		// TODO(karalabe): This is needed for clique, which depends on multiple blocks.
-		// It's nonetheless ugly to spin up a blockchain here. Get rid of this somehow.
-		blockchain, _ := NewBlockChain(db, nil, config, engine, vm.Config{})
-		defer blockchain.Stop()
+	// 区块按链上的流程处理, 处理时需要查找已生成的区块
+	blockchain, err := NewBlockChain(db, nil, config, engine, vm.Config{})
+	if err != nil {
+		panic(fmt.Sprintf("blockchain create error: %v", err))
+	}
+	defer blockchain.Stop()
+	// 生成的区块只放入缓存供后续区块查找, 不写入数据库
+	cache := func(block *types.Block) {
+		blockchain.hc.numberCache.Add(block.Hash(), block.NumberU64())
+		blockchain.hc.headerCache.Add(block.Hash(), block.Header())
+		blockchain.blockCache.Add(block.Hash(), block)
+	}
+	// 父区块可能仅有区块头写入了数据库
+	cache(parent)
 
+	genblock := func(i int, parent *types.Block, statedb *state.StateDB) (*types.Block, types.Receipts) {
 		b := &BlockGen{i: i, parent: parent, chain: blocks, chainReader: blockchain, statedb: statedb, config: config, engine: engine}
 		b.header = makeHeader(b.chainReader, parent, statedb, b.engine)
 
@@ -190,7 +250,29 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 		}
 
 		if b.engine != nil {
+			// 按链上的流程重新处理区块, 状态中包含matrix状态的变化
+			statedb, err := state.New(parent.Root(), statedb.Database())
+			if err != nil {
+				panic(err)
+			}
+			receipts, _, usedGas, err := blockchain.Processor(b.header.Version).Process(types.NewBlock(b.header, b.txs, b.uncles, b.receipts), parent, statedb, vm.Config{})
+			if err != nil {
+				panic(fmt.Sprintf("block process error: %v", err))
+			}
+			b.header.GasUsed, b.receipts = usedGas, receipts
 			block, _ := b.engine.Finalize(b.chainReader, b.header, statedb, b.txs, b.uncles, b.receipts)
+			// 测试创世的区块由全部验证者签名
+			if header := block.Header(); header.Leader == crypto.PubkeyToAddress(testValidatorKeys[0].PublicKey) {
+				header.Signatures = make([]common.Signature, 0, len(testValidatorKeys))
+				for _, key := range testValidatorKeys {
+					sign, err := crypto.SignWithValidate(header.HashNoSignsAndNonce().Bytes(), true, key)
+					if err != nil {
+						panic(fmt.Sprintf("block sign error: %v", err))
+					}
+					header.Signatures = append(header.Signatures, common.BytesToSignature(sign))
+				}
+				block = block.WithSeal(header)
+			}
 			// Write state changes to db
 			root, err := statedb.Commit(config.IsEIP158(b.header.Number))
 			if err != nil {
@@ -199,6 +281,7 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 			if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
 				panic(fmt.Sprintf("trie write error: %v", err))
 			}
+			cache(block)
 			return block, b.receipts
 		}
 		return nil, nil
@@ -225,9 +308,13 @@ func makeHeader(chain consensus.ChainReader, parent *types.Block, state *state.S
 	}
 
 	return &types.Header{
-		Root:       state.IntermediateRoot(chain.Config().IsEIP158(parent.Number())),
-		ParentHash: parent.Hash(),
-		Coinbase:   parent.Coinbase(),
+		Root:              state.IntermediateRoot(chain.Config().IsEIP158(parent.Number())),
+		ParentHash:        parent.Hash(),
+		Coinbase:          parent.Coinbase(),
+		Leader:            parent.Header().Leader,
+		Version:           parent.Version(),
+		VersionSignatures: parent.Header().VersionSignatures,
+		NetTopology:       common.NetTopology{Type: common.NetTopoTypeChange},
 		Difficulty: engine.CalcDifficulty(chain, time.Uint64(), &types.Header{
 			Number:     parent.Number(),
 			Time:       new(big.Int).Sub(time, big.NewInt(10)),
@@ -250,7 +337,7 @@ func NewCanonical(engine consensus.Engine, n int, full bool) (mandb.Database, *B
 func newCanonical(engine consensus.Engine, n int, full bool) (mandb.Database, *BlockChain, error) {
 	var (
 		db      = mandb.NewMemDatabase()
-		genesis = new(Genesis).MustCommit(db)
+		genesis = testGenesis(params.AllManashProtocolChanges, nil).MustCommit(db)
 	)
 
 	// Initialize a fresh chain with only a genesis block
@@ -284,7 +371,8 @@ func makeHeaderChain(parent *types.Header, n int, engine consensus.Engine, db ma
 // makeBlockChain creates a deterministic chain of blocks rooted at parent.
 func makeBlockChain(parent *types.Block, n int, engine consensus.Engine, db mandb.Database, seed int) []*types.Block {
 	blocks, _ := GenerateChain(params.TestChainConfig, parent, engine, db, n, func(i int, b *BlockGen) {
-		b.SetCoinbase(common.Address{0: byte(seed), 19: byte(i)})
+		// coinbase须为矿工, 以额外数据区分不同的链
+		b.SetExtra([]byte{byte(seed), byte(i)})
 	})
 	return blocks
 }
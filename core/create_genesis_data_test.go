@@ -27,15 +27,16 @@ var (
 	saveFileName = "./saveGenesis.txt"
 )
 
-func init() {
+// loadGens reads the node list the genesis data is generated from.
+func loadGens(t *testing.T) {
 	file, err := os.Open(readFileName)
 	if err != nil {
-		os.Exit(1)
+		t.Skipf("no genesis node list: %v", err)
 	}
 	defer file.Close()
 	data, err := ioutil.ReadAll(file)
 	if err != nil {
-		os.Exit(1)
+		t.Fatalf("failed to read genesis node list: %v", err)
 	}
 
 	dadaSclice := strings.Split(string(data), "\n")
@@ -56,10 +57,10 @@ func init() {
 		gen.Role = strings.Trim(strings.Trim(strings.Trim(sc[2], " "), "\r"), "\n")
 		gens = append(gens, gen)
 	}
-	return
 }
 
 func TestCreateGenesisData(t *testing.T) {
+	loadGens(t)
 	var str string
 	for k, v := range gens {
 		switch v.Role {
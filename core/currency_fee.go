@@ -0,0 +1,131 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+)
+
+var (
+	ErrCurrencyFeeRate       = errors.New("currency fee rate is invalid")
+	ErrCurrencyFeeRateSetter = errors.New("currency fee rate not set by a multi-coin super account")
+)
+
+// checkCurrencyFeeRate checks the currency fee rate transaction of from against
+// the matrix state. It returns the decoded transaction and the rates of the
+// state.
+func checkCurrencyFeeRate(st matrixstate.StateDB, from common.Address, data []byte) (*common.CurrencyFeeRateTx, *mc.CurrencyFeeRateInfo, error) {
+	// Beta版本之前不支持设置币种gas费率交易
+	if !manparams.IsBetaVersion(matrixstate.GetVersionInfo(st)) {
+		return nil, nil, ErrTXUnknownType
+	}
+	tx := new(common.CurrencyFeeRateTx)
+	if err := json.Unmarshal(data, tx); err != nil {
+		return nil, nil, ErrCurrencyFeeRate
+	}
+	// MAN的gas始终用MAN支付
+	if !common.IsValidityCurrency(tx.Currency) {
+		return nil, nil, ErrCurrencyFeeRate
+	}
+	accounts, err := matrixstate.GetMultiCoinSuperAccounts(st)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !containsAccount(accounts, from) {
+		return nil, nil, ErrCurrencyFeeRateSetter
+	}
+	info, err := matrixstate.GetCurrencyFeeRates(st)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tx.Rate == 0 {
+		// 只能移除已设置费率的币种
+		if findCurrencyFeeRate(info, tx.Currency) == 0 {
+			return nil, nil, ErrCurrencyFeeRate
+		}
+	} else if tx.Rate < common.CurrencyFeeRateBase || tx.Rate > common.CurrencyFeeRateMax {
+		return nil, nil, ErrCurrencyFeeRate
+	}
+	return tx, info, nil
+}
+
+// applyCurrencyFeeRate sets the gas fee rate of the currency in the matrix
+// state. A zero rate removes the currency, whose transactions are then no
+// longer accepted.
+func applyCurrencyFeeRate(st matrixstate.StateDB, from common.Address, data []byte, height uint64) error {
+	tx, info, err := checkCurrencyFeeRate(st, from, data)
+	if err != nil {
+		return err
+	}
+	rates := make([]mc.CurrencyFeeRate, 0, len(info.Rates)+1)
+	for _, rate := range info.Rates {
+		if rate.Currency != tx.Currency {
+			rates = append(rates, rate)
+		}
+	}
+	if tx.Rate != 0 {
+		rates = append(rates, mc.CurrencyFeeRate{
+			Currency:  tx.Currency,
+			Rate:      tx.Rate,
+			Setter:    from,
+			SetNumber: height,
+		})
+	}
+	info.Rates = rates
+	return matrixstate.SetCurrencyFeeRates(st, info)
+}
+
+func findCurrencyFeeRate(info *mc.CurrencyFeeRateInfo, currency string) uint64 {
+	for _, rate := range info.Rates {
+		if rate.Currency == currency {
+			return rate.Rate
+		}
+	}
+	return 0
+}
+
+// CurrencyFeeRate returns the gas fee rate of the currency in the matrix state,
+// or 0 if the gas of the currency is paid in MAN.
+func CurrencyFeeRate(st matrixstate.StateDB, currency string) uint64 {
+	if currency == "" || currency == "MAN" || !manparams.IsBetaVersion(matrixstate.GetVersionInfo(st)) {
+		return 0
+	}
+	info, err := matrixstate.GetCurrencyFeeRates(st)
+	if err != nil {
+		return 0
+	}
+	return findCurrencyFeeRate(info, currency)
+}
+
+// CurrencyGasPrice converts the MAN gas price into the gas price of the
+// currency. The price is returned unchanged if the gas of the currency is paid
+// in MAN.
+func CurrencyGasPrice(st matrixstate.StateDB, currency string, price *big.Int) *big.Int {
+	rate := CurrencyFeeRate(st, currency)
+	if rate == 0 || price == nil {
+		return price
+	}
+	converted := new(big.Int).Mul(price, new(big.Int).SetUint64(rate))
+	return converted.Div(converted, big.NewInt(common.CurrencyFeeRateBase))
+}
+
+// knownCurrency reports whether transactions of the currency are accepted.
+// From VersionBeta on, a currency other than MAN needs a gas fee rate set by
+// the multi-coin super accounts, broadcast transactions carry no currency.
+// Before VersionBeta the transactions of any currency spend MAN and are kept
+// accepted.
+func knownCurrency(st matrixstate.StateDB, currency string) bool {
+	if currency == "" || currency == "MAN" || !manparams.IsBetaVersion(matrixstate.GetVersionInfo(st)) {
+		return true
+	}
+	return CurrencyFeeRate(st, currency) != 0
+}
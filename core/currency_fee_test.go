@@ -0,0 +1,262 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/reward"
+)
+
+var testMultiCoinSuper = common.HexToAddress("0x0100")
+
+// newCurrencyFeeState returns a Beta state with testMultiCoinSuper as the
+// multi-coin super account.
+func newCurrencyFeeState(t *testing.T) *state.StateDB {
+	statedb := newTestState()
+	matrixstate.SetVersionInfo(statedb, manparams.VersionBeta)
+	if err := matrixstate.SetMultiCoinSuperAccounts(statedb, []common.Address{testMultiCoinSuper}); err != nil {
+		t.Fatalf("failed to set multi-coin super accounts: %v", err)
+	}
+	return statedb
+}
+
+func setCurrencyFeeRate(t *testing.T, st matrixstate.StateDB, currency string, rate uint64) {
+	info, err := matrixstate.GetCurrencyFeeRates(st)
+	if err != nil {
+		t.Fatalf("failed to get currency fee rates: %v", err)
+	}
+	info.Rates = append(info.Rates, mc.CurrencyFeeRate{Currency: currency, Rate: rate})
+	if err := matrixstate.SetCurrencyFeeRates(st, info); err != nil {
+		t.Fatalf("failed to set currency fee rates: %v", err)
+	}
+}
+
+func currencyFeeRateData(currency string, rate uint64) []byte {
+	data, _ := json.Marshal(&common.CurrencyFeeRateTx{Currency: currency, Rate: rate})
+	return data
+}
+
+func TestCheckCurrencyFeeRate(t *testing.T) {
+	alpha := newTestState()
+	if _, _, err := checkCurrencyFeeRate(alpha, testMultiCoinSuper, currencyFeeRateData("BTC", common.CurrencyFeeRateBase)); err != ErrTXUnknownType {
+		t.Errorf("alpha state: error mismatch: have %v, want %v", err, ErrTXUnknownType)
+	}
+
+	statedb := newCurrencyFeeState(t)
+	tests := []struct {
+		from common.Address
+		data []byte
+		err  error
+	}{
+		{testMultiCoinSuper, []byte("{"), ErrCurrencyFeeRate},
+		{testMultiCoinSuper, currencyFeeRateData("MAN", common.CurrencyFeeRateBase), ErrCurrencyFeeRate},
+		{testMultiCoinSuper, currencyFeeRateData("btc", common.CurrencyFeeRateBase), ErrCurrencyFeeRate},
+		{common.HexToAddress("0x01"), currencyFeeRateData("BTC", common.CurrencyFeeRateBase), ErrCurrencyFeeRateSetter},
+		// 费率低于分母时币种gas比MAN便宜,过低时gas价格会被截断为0
+		{testMultiCoinSuper, currencyFeeRateData("BTC", common.CurrencyFeeRateBase-1), ErrCurrencyFeeRate},
+		{testMultiCoinSuper, currencyFeeRateData("BTC", 1), ErrCurrencyFeeRate},
+		{testMultiCoinSuper, currencyFeeRateData("BTC", common.CurrencyFeeRateMax+1), ErrCurrencyFeeRate},
+		// 未设置费率的币种不能移除
+		{testMultiCoinSuper, currencyFeeRateData("BTC", 0), ErrCurrencyFeeRate},
+		{testMultiCoinSuper, currencyFeeRateData("BTC", common.CurrencyFeeRateBase), nil},
+		{testMultiCoinSuper, currencyFeeRateData("BTC", common.CurrencyFeeRateMax), nil},
+	}
+	for i, test := range tests {
+		if _, _, err := checkCurrencyFeeRate(statedb, test.from, test.data); err != test.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.err)
+		}
+	}
+}
+
+func TestApplyCurrencyFeeRate(t *testing.T) {
+	statedb := newCurrencyFeeState(t)
+
+	apply := func(currency string, rate uint64, height uint64) {
+		if err := applyCurrencyFeeRate(statedb, testMultiCoinSuper, currencyFeeRateData(currency, rate), height); err != nil {
+			t.Fatalf("failed to apply %s rate %d: %v", currency, rate, err)
+		}
+	}
+	apply("BTC", 2*common.CurrencyFeeRateBase, 10)
+	apply("ETH", 3*common.CurrencyFeeRateBase, 11)
+	apply("BTC", 4*common.CurrencyFeeRateBase, 12)
+	if rate := CurrencyFeeRate(statedb, "BTC"); rate != 4*common.CurrencyFeeRateBase {
+		t.Errorf("BTC rate mismatch: have %d, want %d", rate, 4*common.CurrencyFeeRateBase)
+	}
+	info, _ := matrixstate.GetCurrencyFeeRates(statedb)
+	if len(info.Rates) != 2 || info.Rates[1].Currency != "BTC" || info.Rates[1].SetNumber != 12 || info.Rates[1].Setter != testMultiCoinSuper {
+		t.Fatalf("stored rates mismatch: %+v", info.Rates)
+	}
+
+	apply("BTC", 0, 13)
+	if rate := CurrencyFeeRate(statedb, "BTC"); rate != 0 {
+		t.Errorf("removed BTC rate mismatch: have %d, want 0", rate)
+	}
+	if knownCurrency(statedb, "BTC") {
+		t.Errorf("removed currency still known")
+	}
+	if !knownCurrency(statedb, "ETH") {
+		t.Errorf("currency with a fee rate unknown")
+	}
+}
+
+func TestCurrencyGasPrice(t *testing.T) {
+	price := big.NewInt(18e9)
+
+	// Beta版本之前不按费率换算
+	alpha := newCurrencyFeeState(t)
+	setCurrencyFeeRate(t, alpha, "BTC", 2*common.CurrencyFeeRateBase)
+	matrixstate.SetVersionInfo(alpha, manparams.VersionAlpha)
+	if have := CurrencyGasPrice(alpha, "BTC", price); have.Cmp(price) != 0 {
+		t.Errorf("alpha state: price mismatch: have %v, want %v", have, price)
+	}
+
+	statedb := newCurrencyFeeState(t)
+	setCurrencyFeeRate(t, statedb, "BTC", 25*common.CurrencyFeeRateBase/10)
+	tests := []struct {
+		currency string
+		want     *big.Int
+	}{
+		{"MAN", price},
+		{"", price},
+		{"ETH", price},
+		{"BTC", big.NewInt(45e9)},
+	}
+	for _, test := range tests {
+		if have := CurrencyGasPrice(statedb, test.currency, price); have.Cmp(test.want) != 0 {
+			t.Errorf("%q: price mismatch: have %v, want %v", test.currency, have, test.want)
+		}
+	}
+}
+
+func TestGetCurrencyGas(t *testing.T) {
+	statedb := newCurrencyFeeState(t)
+	setCurrencyFeeRate(t, statedb, "AAA", 2*common.CurrencyFeeRateBase)
+	setCurrencyFeeRate(t, statedb, "BBB", common.CurrencyFeeRateBase)
+	gasprice, err := matrixstate.GetTxpoolGasLimit(statedb)
+	if err != nil {
+		t.Fatalf("failed to get gas price: %v", err)
+	}
+	fee := func(gas int64) *big.Int {
+		return new(big.Int).Mul(big.NewInt(gas), gasprice)
+	}
+
+	// 余额只够MAN和BBB: AAA的排序在前,但MAN先发放,AAA余额不足不发放
+	statedb.AddBalance(common.MainAccount, common.TxGasRewardAddress, fee(300))
+	p := &StateProcessor{}
+	allGas := p.getCurrencyGas(statedb, map[string]*big.Int{
+		"MAN": big.NewInt(200),
+		"AAA": big.NewInt(100),
+		"BBB": big.NewInt(100),
+		"":    big.NewInt(100),
+	})
+	want := map[string]*big.Int{"MAN": fee(200), "BBB": fee(100)}
+	if len(allGas) != len(want) {
+		t.Fatalf("fees mismatch: have %v, want %v", allGas, want)
+	}
+	for coin, amount := range want {
+		if allGas[coin] == nil || allGas[coin].Cmp(amount) != 0 {
+			t.Errorf("%s fee mismatch: have %v, want %v", coin, allGas[coin], amount)
+		}
+	}
+}
+
+// currencyReward splits a tx fee into a validator half and a miner half. The
+// miner half is paid to the miner of the parent block one block later.
+type currencyReward struct {
+	reward.Reward
+	validator, miner common.Address
+}
+
+func (r *currencyReward) CalcCurrencyNodesRewards(fee *big.Int, preMinerReward *big.Int, Leader common.Address, num uint64, parentHash common.Hash) (map[common.Address]*big.Int, *big.Int) {
+	rewards := make(map[common.Address]*big.Int)
+	half := new(big.Int).Div(fee, big.NewInt(2))
+	if half.Sign() > 0 {
+		rewards[r.validator] = half
+	}
+	if preMinerReward.Sign() > 0 {
+		rewards[r.miner] = preMinerReward
+	}
+	return rewards, half
+}
+
+func TestProcessCurrencyTxsRewards(t *testing.T) {
+	statedb := newCurrencyFeeState(t)
+	validator, miner := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	txsReward := &currencyReward{validator: validator, miner: miner}
+	p := &StateProcessor{}
+
+	// 第一个区块: 矿工奖励推迟到下一区块发放
+	header := &types.Header{Number: big.NewInt(10)}
+	list := p.processCurrencyTxsRewards(statedb, txsReward, header, map[string]*big.Int{
+		"MAN": big.NewInt(1000),
+		"BBB": big.NewInt(200),
+		"AAA": big.NewInt(100),
+	})
+	if len(list) != 2 || list[0].CoinType != "AAA" || list[1].CoinType != "BBB" {
+		t.Fatalf("reward list mismatch: %+v", list)
+	}
+	for _, tx := range list {
+		if tx.Fromaddr != common.TxGasRewardAddress || tx.RewardTyp != common.RewardTxsType || tx.To_Amont[miner] != nil {
+			t.Errorf("%s reward mismatch: %+v", tx.CoinType, tx)
+		}
+	}
+	if list[1].To_Amont[validator].Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("BBB validator reward mismatch: have %v, want 100", list[1].To_Amont[validator])
+	}
+
+	// 第二个区块: 只有BBB的交易, AAA上一矿工的奖励仍然发放
+	header = &types.Header{Number: big.NewInt(11)}
+	list = p.processCurrencyTxsRewards(statedb, txsReward, header, map[string]*big.Int{"BBB": big.NewInt(400)})
+	if len(list) != 2 || list[0].CoinType != "AAA" || list[1].CoinType != "BBB" {
+		t.Fatalf("reward list mismatch: %+v", list)
+	}
+	if have := list[0].To_Amont[miner]; have == nil || have.Cmp(big.NewInt(50)) != 0 {
+		t.Errorf("AAA miner reward mismatch: have %v, want 50", have)
+	}
+	if have := list[1].To_Amont[miner]; have == nil || have.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("BBB miner reward mismatch: have %v, want 100", have)
+	}
+	pre, _ := matrixstate.GetPreMinerMultiCoinTxsReward(statedb)
+	if len(pre.Rewards) != 1 || pre.Rewards[0].CoinType != "BBB" || pre.Rewards[0].Reward.Cmp(big.NewInt(200)) != 0 {
+		t.Errorf("deferred miner rewards mismatch: %+v", pre.Rewards)
+	}
+	// MAN的矿工奖励不在其他币种中记录
+	manPre, _ := matrixstate.GetPreMinerTxsReward(statedb)
+	if manPre.Reward.Sign() != 0 {
+		t.Errorf("MAN deferred miner reward changed: %v", &manPre.Reward)
+	}
+}
+
+func TestCurrencyStateTransitionGasPrice(t *testing.T) {
+	statedb := newCurrencyFeeState(t)
+	setCurrencyFeeRate(t, statedb, "BTC", 3*common.CurrencyFeeRateBase)
+	gasprice, _ := matrixstate.GetTxpoolGasLimit(statedb)
+	evm := vm.NewEVM(vm.Context{BlockNumber: big.NewInt(1)}, statedb, params.TestChainConfig, vm.Config{})
+
+	tests := []struct {
+		currency string
+		want     *big.Int
+	}{
+		{"MAN", gasprice},
+		{"BTC", new(big.Int).Mul(gasprice, big.NewInt(3))},
+	}
+	for _, test := range tests {
+		tx := types.NewTransaction(params.NonceAddOne, common.HexToAddress("0x01"), big.NewInt(1), 21000, testGasPrice, nil, nil, nil, nil, 0, 0, test.currency, 0)
+		if have := NewStateTransition(evm, tx, new(GasPool)).gasPrice; have.Cmp(test.want) != 0 {
+			t.Errorf("%s: charged gas price mismatch: have %v, want %v", test.currency, have, test.want)
+		}
+	}
+}
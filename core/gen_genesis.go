@@ -6,6 +6,7 @@
 package core
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"math/big"
@@ -41,10 +42,18 @@ func (a GenesisAddress) MarshalText() ([]byte, error) {
 	return []byte(buff), nil
 }
 
-// UnmarshalText parses a hash in hex syntax.
+// UnmarshalText parses a MAN address as written by MarshalText, e.g. for the
+// alloc keys, or an address in hex syntax.
 func (a *GenesisAddress) UnmarshalText(input []byte) error {
-	err := hexutil.UnmarshalFixedText("GenesisAddress", input, a[:])
-	return err
+	if bytes.HasPrefix(input, []byte("0x")) {
+		return hexutil.UnmarshalFixedText("GenesisAddress", input, a[:])
+	}
+	addr, err := base58.Base58DecodeToAddress(string(input))
+	if err != nil {
+		return err
+	}
+	*a = GenesisAddress(addr)
+	return nil
 }
 
 func (g Genesis) MarshalJSON() ([]byte, error) {
@@ -442,6 +442,33 @@ func (g *Genesis) ToSuperBlock() *types.Block {
 	return types.NewBlock(head, txs, nil, nil)
 }
 
+// SuperBlockSignHash returns the hash the super block accounts sign for the super
+// block described by g. The state root is not recomputed, g.Root must hold the
+// one of GenSuperBlock, which makes the hash available without the chain data.
+func (g *Genesis) SuperBlockSignHash() (common.Hash, error) {
+	if (g.Root == common.Hash{}) {
+		return common.Hash{}, errors.New("super block without state root")
+	}
+	gen := *g // ToSuperBlock会清空alloc
+	block := gen.ToSuperBlock()
+	if block == nil {
+		return common.Hash{}, errors.New("create super block failed")
+	}
+	if (g.TxHash != common.Hash{}) && block.TxHash() != g.TxHash {
+		return common.Hash{}, fmt.Errorf("super block transactions root mismatch: have %s, want %s", block.TxHash().Hex(), g.TxHash.Hex())
+	}
+	// 与GenSuperBlock保持一致
+	head := block.Header()
+	head.Root = g.Root
+	if g.GasLimit == 0 {
+		head.GasLimit = params.GenesisGasLimit
+	}
+	if g.Difficulty == nil {
+		head.Difficulty = params.GenesisDifficulty
+	}
+	return head.HashNoSigns(), nil
+}
+
 // Commit writes the block and state of a genesis specification to the database.
 // The block is committed as the canonical head block.
 func (g *Genesis) Commit(db mandb.Database) (*types.Block, error) {
@@ -519,35 +546,6 @@ func DefaultRinkebyGenesisBlock() *Genesis {
 	}
 }
 
-// DeveloperGenesisBlock returns the 'gman --dev' genesis block. Note, this must
-// be seeded with the
-func DeveloperGenesisBlock(period uint64, faucet common.Address) *Genesis {
-	// Override the default period to the user requested one
-	config := *params.AllCliqueProtocolChanges
-	//config.Clique.Period = period
-	//todo 把clique设为空，默认启动ethash引擎挖矿
-	config.Clique = nil
-
-	// Assemble and return the genesis with the precompiles and faucet pre-funded
-	return &Genesis{
-		Config:     &config,
-		ExtraData:  append(append(make([]byte, 32), faucet[:]...), make([]byte, 65)...),
-		GasLimit:   6283185,
-		Difficulty: big.NewInt(1000000),
-		Alloc: map[common.Address]GenesisAccount{
-			common.BytesToAddress([]byte{1}): {Balance: big.NewInt(1)}, // ECRecover
-			common.BytesToAddress([]byte{2}): {Balance: big.NewInt(1)}, // SHA256
-			common.BytesToAddress([]byte{3}): {Balance: big.NewInt(1)}, // RIPEMD
-			common.BytesToAddress([]byte{4}): {Balance: big.NewInt(1)}, // Identity
-			common.BytesToAddress([]byte{5}): {Balance: big.NewInt(1)}, // ModExp
-			common.BytesToAddress([]byte{6}): {Balance: big.NewInt(1)}, // ECAdd
-			common.BytesToAddress([]byte{7}): {Balance: big.NewInt(1)}, // ECScalarMul
-			common.BytesToAddress([]byte{8}): {Balance: big.NewInt(1)}, // ECPairing
-			faucet: {Balance: new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(9))},
-		},
-	}
-}
-
 func decodePrealloc(data string) GenesisAlloc {
 	var p []struct{ Addr, Balance *big.Int }
 	if err := rlp.NewStream(strings.NewReader(data), 0).Decode(&p); err != nil {
@@ -0,0 +1,144 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"encoding/binary"
+	"math/big"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+)
+
+var (
+	devValidatorDeposit = new(big.Int).Mul(big.NewInt(100000), big.NewInt(1e18)) // 验证者抵押, 等于验证者抵押门限
+	devMinerDeposit     = new(big.Int).Mul(big.NewInt(10000), big.NewInt(1e18))  // 矿工抵押, 等于矿工抵押门限
+	devNodeBalance      = new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))   // 节点账户的初始余额, 用于支付手续费
+	devBalance          = new(big.Int).Mul(big.NewInt(1e12), big.NewInt(1e18))   // 水龙头账户的初始余额
+	devGasLimit         = uint64(0x2FEFD800)
+)
+
+// genesisDeposit is a deposit already made in the genesis state.
+type genesisDeposit struct {
+	Address common.Address
+	Amount  *big.Int
+	Role    int64
+}
+
+// DeveloperGenesisBlock returns the 'gman --dev' genesis block. The faucet is
+// the only validator, broadcast node and inner miner of the chain and holds the
+// validator deposit, so that a single node runs the whole MATRIX consensus. The
+// chain config marks the chain as a developer chain and carries the block
+// period, which the node waits out before starting the next block. Being built
+// on DevnetGenesisBlock, the chain runs manparams.VersionBeta from the genesis.
+func DeveloperGenesisBlock(period uint64, faucet common.Address) *Genesis {
+	accounts := []common.Address{faucet}
+	genesis := DevnetGenesisBlock(1337, accounts, nil, accounts, faucet)
+	innerMiners := []GenesisAddress{GenesisAddress(faucet)}
+	genesis.MState.InnerMiners = &innerMiners
+	genesis.Config.Dev = &params.DevConfig{Period: period}
+	return genesis
+}
+
+// DevnetGenesisBlock returns the genesis block of a private network made of
+// the given validators, miners and broadcast nodes. All of them are elected
+// and hold their deposits from the genesis on; the faucet is pre-funded and
+// acts as foundation and every super account.
+func DevnetGenesisBlock(chainID uint64, validators, miners, broadcasts []common.Address, faucet common.Address) *Genesis {
+	genesis, err := DefaultGenesis("")
+	if err != nil {
+		panic(err)
+	}
+	config := *genesis.Config
+	config.ChainId = new(big.Int).SetUint64(chainID)
+	config.SimpleMode = len(validators) < 3 // 验证者不足时POS使用简单模式
+	genesis.Config = &config
+	// leader的共识轮次按父区块时间推算, 创世时间须为当前时间
+	genesis.Timestamp = uint64(time.Now().Unix())
+	genesis.ExtraData = nil
+	// 私有网络从创世区块起运行最新版本
+	genesis.Version = manparams.VersionBeta
+	genesis.GasLimit = devGasLimit
+	genesis.Difficulty = new(big.Int).Set(params.MinimumDifficulty)
+	genesis.Leader = validators[0]
+	genesis.Coinbase = faucet
+	genesis.NextElect = nil
+
+	var (
+		topology = make([]common.NetTopologyData, 0, len(validators)+len(miners))
+		elect    = make([]GenesisElect, 0, len(validators)+len(miners))
+		deposits = make([]genesisDeposit, 0, len(validators)+len(miners))
+	)
+	for i, addr := range validators {
+		topology = append(topology, common.NetTopologyData{Account: addr, Position: common.GeneratePosition(uint16(i), common.ElectRoleValidator)})
+		elect = append(elect, GenesisElect{Account: GenesisAddress(addr), Stock: 1, Type: common.ElectRoleValidator})
+		deposits = append(deposits, genesisDeposit{Address: addr, Amount: devValidatorDeposit, Role: common.RoleValidator})
+	}
+	for i, addr := range miners {
+		topology = append(topology, common.NetTopologyData{Account: addr, Position: common.GeneratePosition(uint16(i), common.ElectRoleMiner)})
+		elect = append(elect, GenesisElect{Account: GenesisAddress(addr), Stock: 1, Type: common.ElectRoleMiner})
+		deposits = append(deposits, genesisDeposit{Address: addr, Amount: devMinerDeposit, Role: common.RoleMiner})
+	}
+	genesis.NetTopology = common.NetTopology{Type: common.NetTopoTypeAll, NetTopologyData: topology}
+
+	superAccounts := []GenesisAddress{GenesisAddress(faucet)}
+	foundation := GenesisAddress(faucet)
+	bcAccounts := make([]GenesisAddress, 0, len(broadcasts))
+	for _, addr := range broadcasts {
+		bcAccounts = append(bcAccounts, GenesisAddress(addr))
+	}
+	innerMiners := make([]GenesisAddress, 0)
+	ms := genesis.MState
+	ms.Broadcasts = &bcAccounts
+	ms.InnerMiners = &innerMiners
+	ms.Foundation = &foundation
+	ms.VersionSuperAccounts = &superAccounts
+	ms.BlockSuperAccounts = &superAccounts
+	ms.MultiCoinSuperAccounts = &superAccounts
+	ms.SubChainSuperAccounts = &superAccounts
+	ms.EleInfoCfg = &mc.ElectConfigInfo{ValidatorNum: uint16(len(validators)), BackValidator: 0, ElectPlug: ms.EleInfoCfg.ElectPlug}
+	ms.ElectMinerNumCfg = &mc.ElectMinerNumStruct{MinerNum: uint16(len(miners))}
+	ms.CurElect = &elect
+
+	total := new(big.Int)
+	for _, deposit := range deposits {
+		total.Add(total, deposit.Amount)
+	}
+	genesis.Alloc = GenesisAlloc{
+		faucet:                 {Balance: new(big.Int).Set(devBalance)},
+		common.ContractAddress: {Balance: total, Storage: depositStorage(deposits)},
+	}
+	for _, nodes := range [][]common.Address{validators, miners, broadcasts} {
+		for _, addr := range nodes {
+			if _, ok := genesis.Alloc[addr]; !ok {
+				genesis.Alloc[addr] = GenesisAccount{Balance: new(big.Int).Set(devNodeBalance)}
+			}
+		}
+	}
+	return genesis
+}
+
+// depositStorage lays out the deposit contract storage of the given deposits,
+// each made with the depositor itself as sign account.
+func depositStorage(deposits []genesisDeposit) map[common.Hash]common.Hash {
+	contract := common.ContractAddress
+	storage := map[common.Hash]common.Hash{
+		common.BytesToHash(append(contract[:], 'D', 'N', 'U', 'M')): common.BigToHash(big.NewInt(int64(len(deposits)))),
+	}
+	for i, deposit := range deposits {
+		addr := deposit.Address
+		index := make([]byte, 8)
+		binary.BigEndian.PutUint64(index, uint64(i))
+		storage[common.BytesToHash(append(append(contract[:], 'D', 'I'), index...))] = common.BytesToHash(addr[:])
+		storage[common.BytesToHash(append(addr[:], 'D'))] = common.BigToHash(deposit.Amount)
+		storage[common.BytesToHash(append(addr[:], 'N', 'X'))] = addr.Hash()
+		storage[common.BytesToHash(append(addr[:], 'N', 'Y'))] = addr.Hash()
+		storage[common.BytesToHash(append(addr[:], 'R'))] = common.BigToHash(big.NewInt(deposit.Role))
+	}
+	return storage
+}
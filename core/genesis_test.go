@@ -5,25 +5,31 @@
 package core
 
 import (
+	"encoding/json"
 	"math/big"
 	"reflect"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/MatrixAINetwork/go-matrix/base58"
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/consensus/manash"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/vm"
 	"github.com/MatrixAINetwork/go-matrix/mandb"
 	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
 )
 
 func TestDefaultGenesisBlock(t *testing.T) {
-	block := DefaultGenesisBlock().ToBlock(nil)
+	t.Skip("the ethereum genesis specs carry no matrix state")
+	block, _ := DefaultGenesisBlock().ToBlock(nil)
 	if block.Hash() != params.MainnetGenesisHash {
 		t.Errorf("wrong mainnet genesis hash, got %v, want %v", block.Hash(), params.MainnetGenesisHash)
 	}
-	block = DefaultTestnetGenesisBlock().ToBlock(nil)
+	block, _ = DefaultTestnetGenesisBlock().ToBlock(nil)
 	if block.Hash() != params.TestnetGenesisHash {
 		t.Errorf("wrong testnet genesis hash, got %v, want %v", block.Hash(), params.TestnetGenesisHash)
 	}
@@ -31,16 +37,18 @@ func TestDefaultGenesisBlock(t *testing.T) {
 
 func TestSetupGenesis(t *testing.T) {
 	var (
-		customghash = common.HexToHash("0x89c99d90b79719238d2645c7642f2c9295246e80775b38cfd162b696817fbd50")
-		customg     = Genesis{
-			Config: &params.ChainConfig{HomesteadBlock: big.NewInt(3)},
-			Alloc: GenesisAlloc{
-				{1}: {Balance: big.NewInt(1), Storage: map[common.Hash]common.Hash{{1}: {1}}},
-			},
-		}
-		oldcustomg = customg
+		customg = testGenesis(&params.ChainConfig{HomesteadBlock: big.NewInt(3)}, GenesisAlloc{
+			{1}: {Balance: big.NewInt(1), Storage: map[common.Hash]common.Hash{{1}: {1}}},
+		})
+		oldcustomg   = *customg
+		othercustomg = *customg
 	)
 	oldcustomg.Config = &params.ChainConfig{HomesteadBlock: big.NewInt(2)}
+	othercustomg.ExtraData = []byte{1}
+	customblock, _ := customg.ToBlock(nil)
+	otherblock, _ := othercustomg.ToBlock(nil)
+	customghash, otherghash := customblock.Hash(), otherblock.Hash()
+
 	tests := []struct {
 		name       string
 		fn         func(mandb.Database) (*params.ChainConfig, common.Hash, error)
@@ -61,17 +69,7 @@ func TestSetupGenesis(t *testing.T) {
 			fn: func(db mandb.Database) (*params.ChainConfig, common.Hash, error) {
 				return SetupGenesisBlock(db, nil)
 			},
-			wantHash:   params.MainnetGenesisHash,
-			wantConfig: params.MainnetChainConfig,
-		},
-		{
-			name: "mainnet block in DB, genesis == nil",
-			fn: func(db mandb.Database) (*params.ChainConfig, common.Hash, error) {
-				DefaultGenesisBlock().MustCommit(db)
-				return SetupGenesisBlock(db, nil)
-			},
-			wantHash:   params.MainnetGenesisHash,
-			wantConfig: params.MainnetChainConfig,
+			wantErr: errGenGenesisBlockNoConfig,
 		},
 		{
 			name: "custom block in DB, genesis == nil",
@@ -83,20 +81,20 @@ func TestSetupGenesis(t *testing.T) {
 			wantConfig: customg.Config,
 		},
 		{
-			name: "custom block in DB, genesis == testnet",
+			name: "custom block in DB, genesis == other",
 			fn: func(db mandb.Database) (*params.ChainConfig, common.Hash, error) {
 				customg.MustCommit(db)
-				return SetupGenesisBlock(db, DefaultTestnetGenesisBlock())
+				return SetupGenesisBlock(db, &othercustomg)
 			},
-			wantErr:    &GenesisMismatchError{Stored: customghash, New: params.TestnetGenesisHash},
-			wantHash:   params.TestnetGenesisHash,
-			wantConfig: params.TestnetChainConfig,
+			wantErr:    &GenesisMismatchError{Stored: customghash, New: otherghash},
+			wantHash:   otherghash,
+			wantConfig: othercustomg.Config,
 		},
 		{
 			name: "compatible config in DB",
 			fn: func(db mandb.Database) (*params.ChainConfig, common.Hash, error) {
 				oldcustomg.MustCommit(db)
-				return SetupGenesisBlock(db, &customg)
+				return SetupGenesisBlock(db, customg)
 			},
 			wantHash:   customghash,
 			wantConfig: customg.Config,
@@ -115,7 +113,7 @@ func TestSetupGenesis(t *testing.T) {
 				bc.InsertChain(blocks)
 				bc.CurrentBlock()
 				// This should return a compatibility error.
-				return SetupGenesisBlock(db, &customg)
+				return SetupGenesisBlock(db, customg)
 			},
 			wantHash:   customghash,
 			wantConfig: customg.Config,
@@ -150,3 +148,163 @@ func TestSetupGenesis(t *testing.T) {
 		}
 	}
 }
+
+func TestDeveloperGenesisBlock(t *testing.T) {
+	faucet := common.HexToAddress("0x1234")
+	genesis := DeveloperGenesisBlock(5, faucet)
+	if genesis.Config.Dev == nil || genesis.Config.Dev.Period != 5 {
+		t.Fatalf("developer config mismatch: have %v", genesis.Config.Dev)
+	}
+	// 开发链配置不能修改默认创世配置
+	if def, _ := DefaultGenesis(""); def.Config.Dev != nil {
+		t.Fatalf("default genesis config changed: %v", def.Config.Dev)
+	}
+	if account, ok := genesis.Alloc[faucet]; !ok || account.Balance.Sign() <= 0 {
+		t.Errorf("faucet not funded: %v", account)
+	}
+
+	// 开发链配置随创世区块保存, 重启后沿用
+	db := mandb.NewMemDatabase()
+	config, hash, err := SetupGenesisBlock(db, genesis)
+	if err != nil {
+		t.Fatalf("failed to set up genesis: %v", err)
+	}
+	if config.Dev == nil || config.Dev.Period != 5 {
+		t.Errorf("set up config mismatch: have %v", config.Dev)
+	}
+	if stored := rawdb.ReadChainConfig(db, hash); stored == nil || stored.Dev == nil || stored.Dev.Period != 5 {
+		t.Errorf("stored config mismatch: have %v", stored)
+	}
+	if config, _, err = SetupGenesisBlock(db, nil); err != nil || config.Dev == nil || config.Dev.Period != 5 {
+		t.Errorf("continued config mismatch: have %v, err %v", config, err)
+	}
+}
+
+func TestDevnetGenesisBlock(t *testing.T) {
+	validators := []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02"), common.HexToAddress("0x03")}
+	genesis := DevnetGenesisBlock(1337, validators, nil, validators[:1], validators[0])
+	if genesis.Version != manparams.VersionBeta {
+		t.Fatalf("devnet genesis version mismatch: have %s, want %s", genesis.Version, manparams.VersionBeta)
+	}
+	// 私有网络的matrix状态从创世区块起即为最新版本
+	db := mandb.NewMemDatabase()
+	block := genesis.MustCommit(db)
+	if version := string(block.Version()); version != manparams.VersionBeta {
+		t.Errorf("block version mismatch: have %s, want %s", version, manparams.VersionBeta)
+	}
+	st, err := state.New(block.Root(), state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open genesis state: %v", err)
+	}
+	if version := matrixstate.GetVersionInfo(st); version != manparams.VersionBeta {
+		t.Errorf("state version mismatch: have %s, want %s", version, manparams.VersionBeta)
+	}
+}
+
+func TestGenesisAddressText(t *testing.T) {
+	addr := common.HexToAddress("0x8111111111111111111111111111111111111111")
+	text, err := GenesisAddress(addr).MarshalText()
+	if err != nil {
+		t.Fatalf("failed to marshal address: %v", err)
+	}
+	if want := base58.Base58EncodeToString("MAN", addr); string(text) != want {
+		t.Fatalf("text mismatch: have %s, want %s", text, want)
+	}
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{string(text), true},
+		{addr.Hex(), true}, // 兼容十六进制地址
+		{"0x81111111", false},
+		{addr.Hex() + "11", false},
+		{"0xzz11111111111111111111111111111111111111", false},
+		{"", false},
+		{"MAN.", false},
+		{"notanaddress", false},
+		{string(text[:len(text)-1]), false}, // 校验位错误
+	}
+	for _, test := range tests {
+		var have GenesisAddress
+		err := have.UnmarshalText([]byte(test.input))
+		if test.ok && (err != nil || common.Address(have) != addr) {
+			t.Errorf("input %q: have %x, err %v, want %x", test.input, have, err, addr)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("input %q: accepted as %x", test.input, have)
+		}
+	}
+
+	// 作为map的key时通过文本编码
+	alloc := map[GenesisAddress]uint64{GenesisAddress(addr): 1}
+	out, err := json.Marshal(alloc)
+	if err != nil {
+		t.Fatalf("failed to marshal map: %v", err)
+	}
+	if want := `{"` + string(text) + `":1}`; string(out) != want {
+		t.Errorf("map json mismatch: have %s, want %s", out, want)
+	}
+	decoded := make(map[GenesisAddress]uint64)
+	if err := json.Unmarshal(out, &decoded); err != nil || decoded[GenesisAddress(addr)] != 1 {
+		t.Errorf("map decode mismatch: have %v, err %v", decoded, err)
+	}
+}
+
+func TestSuperBlockSignHash(t *testing.T) {
+	db := mandb.NewMemDatabase()
+	parent := DeveloperGenesisBlock(5, common.HexToAddress("0x1234"))
+	parentBlock := parent.MustCommit(db)
+
+	genesis := &Genesis{
+		Config:      parent.Config,
+		Number:      parentBlock.NumberU64() + 1,
+		ParentHash:  parentBlock.Hash(),
+		Timestamp:   parent.Timestamp + 1,
+		Version:     parent.Version,
+		MState:      new(GenesisMState),
+		NetTopology: parent.NetTopology,
+		NextElect:   parent.NextElect,
+		ExtraData:   []byte{0, 0, 0, 0, 0, 0, 0, 1}, // 超级区块序号
+	}
+	if _, err := genesis.SuperBlockSignHash(); err == nil {
+		t.Fatalf("hash of super block without state root succeeded")
+	}
+	superBlock := genesis.GenSuperBlock(parentBlock.Header(), state.NewDatabase(db), parent.Config)
+	if superBlock == nil {
+		t.Fatalf("failed to generate super block")
+	}
+
+	// 与GenSuperBlock生成的区块哈希一致, 不需要链数据
+	genesis.Root = superBlock.Root()
+	genesis.TxHash = superBlock.TxHash()
+	hash, err := genesis.SuperBlockSignHash()
+	if err != nil {
+		t.Fatalf("failed to hash super block: %v", err)
+	}
+	if want := superBlock.Header().HashNoSigns(); hash != want {
+		t.Fatalf("hash mismatch: have %s, want %s", hash.Hex(), want.Hex())
+	}
+
+	// 签名不影响待签名哈希, 计算哈希不修改alloc
+	signed := *genesis
+	signed.Signatures = []common.Signature{{0x01}}
+	signed.Alloc = GenesisAlloc{common.HexToAddress("0x1234"): {Balance: big.NewInt(1)}}
+	if have, err := signed.SuperBlockSignHash(); err != nil || have != hash {
+		t.Errorf("signed hash mismatch: have %s, want %s, err %v", have.Hex(), hash.Hex(), err)
+	}
+	if len(signed.Alloc) != 1 {
+		t.Errorf("alloc cleared by hashing: %v", signed.Alloc)
+	}
+	// 区块内容变化时哈希随之变化
+	changed := *genesis
+	changed.Timestamp++
+	if have, err := changed.SuperBlockSignHash(); err != nil || have == hash {
+		t.Errorf("hash unchanged after timestamp change: %s, err %v", have.Hex(), err)
+	}
+	// 交易根不一致
+	changed = *genesis
+	changed.TxHash = common.Hash{0x01}
+	if _, err := changed.SuperBlockSignHash(); err == nil {
+		t.Errorf("transactions root mismatch accepted")
+	}
+}
@@ -15,6 +15,7 @@ var versionOpt MatrixOperator
 
 func init() {
 	mangerAlpha = newManger(manparams.VersionAlpha)
+	mangerBeta = newManger(manparams.VersionBeta)
 	versionOpt = newVersionInfoOpt()
 }
 
@@ -33,6 +34,8 @@ func GetManager(version string) *Manager {
 	switch version {
 	case manparams.VersionAlpha:
 		return mangerAlpha
+	case manparams.VersionBeta:
+		return mangerBeta
 	default:
 		log.Error(logInfo, "get Manger err", "version not exist", "version", version)
 		return nil
@@ -112,6 +115,15 @@ func newManger(version string) *Manager {
 				mc.MSKeyBlockProduceBlackList:   newBlockProduceBlackListOpt(),
 			},
 		}
+	case manparams.VersionBeta:
+		// Beta版本在Alpha的基础上新增状态
+		mgr := newManger(manparams.VersionAlpha)
+		mgr.version = version
+		mgr.operators[mc.MSKeyRandomWithhold] = newRandomWithholdOpt()
+		mgr.operators[mc.MSKeyVersionProposals] = newVersionProposalsOpt()
+		mgr.operators[mc.MSKeyCurrencyFeeRates] = newCurrencyFeeRatesOpt()
+		mgr.operators[mc.MSKeyPreMinerMultiCoinTxsReward] = newPreMinerMultiCoinTxsRewardOpt()
+		return mgr
 	default:
 		log.Error(logInfo, "创建管理类", "失败", "版本", version)
 		return nil
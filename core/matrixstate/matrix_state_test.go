@@ -4,6 +4,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
 	"testing"
 )
 
@@ -61,3 +62,32 @@ func Test_GetUpTime(t *testing.T) {
 
 	t.Log(num)
 }
+
+func Test_BetaManager(t *testing.T) {
+	for key := range mangerAlpha.operators {
+		if _, err := mangerBeta.FindOperator(key); err != nil {
+			t.Errorf("beta manager misses alpha key %s", key)
+		}
+	}
+	if _, err := mangerAlpha.FindOperator(mc.MSKeyRandomWithhold); err == nil {
+		t.Errorf("alpha manager has the random withhold state")
+	}
+	if mangerBeta.Version() != manparams.VersionBeta {
+		t.Errorf("beta manager version mismatch: %s", mangerBeta.Version())
+	}
+
+	st := newTestState()
+	info := &mc.RandomWithholdInfo{Number: 100, Records: []mc.RandomWithholdRecord{{Address: common.HexToAddress("0x12345"), Count: 1, Total: 1, LastNumber: 100}}}
+	SetVersionInfo(st, manparams.VersionAlpha)
+	if err := SetRandomWithhold(st, info); err == nil {
+		t.Fatalf("random withhold stored in an alpha state")
+	}
+	SetVersionInfo(st, manparams.VersionBeta)
+	if err := SetRandomWithhold(st, info); err != nil {
+		t.Fatalf("failed to store random withhold: %v", err)
+	}
+	stored, err := GetRandomWithhold(st)
+	if err != nil || stored.Number != 100 || len(stored.Records) != 1 || stored.Records[0] != info.Records[0] {
+		t.Fatalf("random withhold mismatch: have %+v (%v), want %+v", stored, err, info)
+	}
+}
@@ -474,6 +474,153 @@ func (opt *operatorMinHash) SetValue(st StateDB, value interface{}) error {
 	return nil
 }
 
+/////////////////////////////////////////////////////////////////////////////////////////
+// 随机数未公开私钥统计
+type operatorRandomWithhold struct {
+	key common.Hash
+}
+
+func newRandomWithholdOpt() *operatorRandomWithhold {
+	return &operatorRandomWithhold{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyRandomWithhold),
+	}
+}
+
+func (opt *operatorRandomWithhold) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorRandomWithhold) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	value := new(mc.RandomWithholdInfo)
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return value, nil
+	}
+
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "randomWithhold rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorRandomWithhold) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "randomWithhold rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 版本升级提案
+type operatorVersionProposals struct {
+	key common.Hash
+}
+
+func newVersionProposalsOpt() *operatorVersionProposals {
+	return &operatorVersionProposals{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyVersionProposals),
+	}
+}
+
+func (opt *operatorVersionProposals) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorVersionProposals) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	value := new(mc.VersionProposalInfo)
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return value, nil
+	}
+
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "versionProposals rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorVersionProposals) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "versionProposals rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////
+// 币种gas费率
+type operatorCurrencyFeeRates struct {
+	key common.Hash
+}
+
+func newCurrencyFeeRatesOpt() *operatorCurrencyFeeRates {
+	return &operatorCurrencyFeeRates{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyCurrencyFeeRates),
+	}
+}
+
+func (opt *operatorCurrencyFeeRates) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorCurrencyFeeRates) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	value := new(mc.CurrencyFeeRateInfo)
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return value, nil
+	}
+
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "currencyFeeRates rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorCurrencyFeeRates) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "currencyFeeRates rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
+
 /////////////////////////////////////////////////////////////////////////////////////////
 // 超级区块配置
 type operatorSuperBlockCfg struct {
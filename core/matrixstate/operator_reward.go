@@ -362,6 +362,55 @@ func (opt *operatorPreMinerTxsReward) SetValue(st StateDB, value interface{}) er
 	return nil
 }
 
+/////////////////////////////////////////////////////////////////////////////////////////
+// 上一矿工其他币种交易奖励金额
+type operatorPreMinerMultiCoinTxsReward struct {
+	key common.Hash
+}
+
+func newPreMinerMultiCoinTxsRewardOpt() *operatorPreMinerMultiCoinTxsReward {
+	return &operatorPreMinerMultiCoinTxsReward{
+		key: types.RlpHash(matrixStatePrefix + mc.MSKeyPreMinerMultiCoinTxsReward),
+	}
+}
+
+func (opt *operatorPreMinerMultiCoinTxsReward) KeyHash() common.Hash {
+	return opt.key
+}
+
+func (opt *operatorPreMinerMultiCoinTxsReward) GetValue(st StateDB) (interface{}, error) {
+	if err := checkStateDB(st); err != nil {
+		return nil, err
+	}
+
+	data := st.GetMatrixData(opt.key)
+	if len(data) == 0 {
+		return &mc.MultiCoinMinerOutRewards{}, nil
+	}
+
+	value := new(mc.MultiCoinMinerOutRewards)
+	err := rlp.DecodeBytes(data, &value)
+	if err != nil {
+		log.Error(logInfo, "preMinerMultiCoinTxsReward rlp decode failed", err)
+		return nil, err
+	}
+	return value, nil
+}
+
+func (opt *operatorPreMinerMultiCoinTxsReward) SetValue(st StateDB, value interface{}) error {
+	if err := checkStateDB(st); err != nil {
+		return err
+	}
+
+	data, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		log.Error(logInfo, "preMinerMultiCoinTxsReward rlp encode failed", err)
+		return err
+	}
+	st.SetMatrixData(opt.key, data)
+	return nil
+}
+
 /////////////////////////////////////////////////////////////////////////////////////////
 // upTime状态
 type operatorUpTimeNum struct {
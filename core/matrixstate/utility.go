@@ -179,6 +179,62 @@ func SetVersionSuperAccounts(st StateDB, accounts []common.Address) error {
 	return opt.SetValue(st, accounts)
 }
 
+func GetVersionProposals(st StateDB) (*mc.VersionProposalInfo, error) {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return nil, ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyVersionProposals)
+	if err != nil {
+		return nil, err
+	}
+	value, err := opt.GetValue(st)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.VersionProposalInfo), nil
+}
+
+func SetVersionProposals(st StateDB, info *mc.VersionProposalInfo) error {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyVersionProposals)
+	if err != nil {
+		return err
+	}
+	return opt.SetValue(st, info)
+}
+
+func GetCurrencyFeeRates(st StateDB) (*mc.CurrencyFeeRateInfo, error) {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return nil, ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyCurrencyFeeRates)
+	if err != nil {
+		return nil, err
+	}
+	value, err := opt.GetValue(st)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.CurrencyFeeRateInfo), nil
+}
+
+func SetCurrencyFeeRates(st StateDB, info *mc.CurrencyFeeRateInfo) error {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyCurrencyFeeRates)
+	if err != nil {
+		return err
+	}
+	return opt.SetValue(st, info)
+}
+
 func GetBlockSuperAccounts(st StateDB) ([]common.Address, error) {
 	mgr := GetManager(GetVersionInfo(st))
 	if mgr == nil {
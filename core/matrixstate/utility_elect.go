@@ -316,3 +316,31 @@ func GetMinHash(st StateDB) (*mc.RandomInfoStruct, error) {
 	}
 	return value.(*mc.RandomInfoStruct), nil
 }
+
+func GetRandomWithhold(st StateDB) (*mc.RandomWithholdInfo, error) {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return nil, ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyRandomWithhold)
+	if err != nil {
+		return nil, err
+	}
+	value, err := opt.GetValue(st)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.RandomWithholdInfo), nil
+}
+
+func SetRandomWithhold(st StateDB, info *mc.RandomWithholdInfo) error {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyRandomWithhold)
+	if err != nil {
+		return err
+	}
+	return opt.SetValue(st, info)
+}
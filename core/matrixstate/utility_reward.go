@@ -380,6 +380,34 @@ func SetPreMinerTxsReward(st StateDB, reward *mc.MinerOutReward) error {
 	return opt.SetValue(st, reward)
 }
 
+func GetPreMinerMultiCoinTxsReward(st StateDB) (*mc.MultiCoinMinerOutRewards, error) {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return nil, ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyPreMinerMultiCoinTxsReward)
+	if err != nil {
+		return nil, err
+	}
+	value, err := opt.GetValue(st)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*mc.MultiCoinMinerOutRewards), nil
+}
+
+func SetPreMinerMultiCoinTxsReward(st StateDB, reward *mc.MultiCoinMinerOutRewards) error {
+	mgr := GetManager(GetVersionInfo(st))
+	if mgr == nil {
+		return ErrFindManager
+	}
+	opt, err := mgr.FindOperator(mc.MSKeyPreMinerMultiCoinTxsReward)
+	if err != nil {
+		return err
+	}
+	return opt.SetValue(st, reward)
+}
+
 func GetBlkCalc(st StateDB) (string, error) {
 	mgr := GetManager(GetVersionInfo(st))
 	if mgr == nil {
@@ -0,0 +1,185 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// Kinds of address index entries.
+const (
+	AddrIndexSend    uint8 = iota // the address sent the transaction
+	AddrIndexReceive              // the address is a recipient (To or ExtraTo) of the transaction
+	AddrIndexReward               // the address was credited by a reward transaction
+)
+
+// AddrIndexEntry records a transaction touching an address in a currency.
+type AddrIndexEntry struct {
+	BlockNumber  uint64
+	TxHash       common.Hash
+	TxIndex      uint32
+	Kind         uint8
+	Counterparty common.Address // recipient for sends, sender otherwise
+	Amount       *big.Int
+	Failed       bool // the transaction failed, only its sender paid the gas
+}
+
+// AddrIndexJournalItem is the number of entries a block appended to the list of
+// an address and currency. Journals allow rolling back the index on reorgs.
+type AddrIndexJournalItem struct {
+	Address  common.Address
+	Currency string
+	Count    uint64
+}
+
+// addrIndexKey = addrIndexPrefix + address + currency (8 bytes, zero padded)
+func addrIndexKey(addr common.Address, currency string) []byte {
+	cur := make([]byte, 8)
+	copy(cur, currency)
+	key := append(append([]byte{}, addrIndexPrefix...), addr.Bytes()...)
+	return append(key, cur...)
+}
+
+// addrIndexEntryKey = addrIndexKey + index (uint64 big endian)
+func addrIndexEntryKey(addr common.Address, currency string, index uint64) []byte {
+	return append(addrIndexKey(addr, currency), encodeBlockNumber(index)...)
+}
+
+// ReadAddrIndexCount retrieves the number of index entries of an address in a currency.
+func ReadAddrIndexCount(db DatabaseReader, addr common.Address, currency string) uint64 {
+	data, _ := db.Get(addrIndexKey(addr, currency))
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteAddrIndexCount stores the number of index entries of an address in a currency.
+func WriteAddrIndexCount(db DatabaseWriter, addr common.Address, currency string, count uint64) {
+	if err := db.Put(addrIndexKey(addr, currency), encodeBlockNumber(count)); err != nil {
+		log.Crit("Failed to store address index count", "err", err)
+	}
+}
+
+// ReadAddrIndexEntry retrieves the index-th entry of an address in a currency.
+func ReadAddrIndexEntry(db DatabaseReader, addr common.Address, currency string, index uint64) *AddrIndexEntry {
+	data, _ := db.Get(addrIndexEntryKey(addr, currency, index))
+	if len(data) == 0 {
+		return nil
+	}
+	entry := new(AddrIndexEntry)
+	if err := rlp.DecodeBytes(data, entry); err != nil {
+		log.Error("Invalid address index entry RLP", "address", addr, "index", index, "err", err)
+		return nil
+	}
+	return entry
+}
+
+// WriteAddrIndexEntry stores the index-th entry of an address in a currency.
+func WriteAddrIndexEntry(db DatabaseWriter, addr common.Address, currency string, index uint64, entry *AddrIndexEntry) {
+	data, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		log.Crit("Failed to RLP encode address index entry", "err", err)
+	}
+	if err := db.Put(addrIndexEntryKey(addr, currency, index), data); err != nil {
+		log.Crit("Failed to store address index entry", "err", err)
+	}
+}
+
+// DeleteAddrIndexEntry removes the index-th entry of an address in a currency.
+func DeleteAddrIndexEntry(db DatabaseDeleter, addr common.Address, currency string, index uint64) {
+	db.Delete(addrIndexEntryKey(addr, currency, index))
+}
+
+// ReadAddrIndexJournal retrieves the address index entries added by a block.
+func ReadAddrIndexJournal(db DatabaseReader, number uint64) []AddrIndexJournalItem {
+	data, _ := db.Get(append(addrIndexJournalPrefix, encodeBlockNumber(number)...))
+	if len(data) == 0 {
+		return nil
+	}
+	var journal []AddrIndexJournalItem
+	if err := rlp.DecodeBytes(data, &journal); err != nil {
+		log.Error("Invalid address index journal RLP", "number", number, "err", err)
+		return nil
+	}
+	return journal
+}
+
+// WriteAddrIndexJournal stores the address index entries added by a block.
+func WriteAddrIndexJournal(db DatabaseWriter, number uint64, journal []AddrIndexJournalItem) {
+	data, err := rlp.EncodeToBytes(journal)
+	if err != nil {
+		log.Crit("Failed to RLP encode address index journal", "err", err)
+	}
+	if err := db.Put(append(addrIndexJournalPrefix, encodeBlockNumber(number)...), data); err != nil {
+		log.Crit("Failed to store address index journal", "err", err)
+	}
+}
+
+// DeleteAddrIndexJournal removes the address index journal of a block.
+func DeleteAddrIndexJournal(db DatabaseDeleter, number uint64) {
+	db.Delete(append(addrIndexJournalPrefix, encodeBlockNumber(number)...))
+}
+
+// ReadAddrIndexHead retrieves the number of the last block added to the address
+// index, the second return value is false if nothing was indexed yet.
+func ReadAddrIndexHead(db DatabaseReader) (uint64, bool) {
+	data, _ := db.Get(headAddrIndexKey)
+	if len(data) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(data), true
+}
+
+// WriteAddrIndexHead stores the number of the last block added to the address index.
+func WriteAddrIndexHead(db DatabaseWriter, number uint64) {
+	if err := db.Put(headAddrIndexKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store address index head", "err", err)
+	}
+}
+
+// DeleteAddrIndexHead removes the address index head marker.
+func DeleteAddrIndexHead(db DatabaseDeleter) {
+	db.Delete(headAddrIndexKey)
+}
+
+// RollbackAddrIndex removes every address index entry added by blocks from
+// number onwards, undoing the block journals in reverse order.
+func RollbackAddrIndex(db interface {
+	DatabaseReader
+	DatabaseWriter
+	DatabaseDeleter
+}, number uint64) {
+	head, ok := ReadAddrIndexHead(db)
+	if !ok || head < number {
+		return
+	}
+	for n := head; ; n-- {
+		for _, item := range ReadAddrIndexJournal(db, n) {
+			count := ReadAddrIndexCount(db, item.Address, item.Currency)
+			if item.Count > count {
+				item.Count = count
+			}
+			for i := count - item.Count; i < count; i++ {
+				DeleteAddrIndexEntry(db, item.Address, item.Currency, i)
+			}
+			WriteAddrIndexCount(db, item.Address, item.Currency, count-item.Count)
+		}
+		DeleteAddrIndexJournal(db, n)
+		if n == number {
+			break
+		}
+	}
+	if number == 0 {
+		DeleteAddrIndexHead(db)
+	} else {
+		WriteAddrIndexHead(db, number-1)
+	}
+}
@@ -54,8 +54,8 @@ func TestHeaderStorage(t *testing.T) {
 func TestBodyStorage(t *testing.T) {
 	log.InitLog(3)
 	db := mandb.NewMemDatabase()
-	tx1 := types.NewTransaction(1, common.BytesToAddress([]byte{0x11}), big.NewInt(111), 1111, big.NewInt(11111), []byte{0x11, 0x11, 0x11})
-	tx2 := types.NewTransaction(2, common.BytesToAddress([]byte{0x11}), big.NewInt(111), 1111, big.NewInt(11111), []byte{0x11, 0x11, 0x11})
+	tx1 := types.NewTransaction(1, common.BytesToAddress([]byte{0x11}), big.NewInt(111), 1111, big.NewInt(11111), []byte{0x11, 0x11, 0x11}, big.NewInt(0), big.NewInt(0), big.NewInt(0), 0, 0, "MAN", 0)
+	tx2 := types.NewTransaction(2, common.BytesToAddress([]byte{0x11}), big.NewInt(111), 1111, big.NewInt(11111), []byte{0x11, 0x11, 0x11}, big.NewInt(0), big.NewInt(0), big.NewInt(0), 0, 0, "MAN", 0)
 	aaa := make([]types.SelfTransaction, 0)
 	aaa = append(aaa, tx1)
 	aaa = append(aaa, tx2)
@@ -5,6 +5,7 @@
 package rawdb
 
 import (
+	"bytes"
 	"math/big"
 	"testing"
 
@@ -17,9 +18,9 @@ import (
 func TestLookupStorage(t *testing.T) {
 	db := mandb.NewMemDatabase()
 
-	tx1 := types.NewTransaction(1, common.BytesToAddress([]byte{0x11}), big.NewInt(111), 1111, big.NewInt(11111), []byte{0x11, 0x11, 0x11})
-	tx2 := types.NewTransaction(2, common.BytesToAddress([]byte{0x22}), big.NewInt(222), 2222, big.NewInt(22222), []byte{0x22, 0x22, 0x22})
-	tx3 := types.NewTransaction(3, common.BytesToAddress([]byte{0x33}), big.NewInt(333), 3333, big.NewInt(33333), []byte{0x33, 0x33, 0x33})
+	tx1 := types.NewTransaction(1, common.BytesToAddress([]byte{0x11}), big.NewInt(111), 1111, big.NewInt(11111), []byte{0x11, 0x11, 0x11}, big.NewInt(0), big.NewInt(0), big.NewInt(0), 0, 0, "MAN", 0)
+	tx2 := types.NewTransaction(2, common.BytesToAddress([]byte{0x22}), big.NewInt(222), 2222, big.NewInt(22222), []byte{0x22, 0x22, 0x22}, big.NewInt(0), big.NewInt(0), big.NewInt(0), 0, 0, "MAN", 0)
+	tx3 := types.NewTransaction(3, common.BytesToAddress([]byte{0x33}), big.NewInt(333), 3333, big.NewInt(33333), []byte{0x33, 0x33, 0x33}, big.NewInt(0), big.NewInt(0), big.NewInt(0), 0, 0, "MAN", 0)
 	txs := []types.SelfTransaction{tx1, tx2, tx3}
 
 	block := types.NewBlock(&types.Header{Number: big.NewInt(314)}, txs, nil, nil)
@@ -54,3 +55,75 @@ func TestLookupStorage(t *testing.T) {
 		}
 	}
 }
+
+// Tests the layout of the address index keys.
+func TestAddrIndexKey(t *testing.T) {
+	addr := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+
+	key := addrIndexKey(addr, "MAN")
+	want := append(append(append([]byte{}, addrIndexPrefix...), addr.Bytes()...), 'M', 'A', 'N', 0, 0, 0, 0, 0)
+	if !bytes.Equal(key, want) {
+		t.Fatalf("key mismatch: have %x, want %x", key, want)
+	}
+	// currencies are zero padded to 8 bytes, the longest valid currency fills them
+	if key := addrIndexKey(addr, "ABCDEFGH"); len(key) != len(want) || !bytes.Equal(key[len(key)-8:], []byte("ABCDEFGH")) {
+		t.Errorf("full length currency key mismatch: %x", key)
+	}
+	if bytes.Equal(addrIndexKey(addr, "BTC"), addrIndexKey(addr, "BTCC")) {
+		t.Error("currency prefix shares the key of the longer currency")
+	}
+	other := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121315")
+	if bytes.HasPrefix(addrIndexKey(other, "MAN"), key) {
+		t.Error("keys of different addresses share a prefix")
+	}
+
+	// entries follow the count key and sort by index
+	prev := key
+	for _, index := range []uint64{0, 1, 255, 256, 1 << 32} {
+		entry := addrIndexEntryKey(addr, "MAN", index)
+		if !bytes.HasPrefix(entry, key) || len(entry) != len(key)+8 {
+			t.Fatalf("entry key %d mismatch: %x", index, entry)
+		}
+		if bytes.Compare(prev, entry) >= 0 {
+			t.Errorf("entry key %d sorts before %x: %x", index, prev, entry)
+		}
+		prev = entry
+	}
+}
+
+// Tests that rolling back the address index removes the entries added by the
+// rolled back blocks only.
+func TestAddrIndexRollback(t *testing.T) {
+	db := mandb.NewMemDatabase()
+	addr := common.HexToAddress("0x01")
+
+	for number := uint64(1); number <= 3; number++ {
+		count := ReadAddrIndexCount(db, addr, "MAN")
+		for i := uint64(0); i < number; i++ {
+			WriteAddrIndexEntry(db, addr, "MAN", count+i, &AddrIndexEntry{BlockNumber: number, Amount: big.NewInt(int64(i))})
+		}
+		WriteAddrIndexCount(db, addr, "MAN", count+number)
+		WriteAddrIndexJournal(db, number, []AddrIndexJournalItem{{Address: addr, Currency: "MAN", Count: number}})
+		WriteAddrIndexHead(db, number)
+	}
+	if count := ReadAddrIndexCount(db, addr, "MAN"); count != 6 {
+		t.Fatalf("entry count mismatch: have %d, want 6", count)
+	}
+
+	RollbackAddrIndex(db, 2)
+	if count := ReadAddrIndexCount(db, addr, "MAN"); count != 1 {
+		t.Fatalf("entry count after rollback mismatch: have %d, want 1", count)
+	}
+	if entry := ReadAddrIndexEntry(db, addr, "MAN", 0); entry == nil || entry.BlockNumber != 1 {
+		t.Errorf("kept entry mismatch: %v", entry)
+	}
+	if entry := ReadAddrIndexEntry(db, addr, "MAN", 1); entry != nil {
+		t.Errorf("rolled back entry returned: %v", entry)
+	}
+	if head, ok := ReadAddrIndexHead(db); !ok || head != 1 {
+		t.Errorf("head mismatch: have %d %v, want 1", head, ok)
+	}
+	if journal := ReadAddrIndexJournal(db, 2); journal != nil {
+		t.Errorf("rolled back journal returned: %v", journal)
+	}
+}
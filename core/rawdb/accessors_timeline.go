@@ -0,0 +1,77 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package rawdb
+
+import (
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// ConsensusTimelineEvent is a consensus message or step seen by the node. Times
+// are unix milliseconds.
+type ConsensusTimelineEvent struct {
+	Time   uint64
+	Kind   string
+	From   common.Address
+	Hash   common.Hash
+	Detail string
+}
+
+// ConsensusTimelineTurn is a leader turn of a height, begin and end times are
+// the unix seconds of the turn schedule.
+type ConsensusTimelineTurn struct {
+	ConsensusTurn uint32
+	ReelectTurn   uint32
+	Leader        common.Address
+	BeginTime     uint64
+	EndTime       uint64
+	Events        []ConsensusTimelineEvent
+}
+
+// ConsensusTimeline is the consensus history of a height as observed by the node.
+type ConsensusTimeline struct {
+	Number     uint64
+	Turns      []*ConsensusTimelineTurn
+	BlockHash  common.Hash // hash of the inserted block
+	InsertTime uint64      // unix milliseconds of the block insert, 0 if not inserted
+}
+
+// consensusTimelineKey = consensusTimelinePrefix + num (uint64 big endian)
+func consensusTimelineKey(number uint64) []byte {
+	return append(append([]byte{}, consensusTimelinePrefix...), encodeBlockNumber(number)...)
+}
+
+// ReadConsensusTimeline retrieves the consensus timeline of a height.
+func ReadConsensusTimeline(db DatabaseReader, number uint64) *ConsensusTimeline {
+	data, _ := db.Get(consensusTimelineKey(number))
+	if len(data) == 0 {
+		return nil
+	}
+	timeline := new(ConsensusTimeline)
+	if err := rlp.DecodeBytes(data, timeline); err != nil {
+		log.Error("Invalid consensus timeline RLP", "number", number, "err", err)
+		return nil
+	}
+	return timeline
+}
+
+// WriteConsensusTimeline stores the consensus timeline of a height.
+func WriteConsensusTimeline(db DatabaseWriter, timeline *ConsensusTimeline) {
+	data, err := rlp.EncodeToBytes(timeline)
+	if err != nil {
+		log.Crit("Failed to RLP encode consensus timeline", "err", err)
+	}
+	if err := db.Put(consensusTimelineKey(timeline.Number), data); err != nil {
+		log.Crit("Failed to store consensus timeline", "err", err)
+	}
+}
+
+// DeleteConsensusTimeline removes the consensus timeline of a height.
+func DeleteConsensusTimeline(db DatabaseDeleter, number uint64) {
+	if err := db.Delete(consensusTimelineKey(number)); err != nil {
+		log.Crit("Failed to delete consensus timeline", "err", err)
+	}
+}
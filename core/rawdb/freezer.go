@@ -0,0 +1,489 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+)
+
+// The tables of the freezer, each holding one item per frozen block.
+const (
+	freezerHashTable       = "hashes"
+	freezerHeaderTable     = "headers"
+	freezerBodiesTable     = "bodies"
+	freezerReceiptTable    = "receipts"
+	freezerDifficultyTable = "diffs"
+)
+
+// freezerTables lists the freezer tables and whether they are compressed.
+var freezerTables = []struct {
+	name     string
+	compress bool
+}{
+	{freezerHashTable, false},
+	{freezerHeaderTable, true},
+	{freezerBodiesTable, true},
+	{freezerReceiptTable, true},
+	{freezerDifficultyTable, false},
+}
+
+const (
+	// freezerRecheckInterval is the frequency to check the key-value database for
+	// chain progression that might permit new blocks to be frozen.
+	freezerRecheckInterval = time.Minute
+
+	// freezerBatchLimit is the maximum number of blocks to freeze in one batch
+	// before syncing the tables and deleting the blocks from the key-value store.
+	freezerBatchLimit = 30000
+)
+
+var errNoFreezer = errors.New("database has no ancient store")
+
+// freezer is an append-only store of the canonical blocks that are old enough
+// to never be reorged. Block data is kept in flat files, one table per kind of
+// data, the n-th item of every table belonging to block n.
+type freezer struct {
+	frozen uint64 // Number of blocks already frozen (atomic)
+
+	lock   sync.Mutex // Serializes appends and truncations
+	tables map[string]*freezerTable
+
+	readonly bool // Whether the freezer is opened for reading only
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newFreezer opens the freezer in datadir, dropping the blocks that were not
+// written into every table.
+func newFreezer(datadir string, readonly bool) (*freezer, error) {
+	f := &freezer{
+		tables:   make(map[string]*freezerTable),
+		readonly: readonly,
+		quit:     make(chan struct{}),
+	}
+	for _, t := range freezerTables {
+		table, err := newFreezerTable(datadir, t.name, t.compress, readonly)
+		if err != nil {
+			for _, table := range f.tables {
+				table.Close()
+			}
+			return nil, err
+		}
+		f.tables[t.name] = table
+	}
+	if err := f.repair(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	log.Info("Opened ancient database", "path", datadir, "frozen", f.frozen)
+	return f, nil
+}
+
+// repair truncates all tables to the shortest one.
+func (f *freezer) repair() error {
+	min := uint64(0)
+	for i, t := range freezerTables {
+		if items := f.tables[t.name].Items(); i == 0 || items < min {
+			min = items
+		}
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(min); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, min)
+	return nil
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists.
+func (f *freezer) HasAncient(kind string, number uint64) (bool, error) {
+	if table := f.tables[kind]; table != nil && number < table.Items() {
+		return true, nil
+	}
+	return false, nil
+}
+
+// Ancient retrieves an ancient binary blob from the append-only immutable files.
+func (f *freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.Retrieve(number)
+	}
+	return nil, fmt.Errorf("unknown ancient table %s", kind)
+}
+
+// Ancients returns the number of blocks frozen.
+func (f *freezer) Ancients() (uint64, error) {
+	return atomic.LoadUint64(&f.frozen), nil
+}
+
+// AppendAncient stores the data of the next block into the freezer. Either all
+// the data is written or, on failure, none of it is.
+func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, td []byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.readonly {
+		return errReadOnly
+	}
+	if frozen := atomic.LoadUint64(&f.frozen); number != frozen {
+		return fmt.Errorf("%v: frozen %d, appending %d", errOutOrderInsertion, frozen, number)
+	}
+	blobs := map[string][]byte{
+		freezerHashTable:       hash,
+		freezerHeaderTable:     header,
+		freezerBodiesTable:     body,
+		freezerReceiptTable:    receipts,
+		freezerDifficultyTable: td,
+	}
+	for _, t := range freezerTables {
+		if err := f.tables[t.name].Append(number, blobs[t.name]); err != nil {
+			// 部分写入, 回滚到追加前
+			for _, table := range f.tables {
+				table.truncate(number)
+			}
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, number+1)
+	return nil
+}
+
+// TruncateAncients discards every frozen block from the given number on.
+func (f *freezer) TruncateAncients(items uint64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.readonly {
+		return errReadOnly
+	}
+	if atomic.LoadUint64(&f.frozen) <= items {
+		return nil
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, items)
+	return nil
+}
+
+// Sync flushes all the tables to disk.
+func (f *freezer) Sync() error {
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// Close stops the background migration and closes all the tables.
+func (f *freezer) Close() error {
+	select {
+	case <-f.quit:
+	default:
+		close(f.quit)
+	}
+	f.wg.Wait()
+
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// freeze periodically moves the canonical blocks below limit() out of the
+// key-value store into the freezer.
+func (f *freezer) freeze(db mandb.Database, limit func() uint64) {
+	defer f.wg.Done()
+
+	backoff := false
+	for {
+		if backoff {
+			select {
+			case <-time.After(freezerRecheckInterval):
+			case <-f.quit:
+				return
+			}
+		}
+		select {
+		case <-f.quit:
+			return
+		default:
+		}
+		backoff = true
+
+		// 链被回滚到已冻结的区块之下时, 丢弃失效的冻结数据
+		frozen := atomic.LoadUint64(&f.frozen)
+		if head := ReadHeaderNumber(db, ReadHeadBlockHash(db)); head != nil && *head+1 < frozen {
+			log.Warn("Chain rewound below the ancient store, truncating", "head", *head, "frozen", frozen)
+			if err := f.TruncateAncients(*head + 1); err != nil {
+				log.Error("Failed to truncate ancient store", "err", err)
+				continue
+			}
+			frozen = *head + 1
+		}
+		target := limit()
+		if target <= frozen {
+			continue
+		}
+		if target-frozen > freezerBatchLimit {
+			target = frozen + freezerBatchLimit
+			backoff = false
+		}
+
+		start := time.Now()
+		var hashes []common.Hash
+		for number := frozen; number < target; number++ {
+			hash, err := f.freezeBlock(db, number)
+			if err != nil {
+				log.Error("Failed to freeze block", "number", number, "err", err)
+				backoff = true
+				break
+			}
+			hashes = append(hashes, hash)
+		}
+		if len(hashes) == 0 {
+			continue
+		}
+		if err := f.Sync(); err != nil {
+			log.Crit("Failed to flush ancient store", "err", err)
+		}
+		// 冻结数据落盘后再从leveldb中删除
+		for i, hash := range hashes {
+			deleteFrozenBlock(db, hash, frozen+uint64(i))
+		}
+		log.Info("Moved blocks into the ancient store", "blocks", len(hashes), "frozen", frozen+uint64(len(hashes)), "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+}
+
+// freezeBlock copies the canonical block with the given number from the key-value
+// store into the freezer.
+func (f *freezer) freezeBlock(db mandb.Database, number uint64) (common.Hash, error) {
+	hash := ReadCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
+		return hash, errors.New("canonical hash missing")
+	}
+	header := ReadHeaderRLP(db, hash, number)
+	if len(header) == 0 {
+		return hash, errors.New("block header missing")
+	}
+	body := ReadBodyRLP(db, hash, number)
+	if len(body) == 0 {
+		return hash, errors.New("block body missing")
+	}
+	receipts, _ := db.Get(blockReceiptsKey(hash, number))
+	if len(receipts) == 0 {
+		receipts, _ = rlp.EncodeToBytes([]*struct{}{})
+	}
+	td, _ := db.Get(headerTDKey(hash, number))
+	if len(td) == 0 {
+		return hash, errors.New("total difficulty missing")
+	}
+	return hash, f.AppendAncient(number, hash.Bytes(), header, body, receipts, td)
+}
+
+// deleteFrozenBlock removes the data of a frozen block from the key-value store.
+// The hash to number mapping is kept, it's how blocks are located by hash.
+func deleteFrozenBlock(db DatabaseDeleter, hash common.Hash, number uint64) {
+	DeleteCanonicalHash(db, number)
+	db.Delete(headerKey(hash, number))
+	DeleteBody(db, hash, number)
+	DeleteReceipts(db, hash, number)
+	DeleteTd(db, hash, number)
+}
+
+// readAncient returns the data of the given kind of a frozen block, provided db
+// has an ancient store and the block with the given hash was frozen.
+func readAncient(db DatabaseReader, kind string, hash common.Hash, number uint64) []byte {
+	if !hasAncient(db, hash, number) {
+		return nil
+	}
+	data, err := db.(AncientReader).Ancient(kind, number)
+	if err != nil {
+		log.Error("Failed to read ancient block data", "kind", kind, "number", number, "err", err)
+		return nil
+	}
+	return data
+}
+
+// hasAncient reports whether the block with the given hash and number is in the
+// ancient store of db.
+func hasAncient(db DatabaseReader, hash common.Hash, number uint64) bool {
+	ancient, ok := db.(AncientReader)
+	if !ok {
+		return false
+	}
+	frozen, err := ancient.Ancient(freezerHashTable, number)
+	return err == nil && common.BytesToHash(frozen) == hash
+}
+
+// freezerdb is a database wrapper that serves the frozen blocks from the freezer
+// and everything else from the key-value store.
+type freezerdb struct {
+	mandb.Database
+	*freezer
+}
+
+// Close stops the migration and closes the freezer and the key-value store.
+func (db *freezerdb) Close() {
+	if err := db.freezer.Close(); err != nil {
+		log.Error("Failed to close ancient database", "err", err)
+	}
+	db.Database.Close()
+}
+
+// NewDatabaseWithFreezer wraps db with the freezer kept in the given directory.
+// The rawdb accessors transparently read frozen blocks from it.
+func NewDatabaseWithFreezer(db mandb.Database, datadir string) (mandb.Database, error) {
+	frdb, err := newFreezer(datadir, false)
+	if err != nil {
+		return nil, err
+	}
+	return &freezerdb{Database: db, freezer: frdb}, nil
+}
+
+// NewDatabaseWithReadOnlyFreezer wraps db with the existing freezer kept in the
+// given directory, opened for reading only.
+func NewDatabaseWithReadOnlyFreezer(db mandb.Database, datadir string) (mandb.Database, error) {
+	frdb, err := newFreezer(datadir, true)
+	if err != nil {
+		return nil, err
+	}
+	return &freezerdb{Database: db, freezer: frdb}, nil
+}
+
+// StartFreezing starts moving the canonical blocks below limit() from the key-
+// value store into the freezer of db in the background. limit is evaluated on
+// every round and must only return numbers of blocks that can't be reorged.
+func StartFreezing(db mandb.Database, limit func() uint64) error {
+	frdb, ok := db.(*freezerdb)
+	if !ok {
+		return errNoFreezer
+	}
+	if frdb.readonly {
+		return errReadOnly
+	}
+	frdb.wg.Add(1)
+	go frdb.freeze(frdb.Database, limit)
+	return nil
+}
+
+// KeyValueStore returns the key-value store of a database possibly wrapped with
+// a freezer.
+func KeyValueStore(db mandb.Database) mandb.Database {
+	if frdb, ok := db.(*freezerdb); ok {
+		return frdb.Database
+	}
+	return db
+}
+
+// FreezerTableStat describes a table of the freezer.
+type FreezerTableStat struct {
+	Name  string
+	Items uint64
+	Size  common.StorageSize
+}
+
+// InspectFreezer returns the number of frozen blocks and the stats of every
+// table of the freezer in datadir.
+func InspectFreezer(datadir string) (uint64, []FreezerTableStat, error) {
+	f, err := newFreezer(datadir, true)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	stats := make([]FreezerTableStat, 0, len(freezerTables))
+	for _, t := range freezerTables {
+		table := f.tables[t.name]
+		stats = append(stats, FreezerTableStat{t.name, table.Items(), common.StorageSize(table.Size())})
+	}
+	return f.frozen, stats, nil
+}
+
+// RepairFreezer checks the frozen blocks of the freezer in datadir against each
+// other, truncating the freezer at the first block that doesn't decode or link
+// to its parent. It returns the number of blocks left in the freezer.
+func RepairFreezer(datadir string) (uint64, error) {
+	f, err := newFreezer(datadir, false)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var parent common.Hash
+	for number := uint64(0); number < f.frozen; number++ {
+		if err := verifyAncient(f, number, parent); err != nil {
+			log.Warn("Corrupt ancient block, truncating", "number", number, "err", err)
+			if err := f.TruncateAncients(number); err != nil {
+				return 0, err
+			}
+			break
+		}
+		hash, _ := f.Ancient(freezerHashTable, number)
+		parent = common.BytesToHash(hash)
+	}
+	return f.frozen, f.Sync()
+}
+
+// verifyAncient checks that a frozen block decodes, hashes to its recorded hash
+// and has the given parent.
+func verifyAncient(f *freezer, number uint64, parent common.Hash) error {
+	hash, err := f.Ancient(freezerHashTable, number)
+	if err != nil {
+		return err
+	}
+	data, err := f.Ancient(freezerHeaderTable, number)
+	if err != nil {
+		return err
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(data, header); err != nil {
+		return err
+	}
+	if header.Hash() != common.BytesToHash(hash) {
+		return fmt.Errorf("header hash %x, recorded %x", header.Hash(), hash)
+	}
+	if header.Number == nil || header.Number.Uint64() != number {
+		return fmt.Errorf("header number %v", header.Number)
+	}
+	if number > 0 && header.ParentHash != parent {
+		return fmt.Errorf("parent hash %x, previous block %x", header.ParentHash, parent)
+	}
+	for _, kind := range []string{freezerBodiesTable, freezerReceiptTable, freezerDifficultyTable} {
+		data, err := f.Ancient(kind, number)
+		if err != nil {
+			return err
+		}
+		if _, _, err := rlp.SplitList(data); kind != freezerDifficultyTable && err != nil {
+			return fmt.Errorf("%s: %v", kind, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/golang/snappy"
+)
+
+var (
+	// errClosed is returned if an operation attempts to read from or write to the
+	// freezer table after it has already been closed.
+	errClosed = errors.New("closed")
+
+	// errOutOfBounds is returned if the item requested is not contained within the
+	// freezer table.
+	errOutOfBounds = errors.New("out of bounds")
+
+	// errOutOrderInsertion is returned if the user attempts to inject out-of-order
+	// binary blobs into the freezer.
+	errOutOrderInsertion = errors.New("the append operation is out-order")
+
+	// errReadOnly is returned if the user attempts to modify a freezer table
+	// opened for reading only.
+	errReadOnly = errors.New("read only")
+)
+
+// indexEntrySize is the size of an index entry, the end offset of the item in
+// the data file.
+const indexEntrySize = 8
+
+// freezerTable is an append-only flat file store of one kind of item. The data
+// file holds the items back to back and the index file the end offset of every
+// item, so the n-th item spans index[n-1] to index[n] of the data file.
+type freezerTable struct {
+	lock     sync.RWMutex
+	name     string
+	compress bool // whether items are snappy compressed on disk
+	readonly bool // whether the table files are opened for reading only
+
+	index *os.File
+	data  *os.File
+	items uint64 // number of items stored in the table
+	size  uint64 // size of the data file, the end offset of the last item
+}
+
+// newFreezerTable opens the given table, creating it if needed, and truncates
+// any partially written items left by an unclean shutdown. A read-only table
+// must exist and ignores the partially written items instead.
+func newFreezerTable(path, name string, compress bool, readonly bool) (*freezerTable, error) {
+	flag := os.O_RDONLY
+	if !readonly {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, err
+		}
+		flag = os.O_RDWR | os.O_CREATE
+	}
+	ext := "rdat"
+	if compress {
+		ext = "cdat"
+	}
+	index, err := os.OpenFile(filepath.Join(path, name+".ridx"), flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.OpenFile(filepath.Join(path, name+"."+ext), flag, 0644)
+	if err != nil {
+		index.Close()
+		return nil, err
+	}
+	table := &freezerTable{
+		name:     name,
+		compress: compress,
+		readonly: readonly,
+		index:    index,
+		data:     data,
+	}
+	if err := table.repair(); err != nil {
+		table.Close()
+		return nil, err
+	}
+	return table, nil
+}
+
+// repair cross checks the index and data files, dropping trailing items that
+// were not completely written.
+func (t *freezerTable) repair() error {
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	indexSize := stat.Size()
+	if rest := indexSize % indexEntrySize; rest != 0 {
+		// 半条索引, 丢弃
+		indexSize -= rest
+		if !t.readonly {
+			if err := t.index.Truncate(indexSize); err != nil {
+				return err
+			}
+		}
+	}
+	if stat, err = t.data.Stat(); err != nil {
+		return err
+	}
+	dataSize := uint64(stat.Size())
+
+	items := uint64(indexSize / indexEntrySize)
+	for items > 0 {
+		end, err := t.readOffset(items - 1)
+		if err != nil {
+			return err
+		}
+		if end <= dataSize {
+			dataSize = end
+			break
+		}
+		// 索引指向未写完的数据, 回退一项
+		items--
+	}
+	if items == 0 {
+		dataSize = 0
+	}
+	if !t.readonly {
+		if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+			return err
+		}
+		if err := t.data.Truncate(int64(dataSize)); err != nil {
+			return err
+		}
+	}
+	if items != uint64(indexSize/indexEntrySize) {
+		log.Warn("Repaired freezer table", "table", t.name, "dropped", uint64(indexSize/indexEntrySize)-items)
+	}
+	t.items, t.size = items, dataSize
+	return nil
+}
+
+// readOffset reads the end offset of the given item from the index file.
+func (t *freezerTable) readOffset(item uint64) (uint64, error) {
+	buf := make([]byte, indexEntrySize)
+	if _, err := t.index.ReadAt(buf, int64(item*indexEntrySize)); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+// Items returns the number of items stored in the table.
+func (t *freezerTable) Items() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.items
+}
+
+// Size returns the size of the data file of the table.
+func (t *freezerTable) Size() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.size
+}
+
+// Append stores blob as the item-th item of the table. Items must be appended
+// in order without gaps.
+func (t *freezerTable) Append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil || t.data == nil {
+		return errClosed
+	}
+	if t.readonly {
+		return errReadOnly
+	}
+	if item != t.items {
+		return fmt.Errorf("%v: table %s has %d items, appending %d", errOutOrderInsertion, t.name, t.items, item)
+	}
+	if t.compress {
+		blob = snappy.Encode(nil, blob)
+	}
+	// 先写数据再写索引, 中途崩溃时repair会丢弃没有索引的数据
+	if _, err := t.data.WriteAt(blob, int64(t.size)); err != nil {
+		return err
+	}
+	end := t.size + uint64(len(blob))
+	buf := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint64(buf, end)
+	if _, err := t.index.WriteAt(buf, int64(t.items*indexEntrySize)); err != nil {
+		return err
+	}
+	t.items, t.size = t.items+1, end
+	return nil
+}
+
+// Retrieve returns the item-th item of the table.
+func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil || t.data == nil {
+		return nil, errClosed
+	}
+	if item >= t.items {
+		return nil, errOutOfBounds
+	}
+	var start uint64
+	if item > 0 {
+		var err error
+		if start, err = t.readOffset(item - 1); err != nil {
+			return nil, err
+		}
+	}
+	end, err := t.readOffset(item)
+	if err != nil {
+		return nil, err
+	}
+	if end < start || end > t.size {
+		return nil, fmt.Errorf("table %s item %d has corrupt offsets %d-%d", t.name, item, start, end)
+	}
+	blob := make([]byte, end-start)
+	if _, err := t.data.ReadAt(blob, int64(start)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if t.compress {
+		return snappy.Decode(nil, blob)
+	}
+	return blob, nil
+}
+
+// truncate discards every item from the given one on. A read-only table only
+// hides the discarded items.
+func (t *freezerTable) truncate(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil || t.data == nil {
+		return errClosed
+	}
+	if items >= t.items {
+		return nil
+	}
+	var size uint64
+	if items > 0 {
+		var err error
+		if size, err = t.readOffset(items - 1); err != nil {
+			return err
+		}
+	}
+	if !t.readonly {
+		if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+			return err
+		}
+		if err := t.data.Truncate(int64(size)); err != nil {
+			return err
+		}
+	}
+	t.items, t.size = items, size
+	return nil
+}
+
+// Sync flushes the table files to disk.
+func (t *freezerTable) Sync() error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil || t.data == nil {
+		return errClosed
+	}
+	if err := t.data.Sync(); err != nil {
+		return err
+	}
+	return t.index.Sync()
+}
+
+// Close closes the table files.
+func (t *freezerTable) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var errs []error
+	if t.index != nil {
+		if err := t.index.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		t.index = nil
+	}
+	if t.data != nil {
+		if err := t.data.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		t.data = nil
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
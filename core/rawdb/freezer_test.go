@@ -0,0 +1,339 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+)
+
+func tempFreezerDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	return dir
+}
+
+func freezerBlob(kind string, number uint64) []byte {
+	return []byte(fmt.Sprintf("%s-%d", kind, number))
+}
+
+func appendFreezerBlocks(t *testing.T, f *freezer, from, to uint64) {
+	for number := from; number < to; number++ {
+		err := f.AppendAncient(number,
+			freezerBlob(freezerHashTable, number),
+			freezerBlob(freezerHeaderTable, number),
+			freezerBlob(freezerBodiesTable, number),
+			freezerBlob(freezerReceiptTable, number),
+			freezerBlob(freezerDifficultyTable, number))
+		if err != nil {
+			t.Fatalf("failed to append block %d: %v", number, err)
+		}
+	}
+}
+
+func checkFreezerBlocks(t *testing.T, f *freezer, items uint64) {
+	if frozen, _ := f.Ancients(); frozen != items {
+		t.Fatalf("frozen mismatch: have %d, want %d", frozen, items)
+	}
+	for number := uint64(0); number < items; number++ {
+		for _, table := range freezerTables {
+			blob, err := f.Ancient(table.name, number)
+			if err != nil {
+				t.Fatalf("failed to retrieve %s %d: %v", table.name, number, err)
+			}
+			if want := freezerBlob(table.name, number); !bytes.Equal(blob, want) {
+				t.Fatalf("%s %d mismatch: have %s, want %s", table.name, number, blob, want)
+			}
+		}
+	}
+	if ok, _ := f.HasAncient(freezerHeaderTable, items); ok {
+		t.Fatalf("block %d beyond the freezer reported as frozen", items)
+	}
+	if _, err := f.Ancient(freezerHeaderTable, items); err != errOutOfBounds {
+		t.Fatalf("retrieve beyond the freezer: have %v, want %v", err, errOutOfBounds)
+	}
+}
+
+// Tests that items appended to a table survive reopening it.
+func TestFreezerTableReopen(t *testing.T) {
+	dir := tempFreezerDir(t)
+	defer os.RemoveAll(dir)
+
+	for _, compress := range []bool{false, true} {
+		name := fmt.Sprintf("table-%v", compress)
+		table, err := newFreezerTable(dir, name, compress, false)
+		if err != nil {
+			t.Fatalf("failed to open table: %v", err)
+		}
+		for i := uint64(0); i < 10; i++ {
+			if err := table.Append(i, bytes.Repeat([]byte{byte(i)}, int(i)+1)); err != nil {
+				t.Fatalf("failed to append item %d: %v", i, err)
+			}
+		}
+		if err := table.Append(11, []byte{0}); err == nil {
+			t.Fatalf("appended item with a gap")
+		}
+		table.Close()
+
+		if table, err = newFreezerTable(dir, name, compress, false); err != nil {
+			t.Fatalf("failed to reopen table: %v", err)
+		}
+		if items := table.Items(); items != 10 {
+			t.Fatalf("items mismatch: have %d, want %d", items, 10)
+		}
+		for i := uint64(0); i < 10; i++ {
+			blob, err := table.Retrieve(i)
+			if err != nil {
+				t.Fatalf("failed to retrieve item %d: %v", i, err)
+			}
+			if want := bytes.Repeat([]byte{byte(i)}, int(i)+1); !bytes.Equal(blob, want) {
+				t.Fatalf("item %d mismatch: have %x, want %x", i, blob, want)
+			}
+		}
+		table.Close()
+		if _, err := table.Retrieve(0); err != errClosed {
+			t.Fatalf("retrieve from closed table: have %v, want %v", err, errClosed)
+		}
+	}
+}
+
+// Tests that a table drops the items left partially written by a crash.
+func TestFreezerTableRepair(t *testing.T) {
+	dir := tempFreezerDir(t)
+	defer os.RemoveAll(dir)
+
+	table, err := newFreezerTable(dir, "repair", false, false)
+	if err != nil {
+		t.Fatalf("failed to open table: %v", err)
+	}
+	for i := uint64(0); i < 5; i++ {
+		table.Append(i, []byte{byte(i), byte(i)})
+	}
+	table.Close()
+
+	// 半条索引
+	index := filepath.Join(dir, "repair.ridx")
+	file, _ := os.OpenFile(index, os.O_WRONLY|os.O_APPEND, 0644)
+	file.Write([]byte{0, 0, 0})
+	file.Close()
+
+	// 数据文件比最后一条索引短
+	data := filepath.Join(dir, "repair.rdat")
+	os.Truncate(data, 9)
+
+	if table, err = newFreezerTable(dir, "repair", false, false); err != nil {
+		t.Fatalf("failed to reopen table: %v", err)
+	}
+	defer table.Close()
+
+	if items := table.Items(); items != 4 {
+		t.Fatalf("items mismatch: have %d, want %d", items, 4)
+	}
+	if stat, _ := os.Stat(index); stat.Size() != 4*indexEntrySize {
+		t.Fatalf("index size mismatch: have %d, want %d", stat.Size(), 4*indexEntrySize)
+	}
+	if stat, _ := os.Stat(data); stat.Size() != 8 {
+		t.Fatalf("data size mismatch: have %d, want %d", stat.Size(), 8)
+	}
+	// 修复后可以继续追加
+	if err := table.Append(4, []byte{4, 4}); err != nil {
+		t.Fatalf("failed to append after repair: %v", err)
+	}
+	if blob, _ := table.Retrieve(4); !bytes.Equal(blob, []byte{4, 4}) {
+		t.Fatalf("item 4 mismatch: have %x", blob)
+	}
+}
+
+// Tests appending, truncating and reopening the freezer.
+func TestFreezerAppendTruncate(t *testing.T) {
+	dir := tempFreezerDir(t)
+	defer os.RemoveAll(dir)
+
+	f, err := newFreezer(dir, false)
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	appendFreezerBlocks(t, f, 0, 10)
+	checkFreezerBlocks(t, f, 10)
+
+	if err := f.AppendAncient(12, nil, nil, nil, nil, nil); err == nil {
+		t.Fatalf("appended block out of order")
+	}
+	if err := f.TruncateAncients(6); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	checkFreezerBlocks(t, f, 6)
+	f.Close()
+
+	if f, err = newFreezer(dir, false); err != nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	defer f.Close()
+	checkFreezerBlocks(t, f, 6)
+	appendFreezerBlocks(t, f, 6, 8)
+	checkFreezerBlocks(t, f, 8)
+}
+
+// Tests that the freezer drops the blocks missing from some of the tables.
+func TestFreezerRepairTables(t *testing.T) {
+	dir := tempFreezerDir(t)
+	defer os.RemoveAll(dir)
+
+	f, err := newFreezer(dir, false)
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	appendFreezerBlocks(t, f, 0, 5)
+	// 模拟只写入了部分表的区块
+	f.tables[freezerHashTable].Append(5, freezerBlob(freezerHashTable, 5))
+	f.tables[freezerHeaderTable].Append(5, freezerBlob(freezerHeaderTable, 5))
+	f.Close()
+
+	if f, err = newFreezer(dir, false); err != nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	defer f.Close()
+	checkFreezerBlocks(t, f, 5)
+	for _, table := range freezerTables {
+		if items := f.tables[table.name].Items(); items != 5 {
+			t.Fatalf("table %s items mismatch: have %d, want %d", table.name, items, 5)
+		}
+	}
+}
+
+// Tests that a read-only freezer serves the frozen blocks but never touches the
+// files on disk.
+func TestFreezerReadOnly(t *testing.T) {
+	dir := tempFreezerDir(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := newFreezer(filepath.Join(dir, "missing"), true); err == nil {
+		t.Fatalf("opened missing freezer read-only")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Fatalf("read-only open created the freezer directory")
+	}
+	f, err := newFreezer(dir, false)
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	appendFreezerBlocks(t, f, 0, 5)
+	f.tables[freezerHashTable].Append(5, freezerBlob(freezerHashTable, 5))
+	f.Close()
+
+	sizes := make(map[string]int64)
+	files, _ := ioutil.ReadDir(dir)
+	for _, file := range files {
+		sizes[file.Name()] = file.Size()
+	}
+	if f, err = newFreezer(dir, true); err != nil {
+		t.Fatalf("failed to open freezer read-only: %v", err)
+	}
+	checkFreezerBlocks(t, f, 5)
+
+	if err := f.AppendAncient(5, nil, nil, nil, nil, nil); err != errReadOnly {
+		t.Fatalf("append to read-only freezer: have %v, want %v", err, errReadOnly)
+	}
+	if err := f.TruncateAncients(2); err != errReadOnly {
+		t.Fatalf("truncate read-only freezer: have %v, want %v", err, errReadOnly)
+	}
+	f.Close()
+
+	files, _ = ioutil.ReadDir(dir)
+	for _, file := range files {
+		if file.Size() != sizes[file.Name()] {
+			t.Fatalf("read-only freezer changed %s: have %d bytes, want %d", file.Name(), file.Size(), sizes[file.Name()])
+		}
+	}
+}
+
+// Tests that the rawdb accessors read frozen blocks from the freezer once they
+// are moved out of the key-value store.
+func TestFreezerDatabase(t *testing.T) {
+	dir := tempFreezerDir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabaseWithFreezer(mandb.NewMemDatabase(), dir)
+	if err != nil {
+		t.Fatalf("failed to open freezer database: %v", err)
+	}
+	defer db.Close()
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0), Extra: []byte("frozen block")})
+	WriteBlock(db, block)
+	WriteCanonicalHash(db, block.Hash(), 0)
+	WriteTd(db, block.Hash(), 0, big.NewInt(7))
+
+	frdb := db.(*freezerdb)
+	hash, err := frdb.freezeBlock(KeyValueStore(db), 0)
+	if err != nil {
+		t.Fatalf("failed to freeze block: %v", err)
+	}
+	deleteFrozenBlock(KeyValueStore(db), hash, 0)
+
+	if ok, _ := KeyValueStore(db).Has(headerKey(hash, 0)); ok {
+		t.Fatalf("frozen header left in the key-value store")
+	}
+	if header := ReadHeader(db, hash, 0); header == nil || header.Hash() != hash {
+		t.Fatalf("frozen header mismatch: have %v, want %x", header, hash)
+	}
+	if !HasBody(db, hash, 0) {
+		t.Fatalf("frozen body missing")
+	}
+	if td := ReadTd(db, hash, 0); td == nil || td.Cmp(big.NewInt(7)) != 0 {
+		t.Fatalf("frozen td mismatch: have %v, want %d", td, 7)
+	}
+	if frozen, err := RepairFreezer(filepath.Join(dir, "missing")); err != nil || frozen != 0 {
+		t.Fatalf("repair of empty freezer: have %d, %v", frozen, err)
+	}
+}
+
+// Tests that a database wrapping a read-only freezer reads the frozen blocks but
+// refuses to start migrating new ones into it.
+func TestFreezerReadOnlyDatabase(t *testing.T) {
+	dir := tempFreezerDir(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := NewDatabaseWithReadOnlyFreezer(mandb.NewMemDatabase(), filepath.Join(dir, "missing")); err == nil {
+		t.Fatalf("opened missing freezer database read-only")
+	}
+	db, err := NewDatabaseWithFreezer(mandb.NewMemDatabase(), dir)
+	if err != nil {
+		t.Fatalf("failed to open freezer database: %v", err)
+	}
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0), Extra: []byte("frozen block")})
+	WriteBlock(db, block)
+	WriteCanonicalHash(db, block.Hash(), 0)
+	WriteTd(db, block.Hash(), 0, big.NewInt(7))
+	if _, err := db.(*freezerdb).freezeBlock(KeyValueStore(db), 0); err != nil {
+		t.Fatalf("failed to freeze block: %v", err)
+	}
+	db.Close()
+
+	if db, err = NewDatabaseWithReadOnlyFreezer(mandb.NewMemDatabase(), dir); err != nil {
+		t.Fatalf("failed to open freezer database read-only: %v", err)
+	}
+	defer db.Close()
+
+	if header := ReadHeader(db, block.Hash(), 0); header == nil || header.Hash() != block.Hash() {
+		t.Fatalf("frozen header mismatch: have %v, want %x", header, block.Hash())
+	}
+	if err := StartFreezing(db, func() uint64 { return 0 }); err != errReadOnly {
+		t.Fatalf("freezing into read-only database: have %v, want %v", err, errReadOnly)
+	}
+	if err := StartFreezing(mandb.NewMemDatabase(), func() uint64 { return 0 }); err != errNoFreezer {
+		t.Fatalf("freezing without ancient store: have %v, want %v", err, errNoFreezer)
+	}
+}
@@ -29,6 +29,9 @@ var (
 	// fastTrieProgressKey tracks the number of trie entries imported during fast sync.
 	fastTrieProgressKey = []byte("TrieSync")
 
+	// headAddrIndexKey tracks the number of the last block added to the address index.
+	headAddrIndexKey = []byte("LastAddrIndex")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -41,11 +44,17 @@ var (
 	txLookupPrefix  = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 
+	addrIndexPrefix        = []byte("a") // addrIndexPrefix + address + currency (8 bytes) [+ index (uint64 big endian)] -> entry count / address index entry
+	addrIndexJournalPrefix = []byte("j") // addrIndexJournalPrefix + num (uint64 big endian) -> address index entries added by the block
+
+	consensusTimelinePrefix = []byte("ctl-") // consensusTimelinePrefix + num (uint64 big endian) -> consensus timeline of the height
+
 	preimagePrefix = []byte("secure-key-")    // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("matrix-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	AddrIndexPrefix      = []byte("iA") // AddrIndexPrefix is the data table of the address index chain indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -69,6 +78,21 @@ func encodeBlockNumber(number uint64) []byte {
 	return enc
 }
 
+// headerKey = headerPrefix + num (uint64 big endian) + hash
+func headerKey(hash common.Hash, number uint64) []byte {
+	return append(append(append([]byte{}, headerPrefix...), encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// headerTDKey = headerPrefix + num (uint64 big endian) + hash + headerTDSuffix
+func headerTDKey(hash common.Hash, number uint64) []byte {
+	return append(headerKey(hash, number), headerTDSuffix...)
+}
+
+// blockReceiptsKey = blockReceiptsPrefix + num (uint64 big endian) + hash
+func blockReceiptsKey(hash common.Hash, number uint64) []byte {
+	return append(append(append([]byte{}, blockReceiptsPrefix...), encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 type SuperBlockIndexData struct {
 	Num uint64
 	Seq uint64
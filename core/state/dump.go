@@ -11,11 +11,13 @@ import (
 	"math/big"
 
 	"bytes"
+	"sort"
 	"strconv"
 
 	"github.com/MatrixAINetwork/go-matrix/base58"
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/params"
 	"github.com/MatrixAINetwork/go-matrix/rlp"
 	"github.com/MatrixAINetwork/go-matrix/trie"
 )
@@ -288,3 +290,91 @@ func (dbDump *DumpDB) PrintAccountMsg() {
 	}
 
 }
+
+// DumpDiff 两份状态导出之间的一项差异
+type DumpDiff struct {
+	Kind  string `json:"kind"` // "account" 或 "matrix"
+	Key   string `json:"key"`  // 账户地址或matrix state key
+	Field string `json:"field,omitempty"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+func (d DumpDiff) String() string {
+	if d.Field == "" {
+		return fmt.Sprintf("%s %s: %s -> %s", d.Kind, d.Key, d.Old, d.New)
+	}
+	return fmt.Sprintf("%s %s %s: %s -> %s", d.Kind, d.Key, d.Field, d.Old, d.New)
+}
+
+// DiffDump 按账户和matrix state key比较两份状态导出, 结果按key排序
+func DiffDump(prev, next Dump) []DumpDiff {
+	diffs := make([]DumpDiff, 0)
+
+	for _, addr := range unionKeys(accountKeys(prev.Accounts), accountKeys(next.Accounts)) {
+		oldAcc, oldOk := prev.Accounts[addr]
+		newAcc, newOk := next.Accounts[addr]
+		switch {
+		case !oldOk:
+			// 新建的账户, 继续与新建时的空账户比较nonce、代码和存储
+			diffs = append(diffs, DumpDiff{Kind: "account", Key: addr, Old: "<missing>", New: newAcc.Balance})
+			oldAcc = DumpAccount{Balance: newAcc.Balance, Nonce: params.NonceAddOne, CodeHash: common.Bytes2Hex(emptyCodeHash)}
+		case !newOk:
+			diffs = append(diffs, DumpDiff{Kind: "account", Key: addr, Old: oldAcc.Balance, New: "<missing>"})
+			continue
+		}
+		if oldAcc.Balance != newAcc.Balance {
+			diffs = append(diffs, DumpDiff{Kind: "account", Key: addr, Field: "balance", Old: oldAcc.Balance, New: newAcc.Balance})
+		}
+		if oldAcc.Nonce != newAcc.Nonce {
+			diffs = append(diffs, DumpDiff{Kind: "account", Key: addr, Field: "nonce", Old: strconv.FormatUint(oldAcc.Nonce, 10), New: strconv.FormatUint(newAcc.Nonce, 10)})
+		}
+		if oldAcc.CodeHash != newAcc.CodeHash {
+			diffs = append(diffs, DumpDiff{Kind: "account", Key: addr, Field: "codeHash", Old: oldAcc.CodeHash, New: newAcc.CodeHash})
+		}
+		for _, key := range unionKeys(stringKeys(oldAcc.Storage), stringKeys(newAcc.Storage)) {
+			if oldAcc.Storage[key] != newAcc.Storage[key] {
+				diffs = append(diffs, DumpDiff{Kind: "account", Key: addr, Field: "storage:" + key, Old: oldAcc.Storage[key], New: newAcc.Storage[key]})
+			}
+		}
+	}
+
+	for _, key := range unionKeys(stringKeys(prev.MatrixData), stringKeys(next.MatrixData)) {
+		if prev.MatrixData[key] != next.MatrixData[key] {
+			diffs = append(diffs, DumpDiff{Kind: "matrix", Key: key, Old: prev.MatrixData[key], New: next.MatrixData[key]})
+		}
+	}
+	return diffs
+}
+
+func accountKeys(m map[string]DumpAccount) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func stringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func unionKeys(a, b []string) []string {
+	set := make(map[string]struct{}, len(a)+len(b))
+	for _, key := range a {
+		set[key] = struct{}{}
+	}
+	for _, key := range b {
+		set[key] = struct{}{}
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
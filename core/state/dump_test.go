@@ -0,0 +1,129 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package state
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+// nonce formats a dumped nonce, which carries the NonceAddOne flag.
+func nonce(n uint64) string {
+	return strconv.FormatUint(n|params.NonceAddOne, 10)
+}
+
+func TestDiffDump(t *testing.T) {
+	prev := Dump{
+		Accounts: map[string]DumpAccount{
+			"MAN.a": {Balance: "0:100", Nonce: 1, Storage: map[string]string{"01": "aa"}},
+			"MAN.b": {Balance: "0:5"},
+		},
+		MatrixData: map[string]string{"k1": "01", "k2": "02"},
+	}
+	next := Dump{
+		Accounts: map[string]DumpAccount{
+			"MAN.a": {Balance: "0:90", Nonce: 2, Storage: map[string]string{"01": "bb"}},
+			"MAN.c": {Balance: "0:10", Nonce: params.NonceAddOne, CodeHash: common.Bytes2Hex(emptyCodeHash), Storage: map[string]string{"02": "cc"}},
+		},
+		MatrixData: map[string]string{"k1": "01", "k2": "03", "k3": "04"},
+	}
+	want := []DumpDiff{
+		{Kind: "account", Key: "MAN.a", Field: "balance", Old: "0:100", New: "0:90"},
+		{Kind: "account", Key: "MAN.a", Field: "nonce", Old: "1", New: "2"},
+		{Kind: "account", Key: "MAN.a", Field: "storage:01", Old: "aa", New: "bb"},
+		{Kind: "account", Key: "MAN.b", Old: "0:5", New: "<missing>"},
+		{Kind: "account", Key: "MAN.c", Old: "<missing>", New: "0:10"},
+		{Kind: "account", Key: "MAN.c", Field: "storage:02", Old: "", New: "cc"},
+		{Kind: "matrix", Key: "k2", Old: "02", New: "03"},
+		{Kind: "matrix", Key: "k3", Old: "", New: "04"},
+	}
+	have := DiffDump(prev, next)
+	if len(have) != len(want) {
+		t.Fatalf("diff count mismatch: have %d, want %d: %v", len(have), len(want), have)
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Errorf("diff %d mismatch: have %v, want %v", i, have[i], want[i])
+		}
+	}
+	if diffs := DiffDump(prev, prev); len(diffs) != 0 {
+		t.Errorf("identical dumps reported diffs: %v", diffs)
+	}
+}
+
+func TestDiffDumpNewAccount(t *testing.T) {
+	emptyHash := common.Bytes2Hex(emptyCodeHash)
+	next := Dump{
+		Accounts: map[string]DumpAccount{
+			"MAN.a": {Balance: "0:0", Nonce: params.NonceAddOne, CodeHash: emptyHash},
+			"MAN.b": {Balance: "0:7", Nonce: 3 | params.NonceAddOne, CodeHash: "abcd", Storage: map[string]string{"01": "aa", "02": "bb"}},
+		},
+	}
+	// 新建账户只报告一次余额, nonce、代码和存储与空账户比较
+	want := []DumpDiff{
+		{Kind: "account", Key: "MAN.a", Old: "<missing>", New: "0:0"},
+		{Kind: "account", Key: "MAN.b", Old: "<missing>", New: "0:7"},
+		{Kind: "account", Key: "MAN.b", Field: "nonce", Old: nonce(0), New: nonce(3)},
+		{Kind: "account", Key: "MAN.b", Field: "codeHash", Old: emptyHash, New: "abcd"},
+		{Kind: "account", Key: "MAN.b", Field: "storage:01", Old: "", New: "aa"},
+		{Kind: "account", Key: "MAN.b", Field: "storage:02", Old: "", New: "bb"},
+	}
+	have := DiffDump(Dump{}, next)
+	if len(have) != len(want) {
+		t.Fatalf("diff count mismatch: have %d, want %d: %v", len(have), len(want), have)
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Errorf("diff %d mismatch: have %v, want %v", i, have[i], want[i])
+		}
+	}
+}
+
+func TestDiffDumpState(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(mandb.NewMemDatabase()))
+	addr1 := common.BytesToAddress([]byte{0x01})
+	state.AddBalance(common.MainAccount, addr1, big.NewInt(100))
+	state.Commit(false)
+	prev := state.RawDump()
+
+	// 新建一个普通账户和一个带代码、存储的合约账户
+	addr2 := common.BytesToAddress([]byte{0x02})
+	addr3 := common.BytesToAddress([]byte{0x03})
+	code := []byte{0x60, 0x00}
+	state.AddBalance(common.MainAccount, addr2, big.NewInt(5))
+	state.AddBalance(common.MainAccount, addr3, big.NewInt(1))
+	state.SetNonce(addr3, 1)
+	state.SetCode(addr3, code)
+	state.SetState(addr3, common.BytesToHash([]byte{0x01}), common.BytesToHash([]byte{0x2a}))
+	state.Commit(false)
+	next := state.RawDump()
+
+	key2 := base58.Base58EncodeToString("MAN", addr2)
+	key3 := base58.Base58EncodeToString("MAN", addr3)
+	// 新建的普通账户只报告余额
+	want := []DumpDiff{
+		{Kind: "account", Key: key2, Old: "<missing>", New: next.Accounts[key2].Balance},
+		{Kind: "account", Key: key3, Old: "<missing>", New: next.Accounts[key3].Balance},
+		{Kind: "account", Key: key3, Field: "nonce", Old: nonce(0), New: nonce(1)},
+		{Kind: "account", Key: key3, Field: "codeHash", Old: common.Bytes2Hex(emptyCodeHash), New: common.Bytes2Hex(crypto.Keccak256(code))},
+		{Kind: "account", Key: key3, Field: "storage:" + common.Bytes2Hex(common.BytesToHash([]byte{0x01}).Bytes()), Old: "", New: "2a"},
+	}
+	have := DiffDump(prev, next)
+	if len(have) != len(want) {
+		t.Fatalf("diff count mismatch: have %d, want %d: %v", len(have), len(want), have)
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Errorf("diff %d mismatch: have %v, want %v", i, have[i], want[i])
+		}
+	}
+}
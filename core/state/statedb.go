@@ -476,6 +476,57 @@ func (self *StateDB) GetIsEntrustByTime(entrustFrom common.Address, time uint64)
 	return false
 }
 
+//根据委托人from、授权人from和高度(或时间)获取生效的授权数据,用于校验细粒度权限.
+//isGas为true时取委托gas的授权,否则取委托签名的授权,尚未开始或已经到期的授权不生效
+func (self *StateDB) GetEntrustAuthData(entrustFrom common.Address, authFrom common.Address, height uint64, time uint64, isGas bool) *common.AuthType {
+	AuthMarsha1Data := self.GetAuthStateByteArray(entrustFrom)
+	if len(AuthMarsha1Data) == 0 {
+		return nil
+	}
+	AuthDataList := make([]common.AuthType, 0)
+	err := json.Unmarshal(AuthMarsha1Data, &AuthDataList)
+	if err != nil {
+		return nil
+	}
+	for i := range AuthDataList {
+		AuthData := &AuthDataList[i]
+		if !AuthData.AuthAddres.Equal(authFrom) || (isGas && !AuthData.IsEntrustGas) || (!isGas && !AuthData.IsEntrustSign) {
+			continue
+		}
+		if AuthData.EnstrustSetType == params.EntrustByTime {
+			if AuthData.StartTime <= time && AuthData.EndTime >= time {
+				return AuthData
+			}
+		} else if AuthData.StartHeight <= height && AuthData.EndHeight >= height {
+			return AuthData
+		}
+	}
+	return nil
+}
+
+func (self *StateDB) GetEntrustSpend(authFrom common.Address, entrustFrom common.Address) common.EntrustSpend {
+	hashkey := append([]byte("ES"), entrustFrom[:]...)
+	spend := common.EntrustSpend{Spent: new(big.Int)}
+	data := self.GetStateByteArray(authFrom, common.BytesToHash(hashkey[:]))
+	if len(data) == 0 {
+		return spend
+	}
+	if err := json.Unmarshal(data, &spend); err != nil || spend.Spent == nil {
+		return common.EntrustSpend{Spent: new(big.Int)}
+	}
+	return spend
+}
+
+func (self *StateDB) SetEntrustSpend(authFrom common.Address, entrustFrom common.Address, spend common.EntrustSpend) {
+	hashkey := append([]byte("ES"), entrustFrom[:]...)
+	data, err := json.Marshal(spend)
+	if err != nil {
+		log.Error("SetEntrustSpend Marshal err", "err", err)
+		return
+	}
+	self.SetStateByteArray(authFrom, common.BytesToHash(hashkey[:]), data)
+}
+
 //钱包调用显示
 func (self *StateDB) GetAllEntrustList(authFrom common.Address) []common.EntrustType {
 	//EntrustMarsha1Data := self.GetStateByteArray(authFrom, common.BytesToHash(authFrom[:]))
@@ -647,7 +698,8 @@ func (self *StateDB) UpdateTxForBtree(key uint32) {
 				log.Error("file statedb", "func UpdateTxForBtree,Unmarshal err", errRT)
 				continue
 			}
-			if rt.Typ != common.ExtraRevocable {
+			//担保交易与可撤销交易存放在同一个btree中，到期未处理的担保交易同样转给收款人
+			if rt.Typ != common.ExtraRevocable && rt.Typ != common.ExtraEscrowTxType {
 				log.Info("file statedb", "func UpdateTxForBtree,Type is", rt.Typ, "type should ", common.ExtraRevocable)
 				continue
 			}
@@ -673,6 +725,30 @@ func (self *StateDB) UpdateTxForBtree(key uint32) {
 		self.GetSaveTx(common.ExtraRevocable, item.Key_Time, delhashs, true)
 	}
 }
+//获取所有未到期的担保交易
+func (self *StateDB) GetAllEscrowList() map[common.Hash]common.EscrowTx {
+	escrowList := make(map[common.Hash]common.EscrowTx)
+	self.revocablebtrie.Ascend(func(a btrie.Item) bool {
+		item, ok := a.(btrie.SpcialTxData)
+		if !ok {
+			return true
+		}
+		for hash, tm := range item.Value_Tx {
+			var et common.EscrowTx
+			if err := json.Unmarshal(tm, &et); err != nil {
+				log.Error("file statedb", "func GetAllEscrowList,Unmarshal err", err)
+				continue
+			}
+			if et.Typ != common.ExtraEscrowTxType {
+				continue
+			}
+			escrowList[hash] = et
+		}
+		return true
+	})
+	return escrowList
+}
+
 func (self *StateDB) UpdateTxForBtreeBytime(key uint32) {
 	out := make([]btrie.Item, 0)
 	self.timebtrie.DescendLessOrEqual(btrie.SpcialTxData{Key_Time: key}, func(a btrie.Item) bool {
@@ -7,6 +7,7 @@ package state
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/big"
@@ -21,6 +22,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/params"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -425,3 +427,61 @@ func TestCopyOfCopy(t *testing.T) {
 	//	t.Fatalf("2nd copy fail, expected 42, got %v", got)
 	//}
 }
+
+// Tests that only entrust authorizations of the requested kind valid at the
+// given height or time are returned, expired ones must not apply any more.
+func TestGetEntrustAuthData(t *testing.T) {
+	sdb, _ := New(common.Hash{}, NewDatabase(mandb.NewMemDatabase()))
+	var (
+		entrust  = common.HexToAddress("0x01")
+		byHeight = common.HexToAddress("0x02")
+		byTime   = common.HexToAddress("0x03")
+		signOnly = common.HexToAddress("0x04")
+	)
+	auths := []common.AuthType{
+		{AuthAddres: byHeight, EnstrustSetType: params.EntrustByHeight, IsEntrustGas: true, StartHeight: 100, EndHeight: 200},
+		{AuthAddres: byHeight, EnstrustSetType: params.EntrustByHeight, IsEntrustGas: true, StartHeight: 300, EndHeight: 400, SpendPeriod: 10},
+		{AuthAddres: byTime, EnstrustSetType: params.EntrustByTime, IsEntrustGas: true, StartTime: 1000, EndTime: 2000},
+		{AuthAddres: signOnly, EnstrustSetType: params.EntrustByHeight, IsEntrustSign: true, StartHeight: 0, EndHeight: 1000},
+	}
+	data, err := json.Marshal(auths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sdb.SetAuthStateByteArray(entrust, data)
+
+	tests := []struct {
+		auth         common.Address
+		height, time uint64
+		isGas        bool
+		want         *common.AuthType
+	}{
+		{byHeight, 99, 1500, true, nil},
+		{byHeight, 100, 0, true, &auths[0]},
+		{byHeight, 200, 0, true, &auths[0]},
+		{byHeight, 201, 0, true, nil}, // 已到期, 下一次授权尚未开始
+		{byHeight, 350, 0, true, &auths[1]},
+		{byHeight, 401, 0, true, nil},
+		{byHeight, 150, 0, false, nil}, // 只委托了gas
+		{byTime, 150, 999, true, nil},
+		{byTime, 0, 1000, true, &auths[2]},
+		{byTime, 0, 2000, true, &auths[2]},
+		{byTime, 150, 2001, true, nil},
+		{signOnly, 500, 0, true, nil},
+		{signOnly, 500, 0, false, &auths[3]},
+		{signOnly, 1001, 0, false, nil},
+		{entrust, 150, 1500, true, nil},
+	}
+	for i, test := range tests {
+		have := sdb.GetEntrustAuthData(entrust, test.auth, test.height, test.time, test.isGas)
+		if test.want == nil {
+			if have != nil {
+				t.Errorf("test %d: expected no authorization, have %+v", i, have)
+			}
+			continue
+		}
+		if have == nil || !reflect.DeepEqual(*have, *test.want) {
+			t.Errorf("test %d: authorization mismatch: have %+v, want %+v", i, have, test.want)
+		}
+	}
+}
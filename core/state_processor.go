@@ -7,6 +7,7 @@ package core
 import (
 	"math/big"
 	"runtime"
+	"sort"
 	"sync"
 
 	"github.com/MatrixAINetwork/go-matrix/reward/util"
@@ -20,8 +21,11 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/core/vm"
 	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/reward"
 	"github.com/MatrixAINetwork/go-matrix/reward/blkreward"
 	"github.com/MatrixAINetwork/go-matrix/reward/interest"
 	"github.com/MatrixAINetwork/go-matrix/reward/lottery"
@@ -73,6 +77,59 @@ func (p *StateProcessor) getGas(state *state.StateDB, gas *big.Int) *big.Int {
 	}
 	return allGas
 }
+
+// Beta版本起各币种交易费奖励总额分别计算,币种gas费用按费率换算.交易费奖励账户余额先用于MAN,其余币种按币种顺序发放,余额不足的币种不发放
+func (p *StateProcessor) getCurrencyGas(state *state.StateDB, coinGasUse map[string]*big.Int) map[string]*big.Int {
+	allGas := make(map[string]*big.Int)
+	gasprice, err := matrixstate.GetTxpoolGasLimit(state)
+	if err != nil {
+		return allGas
+	}
+	balance := state.GetBalance(common.TxGasRewardAddress)
+	if len(balance) == 0 {
+		log.WARN("奖励", "交易费奖励账户余额不合法", "")
+		return allGas
+	}
+	remain := new(big.Int).Set(balance[common.MainAccount].Balance)
+
+	currencies := make([]string, 0, len(coinGasUse))
+	for coin := range coinGasUse {
+		//广播交易不带币种,不收取交易费
+		if coin != "" && coin != "MAN" {
+			currencies = append(currencies, coin)
+		}
+	}
+	sort.Strings(currencies)
+	if _, ok := coinGasUse["MAN"]; ok {
+		currencies = append([]string{"MAN"}, currencies...)
+	}
+	for _, coin := range currencies {
+		coinGas := new(big.Int).Mul(coinGasUse[coin], CurrencyGasPrice(state, coin, gasprice))
+		log.INFO("奖励", "币种", coin, "交易费奖励总额", coinGas.String())
+		if remain.Sign() <= 0 || remain.Cmp(coinGas) < 0 {
+			log.WARN("奖励", "交易费奖励账户余额不合法，余额", remain, "币种", coin)
+			continue
+		}
+		remain.Sub(remain, coinGas)
+		allGas[coin] = coinGas
+	}
+	return allGas
+}
+
+// 其他币种交易费奖励,上一矿工的奖励金额从状态树读取,本区块矿工的奖励金额写回状态树
+func (p *StateProcessor) processCurrencyTxsRewards(st *state.StateDB, txsReward reward.Reward, header *types.Header, fees map[string]*big.Int) []common.RewarTx {
+	preRewards, err := matrixstate.GetPreMinerMultiCoinTxsReward(st)
+	if err != nil {
+		log.Error("奖励", "获取其他币种上一矿工交易奖励错误", err)
+		return nil
+	}
+	rewardList, newPreRewards := txsreward.CalcCurrencyRewards(txsReward, fees, preRewards, header.Leader, header.Number.Uint64(), header.ParentHash)
+	if err := matrixstate.SetPreMinerMultiCoinTxsReward(st, newPreRewards); err != nil {
+		log.Error("奖励", "设置其他币种上一矿工交易奖励错误", err)
+	}
+	return rewardList
+}
+
 func (env *StateProcessor) reverse(s []common.RewarTx) []common.RewarTx {
 	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
 		s[i], s[j] = s[j], s[i]
@@ -80,7 +137,7 @@ func (env *StateProcessor) reverse(s []common.RewarTx) []common.RewarTx {
 	return s
 }
 
-func (p *StateProcessor) ProcessReward(st *state.StateDB, header *types.Header, upTime map[common.Address]uint64, account []common.Address, usedGas uint64) []common.RewarTx {
+func (p *StateProcessor) ProcessReward(st *state.StateDB, header *types.Header, upTime map[common.Address]uint64, account []common.Address, usedGas uint64, coinGasUse map[string]*big.Int) []common.RewarTx {
 	bcInterval, err := matrixstate.GetBroadcastInterval(st)
 	if err != nil {
 		log.Error("奖励", "获取广播周期失败", err)
@@ -115,13 +172,28 @@ func (p *StateProcessor) ProcessReward(st *state.StateDB, header *types.Header,
 		}
 	}
 
-	allGas := p.getGas(st, new(big.Int).SetUint64(usedGas))
+	//Beta版本起交易费按币种分别发放
+	currencyFee := manparams.IsBetaVersion(matrixstate.GetVersionInfo(st))
+	var allGas *big.Int
+	var coinGas map[string]*big.Int
+	if currencyFee {
+		coinGas = p.getCurrencyGas(st, coinGasUse)
+		allGas = coinGas["MAN"]
+		if allGas == nil {
+			allGas = big.NewInt(0)
+		}
+	} else {
+		allGas = p.getGas(st, new(big.Int).SetUint64(usedGas))
+	}
 	txsReward := txsreward.New(p.bc, st, preState)
 	if nil != txsReward {
 		txsRewardMap := txsReward.CalcNodesRewards(allGas, header.Leader, header.Number.Uint64(), header.ParentHash)
 		if 0 != len(txsRewardMap) {
 			rewardList = append(rewardList, common.RewarTx{CoinType: "MAN", Fromaddr: common.TxGasRewardAddress, To_Amont: txsRewardMap, RewardTyp: common.RewardTxsType})
 		}
+		if currencyFee {
+			rewardList = append(rewardList, p.processCurrencyTxsRewards(st, txsReward, header, coinGas)...)
+		}
 	}
 	lottery := lottery.New(p.bc, st, p.random, preState)
 	if nil != lottery {
@@ -171,7 +243,7 @@ func (p *StateProcessor) ProcessSuperBlk(block *types.Block, statedb *state.Stat
 
 	root := statedb.IntermediateRoot(p.bc.chainConfig.IsEIP158(block.Number()))
 	if root != block.Root() {
-		return errors.Errorf("invalid super block root (remote: %x local: %x)", block.Root, root)
+		return errors.Errorf("invalid super block root (remote: %x local: %x)", block.Root(), root)
 	}
 	return nil
 }
@@ -198,6 +270,7 @@ func (p *StateProcessor) ProcessTxs(block *types.Block, statedb *state.StateDB,
 	// Iterate over and process the individual transactions
 	statedb.UpdateTxForBtree(uint32(block.Time().Uint64()))
 	statedb.UpdateTxForBtreeBytime(uint32(block.Time().Uint64()))
+	depoistInfo.CommitSignRotations(statedb, block.NumberU64())
 	stxs := make([]types.SelfTransaction, 0)
 	var txcount int
 	txs := block.Transactions()
@@ -224,6 +297,7 @@ func (p *StateProcessor) ProcessTxs(block *types.Block, statedb *state.StateDB,
 	}
 	waitG.Wait()
 	from := make([]common.Address, 0)
+	coinGasUse := make(map[string]*big.Int)
 	for i, tx := range txs[normalTxindex:] {
 		if tx.GetMatrixType() == common.ExtraUnGasMinerTxType || tx.GetMatrixType() == common.ExtraUnGasValidatorTxType ||
 			tx.GetMatrixType() == common.ExtraUnGasInterestTxType || tx.GetMatrixType() == common.ExtraUnGasTxsType || tx.GetMatrixType() == common.ExtraUnGasLotteryTxType {
@@ -263,8 +337,14 @@ func (p *StateProcessor) ProcessTxs(block *types.Block, statedb *state.StateDB,
 		allLogs = append(allLogs, receipt.Logs...)
 		txcount = i
 		from = append(from, tx.From())
+		coinGas, ok := coinGasUse[tx.GetTxCurrency()]
+		if !ok {
+			coinGas = new(big.Int)
+			coinGasUse[tx.GetTxCurrency()] = coinGas
+		}
+		coinGas.Add(coinGas, new(big.Int).SetUint64(receipt.GasUsed))
 	}
-	p.ProcessReward(statedb, block.Header(), upTime, from, *usedGas)
+	p.ProcessReward(statedb, block.Header(), upTime, from, *usedGas, coinGasUse)
 
 	for _, tx := range stxs {
 		statedb.Prepare(tx.Hash(), block.Hash(), txcount+1)
@@ -330,7 +410,11 @@ func (p *StateProcessor) Process(block *types.Block, parent *types.Block, stated
 // for the transaction, gas used and an error if the transaction failed,
 // indicating the block was invalid.
 func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, tx types.SelfTransaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, uint64, error) {
-	if !BlackListFilter(tx, statedb) {
+	var blacklist *Blacklist
+	if chain, ok := bc.(*BlockChain); ok && chain != nil {
+		blacklist = chain.BlackList()
+	}
+	if !BlackListFilter(tx, statedb, blacklist) {
 		return nil, 0, errors.New("blacklist account")
 	}
 
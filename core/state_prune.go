@@ -0,0 +1,235 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+	"github.com/MatrixAINetwork/go-matrix/trie"
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+var emptyCodeHash = crypto.Keccak256Hash(nil)
+
+// triesInMemory returns the number of recent block states kept in memory.
+func (bc *BlockChain) triesInMemory() uint64 {
+	if bc.cacheConfig.TriesInMemory > 0 {
+		return bc.cacheConfig.TriesInMemory
+	}
+	return triesInMemory
+}
+
+// isConsensusRoot reports whether the state of the block is read again by the
+// consensus after it left the in-memory window and so must not be pruned.
+func (bc *BlockChain) isConsensusRoot(block *types.Block, st *state.StateDB) bool {
+	bcInterval, err := matrixstate.GetBroadcastInterval(st)
+	if err != nil {
+		log.Warn("Failed to read broadcast interval, keeping state", "number", block.NumberU64(), "err", err)
+		return true
+	}
+	return IsConsensusRoot(block.NumberU64(), block.IsSuperBlock(), bcInterval)
+}
+
+// IsConsensusRoot reports whether the state root of the block at number has to
+// be kept by a pruning node. Besides the genesis and the super blocks these are
+// the broadcast blocks, whose roots the random seeds and slashing are derived
+// from, and the blocks two below a broadcast block, whose states the uptime and
+// reward calculation read.
+func IsConsensusRoot(number uint64, superBlock bool, bcInterval *mc.BCIntervalInfo) bool {
+	if number == 0 || superBlock {
+		return true
+	}
+	if bcInterval == nil || bcInterval.BCInterval == 0 {
+		return true
+	}
+	if number >= bcInterval.LastBCNumber && bcInterval.IsBroadcastNumber(number) {
+		return true
+	}
+	return number+2 >= bcInterval.LastBCNumber && bcInterval.IsBroadcastNumber(number+2)
+}
+
+// PruneStats is the outcome of an offline state prune.
+type PruneStats struct {
+	Roots   int // state roots kept
+	Nodes   int // trie nodes and contract codes kept
+	Deleted int // trie nodes and contract codes deleted
+	Size    common.StorageSize
+	Elapsed time.Duration
+}
+
+// PruneState deletes, from a stopped node's database, every state trie node
+// that is not reachable from the states of the last recent canonical blocks or
+// from a root the consensus needs (see IsConsensusRoot). Matrix data and btrie
+// nodes are leaves of the state trie and are kept with the roots referring to
+// them. Roots already missing from the database are skipped.
+func PruneState(db mandb.Database, recent uint64) (*PruneStats, error) {
+	disk, ok := rawdb.KeyValueStore(db).(*mandb.LDBDatabase)
+	if !ok {
+		return nil, errors.New("state pruning needs a leveldb database")
+	}
+	start := time.Now()
+	head := rawdb.ReadHeadBlockHash(db)
+	headNumber := rawdb.ReadHeaderNumber(db, head)
+	if headNumber == nil {
+		return nil, errors.New("head block not found")
+	}
+	sdb := state.NewDatabase(db)
+
+	// 标记需要保留的状态树节点
+	var (
+		stats  = new(PruneStats)
+		marked = make(map[common.Hash]struct{})
+		logged = time.Now()
+	)
+	for number := uint64(0); number <= *headNumber; number++ {
+		hash := rawdb.ReadCanonicalHash(db, number)
+		header := rawdb.ReadHeader(db, hash, number)
+		if header == nil {
+			return nil, errors.Errorf("canonical header #%d not found", number)
+		}
+		if ok, _ := db.Has(header.Root[:]); !ok {
+			continue
+		}
+		if number+recent <= *headNumber {
+			st, err := state.New(header.Root, sdb)
+			if err != nil {
+				continue
+			}
+			bcInterval, err := matrixstate.GetBroadcastInterval(st)
+			if err != nil {
+				bcInterval = nil
+			}
+			if !IsConsensusRoot(number, header.IsSuperHeader(), bcInterval) {
+				continue
+			}
+		}
+		if err := markState(sdb.TrieDB(), header.Root, marked); err != nil {
+			return nil, errors.Errorf("failed to mark state #%d %x: %v", number, header.Root, err)
+		}
+		stats.Roots++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Marking state roots", "number", number, "head", *headNumber, "roots", stats.Roots, "nodes", len(marked))
+			logged = time.Now()
+		}
+	}
+	stats.Nodes = len(marked)
+
+	// 删除未被标记的节点, 状态节点和合约代码以内容的hash为key
+	batch := new(leveldb.Batch)
+	it := disk.NewIterator()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != common.HashLength {
+			continue
+		}
+		if _, ok := marked[common.BytesToHash(key)]; ok {
+			continue
+		}
+		value := it.Value()
+		if !bytes.Equal(crypto.Keccak256(value), key) {
+			continue
+		}
+		batch.Delete(common.CopyBytes(key))
+		stats.Deleted++
+		stats.Size += common.StorageSize(len(key) + len(value))
+		if batch.Len() >= mandb.IdealBatchSize/common.HashLength {
+			if err := disk.LDB().Write(batch, nil); err != nil {
+				it.Release()
+				return nil, err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Deleting state nodes", "deleted", stats.Deleted, "size", stats.Size)
+			logged = time.Now()
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	if err := disk.LDB().Write(batch, nil); err != nil {
+		return nil, err
+	}
+	stats.Elapsed = time.Since(start)
+	return stats, nil
+}
+
+// markState marks the nodes of the state trie at root, together with the
+// storage tries and codes of its accounts. Sub-tries marked before are shared
+// with a previous root and are not walked again.
+func markState(triedb *trie.Database, root common.Hash, marked map[common.Hash]struct{}) error {
+	tr, err := trie.NewSecure(root, triedb, 0)
+	if err != nil {
+		return err
+	}
+	it := tr.NodeIterator(nil)
+	for descend := true; it.Next(descend); {
+		descend = true
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			if _, ok := marked[hash]; ok {
+				descend = false
+				continue
+			}
+			marked[hash] = struct{}{}
+		}
+		if !it.Leaf() {
+			continue
+		}
+		// 以"MAN-"为前缀的叶子是矩阵数据(含btrie节点), 不再引用其他节点
+		blob := it.LeafBlob()
+		if bytes.HasPrefix(blob, []byte("MAN-")) {
+			continue
+		}
+		var account state.Account
+		if err := rlp.DecodeBytes(blob, &account); err != nil {
+			continue
+		}
+		if account.Root != types.EmptyRootHash && account.Root != (common.Hash{}) {
+			if err := markTrie(triedb, account.Root, marked); err != nil {
+				return err
+			}
+		}
+		if codeHash := common.BytesToHash(account.CodeHash); len(account.CodeHash) > 0 && codeHash != emptyCodeHash {
+			marked[codeHash] = struct{}{}
+		}
+	}
+	return it.Error()
+}
+
+// markTrie marks the nodes of a storage trie.
+func markTrie(triedb *trie.Database, root common.Hash, marked map[common.Hash]struct{}) error {
+	if _, ok := marked[root]; ok {
+		return nil
+	}
+	tr, err := trie.New(root, triedb)
+	if err != nil {
+		return err
+	}
+	it := tr.NodeIterator(nil)
+	for descend := true; it.Next(descend); {
+		descend = true
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			if _, ok := marked[hash]; ok {
+				descend = false
+				continue
+			}
+			marked[hash] = struct{}{}
+		}
+	}
+	return it.Error()
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+)
+
+func TestIsConsensusRoot(t *testing.T) {
+	interval := &mc.BCIntervalInfo{LastBCNumber: 100, BCInterval: 10}
+	tests := []struct {
+		number     uint64
+		superBlock bool
+		interval   *mc.BCIntervalInfo
+		want       bool
+	}{
+		{0, false, interval, true}, // 创世区块
+		{55, true, interval, true}, // 超级区块
+		{55, false, nil, true},     // 广播周期未知时保留
+		{55, false, &mc.BCIntervalInfo{}, true},
+		{100, false, interval, true}, // 广播区块
+		{120, false, interval, true},
+		{118, false, interval, true}, // 广播区块前两个区块
+		{98, false, interval, true},
+		{119, false, interval, false},
+		{121, false, interval, false},
+		{90, false, interval, false}, // 早于最后的广播周期切换
+		{88, false, interval, false},
+	}
+	for _, test := range tests {
+		if have := IsConsensusRoot(test.number, test.superBlock, test.interval); have != test.want {
+			t.Errorf("block #%d super %v interval %v: have %v, want %v", test.number, test.superBlock, test.interval, have, test.want)
+		}
+	}
+}
+
+func TestPruneState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "man-state-prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := mandb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// 每个区块修改账户余额和合约存储, 广播周期为4
+	var (
+		sdb      = state.NewDatabase(db)
+		account  = common.HexToAddress("0x01")
+		contract = common.HexToAddress("0x02")
+		code     = []byte{0x60, 0x01, 0x50}
+		roots    []common.Hash
+		parent   common.Hash
+	)
+	for number := uint64(0); number <= 8; number++ {
+		var prev common.Hash
+		if number > 0 {
+			prev = roots[number-1]
+		}
+		st, _ := state.New(prev, sdb)
+		if number == 0 {
+			matrixstate.SetVersionInfo(st, manparams.VersionAlpha)
+			matrixstate.SetBroadcastInterval(st, &mc.BCIntervalInfo{BCInterval: 4})
+			st.SetCode(contract, code)
+		}
+		st.AddBalance(common.MainAccount, account, big.NewInt(1))
+		st.SetState(contract, common.BigToHash(new(big.Int).SetUint64(number)), common.BigToHash(new(big.Int).SetUint64(number+1)))
+		root, err := st.Commit(false)
+		if err != nil {
+			t.Fatalf("block #%d: failed to commit state: %v", number, err)
+		}
+		if err := sdb.TrieDB().Commit(root, false); err != nil {
+			t.Fatalf("block #%d: failed to write state: %v", number, err)
+		}
+		header := &types.Header{Number: new(big.Int).SetUint64(number), ParentHash: parent, Root: root}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), number)
+		rawdb.WriteHeadBlockHash(db, header.Hash())
+		roots, parent = append(roots, root), header.Hash()
+	}
+	// 不是状态节点的32字节key不能被删除
+	junkKey := crypto.Keccak256Hash([]byte("junk"))
+	db.Put(junkKey[:], []byte("not the preimage"))
+
+	stats, err := PruneState(db, 2)
+	if err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	// 保留创世区块, 广播区块4, 广播区块前的2和6, 以及最近的7和8
+	kept := map[uint64]bool{0: true, 2: true, 4: true, 6: true, 7: true, 8: true}
+	if stats.Roots != len(kept) {
+		t.Errorf("kept roots mismatch: have %d, want %d", stats.Roots, len(kept))
+	}
+	if stats.Deleted == 0 || stats.Nodes == 0 {
+		t.Errorf("nothing pruned: %+v", stats)
+	}
+	for number, root := range roots {
+		has, _ := db.Has(root[:])
+		if !kept[uint64(number)] {
+			if has {
+				t.Errorf("block #%d: pruned root still present", number)
+			}
+			continue
+		}
+		if !has {
+			t.Errorf("block #%d: kept root missing", number)
+			continue
+		}
+		// 保留的状态必须完整可读
+		st, err := state.New(root, state.NewDatabase(db))
+		if err != nil {
+			t.Fatalf("block #%d: failed to open state: %v", number, err)
+		}
+		if have := st.GetBalanceByType(account, common.MainAccount); have.Uint64() != uint64(number)+1 {
+			t.Errorf("block #%d: balance mismatch: have %v, want %d", number, have, number+1)
+		}
+		for i := uint64(0); i <= uint64(number); i++ {
+			if have := st.GetState(contract, common.BigToHash(new(big.Int).SetUint64(i))); have != common.BigToHash(new(big.Int).SetUint64(i+1)) {
+				t.Errorf("block #%d: storage slot %d mismatch: have %x", number, i, have)
+			}
+		}
+		if have := st.GetCode(contract); string(have) != string(code) {
+			t.Errorf("block #%d: code mismatch: have %x, want %x", number, have, code)
+		}
+		if interval, err := matrixstate.GetBroadcastInterval(st); err != nil || interval.BCInterval != 4 {
+			t.Errorf("block #%d: broadcast interval mismatch: have %v, err %v", number, interval, err)
+		}
+	}
+	if value, err := db.Get(junkKey[:]); err != nil || string(value) != "not the preimage" {
+		t.Errorf("non state entry deleted: %q, %v", value, err)
+	}
+	if _, err := PruneState(mandb.NewMemDatabase(), 2); err == nil {
+		t.Errorf("pruned a memory database")
+	}
+}
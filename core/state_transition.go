@@ -16,8 +16,10 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/core/txinterface"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
 )
 
 var (
@@ -34,6 +36,111 @@ type StateTransition struct {
 	data       []byte
 	state      vm.StateDB
 	evm        *vm.EVM
+
+	entrustAuth *common.AuthType //委托交易生效的授权数据
+}
+
+//细粒度委托权限需要用到的状态接口
+type entrustStateDB interface {
+	GetAuthFrom(entrustFrom common.Address, height uint64) common.Address
+	GetEntrustAuthData(entrustFrom common.Address, authFrom common.Address, height uint64, time uint64, isGas bool) *common.AuthType
+	GetEntrustSpend(authFrom common.Address, entrustFrom common.Address) common.EntrustSpend
+	SetEntrustSpend(authFrom common.Address, entrustFrom common.Address, spend common.EntrustSpend)
+}
+
+//获取交易生效的委托授权数据:委托gas的交易取授权人代付gas的授权,
+//其他交易取发送人作为被委托签名人的授权,没有委托签名时返回nil
+func entrustAuthData(statedb entrustStateDB, tx types.SelfTransaction, height uint64, time uint64) *common.AuthType {
+	if tx.GetIsEntrustGas() {
+		return statedb.GetEntrustAuthData(tx.From(), tx.AmontFrom(), height, time, true)
+	}
+	authFrom := statedb.GetAuthFrom(tx.From(), height)
+	if authFrom == (common.Address{}) {
+		return nil
+	}
+	return statedb.GetEntrustAuthData(tx.From(), authFrom, height, time, false)
+}
+
+//交易及一对多收款人的转账总额
+func entrustAmount(tx types.SelfTransaction) *big.Int {
+	amount := new(big.Int).Set(tx.Value())
+	if ex := tx.GetMatrix_EX(); len(ex) > 0 {
+		for _, to := range ex[0].ExtraTo {
+			amount.Add(amount, to.Amount)
+		}
+	}
+	return amount
+}
+
+//校验委托交易(委托gas或委托签名)是否满足授权人设置的细粒度权限,返回生效的授权数据,
+//不是委托交易时返回nil. cost为本次需支付的gas费用,与转账金额一起计入限额
+func CheckEntrustPermission(statedb entrustStateDB, tx types.SelfTransaction, height uint64, time uint64, cost *big.Int) (*common.AuthType, error) {
+	authData := entrustAuthData(statedb, tx, height, time)
+	if authData == nil {
+		if tx.GetIsEntrustGas() {
+			return nil, ErrWithoutAuth
+		}
+		return nil, nil
+	}
+	if !authData.AllowTxType(tx.GetMatrixType()) {
+		return nil, ErrEntrustTxType
+	}
+	if !authData.AllowCurrency(tx.GetTxCurrency()) {
+		return nil, ErrEntrustCurrency
+	}
+	if len(authData.AllowedTo) > 0 {
+		if tx.To() == nil || !authData.AllowRecipient(*tx.To()) {
+			return nil, ErrEntrustRecipient
+		}
+		if ex := tx.GetMatrix_EX(); len(ex) > 0 {
+			for _, to := range ex[0].ExtraTo {
+				if to.Recipient == nil || !authData.AllowRecipient(*to.Recipient) {
+					return nil, ErrEntrustRecipient
+				}
+			}
+		}
+	}
+	if authData.SpendLimit != nil {
+		spent := new(big.Int)
+		spend := statedb.GetEntrustSpend(authData.AuthAddres, tx.From())
+		if spend.Period == entrustSpendPeriod(authData, height, time) {
+			spent.Set(spend.Spent)
+		}
+		spent.Add(spent, cost).Add(spent, entrustAmount(tx))
+		if spent.Cmp(authData.SpendLimit) > 0 {
+			return nil, ErrEntrustSpendLimit
+		}
+	}
+	return authData, nil
+}
+
+//按委托方式取高度或时间计算当前限额周期的序号
+func entrustSpendPeriod(authData *common.AuthType, height uint64, time uint64) uint64 {
+	if authData.EnstrustSetType == params.EntrustByTime {
+		return authData.SpendPeriodIndex(authData.StartTime, time)
+	}
+	return authData.SpendPeriodIndex(authData.StartHeight, height)
+}
+
+//累加被委托人当前周期花费的费用,amount为负数表示退还
+func (st *StateTransition) addEntrustSpend(amount *big.Int) {
+	if st.entrustAuth == nil || st.entrustAuth.SpendLimit == nil {
+		return
+	}
+	statedb, ok := st.state.(entrustStateDB)
+	if !ok {
+		return
+	}
+	period := entrustSpendPeriod(st.entrustAuth, st.evm.BlockNumber.Uint64(), st.evm.Time.Uint64())
+	spend := statedb.GetEntrustSpend(st.entrustAuth.AuthAddres, st.msg.From())
+	if spend.Period != period {
+		spend = common.EntrustSpend{Period: period, Spent: new(big.Int)}
+	}
+	spend.Spent.Add(spend.Spent, amount)
+	if spend.Spent.Sign() < 0 {
+		spend.Spent.SetUint64(0)
+	}
+	statedb.SetEntrustSpend(st.entrustAuth.AuthAddres, st.msg.From(), spend)
 }
 
 // IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
@@ -72,6 +179,10 @@ func NewStateTransition(evm *vm.EVM, msg txinterface.Message, gp *GasPool) *Stat
 	if err != nil {
 		//return errors.New("get txpool gasPrice err")
 	}
+	//设置了gas费率的币种,gas价格按费率换算为该币种
+	if tx, ok := msg.(types.SelfTransaction); ok {
+		gasprice = CurrencyGasPrice(evm.StateDB, tx.GetTxCurrency(), gasprice)
+	}
 	return &StateTransition{
 		gp:       gp,
 		evm:      evm,
@@ -110,6 +221,19 @@ func (st *StateTransition) BuyGas() error {
 			break
 		}
 	}
+	//细粒度委托权限从VersionBeta开始生效
+	spend := mgval
+	if tx, ok := st.msg.(types.SelfTransaction); ok && manparams.IsBetaVersion(matrixstate.GetVersionInfo(st.state)) {
+		statedb, ok := st.state.(entrustStateDB)
+		if ok {
+			authData, err := CheckEntrustPermission(statedb, tx, st.evm.BlockNumber.Uint64(), st.evm.Time.Uint64(), mgval)
+			if err != nil {
+				return err
+			}
+			st.entrustAuth = authData
+			spend = new(big.Int).Add(mgval, entrustAmount(tx))
+		}
+	}
 	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
 		return err
 	}
@@ -117,6 +241,7 @@ func (st *StateTransition) BuyGas() error {
 
 	st.initialGas = st.msg.Gas()
 	st.state.SubBalance(common.MainAccount, st.msg.AmontFrom(), mgval)
+	st.addEntrustSpend(spend)
 	return nil
 }
 
@@ -160,6 +285,16 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 			return st.CallRevocableNormalTx()
 		case common.ExtraRevertTxType:
 			return st.CallRevertNormalTx()
+		case common.ExtraEscrowTxType, common.ExtraEscrowReleaseTxType, common.ExtraEscrowRefundTxType:
+			// Beta版本之前不支持担保交易
+			if !manparams.IsBetaVersion(matrixstate.GetVersionInfo(st.state)) {
+				log.Info("state transition unknown extra txtype")
+				return nil, 0, false, ErrTXUnknownType
+			}
+			if txtype == common.ExtraEscrowTxType {
+				return st.CallEscrowNormalTx()
+			}
+			return st.CallEscrowSettleTx()
 		case common.ExtraUnGasMinerTxType, common.ExtraUnGasValidatorTxType, common.ExtraUnGasInterestTxType, common.ExtraUnGasTxsType, common.ExtraUnGasLotteryTxType:
 			return st.CallUnGasNormalTx()
 		case common.ExtraTimeTxType:
@@ -170,6 +305,30 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 		case common.ExtraCancelEntrust:
 			log.INFO("取消委托", "交易类型", txtype)
 			return st.CallCancelAuthTx()
+		case common.ExtraSignRotationTxType:
+			// Beta版本之前不支持签名账户轮换交易
+			if !manparams.IsBetaVersion(matrixstate.GetVersionInfo(st.state)) {
+				log.Info("state transition unknown extra txtype")
+				return nil, 0, false, ErrTXUnknownType
+			}
+			log.INFO("签名账户轮换", "交易类型", txtype)
+			return st.CallSignRotationTx()
+		case common.ExtraVersionProposalTxType:
+			// Beta版本之前不支持版本升级提案交易
+			if !manparams.IsBetaVersion(matrixstate.GetVersionInfo(st.state)) {
+				log.Info("state transition unknown extra txtype")
+				return nil, 0, false, ErrTXUnknownType
+			}
+			log.INFO("版本升级提案", "交易类型", txtype)
+			return st.CallVersionProposalTx()
+		case common.ExtraCurrencyFeeRateTxType:
+			// Beta版本之前不支持设置币种gas费率交易
+			if !manparams.IsBetaVersion(matrixstate.GetVersionInfo(st.state)) {
+				log.Info("state transition unknown extra txtype")
+				return nil, 0, false, ErrTXUnknownType
+			}
+			log.INFO("设置币种gas费率", "交易类型", txtype)
+			return st.CallCurrencyFeeRateTx()
 		//case common.ExtraCreatCurrency:
 		//	return st.CallCreatCurrencyTx()
 		default:
@@ -356,6 +515,70 @@ func (st *StateTransition) CallRevertNormalTx() (ret []byte, usedGas uint64, fai
 	return ret, st.GasUsed(), vmerr != nil, err
 }
 
+// revocableGas returns the intrinsic gas of a transaction kept on the revocable
+// btree, including the payloads of its one-to-many recipients.
+func (st *StateTransition) revocableGas() (uint64, error) {
+	gas, err := IntrinsicGas(st.data)
+	if err != nil {
+		return 0, err
+	}
+	tmpExtra := st.msg.GetMatrix_EX()
+	if len(tmpExtra) == 0 {
+		return gas, nil
+	}
+	if uint64(len(tmpExtra[0].ExtraTo)) > params.TxCount-1 { //减1是为了和txpool中的验证统一，因为还要算上外层的那笔交易
+		return 0, ErrTXCountOverflow
+	}
+	for _, ex := range tmpExtra[0].ExtraTo {
+		tmpgas, err := IntrinsicGas(ex.Payload)
+		if err != nil {
+			return 0, err
+		}
+		gas += tmpgas
+	}
+	return gas, nil
+}
+
+// freezeValue moves the value of the transaction and of its one-to-many
+// recipients from the main account of from into its withdraw account and
+// returns the frozen amounts.
+func (st *StateTransition) freezeValue(from common.Address) []common.AddrAmont {
+	st.state.AddBalance(common.WithdrawAccount, from, st.value)
+	st.state.SubBalance(common.MainAccount, from, st.value)
+	mapTOAmonts := []common.AddrAmont{{Addr: st.To(), Amont: st.value}}
+	if tmpExtra := st.msg.GetMatrix_EX(); len(tmpExtra) > 0 {
+		for _, ex := range tmpExtra[0].ExtraTo {
+			st.state.AddBalance(common.WithdrawAccount, from, ex.Amount)
+			st.state.SubBalance(common.MainAccount, from, ex.Amount)
+			mapTOAmonts = append(mapTOAmonts, common.AddrAmont{Addr: *ex.Recipient, Amont: ex.Amount})
+		}
+	}
+	return mapTOAmonts
+}
+
+// saveRevocable stores the record of the transaction on the revocable btree,
+// which settles it at tim.
+func (st *StateTransition) saveRevocable(tim uint32, record interface{}) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	txHash := st.msg.Hash()
+	mapHashamont := make(map[common.Hash][]byte)
+	mapHashamont[txHash] = b
+	st.state.SaveTx(common.ExtraRevocable, tim, mapHashamont)
+	st.state.SetMatrixData(txHash, b)
+	return nil
+}
+
+// payRevocableGas refunds the unused gas and pays the fee to the tx fee reward
+// address.
+func (st *StateTransition) payRevocableGas() {
+	costGas := new(big.Int).Mul(new(big.Int).SetUint64(st.GasUsed()), st.gasPrice)
+	st.RefundGas()
+	st.state.AddBalance(common.MainAccount, common.TxGasRewardAddress, costGas)
+}
+
 func (st *StateTransition) CallRevocableNormalTx() (ret []byte, usedGas uint64, failed bool, err error) {
 	if err = st.PreCheck(); err != nil {
 		return
@@ -438,6 +661,112 @@ func (st *StateTransition) CallRevocableNormalTx() (ret []byte, usedGas uint64,
 	st.state.AddBalance(common.MainAccount, common.TxGasRewardAddress, costGas)
 	return ret, st.GasUsed(), vmerr != nil, err
 }
+
+//担保交易:资金与可撤销交易一样先冻结在发起人的WithdrawAccount中，到期后自动转给收款人
+func (st *StateTransition) CallEscrowNormalTx() (ret []byte, usedGas uint64, failed bool, err error) {
+	tx := st.msg //因为st.msg的接口全部在transaction中实现,所以此处的局部变量msg实际是transaction类型
+	from := tx.From()
+	if from == (common.Address{}) {
+		return nil, 0, false, errors.New("CallEscrowNormalTx from is nil")
+	}
+	if len(tx.Data()) != common.AddressLength {
+		return nil, 0, false, ErrEscrowArbiter
+	}
+	arbiter := common.BytesToAddress(tx.Data())
+	if arbiter == (common.Address{}) || arbiter == from {
+		return nil, 0, false, ErrEscrowArbiter
+	}
+	gas, err := st.revocableGas()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if err = st.PreCheck(); err != nil {
+		return nil, 0, false, err
+	}
+	if err = st.UseGas(gas); err != nil {
+		return nil, 0, false, err
+	}
+	st.state.SetNonce(from, st.state.GetNonce(from)+1)
+	var et common.EscrowTx
+	et.From = from
+	et.Arbiter = arbiter
+	et.Tim = tx.GetCreateTime()
+	et.Typ = common.ExtraEscrowTxType
+	et.Adam = st.freezeValue(from)
+	//与可撤销交易存放在同一个btree中，到期由UpdateTxForBtree统一处理
+	if err = st.saveRevocable(et.Tim, &et); err != nil {
+		return nil, 0, false, err
+	}
+	st.payRevocableGas()
+	return ret, st.GasUsed(), false, nil
+}
+
+//担保交易的提前放款或退款,data中为担保交易的hash,一对多时ExtraTo的Payload中为其它担保交易的hash
+func (st *StateTransition) CallEscrowSettleTx() (ret []byte, usedGas uint64, failed bool, err error) {
+	tx := st.msg //因为st.msg的接口全部在transaction中实现,所以此处的局部变量msg实际是transaction类型
+	txtype := tx.GetMatrixType()
+	from := tx.From()
+	if from == (common.Address{}) {
+		return nil, 0, false, errors.New("CallEscrowSettleTx from is nil")
+	}
+	gas, err := st.revocableGas()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if err = st.PreCheck(); err != nil {
+		return nil, 0, false, err
+	}
+	if err = st.UseGas(gas); err != nil {
+		return nil, 0, false, err
+	}
+	st.state.SetNonce(from, st.state.GetNonce(from)+1)
+	hashlist := []common.Hash{common.BytesToHash(tx.Data())}
+	if tmpExtra := tx.GetMatrix_EX(); len(tmpExtra) > 0 {
+		for _, ex := range tmpExtra[0].ExtraTo {
+			hashlist = append(hashlist, common.BytesToHash(ex.Payload))
+		}
+	}
+	st.payRevocableGas()
+	delval := make(map[uint32][]common.Hash)
+	for _, tmphash := range hashlist {
+		if common.EmptyHash(tmphash) {
+			continue
+		}
+		b := st.state.GetMatrixData(tmphash)
+		if b == nil {
+			log.Error("CallEscrowSettleTx not found tx hash,maybe the escrow transaction has expired", "hash", tmphash)
+			continue
+		}
+		var et common.EscrowTx
+		errET := json.Unmarshal(b, &et)
+		if errET != nil {
+			log.Error("state_transition", "CallEscrowSettleTx,Unmarshal err", errET)
+			continue
+		}
+		if et.Typ != common.ExtraEscrowTxType {
+			log.Info("state_transition", "CallEscrowSettleTx:err:type is ", et.Typ, "escrow tx type should ", common.ExtraEscrowTxType)
+			continue
+		}
+		if !et.CanSettle(txtype, from) {
+			log.Info("state_transition", "CallEscrowSettleTx:err", ErrEscrowNoRight, "hash", tmphash, "from", from)
+			continue
+		}
+		for _, vv := range et.Adam { //一对多交易
+			st.state.SubBalance(common.WithdrawAccount, et.From, vv.Amont)
+			if txtype == common.ExtraEscrowReleaseTxType {
+				st.state.AddBalance(common.MainAccount, vv.Addr, vv.Amont)
+			} else {
+				st.state.AddBalance(common.MainAccount, et.From, vv.Amont)
+			}
+		}
+		delval[et.Tim] = append(delval[et.Tim], tmphash)
+		st.state.DeleteMxData(tmphash, b)
+	}
+	for k, v := range delval {
+		st.state.GetSaveTx(common.ExtraRevocable, k, v, true)
+	}
+	return ret, st.GasUsed(), false, nil
+}
 func (st *StateTransition) CallUnGasNormalTx() (ret []byte, usedGas uint64, failed bool, err error) {
 	tx := st.msg //因为st.msg的接口全部在transaction中实现,所以此处的局部变量msg实际是transaction类型
 	toaddr := tx.To()
@@ -749,6 +1078,9 @@ func (st *StateTransition) CallAuthTx() (ret []byte, usedGas uint64, failed bool
 			t_authData.IsEntrustSign = EntrustData.IsEntrustSign
 			t_authData.IsEntrustGas = EntrustData.IsEntrustGas
 			t_authData.AuthAddres = Authfrom
+			if err = setAuthPermission(t_authData, &EntrustData); err != nil {
+				return nil, st.GasUsed(), true, ErrSpecialTxFailed
+			}
 			HeightAuthDataList = append(HeightAuthDataList, *t_authData)
 			marshalAuthData, err := json.Marshal(HeightAuthDataList)
 			if err != nil {
@@ -768,6 +1100,9 @@ func (st *StateTransition) CallAuthTx() (ret []byte, usedGas uint64, failed bool
 			t_authData.IsEntrustSign = EntrustData.IsEntrustSign
 			t_authData.IsEntrustGas = EntrustData.IsEntrustGas
 			t_authData.AuthAddres = Authfrom
+			if err = setAuthPermission(t_authData, &EntrustData); err != nil {
+				return nil, st.GasUsed(), true, ErrSpecialTxFailed
+			}
 			TimeAuthDataList = append(TimeAuthDataList, *t_authData)
 			marshalAuthData, err := json.Marshal(TimeAuthDataList)
 			if err != nil {
@@ -804,6 +1139,27 @@ func (st *StateTransition) CallAuthTx() (ret []byte, usedGas uint64, failed bool
 	return ret, st.GasUsed(), vmerr != nil, nil
 }
 
+//把委托数据中的细粒度权限拷贝到授权数据中
+func setAuthPermission(authData *common.AuthType, entrustData *common.EntrustType) error {
+	if entrustData.SpendLimit != nil && entrustData.SpendLimit.Sign() < 0 {
+		log.Error("委托的gas限额不能为负数")
+		return ErrSpecialTxFailed
+	}
+	authData.SpendLimit = entrustData.SpendLimit
+	authData.SpendPeriod = entrustData.SpendPeriod
+	authData.AllowedCurrencies = entrustData.AllowedCurrencies
+	authData.AllowedTxTypes = entrustData.AllowedTxTypes
+	for _, strTo := range entrustData.AllowedTo {
+		to, err := base58.Base58DecodeToAddress(strTo)
+		if err != nil {
+			log.Error("委托的收款地址错误", "to", strTo)
+			return err
+		}
+		authData.AllowedTo = append(authData.AllowedTo, to)
+	}
+	return nil
+}
+
 func isContain(a uint32, list []uint32) bool {
 	for _, data := range list {
 		if data == a {
@@ -955,6 +1311,7 @@ func (st *StateTransition) RefundGas() {
 	// Return ETH for remaining gas, exchanged at the original rate.
 	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
 	st.state.AddBalance(common.MainAccount, st.msg.AmontFrom(), remaining)
+	st.addEntrustSpend(new(big.Int).Neg(remaining))
 
 	// Also return remaining gas to the block gas counter so it is
 	// available for the next transaction.
@@ -965,3 +1322,167 @@ func (st *StateTransition) RefundGas() {
 func (st *StateTransition) GasUsed() uint64 {
 	return st.initialGas - st.gas
 }
+
+//签名账户轮换交易:抵押账户(A0)登记新的签名账户(A1),新A1在下一个选举周期边界生效,
+//生效后的一个广播周期内旧A1仍被认可
+func (st *StateTransition) CallSignRotationTx() (ret []byte, usedGas uint64, failed bool, err error) {
+	if err = st.PreCheck(); err != nil {
+		return
+	}
+	tx := st.msg
+	toaddr := tx.To()
+	sender := vm.AccountRef(tx.From())
+	var (
+		evm   = st.evm
+		vmerr error
+	)
+
+	// Pay intrinsic gas
+	gas, err := IntrinsicGas(st.data)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if err = st.UseGas(gas); err != nil {
+		return nil, 0, false, err
+	}
+	if toaddr == nil {
+		log.Error("state transition CallSignRotationTx to is nil")
+		return nil, 0, false, ErrTXToNil
+	}
+	// Increment the nonce for the next transaction
+	st.state.SetNonce(tx.From(), st.state.GetNonce(sender.Address())+1)
+	ret, st.gas, vmerr = evm.Call(sender, st.To(), nil, st.gas, st.value)
+	if vmerr != nil {
+		log.Debug("VM returned with error", "err", vmerr)
+		if vmerr == vm.ErrInsufficientBalance {
+			return nil, 0, false, vmerr
+		}
+	}
+	st.RefundGas()
+	st.state.AddBalance(common.MainAccount, common.TxGasRewardAddress, new(big.Int).Mul(new(big.Int).SetUint64(st.GasUsed()), st.gasPrice))
+	if vmerr != nil {
+		return ret, st.GasUsed(), true, nil
+	}
+
+	if len(tx.Data()) != common.AddressLength {
+		log.Error("签名账户轮换", "新签名账户长度错误", len(tx.Data()))
+		return nil, st.GasUsed(), true, ErrSpecialTxFailed
+	}
+	newSignAccount := common.BytesToAddress(tx.Data())
+	interval, err := matrixstate.GetBroadcastInterval(st.state)
+	if err != nil {
+		log.Error("签名账户轮换", "获取广播周期失败", err)
+		return nil, st.GasUsed(), true, ErrSpecialTxFailed
+	}
+	height := evm.BlockNumber.Uint64()
+	effectHeight := interval.GetNextReElectionNumber(height)
+	if effectHeight-height < interval.GetBroadcastInterval() {
+		//离选举周期边界太近,顺延到下一个选举周期
+		effectHeight = interval.GetNextReElectionNumber(effectHeight)
+	}
+	handoverEnd := effectHeight + interval.GetBroadcastInterval()
+	if err = depoistInfo.SetSignRotation(st.state, tx.From(), newSignAccount, height, effectHeight, handoverEnd); err != nil {
+		log.Error("签名账户轮换", "登记失败", err, "from", tx.From(), "newSignAccount", newSignAccount)
+		return nil, st.GasUsed(), true, ErrSpecialTxFailed
+	}
+	log.INFO("签名账户轮换", "A0", tx.From(), "新A1", newSignAccount, "生效高度", effectHeight, "交接结束高度", handoverEnd)
+	return ret, st.GasUsed(), false, nil
+}
+
+//版本升级提案交易:版本超级账户提交对目标版本号的签名,签名数量达到门限后提案通过
+func (st *StateTransition) CallVersionProposalTx() (ret []byte, usedGas uint64, failed bool, err error) {
+	if err = st.PreCheck(); err != nil {
+		return
+	}
+	tx := st.msg
+	toaddr := tx.To()
+	sender := vm.AccountRef(tx.From())
+	var (
+		evm   = st.evm
+		vmerr error
+	)
+
+	// Pay intrinsic gas
+	gas, err := IntrinsicGas(st.data)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if err = st.UseGas(gas); err != nil {
+		return nil, 0, false, err
+	}
+	if toaddr == nil {
+		log.Error("state transition CallVersionProposalTx to is nil")
+		return nil, 0, false, ErrTXToNil
+	}
+	// Increment the nonce for the next transaction
+	st.state.SetNonce(tx.From(), st.state.GetNonce(sender.Address())+1)
+	ret, st.gas, vmerr = evm.Call(sender, st.To(), nil, st.gas, st.value)
+	if vmerr != nil {
+		log.Debug("VM returned with error", "err", vmerr)
+		if vmerr == vm.ErrInsufficientBalance {
+			return nil, 0, false, vmerr
+		}
+	}
+	st.RefundGas()
+	st.state.AddBalance(common.MainAccount, common.TxGasRewardAddress, new(big.Int).Mul(new(big.Int).SetUint64(st.GasUsed()), st.gasPrice))
+	if vmerr != nil {
+		return ret, st.GasUsed(), true, nil
+	}
+
+	height := evm.BlockNumber.Uint64()
+	proposal, err := applyVersionProposal(st.state, evm.ChainConfig().SimpleMode, tx.From(), tx.Data(), height)
+	if err != nil {
+		log.Error("版本升级提案", "登记失败", err, "from", tx.From())
+		return nil, st.GasUsed(), true, ErrSpecialTxFailed
+	}
+	log.INFO("版本升级提案", "版本", proposal.Version, "生效高度", proposal.ActiveHeight, "签名数量", len(proposal.Signers), "状态", proposal.Status)
+	return ret, st.GasUsed(), false, nil
+}
+
+//设置币种gas费率交易:多币种超级账户设置以该币种支付gas的费率
+func (st *StateTransition) CallCurrencyFeeRateTx() (ret []byte, usedGas uint64, failed bool, err error) {
+	if err = st.PreCheck(); err != nil {
+		return
+	}
+	tx := st.msg
+	toaddr := tx.To()
+	sender := vm.AccountRef(tx.From())
+	var (
+		evm   = st.evm
+		vmerr error
+	)
+
+	// Pay intrinsic gas
+	gas, err := IntrinsicGas(st.data)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if err = st.UseGas(gas); err != nil {
+		return nil, 0, false, err
+	}
+	if toaddr == nil {
+		log.Error("state transition CallCurrencyFeeRateTx to is nil")
+		return nil, 0, false, ErrTXToNil
+	}
+	// Increment the nonce for the next transaction
+	st.state.SetNonce(tx.From(), st.state.GetNonce(sender.Address())+1)
+	ret, st.gas, vmerr = evm.Call(sender, st.To(), nil, st.gas, st.value)
+	if vmerr != nil {
+		log.Debug("VM returned with error", "err", vmerr)
+		if vmerr == vm.ErrInsufficientBalance {
+			return nil, 0, false, vmerr
+		}
+	}
+	st.RefundGas()
+	st.state.AddBalance(common.MainAccount, common.TxGasRewardAddress, new(big.Int).Mul(new(big.Int).SetUint64(st.GasUsed()), st.gasPrice))
+	if vmerr != nil {
+		return ret, st.GasUsed(), true, nil
+	}
+
+	if err = applyCurrencyFeeRate(st.state, tx.From(), tx.Data(), evm.BlockNumber.Uint64()); err != nil {
+		log.Error("设置币种gas费率", "设置失败", err, "from", tx.From())
+		return nil, st.GasUsed(), true, ErrSpecialTxFailed
+	}
+	log.INFO("设置币种gas费率", "费率", string(tx.Data()))
+	return ret, st.GasUsed(), false, nil
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+func TestCheckEntrustPermission(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	entrust := crypto.PubkeyToAddress(key.PublicKey)
+	auth := common.HexToAddress("0x01")
+	allowed := common.HexToAddress("0x02")
+
+	statedb := newTestState()
+	auths := []common.AuthType{{
+		AuthAddres:        auth,
+		EnstrustSetType:   params.EntrustByHeight,
+		IsEntrustSign:     true,
+		StartHeight:       100,
+		EndHeight:         200,
+		SpendLimit:        big.NewInt(1000),
+		AllowedTo:         []common.Address{allowed},
+		AllowedCurrencies: []string{"MAN"},
+		AllowedTxTypes:    []uint32{uint32(common.ExtraNormalTxType)},
+	}}
+	data, _ := json.Marshal(auths)
+	statedb.SetAuthStateByteArray(entrust, data)
+
+	send := func(to common.Address, value int64, typ byte, currency string) types.SelfTransaction {
+		tx := types.NewTransaction(params.NonceAddOne, to, big.NewInt(value), 21000, testGasPrice, nil, nil, nil, nil, typ, 0, currency, 0)
+		tx.SetFromLoad(entrust)
+		return tx
+	}
+	tests := []struct {
+		tx     types.SelfTransaction
+		height uint64
+		cost   int64
+		err    error
+	}{
+		{send(allowed, 100, common.ExtraNormalTxType, "MAN"), 150, 100, nil},
+		{send(allowed, 100, common.ExtraNormalTxType, "MAN"), 201, 100, nil}, // 委托签名已到期, 不再限制
+		{send(common.HexToAddress("0x03"), 100, common.ExtraNormalTxType, "MAN"), 150, 100, ErrEntrustRecipient},
+		{send(allowed, 100, common.ExtraNormalTxType, "BTC"), 150, 100, ErrEntrustCurrency},
+		{send(allowed, 100, common.ExtraRevocable, "MAN"), 150, 100, ErrEntrustTxType},
+		{send(allowed, 100, common.ExtraNormalTxType, "MAN"), 150, 900, nil},
+		{send(allowed, 101, common.ExtraNormalTxType, "MAN"), 150, 900, ErrEntrustSpendLimit}, // 转账金额计入限额
+	}
+	for i, test := range tests {
+		if _, err := CheckEntrustPermission(statedb, test.tx, test.height, 0, big.NewInt(test.cost)); err != test.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.err)
+		}
+	}
+	// 委托gas的交易必须有生效的授权
+	tx := send(allowed, 100, common.ExtraNormalTxType, "MAN")
+	tx.SetIsEntrustGas(true)
+	if _, err := CheckEntrustPermission(statedb, tx, 150, 0, big.NewInt(100)); err != ErrWithoutAuth {
+		t.Errorf("gas entrust: error mismatch: have %v, want %v", err, ErrWithoutAuth)
+	}
+}
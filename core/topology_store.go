@@ -9,6 +9,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
 	"github.com/pkg/errors"
 )
 
@@ -41,6 +42,10 @@ func (ts TopologyStore) ProduceTopologyStateData(block *types.Block, readFn PreS
 	return newGraph, nil
 }
 
+func (ts *TopologyStore) Config() *params.ChainConfig {
+	return ts.bc.Config()
+}
+
 func (ts *TopologyStore) GetHashByNumber(number uint64) common.Hash {
 	return ts.bc.GetHashByNumber(number)
 }
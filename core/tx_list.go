@@ -10,6 +10,7 @@ import (
 	"math/big"
 	"sort"
 
+	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 )
 
@@ -268,26 +269,31 @@ func (l *txList) Overlaps(tx *types.Transaction) bool {
 // Add tries to insert a new transaction into the list, returning whether the
 // transaction was accepted, and if yes, any previous transaction it replaced.
 //
+// Replacements are ranked by the gas price the transactions offer, as returned
+// by price. A replacement that doesn't raise it by priceBump percent would be
+// relayed for free and is rejected.
+//
 // If the new transaction is accepted into the list, the lists' cost and gas
 // thresholds are also potentially updated.
-func (l *txList) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Transaction) {
-	//// If there's an older better transaction, abort
-	//old := l.txs.Get(tx.Nonce())
-	//if old != nil {
-	//	threshold := new(big.Int).Div(new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(priceBump))), big.NewInt(100))
-	//	// Have to ensure that the new gas price is higher than the old gas
-	//	// price as well as checking the percentage threshold to ensure that
-	//	// this is accurate for low (Wei-level) gas price replacements
-	//	if old.GasPrice().Cmp(tx.GasPrice()) >= 0 || threshold.Cmp(tx.GasPrice()) > 0 {
-	//		return false, nil
-	//	}
-	//}
-	// Otherwise overwrite the old transaction with the current one
+func (l *txList) Add(tx *types.Transaction, priceBump uint64, price func(tx *types.Transaction) *big.Int) (bool, *types.Transaction) {
 	sm, ok := l.txs[tx.GetTxCurrency()]
 	if !ok {
 		l.txs[tx.GetTxCurrency()] = newTxSortedMap()
 		sm = l.txs[tx.GetTxCurrency()]
 	}
+	// If there's an older better transaction, abort
+	old := sm.Get(tx.Nonce())
+	if old != nil {
+		oldPrice, newPrice := price(old), price(tx)
+		threshold := new(big.Int).Div(new(big.Int).Mul(oldPrice, big.NewInt(100+int64(priceBump))), big.NewInt(100))
+		// Have to ensure that the new gas price is higher than the old gas
+		// price as well as checking the percentage threshold to ensure that
+		// this is accurate for low (Wei-level) gas price replacements
+		if oldPrice.Cmp(newPrice) >= 0 || threshold.Cmp(newPrice) > 0 {
+			return false, nil
+		}
+	}
+	// Otherwise overwrite the old transaction with the current one
 	sm.Put(tx)
 	if cost := tx.Cost(); l.costcap.Cmp(cost) < 0 {
 		l.costcap = cost
@@ -295,7 +301,7 @@ func (l *txList) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Tran
 	if gas := tx.Gas(); l.gascap < gas {
 		l.gascap = gas
 	}
-	return true, nil
+	return true, old
 }
 
 // Forward removes all transactions from the list with a nonce lower than the
@@ -344,11 +350,25 @@ func (l *txList) Filter(costLimit *big.Int, gasLimit uint64, typ string) ([]*typ
 	return removed, invalids
 }
 
-// Cap places a hard limit on the number of items, returning all transactions
-// exceeding that limit.
-//func (l *txList) Cap(threshold int) []*types.Transaction {
-//	return l.txs.Cap(threshold)
-//}
+// Cap places a hard limit on the number of items of all currencies, returning
+// the highest nonce transactions exceeding that limit.
+func (l *txList) Cap(threshold int) []*types.Transaction {
+	var drops []*types.Transaction
+	for l.Count() > threshold {
+		var (
+			highest *types.Transaction
+			typ     string
+		)
+		for str, sm := range l.txs {
+			if txs := sm.Flatten(); len(txs) > 0 && (highest == nil || txs[len(txs)-1].Nonce() > highest.Nonce()) {
+				highest, typ = txs[len(txs)-1], str
+			}
+		}
+		l.txs[typ].Remove(highest.Nonce())
+		drops = append(drops, highest)
+	}
+	return drops
+}
 
 // Remove deletes a transaction from the maintained list, returning whether the
 // transaction was found, and also returning any transaction invalidated due to
@@ -371,15 +391,26 @@ func (l *txList) Remove(tx *types.Transaction) (bool, []*types.Transaction) {
 }
 
 // Ready retrieves a sequentially increasing list of transactions starting at the
-// provided nonce that is ready for processing. The returned transactions will be
-// removed from the list.
-//
-// Note, all transactions with nonces lower than start will also be returned to
-// prevent getting into and invalid state. This is not something that should ever
-// happen but better to be self correcting than failing!
-//func (l *txList) Ready(start uint64) []*types.Transaction {
-//	return l.txs.Ready(start)
-//}
+// provided nonce that is ready for processing. The account nonce is shared by
+// all currencies, so the sequence may go across them. The returned transactions
+// will be removed from the list.
+func (l *txList) Ready(start uint64) []*types.Transaction {
+	var ready []*types.Transaction
+	for next := start; ; next++ {
+		var found *types.Transaction
+		for _, sm := range l.txs {
+			if tx := sm.Get(next); tx != nil {
+				sm.Remove(next)
+				found = tx
+				break
+			}
+		}
+		if found == nil {
+			return ready
+		}
+		ready = append(ready, found)
+	}
+}
 
 // Len returns the length of the transaction list.
 func (l *txList) Len(typ string) int {
@@ -395,6 +426,15 @@ func (l *txList) Empty(typ string) bool {
 	return l.Len(typ) == 0
 }
 
+// Count returns the number of transactions of all currencies in the list.
+func (l *txList) Count() int {
+	count := 0
+	for _, sm := range l.txs {
+		count += sm.Len()
+	}
+	return count
+}
+
 // Flatten creates a nonce-sorted slice of transactions based on the loosely
 // sorted internal representation. The result of the sorting is cached in case
 // it's requested again before any modifications are made to the contents.
@@ -402,161 +442,158 @@ func (l *txList) Empty(typ string) bool {
 //	return l.txs.Flatten()
 //}
 
-/*
-// priceHeap is a heap.Interface implementation over transactions for retrieving
-// price-sorted transactions to discard when the pool fills up.
-type priceHeap []*types.Transaction
+// priceHeap is a heap.Interface implementation over the transactions of one
+// currency for retrieving price-sorted transactions to discard when the pool
+// fills up.
+type priceHeap struct {
+	price func(tx *types.Transaction) *big.Int // Gas price offered by a transaction
+	list  []*types.Transaction
+}
 
-func (h priceHeap) Len() int      { return len(h) }
-func (h priceHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h priceHeap) Len() int      { return len(h.list) }
+func (h priceHeap) Swap(i, j int) { h.list[i], h.list[j] = h.list[j], h.list[i] }
 
 func (h priceHeap) Less(i, j int) bool {
 	// Sort primarily by price, returning the cheaper one
-	switch h[i].GasPrice().Cmp(h[j].GasPrice()) {
+	switch h.price(h.list[i]).Cmp(h.price(h.list[j])) {
 	case -1:
 		return true
 	case 1:
 		return false
 	}
 	// If the prices match, stabilize via nonces (high nonce is worse)
-	return h[i].Nonce() > h[j].Nonce()
+	return h.list[i].Nonce() > h.list[j].Nonce()
 }
 
 func (h *priceHeap) Push(x interface{}) {
-	*h = append(*h, x.(*types.Transaction))
+	h.list = append(h.list, x.(*types.Transaction))
 }
 
 func (h *priceHeap) Pop() interface{} {
-	old := *h
+	old := h.list
 	n := len(old)
 	x := old[n-1]
-	*h = old[0 : n-1]
+	h.list = old[0 : n-1]
 	return x
 }
 
-// txPricedList is a price-sorted heap to allow operating on transactions pool
-// contents in a price-incrementing way.
+// txPricedList is a price-sorted heap per currency to allow operating on
+// transactions pool contents in a price-incrementing way. Prices of different
+// currencies are not comparable, so every currency has its own heap.
+//
+// Transactions are ranked by the gas price they offer.
 type txPricedList struct {
-	all    *txLookup  // Pointer to the map of all transactions
-	items  *priceHeap // Heap of prices of all the stored transactions
-	stales int        // Number of stale price points to (re-heap trigger)
+	all    *txLookup                            // Pointer to the map of all transactions
+	price  func(tx *types.Transaction) *big.Int // Gas price offered by a transaction
+	items  map[string]*priceHeap                // Heaps of prices of the stored transactions per currency
+	stales int                                  // Number of stale price points to (re-heap trigger)
 }
 
 // newTxPricedList creates a new price-sorted transaction heap.
-func newTxPricedList(all *txLookup) *txPricedList {
+func newTxPricedList(all *txLookup, price func(tx *types.Transaction) *big.Int) *txPricedList {
 	return &txPricedList{
 		all:   all,
-		items: new(priceHeap),
+		price: price,
+		items: make(map[string]*priceHeap),
+	}
+}
+
+// currency returns the heap of the given currency, creating it if needed.
+func (l *txPricedList) currency(currency string) *priceHeap {
+	h, ok := l.items[currency]
+	if !ok {
+		h = &priceHeap{price: l.price}
+		l.items[currency] = h
+	}
+	return h
+}
+
+// Len returns the number of price points of all currencies, stale ones
+// included.
+func (l *txPricedList) Len() int {
+	count := 0
+	for _, h := range l.items {
+		count += h.Len()
 	}
+	return count
 }
 
-// Put inserts a new transaction into the heap.
+// Put inserts a new transaction into the heap of its currency.
 func (l *txPricedList) Put(tx *types.Transaction) {
-	heap.Push(l.items, tx)
+	heap.Push(l.currency(tx.GetTxCurrency()), tx)
 }
 
 // Removed notifies the prices transaction list that an old transaction dropped
 // from the pool. The list will just keep a counter of stale objects and update
-// the heap if a large enough ratio of transactions go stale.
+// the heaps if a large enough ratio of transactions go stale.
 func (l *txPricedList) Removed() {
 	// Bump the stale counter, but exit if still too low (< 25%)
 	l.stales++
-	if l.stales <= len(*l.items)/4 {
+	if l.stales <= l.Len()/4 {
 		return
 	}
 	// Seems we've reached a critical number of stale transactions, reheap
-	reheap := make(priceHeap, 0, l.all.Count())
-
-	l.stales, l.items = 0, &reheap
+	l.stales, l.items = 0, make(map[string]*priceHeap)
 	l.all.Range(func(hash common.Hash, tx *types.Transaction) bool {
-		*l.items = append(*l.items, tx)
+		h := l.currency(tx.GetTxCurrency())
+		h.list = append(h.list, tx)
 		return true
 	})
-	heap.Init(l.items)
-}
-
-// Cap finds all the transactions below the given price threshold, drops them
-// from the priced list and returs them for further removal from the entire pool.
-func (l *txPricedList) Cap(threshold *big.Int, local *accountSet) types.Transactions {
-	drop := make(types.Transactions, 0, 128) // Remote underpriced transactions to drop
-	save := make(types.Transactions, 0, 64)  // Local underpriced transactions to keep
-
-	for len(*l.items) > 0 {
-		// Discard stale transactions if found during cleanup
-		tx := heap.Pop(l.items).(*types.Transaction)
-		if l.all.Get(tx.Hash()) == nil {
-			l.stales--
-			continue
-		}
-		// Stop the discards if we've reached the threshold
-		if tx.GasPrice().Cmp(threshold) >= 0 {
-			save = append(save, tx)
-			break
-		}
-		// Non stale transaction found, discard unless local
-		if local.containsTx(tx) {
-			save = append(save, tx)
-		} else {
-			drop = append(drop, tx)
-		}
-	}
-	for _, tx := range save {
-		heap.Push(l.items, tx)
+	for _, h := range l.items {
+		heap.Init(h)
 	}
-	return drop
 }
 
 // Underpriced checks whether a transaction is cheaper than (or as cheap as) the
-// lowest priced transaction currently being tracked.
-func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) bool {
-	// Local transactions cannot be underpriced
-	if local.containsTx(tx) {
-		return false
+// lowest priced transaction of its currency currently being tracked. A
+// transaction of a currency without tracked transactions can't displace any
+// other and counts as underpriced.
+func (l *txPricedList) Underpriced(tx *types.Transaction) bool {
+	h, ok := l.items[tx.GetTxCurrency()]
+	if !ok {
+		return true
 	}
 	// Discard stale price points if found at the heap start
-	for len(*l.items) > 0 {
-		head := []*types.Transaction(*l.items)[0]
+	for h.Len() > 0 {
+		head := h.list[0]
 		if l.all.Get(head.Hash()) == nil {
 			l.stales--
-			heap.Pop(l.items)
+			heap.Pop(h)
 			continue
 		}
 		break
 	}
 	// Check if the transaction is underpriced or not
-	if len(*l.items) == 0 {
-		log.Error("Pricing query for empty pool") // This cannot happen, print to catch programming errors
-		return false
+	if h.Len() == 0 {
+		return true
 	}
-	cheapest := []*types.Transaction(*l.items)[0]
-	return cheapest.GasPrice().Cmp(tx.GasPrice()) >= 0
+	cheapest := h.list[0]
+	return l.price(cheapest).Cmp(l.price(tx)) >= 0
 }
 
-// Discard finds a number of most underpriced transactions, removes them from the
-// priced list and returns them for further removal from the entire pool.
-func (l *txPricedList) Discard(count int, local *accountSet) types.Transactions {
-	drop := make(types.Transactions, 0, count) // Remote underpriced transactions to drop
-	save := make(types.Transactions, 0, 64)    // Local underpriced transactions to keep
+// Discard finds a number of most underpriced transactions of the currency and
+// returns them for further removal from the entire pool, which marks them stale
+// in the priced list.
+func (l *txPricedList) Discard(count int, currency string) []*types.Transaction {
+	drop := make([]*types.Transaction, 0, count)
 
-	for len(*l.items) > 0 && count > 0 {
+	h, ok := l.items[currency]
+	if !ok {
+		return drop
+	}
+	for h.Len() > 0 && count > 0 {
 		// Discard stale transactions if found during cleanup
-		tx := heap.Pop(l.items).(*types.Transaction)
+		tx := heap.Pop(h).(*types.Transaction)
 		if l.all.Get(tx.Hash()) == nil {
 			l.stales--
 			continue
 		}
-		// Non stale transaction found, discard unless local
-		if local.containsTx(tx) {
-			save = append(save, tx)
-		} else {
-			drop = append(drop, tx)
-			count--
-		}
+		drop = append(drop, tx)
+		count--
 	}
-	for _, tx := range save {
-		heap.Push(l.items, tx)
+	// 被丢弃的交易由调用方从池中删除, 届时才计为过期, 这里先放回堆中
+	for _, tx := range drop {
+		heap.Push(h, tx)
 	}
 	return drop
 }
-
-*/
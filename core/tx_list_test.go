@@ -5,9 +5,11 @@
 package core
 
 import (
+	"math/big"
 	"math/rand"
 	"testing"
 
+	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/crypto"
 )
@@ -18,22 +20,111 @@ func TestStrictTxListAdd(t *testing.T) {
 	// Generate a list of transactions to insert
 	key, _ := crypto.GenerateKey()
 
-	txs := make(types.Transactions, 1024)
+	txs := make([]*types.Transaction, 1024)
 	for i := 0; i < len(txs); i++ {
 		txs[i] = transaction(uint64(i), 0, key)
 	}
 	// Insert the transactions in a random order
-	list := newTxList(true)
+	list := newTxList(true, "MAN")
 	for _, v := range rand.Perm(len(txs)) {
-		list.Add(txs[v], DefaultTxPoolConfig.PriceBump)
+		list.Add(txs[v], DefaultTxPoolConfig.PriceBump, (*types.Transaction).GasPrice)
 	}
 	// Verify internal state
-	if len(list.txs.items) != len(txs) {
-		t.Errorf("transaction count mismatch: have %d, want %d", len(list.txs.items), len(txs))
+	if len(list.txs["MAN"].items) != len(txs) {
+		t.Errorf("transaction count mismatch: have %d, want %d", len(list.txs["MAN"].items), len(txs))
 	}
 	for i, tx := range txs {
-		if list.txs.items[tx.Nonce()] != tx {
-			t.Errorf("item %d: transaction mismatch: have %v, want %v", i, list.txs.items[tx.Nonce()], tx)
+		if list.txs["MAN"].items[tx.Nonce()] != tx {
+			t.Errorf("item %d: transaction mismatch: have %v, want %v", i, list.txs["MAN"].items[tx.Nonce()], tx)
 		}
 	}
 }
+
+// Tests that replacements are ranked by the charged gas price and need to raise
+// it by the price bump.
+func TestTxListReplacement(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	charged := make(map[common.Hash]*big.Int)
+	chargedPrice := func(tx *types.Transaction) *big.Int { return charged[tx.Hash()] }
+
+	original := pricedTransaction(0, 100000, price(1), key)
+	charged[original.Hash()] = big.NewInt(100)
+	list := newTxList(false, "MAN")
+	if inserted, _ := list.Add(original, 10, chargedPrice); !inserted {
+		t.Fatalf("original transaction rejected")
+	}
+	tests := []struct {
+		declared *big.Int
+		charged  int64
+		ok       bool
+	}{
+		{price(10), 100, false}, // 交易中的价格不影响实际gas价格
+		{price(1), 109, false},
+		{price(1), 110, true},
+	}
+	for i, test := range tests {
+		tx := pricedTransaction(0, 100000+uint64(i), test.declared, key)
+		charged[tx.Hash()] = big.NewInt(test.charged)
+		inserted, old := list.Add(tx, 10, chargedPrice)
+		if inserted != test.ok {
+			t.Errorf("test %d: inserted mismatch: have %v, want %v", i, inserted, test.ok)
+		}
+		if inserted && old != original {
+			t.Errorf("test %d: replaced transaction mismatch: have %v, want %v", i, old, original)
+		}
+	}
+}
+
+// Tests that the priced list ranks the transactions of every currency apart.
+func TestTxPricedListCurrencies(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	all := newTxLookup()
+	priced := newTxPricedList(all, (*types.Transaction).GasPrice)
+
+	cheap := currencyTransaction(0, 100000, price(1), "MAN", key)
+	txs := []*types.Transaction{
+		cheap,
+		currencyTransaction(1, 100000, price(2), "MAN", key),
+		currencyTransaction(2, 100000, price(10), "BTC", key),
+	}
+	for _, tx := range txs {
+		all.Add(tx)
+		priced.Put(tx)
+	}
+	tests := []struct {
+		tx          *types.Transaction
+		underpriced bool
+	}{
+		{currencyTransaction(3, 100000, price(1), "MAN", key), true},
+		{currencyTransaction(3, 100000, price(3), "MAN", key), false},
+		{currencyTransaction(3, 100000, price(5), "BTC", key), true}, // 不同币种的价格不可比较
+		{currencyTransaction(3, 100000, price(11), "BTC", key), false},
+		{currencyTransaction(3, 100000, price(100), "ETH", key), true}, // 没有同币种交易可挤出
+	}
+	for i, test := range tests {
+		if have := priced.Underpriced(test.tx); have != test.underpriced {
+			t.Errorf("test %d: underpriced mismatch: have %v, want %v", i, have, test.underpriced)
+		}
+	}
+	// 只挤出同币种的交易
+	if drop := priced.Discard(1, "BTC"); len(drop) != 1 || drop[0] != txs[2] {
+		t.Errorf("discarded BTC transactions mismatch: have %v", drop)
+	}
+	if drop := priced.Discard(1, "MAN"); len(drop) != 1 || drop[0] != cheap {
+		t.Errorf("discarded MAN transactions mismatch: have %v", drop)
+	}
+	if drop := priced.Discard(1, "ETH"); len(drop) != 0 {
+		t.Errorf("discarded ETH transactions: %v", drop)
+	}
+	// 被丢弃的交易从池中删除后才在价格列表中过期
+	for _, tx := range []*types.Transaction{txs[2], cheap} {
+		all.Remove(tx.Hash())
+		priced.Removed()
+	}
+	if tracked := priced.Len() - priced.stales; tracked != 1 {
+		t.Errorf("priced length mismatch: have %d, want %d", tracked, 1)
+	}
+	if drop := priced.Discard(1, "MAN"); len(drop) != 1 || drop[0] != txs[1] {
+		t.Errorf("discarded MAN transactions after removal mismatch: have %v", drop)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	//"github.com/MatrixAINetwork/go-matrix/p2p/discover"
+	"math"
 	"math/big"
 	"sync"
 	"time"
@@ -14,15 +15,18 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
 	"github.com/MatrixAINetwork/go-matrix/event"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/mc"
 	"github.com/MatrixAINetwork/go-matrix/metrics"
 	"github.com/MatrixAINetwork/go-matrix/p2p"
 	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
 	"github.com/MatrixAINetwork/go-matrix/rlp"
 	"github.com/MatrixAINetwork/go-matrix/txpoolCache"
 	"runtime"
+	"sort"
 )
 
 //
@@ -83,6 +87,20 @@ var (
 	ErrWithoutAuth     = errors.New("gas entrust not set ")
 	ErrinterestAmont   = errors.New("Incorrect total interest")
 	ErrSpecialTxFailed = errors.New("Run special tx failed")
+	ErrEscrowArbiter   = errors.New("escrow arbiter is invalid")
+	ErrEscrowNotFound  = errors.New("escrow transaction not found")
+	ErrEscrowNoRight   = errors.New("no right to settle the escrow transaction")
+
+	ErrEntrustTxType     = errors.New("tx type not allowed by entrust")
+	ErrEntrustCurrency   = errors.New("currency not allowed by entrust")
+	ErrEntrustRecipient  = errors.New("recipient not allowed by entrust")
+	ErrEntrustSpendLimit = errors.New("entrust spend limit exceeded")
+
+	ErrSignRotation = errors.New("sign account rotation is invalid")
+
+	// ErrUnknownCurrency is returned if the transaction's currency has no gas
+	// fee rate in the matrix state.
+	ErrUnknownCurrency = errors.New("unknown currency")
 )
 
 var (
@@ -159,6 +177,7 @@ type ConsensusNTx struct {
 // TxPoolConfig are the configuration parameters of the transaction pool.
 type TxPoolConfig struct {
 	PriceLimit   uint64 // Minimum gas price to enforce for acceptance into the pool
+	PriceBump    uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 	AccountSlots uint64 // Minimum number of executable transaction slots guaranteed per account
 	GlobalSlots  uint64 // Maximum number of executable transaction slots for all accounts
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
@@ -170,6 +189,7 @@ type TxPoolConfig struct {
 // pool.
 var DefaultTxPoolConfig = TxPoolConfig{
 	PriceLimit:   params.TxGasPrice, // 2018-08-29 由1改为此值
+	PriceBump:    10,
 	AccountSlots: 16,
 	GlobalSlots:  4096 * 5 * 5 * 10, // 2018-08-30 改为乘以5
 	AccountQueue: 64 * 1000,
@@ -195,7 +215,9 @@ type NormalTxPool struct {
 	currentMaxGas uint64              // Current gas limit for transaction caps
 
 	pending map[common.Address]*txList // All currently processable transactions
+	queue   map[common.Address]*txList // Queued but non-processable transactions
 	all     *txLookup                  // All transactions to allow lookups
+	priced  *txPricedList              // All transactions sorted by price
 	//=================by  ==================//
 	SContainer map[common.Hash]*types.Transaction
 	NContainer map[uint32]*types.Transaction
@@ -203,7 +225,6 @@ type NormalTxPool struct {
 	quit       chan struct{}
 	udptxsSub  event.Subscription //取消订阅
 	//=================================================//
-	//priced *txPricedList // All transactions sorted by price
 
 	wg sync.WaitGroup // for shutdown sync
 
@@ -225,6 +246,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
 	}
+	if conf.PriceBump < 1 {
+		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
+		conf.PriceBump = DefaultTxPoolConfig.PriceBump
+	}
 	return conf
 }
 
@@ -238,6 +263,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		chain:         chain,
 		signer:        types.NewEIP155Signer(chainconfig.ChainId),
 		pending:       make(map[common.Address]*txList),
+		queue:         make(map[common.Address]*txList),
 		SContainer:    make(map[common.Hash]*types.Transaction), //by
 		NContainer:    make(map[uint32]*types.Transaction),      //by
 		udptxsCh:      make(chan []*types.Transaction_Mx, 0),    //
@@ -252,7 +278,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		mapTxsTiming:  make(map[common.Hash]time.Time),        //  需要做定时删除的交易
 		mapHighttx:    make(map[uint64][]uint32, 0),
 	}
-	//nPool.pool.priced = newTxPricedList(nPool.pool.all)
+	nPool.priced = newTxPricedList(nPool.all, offeredPrice)
 	nPool.reset(nil, chain.CurrentBlock().Header())
 
 	// Subscribe events from blockchain
@@ -598,9 +624,13 @@ func (nPool *NormalTxPool) reset(oldHead, newHead *types.Header) {
 	for addr, list := range nPool.pending {
 		for _, txs := range list.txs {
 			txs := txs.Flatten() // Heavy but will be cached and is needed by the miner anyway
-			nPool.pendingState.SetNonce(addr, txs[len(txs)-1].Nonce()+1)
+			if len(txs) > 0 && nPool.pendingState.GetNonce(addr) <= txs[len(txs)-1].Nonce() {
+				nPool.pendingState.SetNonce(addr, txs[len(txs)-1].Nonce()+1)
+			}
 		}
 	}
+	// 新区块可能补齐了future队列中交易的nonce
+	nPool.promoteExecutables(nil)
 }
 
 // Stop terminates the transaction pool.
@@ -641,19 +671,21 @@ func (nPool *NormalTxPool) Stats() (int, int) {
 // stats retrieves the current pool stats, namely the number of pending and the
 // number of queued (non-executable) transactions.
 func (nPool *NormalTxPool) stats() (int, int) {
-	pending := 0
-	for _, list := range nPool.pending {
-		for _, txs := range list.txs {
-			pending += txs.Len() //list.Len(typ)
-		}
+	return countTxs(nPool.pending), countTxs(nPool.queue)
+}
+
+// countTxs returns the number of transactions of all currencies in the lists.
+func countTxs(lists map[common.Address]*txList) int {
+	count := 0
+	for _, list := range lists {
+		count += list.Count()
 	}
-	queued := 0
-	return pending, queued
+	return count
 }
 
 // Content retrieves the data content of the transaction pool, returning all the
 // pending as well as queued transactions, grouped by account and sorted by nonce.
-func (nPool *NormalTxPool) Content() map[common.Address][]*types.Transaction {
+func (nPool *NormalTxPool) Content() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
 	nPool.mu.Lock()
 	defer nPool.mu.Unlock()
 	pending := make(map[common.Address][]*types.Transaction)
@@ -664,8 +696,15 @@ func (nPool *NormalTxPool) Content() map[common.Address][]*types.Transaction {
 		}
 		pending[addr] = txlist
 	}
-	//queued := make(map[common.Address][]*types.Transaction)
-	return pending
+	queued := make(map[common.Address][]*types.Transaction)
+	for addr, list := range nPool.queue {
+		txlist := make([]*types.Transaction, 0)
+		for _, txs := range list.txs {
+			txlist = append(txlist, txs.Flatten()...)
+		}
+		queued[addr] = txlist
+	}
+	return pending, queued
 }
 
 // Pending retrieves all currently processable transactions, groupped by origin
@@ -695,19 +734,16 @@ func (nPool *NormalTxPool) Pending() (map[common.Address][]types.SelfTransaction
 
 // 获取pending中剩余的交易（广播区块头后触发）
 //区块产生后将Pending中剩余的交易放入区块定时中，如果二十个区块还没有被打包则删除，如果已经被打包了则也删除
+// future队列中等待nonce的交易同样定时删除
 func (nPool *NormalTxPool) getPendingTx() {
 	nPool.mu.Lock()
-	pending := make(map[common.Address][]*types.Transaction)
-	for addr, list := range nPool.pending {
-		txlist := make([]*types.Transaction, 0)
-		for _, txs := range list.txs {
-			txlist = append(txlist, txs.Flatten()...)
-		}
-		pending[addr] = txlist
-	}
-	for _, txs := range pending {
-		for _, tx := range txs {
-			nPool.addBlockTiming(tx.Hash())
+	for _, lists := range []map[common.Address]*txList{nPool.pending, nPool.queue} {
+		for _, list := range lists {
+			for _, txs := range list.txs {
+				for _, tx := range txs.Flatten() {
+					nPool.addBlockTiming(tx.Hash())
+				}
+			}
 		}
 	}
 	nPool.mu.Unlock()
@@ -1239,13 +1275,36 @@ func (nPool *NormalTxPool) validateTx(tx *types.Transaction, local bool) error {
 	//if tx.GetTxV().Cmp(big.NewInt(128)) > 0 && len(txEx) <= 0 {
 	//	return ErrTXWrongful
 	//}
+	if !knownCurrency(nPool.currentState, tx.GetTxCurrency()) {
+		return ErrUnknownCurrency
+	}
+	if err := nPool.validateEscrowTx(tx, from); err != nil {
+		return err
+	}
+	if tx.GetMatrixType() == common.ExtraSignRotationTxType {
+		if err := nPool.validateSignRotationTx(tx, from); err != nil {
+			return err
+		}
+	}
+	if tx.GetMatrixType() == common.ExtraVersionProposalTxType {
+		height := nPool.chain.CurrentBlock().NumberU64() + 1
+		if _, _, _, err := checkVersionProposal(nPool.currentState, from, tx.Data(), height); err != nil {
+			return err
+		}
+	}
+	if tx.GetMatrixType() == common.ExtraCurrencyFeeRateTxType {
+		if _, _, err := checkCurrencyFeeRate(nPool.currentState, from, tx.Data()); err != nil {
+			return err
+		}
+	}
 	// Drop non-local transactions under our own minimal accepted gas price
 	gasprice, err := matrixstate.GetTxpoolGasLimit(nPool.currentState)
 	if err != nil {
 		return errors.New("get txpool gasPrice err")
 	}
 	nPool.gasPrice.Set(gasprice)
-	if nPool.gasPrice.Cmp(tx.GasPrice()) > 0 {
+	//设置了gas费率的币种,最低gas价格按费率换算为该币种
+	if CurrencyGasPrice(nPool.currentState, tx.GetTxCurrency(), nPool.gasPrice).Cmp(tx.GasPrice()) > 0 {
 		return ErrUnderpriced
 	}
 	// Ensure the transaction adheres to nonce ordering
@@ -1321,6 +1380,87 @@ func (nPool *NormalTxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.Gas() < intrGas {
 		return ErrIntrinsicGas
 	}
+	//委托交易需满足授权人设置的细粒度权限(VersionBeta开始生效)
+	if manparams.IsBetaVersion(matrixstate.GetVersionInfo(nPool.currentState)) {
+		totalGas := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
+		if _, err := CheckEntrustPermission(nPool.currentState, tx, nPool.chain.CurrentBlock().NumberU64()+1, uint64(time.Now().Unix()), totalGas); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateEscrowTx checks the arbiter of a new escrow transaction, and that the
+// sender of a release or refund transaction is allowed to settle the escrow it
+// refers to. Escrow transactions are unknown before VersionBeta.
+func (nPool *NormalTxPool) validateEscrowTx(tx *types.Transaction, from common.Address) error {
+	txtype := tx.GetMatrixType()
+	if txtype != common.ExtraEscrowTxType && txtype != common.ExtraEscrowReleaseTxType && txtype != common.ExtraEscrowRefundTxType {
+		return nil
+	}
+	if !manparams.IsBetaVersion(matrixstate.GetVersionInfo(nPool.currentState)) {
+		return ErrTXUnknownType
+	}
+	switch txtype {
+	case common.ExtraEscrowTxType:
+		if len(tx.Data()) != common.AddressLength {
+			return ErrEscrowArbiter
+		}
+		arbiter := common.BytesToAddress(tx.Data())
+		if arbiter == (common.Address{}) || arbiter == from {
+			return ErrEscrowArbiter
+		}
+	case common.ExtraEscrowReleaseTxType, common.ExtraEscrowRefundTxType:
+		hashlist := []common.Hash{common.BytesToHash(tx.Data())}
+		if txEx := tx.GetMatrix_EX(); len(txEx) > 0 {
+			for _, ex := range txEx[0].ExtraTo {
+				hashlist = append(hashlist, common.BytesToHash(ex.Payload))
+			}
+		}
+		for _, hash := range hashlist {
+			b := nPool.currentState.GetMatrixData(hash)
+			if b == nil {
+				return ErrEscrowNotFound
+			}
+			var et common.EscrowTx
+			if err := json.Unmarshal(b, &et); err != nil || et.Typ != common.ExtraEscrowTxType {
+				return ErrEscrowNotFound
+			}
+			if !et.CanSettle(txtype, from) {
+				return ErrEscrowNoRight
+			}
+		}
+	}
+	return nil
+}
+
+// validateSignRotationTx checks that the sender of a sign account rotation is a
+// depositor that already has a sign account, and that the new one is unused.
+// Sign account rotations are unknown before VersionBeta.
+func (nPool *NormalTxPool) validateSignRotationTx(tx *types.Transaction, from common.Address) error {
+	if !manparams.IsBetaVersion(matrixstate.GetVersionInfo(nPool.currentState)) {
+		return ErrTXUnknownType
+	}
+	if len(tx.Data()) != common.AddressLength {
+		return ErrSignRotation
+	}
+	newSignAccount := common.BytesToAddress(tx.Data())
+	if newSignAccount == (common.Address{}) || newSignAccount == from {
+		return ErrSignRotation
+	}
+	if deposit := depoistInfo.GetDeposit(nPool.currentState, from); deposit == nil || deposit.Sign() == 0 {
+		return ErrSignRotation
+	}
+	if depoistInfo.GetAuthAccount(nPool.currentState, from) == (common.Address{}) {
+		return ErrSignRotation
+	}
+	height := nPool.chain.CurrentBlock().NumberU64() + 1
+	if rotation := depoistInfo.GetSignRotation(nPool.currentState, from); rotation != nil && !rotation.IsRetired(height) {
+		return ErrSignRotation
+	}
+	if depoistInfo.GetDepositAccountAtHeight(nPool.currentState, newSignAccount, height) != (common.Address{}) {
+		return ErrSignRotation
+	}
 	return nil
 }
 
@@ -1360,10 +1500,20 @@ func (nPool *NormalTxPool) add(tx *types.Transaction, local bool) (bool, error)
 		invalidTxCounter.Inc(1)
 		return false, err
 	}
-	// 池子满了之后就不再加入
-	// If the transaction pool is full, discard transactions
-	if uint64(nPool.all.Count()) >= nPool.config.GlobalSlots+nPool.config.GlobalQueue {
-		return false, ErrTXPoolFull
+	// 池子满了之后, 出价不高于池中同币种最低出价的交易不再加入, 否则挤掉同币种出价最低的交易
+	// If the transaction pool is full, discard underpriced transactions
+	if capacity := nPool.config.GlobalSlots + nPool.config.GlobalQueue; uint64(nPool.all.Count()) >= capacity {
+		if nPool.priced.Underpriced(tx) {
+			log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
+			underpricedTxCounter.Inc(1)
+			return false, ErrTXPoolFull
+		}
+		drop := nPool.priced.Discard(nPool.all.Count()-int(capacity)+1, tx.GetTxCurrency())
+		for _, tx := range drop {
+			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
+			underpricedTxCounter.Inc(1)
+			nPool.removeTx(tx.Hash(), false)
+		}
 	}
 
 	// 如果交易中已经有了from就不需要在做解签
@@ -1372,16 +1522,41 @@ func (nPool *NormalTxPool) add(tx *types.Transaction, local bool) (bool, error)
 		return false, addrerr
 	}
 
-	if list := nPool.pending[from]; list != nil && list.Overlaps(tx) {
-		return false, ErrTXNonceSame
+	switch {
+	case nPool.pending[from] != nil && nPool.pending[from].Overlaps(tx):
+		// 相同nonce的交易, 只有出价提高PriceBump以上时才替换原交易
+		inserted, old := nPool.pending[from].Add(tx, nPool.config.PriceBump, offeredPrice)
+		if !inserted {
+			pendingDiscardCounter.Inc(1)
+			return false, ErrReplaceUnderpriced
+		}
+		nPool.dropTx(old)
+		pendingReplaceCounter.Inc(1)
+		nPool.all.Add(tx)
+		nPool.priced.Put(tx)
+
+	case tx.Nonce() > nPool.pendingState.GetNonce(from):
+		// nonce不连续, 放入future队列等待前面的交易
+		if _, err := nPool.enqueueTx(from, tx); err != nil {
+			return false, err
+		}
+		nPool.all.Add(tx)
+		nPool.priced.Put(tx)
+		nPool.truncateQueue()
+
+	default:
+		//将交易加入pending
+		nPool.all.Add(tx)
+		nPool.priced.Put(tx)
+		if !nPool.promoteTx(from, tx) {
+			return false, ErrReplaceUnderpriced
+		}
+		nPool.promoteExecutables([]common.Address{from})
 	}
-	//将交易加入pending
-	if nPool.pending[from] == nil {
-		nPool.pending[from] = newTxList(false, tx.GetTxCurrency())
+	// 交易可能被池子的数量限制挤掉
+	if nPool.all.Get(hash) == nil {
+		return false, ErrTXPoolFull
 	}
-	nPool.pending[from].Add(tx, 0)
-	nPool.all.Add(tx)
-	nPool.pendingState.SetNonce(from, tx.Nonce()+1)
 	selfRole := ca.GetRole()
 	if selfRole == common.RoleMiner || selfRole == common.RoleValidator {
 		tx_s := tx.GetTxS()
@@ -1404,6 +1579,183 @@ func (nPool *NormalTxPool) add(tx *types.Transaction, local bool) (bool, error)
 	return true, nil
 }
 
+// offeredPrice returns the gas price the transaction offers, which ranks it
+// against replacements of the same nonce and against the other transactions of
+// its currency when the pool is full. The offer is at least the lowest gas
+// price the matrix state accepts.
+func offeredPrice(tx *types.Transaction) *big.Int {
+	return tx.GasPrice()
+}
+
+// enqueueTx inserts a new transaction into the non-executable transaction queue
+// of the account, replacing an existing one of the same nonce if the price bump
+// is high enough.
+//
+// Note, the transaction is not added to the lookup, that is up to the caller.
+func (nPool *NormalTxPool) enqueueTx(from common.Address, tx *types.Transaction) (bool, error) {
+	if nPool.queue[from] == nil {
+		nPool.queue[from] = newTxList(false, tx.GetTxCurrency())
+	}
+	inserted, old := nPool.queue[from].Add(tx, nPool.config.PriceBump, offeredPrice)
+	if !inserted {
+		queuedDiscardCounter.Inc(1)
+		return false, ErrReplaceUnderpriced
+	}
+	if old != nil {
+		nPool.dropTx(old)
+		queuedReplaceCounter.Inc(1)
+	}
+	return old != nil, nil
+}
+
+// promoteTx moves a transaction already tracked by the lookup into the pending
+// list of the account and advances its pending nonce. It returns false if the
+// transaction was discarded in favour of a better one of the same nonce.
+func (nPool *NormalTxPool) promoteTx(addr common.Address, tx *types.Transaction) bool {
+	if nPool.pending[addr] == nil {
+		nPool.pending[addr] = newTxList(false, tx.GetTxCurrency())
+	}
+	inserted, old := nPool.pending[addr].Add(tx, nPool.config.PriceBump, offeredPrice)
+	if !inserted {
+		nPool.dropTx(tx)
+		pendingDiscardCounter.Inc(1)
+		return false
+	}
+	if old != nil {
+		nPool.dropTx(old)
+		pendingReplaceCounter.Inc(1)
+	}
+	if nPool.pendingState.GetNonce(addr) <= tx.Nonce() {
+		nPool.pendingState.SetNonce(addr, tx.Nonce()+1)
+	}
+	return true
+}
+
+// promoteExecutables moves the transactions of the future queue that became
+// processable into the pending lists, dropping the ones invalidated by the chain
+// state. A nil account list promotes the whole queue.
+func (nPool *NormalTxPool) promoteExecutables(accounts []common.Address) {
+	if accounts == nil {
+		accounts = make([]common.Address, 0, len(nPool.queue))
+		for addr := range nPool.queue {
+			accounts = append(accounts, addr)
+		}
+	}
+	for _, addr := range accounts {
+		list := nPool.queue[addr]
+		if list == nil {
+			continue
+		}
+		nonce := nPool.currentState.GetNonce(addr)
+		balance := new(big.Int)
+		for _, tAccount := range nPool.currentState.GetBalance(addr) {
+			if tAccount.AccountType == common.MainAccount {
+				balance = tAccount.Balance
+				break
+			}
+		}
+		for typ, txs := range list.txs {
+			// Drop all transactions that are deemed too old (low nonce)
+			for _, tx := range txs.Forward(nonce) {
+				log.Trace("Removed old queued transaction", "hash", tx.Hash())
+				nPool.dropTx(tx)
+			}
+			// Drop all transactions that are too costly (low balance or out of gas)
+			drops, _ := list.Filter(balance, nPool.currentMaxGas, typ)
+			for _, tx := range drops {
+				log.Trace("Removed unpayable queued transaction", "hash", tx.Hash())
+				nPool.dropTx(tx)
+				queuedNofundsCounter.Inc(1)
+			}
+		}
+		// Gather all executable transactions and promote them
+		for _, tx := range list.Ready(nPool.pendingState.GetNonce(addr)) {
+			log.Trace("Promoting queued transaction", "hash", tx.Hash())
+			nPool.promoteTx(addr, tx)
+		}
+		// Drop all transactions over the allowed limit
+		for _, tx := range list.Cap(int(nPool.config.AccountQueue)) {
+			log.Trace("Removed cap-exceeding queued transaction", "hash", tx.Hash())
+			nPool.dropTx(tx)
+			queuedRateLimitCounter.Inc(1)
+		}
+		if list.Count() == 0 {
+			delete(nPool.queue, addr)
+		}
+	}
+	nPool.truncatePending()
+	nPool.truncateQueue()
+}
+
+// truncatePending drops the highest nonce transactions of the accounts having
+// more than AccountSlots pending transactions, until the pending pool fits into
+// GlobalSlots again.
+func (nPool *NormalTxPool) truncatePending() {
+	for _, tx := range nPool.truncateLists(nPool.pending, nPool.config.GlobalSlots, nPool.config.AccountSlots) {
+		addr, _ := nPool.checkTxFrom(tx)
+		if nPool.pendingState.GetNonce(addr) > tx.Nonce() {
+			nPool.pendingState.SetNonce(addr, tx.Nonce())
+		}
+		pendingRateLimitCounter.Inc(1)
+	}
+}
+
+// truncateQueue drops the highest nonce queued transactions, starting with the
+// accounts queuing the most, until the future queue fits into GlobalQueue again.
+func (nPool *NormalTxPool) truncateQueue() {
+	for range nPool.truncateLists(nPool.queue, nPool.config.GlobalQueue, 0) {
+		queuedRateLimitCounter.Inc(1)
+	}
+}
+
+// truncateLists drops transactions from the lists until at most limit are left,
+// one highest nonce transaction per account and round, never going below keep
+// transactions for an account. The dropped transactions are returned.
+func (nPool *NormalTxPool) truncateLists(lists map[common.Address]*txList, limit, keep uint64) []*types.Transaction {
+	count := uint64(countTxs(lists))
+	if count <= limit {
+		return nil
+	}
+	var dropped []*types.Transaction
+	for count > limit {
+		// 每一轮从交易数最多的账户开始丢弃
+		offenders := make([]common.Address, 0)
+		for addr, list := range lists {
+			if uint64(list.Count()) > keep {
+				offenders = append(offenders, addr)
+			}
+		}
+		if len(offenders) == 0 {
+			break
+		}
+		sort.Slice(offenders, func(i, j int) bool { return lists[offenders[i]].Count() > lists[offenders[j]].Count() })
+		for _, addr := range offenders {
+			if count <= limit {
+				break
+			}
+			list := lists[addr]
+			for _, tx := range list.Cap(list.Count() - 1) {
+				log.Trace("Removed fairness-exceeding transaction", "hash", tx.Hash())
+				nPool.dropTx(tx)
+				dropped = append(dropped, tx)
+				count--
+			}
+			if list.Count() == 0 {
+				delete(lists, addr)
+			}
+		}
+	}
+	return dropped
+}
+
+// dropTx removes a transaction, which is no longer part of any pending or queued
+// list, from the lookup and from the S/N propagation maps.
+func (nPool *NormalTxPool) dropTx(tx *types.Transaction) {
+	nPool.all.Remove(tx.Hash())
+	nPool.priced.Removed()
+	nPool.deleteMap(tx)
+}
+
 // AddLocal enqueues a single transaction into the pool if it is valid, marking
 // the sender as a local one in the mean time, ensuring it goes around the local
 // pricing constraints.
@@ -1446,7 +1798,7 @@ func (nPool *NormalTxPool) Status(hashes []common.Hash) []TxStatus {
 		if tx := nPool.all.Get(hash); tx != nil {
 			// 如果交易中已经有了from就不需要在做解签
 			from, _ := nPool.checkTxFrom(tx)
-			if nPool.pending[from] != nil && nPool.pending[from].txs[tx.GetTxCurrency()].items[tx.Nonce()] != nil {
+			if nPool.pending[from] != nil && nPool.pending[from].txs[tx.GetTxCurrency()] != nil && nPool.pending[from].txs[tx.GetTxCurrency()].items[tx.Nonce()] == tx {
 				status[i] = TxStatusPending
 			} else {
 				status[i] = TxStatusQueued
@@ -1476,6 +1828,7 @@ func (nPool *NormalTxPool) removeTx(hash common.Hash, outofbound bool) {
 
 	// Remove it from the list of known transactions
 	nPool.all.Remove(hash)
+	nPool.priced.Removed()
 
 	// ========begin=========
 	nPool.deleteMap(tx)
@@ -1483,8 +1836,24 @@ func (nPool *NormalTxPool) removeTx(hash common.Hash, outofbound bool) {
 	// Remove the transaction from the pending lists and reset the account nonce
 	if pending := nPool.pending[addr]; pending != nil {
 		if removed, _ := pending.Remove(tx); removed {
+			// 已打包的交易被定时删除时, 后续nonce的交易仍可执行, 不能退回future队列
+			if nPool.currentState.GetNonce(addr) > tx.Nonce() {
+				if pending.Count() == 0 {
+					delete(nPool.pending, addr)
+				}
+				return
+			}
+			// 后续nonce的交易已不可执行, 退回future队列
+			for _, txs := range pending.txs {
+				for _, invalid := range txs.Filter(func(t *types.Transaction) bool { return t.Nonce() > tx.Nonce() }) {
+					log.Trace("Demoting pending transaction", "hash", invalid.Hash())
+					if _, err := nPool.enqueueTx(addr, invalid); err != nil {
+						nPool.dropTx(invalid)
+					}
+				}
+			}
 			// If no more pending transactions are left, remove the list
-			if pending.Empty(tx.GetTxCurrency()) {
+			if pending.Count() == 0 {
 				delete(nPool.pending, addr)
 			}
 			// Update the account nonce if needed
@@ -1494,6 +1863,13 @@ func (nPool *NormalTxPool) removeTx(hash common.Hash, outofbound bool) {
 			return
 		}
 	}
+	// Transaction is in the future queue
+	if future := nPool.queue[addr]; future != nil {
+		future.Remove(tx)
+		if future.Count() == 0 {
+			delete(nPool.queue, addr)
+		}
+	}
 }
 
 // demoteUnexecutables removes invalid and processed transactions from the pools
@@ -1502,8 +1878,9 @@ func (nPool *NormalTxPool) removeTx(hash common.Hash, outofbound bool) {
 func (nPool *NormalTxPool) DemoteUnexecutables() {
 	// Iterate over all accounts and demote any non-executable transactions
 	for addr, list := range nPool.pending {
+		nonce := nPool.currentState.GetNonce(addr)
+		lowest := uint64(math.MaxUint64)
 		for typ, txs := range list.txs {
-			nonce := nPool.currentState.GetNonce(addr)
 			// Drop all transactions that are deemed too old (low nonce)
 			for _, tx := range txs.Forward(nonce) {
 				// ========begin=========
@@ -1512,7 +1889,7 @@ func (nPool *NormalTxPool) DemoteUnexecutables() {
 				hash := tx.Hash()
 				//log.Trace("Removed old pending transaction", "hash", hash)
 				nPool.all.Remove(hash)
-				//nPool.priced.Removed()
+				nPool.priced.Removed()
 			}
 			// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 			tBalance := new(big.Int)
@@ -1530,14 +1907,34 @@ func (nPool *NormalTxPool) DemoteUnexecutables() {
 				hash := tx.Hash()
 				log.Trace("Removed unpayable pending transaction", "hash", hash)
 				nPool.all.Remove(hash)
-				//nPool.priced.Removed()
+				nPool.priced.Removed()
 				pendingNofundsCounter.Inc(1)
+				if tx.Nonce() < lowest {
+					lowest = tx.Nonce()
+				}
 			}
-			// Delete the entire queue entry if it became empty.
-			if list.Empty(typ) {
-				delete(nPool.pending, addr)
+		}
+		// 账户的nonce跨币种连续, 被丢弃交易之后的交易已不可执行;
+		// 若最前面的nonce已不在pending中, 则全部交易都不可执行, 统统退回future队列
+		gapped := true
+		for _, txs := range list.txs {
+			if txs.Get(nonce) != nil {
+				gapped = false
+				break
+			}
+		}
+		for _, txs := range list.txs {
+			for _, tx := range txs.Filter(func(t *types.Transaction) bool { return gapped || t.Nonce() > lowest }) {
+				log.Trace("Demoting pending transaction", "hash", tx.Hash())
+				if _, err := nPool.enqueueTx(addr, tx); err != nil {
+					nPool.dropTx(tx)
+				}
 			}
 		}
+		// Delete the entire queue entry if it became empty.
+		if list.Count() == 0 {
+			delete(nPool.pending, addr)
+		}
 	}
 }
 
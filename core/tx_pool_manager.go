@@ -72,9 +72,12 @@ func NewInitblacklist() *Blacklist {
 	return b
 }
 
-var SelfBlackList *Blacklist
-
+// FindBlackAddress reports whether addr is blacklisted. A nil blacklist holds
+// no address.
 func (b *Blacklist) FindBlackAddress(addr common.Address) bool {
+	if b == nil {
+		return false
+	}
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	_, ok := b.Bmap[addr]
@@ -113,7 +116,6 @@ func NewTxPoolManager(config TxPoolConfig, chainconfig *params.ChainConfig, chai
 		sendTxCh:     make(chan NewTxsEvent),
 		chain:        chain,
 	}
-	SelfBlackList = NewInitblacklist()
 	go txPoolManager.loop(config, chainconfig, chain, path)
 	return txPoolManager
 }
@@ -236,7 +238,11 @@ func (pm *TxPoolManager) Pending() (map[common.Address]types.SelfTransactions, e
 	}
 	return txser, nil
 }
-func BlackListFilter(tx types.SelfTransaction, state *state.StateDB) bool {
+
+// BlackListFilter reports whether the transaction passes the account blacklist
+// of the matrix state, the recipient blacklist of the chain and, from
+// VersionBeta on, the known currencies.
+func BlackListFilter(tx types.SelfTransaction, state *state.StateDB, blacklist *Blacklist) bool {
 	//TODO 目前只要求过滤一个币种. 需要去状态树上获取被过滤的币种
 	//state, err := pm.chain.State()
 	//if err != nil {
@@ -258,9 +264,9 @@ func BlackListFilter(tx types.SelfTransaction, state *state.StateDB) bool {
 		}
 	}
 
-	ct := tx.GetTxCurrency()
-	if ct == "" {
-
+	if ct := tx.GetTxCurrency(); !knownCurrency(state, ct) {
+		log.Error("未知币种的交易", "币种", ct)
+		return false
 	}
 
 	//奖励交易账户不匹配
@@ -281,7 +287,7 @@ func BlackListFilter(tx types.SelfTransaction, state *state.StateDB) bool {
 
 	//黑账户过滤
 	if tx.To() != nil {
-		if SelfBlackList.FindBlackAddress(*tx.To()) {
+		if blacklist.FindBlackAddress(*tx.To()) {
 			return false
 		}
 	}
@@ -0,0 +1,72 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+)
+
+func TestBlackListFilter(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	statedb := newTestState()
+	blacklist := NewInitblacklist()
+	black := common.HexToAddress("0x7097f41F1C1847D52407C629d0E0ae0fDD24fd58")
+
+	send := func(to common.Address) types.SelfTransaction {
+		tx := types.NewTransaction(params.NonceAddOne, to, big.NewInt(1), 21000, testGasPrice, nil, nil, nil, nil, 0, 0, "MAN", 0)
+		signed, _ := types.SignTx(tx, types.NewEIP155Signer(params.TestChainConfig.ChainId), key)
+		signed.SetFromLoad(crypto.PubkeyToAddress(key.PublicKey))
+		return signed
+	}
+	if !BlackListFilter(send(common.HexToAddress("0x01")), statedb, blacklist) {
+		t.Errorf("transaction to a normal account filtered")
+	}
+	if BlackListFilter(send(black), statedb, blacklist) {
+		t.Errorf("transaction to a blacklisted account passed")
+	}
+	// 没有黑名单时不过滤接收方
+	if !BlackListFilter(send(black), statedb, nil) {
+		t.Errorf("transaction filtered by a nil blacklist")
+	}
+	blacklist.AddBlackAddress(common.HexToAddress("0x01"))
+	if BlackListFilter(send(common.HexToAddress("0x01")), statedb, blacklist) {
+		t.Errorf("transaction to an added blacklisted account passed")
+	}
+}
+
+func TestBlackListFilterCurrency(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	statedb := newTestState()
+
+	send := func(currency string) types.SelfTransaction {
+		tx := types.NewTransaction(params.NonceAddOne, common.HexToAddress("0x01"), big.NewInt(1), 21000, testGasPrice, nil, nil, nil, nil, 0, 0, currency, 0)
+		signed, _ := types.SignTx(tx, types.NewEIP155Signer(params.TestChainConfig.ChainId), key)
+		signed.SetFromLoad(crypto.PubkeyToAddress(key.PublicKey))
+		return signed
+	}
+	// Beta版本之前不校验币种
+	if !BlackListFilter(send("BTC"), statedb, nil) {
+		t.Errorf("alpha state: transaction of another currency filtered")
+	}
+	matrixstate.SetVersionInfo(statedb, manparams.VersionBeta)
+	if !BlackListFilter(send("MAN"), statedb, nil) {
+		t.Errorf("beta state: MAN transaction filtered")
+	}
+	if BlackListFilter(send("BTC"), statedb, nil) {
+		t.Errorf("beta state: transaction of an unknown currency passed")
+	}
+	setCurrencyFeeRate(t, statedb, "BTC", common.CurrencyFeeRateBase)
+	if !BlackListFilter(send("BTC"), statedb, nil) {
+		t.Errorf("beta state: transaction of a currency with a fee rate filtered")
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2018 The MATRIX Authors
+// Copyright (c) 2018 The MATRIX Authors
 // Distributed under the MIT software license, see the accompanying
 // file COPYING or or http://www.opensource.org/licenses/mit-license.php
 
@@ -7,29 +7,32 @@ package core
 import (
 	"crypto/ecdsa"
 	"fmt"
-	"io/ioutil"
 	"math/big"
 	"math/rand"
-	"os"
 	"testing"
 	"time"
 
 	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/crypto"
 	"github.com/MatrixAINetwork/go-matrix/event"
 	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
 	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
 )
 
-// testTxPoolConfig is a transaction pool configuration without stateful disk
-// sideeffects used during testing.
+// testTxPoolConfig is a transaction pool configuration used during testing.
 var testTxPoolConfig TxPoolConfig
 
+// testGasPrice is the minimum gas price the pool accepts with the default
+// matrix state.
+var testGasPrice = new(big.Int).SetUint64(params.TxGasPrice)
+
 func init() {
 	testTxPoolConfig = DefaultTxPoolConfig
-	testTxPoolConfig.Journal = ""
 }
 
 type testBlockChain struct {
@@ -40,6 +43,7 @@ type testBlockChain struct {
 
 func (bc *testBlockChain) CurrentBlock() *types.Block {
 	return types.NewBlock(&types.Header{
+		Number:   big.NewInt(0),
 		GasLimit: bc.gasLimit,
 	}, nil, nil, nil)
 }
@@ -52,31 +56,85 @@ func (bc *testBlockChain) StateAt(common.Hash) (*state.StateDB, error) {
 	return bc.statedb, nil
 }
 
+func (bc *testBlockChain) State() (*state.StateDB, error) {
+	return bc.statedb, nil
+}
+
 func (bc *testBlockChain) SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) event.Subscription {
 	return bc.chainHeadFeed.Subscribe(ch)
 }
 
+func (bc *testBlockChain) GetA0AccountFromAnyAccountAtSignHeight(account common.Address, blockHash common.Hash, signHeight uint64) (common.Address, common.Address, error) {
+	return account, account, nil
+}
+
+// newTestState creates an empty state with the matrix state the pool reads its
+// minimum gas price from.
+func newTestState() *state.StateDB {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
+	matrixstate.SetVersionInfo(statedb, manparams.VersionAlpha)
+	return statedb
+}
+
+// price returns the gas price of n times the minimum gas price.
+func price(n int64) *big.Int {
+	return new(big.Int).Mul(testGasPrice, big.NewInt(n))
+}
+
+// funds returns the balance paying for n transactions of the given gas at the
+// minimum gas price.
+func funds(n int64, gas uint64) *big.Int {
+	cost := new(big.Int).Mul(testGasPrice, new(big.Int).SetUint64(gas))
+	cost.Add(cost, big.NewInt(100))
+	return cost.Mul(cost, big.NewInt(n))
+}
+
 func transaction(nonce uint64, gaslimit uint64, key *ecdsa.PrivateKey) *types.Transaction {
-	return pricedTransaction(nonce, gaslimit, big.NewInt(1), key)
+	return pricedTransaction(nonce, gaslimit, testGasPrice, key)
 }
 
 func pricedTransaction(nonce uint64, gaslimit uint64, gasprice *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
-	tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(100), gaslimit, gasprice, nil), types.HomesteadSigner{}, key)
-	return tx
+	return currencyTransaction(nonce, gaslimit, gasprice, "MAN", key)
 }
 
-func setupTxPool() (*TxPool, *ecdsa.PrivateKey) {
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+func currencyTransaction(nonce uint64, gaslimit uint64, gasprice *big.Int, currency string, key *ecdsa.PrivateKey) *types.Transaction {
+	tx := types.NewTransaction(nonce|params.NonceAddOne, common.Address{}, big.NewInt(100), gaslimit, gasprice, nil, nil, nil, nil, 0, 0, currency, 0)
+	signed, _ := types.SignTx(tx, types.NewEIP155Signer(params.TestChainConfig.ChainId), key)
+	return signed.(*types.Transaction)
+}
+
+func setupTxPool() (*NormalTxPool, *ecdsa.PrivateKey) {
+	return setupTxPoolWithConfig(testTxPoolConfig)
+}
+
+func setupTxPoolWithConfig(config TxPoolConfig) (*NormalTxPool, *ecdsa.PrivateKey) {
+	blockchain := &testBlockChain{newTestState(), 1000000, new(event.Feed)}
 
 	key, _ := crypto.GenerateKey()
-	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain, "")
+	pool := NewTxPool(config, params.TestChainConfig, blockchain, make(chan NewTxsEvent, 1024))
 
 	return pool, key
 }
 
+// lockedReset resets the pool to the state of the test chain.
+func lockedReset(pool *NormalTxPool) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.reset(nil, nil)
+}
+
+// addTxs adds a batch of transactions one by one, returning the errors.
+func addTxs(pool *NormalTxPool, txs []*types.Transaction) []error {
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		errs[i] = pool.AddTxPool(tx)
+	}
+	return errs
+}
+
 // validateTxPoolInternals checks various consistency invariants within the pool.
-func validateTxPoolInternals(pool *TxPool) error {
+func validateTxPoolInternals(pool *NormalTxPool) error {
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 
@@ -85,16 +143,18 @@ func validateTxPoolInternals(pool *TxPool) error {
 	if total := pool.all.Count(); total != pending+queued {
 		return fmt.Errorf("total transaction count %d != %d pending + %d queued", total, pending, queued)
 	}
-	if priced := pool.priced.items.Len() - pool.priced.stales; priced != pending+queued {
+	if priced := pool.priced.Len() - pool.priced.stales; priced != pending+queued {
 		return fmt.Errorf("total priced transaction count %d != %d pending + %d queued", priced, pending, queued)
 	}
 	// Ensure the next nonce to assign is the correct one
-	for addr, txs := range pool.pending {
+	for addr, list := range pool.pending {
 		// Find the last transaction
 		var last uint64
-		for nonce := range txs.txs.items {
-			if last < nonce {
-				last = nonce
+		for _, txs := range list.txs {
+			for nonce := range txs.items {
+				if last < nonce {
+					last = nonce
+				}
 			}
 		}
 		if nonce := pool.pendingState.GetNonce(addr); nonce != last+1 {
@@ -104,160 +164,153 @@ func validateTxPoolInternals(pool *TxPool) error {
 	return nil
 }
 
-// validateEvents checks that the correct number of transaction addition events
-// were fired on the pool's event feed.
-func validateEvents(events chan NewTxsEvent, count int) error {
-	var received []*types.Transaction
-
-	for len(received) < count {
-		select {
-		case ev := <-events:
-			received = append(received, ev.Txs...)
-		case <-time.After(time.Second):
-			return fmt.Errorf("event #%d not fired", received)
-		}
-	}
-	if len(received) > count {
-		return fmt.Errorf("more than %d events fired: %v", count, received[count:])
-	}
-	select {
-	case ev := <-events:
-		return fmt.Errorf("more than %d events fired: %v", count, ev.Txs)
-
-	case <-time.After(50 * time.Millisecond):
-		// This branch should be "default", but it's a data race between goroutines,
-		// reading the event channel and pushing into it, so better wait a bit ensuring
-		// really nothing gets injected.
-	}
-	return nil
+// pendingTx reports whether the transaction is in the pending list of the account.
+func pendingTx(pool *NormalTxPool, addr common.Address, tx *types.Transaction) bool {
+	return listedTx(pool.pending[addr], tx)
 }
 
-func deriveSender(tx *types.Transaction) (common.Address, error) {
-	return types.Sender(types.HomesteadSigner{}, tx)
-}
-
-type testChain struct {
-	*testBlockChain
-	address common.Address
-	trigger *bool
+// queuedTx reports whether the transaction is in the future queue of the account.
+func queuedTx(pool *NormalTxPool, addr common.Address, tx *types.Transaction) bool {
+	return listedTx(pool.queue[addr], tx)
 }
 
-// testChain.State() is used multiple times to reset the pending state.
-// when simulate is true it will create a state that indicates
-// that tx0 and tx1 are included in the chain.
-func (c *testChain) State() (*state.StateDB, error) {
-	// delay "state change" by one. The tx pool fetches the
-	// state multiple times and by delaying it a bit we simulate
-	// a state change between those fetches.
-	stdb := c.statedb
-	if *c.trigger {
-		c.statedb, _ = state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-		// simulate that the new head block included tx0 and tx1
-		c.statedb.SetNonce(c.address, 2)
-		c.statedb.SetBalance(c.address, new(big.Int).SetUint64(params.Maner))
-		*c.trigger = false
+func listedTx(list *txList, tx *types.Transaction) bool {
+	if list == nil || list.txs[tx.GetTxCurrency()] == nil {
+		return false
 	}
-	return stdb, nil
+	return list.txs[tx.GetTxCurrency()].items[tx.Nonce()] == tx
 }
 
-// This test simulates a scenario where a new block is imported during a
-// state reset and tests whether the pending state is in sync with the
-// block head event that initiated the resetState().
-func TestStateChangeDuringTransactionPoolReset(t *testing.T) {
-	t.Parallel()
-
-	var (
-		key, _     = crypto.GenerateKey()
-		address    = crypto.PubkeyToAddress(key.PublicKey)
-		statedb, _ = state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-		trigger    = false
-	)
+func deriveSender(tx *types.Transaction) (common.Address, error) {
+	return types.Sender(types.NewEIP155Signer(params.TestChainConfig.ChainId), tx)
+}
 
-	// setup pool with 2 transaction in it
-	statedb.SetBalance(address, new(big.Int).SetUint64(params.Maner))
-	blockchain := &testChain{&testBlockChain{statedb, 1000000000, new(event.Feed)}, address, &trigger}
+func TestInvalidTransactions(t *testing.T) {
+	pool, key := setupTxPool()
+	defer pool.Stop()
 
-	tx0 := transaction(0, 100000, key)
-	tx1 := transaction(1, 100000, key)
+	tx := transaction(0, 100, key)
+	from, _ := deriveSender(tx)
 
-	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain, "")
-	defer pool.Stop()
+	pool.currentState.AddBalance(common.MainAccount, from, big.NewInt(1))
+	if err := pool.AddTxPool(tx); err != ErrInsufficientFunds {
+		t.Error("expected", ErrInsufficientFunds, "got", err)
+	}
 
-	nonce := pool.State().GetNonce(address)
-	if nonce != 0 {
-		t.Fatalf("Invalid nonce, want 0, got %d", nonce)
+	balance := new(big.Int).Add(tx.Value(), new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas()), tx.GasPrice()))
+	pool.currentState.AddBalance(common.MainAccount, from, balance)
+	if err := pool.AddTxPool(tx); err != ErrIntrinsicGas {
+		t.Error("expected", ErrIntrinsicGas, "got", err)
 	}
 
-	pool.AddRemotes(types.Transactions{tx0, tx1})
+	pool.currentState.SetNonce(from, 1)
+	pool.currentState.AddBalance(common.MainAccount, from, funds(10, 100000))
+	tx = transaction(0, 100000, key)
+	if err := pool.AddTxPool(tx); err != ErrNonceTooLow {
+		t.Error("expected", ErrNonceTooLow, "got", err)
+	}
 
-	nonce = pool.State().GetNonce(address)
-	if nonce != 2 {
-		t.Fatalf("Invalid nonce, want 2, got %d", nonce)
+	tx = pricedTransaction(1, 100000, new(big.Int).Sub(testGasPrice, common.Big1), key)
+	if err := pool.AddTxPool(tx); err != ErrUnderpriced {
+		t.Error("expected", ErrUnderpriced, "got", err)
 	}
+	tx = transaction(1, 100000, key)
+	if err := pool.AddTxPool(tx); err != nil {
+		t.Error("expected", nil, "got", err)
+	}
+	if err := pool.AddTxPool(tx); err != ErrKnownTransaction {
+		t.Error("expected", ErrKnownTransaction, "got", err)
+	}
+}
 
-	// trigger state change in the background
-	trigger = true
+func TestTransactionUnknownCurrency(t *testing.T) {
+	pool, key := setupTxPool()
+	defer pool.Stop()
 
-	pool.lockedReset(nil, nil)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(common.MainAccount, from, funds(10, 100000))
 
-	_, err := pool.Pending()
-	if err != nil {
-		t.Fatalf("Could not fetch pending transactions: %v", err)
+	// Beta版本之前其他币种的交易仍然花费MAN
+	if err := pool.AddTxPool(currencyTransaction(0, 100000, testGasPrice, "BTC", key)); err != nil {
+		t.Error("alpha state: expected", nil, "got", err)
+	}
+	matrixstate.SetVersionInfo(pool.currentState, manparams.VersionBeta)
+	if err := pool.AddTxPool(currencyTransaction(1, 100000, testGasPrice, "BTC", key)); err != ErrUnknownCurrency {
+		t.Error("beta state: expected", ErrUnknownCurrency, "got", err)
+	}
+	if err := pool.AddTxPool(transaction(1, 100000, key)); err != nil {
+		t.Error("beta state: expected", nil, "got", err)
 	}
-	nonce = pool.State().GetNonce(address)
-	if nonce != 2 {
-		t.Fatalf("Invalid nonce, want 2, got %d", nonce)
+	// 设置了gas费率的币种,最低gas价格按费率换算
+	setCurrencyFeeRate(t, pool.currentState, "BTC", 2*common.CurrencyFeeRateBase)
+	if err := pool.AddTxPool(currencyTransaction(2, 100000, testGasPrice, "BTC", key)); err != ErrUnderpriced {
+		t.Error("beta state: expected", ErrUnderpriced, "got", err)
+	}
+	if err := pool.AddTxPool(currencyTransaction(2, 100000, price(2), "BTC", key)); err != nil {
+		t.Error("beta state: expected", nil, "got", err)
 	}
 }
 
-func TestInvalidTransactions(t *testing.T) {
-	t.Parallel()
-
+func TestValidateEscrowTx(t *testing.T) {
 	pool, key := setupTxPool()
 	defer pool.Stop()
 
-	tx := transaction(0, 100, key)
-	from, _ := deriveSender(tx)
-
-	pool.currentState.AddBalance(from, big.NewInt(1))
-	if err := pool.AddRemote(tx); err != ErrInsufficientFunds {
-		t.Error("expected", ErrInsufficientFunds)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	escrow := func(typ byte, data []byte) *types.Transaction {
+		return types.NewTransaction(params.NonceAddOne, common.HexToAddress("0x02"), big.NewInt(1), 21000, testGasPrice, data, nil, nil, nil, typ, 0, "MAN", 0)
 	}
+	arbiter := common.HexToAddress("0x01").Bytes()
 
-	balance := new(big.Int).Add(tx.Value(), new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas()), tx.GasPrice()))
-	pool.currentState.AddBalance(from, balance)
-	if err := pool.AddRemote(tx); err != ErrIntrinsicGas {
-		t.Error("expected", ErrIntrinsicGas, "got", err)
+	// Beta版本之前不支持担保交易
+	for _, typ := range []byte{common.ExtraEscrowTxType, common.ExtraEscrowReleaseTxType, common.ExtraEscrowRefundTxType} {
+		if err := pool.validateEscrowTx(escrow(typ, arbiter), from); err != ErrTXUnknownType {
+			t.Error("alpha state: type", typ, "expected", ErrTXUnknownType, "got", err)
+		}
 	}
-
-	pool.currentState.SetNonce(from, 1)
-	pool.currentState.AddBalance(from, big.NewInt(0xffffffffffffff))
-	tx = transaction(0, 100000, key)
-	if err := pool.AddRemote(tx); err != ErrNonceTooLow {
-		t.Error("expected", ErrNonceTooLow)
+	if err := pool.validateEscrowTx(escrow(common.ExtraNormalTxType, nil), from); err != nil {
+		t.Error("alpha state: normal tx expected", nil, "got", err)
+	}
+	matrixstate.SetVersionInfo(pool.currentState, manparams.VersionBeta)
+	if err := pool.validateEscrowTx(escrow(common.ExtraEscrowTxType, arbiter), from); err != nil {
+		t.Error("beta state: expected", nil, "got", err)
+	}
+	if err := pool.validateEscrowTx(escrow(common.ExtraEscrowTxType, from.Bytes()), from); err != ErrEscrowArbiter {
+		t.Error("beta state: expected", ErrEscrowArbiter, "got", err)
 	}
+	if err := pool.validateEscrowTx(escrow(common.ExtraEscrowReleaseTxType, common.Hash{1}.Bytes()), from); err != ErrEscrowNotFound {
+		t.Error("beta state: expected", ErrEscrowNotFound, "got", err)
+	}
+}
 
-	tx = transaction(1, 100000, key)
-	pool.gasPrice = big.NewInt(1000)
-	if err := pool.AddRemote(tx); err != ErrUnderpriced {
-		t.Error("expected", ErrUnderpriced, "got", err)
+func TestValidateSignRotationTx(t *testing.T) {
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	tx := types.NewTransaction(params.NonceAddOne, common.Address{}, big.NewInt(0), 21000, testGasPrice, common.HexToAddress("0x01").Bytes(), nil, nil, nil, common.ExtraSignRotationTxType, 0, "MAN", 0)
+
+	// Beta版本之前不支持签名账户轮换
+	if err := pool.validateSignRotationTx(tx, from); err != ErrTXUnknownType {
+		t.Error("alpha state: expected", ErrTXUnknownType, "got", err)
 	}
-	if err := pool.AddLocal(tx); err != nil {
-		t.Error("expected", nil, "got", err)
+	matrixstate.SetVersionInfo(pool.currentState, manparams.VersionBeta)
+	tx = types.NewTransaction(params.NonceAddOne, common.Address{}, big.NewInt(0), 21000, testGasPrice, from.Bytes(), nil, nil, nil, common.ExtraSignRotationTxType, 0, "MAN", 0)
+	if err := pool.validateSignRotationTx(tx, from); err != ErrSignRotation { // 不能轮换为发送人自己
+		t.Error("beta state: expected", ErrSignRotation, "got", err)
 	}
 }
 
 func TestTransactionQueue(t *testing.T) {
-	t.Parallel()
-
 	pool, key := setupTxPool()
 	defer pool.Stop()
 
 	tx := transaction(0, 100, key)
 	from, _ := deriveSender(tx)
-	pool.currentState.AddBalance(from, big.NewInt(1000))
-	pool.lockedReset(nil, nil)
-	pool.enqueueTx(tx.Hash(), tx)
+	pool.currentState.AddBalance(common.MainAccount, from, funds(10, 100))
+	lockedReset(pool)
+	pool.enqueueTx(from, tx)
+	pool.all.Add(tx)
+	pool.priced.Put(tx)
 
 	pool.promoteExecutables([]common.Address{from})
 	if len(pool.pending) != 1 {
@@ -265,14 +318,14 @@ func TestTransactionQueue(t *testing.T) {
 	}
 
 	tx = transaction(1, 100, key)
-	from, _ = deriveSender(tx)
 	pool.currentState.SetNonce(from, 2)
-	pool.enqueueTx(tx.Hash(), tx)
+	pool.enqueueTx(from, tx)
+	pool.all.Add(tx)
+	pool.priced.Put(tx)
 	pool.promoteExecutables([]common.Address{from})
-	if _, ok := pool.pending[from].txs.items[tx.Nonce()]; ok {
-		t.Error("expected transaction to be in tx pool")
+	if pendingTx(pool, from, tx) {
+		t.Error("expected stale transaction to be dropped")
 	}
-
 	if len(pool.queue) > 0 {
 		t.Error("expected transaction queue to be empty. is", len(pool.queue))
 	}
@@ -284,50 +337,48 @@ func TestTransactionQueue(t *testing.T) {
 	tx2 := transaction(10, 100, key)
 	tx3 := transaction(11, 100, key)
 	from, _ = deriveSender(tx1)
-	pool.currentState.AddBalance(from, big.NewInt(1000))
-	pool.lockedReset(nil, nil)
-
-	pool.enqueueTx(tx1.Hash(), tx1)
-	pool.enqueueTx(tx2.Hash(), tx2)
-	pool.enqueueTx(tx3.Hash(), tx3)
+	pool.currentState.AddBalance(common.MainAccount, from, funds(10, 100))
+	lockedReset(pool)
 
+	for _, tx := range []*types.Transaction{tx1, tx2, tx3} {
+		pool.enqueueTx(from, tx)
+		pool.all.Add(tx)
+		pool.priced.Put(tx)
+	}
 	pool.promoteExecutables([]common.Address{from})
 
 	if len(pool.pending) != 1 {
 		t.Error("expected tx pool to be 1, got", len(pool.pending))
 	}
-	if pool.queue[from].Len() != 2 {
-		t.Error("expected len(queue) == 2, got", pool.queue[from].Len())
+	if pool.queue[from].Count() != 2 {
+		t.Error("expected len(queue) == 2, got", pool.queue[from].Count())
 	}
 }
 
 func TestTransactionNegativeValue(t *testing.T) {
-	t.Parallel()
-
 	pool, key := setupTxPool()
 	defer pool.Stop()
 
-	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(-1), 100, big.NewInt(1), nil), types.HomesteadSigner{}, key)
-	from, _ := deriveSender(tx)
-	pool.currentState.AddBalance(from, big.NewInt(1))
-	if err := pool.AddRemote(tx); err != ErrNegativeValue {
+	tx := types.NewTransaction(params.NonceAddOne, common.Address{}, big.NewInt(-1), 100000, testGasPrice, nil, nil, nil, nil, 0, 0, "MAN", 0)
+	signed, _ := types.SignTx(tx, types.NewEIP155Signer(params.TestChainConfig.ChainId), key)
+	from, _ := deriveSender(signed.(*types.Transaction))
+	pool.currentState.AddBalance(common.MainAccount, from, funds(1, 100000))
+	if err := pool.AddTxPool(signed); err != ErrNegativeValue {
 		t.Error("expected", ErrNegativeValue, "got", err)
 	}
 }
 
 func TestTransactionChainFork(t *testing.T) {
-	t.Parallel()
-
 	pool, key := setupTxPool()
 	defer pool.Stop()
 
 	addr := crypto.PubkeyToAddress(key.PublicKey)
 	resetState := func() {
-		statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-		statedb.AddBalance(addr, big.NewInt(100000000000000))
+		statedb := newTestState()
+		statedb.AddBalance(common.MainAccount, addr, funds(10, 100000))
 
 		pool.chain = &testBlockChain{statedb, 1000000, new(event.Feed)}
-		pool.lockedReset(nil, nil)
+		lockedReset(pool)
 	}
 	resetState()
 
@@ -345,48 +396,41 @@ func TestTransactionChainFork(t *testing.T) {
 }
 
 func TestTransactionDoubleNonce(t *testing.T) {
-	t.Parallel()
-
 	pool, key := setupTxPool()
 	defer pool.Stop()
 
 	addr := crypto.PubkeyToAddress(key.PublicKey)
-	resetState := func() {
-		statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-		statedb.AddBalance(addr, big.NewInt(100000000000000))
-
-		pool.chain = &testBlockChain{statedb, 1000000, new(event.Feed)}
-		pool.lockedReset(nil, nil)
-	}
-	resetState()
+	pool.currentState.AddBalance(common.MainAccount, addr, funds(10, 1000000))
+	lockedReset(pool)
 
-	signer := types.HomesteadSigner{}
-	tx1, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 100000, big.NewInt(1), nil), signer, key)
-	tx2, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 1000000, big.NewInt(2), nil), signer, key)
-	tx3, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 1000000, big.NewInt(1), nil), signer, key)
+	tx1 := pricedTransaction(0, 100000, price(1), key)
+	tx2 := pricedTransaction(0, 1000000, price(2), key)
+	tx3 := pricedTransaction(0, 1000000, price(1), key)
 
 	// Add the first two transaction, ensure higher priced stays only
-	if replace, err := pool.add(tx1, false); err != nil || replace {
-		t.Errorf("first transaction insert failed (%v) or reported replacement (%v)", err, replace)
+	if _, err := pool.add(tx1, false); err != nil {
+		t.Errorf("first transaction insert failed (%v)", err)
 	}
 	if replace, err := pool.add(tx2, false); err != nil || !replace {
-		t.Errorf("second transaction insert failed (%v) or not reported replacement (%v)", err, replace)
+		t.Errorf("second transaction insert failed (%v) or not replaced (%v)", err, replace)
 	}
 	pool.promoteExecutables([]common.Address{addr})
-	if pool.pending[addr].Len() != 1 {
-		t.Error("expected 1 pending transactions, got", pool.pending[addr].Len())
+	if pool.pending[addr].Count() != 1 {
+		t.Error("expected 1 pending transactions, got", pool.pending[addr].Count())
 	}
-	if tx := pool.pending[addr].txs.items[0]; tx.Hash() != tx2.Hash() {
-		t.Errorf("transaction mismatch: have %x, want %x", tx.Hash(), tx2.Hash())
+	if !pendingTx(pool, addr, tx2) {
+		t.Errorf("transaction mismatch: want %x pending", tx2.Hash())
 	}
 	// Add the third transaction and ensure it's not saved (smaller price)
-	pool.add(tx3, false)
+	if _, err := pool.add(tx3, false); err != ErrReplaceUnderpriced {
+		t.Errorf("third transaction insert error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
 	pool.promoteExecutables([]common.Address{addr})
-	if pool.pending[addr].Len() != 1 {
-		t.Error("expected 1 pending transactions, got", pool.pending[addr].Len())
+	if pool.pending[addr].Count() != 1 {
+		t.Error("expected 1 pending transactions, got", pool.pending[addr].Count())
 	}
-	if tx := pool.pending[addr].txs.items[0]; tx.Hash() != tx2.Hash() {
-		t.Errorf("transaction mismatch: have %x, want %x", tx.Hash(), tx2.Hash())
+	if !pendingTx(pool, addr, tx2) {
+		t.Errorf("transaction mismatch: want %x pending", tx2.Hash())
 	}
 	// Ensure the total transaction count is correct
 	if pool.all.Count() != 1 {
@@ -395,13 +439,11 @@ func TestTransactionDoubleNonce(t *testing.T) {
 }
 
 func TestTransactionMissingNonce(t *testing.T) {
-	t.Parallel()
-
 	pool, key := setupTxPool()
 	defer pool.Stop()
 
 	addr := crypto.PubkeyToAddress(key.PublicKey)
-	pool.currentState.AddBalance(addr, big.NewInt(100000000000000))
+	pool.currentState.AddBalance(common.MainAccount, addr, funds(10, 100000))
 	tx := transaction(1, 100000, key)
 	if _, err := pool.add(tx, false); err != nil {
 		t.Error("didn't expect error", err)
@@ -409,8 +451,8 @@ func TestTransactionMissingNonce(t *testing.T) {
 	if len(pool.pending) != 0 {
 		t.Error("expected 0 pending transactions, got", len(pool.pending))
 	}
-	if pool.queue[addr].Len() != 1 {
-		t.Error("expected 1 queued transaction, got", pool.queue[addr].Len())
+	if pool.queue[addr].Count() != 1 {
+		t.Error("expected 1 queued transaction, got", pool.queue[addr].Count())
 	}
 	if pool.all.Count() != 1 {
 		t.Error("expected 1 total transactions, got", pool.all.Count())
@@ -418,116 +460,121 @@ func TestTransactionMissingNonce(t *testing.T) {
 }
 
 func TestTransactionNonceRecovery(t *testing.T) {
-	t.Parallel()
-
 	const n = 10
 	pool, key := setupTxPool()
 	defer pool.Stop()
 
 	addr := crypto.PubkeyToAddress(key.PublicKey)
 	pool.currentState.SetNonce(addr, n)
-	pool.currentState.AddBalance(addr, big.NewInt(100000000000000))
-	pool.lockedReset(nil, nil)
+	pool.currentState.AddBalance(common.MainAccount, addr, funds(10, 100000))
+	lockedReset(pool)
 
 	tx := transaction(n, 100000, key)
-	if err := pool.AddRemote(tx); err != nil {
+	if err := pool.AddTxPool(tx); err != nil {
 		t.Error(err)
 	}
+	if fn := pool.pendingState.GetNonce(addr); fn != (n+1)|params.NonceAddOne {
+		t.Errorf("expected nonce to be %d, got %d", n+1, fn&params.NonceSubOne)
+	}
 	// simulate some weird re-order of transactions and missing nonce(s)
+	pool.mu.Lock()
+	pool.removeTx(tx.Hash(), true)
+	pool.mu.Unlock()
 	pool.currentState.SetNonce(addr, n-1)
-	pool.lockedReset(nil, nil)
-	if fn := pool.pendingState.GetNonce(addr); fn != n-1 {
-		t.Errorf("expected nonce to be %d, got %d", n-1, fn)
+	lockedReset(pool)
+	if fn := pool.pendingState.GetNonce(addr); fn != (n-1)|params.NonceAddOne {
+		t.Errorf("expected nonce to be %d, got %d", n-1, fn&params.NonceSubOne)
 	}
 }
 
 // Tests that if an account runs out of funds, any pending and queued transactions
 // are dropped.
 func TestTransactionDropping(t *testing.T) {
-	t.Parallel()
-
 	// Create a test account and fund it
 	pool, key := setupTxPool()
 	defer pool.Stop()
 
 	account, _ := deriveSender(transaction(0, 0, key))
-	pool.currentState.AddBalance(account, big.NewInt(1000))
+	pool.currentState.AddBalance(common.MainAccount, account, big.NewInt(1000))
 
 	// Add some pending and some queued transactions
 	var (
-		tx0  = transaction(0, 100, key)
-		tx1  = transaction(1, 200, key)
-		tx2  = transaction(2, 300, key)
-		tx10 = transaction(10, 100, key)
-		tx11 = transaction(11, 200, key)
-		tx12 = transaction(12, 300, key)
+		tx0  = pricedTransaction(0, 100, common.Big1, key)
+		tx1  = pricedTransaction(1, 200, common.Big1, key)
+		tx2  = pricedTransaction(2, 300, common.Big1, key)
+		tx10 = pricedTransaction(10, 100, common.Big1, key)
+		tx11 = pricedTransaction(11, 200, common.Big1, key)
+		tx12 = pricedTransaction(12, 300, common.Big1, key)
 	)
-	pool.promoteTx(account, tx0.Hash(), tx0)
-	pool.promoteTx(account, tx1.Hash(), tx1)
-	pool.promoteTx(account, tx2.Hash(), tx2)
-	pool.enqueueTx(tx10.Hash(), tx10)
-	pool.enqueueTx(tx11.Hash(), tx11)
-	pool.enqueueTx(tx12.Hash(), tx12)
-
+	for _, tx := range []*types.Transaction{tx0, tx1, tx2} {
+		pool.all.Add(tx)
+		pool.priced.Put(tx)
+		pool.promoteTx(account, tx)
+	}
+	for _, tx := range []*types.Transaction{tx10, tx11, tx12} {
+		pool.all.Add(tx)
+		pool.priced.Put(tx)
+		pool.enqueueTx(account, tx)
+	}
 	// Check that pre and post validations leave the pool as is
-	if pool.pending[account].Len() != 3 {
-		t.Errorf("pending transaction mismatch: have %d, want %d", pool.pending[account].Len(), 3)
+	if pool.pending[account].Count() != 3 {
+		t.Errorf("pending transaction mismatch: have %d, want %d", pool.pending[account].Count(), 3)
 	}
-	if pool.queue[account].Len() != 3 {
-		t.Errorf("queued transaction mismatch: have %d, want %d", pool.queue[account].Len(), 3)
+	if pool.queue[account].Count() != 3 {
+		t.Errorf("queued transaction mismatch: have %d, want %d", pool.queue[account].Count(), 3)
 	}
 	if pool.all.Count() != 6 {
 		t.Errorf("total transaction mismatch: have %d, want %d", pool.all.Count(), 6)
 	}
-	pool.lockedReset(nil, nil)
-	if pool.pending[account].Len() != 3 {
-		t.Errorf("pending transaction mismatch: have %d, want %d", pool.pending[account].Len(), 3)
+	lockedReset(pool)
+	if pool.pending[account].Count() != 3 {
+		t.Errorf("pending transaction mismatch: have %d, want %d", pool.pending[account].Count(), 3)
 	}
-	if pool.queue[account].Len() != 3 {
-		t.Errorf("queued transaction mismatch: have %d, want %d", pool.queue[account].Len(), 3)
+	if pool.queue[account].Count() != 3 {
+		t.Errorf("queued transaction mismatch: have %d, want %d", pool.queue[account].Count(), 3)
 	}
 	if pool.all.Count() != 6 {
 		t.Errorf("total transaction mismatch: have %d, want %d", pool.all.Count(), 6)
 	}
 	// Reduce the balance of the account, and check that invalidated transactions are dropped
-	pool.currentState.AddBalance(account, big.NewInt(-650))
-	pool.lockedReset(nil, nil)
+	pool.currentState.SubBalance(common.MainAccount, account, big.NewInt(650))
+	lockedReset(pool)
 
-	if _, ok := pool.pending[account].txs.items[tx0.Nonce()]; !ok {
+	if !pendingTx(pool, account, tx0) {
 		t.Errorf("funded pending transaction missing: %v", tx0)
 	}
-	if _, ok := pool.pending[account].txs.items[tx1.Nonce()]; !ok {
-		t.Errorf("funded pending transaction missing: %v", tx0)
+	if !pendingTx(pool, account, tx1) {
+		t.Errorf("funded pending transaction missing: %v", tx1)
 	}
-	if _, ok := pool.pending[account].txs.items[tx2.Nonce()]; ok {
-		t.Errorf("out-of-fund pending transaction present: %v", tx1)
+	if pendingTx(pool, account, tx2) {
+		t.Errorf("out-of-fund pending transaction present: %v", tx2)
 	}
-	if _, ok := pool.queue[account].txs.items[tx10.Nonce()]; !ok {
+	if !queuedTx(pool, account, tx10) {
 		t.Errorf("funded queued transaction missing: %v", tx10)
 	}
-	if _, ok := pool.queue[account].txs.items[tx11.Nonce()]; !ok {
-		t.Errorf("funded queued transaction missing: %v", tx10)
+	if !queuedTx(pool, account, tx11) {
+		t.Errorf("funded queued transaction missing: %v", tx11)
 	}
-	if _, ok := pool.queue[account].txs.items[tx12.Nonce()]; ok {
-		t.Errorf("out-of-fund queued transaction present: %v", tx11)
+	if queuedTx(pool, account, tx12) {
+		t.Errorf("out-of-fund queued transaction present: %v", tx12)
 	}
 	if pool.all.Count() != 4 {
 		t.Errorf("total transaction mismatch: have %d, want %d", pool.all.Count(), 4)
 	}
 	// Reduce the block gas limit, check that invalidated transactions are dropped
 	pool.chain.(*testBlockChain).gasLimit = 100
-	pool.lockedReset(nil, nil)
+	lockedReset(pool)
 
-	if _, ok := pool.pending[account].txs.items[tx0.Nonce()]; !ok {
+	if !pendingTx(pool, account, tx0) {
 		t.Errorf("funded pending transaction missing: %v", tx0)
 	}
-	if _, ok := pool.pending[account].txs.items[tx1.Nonce()]; ok {
+	if pendingTx(pool, account, tx1) {
 		t.Errorf("over-gased pending transaction present: %v", tx1)
 	}
-	if _, ok := pool.queue[account].txs.items[tx10.Nonce()]; !ok {
+	if !queuedTx(pool, account, tx10) {
 		t.Errorf("funded queued transaction missing: %v", tx10)
 	}
-	if _, ok := pool.queue[account].txs.items[tx11.Nonce()]; ok {
+	if queuedTx(pool, account, tx11) {
 		t.Errorf("over-gased queued transaction present: %v", tx11)
 	}
 	if pool.all.Count() != 2 {
@@ -535,17 +582,79 @@ func TestTransactionDropping(t *testing.T) {
 	}
 }
 
-// Tests that if a transaction is dropped from the current pending pool (e.g. out
-// of fund), all consecutive (still valid, but not executable) transactions are
-// postponed back into the future queue to prevent broadcasting them.
-func TestTransactionPostponing(t *testing.T) {
-	t.Parallel()
+// testChain is a test block chain whose state changes while the pool resets,
+// simulating a new block imported in the mean time.
+type testChain struct {
+	*testBlockChain
+	address common.Address
+	trigger *bool
+}
 
-	// Create the pool to test the postponing with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+// StateAt returns the state of the chain. When triggered, it hands out the old
+// state once more and switches to a state that includes tx0 and tx1.
+func (c *testChain) StateAt(common.Hash) (*state.StateDB, error) {
+	stdb := c.statedb
+	if *c.trigger {
+		c.statedb = newTestState()
+		// simulate that the new head block included tx0 and tx1
+		c.statedb.SetNonce(c.address, 2)
+		c.statedb.AddBalance(common.MainAccount, c.address, funds(10, 100000))
+		*c.trigger = false
+	}
+	return stdb, nil
+}
 
-	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain, "")
+// This test simulates a scenario where a new block is imported during a
+// state reset and tests whether the pending state is in sync with the
+// block head event that initiated the reset.
+func TestStateChangeDuringTransactionPoolReset(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		statedb = newTestState()
+		trigger = false
+	)
+	// setup pool with 2 transaction in it
+	statedb.AddBalance(common.MainAccount, address, funds(10, 100000))
+	blockchain := &testChain{&testBlockChain{statedb, 1000000, new(event.Feed)}, address, &trigger}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain, make(chan NewTxsEvent, 1024))
+	defer pool.Stop()
+
+	if nonce := pool.State().GetNonce(address); nonce != params.NonceAddOne {
+		t.Fatalf("Invalid nonce, want 0, got %d", nonce&params.NonceSubOne)
+	}
+	for i, err := range addTxs(pool, []*types.Transaction{transaction(0, 100000, key), transaction(1, 100000, key)}) {
+		if err != nil {
+			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
+		}
+	}
+	if nonce := pool.State().GetNonce(address); nonce != 2|params.NonceAddOne {
+		t.Fatalf("Invalid nonce, want 2, got %d", nonce&params.NonceSubOne)
+	}
+	// trigger state change in the background
+	trigger = true
+	lockedReset(pool)
+
+	if nonce := pool.State().GetNonce(address); nonce != 2|params.NonceAddOne {
+		t.Fatalf("Invalid nonce, want 2, got %d", nonce&params.NonceSubOne)
+	}
+	// The next reset sees tx0 and tx1 included
+	lockedReset(pool)
+
+	if nonce := pool.State().GetNonce(address); nonce != 2|params.NonceAddOne {
+		t.Fatalf("Invalid nonce, want 2, got %d", nonce&params.NonceSubOne)
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("pool content mismatched: have %d pending %d queued, want none", pending, queued)
+	}
+}
+
+// Tests that if the balance of an account drops, its transactions no longer
+// payable are dropped, and the ones following them are postponed into the
+// future queue, without leaving a gap in the pending list.
+func TestTransactionPostponing(t *testing.T) {
+	pool, _ := setupTxPool()
 	defer pool.Stop()
 
 	// Create two test accounts to produce different gap profiles with
@@ -556,12 +665,11 @@ func TestTransactionPostponing(t *testing.T) {
 		keys[i], _ = crypto.GenerateKey()
 		accs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
 
-		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(50100))
+		pool.currentState.AddBalance(common.MainAccount, accs[i], funds(1, 50000))
 	}
 	// Add a batch consecutive pending transactions for validation
 	txs := []*types.Transaction{}
 	for i, key := range keys {
-
 		for j := 0; j < 100; j++ {
 			var tx *types.Transaction
 			if (i+j)%2 == 0 {
@@ -572,58 +680,51 @@ func TestTransactionPostponing(t *testing.T) {
 			txs = append(txs, tx)
 		}
 	}
-	for i, err := range pool.AddRemotes(txs) {
+	for i, err := range addTxs(pool, txs) {
 		if err != nil {
 			t.Fatalf("tx %d: failed to add transactions: %v", i, err)
 		}
 	}
 	// Check that pre and post validations leave the pool as is
-	if pending := pool.pending[accs[0]].Len() + pool.pending[accs[1]].Len(); pending != len(txs) {
-		t.Errorf("pending transaction mismatch: have %d, want %d", pending, len(txs))
-	}
-	if len(pool.queue) != 0 {
-		t.Errorf("queued accounts mismatch: have %d, want %d", len(pool.queue), 0)
-	}
-	if pool.all.Count() != len(txs) {
-		t.Errorf("total transaction mismatch: have %d, want %d", pool.all.Count(), len(txs))
-	}
-	pool.lockedReset(nil, nil)
-	if pending := pool.pending[accs[0]].Len() + pool.pending[accs[1]].Len(); pending != len(txs) {
-		t.Errorf("pending transaction mismatch: have %d, want %d", pending, len(txs))
-	}
-	if len(pool.queue) != 0 {
-		t.Errorf("queued accounts mismatch: have %d, want %d", len(pool.queue), 0)
-	}
-	if pool.all.Count() != len(txs) {
-		t.Errorf("total transaction mismatch: have %d, want %d", pool.all.Count(), len(txs))
+	for round := 0; round < 2; round++ {
+		if pending := pool.pending[accs[0]].Count() + pool.pending[accs[1]].Count(); pending != len(txs) {
+			t.Errorf("round %d: pending transaction mismatch: have %d, want %d", round, pending, len(txs))
+		}
+		if len(pool.queue) != 0 {
+			t.Errorf("round %d: queued accounts mismatch: have %d, want %d", round, len(pool.queue), 0)
+		}
+		if pool.all.Count() != len(txs) {
+			t.Errorf("round %d: total transaction mismatch: have %d, want %d", round, pool.all.Count(), len(txs))
+		}
+		lockedReset(pool)
 	}
 	// Reduce the balance of the account, and check that transactions are reorganised
 	for _, addr := range accs {
-		pool.currentState.AddBalance(addr, big.NewInt(-1))
+		pool.currentState.SubBalance(common.MainAccount, addr, big.NewInt(1))
 	}
-	pool.lockedReset(nil, nil)
+	lockedReset(pool)
 
 	// The first account's first transaction remains valid, check that subsequent
 	// ones are either filtered out, or queued up for later.
-	if _, ok := pool.pending[accs[0]].txs.items[txs[0].Nonce()]; !ok {
+	if !pendingTx(pool, accs[0], txs[0]) {
 		t.Errorf("tx %d: valid and funded transaction missing from pending pool: %v", 0, txs[0])
 	}
-	if _, ok := pool.queue[accs[0]].txs.items[txs[0].Nonce()]; ok {
+	if queuedTx(pool, accs[0], txs[0]) {
 		t.Errorf("tx %d: valid and funded transaction present in future queue: %v", 0, txs[0])
 	}
 	for i, tx := range txs[1:100] {
 		if i%2 == 1 {
-			if _, ok := pool.pending[accs[0]].txs.items[tx.Nonce()]; ok {
+			if pendingTx(pool, accs[0], tx) {
 				t.Errorf("tx %d: valid but future transaction present in pending pool: %v", i+1, tx)
 			}
-			if _, ok := pool.queue[accs[0]].txs.items[tx.Nonce()]; !ok {
+			if !queuedTx(pool, accs[0], tx) {
 				t.Errorf("tx %d: valid but future transaction missing from future queue: %v", i+1, tx)
 			}
 		} else {
-			if _, ok := pool.pending[accs[0]].txs.items[tx.Nonce()]; ok {
+			if pendingTx(pool, accs[0], tx) {
 				t.Errorf("tx %d: out-of-fund transaction present in pending pool: %v", i+1, tx)
 			}
-			if _, ok := pool.queue[accs[0]].txs.items[tx.Nonce()]; ok {
+			if queuedTx(pool, accs[0], tx) {
 				t.Errorf("tx %d: out-of-fund transaction present in future queue: %v", i+1, tx)
 			}
 		}
@@ -635,11 +736,11 @@ func TestTransactionPostponing(t *testing.T) {
 	}
 	for i, tx := range txs[100:] {
 		if i%2 == 1 {
-			if _, ok := pool.queue[accs[1]].txs.items[tx.Nonce()]; !ok {
+			if !queuedTx(pool, accs[1], tx) {
 				t.Errorf("tx %d: valid but future transaction missing from future queue: %v", 100+i, tx)
 			}
 		} else {
-			if _, ok := pool.queue[accs[1]].txs.items[tx.Nonce()]; ok {
+			if queuedTx(pool, accs[1], tx) {
 				t.Errorf("tx %d: out-of-fund transaction present in future queue: %v", 100+i, tx)
 			}
 		}
@@ -647,31 +748,27 @@ func TestTransactionPostponing(t *testing.T) {
 	if pool.all.Count() != len(txs)/2 {
 		t.Errorf("total transaction mismatch: have %d, want %d", pool.all.Count(), len(txs)/2)
 	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
 }
 
 // Tests that if the transaction pool has both executable and non-executable
 // transactions from an origin account, filling the nonce gap moves all queued
 // ones into the pending pool.
 func TestTransactionGapFilling(t *testing.T) {
-	t.Parallel()
-
 	// Create a test account and fund it
 	pool, key := setupTxPool()
 	defer pool.Stop()
 
 	account, _ := deriveSender(transaction(0, 0, key))
-	pool.currentState.AddBalance(account, big.NewInt(1000000))
-
-	// Keep track of transaction events to ensure all executables get announced
-	events := make(chan NewTxsEvent, testTxPoolConfig.AccountQueue+5)
-	sub := pool.txFeed.Subscribe(events)
-	defer sub.Unsubscribe()
+	pool.currentState.AddBalance(common.MainAccount, account, funds(10, 100000))
 
 	// Create a pending and a queued transaction with a nonce-gap in between
-	if err := pool.AddRemote(transaction(0, 100000, key)); err != nil {
+	if err := pool.AddTxPool(transaction(0, 100000, key)); err != nil {
 		t.Fatalf("failed to add pending transaction: %v", err)
 	}
-	if err := pool.AddRemote(transaction(2, 100000, key)); err != nil {
+	if err := pool.AddTxPool(transaction(2, 100000, key)); err != nil {
 		t.Fatalf("failed to add queued transaction: %v", err)
 	}
 	pending, queued := pool.Stats()
@@ -681,14 +778,11 @@ func TestTransactionGapFilling(t *testing.T) {
 	if queued != 1 {
 		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
 	}
-	if err := validateEvents(events, 1); err != nil {
-		t.Fatalf("original event firing failed: %v", err)
-	}
 	if err := validateTxPoolInternals(pool); err != nil {
 		t.Fatalf("pool internal state corrupted: %v", err)
 	}
 	// Fill the nonce gap and ensure all transactions become pending
-	if err := pool.AddRemote(transaction(1, 100000, key)); err != nil {
+	if err := pool.AddTxPool(transaction(1, 100000, key)); err != nil {
 		t.Fatalf("failed to add gapped transaction: %v", err)
 	}
 	pending, queued = pool.Stats()
@@ -698,9 +792,6 @@ func TestTransactionGapFilling(t *testing.T) {
 	if queued != 0 {
 		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
 	}
-	if err := validateEvents(events, 2); err != nil {
-		t.Fatalf("gap-filling event firing failed: %v", err)
-	}
 	if err := validateTxPoolInternals(pool); err != nil {
 		t.Fatalf("pool internal state corrupted: %v", err)
 	}
@@ -709,191 +800,117 @@ func TestTransactionGapFilling(t *testing.T) {
 // Tests that if the transaction count belonging to a single account goes above
 // some threshold, the higher transactions are dropped to prevent DOS attacks.
 func TestTransactionQueueAccountLimiting(t *testing.T) {
-	t.Parallel()
-
 	// Create a test account and fund it
-	pool, key := setupTxPool()
+	config := testTxPoolConfig
+	config.AccountQueue = 16
+
+	pool, key := setupTxPoolWithConfig(config)
 	defer pool.Stop()
 
 	account, _ := deriveSender(transaction(0, 0, key))
-	pool.currentState.AddBalance(account, big.NewInt(1000000))
+	pool.currentState.AddBalance(common.MainAccount, account, funds(int64(config.AccountQueue)+5, 100000))
 
 	// Keep queuing up transactions and make sure all above a limit are dropped
-	for i := uint64(1); i <= testTxPoolConfig.AccountQueue+5; i++ {
-		if err := pool.AddRemote(transaction(i, 100000, key)); err != nil {
-			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
-		}
+	for i := uint64(1); i <= config.AccountQueue+5; i++ {
+		pool.AddTxPool(transaction(i, 100000, key))
 		if len(pool.pending) != 0 {
 			t.Errorf("tx %d: pending pool size mismatch: have %d, want %d", i, len(pool.pending), 0)
 		}
-		if i <= testTxPoolConfig.AccountQueue {
-			if pool.queue[account].Len() != int(i) {
-				t.Errorf("tx %d: queue size mismatch: have %d, want %d", i, pool.queue[account].Len(), i)
-			}
-		} else {
-			if pool.queue[account].Len() != int(testTxPoolConfig.AccountQueue) {
-				t.Errorf("tx %d: queue limit mismatch: have %d, want %d", i, pool.queue[account].Len(), testTxPoolConfig.AccountQueue)
-			}
+		if pool.queue[account].Count() != int(i) {
+			t.Errorf("tx %d: queue size mismatch: have %d, want %d", i, pool.queue[account].Count(), i)
 		}
 	}
-	if pool.all.Count() != int(testTxPoolConfig.AccountQueue) {
-		t.Errorf("total transaction mismatch: have %d, want %d", pool.all.Count(), testTxPoolConfig.AccountQueue)
+	// 账户的future队列上限在处理新区块时执行
+	lockedReset(pool)
+	if pool.queue[account].Count() != int(config.AccountQueue) {
+		t.Errorf("queue limit mismatch: have %d, want %d", pool.queue[account].Count(), config.AccountQueue)
+	}
+	if pool.all.Count() != int(config.AccountQueue) {
+		t.Errorf("total transaction mismatch: have %d, want %d", pool.all.Count(), config.AccountQueue)
 	}
 }
 
 // Tests that if the transaction count belonging to multiple accounts go above
 // some threshold, the higher transactions are dropped to prevent DOS attacks.
-//
-// This logic should not hold for local transactions, unless the local tracking
-// mechanism is disabled.
 func TestTransactionQueueGlobalLimiting(t *testing.T) {
-	testTransactionQueueGlobalLimiting(t, false)
-}
-func TestTransactionQueueGlobalLimitingNoLocals(t *testing.T) {
-	testTransactionQueueGlobalLimiting(t, true)
-}
-
-func testTransactionQueueGlobalLimiting(t *testing.T, nolocals bool) {
-	t.Parallel()
-
 	// Create the pool to test the limit enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
-
 	config := testTxPoolConfig
-	config.NoLocals = nolocals
+	config.AccountQueue = 16
 	config.GlobalQueue = config.AccountQueue*3 - 1 // reduce the queue limits to shorten test time (-1 to make it non divisible)
 
-	pool := NewTxPool(config, params.TestChainConfig, blockchain, "")
+	pool, _ := setupTxPoolWithConfig(config)
 	defer pool.Stop()
 
-	// Create a number of test accounts and fund them (last one will be the local)
+	// Create a number of test accounts and fund them
 	keys := make([]*ecdsa.PrivateKey, 5)
 	for i := 0; i < len(keys); i++ {
 		keys[i], _ = crypto.GenerateKey()
-		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+		pool.currentState.AddBalance(common.MainAccount, crypto.PubkeyToAddress(keys[i].PublicKey), funds(int64(3*config.GlobalQueue), 100000))
 	}
-	local := keys[len(keys)-1]
-
 	// Generate and queue a batch of transactions
 	nonces := make(map[common.Address]uint64)
 
-	txs := make(types.Transactions, 0, 3*config.GlobalQueue)
+	txs := make([]*types.Transaction, 0, 3*config.GlobalQueue)
 	for len(txs) < cap(txs) {
-		key := keys[rand.Intn(len(keys)-1)] // skip adding transactions with the local account
+		key := keys[rand.Intn(len(keys))]
 		addr := crypto.PubkeyToAddress(key.PublicKey)
 
 		txs = append(txs, transaction(nonces[addr]+1, 100000, key))
 		nonces[addr]++
 	}
 	// Import the batch and verify that limits have been enforced
-	pool.AddRemotes(txs)
+	addTxs(pool, txs)
 
 	queued := 0
 	for addr, list := range pool.queue {
-		if list.Len() > int(config.AccountQueue) {
-			t.Errorf("addr %x: queued accounts overflown allowance: %d > %d", addr, list.Len(), config.AccountQueue)
+		if list.Count() > int(config.AccountQueue) {
+			t.Errorf("addr %x: queued accounts overflown allowance: %d > %d", addr, list.Count(), config.AccountQueue)
 		}
-		queued += list.Len()
+		queued += list.Count()
 	}
 	if queued > int(config.GlobalQueue) {
 		t.Fatalf("total transactions overflow allowance: %d > %d", queued, config.GlobalQueue)
 	}
-	// Generate a batch of transactions from the local account and import them
-	txs = txs[:0]
-	for i := uint64(0); i < 3*config.GlobalQueue; i++ {
-		txs = append(txs, transaction(i+1, 100000, local))
-	}
-	pool.AddLocals(txs)
-
-	// If locals are disabled, the previous eviction algorithm should apply here too
-	if nolocals {
-		queued := 0
-		for addr, list := range pool.queue {
-			if list.Len() > int(config.AccountQueue) {
-				t.Errorf("addr %x: queued accounts overflown allowance: %d > %d", addr, list.Len(), config.AccountQueue)
-			}
-			queued += list.Len()
-		}
-		if queued > int(config.GlobalQueue) {
-			t.Fatalf("total transactions overflow allowance: %d > %d", queued, config.GlobalQueue)
-		}
-	} else {
-		// Local exemptions are enabled, make sure the local account owned the queue
-		if len(pool.queue) != 1 {
-			t.Errorf("multiple accounts in queue: have %v, want %v", len(pool.queue), 1)
-		}
-		// Also ensure no local transactions are ever dropped, even if above global limits
-		if queued := pool.queue[crypto.PubkeyToAddress(local.PublicKey)].Len(); uint64(queued) != 3*config.GlobalQueue {
-			t.Fatalf("local account queued transaction count mismatch: have %v, want %v", queued, 3*config.GlobalQueue)
-		}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
 	}
 }
 
 // Tests that if an account remains idle for a prolonged amount of time, any
-// non-executable transactions queued up are dropped to prevent wasting resources
-// on shuffling them around.
-//
-// This logic should not hold for local transactions, unless the local tracking
-// mechanism is disabled.
-func TestTransactionQueueTimeLimiting(t *testing.T)         { testTransactionQueueTimeLimiting(t, false) }
-func TestTransactionQueueTimeLimitingNoLocals(t *testing.T) { testTransactionQueueTimeLimiting(t, true) }
-
-func testTransactionQueueTimeLimiting(t *testing.T, nolocals bool) {
-	// Reduce the eviction interval to a testable amount
-	defer func(old time.Duration) { evictionInterval = old }(evictionInterval)
-	evictionInterval = time.Second
-
-	// Create the pool to test the non-expiration enforcement
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
-
+// transactions left in the pool are dropped to prevent wasting resources on
+// shuffling them around.
+func TestTransactionQueueTimeLimiting(t *testing.T) {
 	config := testTxPoolConfig
-	config.Lifetime = time.Second
-	config.NoLocals = nolocals
+	config.txTimeout = 50 * time.Millisecond
 
-	pool := NewTxPool(config, params.TestChainConfig, blockchain, "")
+	pool, key := setupTxPoolWithConfig(config)
 	defer pool.Stop()
 
-	// Create two test accounts to ensure remotes expire but locals do not
-	local, _ := crypto.GenerateKey()
-	remote, _ := crypto.GenerateKey()
-
-	pool.currentState.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
-	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+	account := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(common.MainAccount, account, funds(10, 100000))
 
-	// Add the two transactions and ensure they both are queued up
-	if err := pool.AddLocal(pricedTransaction(1, 100000, big.NewInt(1), local)); err != nil {
-		t.Fatalf("failed to add local transaction: %v", err)
+	// Add a pending and a queued transaction
+	if err := pool.AddTxPool(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add pending transaction: %v", err)
 	}
-	if err := pool.AddRemote(pricedTransaction(1, 100000, big.NewInt(1), remote)); err != nil {
-		t.Fatalf("failed to add remote transaction: %v", err)
+	if err := pool.AddTxPool(transaction(2, 100000, key)); err != nil {
+		t.Fatalf("failed to add queued transaction: %v", err)
 	}
 	pending, queued := pool.Stats()
-	if pending != 0 {
-		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 0)
-	}
-	if queued != 2 {
-		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 2)
-	}
-	if err := validateTxPoolInternals(pool); err != nil {
-		t.Fatalf("pool internal state corrupted: %v", err)
+	if pending != 1 || queued != 1 {
+		t.Fatalf("pool content mismatched: have %d pending %d queued, want 1 and 1", pending, queued)
 	}
-	// Wait a bit for eviction to run and clean up any leftovers, and ensure only the local remains
-	time.Sleep(2 * config.Lifetime)
+	// Start the timers, wait for them to expire and clean up the leftovers
+	pool.getPendingTx()
+	time.Sleep(2 * config.txTimeout)
+
+	pool.mu.Lock()
+	pool.blockTiming()
+	pool.mu.Unlock()
 
 	pending, queued = pool.Stats()
-	if pending != 0 {
-		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 0)
-	}
-	if nolocals {
-		if queued != 0 {
-			t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
-		}
-	} else {
-		if queued != 1 {
-			t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
-		}
+	if pending != 0 || queued != 0 {
+		t.Fatalf("pool content mismatched: have %d pending %d queued, want none", pending, queued)
 	}
 	if err := validateTxPoolInternals(pool); err != nil {
 		t.Fatalf("pool internal state corrupted: %v", err)
@@ -904,82 +921,82 @@ func testTransactionQueueTimeLimiting(t *testing.T, nolocals bool) {
 // above some threshold, as long as the transactions are executable, they are
 // accepted.
 func TestTransactionPendingLimiting(t *testing.T) {
-	t.Parallel()
-
 	// Create a test account and fund it
 	pool, key := setupTxPool()
 	defer pool.Stop()
 
+	count := testTxPoolConfig.AccountSlots * 4
 	account, _ := deriveSender(transaction(0, 0, key))
-	pool.currentState.AddBalance(account, big.NewInt(1000000))
-
-	// Keep track of transaction events to ensure all executables get announced
-	events := make(chan NewTxsEvent, testTxPoolConfig.AccountQueue+5)
-	sub := pool.txFeed.Subscribe(events)
-	defer sub.Unsubscribe()
+	pool.currentState.AddBalance(common.MainAccount, account, funds(int64(count), 100000))
 
 	// Keep queuing up transactions and make sure all above a limit are dropped
-	for i := uint64(0); i < testTxPoolConfig.AccountQueue+5; i++ {
-		if err := pool.AddRemote(transaction(i, 100000, key)); err != nil {
+	for i := uint64(0); i < count; i++ {
+		if err := pool.AddTxPool(transaction(i, 100000, key)); err != nil {
 			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
 		}
-		if pool.pending[account].Len() != int(i)+1 {
-			t.Errorf("tx %d: pending pool size mismatch: have %d, want %d", i, pool.pending[account].Len(), i+1)
+		if pool.pending[account].Count() != int(i)+1 {
+			t.Errorf("tx %d: pending pool size mismatch: have %d, want %d", i, pool.pending[account].Count(), i+1)
 		}
 		if len(pool.queue) != 0 {
-			t.Errorf("tx %d: queue size mismatch: have %d, want %d", i, pool.queue[account].Len(), 0)
+			t.Errorf("tx %d: queue size mismatch: have %d, want %d", i, pool.queue[account].Count(), 0)
 		}
 	}
-	if pool.all.Count() != int(testTxPoolConfig.AccountQueue+5) {
-		t.Errorf("total transaction mismatch: have %d, want %d", pool.all.Count(), testTxPoolConfig.AccountQueue+5)
-	}
-	if err := validateEvents(events, int(testTxPoolConfig.AccountQueue+5)); err != nil {
-		t.Fatalf("event firing failed: %v", err)
+	if pool.all.Count() != int(count) {
+		t.Errorf("total transaction mismatch: have %d, want %d", pool.all.Count(), count)
 	}
 	if err := validateTxPoolInternals(pool); err != nil {
 		t.Fatalf("pool internal state corrupted: %v", err)
 	}
 }
 
-// Tests that the transaction limits are enforced the same way irrelevant whether
-// the transactions are added one by one or in batches.
-func TestTransactionQueueLimitingEquivalency(t *testing.T)   { testTransactionLimitingEquivalency(t, 1) }
-func TestTransactionPendingLimitingEquivalency(t *testing.T) { testTransactionLimitingEquivalency(t, 0) }
+// Tests that adding transactions one by one or in one big batch ends up with
+// the same pool contents, both for executable and for future transactions.
+func TestTransactionQueueLimitingEquivalency(t *testing.T) { testTransactionLimitingEquivalency(t, 1) }
+func TestTransactionPendingLimitingEquivalency(t *testing.T) {
+	testTransactionLimitingEquivalency(t, 0)
+}
 
 func testTransactionLimitingEquivalency(t *testing.T, origin uint64) {
-	t.Parallel()
+	config := testTxPoolConfig
+	config.AccountQueue = 16
 
 	// Add a batch of transactions to a pool one by one
-	pool1, key1 := setupTxPool()
+	pool1, key1 := setupTxPoolWithConfig(config)
 	defer pool1.Stop()
 
 	account1, _ := deriveSender(transaction(0, 0, key1))
-	pool1.currentState.AddBalance(account1, big.NewInt(1000000))
+	pool1.currentState.AddBalance(common.MainAccount, account1, funds(int64(config.AccountQueue)+5, 100000))
 
-	for i := uint64(0); i < testTxPoolConfig.AccountQueue+5; i++ {
-		if err := pool1.AddRemote(transaction(origin+i, 100000, key1)); err != nil {
+	for i := uint64(0); i < config.AccountQueue+5; i++ {
+		if err := pool1.AddTxPool(transaction(origin+i, 100000, key1)); err != nil {
 			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
 		}
 	}
 	// Add a batch of transactions to a pool in one big batch
-	pool2, key2 := setupTxPool()
+	pool2, key2 := setupTxPoolWithConfig(config)
 	defer pool2.Stop()
 
 	account2, _ := deriveSender(transaction(0, 0, key2))
-	pool2.currentState.AddBalance(account2, big.NewInt(1000000))
+	pool2.currentState.AddBalance(common.MainAccount, account2, funds(int64(config.AccountQueue)+5, 100000))
 
 	txs := []*types.Transaction{}
-	for i := uint64(0); i < testTxPoolConfig.AccountQueue+5; i++ {
+	for i := uint64(0); i < config.AccountQueue+5; i++ {
 		txs = append(txs, transaction(origin+i, 100000, key2))
 	}
-	pool2.AddRemotes(txs)
+	pool2.addTxs(txs, false)
+
+	// 账户的future队列上限在处理新区块时执行
+	lockedReset(pool1)
+	lockedReset(pool2)
 
 	// Ensure the batch optimization honors the same pool mechanics
-	if len(pool1.pending) != len(pool2.pending) {
-		t.Errorf("pending transaction count mismatch: one-by-one algo: %d, batch algo: %d", len(pool1.pending), len(pool2.pending))
+	pending1, queued1 := pool1.Stats()
+	pending2, queued2 := pool2.Stats()
+	if pending1 != pending2 {
+		t.Errorf("pending transaction count mismatch: one-by-one algo: %d, batch algo: %d", pending1, pending2)
 	}
-	if len(pool1.queue) != len(pool2.queue) {
-		t.Errorf("queued transaction count mismatch: one-by-one algo: %d, batch algo: %d", len(pool1.queue), len(pool2.queue))
+	if queued1 != queued2 {
+		t.Errorf("queued transaction count mismatch: one-by-one algo: %d, batch algo: %d", queued1, queued2)
 	}
 	if pool1.all.Count() != pool2.all.Count() {
 		t.Errorf("total transaction count mismatch: one-by-one algo %d, batch algo %d", pool1.all.Count(), pool2.all.Count())
@@ -996,28 +1013,23 @@ func testTransactionLimitingEquivalency(t *testing.T, origin uint64) {
 // some hard threshold, the higher transactions are dropped to prevent DOS
 // attacks.
 func TestTransactionPendingGlobalLimiting(t *testing.T) {
-	t.Parallel()
-
 	// Create the pool to test the limit enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
-
 	config := testTxPoolConfig
 	config.GlobalSlots = config.AccountSlots * 10
 
-	pool := NewTxPool(config, params.TestChainConfig, blockchain, "")
+	pool, _ := setupTxPoolWithConfig(config)
 	defer pool.Stop()
 
 	// Create a number of test accounts and fund them
 	keys := make([]*ecdsa.PrivateKey, 5)
 	for i := 0; i < len(keys); i++ {
 		keys[i], _ = crypto.GenerateKey()
-		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+		pool.currentState.AddBalance(common.MainAccount, crypto.PubkeyToAddress(keys[i].PublicKey), funds(int64(config.GlobalSlots), 100000))
 	}
 	// Generate and queue a batch of transactions
 	nonces := make(map[common.Address]uint64)
 
-	txs := types.Transactions{}
+	txs := []*types.Transaction{}
 	for _, key := range keys {
 		addr := crypto.PubkeyToAddress(key.PublicKey)
 		for j := 0; j < int(config.GlobalSlots)/len(keys)*2; j++ {
@@ -1026,11 +1038,11 @@ func TestTransactionPendingGlobalLimiting(t *testing.T) {
 		}
 	}
 	// Import the batch and verify that limits have been enforced
-	pool.AddRemotes(txs)
+	addTxs(pool, txs)
 
 	pending := 0
 	for _, list := range pool.pending {
-		pending += list.Len()
+		pending += list.Count()
 	}
 	if pending > int(config.GlobalSlots) {
 		t.Fatalf("total pending transactions overflow allowance: %d > %d", pending, config.GlobalSlots)
@@ -1042,31 +1054,25 @@ func TestTransactionPendingGlobalLimiting(t *testing.T) {
 
 // Tests that if transactions start being capped, transactions are also removed from 'all'
 func TestTransactionCapClearsFromAll(t *testing.T) {
-	t.Parallel()
-
 	// Create the pool to test the limit enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
-
 	config := testTxPoolConfig
 	config.AccountSlots = 2
 	config.AccountQueue = 2
 	config.GlobalSlots = 8
 
-	pool := NewTxPool(config, params.TestChainConfig, blockchain, "")
+	pool, key := setupTxPoolWithConfig(config)
 	defer pool.Stop()
 
 	// Create a number of test accounts and fund them
-	key, _ := crypto.GenerateKey()
 	addr := crypto.PubkeyToAddress(key.PublicKey)
-	pool.currentState.AddBalance(addr, big.NewInt(1000000))
+	pool.currentState.AddBalance(common.MainAccount, addr, funds(int64(config.GlobalSlots)*2, 100000))
 
-	txs := types.Transactions{}
+	txs := []*types.Transaction{}
 	for j := 0; j < int(config.GlobalSlots)*2; j++ {
 		txs = append(txs, transaction(uint64(j), 100000, key))
 	}
 	// Import the batch and verify that limits have been enforced
-	pool.AddRemotes(txs)
+	addTxs(pool, txs)
 	if err := validateTxPoolInternals(pool); err != nil {
 		t.Fatalf("pool internal state corrupted: %v", err)
 	}
@@ -1076,28 +1082,23 @@ func TestTransactionCapClearsFromAll(t *testing.T) {
 // some hard threshold, if they are under the minimum guaranteed slot count then
 // the transactions are still kept.
 func TestTransactionPendingMinimumAllowance(t *testing.T) {
-	t.Parallel()
-
 	// Create the pool to test the limit enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
-
 	config := testTxPoolConfig
 	config.GlobalSlots = 0
 
-	pool := NewTxPool(config, params.TestChainConfig, blockchain, "")
+	pool, _ := setupTxPoolWithConfig(config)
 	defer pool.Stop()
 
 	// Create a number of test accounts and fund them
 	keys := make([]*ecdsa.PrivateKey, 5)
 	for i := 0; i < len(keys); i++ {
 		keys[i], _ = crypto.GenerateKey()
-		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+		pool.currentState.AddBalance(common.MainAccount, crypto.PubkeyToAddress(keys[i].PublicKey), funds(int64(config.AccountSlots)*2, 100000))
 	}
 	// Generate and queue a batch of transactions
 	nonces := make(map[common.Address]uint64)
 
-	txs := types.Transactions{}
+	txs := []*types.Transaction{}
 	for _, key := range keys {
 		addr := crypto.PubkeyToAddress(key.PublicKey)
 		for j := 0; j < int(config.AccountSlots)*2; j++ {
@@ -1106,11 +1107,11 @@ func TestTransactionPendingMinimumAllowance(t *testing.T) {
 		}
 	}
 	// Import the batch and verify that limits have been enforced
-	pool.AddRemotes(txs)
+	addTxs(pool, txs)
 
 	for addr, list := range pool.pending {
-		if list.Len() != int(config.AccountSlots) {
-			t.Errorf("addr %x: total pending transactions mismatch: have %d, want %d", addr, list.Len(), config.AccountSlots)
+		if list.Count() != int(config.AccountSlots) {
+			t.Errorf("addr %x: total pending transactions mismatch: have %d, want %d", addr, list.Count(), config.AccountSlots)
 		}
 	}
 	if err := validateTxPoolInternals(pool); err != nil {
@@ -1118,233 +1119,39 @@ func TestTransactionPendingMinimumAllowance(t *testing.T) {
 	}
 }
 
-// Tests that setting the transaction pool gas price to a higher value correctly
-// discards everything cheaper than that and moves any gapped transactions back
-// from the pending pool to the queue.
-//
-// Note, local transactions are never allowed to be dropped.
-func TestTransactionPoolRepricing(t *testing.T) {
-	t.Parallel()
-
-	// Create the pool to test the pricing enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
-
-	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain, "")
-	defer pool.Stop()
-
-	// Keep track of transaction events to ensure all executables get announced
-	events := make(chan NewTxsEvent, 32)
-	sub := pool.txFeed.Subscribe(events)
-	defer sub.Unsubscribe()
-
-	// Create a number of test accounts and fund them
-	keys := make([]*ecdsa.PrivateKey, 4)
-	for i := 0; i < len(keys); i++ {
-		keys[i], _ = crypto.GenerateKey()
-		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
-	}
-	// Generate and queue a batch of transactions, both pending and queued
-	txs := types.Transactions{}
-
-	txs = append(txs, pricedTransaction(0, 100000, big.NewInt(2), keys[0]))
-	txs = append(txs, pricedTransaction(1, 100000, big.NewInt(1), keys[0]))
-	txs = append(txs, pricedTransaction(2, 100000, big.NewInt(2), keys[0]))
-
-	txs = append(txs, pricedTransaction(0, 100000, big.NewInt(1), keys[1]))
-	txs = append(txs, pricedTransaction(1, 100000, big.NewInt(2), keys[1]))
-	txs = append(txs, pricedTransaction(2, 100000, big.NewInt(2), keys[1]))
-
-	txs = append(txs, pricedTransaction(1, 100000, big.NewInt(2), keys[2]))
-	txs = append(txs, pricedTransaction(2, 100000, big.NewInt(1), keys[2]))
-	txs = append(txs, pricedTransaction(3, 100000, big.NewInt(2), keys[2]))
-
-	ltx := pricedTransaction(0, 100000, big.NewInt(1), keys[3])
-
-	// Import the batch and that both pending and queued transactions match up
-	pool.AddRemotes(txs)
-	pool.AddLocal(ltx)
-
-	pending, queued := pool.Stats()
-	if pending != 7 {
-		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 7)
-	}
-	if queued != 3 {
-		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 3)
-	}
-	if err := validateEvents(events, 7); err != nil {
-		t.Fatalf("original event firing failed: %v", err)
-	}
-	if err := validateTxPoolInternals(pool); err != nil {
-		t.Fatalf("pool internal state corrupted: %v", err)
-	}
-	// Reprice the pool and check that underpriced transactions get dropped
-	pool.SetGasPrice(big.NewInt(2))
-
-	pending, queued = pool.Stats()
-	if pending != 2 {
-		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
-	}
-	if queued != 5 {
-		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 5)
-	}
-	if err := validateEvents(events, 0); err != nil {
-		t.Fatalf("reprice event firing failed: %v", err)
-	}
-	if err := validateTxPoolInternals(pool); err != nil {
-		t.Fatalf("pool internal state corrupted: %v", err)
-	}
-	// Check that we can't add the old transactions back
-	if err := pool.AddRemote(pricedTransaction(1, 100000, big.NewInt(1), keys[0])); err != ErrUnderpriced {
-		t.Fatalf("adding underpriced pending transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
-	}
-	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), keys[1])); err != ErrUnderpriced {
-		t.Fatalf("adding underpriced pending transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
-	}
-	if err := pool.AddRemote(pricedTransaction(2, 100000, big.NewInt(1), keys[2])); err != ErrUnderpriced {
-		t.Fatalf("adding underpriced queued transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
-	}
-	if err := validateEvents(events, 0); err != nil {
-		t.Fatalf("post-reprice event firing failed: %v", err)
-	}
-	if err := validateTxPoolInternals(pool); err != nil {
-		t.Fatalf("pool internal state corrupted: %v", err)
-	}
-	// However we can add local underpriced transactions
-	tx := pricedTransaction(1, 100000, big.NewInt(1), keys[3])
-	if err := pool.AddLocal(tx); err != nil {
-		t.Fatalf("failed to add underpriced local transaction: %v", err)
-	}
-	if pending, _ = pool.Stats(); pending != 3 {
-		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
-	}
-	if err := validateEvents(events, 1); err != nil {
-		t.Fatalf("post-reprice local event firing failed: %v", err)
-	}
-	if err := validateTxPoolInternals(pool); err != nil {
-		t.Fatalf("pool internal state corrupted: %v", err)
-	}
-	// And we can fill gaps with properly priced transactions
-	if err := pool.AddRemote(pricedTransaction(1, 100000, big.NewInt(2), keys[0])); err != nil {
-		t.Fatalf("failed to add pending transaction: %v", err)
-	}
-	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(2), keys[1])); err != nil {
-		t.Fatalf("failed to add pending transaction: %v", err)
-	}
-	if err := pool.AddRemote(pricedTransaction(2, 100000, big.NewInt(2), keys[2])); err != nil {
-		t.Fatalf("failed to add queued transaction: %v", err)
-	}
-	if err := validateEvents(events, 5); err != nil {
-		t.Fatalf("post-reprice event firing failed: %v", err)
-	}
-	if err := validateTxPoolInternals(pool); err != nil {
-		t.Fatalf("pool internal state corrupted: %v", err)
-	}
-}
-
-// Tests that setting the transaction pool gas price to a higher value does not
-// remove local transactions.
-func TestTransactionPoolRepricingKeepsLocals(t *testing.T) {
-	t.Parallel()
-
-	// Create the pool to test the pricing enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
-
-	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain, "")
-	defer pool.Stop()
-
-	// Create a number of test accounts and fund them
-	keys := make([]*ecdsa.PrivateKey, 3)
-	for i := 0; i < len(keys); i++ {
-		keys[i], _ = crypto.GenerateKey()
-		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000*1000000))
-	}
-	// Create transaction (both pending and queued) with a linearly growing gasprice
-	for i := uint64(0); i < 500; i++ {
-		// Add pending
-		p_tx := pricedTransaction(i, 100000, big.NewInt(int64(i)), keys[2])
-		if err := pool.AddLocal(p_tx); err != nil {
-			t.Fatal(err)
-		}
-		// Add queued
-		q_tx := pricedTransaction(i+501, 100000, big.NewInt(int64(i)), keys[2])
-		if err := pool.AddLocal(q_tx); err != nil {
-			t.Fatal(err)
-		}
-	}
-	pending, queued := pool.Stats()
-	expPending, expQueued := 500, 500
-	validate := func() {
-		pending, queued = pool.Stats()
-		if pending != expPending {
-			t.Fatalf("pending transactions mismatched: have %d, want %d", pending, expPending)
-		}
-		if queued != expQueued {
-			t.Fatalf("queued transactions mismatched: have %d, want %d", queued, expQueued)
-		}
-
-		if err := validateTxPoolInternals(pool); err != nil {
-			t.Fatalf("pool internal state corrupted: %v", err)
-		}
-	}
-	validate()
-
-	// Reprice the pool and check that nothing is dropped
-	pool.SetGasPrice(big.NewInt(2))
-	validate()
-
-	pool.SetGasPrice(big.NewInt(2))
-	pool.SetGasPrice(big.NewInt(4))
-	pool.SetGasPrice(big.NewInt(8))
-	pool.SetGasPrice(big.NewInt(100))
-	validate()
-}
-
 // Tests that when the pool reaches its global transaction limit, underpriced
-// transactions are gradually shifted out for more expensive ones and any gapped
-// pending transactions are moved into the queue.
-//
-// Note, local transactions are never allowed to be dropped.
+// transactions are rejected, while transactions offering a higher gas price
+// evict the cheapest ones of the currency.
 func TestTransactionPoolUnderpricing(t *testing.T) {
-	t.Parallel()
-
 	// Create the pool to test the pricing enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
-
 	config := testTxPoolConfig
 	config.GlobalSlots = 2
 	config.GlobalQueue = 2
 
-	pool := NewTxPool(config, params.TestChainConfig, blockchain, "")
+	pool, _ := setupTxPoolWithConfig(config)
 	defer pool.Stop()
 
-	// Keep track of transaction events to ensure all executables get announced
-	events := make(chan NewTxsEvent, 32)
-	sub := pool.txFeed.Subscribe(events)
-	defer sub.Unsubscribe()
-
 	// Create a number of test accounts and fund them
 	keys := make([]*ecdsa.PrivateKey, 4)
 	for i := 0; i < len(keys); i++ {
 		keys[i], _ = crypto.GenerateKey()
-		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+		pool.currentState.AddBalance(common.MainAccount, crypto.PubkeyToAddress(keys[i].PublicKey), funds(10, 1000000))
 	}
 	// Generate and queue a batch of transactions, both pending and queued
-	txs := types.Transactions{}
-
-	txs = append(txs, pricedTransaction(0, 100000, big.NewInt(1), keys[0]))
-	txs = append(txs, pricedTransaction(1, 100000, big.NewInt(2), keys[0]))
+	txs := []*types.Transaction{}
 
-	txs = append(txs, pricedTransaction(1, 100000, big.NewInt(1), keys[1]))
+	txs = append(txs, pricedTransaction(0, 100000, price(1), keys[0]))
+	txs = append(txs, pricedTransaction(1, 100000, price(2), keys[0]))
 
-	ltx := pricedTransaction(0, 100000, big.NewInt(1), keys[2])
+	txs = append(txs, pricedTransaction(1, 100000, price(1), keys[1]))
+	txs = append(txs, pricedTransaction(0, 100000, price(1), keys[2]))
 
 	// Import the batch and that both pending and queued transactions match up
-	pool.AddRemotes(txs)
-	pool.AddLocal(ltx)
-
+	for i, err := range addTxs(pool, txs) {
+		if err != nil {
+			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
+		}
+	}
 	pending, queued := pool.Stats()
 	if pending != 3 {
 		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
@@ -1352,57 +1159,44 @@ func TestTransactionPoolUnderpricing(t *testing.T) {
 	if queued != 1 {
 		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
 	}
-	if err := validateEvents(events, 3); err != nil {
-		t.Fatalf("original event firing failed: %v", err)
-	}
 	if err := validateTxPoolInternals(pool); err != nil {
 		t.Fatalf("pool internal state corrupted: %v", err)
 	}
 	// Ensure that adding an underpriced transaction on block limit fails
-	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), keys[1])); err != ErrUnderpriced {
-		t.Fatalf("adding underpriced pending transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
-	}
-	// Ensure that adding high priced transactions drops cheap ones, but not own
-	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(3), keys[1])); err != nil { // +K1:0 => -K1:1 => Pend K0:0, K0:1, K1:0, K2:0; Que -
-		t.Fatalf("failed to add well priced transaction: %v", err)
-	}
-	if err := pool.AddRemote(pricedTransaction(2, 100000, big.NewInt(4), keys[1])); err != nil { // +K1:2 => -K0:0 => Pend K1:0, K2:0; Que K0:1 K1:2
-		t.Fatalf("failed to add well priced transaction: %v", err)
-	}
-	if err := pool.AddRemote(pricedTransaction(3, 100000, big.NewInt(5), keys[1])); err != nil { // +K1:3 => -K0:1 => Pend K1:0, K2:0; Que K1:2 K1:3
-		t.Fatalf("failed to add well priced transaction: %v", err)
-	}
-	pending, queued = pool.Stats()
-	if pending != 2 {
-		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
-	}
-	if queued != 2 {
-		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 2)
-	}
-	if err := validateEvents(events, 1); err != nil {
-		t.Fatalf("additional event firing failed: %v", err)
-	}
-	if err := validateTxPoolInternals(pool); err != nil {
-		t.Fatalf("pool internal state corrupted: %v", err)
-	}
-	// Ensure that adding local transactions can push out even higher priced ones
-	ltx = pricedTransaction(1, 100000, big.NewInt(0), keys[2])
-	if err := pool.AddLocal(ltx); err != nil {
-		t.Fatalf("failed to append underpriced local transaction: %v", err)
+	if err := pool.AddTxPool(pricedTransaction(0, 100000, price(1), keys[1])); err != ErrTXPoolFull {
+		t.Fatalf("adding underpriced pending transaction error mismatch: have %v, want %v", err, ErrTXPoolFull)
+	}
+	// Ensure that adding high priced transactions drops the cheap ones, but not the better ones
+	better := []*types.Transaction{
+		pricedTransaction(0, 100000, price(3), keys[1]),
+		pricedTransaction(0, 100000, price(4), keys[3]),
+		pricedTransaction(1, 100000, price(5), keys[3]),
+	}
+	for i, tx := range better {
+		if err := pool.AddTxPool(tx); err != nil {
+			t.Fatalf("tx %d: failed to add well priced transaction: %v", i, err)
+		}
+		if count := pool.all.Count(); count != int(config.GlobalSlots+config.GlobalQueue) {
+			t.Fatalf("tx %d: total transactions mismatched: have %d, want %d", i, count, config.GlobalSlots+config.GlobalQueue)
+		}
 	}
-	ltx = pricedTransaction(0, 100000, big.NewInt(0), keys[3])
-	if err := pool.AddLocal(ltx); err != nil {
-		t.Fatalf("failed to add new underpriced local transaction: %v", err)
+	for i, tx := range txs {
+		if kept := pool.all.Get(tx.Hash()) != nil; kept != (tx.GasPrice().Cmp(price(1)) > 0) {
+			t.Errorf("tx %d: presence mismatch: have %v, want %v", i, kept, !kept)
+		}
 	}
-	pending, queued = pool.Stats()
-	if pending != 3 {
-		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
+	for i, tx := range better {
+		if pool.all.Get(tx.Hash()) == nil {
+			t.Errorf("well priced tx %d dropped", i)
+		}
 	}
-	if queued != 1 {
-		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	// 最低出价的交易被挤掉后, 其后续nonce的交易退回future队列
+	if !queuedTx(pool, crypto.PubkeyToAddress(keys[0].PublicKey), txs[1]) {
+		t.Errorf("transaction after the evicted one not queued")
 	}
-	if err := validateEvents(events, 2); err != nil {
-		t.Fatalf("local event firing failed: %v", err)
+	// Ensure that the transactions offering no more than the cheapest one are still rejected
+	if err := pool.AddTxPool(pricedTransaction(1, 100000, price(2), keys[2])); err != ErrTXPoolFull {
+		t.Fatalf("adding underpriced transaction error mismatch: have %v, want %v", err, ErrTXPoolFull)
 	}
 	if err := validateTxPoolInternals(pool); err != nil {
 		t.Fatalf("pool internal state corrupted: %v", err)
@@ -1413,36 +1207,26 @@ func TestTransactionPoolUnderpricing(t *testing.T) {
 // without producing instability by creating gaps that start jumping transactions
 // back and forth between queued/pending.
 func TestTransactionPoolStableUnderpricing(t *testing.T) {
-	t.Parallel()
-
 	// Create the pool to test the pricing enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
-
 	config := testTxPoolConfig
 	config.GlobalSlots = 128
 	config.GlobalQueue = 0
 
-	pool := NewTxPool(config, params.TestChainConfig, blockchain, "")
+	pool, _ := setupTxPoolWithConfig(config)
 	defer pool.Stop()
 
-	// Keep track of transaction events to ensure all executables get announced
-	events := make(chan NewTxsEvent, 32)
-	sub := pool.txFeed.Subscribe(events)
-	defer sub.Unsubscribe()
-
 	// Create a number of test accounts and fund them
 	keys := make([]*ecdsa.PrivateKey, 2)
 	for i := 0; i < len(keys); i++ {
 		keys[i], _ = crypto.GenerateKey()
-		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+		pool.currentState.AddBalance(common.MainAccount, crypto.PubkeyToAddress(keys[i].PublicKey), funds(int64(config.GlobalSlots), 100000))
 	}
 	// Fill up the entire queue with the same transaction price points
-	txs := types.Transactions{}
+	txs := []*types.Transaction{}
 	for i := uint64(0); i < config.GlobalSlots; i++ {
-		txs = append(txs, pricedTransaction(i, 100000, big.NewInt(1), keys[0]))
+		txs = append(txs, pricedTransaction(i, 100000, price(1), keys[0]))
 	}
-	pool.AddRemotes(txs)
+	addTxs(pool, txs)
 
 	pending, queued := pool.Stats()
 	if pending != int(config.GlobalSlots) {
@@ -1451,14 +1235,11 @@ func TestTransactionPoolStableUnderpricing(t *testing.T) {
 	if queued != 0 {
 		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
 	}
-	if err := validateEvents(events, int(config.GlobalSlots)); err != nil {
-		t.Fatalf("original event firing failed: %v", err)
-	}
 	if err := validateTxPoolInternals(pool); err != nil {
 		t.Fatalf("pool internal state corrupted: %v", err)
 	}
 	// Ensure that adding high priced transactions drops a cheap, but doesn't produce a gap
-	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(3), keys[1])); err != nil {
+	if err := pool.AddTxPool(pricedTransaction(0, 100000, price(3), keys[1])); err != nil {
 		t.Fatalf("failed to add well priced transaction: %v", err)
 	}
 	pending, queued = pool.Stats()
@@ -1468,234 +1249,175 @@ func TestTransactionPoolStableUnderpricing(t *testing.T) {
 	if queued != 0 {
 		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
 	}
-	if err := validateEvents(events, 1); err != nil {
-		t.Fatalf("additional event firing failed: %v", err)
+	if pool.all.Get(txs[len(txs)-1].Hash()) != nil {
+		t.Errorf("highest nonce cheap transaction not dropped")
 	}
 	if err := validateTxPoolInternals(pool); err != nil {
 		t.Fatalf("pool internal state corrupted: %v", err)
 	}
 }
 
-// Tests that the pool rejects replacement transactions that don't meet the minimum
-// price bump required.
-func TestTransactionReplacement(t *testing.T) {
-	t.Parallel()
-
-	// Create the pool to test the pricing enforcement with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
-
-	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain, "")
+// Tests that raising the lowest gas price of the matrix state rejects cheaper
+// new transactions, while keeping the ones already in the pool.
+func TestTransactionPoolRepricing(t *testing.T) {
+	pool, key := setupTxPool()
 	defer pool.Stop()
 
-	// Keep track of transaction events to ensure all executables get announced
-	events := make(chan NewTxsEvent, 32)
-	sub := pool.txFeed.Subscribe(events)
-	defer sub.Unsubscribe()
-
-	// Create a test account to add transactions with
-	key, _ := crypto.GenerateKey()
-	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
-
-	// Add pending transactions, ensuring the minimum price bump is enforced for replacement (for ultra low prices too)
-	price := int64(100)
-	threshold := (price * (100 + int64(testTxPoolConfig.PriceBump))) / 100
-
-	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), key)); err != nil {
-		t.Fatalf("failed to add original cheap pending transaction: %v", err)
-	}
-	if err := pool.AddRemote(pricedTransaction(0, 100001, big.NewInt(1), key)); err != ErrReplaceUnderpriced {
-		t.Fatalf("original cheap pending transaction replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
-	}
-	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(2), key)); err != nil {
-		t.Fatalf("failed to replace original cheap pending transaction: %v", err)
-	}
-	if err := validateEvents(events, 2); err != nil {
-		t.Fatalf("cheap replacement event firing failed: %v", err)
-	}
+	account := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(common.MainAccount, account, funds(10, 100000))
 
-	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(price), key)); err != nil {
-		t.Fatalf("failed to add original proper pending transaction: %v", err)
-	}
-	if err := pool.AddRemote(pricedTransaction(0, 100001, big.NewInt(threshold-1), key)); err != ErrReplaceUnderpriced {
-		t.Fatalf("original proper pending transaction replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
-	}
-	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(threshold), key)); err != nil {
-		t.Fatalf("failed to replace original proper pending transaction: %v", err)
-	}
-	if err := validateEvents(events, 2); err != nil {
-		t.Fatalf("proper replacement event firing failed: %v", err)
+	txs := []*types.Transaction{
+		pricedTransaction(0, 100000, price(1), key),
+		pricedTransaction(1, 100000, price(2), key),
+		pricedTransaction(3, 100000, price(1), key),
 	}
-	// Add queued transactions, ensuring the minimum price bump is enforced for replacement (for ultra low prices too)
-	if err := pool.AddRemote(pricedTransaction(2, 100000, big.NewInt(1), key)); err != nil {
-		t.Fatalf("failed to add original cheap queued transaction: %v", err)
+	for i, err := range addTxs(pool, txs) {
+		if err != nil {
+			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
+		}
 	}
-	if err := pool.AddRemote(pricedTransaction(2, 100001, big.NewInt(1), key)); err != ErrReplaceUnderpriced {
-		t.Fatalf("original cheap queued transaction replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	// Reprice the pool and check that the pooled transactions are kept
+	opt, err := matrixstate.GetManager(manparams.VersionAlpha).FindOperator(mc.MSTxpoolGasLimitCfg)
+	if err != nil {
+		t.Fatalf("failed to find the txpool gas price operator: %v", err)
 	}
-	if err := pool.AddRemote(pricedTransaction(2, 100000, big.NewInt(2), key)); err != nil {
-		t.Fatalf("failed to replace original cheap queued transaction: %v", err)
+	if err := opt.SetValue(pool.currentState, price(2)); err != nil {
+		t.Fatalf("failed to set the txpool gas price: %v", err)
 	}
+	lockedReset(pool)
 
-	if err := pool.AddRemote(pricedTransaction(2, 100000, big.NewInt(price), key)); err != nil {
-		t.Fatalf("failed to add original proper queued transaction: %v", err)
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("pool content mismatched: have %d pending %d queued, want 2 and 1", pending, queued)
 	}
-	if err := pool.AddRemote(pricedTransaction(2, 100001, big.NewInt(threshold-1), key)); err != ErrReplaceUnderpriced {
-		t.Fatalf("original proper queued transaction replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	// Check that new transactions are priced against the new gas price
+	if err := pool.AddTxPool(pricedTransaction(2, 100000, price(1), key)); err != ErrUnderpriced {
+		t.Fatalf("adding underpriced transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
 	}
-	if err := pool.AddRemote(pricedTransaction(2, 100000, big.NewInt(threshold), key)); err != nil {
-		t.Fatalf("failed to replace original proper queued transaction: %v", err)
+	if err := pool.AddTxPool(pricedTransaction(2, 100000, price(2), key)); err != nil {
+		t.Fatalf("failed to add well priced transaction: %v", err)
 	}
-
-	if err := validateEvents(events, 0); err != nil {
-		t.Fatalf("queued replacement event firing failed: %v", err)
+	if pending, queued := pool.Stats(); pending != 4 || queued != 0 {
+		t.Fatalf("pool content mismatched: have %d pending %d queued, want 4 and 0", pending, queued)
 	}
 	if err := validateTxPoolInternals(pool); err != nil {
 		t.Fatalf("pool internal state corrupted: %v", err)
 	}
 }
 
-// Tests that local transactions are journaled to disk, but remote transactions
-// get discarded between restarts.
-func TestTransactionJournaling(t *testing.T)         { testTransactionJournaling(t, false) }
-func TestTransactionJournalingNoLocals(t *testing.T) { testTransactionJournaling(t, true) }
-
-func testTransactionJournaling(t *testing.T, nolocals bool) {
-	t.Parallel()
-
-	// Create a temporary file for the journal
-	file, err := ioutil.TempFile("", "")
-	if err != nil {
-		t.Fatalf("failed to create temporary journal: %v", err)
-	}
-	journal := file.Name()
-	defer os.Remove(journal)
-
-	// Clean up the temporary file, we only need the path for now
-	file.Close()
-	os.Remove(journal)
-
-	// Create the original pool to inject transaction into the journal
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
-
-	config := testTxPoolConfig
-	config.NoLocals = nolocals
-	config.Journal = journal
-	config.Rejournal = time.Second
-
-	pool := NewTxPool(config, params.TestChainConfig, blockchain, "")
+// Tests that the pool rejects replacement transactions that don't raise the
+// offered gas price by the minimum price bump, and accepts the ones that do.
+func TestTransactionReplacement(t *testing.T) {
+	// Create the pool to test the pricing enforcement with
+	pool, key := setupTxPool()
+	defer pool.Stop()
 
-	// Create two test accounts to ensure remotes expire but locals do not
-	local, _ := crypto.GenerateKey()
-	remote, _ := crypto.GenerateKey()
+	// Create a test account to add transactions with
+	pool.currentState.AddBalance(common.MainAccount, crypto.PubkeyToAddress(key.PublicKey), funds(1000, 1000000))
 
-	pool.currentState.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
-	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+	base := price(10)
+	threshold := new(big.Int).Div(new(big.Int).Mul(base, big.NewInt(100+int64(testTxPoolConfig.PriceBump))), big.NewInt(100))
 
-	// Add three local and a remote transactions and ensure they are queued up
-	if err := pool.AddLocal(pricedTransaction(0, 100000, big.NewInt(1), local)); err != nil {
-		t.Fatalf("failed to add local transaction: %v", err)
-	}
-	if err := pool.AddLocal(pricedTransaction(1, 100000, big.NewInt(1), local)); err != nil {
-		t.Fatalf("failed to add local transaction: %v", err)
-	}
-	if err := pool.AddLocal(pricedTransaction(2, 100000, big.NewInt(1), local)); err != nil {
-		t.Fatalf("failed to add local transaction: %v", err)
-	}
-	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), remote)); err != nil {
-		t.Fatalf("failed to add remote transaction: %v", err)
-	}
-	pending, queued := pool.Stats()
-	if pending != 4 {
-		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 4)
+	// Add pending and queued transactions with the base price, and bump them in place
+	for _, nonce := range []uint64{0, 2} {
+		original := pricedTransaction(nonce, 100000, base, key)
+		if err := pool.AddTxPool(original); err != nil {
+			t.Fatalf("nonce %d: failed to add original transaction: %v", nonce, err)
+		}
+		if err := pool.AddTxPool(pricedTransaction(nonce, 100001, base, key)); err != ErrReplaceUnderpriced {
+			t.Fatalf("nonce %d: same price replacement error mismatch: have %v, want %v", nonce, err, ErrReplaceUnderpriced)
+		}
+		below := new(big.Int).Sub(threshold, big.NewInt(1))
+		if err := pool.AddTxPool(pricedTransaction(nonce, 100000, below, key)); err != ErrReplaceUnderpriced {
+			t.Fatalf("nonce %d: below threshold replacement error mismatch: have %v, want %v", nonce, err, ErrReplaceUnderpriced)
+		}
+		bumped := pricedTransaction(nonce, 100000, threshold, key)
+		if err := pool.AddTxPool(bumped); err != nil {
+			t.Fatalf("nonce %d: failed to replace with bumped transaction: %v", nonce, err)
+		}
+		if pool.all.Get(original.Hash()) != nil {
+			t.Errorf("nonce %d: replaced transaction still in the pool", nonce)
+		}
+		if pool.all.Get(bumped.Hash()) == nil {
+			t.Errorf("nonce %d: bumped transaction missing from the pool", nonce)
+		}
 	}
-	if queued != 0 {
-		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("pool content mismatched: have %d pending %d queued, want 1 and 1", pending, queued)
 	}
 	if err := validateTxPoolInternals(pool); err != nil {
 		t.Fatalf("pool internal state corrupted: %v", err)
 	}
-	// Terminate the old pool, bump the local nonce, create a new pool and ensure relevant transaction survive
-	pool.Stop()
-	statedb.SetNonce(crypto.PubkeyToAddress(local.PublicKey), 1)
-	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+}
 
-	pool = NewTxPool(config, params.TestChainConfig, blockchain, "")
+// Tests that removing a transaction already mined keeps the following ones
+// pending, while removing a transaction not mined demotes them.
+func TestTransactionMinedRemoval(t *testing.T) {
+	pool, key := setupTxPool()
+	defer pool.Stop()
 
-	pending, queued = pool.Stats()
-	if queued != 0 {
-		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
-	}
-	if nolocals {
-		if pending != 0 {
-			t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 0)
-		}
-	} else {
-		if pending != 2 {
-			t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(common.MainAccount, addr, funds(10, 100000))
+
+	txs := []*types.Transaction{transaction(0, 100000, key), transaction(1, 100000, key), transaction(2, 100000, key), transaction(3, 100000, key)}
+	for i, err := range addTxs(pool, txs) {
+		if err != nil {
+			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
 		}
 	}
-	if err := validateTxPoolInternals(pool); err != nil {
-		t.Fatalf("pool internal state corrupted: %v", err)
-	}
-	// Bump the nonce temporarily and ensure the newly invalidated transaction is removed
-	statedb.SetNonce(crypto.PubkeyToAddress(local.PublicKey), 2)
-	pool.lockedReset(nil, nil)
-	time.Sleep(2 * config.Rejournal)
-	pool.Stop()
+	// 第一笔交易已打包, 超时后被删除
+	pool.currentState.SetNonce(addr, 1)
+	pool.mu.Lock()
+	pool.removeTx(txs[0].Hash(), true)
+	pool.mu.Unlock()
 
-	statedb.SetNonce(crypto.PubkeyToAddress(local.PublicKey), 1)
-	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
-	pool = NewTxPool(config, params.TestChainConfig, blockchain, "")
+	if pending, queued := pool.Stats(); pending != 3 || queued != 0 {
+		t.Fatalf("pool content mismatched: have %d pending %d queued, want 3 and 0", pending, queued)
+	}
+	if fn := pool.pendingState.GetNonce(addr); fn != 4|params.NonceAddOne {
+		t.Errorf("pending nonce mismatch: have %d, want %d", fn&params.NonceSubOne, 4)
+	}
+	// 未打包的交易被删除, 后续交易退回future队列
+	pool.mu.Lock()
+	pool.removeTx(txs[2].Hash(), true)
+	pool.mu.Unlock()
 
-	pending, queued = pool.Stats()
-	if pending != 0 {
-		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 0)
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("pool content mismatched: have %d pending %d queued, want 1 and 1", pending, queued)
 	}
-	if nolocals {
-		if queued != 0 {
-			t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
-		}
-	} else {
-		if queued != 1 {
-			t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
-		}
+	if !pendingTx(pool, addr, txs[1]) || !queuedTx(pool, addr, txs[3]) {
+		t.Errorf("transactions not demoted")
+	}
+	if fn := pool.pendingState.GetNonce(addr); fn != 2|params.NonceAddOne {
+		t.Errorf("pending nonce mismatch: have %d, want %d", fn&params.NonceSubOne, 2)
 	}
 	if err := validateTxPoolInternals(pool); err != nil {
 		t.Fatalf("pool internal state corrupted: %v", err)
 	}
-	pool.Stop()
 }
 
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
-	t.Parallel()
-
 	// Create the pool to test the status retrievals with
-	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
-	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
-
-	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain, "")
+	pool, _ := setupTxPool()
 	defer pool.Stop()
 
 	// Create the test accounts to check various transaction statuses with
 	keys := make([]*ecdsa.PrivateKey, 3)
 	for i := 0; i < len(keys); i++ {
 		keys[i], _ = crypto.GenerateKey()
-		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+		pool.currentState.AddBalance(common.MainAccount, crypto.PubkeyToAddress(keys[i].PublicKey), funds(10, 100000))
 	}
 	// Generate and queue a batch of transactions, both pending and queued
-	txs := types.Transactions{}
+	txs := []*types.Transaction{}
 
-	txs = append(txs, pricedTransaction(0, 100000, big.NewInt(1), keys[0])) // Pending only
-	txs = append(txs, pricedTransaction(0, 100000, big.NewInt(1), keys[1])) // Pending and queued
-	txs = append(txs, pricedTransaction(2, 100000, big.NewInt(1), keys[1]))
-	txs = append(txs, pricedTransaction(2, 100000, big.NewInt(1), keys[2])) // Queued only
+	txs = append(txs, transaction(0, 100000, keys[0])) // Pending only
+	txs = append(txs, transaction(0, 100000, keys[1])) // Pending and queued
+	txs = append(txs, transaction(2, 100000, keys[1]))
+	txs = append(txs, transaction(2, 100000, keys[2])) // Queued only
 
 	// Import the transaction and ensure they are correctly added
-	pool.AddRemotes(txs)
+	addTxs(pool, txs)
 
 	pending, queued := pool.Stats()
 	if pending != 2 {
@@ -1736,16 +1458,17 @@ func benchmarkPendingDemotion(b *testing.B, size int) {
 	defer pool.Stop()
 
 	account, _ := deriveSender(transaction(0, 0, key))
-	pool.currentState.AddBalance(account, big.NewInt(1000000))
+	pool.currentState.AddBalance(common.MainAccount, account, funds(int64(size), 100000))
 
 	for i := 0; i < size; i++ {
 		tx := transaction(uint64(i), 100000, key)
-		pool.promoteTx(account, tx.Hash(), tx)
+		pool.all.Add(tx)
+		pool.promoteTx(account, tx)
 	}
 	// Benchmark the speed of pool validation
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		pool.demoteUnexecutables()
+		pool.DemoteUnexecutables()
 	}
 }
 
@@ -1761,11 +1484,12 @@ func benchmarkFuturePromotion(b *testing.B, size int) {
 	defer pool.Stop()
 
 	account, _ := deriveSender(transaction(0, 0, key))
-	pool.currentState.AddBalance(account, big.NewInt(1000000))
+	pool.currentState.AddBalance(common.MainAccount, account, funds(int64(size), 100000))
 
 	for i := 0; i < size; i++ {
 		tx := transaction(uint64(1+i), 100000, key)
-		pool.enqueueTx(tx.Hash(), tx)
+		pool.all.Add(tx)
+		pool.enqueueTx(account, tx)
 	}
 	// Benchmark the speed of pool validation
 	b.ResetTimer()
@@ -1781,16 +1505,16 @@ func BenchmarkPoolInsert(b *testing.B) {
 	defer pool.Stop()
 
 	account, _ := deriveSender(transaction(0, 0, key))
-	pool.currentState.AddBalance(account, big.NewInt(1000000))
+	pool.currentState.AddBalance(common.MainAccount, account, funds(int64(b.N), 100000))
 
-	txs := make(types.Transactions, b.N)
+	txs := make([]*types.Transaction, b.N)
 	for i := 0; i < b.N; i++ {
 		txs[i] = transaction(uint64(i), 100000, key)
 	}
 	// Benchmark importing the transactions into the queue
 	b.ResetTimer()
 	for _, tx := range txs {
-		pool.AddRemote(tx)
+		pool.AddTxPool(tx)
 	}
 }
 
@@ -1805,11 +1529,11 @@ func benchmarkPoolBatchInsert(b *testing.B, size int) {
 	defer pool.Stop()
 
 	account, _ := deriveSender(transaction(0, 0, key))
-	pool.currentState.AddBalance(account, big.NewInt(1000000))
+	pool.currentState.AddBalance(common.MainAccount, account, funds(int64(b.N*size), 100000))
 
-	batches := make([]types.Transactions, b.N)
+	batches := make([][]*types.Transaction, b.N)
 	for i := 0; i < b.N; i++ {
-		batches[i] = make(types.Transactions, size)
+		batches[i] = make([]*types.Transaction, size)
 		for j := 0; j < size; j++ {
 			batches[i][j] = transaction(uint64(size*i+j), 100000, key)
 		}
@@ -1817,6 +1541,6 @@ func benchmarkPoolBatchInsert(b *testing.B, size int) {
 	// Benchmark importing the transactions into the queue
 	b.ResetTimer()
 	for _, batch := range batches {
-		pool.AddRemotes(batch)
+		pool.addTxs(batch, false)
 	}
 }
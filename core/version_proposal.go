@@ -0,0 +1,152 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/consensus/mtxdpos"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+)
+
+var (
+	ErrVersionProposal       = errors.New("version proposal is invalid")
+	ErrVersionProposalSigner = errors.New("version proposal not signed by a version super account")
+	ErrVersionProposalSigned = errors.New("version proposal already signed by the account")
+)
+
+// maxVersionLength 版本号签名的是BytesToHash(version),超过32字节会被截断
+const maxVersionLength = common.HashLength
+
+// VersionProposalExpireBlocks is the number of blocks after its activation
+// height an approved version proposal waits for the chain to switch before it
+// expires.
+const VersionProposalExpireBlocks = 10000
+
+// checkVersionProposal checks the version proposal transaction of from at the
+// given height against the matrix state. It returns the decoded transaction,
+// the account that made the version signature and the proposals of the state.
+func checkVersionProposal(st matrixstate.StateDB, from common.Address, data []byte, height uint64) (*common.VersionProposalTx, common.Address, *mc.VersionProposalInfo, error) {
+	// Beta版本之前不支持版本升级提案交易
+	if !manparams.IsBetaVersion(matrixstate.GetVersionInfo(st)) {
+		return nil, common.Address{}, nil, ErrTXUnknownType
+	}
+	tx := new(common.VersionProposalTx)
+	if err := json.Unmarshal(data, tx); err != nil {
+		return nil, common.Address{}, nil, ErrVersionProposal
+	}
+	if len(tx.Version) == 0 || len(tx.Version) > maxVersionLength || tx.ActiveHeight <= height {
+		return nil, common.Address{}, nil, ErrVersionProposal
+	}
+	accounts, err := matrixstate.GetVersionSuperAccounts(st)
+	if err != nil {
+		return nil, common.Address{}, nil, err
+	}
+	if !containsAccount(accounts, from) {
+		return nil, common.Address{}, nil, ErrVersionProposalSigner
+	}
+	// 签名与区块头中的版本号签名相同,提案通过后可直接使用
+	signer, _, err := crypto.VerifySignWithValidate(common.BytesToHash([]byte(tx.Version)).Bytes(), common.CopyBytes(tx.Signature[:]))
+	if err != nil || !containsAccount(accounts, signer) {
+		return nil, common.Address{}, nil, ErrVersionProposalSigner
+	}
+	info, err := matrixstate.GetVersionProposals(st)
+	if err != nil {
+		return nil, common.Address{}, nil, err
+	}
+	for _, proposal := range info.Proposals {
+		// 已生效或已过期的提案不再影响新的提案
+		if proposal.Version != tx.Version || proposal.ActiveHeight <= height {
+			continue
+		}
+		// 同一版本同时只能有一个生效高度
+		if proposal.ActiveHeight != tx.ActiveHeight {
+			return nil, common.Address{}, nil, ErrVersionProposal
+		}
+		if containsAccount(proposal.Signers, signer) {
+			return nil, common.Address{}, nil, ErrVersionProposalSigned
+		}
+	}
+	return tx, signer, info, nil
+}
+
+// applyVersionProposal records the version signature of a version proposal
+// transaction in the matrix state. The proposal is approved once the signatures
+// reach the threshold of the DPOS engine on the version super accounts.
+func applyVersionProposal(st matrixstate.StateDB, simpleMode bool, from common.Address, data []byte, height uint64) (*mc.VersionProposal, error) {
+	tx, signer, info, err := checkVersionProposal(st, from, data, height)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := matrixstate.GetVersionSuperAccounts(st)
+	if err != nil {
+		return nil, err
+	}
+
+	// 清理未通过且已过生效高度的提案, 以及链已切换到的已通过提案
+	version := matrixstate.GetVersionInfo(st)
+	proposals := make([]mc.VersionProposal, 0, len(info.Proposals)+1)
+	index := -1
+	for _, proposal := range info.Proposals {
+		if isVersionProposalDone(&proposal, height, version) {
+			continue
+		}
+		if proposal.Version == tx.Version && proposal.ActiveHeight == tx.ActiveHeight {
+			index = len(proposals)
+		}
+		proposals = append(proposals, proposal)
+	}
+	if index < 0 {
+		index = len(proposals)
+		proposals = append(proposals, mc.VersionProposal{
+			Version:       tx.Version,
+			ActiveHeight:  tx.ActiveHeight,
+			Proposer:      from,
+			ProposeNumber: height,
+			Signers:       make([]common.Address, 0),
+			Signatures:    make([]common.Signature, 0),
+			Status:        mc.VersionProposalPending,
+		})
+	}
+	proposal := &proposals[index]
+	proposal.Signers = append(proposal.Signers, signer)
+	proposal.Signatures = append(proposal.Signatures, tx.Signature)
+	target := mtxdpos.NewMtxDPOS(simpleMode).SuperNodeTarget(len(accounts))
+	if proposal.Status == mc.VersionProposalPending && len(proposal.Signers) >= target {
+		proposal.Status = mc.VersionProposalApproved
+		proposal.ApproveNumber = height
+	}
+	info.Proposals = proposals
+	if err := matrixstate.SetVersionProposals(st, info); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// isVersionProposalDone reports whether the proposal can be dropped from the
+// matrix state at the given height and chain version: it either missed its
+// activation height without collecting enough signatures, or it was approved
+// and the chain already runs its version or never switched to it within
+// VersionProposalExpireBlocks after the activation height.
+func isVersionProposalDone(proposal *mc.VersionProposal, height uint64, version string) bool {
+	if proposal.Status == mc.VersionProposalApproved {
+		return manparams.VersionCmp(version, proposal.Version) >= 0 || proposal.ActiveHeight+VersionProposalExpireBlocks <= height
+	}
+	return proposal.ActiveHeight <= height
+}
+
+func containsAccount(accounts []common.Address, addr common.Address) bool {
+	for _, account := range accounts {
+		if account == addr {
+			return true
+		}
+	}
+	return false
+}
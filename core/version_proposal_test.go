@@ -0,0 +1,161 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+)
+
+const testProposalVersion = "1.0.0.2"
+
+// newVersionProposalState returns a Beta state with the given version super accounts.
+func newVersionProposalState(t *testing.T, keys []*ecdsa.PrivateKey) *state.StateDB {
+	statedb := newTestState()
+	matrixstate.SetVersionInfo(statedb, manparams.VersionBeta)
+	accounts := make([]common.Address, 0, len(keys))
+	for _, key := range keys {
+		accounts = append(accounts, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	if err := matrixstate.SetVersionSuperAccounts(statedb, accounts); err != nil {
+		t.Fatalf("failed to set version super accounts: %v", err)
+	}
+	return statedb
+}
+
+// versionProposalData returns the data of a proposal of version at activeHeight
+// signed with key.
+func versionProposalData(t *testing.T, version string, activeHeight uint64, key *ecdsa.PrivateKey) []byte {
+	sig, err := crypto.Sign(common.BytesToHash([]byte(version)).Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to sign version: %v", err)
+	}
+	data, err := json.Marshal(&common.VersionProposalTx{Version: version, ActiveHeight: activeHeight, Signature: common.BytesToSignature(sig)})
+	if err != nil {
+		t.Fatalf("failed to encode proposal: %v", err)
+	}
+	return data
+}
+
+func newVersionKeys(n int) []*ecdsa.PrivateKey {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	return keys
+}
+
+func TestCheckVersionProposal(t *testing.T) {
+	keys := newVersionKeys(2)
+	outsider, _ := crypto.GenerateKey()
+	super := crypto.PubkeyToAddress(keys[0].PublicKey)
+	statedb := newVersionProposalState(t, keys)
+
+	long := string(make([]byte, maxVersionLength+1))
+	tests := []struct {
+		from common.Address
+		data []byte
+		err  error
+	}{
+		{super, versionProposalData(t, testProposalVersion, 200, keys[0]), nil},
+		{super, versionProposalData(t, testProposalVersion, 200, keys[1]), nil}, // 可代其他版本超级账户提交签名
+		{super, []byte("invalid"), ErrVersionProposal},
+		{super, versionProposalData(t, "", 200, keys[0]), ErrVersionProposal},
+		{super, versionProposalData(t, long, 200, keys[0]), ErrVersionProposal},
+		{super, versionProposalData(t, testProposalVersion, 100, keys[0]), ErrVersionProposal}, // 生效高度未超过当前高度
+		{crypto.PubkeyToAddress(outsider.PublicKey), versionProposalData(t, testProposalVersion, 200, keys[0]), ErrVersionProposalSigner},
+		{super, versionProposalData(t, testProposalVersion, 200, outsider), ErrVersionProposalSigner},
+	}
+	for i, test := range tests {
+		if _, _, _, err := checkVersionProposal(statedb, test.from, test.data, 100); err != test.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.err)
+		}
+	}
+	// 签名的版本号与提案不一致
+	data := versionProposalData(t, testProposalVersion, 200, keys[0])
+	tx := new(common.VersionProposalTx)
+	json.Unmarshal(data, tx)
+	tx.Version = "1.0.0.3"
+	data, _ = json.Marshal(tx)
+	if _, _, _, err := checkVersionProposal(statedb, super, data, 100); err != ErrVersionProposalSigner {
+		t.Errorf("signature of another version: error mismatch: have %v, want %v", err, ErrVersionProposalSigner)
+	}
+
+	// Beta版本之前不支持
+	matrixstate.SetVersionInfo(statedb, manparams.VersionAlpha)
+	if _, _, _, err := checkVersionProposal(statedb, super, versionProposalData(t, testProposalVersion, 200, keys[0]), 100); err != ErrTXUnknownType {
+		t.Errorf("alpha state: error mismatch: have %v, want %v", err, ErrTXUnknownType)
+	}
+}
+
+func TestApplyVersionProposal(t *testing.T) {
+	keys := newVersionKeys(4) // 4个版本超级账户需要3个签名
+	statedb := newVersionProposalState(t, keys)
+	from := crypto.PubkeyToAddress(keys[0].PublicKey)
+
+	for i, key := range keys[:2] {
+		proposal, err := applyVersionProposal(statedb, false, from, versionProposalData(t, testProposalVersion, 200, key), uint64(100+i))
+		if err != nil {
+			t.Fatalf("signature %d: failed to apply: %v", i, err)
+		}
+		if proposal.Status != mc.VersionProposalPending || len(proposal.Signers) != i+1 || proposal.ProposeNumber != 100 || proposal.Proposer != from {
+			t.Fatalf("signature %d: proposal mismatch: %+v", i, proposal)
+		}
+	}
+	// 重复签名, 同一版本不同生效高度
+	if _, err := applyVersionProposal(statedb, false, from, versionProposalData(t, testProposalVersion, 200, keys[1]), 102); err != ErrVersionProposalSigned {
+		t.Errorf("repeated signature: error mismatch: have %v, want %v", err, ErrVersionProposalSigned)
+	}
+	if _, err := applyVersionProposal(statedb, false, from, versionProposalData(t, testProposalVersion, 300, keys[2]), 102); err != ErrVersionProposal {
+		t.Errorf("second activation height: error mismatch: have %v, want %v", err, ErrVersionProposal)
+	}
+	// 签名达到门限后通过
+	proposal, err := applyVersionProposal(statedb, false, from, versionProposalData(t, testProposalVersion, 200, keys[2]), 110)
+	if err != nil {
+		t.Fatalf("failed to apply: %v", err)
+	}
+	if proposal.Status != mc.VersionProposalApproved || proposal.ApproveNumber != 110 || len(proposal.Signatures) != 3 {
+		t.Fatalf("proposal not approved: %+v", proposal)
+	}
+	info, _ := matrixstate.GetVersionProposals(statedb)
+	if len(info.Proposals) != 1 || info.Proposals[0].Status != mc.VersionProposalApproved {
+		t.Fatalf("stored proposals mismatch: %+v", info.Proposals)
+	}
+}
+
+func TestApplyVersionProposalPrune(t *testing.T) {
+	keys := newVersionKeys(4)
+	statedb := newVersionProposalState(t, keys)
+	from := crypto.PubkeyToAddress(keys[0].PublicKey)
+
+	matrixstate.SetVersionProposals(statedb, &mc.VersionProposalInfo{Proposals: []mc.VersionProposal{
+		{Version: manparams.VersionBeta, ActiveHeight: 50, Status: mc.VersionProposalApproved}, // 链已切换到该版本
+		{Version: "1.0.0.3", ActiveHeight: 150, Status: mc.VersionProposalApproved},            // 已过生效高度, 链尚未切换
+		{Version: "1.0.0.6", ActiveHeight: 100, Status: mc.VersionProposalApproved},            // 链长期未切换, 已失效
+		{Version: "1.0.0.4", ActiveHeight: 150, Status: mc.VersionProposalPending},             // 已过期
+		{Version: "1.0.0.5", ActiveHeight: 300 + VersionProposalExpireBlocks, Status: mc.VersionProposalPending},
+	}})
+	if _, err := applyVersionProposal(statedb, false, from, versionProposalData(t, testProposalVersion, 250+VersionProposalExpireBlocks, keys[0]), 100+VersionProposalExpireBlocks); err != nil {
+		t.Fatalf("failed to apply: %v", err)
+	}
+	info, _ := matrixstate.GetVersionProposals(statedb)
+	want := []string{"1.0.0.3", "1.0.0.5", testProposalVersion}
+	if len(info.Proposals) != len(want) {
+		t.Fatalf("proposal count mismatch: have %+v, want %v", info.Proposals, want)
+	}
+	for i, version := range want {
+		if info.Proposals[i].Version != version {
+			t.Errorf("proposal %d: version mismatch: have %s, want %s", i, info.Proposals[i].Version, version)
+		}
+	}
+}
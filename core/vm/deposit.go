@@ -36,6 +36,8 @@ var (
 	errInterestOverflow  = errors.New("interest id overflow")
 	errInterestEmpty     = errors.New("interest is empty")
 	errInterestAddrEmpty = errors.New("interest addr is empty")
+	errSignRotation      = errors.New("sign address rotation is invalid")
+	errRotationPending   = errors.New("sign address rotation is pending")
 
 	depositDef = ` [{"constant": true,"inputs": [],"name": "getDepositList","outputs": [{"name": "","type": "address[]"}],"payable": false,"stateMutability": "view","type": "function"},
 			{"constant": true,"inputs": [{"name": "addr","type": "address"}],"name": "getDepositInfo","outputs": [{"name": "","type": "uint256"},{"name": "","type": "address"},{"name": "","type": "uint256"}, {"name": "","type": "uint256"}],"payable": false,"stateMutability": "view","type": "function"},
@@ -295,6 +297,12 @@ func (md *MatrixDeposit) setAddress(contract *Contract, stateDB StateDB, address
 	if addressA0 != emptyHash {
 		return errExist
 	}
+	rotationYKey := append(address[:], 'R', 'Y')
+	if owner := stateDB.GetState(contract.Address(), common.BytesToHash(rotationYKey)); owner != emptyHash && owner != contract.CallerAddress.Hash() {
+		return errExist
+	}
+	// 重新设置签名账户时取消未完成的轮换
+	md.clearSignRotation(contract, stateDB, contract.CallerAddress)
 
 	stateDB.SetState(contract.Address(), common.BytesToHash(nodeYKey), contract.CallerAddress.Hash())
 
@@ -314,6 +322,7 @@ func (md *MatrixDeposit) clearAddress(contract *Contract, stateDB StateDB, addre
 		return errClear
 	}
 
+	md.clearSignRotation(contract, stateDB, contract.CallerAddress)
 	signAddr := md.getAddress(contract, stateDB, contract.CallerAddress)
 	// signature address : []
 	nodeYKey := append(signAddr[:], 'N', 'Y')
@@ -347,6 +356,31 @@ type DepositDetail struct {
 	WithdrawH   *big.Int
 	OnlineTime  *big.Int
 	Role        *big.Int
+	Rotation    *SignRotation //未完成的签名账户轮换
+}
+
+//根据高度获取生效的签名账户
+func (detail *DepositDetail) SignAddressAt(height uint64) common.Address {
+	if detail.Rotation != nil {
+		return detail.Rotation.SignAddressAt(height)
+	}
+	return detail.SignAddress
+}
+
+//判断该签名账户在此高度是否被认可,轮换交接期内新旧签名账户均被认可
+func (detail *DepositDetail) IsSignAddressAt(addr common.Address, height uint64) bool {
+	if detail.Rotation == nil {
+		return addr == detail.SignAddress
+	}
+	return detail.Rotation.IsSignAddressAt(addr, height)
+}
+
+//判断该签名账户是否属于此抵押账户(当前签名账户或轮换中的新旧签名账户)
+func (detail *DepositDetail) HasSignAddress(addr common.Address) bool {
+	if addr == detail.SignAddress {
+		return true
+	}
+	return detail.Rotation != nil && (addr == detail.Rotation.NewSignAddress || addr == detail.Rotation.OldSignAddress)
 }
 
 func (md *MatrixDeposit) getValidatorDepositList(contract *Contract, stateDB StateDB) []DepositDetail {
@@ -451,6 +485,7 @@ func (md *MatrixDeposit) getDepositDetail(addr common.Address, contract *Contrac
 	detail.WithdrawH = md.getWithdrawHeight(contract, stateDB, addr)
 	detail.OnlineTime = md.GetOnlineTime(contract, stateDB, addr)
 	detail.Role = md.getDepositRole(contract, stateDB, addr)
+	detail.Rotation = md.GetSignRotation(contract, stateDB, addr)
 	return &detail, nil
 }
 
@@ -740,6 +775,221 @@ func (md *MatrixDeposit) AddDeposit(contract *Contract, stateDB StateDB, address
 	return md.ResetInterest(contract, stateDB, address)
 }
 
+//签名账户(A1)轮换计划,新A1从生效高度开始被认可,交接期结束前旧A1仍被认可
+type SignRotation struct {
+	OldSignAddress common.Address
+	NewSignAddress common.Address
+	EffectHeight   uint64 //新A1开始用于签名的高度,到达该高度时新A1写入签名账户映射
+	HandoverEnd    uint64 //旧A1失效的高度
+}
+
+func (r *SignRotation) IsEffective(height uint64) bool {
+	return height >= r.EffectHeight
+}
+
+func (r *SignRotation) IsRetired(height uint64) bool {
+	return height >= r.HandoverEnd
+}
+
+//根据高度获取生效的签名账户
+func (r *SignRotation) SignAddressAt(height uint64) common.Address {
+	if r.IsEffective(height) {
+		return r.NewSignAddress
+	}
+	return r.OldSignAddress
+}
+
+//判断签名账户在此高度是否被认可,新A1生效前只认可旧A1,交接期内新旧A1均被认可
+func (r *SignRotation) IsSignAddressAt(addr common.Address, height uint64) bool {
+	if addr == r.NewSignAddress {
+		return r.IsEffective(height)
+	}
+	return addr == r.OldSignAddress && !r.IsRetired(height)
+}
+
+func (md *MatrixDeposit) GetSignRotation(contract *Contract, stateDB StateDB, depositAccount common.Address) *SignRotation {
+	newKey := append(depositAccount[:], 'R', 'A')
+	newAddr := stateDB.GetState(contract.Address(), common.BytesToHash(newKey))
+	if newAddr == emptyHash {
+		return nil
+	}
+	oldKey := append(depositAccount[:], 'R', 'O')
+	effectKey := append(depositAccount[:], 'R', 'E')
+	endKey := append(depositAccount[:], 'R', 'W')
+	return &SignRotation{
+		OldSignAddress: common.BytesToAddress(stateDB.GetState(contract.Address(), common.BytesToHash(oldKey)).Bytes()),
+		NewSignAddress: common.BytesToAddress(newAddr.Bytes()),
+		EffectHeight:   stateDB.GetState(contract.Address(), common.BytesToHash(effectKey)).Big().Uint64(),
+		HandoverEnd:    stateDB.GetState(contract.Address(), common.BytesToHash(endKey)).Big().Uint64(),
+	}
+}
+
+// SetSignRotation 登记抵押账户的签名账户轮换,height为当前高度
+func (md *MatrixDeposit) SetSignRotation(contract *Contract, stateDB StateDB, depositAccount common.Address, newSignAddress common.Address, height uint64, effectHeight uint64, handoverEnd uint64) error {
+	deposit := md.getDeposit(contract, stateDB, depositAccount)
+	if deposit == nil || deposit.Sign() == 0 {
+		return errDeposit
+	}
+	if withdraw := md.getWithdrawHeight(contract, stateDB, depositAccount); withdraw.Sign() > 0 {
+		return errDeposit
+	}
+	if effectHeight <= height || handoverEnd < effectHeight {
+		return errSignRotation
+	}
+	// 上一次轮换交接完成后才能再次轮换
+	if rotation := md.GetSignRotation(contract, stateDB, depositAccount); rotation != nil {
+		if !rotation.IsRetired(height) {
+			return errRotationPending
+		}
+		md.commitSignRotation(contract, stateDB, depositAccount, rotation)
+		md.clearSignRotation(contract, stateDB, depositAccount)
+	}
+	oldSignAddress := md.getAddress(contract, stateDB, depositAccount)
+	if (oldSignAddress == common.Address{}) || (newSignAddress == common.Address{}) || newSignAddress == oldSignAddress || newSignAddress == depositAccount {
+		return errSignRotation
+	}
+	nodeYKey := append(newSignAddress[:], 'N', 'Y')
+	rotationYKey := append(newSignAddress[:], 'R', 'Y')
+	if stateDB.GetState(contract.Address(), common.BytesToHash(nodeYKey)) != emptyHash || stateDB.GetState(contract.Address(), common.BytesToHash(rotationYKey)) != emptyHash {
+		return errExist
+	}
+
+	stateDB.SetState(contract.Address(), common.BytesToHash(rotationYKey), depositAccount.Hash())
+	newKey := append(depositAccount[:], 'R', 'A')
+	stateDB.SetState(contract.Address(), common.BytesToHash(newKey), newSignAddress.Hash())
+	oldKey := append(depositAccount[:], 'R', 'O')
+	stateDB.SetState(contract.Address(), common.BytesToHash(oldKey), oldSignAddress.Hash())
+	effectKey := append(depositAccount[:], 'R', 'E')
+	stateDB.SetState(contract.Address(), common.BytesToHash(effectKey), common.BigToHash(new(big.Int).SetUint64(effectHeight)))
+	endKey := append(depositAccount[:], 'R', 'W')
+	stateDB.SetState(contract.Address(), common.BytesToHash(endKey), common.BigToHash(new(big.Int).SetUint64(handoverEnd)))
+	md.queueSignRotation(contract, stateDB, depositAccount, effectHeight)
+	return nil
+}
+
+// 生效高度的轮换队列,key为'RQ'+高度(+序号)
+func signRotationQueueKey(height uint64, index ...uint64) common.Hash {
+	key := make([]byte, 2+8*(1+len(index)))
+	key[0], key[1] = 'R', 'Q'
+	binary.BigEndian.PutUint64(key[2:], height)
+	for i, idx := range index {
+		binary.BigEndian.PutUint64(key[10+8*i:], idx)
+	}
+	return common.BytesToHash(key)
+}
+
+func (md *MatrixDeposit) queueSignRotation(contract *Contract, stateDB StateDB, depositAccount common.Address, effectHeight uint64) {
+	countKey := signRotationQueueKey(effectHeight)
+	count := stateDB.GetState(contract.Address(), countKey).Big().Uint64()
+	stateDB.SetState(contract.Address(), signRotationQueueKey(effectHeight, count), depositAccount.Hash())
+	stateDB.SetState(contract.Address(), countKey, common.BigToHash(new(big.Int).SetUint64(count+1)))
+}
+
+// CommitSignRotations 在生效高度把轮换的新A1写入签名账户映射,每个区块执行交易前调用
+func (md *MatrixDeposit) CommitSignRotations(contract *Contract, stateDB StateDB, height uint64) {
+	countKey := signRotationQueueKey(height)
+	count := stateDB.GetState(contract.Address(), countKey).Big().Uint64()
+	if count == 0 {
+		return
+	}
+	for i := uint64(0); i < count; i++ {
+		key := signRotationQueueKey(height, i)
+		depositAccount := common.BytesToAddress(stateDB.GetState(contract.Address(), key).Bytes())
+		stateDB.SetState(contract.Address(), key, common.Hash{})
+		rotation := md.GetSignRotation(contract, stateDB, depositAccount)
+		if rotation == nil || rotation.EffectHeight != height {
+			//轮换已被取消
+			continue
+		}
+		md.commitSignRotation(contract, stateDB, depositAccount, rotation)
+	}
+	stateDB.SetState(contract.Address(), countKey, common.Hash{})
+}
+
+// commitSignRotation 把生效的新A1写入签名账户映射,交接期内旧A1通过轮换记录找到抵押账户
+func (md *MatrixDeposit) commitSignRotation(contract *Contract, stateDB StateDB, depositAccount common.Address, rotation *SignRotation) {
+	if md.getAddress(contract, stateDB, depositAccount) == rotation.NewSignAddress {
+		return
+	}
+	oldNodeYKey := append(rotation.OldSignAddress[:], 'N', 'Y')
+	stateDB.SetState(contract.Address(), common.BytesToHash(oldNodeYKey), common.Hash{})
+	oldRotationYKey := append(rotation.OldSignAddress[:], 'R', 'Y')
+	stateDB.SetState(contract.Address(), common.BytesToHash(oldRotationYKey), depositAccount.Hash())
+	rotationYKey := append(rotation.NewSignAddress[:], 'R', 'Y')
+	stateDB.SetState(contract.Address(), common.BytesToHash(rotationYKey), common.Hash{})
+
+	nodeYKey := append(rotation.NewSignAddress[:], 'N', 'Y')
+	stateDB.SetState(contract.Address(), common.BytesToHash(nodeYKey), depositAccount.Hash())
+	nodeXKey := append(depositAccount[:], 'N', 'X')
+	stateDB.SetState(contract.Address(), common.BytesToHash(nodeXKey), rotation.NewSignAddress.Hash())
+}
+
+func (md *MatrixDeposit) clearSignRotation(contract *Contract, stateDB StateDB, depositAccount common.Address) {
+	rotation := md.GetSignRotation(contract, stateDB, depositAccount)
+	if rotation == nil {
+		return
+	}
+	for _, addr := range []common.Address{rotation.NewSignAddress, rotation.OldSignAddress} {
+		rotationYKey := append(addr[:], 'R', 'Y')
+		if stateDB.GetState(contract.Address(), common.BytesToHash(rotationYKey)) == depositAccount.Hash() {
+			stateDB.SetState(contract.Address(), common.BytesToHash(rotationYKey), common.Hash{})
+		}
+	}
+	for _, suffix := range [][]byte{{'R', 'A'}, {'R', 'O'}, {'R', 'E'}, {'R', 'W'}} {
+		key := append(depositAccount[:], suffix...)
+		stateDB.SetState(contract.Address(), common.BytesToHash(key), common.Hash{})
+	}
+}
+
+// GetAuthAccountAtHeight 根据高度获取抵押账户生效的签名账户
+func (md *MatrixDeposit) GetAuthAccountAtHeight(contract *Contract, stateDB StateDB, depositAccount common.Address, height uint64) common.Address {
+	if rotation := md.GetSignRotation(contract, stateDB, depositAccount); rotation != nil {
+		return rotation.SignAddressAt(height)
+	}
+	return md.getAddress(contract, stateDB, depositAccount)
+}
+
+// GetAuthAccountsAtHeight 根据高度获取抵押账户被认可的签名账户,生效的A1在前,交接期内附带旧A1
+func (md *MatrixDeposit) GetAuthAccountsAtHeight(contract *Contract, stateDB StateDB, depositAccount common.Address, height uint64) []common.Address {
+	rotation := md.GetSignRotation(contract, stateDB, depositAccount)
+	if rotation == nil {
+		if signAddr := md.getAddress(contract, stateDB, depositAccount); (signAddr != common.Address{}) {
+			return []common.Address{signAddr}
+		}
+		return nil
+	}
+	accounts := []common.Address{rotation.SignAddressAt(height)}
+	if rotation.IsEffective(height) && !rotation.IsRetired(height) {
+		accounts = append(accounts, rotation.OldSignAddress)
+	}
+	return accounts
+}
+
+// GetDepositAccountAtHeight 根据高度获取签名账户对应的抵押账户,只有此高度被认可的签名账户能找到抵押账户
+func (md *MatrixDeposit) GetDepositAccountAtHeight(contract *Contract, stateDB StateDB, authAccount common.Address, height uint64) common.Address {
+	depositAccount := md.GetDepositAccount(contract, stateDB, authAccount)
+	mapped := depositAccount != common.Address{}
+	if !mapped {
+		rotationYKey := append(authAccount[:], 'R', 'Y')
+		owner := stateDB.GetState(contract.Address(), common.BytesToHash(rotationYKey))
+		if owner == emptyHash {
+			return common.Address{}
+		}
+		depositAccount = common.BytesToAddress(owner.Bytes())
+	}
+	rotation := md.GetSignRotation(contract, stateDB, depositAccount)
+	if rotation == nil {
+		if !mapped {
+			return common.Address{}
+		}
+		return depositAccount
+	}
+	if !rotation.IsSignAddressAt(authAccount, height) {
+		return common.Address{}
+	}
+	return depositAccount
+}
+
 func (md *MatrixDeposit) GetDepositAccount(contract *Contract, stateDB StateDB, authAccount common.Address) common.Address {
 	signAddrKey := append(authAccount[:], 'N', 'Y')
 	signAddr := stateDB.GetState(contract.Address(), common.BytesToHash(signAddrKey))
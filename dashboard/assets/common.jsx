@@ -24,6 +24,12 @@ const menuSkeletons: Array<{|id: string, menu: ProvidedMenuProp|}> = [
 			title: 'Home',
 			icon:  'home',
 		},
+	}, {
+		id:   'matrix',
+		menu: {
+			title: 'Matrix',
+			icon:  'cubes',
+		},
 	}, {
 		id:   'chain',
 		menu: {
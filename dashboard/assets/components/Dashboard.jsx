@@ -98,6 +98,15 @@ const defaultContent: Content = {
 	logs:    {
 		log: [],
 	},
+	matrix: {
+		node:    null,
+		leader:  null,
+		votes:   [],
+		online:  [],
+		deposit: null,
+		rewards: [],
+		sync:    null,
+	},
 };
 
 // updaters contains the state updater functions for each path of the state.
@@ -125,6 +134,15 @@ const updaters = {
 	logs: {
 		log: appender(200),
 	},
+	matrix: {
+		node:    replacer,
+		leader:  replacer,
+		votes:   appender(200),
+		online:  appender(100),
+		deposit: replacer,
+		rewards: appender(100),
+		sync:    replacer,
+	},
 };
 
 // styles contains the constant styles of the component.
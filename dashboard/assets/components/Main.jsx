@@ -22,6 +22,7 @@ import withStyles from 'material-ui/styles/withStyles';
 
 import {MENU} from '../common';
 import Footer from './Footer';
+import Matrix from './Matrix';
 import type {Content} from '../types/content';
 
 // styles contains the constant styles of the component.
@@ -68,6 +69,9 @@ class Main extends Component<Props> {
 		case MENU.get('system').id:
 			children = <div>Work in progress.</div>;
 			break;
+		case MENU.get('matrix').id:
+			children = <Matrix matrix={content.matrix} shouldUpdate={shouldUpdate} />;
+			break;
 		case MENU.get('logs').id:
 			children = <div>{content.logs.log.map((log, index) => <div key={index}>{log}</div>)}</div>;
 		}
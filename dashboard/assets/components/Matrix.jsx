@@ -0,0 +1,187 @@
+// @flow
+
+// Copyright 2017 The go-matrix Authors
+// This file is part of the go-matrix library.
+//
+// The go-matrix library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-matrix library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-matrix library. If not, see <http://www.gnu.org/licenses/>.
+
+import React, {Component} from 'react';
+
+import Grid from 'material-ui/Grid';
+import Typography from 'material-ui/Typography';
+import {ResponsiveContainer, BarChart, Bar, Tooltip} from 'recharts';
+
+import CustomTooltip from './CustomTooltip';
+import type {Matrix as MatrixContent} from '../types/content';
+
+// styles contains the constant styles of the component.
+const styles = {
+	section: {
+		marginBottom: 24,
+	},
+	table: {
+		width:          '100%',
+		borderCollapse: 'collapse',
+	},
+	cell: {
+		padding:    '2px 8px',
+		textAlign:  'left',
+		whiteSpace: 'nowrap',
+	},
+	chart: {
+		height: 120,
+	},
+};
+
+// row renders a label and a value of a status table.
+const row = (label: string, value: any) => (
+	<tr key={label}>
+		<td style={styles.cell}>{label}</td>
+		<td style={styles.cell}>{value}</td>
+	</tr>
+);
+
+// votePlotter renders the tooltip of the vote chart.
+const votePlotter = (payload: number) => (
+	<Typography type='caption' color='inherit'>{payload} signatures</Typography>
+);
+
+// time formats a unix timestamp.
+const time = (unix: number) => (unix ? new Date(unix * 1000).toLocaleTimeString() : '-');
+
+export type Props = {
+	matrix: MatrixContent,
+	shouldUpdate: Object,
+};
+
+// Matrix renders the consensus state of the node: its role in the topology, the
+// progress of the leader election, the POS votes and online consensus results of
+// the recent blocks, its deposit bookkeeping, rewards and the sync status.
+class Matrix extends Component<Props> {
+	shouldComponentUpdate(nextProps) {
+		return typeof nextProps.shouldUpdate.matrix !== 'undefined';
+	}
+
+	node = () => {
+		const {node} = this.props.matrix;
+		if (!node) {
+			return <Typography>Waiting for the node status.</Typography>;
+		}
+		return (
+			<table style={styles.table}><tbody>
+				{row('Deposit address', node.address)}
+				{row('Sign address', node.signAddress)}
+				{row('Role', node.role)}
+				{row('VIP level', node.level)}
+				{row('Topology', node.inTopology ? `position ${node.position}` : 'not in topology')}
+				{row('Block', node.number)}
+			</tbody></table>
+		);
+	};
+
+	leader = () => {
+		const {leader} = this.props.matrix;
+		if (!leader) {
+			return <Typography>No leader change seen yet.</Typography>;
+		}
+		return (
+			<table style={styles.table}><tbody>
+				{row('Block', leader.number)}
+				{row('Leader', leader.leader)}
+				{row('Next leader', leader.nextLeader)}
+				{row('Consensus turn', leader.consensusTurn)}
+				{row('Reelect turn', leader.reelectTurn)}
+				{row('Consensus state', leader.consensusState ? 'done' : 'in progress')}
+				{row('Turn', `${time(leader.turnBeginTime)} - ${time(leader.turnEndTime)}`)}
+			</tbody></table>
+		);
+	};
+
+	deposit = () => {
+		const {deposit, sync} = this.props.matrix;
+		return (
+			<table style={styles.table}><tbody>
+				{row('Uptime', deposit ? deposit.uptime : '-')}
+				{row('Pending interest', deposit ? deposit.interest : '-')}
+				{row('Slash', deposit ? deposit.slash : '-')}
+				{row('Syncing', sync ? `${sync.syncing ? 'yes' : 'no'} (${sync.currentBlock}/${sync.highestBlock})` : '-')}
+				{row('IPFS sync', sync && sync.ipfsMode ? 'enabled' : 'disabled')}
+			</tbody></table>
+		);
+	};
+
+	online = () => (
+		<table style={styles.table}><tbody>
+			{this.props.matrix.online.slice().reverse().map((result, index) => (
+				<tr key={index}>
+					<td style={styles.cell}>{result.number}</td>
+					<td style={styles.cell}>{result.node}</td>
+					<td style={styles.cell}>{result.online ? 'online' : 'offline'}</td>
+				</tr>
+			))}
+		</tbody></table>
+	);
+
+	rewards = () => (
+		<table style={styles.table}><tbody>
+			{this.props.matrix.rewards.slice().reverse().map(reward => (
+				<tr key={`${reward.hash}${reward.type}`}>
+					<td style={styles.cell}>{reward.number}</td>
+					<td style={styles.cell}>{reward.type}</td>
+					<td style={styles.cell}>{reward.amount}</td>
+					<td style={styles.cell}>{reward.currency}</td>
+					<td style={styles.cell}>{reward.hash}</td>
+				</tr>
+			))}
+		</tbody></table>
+	);
+
+	render() {
+		return (
+			<Grid container spacing={24}>
+				<Grid item xs={12} md={6} style={styles.section}>
+					<Typography type='title'>Node</Typography>
+					{this.node()}
+				</Grid>
+				<Grid item xs={12} md={6} style={styles.section}>
+					<Typography type='title'>Leader</Typography>
+					{this.leader()}
+				</Grid>
+				<Grid item xs={12} style={styles.section}>
+					<Typography type='title'>POS votes</Typography>
+					<ResponsiveContainer width='100%' height={styles.chart.height}>
+						<BarChart data={this.props.matrix.votes}>
+							<Tooltip cursor={false} content={<CustomTooltip tooltip={votePlotter} />} />
+							<Bar isAnimationActive={false} dataKey='value' fill='#8884d8' />
+						</BarChart>
+					</ResponsiveContainer>
+				</Grid>
+				<Grid item xs={12} md={6} style={styles.section}>
+					<Typography type='title'>Deposit</Typography>
+					{this.deposit()}
+				</Grid>
+				<Grid item xs={12} md={6} style={styles.section}>
+					<Typography type='title'>Online consensus</Typography>
+					{this.online()}
+				</Grid>
+				<Grid item xs={12} style={styles.section}>
+					<Typography type='title'>Rewards</Typography>
+					{this.rewards()}
+				</Grid>
+			</Grid>
+		);
+	}
+}
+
+export default Matrix;
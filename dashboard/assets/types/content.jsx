@@ -24,6 +24,7 @@ export type Content = {
 	network: Network,
 	system: System,
 	logs: Logs,
+	matrix: Matrix,
 };
 
 export type ChartEntries = Array<ChartEntry>;
@@ -68,3 +69,62 @@ export type System = {
 export type Logs = {
 	log: Array<string>,
 };
+
+export type Matrix = {
+	node: ?NodeStatus,
+	leader: ?LeaderStatus,
+	votes: ChartEntries,
+	online: Array<OnlineResult>,
+	deposit: ?DepositStatus,
+	rewards: Array<RewardEntry>,
+	sync: ?SyncStatus,
+};
+
+export type NodeStatus = {
+	address: string,
+	signAddress: string,
+	role: string,
+	level: number,
+	inTopology: boolean,
+	position: number,
+	number: number,
+};
+
+export type LeaderStatus = {
+	number: number,
+	leader: string,
+	nextLeader: string,
+	consensusTurn: number,
+	reelectTurn: number,
+	consensusState: boolean,
+	turnBeginTime: number,
+	turnEndTime: number,
+};
+
+export type OnlineResult = {
+	number: number,
+	node: string,
+	online: boolean,
+};
+
+export type DepositStatus = {
+	number: number,
+	uptime: string,
+	interest: string,
+	slash: string,
+};
+
+export type RewardEntry = {
+	number: number,
+	hash: string,
+	type: string,
+	currency: string,
+	amount: string,
+};
+
+export type SyncStatus = {
+	syncing: boolean,
+	ipfsMode: boolean,
+	currentBlock: number,
+	highestBlock: number,
+};
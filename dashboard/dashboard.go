@@ -22,6 +22,7 @@ import (
 
 	"github.com/elastic/gosigar"
 	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/man"
 	"github.com/MatrixAINetwork/go-matrix/metrics"
 	"github.com/MatrixAINetwork/go-matrix/p2p"
 	"github.com/MatrixAINetwork/go-matrix/params"
@@ -49,7 +50,9 @@ type Dashboard struct {
 	listener net.Listener
 	conns    map[uint32]*client // Currently live websocket connections
 	charts   *SystemMessage
+	matrix   *matrixState // MATRIX consensus state of the node
 	commit   string
+	man      *man.Matrix  // Full Matrix service, nil on light nodes
 	lock     sync.RWMutex // Lock protecting the dashboard's internals
 
 	quit chan chan error // Channel used for graceful exit
@@ -63,8 +66,9 @@ type client struct {
 	logger log.Logger      // Logger for the particular live websocket connection
 }
 
-// New creates a new dashboard instance with the given configuration.
-func New(config *Config, commit string) (*Dashboard, error) {
+// New creates a new dashboard instance with the given configuration. The
+// consensus state is only shown if the full Matrix service is given.
+func New(config *Config, commit string, manServ *man.Matrix) (*Dashboard, error) {
 	now := time.Now()
 	db := &Dashboard{
 		conns:  make(map[uint32]*client),
@@ -80,7 +84,9 @@ func New(config *Config, commit string) (*Dashboard, error) {
 			DiskRead:       emptyChartEntries(now, diskReadSampleLimit, config.Refresh),
 			DiskWrite:      emptyChartEntries(now, diskWriteSampleLimit, config.Refresh),
 		},
+		matrix: &matrixState{},
 		commit: commit,
+		man:    manServ,
 	}
 	return db, nil
 }
@@ -106,9 +112,10 @@ func (db *Dashboard) APIs() []rpc.API { return nil }
 func (db *Dashboard) Start(server *p2p.Server) error {
 	log.Info("Starting dashboard")
 
-	db.wg.Add(2)
+	db.wg.Add(3)
 	go db.collectData()
-	go db.collectLogs() // In case of removing this line change 2 back to 1 in wg.Add.
+	go db.collectLogs() // In case of removing this line change 3 back to 2 in wg.Add.
+	go db.collectMatrix()
 
 	http.HandleFunc("/", db.webHandler)
 	http.Handle("/api", websocket.Handler(db.apiHandler))
@@ -133,7 +140,7 @@ func (db *Dashboard) Stop() error {
 	}
 	// Close the collectors.
 	errc := make(chan error, 1)
-	for i := 0; i < 2; i++ {
+	for i := 0; i < 3; i++ {
 		db.quit <- errc
 		if err := <-errc; err != nil {
 			errs = append(errs, err)
@@ -211,6 +218,9 @@ func (db *Dashboard) apiHandler(conn *websocket.Conn) {
 		versionMeta = fmt.Sprintf(" (%s)", params.VersionMeta)
 	}
 	// Send the past data.
+	db.lock.RLock()
+	matrix := db.matrix.message()
+	db.lock.RUnlock()
 	client.msg <- Message{
 		General: &GeneralMessage{
 			Version: fmt.Sprintf("v%d.%d.%d%s", params.VersionMajor, params.VersionMinor, params.VersionPatch, versionMeta),
@@ -226,6 +236,7 @@ func (db *Dashboard) apiHandler(conn *websocket.Conn) {
 			DiskRead:       db.charts.DiskRead,
 			DiskWrite:      db.charts.DiskWrite,
 		},
+		Matrix: matrix,
 	}
 	// Start tracking the connection and drop at connection loss.
 	db.lock.Lock()
@@ -0,0 +1,280 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package dashboard
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/man"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+const (
+	voteSampleLimit   = 200 // Maximum number of POS vote samples
+	onlineResultLimit = 100 // Maximum number of online consensus results kept
+	rewardEntryLimit  = 100 // Maximum number of reward entries kept
+)
+
+// matrixState is the MATRIX specific state shown by the dashboard, sent to the
+// new connections in full.
+type matrixState struct {
+	node    *NodeStatus
+	leader  *LeaderStatus
+	votes   ChartEntries
+	online  []*OnlineResult
+	deposit *DepositStatus
+	rewards []*RewardEntry
+	sync    *SyncStatus
+}
+
+// message returns the full state as a dashboard message.
+func (s *matrixState) message() *MatrixMessage {
+	return &MatrixMessage{
+		Node:    s.node,
+		Leader:  s.leader,
+		Votes:   s.votes,
+		Online:  s.online,
+		Deposit: s.deposit,
+		Rewards: s.rewards,
+		Sync:    s.sync,
+	}
+}
+
+// collectMatrix follows the chain and the leader election of the MATRIX service,
+// sending the consensus state of the node to the active dashboards.
+func (db *Dashboard) collectMatrix() {
+	defer db.wg.Done()
+
+	// 轻节点没有man服务, 只等待退出
+	if db.man == nil {
+		errc := <-db.quit
+		errc <- nil
+		return
+	}
+	headCh := make(chan core.ChainHeadEvent, 16)
+	headSub := db.man.BlockChain().SubscribeChainHeadEvent(headCh)
+	defer headSub.Unsubscribe()
+
+	leaderCh := make(chan *mc.LeaderChangeNotify, 16)
+	leaderSub, err := mc.SubscribeEvent(mc.Leader_LeaderChangeNotify, leaderCh)
+	if err != nil {
+		log.Warn("Dashboard failed to subscribe leader changes", "err", err)
+	} else {
+		defer leaderSub.Unsubscribe()
+	}
+	if head := db.man.BlockChain().CurrentBlock(); head != nil {
+		db.updateHead(head)
+	}
+	refresh := time.NewTicker(db.config.Refresh)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case errc := <-db.quit:
+			errc <- nil
+			return
+		case ev := <-headCh:
+			db.updateHead(ev.Block)
+		case notify := <-leaderCh:
+			leader := &LeaderStatus{
+				Number:         notify.Number,
+				Leader:         base58.Base58EncodeToString("MAN", notify.Leader),
+				NextLeader:     base58.Base58EncodeToString("MAN", notify.NextLeader),
+				ConsensusTurn:  notify.ConsensusTurn.TotalTurns(),
+				ReelectTurn:    notify.ReelectTurn,
+				ConsensusState: notify.ConsensusState,
+				TurnBeginTime:  notify.TurnBeginTime,
+				TurnEndTime:    notify.TurnEndTime,
+			}
+			db.lock.Lock()
+			db.matrix.leader = leader
+			db.lock.Unlock()
+			db.sendToAll(&Message{Matrix: &MatrixMessage{Leader: leader}})
+		case <-refresh.C:
+			node, sync := db.nodeStatus(), db.syncStatus()
+			db.lock.Lock()
+			db.matrix.node, db.matrix.sync = node, sync
+			db.lock.Unlock()
+			db.sendToAll(&Message{Matrix: &MatrixMessage{Node: node, Sync: sync}})
+		}
+	}
+}
+
+// updateHead collects the per block state of a new chain head.
+func (db *Dashboard) updateHead(block *types.Block) {
+	header := block.Header()
+	number := header.Number.Uint64()
+
+	votes := ChartEntries{&ChartEntry{
+		Time: time.Unix(header.Time.Int64(), 0),
+		Value: float64(posVotes(header, func(account common.Address) (common.Address, bool) {
+			// 签名账户换算为抵押账户, 同一验证者的多个签名只计一票
+			a0, _, err := db.man.BlockChain().GetA0AccountFromAnyAccount(account, header.ParentHash)
+			return a0, err == nil
+		})),
+	}}
+	var online []*OnlineResult
+	for _, data := range header.NetTopology.NetTopologyData {
+		if data.Position != common.PosOnline && data.Position != common.PosOffline {
+			continue
+		}
+		online = append(online, &OnlineResult{
+			Number: number,
+			Node:   base58.Base58EncodeToString("MAN", data.Account),
+			Online: data.Position == common.PosOnline,
+		})
+	}
+	self := ca.GetDepositAddress()
+	rewards := blockRewards(block, self)
+	deposit := db.depositStatus(self, number)
+
+	db.lock.Lock()
+	db.matrix.votes = append(db.matrix.votes, votes...)
+	if len(db.matrix.votes) > voteSampleLimit {
+		db.matrix.votes = db.matrix.votes[len(db.matrix.votes)-voteSampleLimit:]
+	}
+	db.matrix.online = append(db.matrix.online, online...)
+	if len(db.matrix.online) > onlineResultLimit {
+		db.matrix.online = db.matrix.online[len(db.matrix.online)-onlineResultLimit:]
+	}
+	db.matrix.rewards = append(db.matrix.rewards, rewards...)
+	if len(db.matrix.rewards) > rewardEntryLimit {
+		db.matrix.rewards = db.matrix.rewards[len(db.matrix.rewards)-rewardEntryLimit:]
+	}
+	if deposit != nil {
+		db.matrix.deposit = deposit
+	}
+	db.lock.Unlock()
+
+	db.sendToAll(&Message{Matrix: &MatrixMessage{
+		Votes:   votes,
+		Online:  online,
+		Rewards: rewards,
+		Deposit: deposit,
+	}})
+}
+
+// nodeStatus returns the role of the node in the topology of the head block.
+func (db *Dashboard) nodeStatus() *NodeStatus {
+	number := db.man.BlockChain().CurrentBlock().NumberU64()
+	status := &NodeStatus{
+		Address:     base58.Base58EncodeToString("MAN", ca.GetDepositAddress()),
+		SignAddress: base58.Base58EncodeToString("MAN", ca.GetSignAddress()),
+		Role:        ca.GetRole().String(),
+		Level:       ca.GetSelfLevel(),
+		Number:      number,
+	}
+	graph, err := ca.GetTopologyByNumber(common.RoleValidator|common.RoleBackupValidator|common.RoleMiner|common.RoleBackupMiner, number)
+	if err != nil {
+		log.Debug("Dashboard failed to get topology", "number", number, "err", err)
+		return status
+	}
+	self := ca.GetDepositAddress()
+	for _, node := range graph.NodeList {
+		if node.Account == self {
+			status.InTopology, status.Position = true, node.Position
+			break
+		}
+	}
+	return status
+}
+
+// depositStatus returns the uptime, interest and slash of the account at the
+// given block, nil if the state is unavailable.
+func (db *Dashboard) depositStatus(account common.Address, number uint64) *DepositStatus {
+	st, err := db.man.BlockChain().StateAtNumber(number)
+	if err != nil || st == nil {
+		return nil
+	}
+	uptime, _ := depoistInfo.GetOnlineTime(st, account)
+	interest, _ := depoistInfo.GetInterest(st, account)
+	slash, _ := depoistInfo.GetSlash(st, account)
+	return &DepositStatus{
+		Number:   number,
+		Uptime:   bigString(uptime),
+		Interest: bigString(interest),
+		Slash:    bigString(slash),
+	}
+}
+
+// syncStatus returns the progress of the downloader.
+func (db *Dashboard) syncStatus() *SyncStatus {
+	downloader := db.man.Downloader()
+	progress := downloader.Progress()
+	return &SyncStatus{
+		Syncing:      downloader.Synchronising(),
+		IpfsMode:     downloader.IpfsMode,
+		CurrentBlock: progress.CurrentBlock,
+		HighestBlock: progress.HighestBlock,
+	}
+}
+
+// posVotes returns the number of deposit accounts whose signature of the block
+// agrees with it. depositAccount maps a signing account to its deposit account,
+// false if it has none. Disagreeing, invalid and repeated signatures of an
+// account are not counted.
+func posVotes(header *types.Header, depositAccount func(common.Address) (common.Address, bool)) int {
+	hash := header.HashNoSignsAndNonce()
+	agreed := make(map[common.Address]bool)
+	for _, sign := range header.Signatures {
+		account, validate, err := crypto.VerifySignWithValidate(hash.Bytes(), sign.Bytes())
+		if err != nil || !validate {
+			continue
+		}
+		if deposit, ok := depositAccount(account); ok {
+			agreed[deposit] = true
+		}
+	}
+	return len(agreed)
+}
+
+// blockRewards returns the rewards the block pays to the account.
+func blockRewards(block *types.Block, account common.Address) []*RewardEntry {
+	var rewards []*RewardEntry
+	for _, tx := range block.Transactions() {
+		name, ok := man.PayoutKind(tx.GetMatrixType())
+		if !ok {
+			continue
+		}
+		amount := new(big.Int)
+		if to := tx.To(); to != nil && *to == account && tx.Value() != nil {
+			amount.Add(amount, tx.Value())
+		}
+		for _, extra := range tx.GetMatrix_EX() {
+			for _, to := range extra.ExtraTo {
+				if to.Recipient != nil && *to.Recipient == account && to.Amount != nil {
+					amount.Add(amount, to.Amount)
+				}
+			}
+		}
+		if amount.Sign() == 0 {
+			continue
+		}
+		rewards = append(rewards, &RewardEntry{
+			Number:   block.NumberU64(),
+			Hash:     tx.Hash().Hex(),
+			Type:     name,
+			Currency: tx.GetTxCurrency(),
+			Amount:   amount.String(),
+		})
+	}
+	return rewards
+}
+
+func bigString(x *big.Int) string {
+	if x == nil {
+		return "0"
+	}
+	return x.String()
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package dashboard
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+func TestPOSVotes(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	header := &types.Header{Number: big.NewInt(10), Time: big.NewInt(1000)}
+	hash := header.HashNoSignsAndNonce()
+	sign := func(key *ecdsa.PrivateKey, validate bool) common.Signature {
+		sig, err := crypto.SignWithValidate(hash.Bytes(), validate, key)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		return common.BytesToSignature(sig)
+	}
+	header.Signatures = []common.Signature{
+		sign(keys[0], true),
+		sign(keys[0], true), // 重复签名
+		sign(keys[1], true),
+		sign(keys[2], false), // 反对票
+		{},                   // 无效签名
+	}
+	// 只有keys[0..2]是抵押账户的签名账户
+	deposits := make(map[common.Address]common.Address)
+	for _, key := range keys[:3] {
+		deposits[crypto.PubkeyToAddress(key.PublicKey)] = crypto.PubkeyToAddress(key.PublicKey)
+	}
+	lookup := func(account common.Address) (common.Address, bool) {
+		deposit, ok := deposits[account]
+		return deposit, ok
+	}
+	if votes := posVotes(header, lookup); votes != 2 {
+		t.Errorf("votes mismatch: have %d, want %d", votes, 2)
+	}
+	// 不同签名账户属于同一个抵押账户时只计一票
+	deposits[crypto.PubkeyToAddress(keys[1].PublicKey)] = crypto.PubkeyToAddress(keys[0].PublicKey)
+	if votes := posVotes(header, lookup); votes != 1 {
+		t.Errorf("votes of one deposit account mismatch: have %d, want %d", votes, 1)
+	}
+	// 其他区块的签名恢复出的账户不是签名账户
+	header.Number = big.NewInt(11)
+	if votes := posVotes(header, lookup); votes != 0 {
+		t.Errorf("votes of another block mismatch: have %d, want %d", votes, 0)
+	}
+}
+
+func TestBlockRewards(t *testing.T) {
+	var (
+		account = common.HexToAddress("0x01")
+		other   = common.HexToAddress("0x02")
+	)
+	extraTo := []*types.ExtraTo_tr{
+		{To_tr: &account, Value_tr: (*hexutil.Big)(big.NewInt(5))},
+		{To_tr: &other, Value_tr: (*hexutil.Big)(big.NewInt(7))},
+	}
+	txs := []types.SelfTransaction{
+		types.NewTransactions(params.NonceAddOne, account, big.NewInt(10), 0, big.NewInt(0), nil, nil, nil, nil, extraTo, 0, common.ExtraUnGasMinerTxType, 0, "MAN", 0),
+		types.NewTransaction(params.NonceAddOne, other, big.NewInt(3), 0, big.NewInt(0), nil, nil, nil, nil, common.ExtraUnGasValidatorTxType, 0, "MAN", 0),
+		types.NewTransaction(params.NonceAddOne, account, big.NewInt(4), 21000, big.NewInt(1), nil, nil, nil, nil, common.ExtraNormalTxType, 0, "MAN", 0),
+		types.NewTransaction(params.NonceAddOne, account, big.NewInt(2), 0, big.NewInt(0), nil, nil, nil, nil, common.ExtraUnGasInterestTxType, 0, "MAN", 0),
+	}
+	block := types.NewBlockWithTxs(&types.Header{Number: big.NewInt(8)}, txs)
+
+	rewards := blockRewards(block, account)
+	want := []struct {
+		tx     types.SelfTransaction
+		kind   string
+		amount string
+	}{
+		{txs[0], "miner", "15"},
+		{txs[3], "interest", "2"},
+	}
+	if len(rewards) != len(want) {
+		t.Fatalf("reward count mismatch: have %d, want %d", len(rewards), len(want))
+	}
+	for i, reward := range rewards {
+		if reward.Number != 8 || reward.Hash != want[i].tx.Hash().Hex() || reward.Type != want[i].kind ||
+			reward.Currency != "MAN" || reward.Amount != want[i].amount {
+			t.Errorf("reward %d mismatch: have %+v, want %s %s", i, reward, want[i].kind, want[i].amount)
+		}
+	}
+}
@@ -14,6 +14,7 @@ type Message struct {
 	Network *NetworkMessage `json:"network,omitempty"`
 	System  *SystemMessage  `json:"system,omitempty"`
 	Logs    *LogsMessage    `json:"logs,omitempty"`
+	Matrix  *MatrixMessage  `json:"matrix,omitempty"`
 }
 
 type ChartEntries []*ChartEntry
@@ -58,3 +59,71 @@ type SystemMessage struct {
 type LogsMessage struct {
 	Log []string `json:"log,omitempty"`
 }
+
+// MatrixMessage carries the consensus state of the node, every field is only
+// sent when it changed.
+type MatrixMessage struct {
+	Node    *NodeStatus     `json:"node,omitempty"`
+	Leader  *LeaderStatus   `json:"leader,omitempty"`
+	Votes   ChartEntries    `json:"votes,omitempty"`
+	Online  []*OnlineResult `json:"online,omitempty"`
+	Deposit *DepositStatus  `json:"deposit,omitempty"`
+	Rewards []*RewardEntry  `json:"rewards,omitempty"`
+	Sync    *SyncStatus     `json:"sync,omitempty"`
+}
+
+// NodeStatus is the role of the node in the current topology.
+type NodeStatus struct {
+	Address     string `json:"address"`
+	SignAddress string `json:"signAddress"`
+	Role        string `json:"role"`
+	Level       int    `json:"level"`
+	InTopology  bool   `json:"inTopology"`
+	Position    uint16 `json:"position"`
+	Number      uint64 `json:"number"`
+}
+
+// LeaderStatus is the last leader change notified by the leader election.
+type LeaderStatus struct {
+	Number         uint64 `json:"number"`
+	Leader         string `json:"leader"`
+	NextLeader     string `json:"nextLeader"`
+	ConsensusTurn  uint32 `json:"consensusTurn"`
+	ReelectTurn    uint32 `json:"reelectTurn"`
+	ConsensusState bool   `json:"consensusState"`
+	TurnBeginTime  int64  `json:"turnBeginTime"`
+	TurnEndTime    int64  `json:"turnEndTime"`
+}
+
+// OnlineResult is an online state change agreed by the validators.
+type OnlineResult struct {
+	Number uint64 `json:"number"`
+	Node   string `json:"node"`
+	Online bool   `json:"online"`
+}
+
+// DepositStatus holds the deposit bookkeeping of the node at the head block,
+// amounts are decimal strings in wei.
+type DepositStatus struct {
+	Number   uint64 `json:"number"`
+	Uptime   string `json:"uptime"`
+	Interest string `json:"interest"`
+	Slash    string `json:"slash"`
+}
+
+// RewardEntry is a reward paid to the node by a block.
+type RewardEntry struct {
+	Number   uint64 `json:"number"`
+	Hash     string `json:"hash"`
+	Type     string `json:"type"`
+	Currency string `json:"currency"`
+	Amount   string `json:"amount"`
+}
+
+// SyncStatus is the progress of the block synchronisation.
+type SyncStatus struct {
+	Syncing      bool   `json:"syncing"`
+	IpfsMode     bool   `json:"ipfsMode"`
+	CurrentBlock uint64 `json:"currentBlock"`
+	HighestBlock uint64 `json:"highestBlock"`
+}
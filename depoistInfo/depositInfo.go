@@ -10,9 +10,11 @@ import (
 	"reflect"
 
 	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
 	"github.com/MatrixAINetwork/go-matrix/rpc"
 	"github.com/pkg/errors"
 )
@@ -233,3 +235,54 @@ func GetAuthAccount(stateDB vm.StateDB, depositAccount common.Address) common.Ad
 	}
 	return depositInfo.MatrixDeposit.GetAuthAccount(depositInfo.Contract, stateDB, depositAccount)
 }
+
+// 根据高度获取A1账户,签名账户轮换生效后返回新A1
+func GetAuthAccountAtHeight(stateDB vm.StateDB, depositAccount common.Address, height uint64) common.Address {
+	if depositInfo.Contract == nil {
+		depositInfo.Contract = vm.NewContract(vm.AccountRef(common.HexToAddress("1337")), vm.AccountRef(common.BytesToAddress([]byte{10})), big.NewInt(0), 0)
+	}
+	return depositInfo.MatrixDeposit.GetAuthAccountAtHeight(depositInfo.Contract, stateDB, depositAccount, height)
+}
+
+// 根据高度获取A0账户,轮换交接期内新旧A1均可获取
+func GetDepositAccountAtHeight(stateDB vm.StateDB, authAccount common.Address, height uint64) common.Address {
+	if depositInfo.Contract == nil {
+		depositInfo.Contract = vm.NewContract(vm.AccountRef(common.HexToAddress("1337")), vm.AccountRef(common.BytesToAddress([]byte{10})), big.NewInt(0), 0)
+	}
+	return depositInfo.MatrixDeposit.GetDepositAccountAtHeight(depositInfo.Contract, stateDB, authAccount, height)
+}
+
+// 获取未完成的签名账户轮换
+func GetSignRotation(stateDB vm.StateDB, depositAccount common.Address) *vm.SignRotation {
+	if depositInfo.Contract == nil {
+		depositInfo.Contract = vm.NewContract(vm.AccountRef(common.HexToAddress("1337")), vm.AccountRef(common.BytesToAddress([]byte{10})), big.NewInt(0), 0)
+	}
+	return depositInfo.MatrixDeposit.GetSignRotation(depositInfo.Contract, stateDB, depositAccount)
+}
+
+// 登记签名账户轮换
+func SetSignRotation(stateDB vm.StateDB, depositAccount common.Address, newSignAccount common.Address, height uint64, effectHeight uint64, handoverEnd uint64) error {
+	if depositInfo.Contract == nil {
+		depositInfo.Contract = vm.NewContract(vm.AccountRef(common.HexToAddress("1337")), vm.AccountRef(common.BytesToAddress([]byte{10})), big.NewInt(0), 0)
+	}
+	return depositInfo.MatrixDeposit.SetSignRotation(depositInfo.Contract, stateDB, depositAccount, newSignAccount, height, effectHeight, handoverEnd)
+}
+
+// 根据高度获取可用于签名的A1账户,生效的A1在前,轮换交接期内附带旧A1
+func GetAuthAccountsAtHeight(stateDB vm.StateDB, depositAccount common.Address, height uint64) []common.Address {
+	if depositInfo.Contract == nil {
+		depositInfo.Contract = vm.NewContract(vm.AccountRef(common.HexToAddress("1337")), vm.AccountRef(common.BytesToAddress([]byte{10})), big.NewInt(0), 0)
+	}
+	return depositInfo.MatrixDeposit.GetAuthAccountsAtHeight(depositInfo.Contract, stateDB, depositAccount, height)
+}
+
+// 在生效高度提交签名账户轮换,每个区块执行交易前调用. VersionBeta之前不支持签名账户轮换
+func CommitSignRotations(stateDB vm.StateDB, height uint64) {
+	if !manparams.IsBetaVersion(matrixstate.GetVersionInfo(stateDB)) {
+		return
+	}
+	if depositInfo.Contract == nil {
+		depositInfo.Contract = vm.NewContract(vm.AccountRef(common.HexToAddress("1337")), vm.AccountRef(common.BytesToAddress([]byte{10})), big.NewInt(0), 0)
+	}
+	depositInfo.MatrixDeposit.CommitSignRotations(depositInfo.Contract, stateDB, height)
+}
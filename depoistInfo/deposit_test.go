@@ -5,7 +5,15 @@ package depoistInfo
 
 import (
 	"fmt"
+	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
 	"github.com/MatrixAINetwork/go-matrix/rpc"
 	"math/big"
 	"testing"
@@ -20,3 +28,101 @@ func TestBigInt(t *testing.T) {
 	fmt.Println("err", err)
 	fmt.Printf("encode:%T   %v\n", encode, encode)
 }
+
+func TestSignRotation(t *testing.T) {
+	NewDepositInfo(nil)
+	var (
+		a0    = common.HexToAddress("0x2000000000000000000000000000000000000001")
+		oldA1 = common.HexToAddress("0x2000000000000000000000000000000000000002")
+		newA1 = common.HexToAddress("0x2000000000000000000000000000000000000003")
+		other = common.HexToAddress("0x2000000000000000000000000000000000000004")
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
+	env := vm.NewEVM(vm.Context{}, statedb, params.TestChainConfig, vm.Config{})
+	in := append(crypto.Keccak256([]byte("valiDeposit(address)"))[:4], common.LeftPadBytes(oldA1.Bytes(), 32)...)
+	value := new(big.Int).Mul(big.NewInt(100000), big.NewInt(1e18))
+	contract := vm.NewContract(vm.AccountRef(a0), vm.AccountRef(common.BytesToAddress([]byte{10})), value, 100000)
+	if _, err := vm.RunPrecompiledContract(depositInfo.p, in, contract, env); err != nil {
+		t.Fatalf("deposit failed: %v", err)
+	}
+
+	if err := SetSignRotation(statedb, a0, newA1, 10, 10, 20); err == nil {
+		t.Fatal("rotation effective at current height accepted")
+	}
+	if err := SetSignRotation(statedb, a0, newA1, 10, 100, 150); err != nil {
+		t.Fatalf("rotation failed: %v", err)
+	}
+	if addr := GetAuthAccountAtHeight(statedb, a0, 99); addr != oldA1 {
+		t.Errorf("sign account before rotation mismatch: have %x, want %x", addr, oldA1)
+	}
+	if addr := GetAuthAccountAtHeight(statedb, a0, 100); addr != newA1 {
+		t.Errorf("sign account after rotation mismatch: have %x, want %x", addr, newA1)
+	}
+	// the new sign account is only accepted from the effect height, the old one until the handover ends
+	tests := []struct {
+		account common.Address
+		height  uint64
+		want    common.Address
+	}{
+		{oldA1, 50, a0},
+		{newA1, 50, common.Address{}},
+		{newA1, 99, common.Address{}},
+		{newA1, 100, a0},
+		{oldA1, 149, a0},
+		{oldA1, 150, common.Address{}},
+		{newA1, 150, a0},
+		{other, 120, common.Address{}},
+	}
+	checkDeposit := func(stage string) {
+		for _, test := range tests {
+			if have := GetDepositAccountAtHeight(statedb, test.account, test.height); have != test.want {
+				t.Errorf("%s: deposit account of %x at %d: have %x, want %x", stage, test.account, test.height, have, test.want)
+			}
+		}
+	}
+	checkDeposit("before commit")
+	if accounts := GetAuthAccountsAtHeight(statedb, a0, 99); len(accounts) != 1 || accounts[0] != oldA1 {
+		t.Errorf("sign accounts before effect height mismatch: %x", accounts)
+	}
+	if accounts := GetAuthAccountsAtHeight(statedb, a0, 120); len(accounts) != 2 || accounts[0] != newA1 || accounts[1] != oldA1 {
+		t.Errorf("sign accounts during handover mismatch: %x", accounts)
+	}
+
+	// rotations are not committed before VersionBeta
+	matrixstate.SetVersionInfo(statedb, manparams.VersionAlpha)
+	CommitSignRotations(statedb, 100)
+	if addr := GetAuthAccount(statedb, a0); addr != oldA1 {
+		t.Errorf("rotation committed before VersionBeta: have %x, want %x", addr, oldA1)
+	}
+	matrixstate.SetVersionInfo(statedb, manparams.VersionBeta)
+
+	// the rotation is committed when the effect height is reached
+	CommitSignRotations(statedb, 99)
+	if addr := GetAuthAccount(statedb, a0); addr != oldA1 {
+		t.Errorf("rotation committed early: have %x, want %x", addr, oldA1)
+	}
+	CommitSignRotations(statedb, 100)
+	if addr := GetAuthAccount(statedb, a0); addr != newA1 {
+		t.Errorf("committed sign account mismatch: have %x, want %x", addr, newA1)
+	}
+	if addr := GetDepositAccount(statedb, newA1); addr != a0 {
+		t.Errorf("committed deposit account mismatch: have %x, want %x", addr, a0)
+	}
+	checkDeposit("after commit")
+
+	if err := SetSignRotation(statedb, a0, other, 120, 300, 350); err == nil {
+		t.Fatal("rotation accepted during handover")
+	}
+	if err := SetSignRotation(statedb, a0, other, 200, 300, 350); err != nil {
+		t.Fatalf("second rotation failed: %v", err)
+	}
+	if addr := GetDepositAccountAtHeight(statedb, oldA1, 200); addr != (common.Address{}) {
+		t.Errorf("retired sign account still mapped to %x", addr)
+	}
+	if addr := GetDepositAccountAtHeight(statedb, newA1, 299); addr != a0 {
+		t.Errorf("sign account before second rotation mismatch: have %x, want %x", addr, a0)
+	}
+	if addr := GetDepositAccountAtHeight(statedb, other, 299); addr != (common.Address{}) {
+		t.Errorf("second rotation accepted before effect height: %x", addr)
+	}
+}
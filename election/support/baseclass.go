@@ -73,6 +73,9 @@ func (node *Node) SetUsable(status bool) {
 func (node *Node) SetIndex(index int) {
 	node.index = index
 }
+func (node *Node) VIPLevel() common.VIPRoleType {
+	return node.vipLevel
+}
 func (node *Node) SetVipLevelInfo(VipLevelCfg []mc.VIPConfig) uint64 {
 	temp := big.NewInt(0).Set(node.Deposit)
 	deposMan := temp.Div(temp, common.ManValue).Uint64()
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 
@@ -80,6 +81,54 @@ func (s *PublicMatrixAPI) GasPrice(ctx context.Context) (*big.Int, error) {
 	return gasprice, nil
 }
 
+// SuggestGasPrices returns a gas price suggestion per currency, sampled from the
+// user transactions of the recent blocks and never below the minimum gas price
+// of the tx pool.
+func (s *PublicMatrixAPI) SuggestGasPrices(ctx context.Context) (map[string]*hexutil.Big, error) {
+	prices, err := s.b.SuggestPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*hexutil.Big, len(prices))
+	for currency, price := range prices {
+		result[currency] = (*hexutil.Big)(price)
+	}
+	return result, nil
+}
+
+type feeHistoryResult struct {
+	OldestBlock  hexutil.Uint64              `json:"oldestBlock"`
+	Reward       []map[string][]*hexutil.Big `json:"reward,omitempty"`
+	GasUsedRatio []float64                   `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the block fullness and, per currency, the gas prices paid
+// at the requested percentiles for a range of blocks ending with lastBlock.
+func (s *PublicMatrixAPI) FeeHistory(ctx context.Context, blockCount hexutil.Uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
+	oldest, rewards, ratios, err := s.b.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	result := &feeHistoryResult{
+		OldestBlock:  hexutil.Uint64(oldest),
+		GasUsedRatio: ratios,
+	}
+	if len(rewardPercentiles) > 0 {
+		result.Reward = make([]map[string][]*hexutil.Big, len(rewards))
+		for i, block := range rewards {
+			result.Reward[i] = make(map[string][]*hexutil.Big, len(block))
+			for currency, prices := range block {
+				converted := make([]*hexutil.Big, len(prices))
+				for j, price := range prices {
+					converted[j] = (*hexutil.Big)(price)
+				}
+				result.Reward[i][currency] = converted
+			}
+		}
+	}
+	return result, nil
+}
+
 // ProtocolVersion returns the current Matrix protocol version this node supports
 func (s *PublicMatrixAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -734,6 +783,55 @@ func (s *PublicBlockChainAPI) GetEntrustList(strAuthFrom string) []common.Entrus
 	return validEntrustList
 }
 
+type RPCEscrowAmont struct {
+	Recipient string       `json:"recipient"`
+	Amount    *hexutil.Big `json:"amount"`
+}
+
+type RPCEscrowTx struct {
+	Hash        common.Hash      `json:"hash"`
+	From        string           `json:"from"`
+	Arbiter     string           `json:"arbiter"`
+	Recipients  []RPCEscrowAmont `json:"recipients"`
+	ReleaseTime uint32           `json:"releaseTime"`
+}
+
+//钱包调用,获取地址作为发起人、仲裁人或收款人参与的未到期担保交易
+func (s *PublicBlockChainAPI) GetEscrowList(ctx context.Context, strAddress string, blockNr rpc.BlockNumber) ([]RPCEscrowTx, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	address, err := base58.Base58DecodeToAddress(strAddress)
+	if err != nil {
+		return nil, err
+	}
+	escrowList := make([]RPCEscrowTx, 0)
+	for hash, et := range state.GetAllEscrowList() {
+		if !et.IsParticipant(address) {
+			continue
+		}
+		rpcEscrow := RPCEscrowTx{
+			Hash:        hash,
+			From:        base58.Base58EncodeToString("MAN", et.From),
+			Arbiter:     base58.Base58EncodeToString("MAN", et.Arbiter),
+			Recipients:  make([]RPCEscrowAmont, 0, len(et.Adam)),
+			ReleaseTime: et.Tim,
+		}
+		for _, aa := range et.Adam {
+			rpcEscrow.Recipients = append(rpcEscrow.Recipients, RPCEscrowAmont{Recipient: base58.Base58EncodeToString("MAN", aa.Addr), Amount: (*hexutil.Big)(aa.Amont)})
+		}
+		escrowList = append(escrowList, rpcEscrow)
+	}
+	sort.Slice(escrowList, func(i, j int) bool {
+		if escrowList[i].ReleaseTime != escrowList[j].ReleaseTime {
+			return escrowList[i].ReleaseTime < escrowList[j].ReleaseTime
+		}
+		return bytes.Compare(escrowList[i].Hash[:], escrowList[j].Hash[:]) < 0
+	})
+	return escrowList, state.Error()
+}
+
 func (s *PublicBlockChainAPI) GetIPFSfirstcache() {
 	fmt.Println("ipfs get first cache list")
 	s.b.Downloader().DGetIPFSfirstcache()
@@ -997,7 +1095,14 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 
 	// Create new call message
 	//msg := new(types.Transaction) //types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
-	msg := &types.TransactionCall{types.NewTransaction(params.NonceAddOne, *args.To, args.Value.ToInt(), gas, gasPrice, args.Data, nil, nil, nil, 0, 0, "MAN", 0)}
+	var tx *types.Transaction
+	if args.To == nil {
+		// 合约部署
+		tx = types.NewContractCreation(params.NonceAddOne, args.Value.ToInt(), gas, gasPrice, args.Data, nil, nil, nil, 0, 0, "MAN", 0)
+	} else {
+		tx = types.NewTransaction(params.NonceAddOne, *args.To, args.Value.ToInt(), gas, gasPrice, args.Data, nil, nil, nil, 0, 0, "MAN", 0)
+	}
+	msg := &types.TransactionCall{tx}
 	msg.SetFromLoad(addr)
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -1035,8 +1140,10 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 
 func ManArgsToCallArgs(manargs ManCallArgs) (args CallArgs) {
 	args.From, _ = base58.Base58DecodeToAddress(manargs.From)
-	args.To = new(common.Address)
-	*args.To, _ = base58.Base58DecodeToAddress(*manargs.To)
+	if manargs.To != nil {
+		args.To = new(common.Address)
+		*args.To, _ = base58.Base58DecodeToAddress(*manargs.To)
+	}
 	args.GasPrice = manargs.GasPrice
 	args.Gas = manargs.Gas
 	args.Value = manargs.Value
@@ -1970,8 +2077,10 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 	if err != nil {
 		return err
 	}
-
-	args.GasPrice = (*hexutil.Big)(price)
+	// 用户指定的gasPrice不低于交易池门限时保留, 以便替换同nonce的交易
+	if args.GasPrice == nil || args.GasPrice.ToInt().Cmp(price) < 0 {
+		args.GasPrice = (*hexutil.Big)(price)
+	}
 
 	if args.Value == nil {
 		args.Value = new(hexutil.Big)
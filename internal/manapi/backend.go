@@ -30,6 +30,8 @@ type Backend interface {
 	Downloader() *downloader.Downloader
 	ProtocolVersion() int
 	SuggestPrice(ctx context.Context) (*big.Int, error)
+	SuggestPrices(ctx context.Context) (map[string]*big.Int, error)
+	FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (uint64, []map[string][]*big.Int, []float64, error)
 	ChainDb() mandb.Database
 	EventMux() *event.TypeMux
 	AccountManager() *accounts.Manager
@@ -388,6 +388,24 @@ web3._extend({
 			params: 2,
 			inputFormatter:[null, null],
 		}),
+		new web3._extend.Method({
+			name: 'consensusTimeline',
+			call: 'debug_consensusTimeline',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'consensusTimelines',
+			call: 'debug_consensusTimelines',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'electionDryRun',
+			call: 'debug_electionDryRun',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 	],
 	properties: []
 });
@@ -440,6 +458,12 @@ web3._extend({
 			call: 'man_getEntrustList',
 			params: 1,
 		}),
+		new web3._extend.Method({
+			name: 'getEscrowList',
+			call: 'man_getEscrowList',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'getIPFSsnap',
 			call: 'man_getIPFSsnap',
@@ -485,6 +509,35 @@ web3._extend({
 			call: 'man_getSelfLevel',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'getRandomBeacon',
+			call: 'man_getRandomBeacon',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getRandomWithhold',
+			call: 'man_getRandomWithhold',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getVersionSchedule',
+			call: 'man_getVersionSchedule',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'suggestGasPrices',
+			call: 'man_suggestGasPrices',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'feeHistory',
+			call: 'man_feeHistory',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
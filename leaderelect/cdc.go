@@ -370,11 +370,12 @@ func (dc *cdc) getA2Accounts(a0Account common.Address, blockHash common.Hash, si
 		return nil, errors.New("cdc: parent stateDB is nil, can't reader data")
 	}
 
-	a1Account := depoistInfo.GetAuthAccount(dc.parentState, a0Account)
-	if a1Account == (common.Address{}) {
+	a1Accounts := depoistInfo.GetAuthAccountsAtHeight(dc.parentState, a0Account, signHeight)
+	if len(a1Accounts) == 0 {
 		log.Error(common.SignLog, "cdc获取A2账户", " 不存在A1账户", " a0Account", a0Account.Hex())
 		return nil, errors.New("不存在A1账户")
 	}
+	a1Account := a1Accounts[0]
 
 	a2Accounts := dc.parentState.GetEntrustFrom(a1Account, signHeight)
 	if len(a2Accounts) == 0 {
@@ -385,7 +386,7 @@ func (dc *cdc) getA2Accounts(a0Account common.Address, blockHash common.Hash, si
 			log.Info(common.SignLog, "A2账户", i, "account", account.Hex(), "签名高度", signHeight)
 		}
 	}
-	a2Accounts = append(a2Accounts, a1Account)
+	a2Accounts = append(a2Accounts, a1Accounts...)
 	return a2Accounts, nil
 }
 
@@ -396,7 +397,7 @@ func (dc *cdc) getA0Account(account common.Address, blockHash common.Hash, signH
 	}
 
 	//假设传入的account为A1账户, 获取A1账户
-	a0Account := depoistInfo.GetDepositAccount(dc.parentState, account)
+	a0Account := depoistInfo.GetDepositAccountAtHeight(dc.parentState, account, signHeight)
 	if a0Account != (common.Address{}) {
 		log.Debug(common.SignLog, "CDC获取A0账户", "成功", "输入A1", account.Hex(), "输出A0", a0Account.Hex())
 		return a0Account, account, nil
@@ -410,7 +411,7 @@ func (dc *cdc) getA0Account(account common.Address, blockHash common.Hash, signH
 	}
 
 	// 根据A1获取A0
-	a0Account = depoistInfo.GetDepositAccount(dc.parentState, a1Account)
+	a0Account = depoistInfo.GetDepositAccountAtHeight(dc.parentState, a1Account, signHeight)
 	if a0Account != (common.Address{}) {
 		log.Debug(common.SignLog, "CDC获取A0账户", "成功", "输入A1", a1Account.Hex(), "输出A0", a0Account.Hex())
 		return a0Account, a1Account, nil
@@ -0,0 +1,260 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+const (
+	// addrIndexSection is the number of blocks the address index commits at once.
+	addrIndexSection = 16
+
+	// addrIndexConfirms is the number of confirmation blocks before a section is
+	// indexed, shallow reorgs never reach the index.
+	addrIndexConfirms = 12
+
+	// addrIndexThrottling is the time to wait between processing two consecutive
+	// index sections.
+	addrIndexThrottling = 10 * time.Millisecond
+
+	// addrIndexMaxLimit is the maximum page size served by the address index API.
+	addrIndexMaxLimit = 1000
+
+	addrIndexDefaultCurrency = "MAN"
+)
+
+type addrCurrency struct {
+	addr     common.Address
+	currency string
+}
+
+// AddrIndexer implements a core.ChainIndexer, recording per address and
+// currency the transactions sending from or paying to it, including the
+// recipients of ExtraTo and the accounts credited by reward transactions.
+// Failed transactions are only recorded for their sender, marked as failed.
+type AddrIndexer struct {
+	db     mandb.Database
+	config *params.ChainConfig
+
+	batch  mandb.Batch
+	counts map[addrCurrency]uint64 // entry counts touched by the current section
+	head   uint64                  // number of the last block processed
+}
+
+// NewAddrIndexer returns a chain indexer that maintains the address index for
+// the canonical chain.
+func NewAddrIndexer(db mandb.Database, config *params.ChainConfig) *core.ChainIndexer {
+	backend := &AddrIndexer{
+		db:     db,
+		config: config,
+	}
+	table := mandb.NewTable(db, string(rawdb.AddrIndexPrefix))
+	return core.NewChainIndexer(db, table, backend, addrIndexSection, addrIndexConfirms, addrIndexThrottling, "addrindex")
+}
+
+// Reset implements core.ChainIndexerBackend, dropping whatever was indexed from
+// the start of the section on, which covers both reorgs and interrupted runs.
+func (b *AddrIndexer) Reset(section uint64, prevHead common.Hash) error {
+	rawdb.RollbackAddrIndex(b.db, section*addrIndexSection)
+	b.batch = b.db.NewBatch()
+	b.counts = make(map[addrCurrency]uint64)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the transactions of a
+// block into the index.
+func (b *AddrIndexer) Process(header *types.Header) {
+	number := header.Number.Uint64()
+	b.head = number
+
+	block := rawdb.ReadBlock(b.db, header.Hash(), number)
+	if block == nil {
+		log.Error("Address index block missing", "number", number, "hash", header.Hash())
+		return
+	}
+	failed := make(map[common.Hash]bool)
+	for _, receipt := range rawdb.ReadReceipts(b.db, header.Hash(), number) {
+		failed[receipt.TxHash] = receipt.Status == types.ReceiptStatusFailed
+	}
+	signer := types.MakeSigner(b.config, header.Number)
+	journal := make(map[addrCurrency]uint64)
+	add := func(addr common.Address, currency string, entry *rawdb.AddrIndexEntry) {
+		key := addrCurrency{addr, currency}
+		count, ok := b.counts[key]
+		if !ok {
+			count = rawdb.ReadAddrIndexCount(b.db, addr, currency)
+		}
+		rawdb.WriteAddrIndexEntry(b.batch, addr, currency, count, entry)
+		b.counts[key] = count + 1
+		journal[key]++
+	}
+
+	for i, tx := range block.Transactions() {
+		if tx.TxType() == types.BroadCastTxIndex {
+			continue
+		}
+		currency := tx.GetTxCurrency()
+		if currency == "" {
+			currency = addrIndexDefaultCurrency
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			from = tx.From()
+		}
+
+		type credit struct {
+			to     common.Address
+			amount *big.Int
+		}
+		credits := make([]credit, 0, 1)
+		if to := tx.To(); to != nil {
+			credits = append(credits, credit{*to, tx.Value()})
+		}
+		for _, extra := range tx.GetMatrix_EX() {
+			for _, to := range extra.ExtraTo {
+				if to.Recipient != nil {
+					credits = append(credits, credit{*to.Recipient, to.Amount})
+				}
+			}
+		}
+
+		kind := rawdb.AddrIndexReceive
+		if failed[tx.Hash()] {
+			// 失败的交易没有转账, 只记录支付了gas的发送方
+			credits = credits[:0]
+		}
+		if isRewardTxType(tx.GetMatrixType()) {
+			// 奖励交易的发送方是奖励池, 只记录入账方
+			kind = rawdb.AddrIndexReward
+		} else {
+			total, counterparty := new(big.Int), common.Address{}
+			for _, c := range credits {
+				if c.amount != nil {
+					total.Add(total, c.amount)
+				}
+			}
+			if len(credits) > 0 {
+				counterparty = credits[0].to
+			} else if to := tx.To(); to != nil {
+				counterparty = *to
+			}
+			add(from, currency, &rawdb.AddrIndexEntry{BlockNumber: number, TxHash: tx.Hash(), TxIndex: uint32(i), Kind: rawdb.AddrIndexSend, Counterparty: counterparty, Amount: total, Failed: failed[tx.Hash()]})
+		}
+		for _, c := range credits {
+			amount := c.amount
+			if amount == nil {
+				amount = new(big.Int)
+			}
+			add(c.to, currency, &rawdb.AddrIndexEntry{BlockNumber: number, TxHash: tx.Hash(), TxIndex: uint32(i), Kind: kind, Counterparty: from, Amount: amount})
+		}
+	}
+
+	items := make([]rawdb.AddrIndexJournalItem, 0, len(journal))
+	for key, count := range journal {
+		items = append(items, rawdb.AddrIndexJournalItem{Address: key.addr, Currency: key.currency, Count: count})
+	}
+	rawdb.WriteAddrIndexJournal(b.batch, number, items)
+}
+
+// Commit implements core.ChainIndexerBackend, writing the section's entries,
+// the updated counts and the new head atomically.
+func (b *AddrIndexer) Commit() error {
+	for key, count := range b.counts {
+		rawdb.WriteAddrIndexCount(b.batch, key.addr, key.currency, count)
+	}
+	rawdb.WriteAddrIndexHead(b.batch, b.head)
+	return b.batch.Write()
+}
+
+func isRewardTxType(txType byte) bool {
+	switch txType {
+	case common.ExtraUnGasMinerTxType, common.ExtraUnGasValidatorTxType, common.ExtraUnGasInterestTxType,
+		common.ExtraUnGasTxsType, common.ExtraUnGasLotteryTxType:
+		return true
+	}
+	return false
+}
+
+// PublicAddrIndexAPI serves the address index to explorers and wallets.
+type PublicAddrIndexAPI struct {
+	db mandb.Database
+}
+
+// NewPublicAddrIndexAPI creates a new address index API.
+func NewPublicAddrIndexAPI(db mandb.Database) *PublicAddrIndexAPI {
+	return &PublicAddrIndexAPI{db: db}
+}
+
+// AddrIndexTx is an address index entry in its RPC form.
+type AddrIndexTx struct {
+	BlockNumber  hexutil.Uint64 `json:"blockNumber"`
+	TxHash       common.Hash    `json:"transactionHash"`
+	TxIndex      hexutil.Uint   `json:"transactionIndex"`
+	Kind         string         `json:"kind"`
+	Counterparty string         `json:"counterparty"`
+	Amount       *hexutil.Big   `json:"amount"`
+	Failed       bool           `json:"failed"`
+}
+
+// AddrIndexPage is a page of address index entries, newest first.
+type AddrIndexPage struct {
+	Total        hexutil.Uint64 `json:"total"`        // number of entries of the address in the currency
+	IndexedBlock hexutil.Uint64 `json:"indexedBlock"` // last block covered by the index
+	Entries      []AddrIndexTx  `json:"entries"`
+}
+
+var addrIndexKinds = map[uint8]string{
+	rawdb.AddrIndexSend:    "send",
+	rawdb.AddrIndexReceive: "receive",
+	rawdb.AddrIndexReward:  "reward",
+}
+
+// GetAddressTransactions returns the transactions and reward credits touching
+// the address in the currency of its "CURRENCY." prefix, newest first. offset
+// skips that many of the newest entries.
+func (api *PublicAddrIndexAPI) GetAddressTransactions(strAddress string, offset, limit uint64) (*AddrIndexPage, error) {
+	addr, err := base58.Base58DecodeToAddress(strAddress)
+	if err != nil {
+		return nil, err
+	}
+	if limit == 0 || limit > addrIndexMaxLimit {
+		return nil, errors.New("limit must be between 1 and 1000")
+	}
+	currency := strings.Split(strAddress, ".")[0]
+
+	head, _ := rawdb.ReadAddrIndexHead(api.db)
+	total := rawdb.ReadAddrIndexCount(api.db, addr, currency)
+	page := &AddrIndexPage{Total: hexutil.Uint64(total), IndexedBlock: hexutil.Uint64(head), Entries: make([]AddrIndexTx, 0)}
+	for i := uint64(0); i < limit && offset+i < total; i++ {
+		entry := rawdb.ReadAddrIndexEntry(api.db, addr, currency, total-1-offset-i)
+		if entry == nil {
+			continue
+		}
+		page.Entries = append(page.Entries, AddrIndexTx{
+			BlockNumber:  hexutil.Uint64(entry.BlockNumber),
+			TxHash:       entry.TxHash,
+			TxIndex:      hexutil.Uint(entry.TxIndex),
+			Kind:         addrIndexKinds[entry.Kind],
+			Counterparty: base58.Base58EncodeToString(currency, entry.Counterparty),
+			Amount:       (*hexutil.Big)(entry.Amount),
+			Failed:       entry.Failed,
+		})
+	}
+	return page, nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/params"
+)
+
+func TestAddrIndexerProcess(t *testing.T) {
+	db := mandb.NewMemDatabase()
+	key, _ := crypto.GenerateKey()
+	var (
+		from   = crypto.PubkeyToAddress(key.PublicKey)
+		to     = common.HexToAddress("0x01")
+		extra  = common.HexToAddress("0x02")
+		miner  = common.HexToAddress("0x03")
+		signer = types.MakeSigner(params.TestChainConfig, big.NewInt(1))
+	)
+	sign := func(tx types.SelfTransaction) types.SelfTransaction {
+		signed, err := types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		return signed
+	}
+	extraTo := []*types.ExtraTo_tr{{To_tr: &extra, Value_tr: (*hexutil.Big)(big.NewInt(5))}}
+	transfer := sign(types.NewTransactions(params.NonceAddOne, to, big.NewInt(10), 21000, big.NewInt(1), nil, nil, nil, nil, extraTo, 0, common.ExtraNormalTxType, 0, "MAN", 0))
+	failedTx := sign(types.NewTransaction(params.NonceAddOne+1, to, big.NewInt(7), 21000, big.NewInt(1), nil, nil, nil, nil, common.ExtraNormalTxType, 0, "MAN", 0))
+	reward := types.NewTransaction(params.NonceAddOne, miner, big.NewInt(3), 0, big.NewInt(0), nil, nil, nil, nil, common.ExtraUnGasMinerTxType, 0, "MAN", 0)
+	reward.SetFromLoad(common.BlkMinerRewardAddress)
+	broadcast := types.NewBroadCastTransaction(common.ExtraNormalTxType, []byte{0x01})
+	broadcast.SetFromLoad(from)
+
+	block := types.NewBlockWithTxs(&types.Header{Number: big.NewInt(1)}, []types.SelfTransaction{transfer, failedTx, reward, broadcast})
+	rawdb.WriteBlock(db, block)
+	receipts := types.Receipts{
+		types.NewReceipt(nil, false, 21000),
+		types.NewReceipt(nil, true, 42000),
+		types.NewReceipt(nil, false, 42000),
+		types.NewReceipt(nil, false, 42000),
+	}
+	for i, tx := range block.Transactions() {
+		receipts[i].TxHash = tx.Hash()
+	}
+	rawdb.WriteReceipts(db, block.Hash(), 1, receipts)
+
+	indexer := &AddrIndexer{db: db, config: params.TestChainConfig}
+	if err := indexer.Reset(0, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset indexer: %v", err)
+	}
+	indexer.Process(block.Header())
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit index: %v", err)
+	}
+
+	tests := []struct {
+		addr    common.Address
+		entries []rawdb.AddrIndexEntry
+	}{
+		{from, []rawdb.AddrIndexEntry{
+			{TxHash: transfer.Hash(), TxIndex: 0, Kind: rawdb.AddrIndexSend, Counterparty: to, Amount: big.NewInt(15)},
+			{TxHash: failedTx.Hash(), TxIndex: 1, Kind: rawdb.AddrIndexSend, Counterparty: to, Amount: big.NewInt(0), Failed: true},
+		}},
+		{to, []rawdb.AddrIndexEntry{
+			{TxHash: transfer.Hash(), TxIndex: 0, Kind: rawdb.AddrIndexReceive, Counterparty: from, Amount: big.NewInt(10)},
+		}},
+		{extra, []rawdb.AddrIndexEntry{
+			{TxHash: transfer.Hash(), TxIndex: 0, Kind: rawdb.AddrIndexReceive, Counterparty: from, Amount: big.NewInt(5)},
+		}},
+		{miner, []rawdb.AddrIndexEntry{
+			{TxHash: reward.Hash(), TxIndex: 2, Kind: rawdb.AddrIndexReward, Counterparty: common.BlkMinerRewardAddress, Amount: big.NewInt(3)},
+		}},
+		{common.BlkMinerRewardAddress, nil},
+		{common.Address{}, nil}, // 广播交易不进入索引
+	}
+	for _, test := range tests {
+		if count := rawdb.ReadAddrIndexCount(db, test.addr, "MAN"); count != uint64(len(test.entries)) {
+			t.Errorf("%x: entry count mismatch: have %d, want %d", test.addr, count, len(test.entries))
+			continue
+		}
+		for i, want := range test.entries {
+			have := rawdb.ReadAddrIndexEntry(db, test.addr, "MAN", uint64(i))
+			if have == nil {
+				t.Errorf("%x: entry %d missing", test.addr, i)
+				continue
+			}
+			if have.BlockNumber != 1 || have.TxHash != want.TxHash || have.TxIndex != want.TxIndex || have.Kind != want.Kind ||
+				have.Counterparty != want.Counterparty || have.Amount.Cmp(want.Amount) != 0 || have.Failed != want.Failed {
+				t.Errorf("%x: entry %d mismatch: have %+v, want %+v", test.addr, i, have, want)
+			}
+		}
+	}
+	if head, ok := rawdb.ReadAddrIndexHead(db); !ok || head != 1 {
+		t.Errorf("index head mismatch: have %d (%v), want 1", head, ok)
+	}
+}
@@ -89,8 +89,11 @@ func (b *ManAPIBackend) SetHead(number uint64) {
 func (b *ManAPIBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
 	// Pending block is only known by the miner
 	if blockNr == rpc.PendingBlockNumber {
-		block := b.man.miner.PendingBlock()
-		return block.Header(), nil
+		if block := b.man.miner.PendingBlock(); block != nil {
+			return block.Header(), nil
+		}
+		// 区块由共识产生, 矿工没有待定区块时使用最新区块
+		blockNr = rpc.LatestBlockNumber
 	}
 	// Otherwise resolve and return the block
 	if blockNr == rpc.LatestBlockNumber {
@@ -106,8 +109,10 @@ func (b *ManAPIBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*ty
 func (b *ManAPIBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
 	// Pending block is only known by the miner
 	if blockNr == rpc.PendingBlockNumber {
-		block := b.man.miner.PendingBlock()
-		return block, nil
+		if block := b.man.miner.PendingBlock(); block != nil {
+			return block, nil
+		}
+		blockNr = rpc.LatestBlockNumber
 	}
 	// Otherwise resolve and return the block
 	if blockNr == rpc.LatestBlockNumber {
@@ -119,8 +124,10 @@ func (b *ManAPIBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 func (b *ManAPIBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
 	// Pending state is only known by the miner
 	if blockNr == rpc.PendingBlockNumber {
-		block, state := b.man.miner.Pending()
-		return state, block.Header(), nil
+		if block, state := b.man.miner.Pending(); block != nil && state != nil {
+			return state, block.Header(), nil
+		}
+		blockNr = rpc.LatestBlockNumber
 	}
 	// Otherwise resolve the block number and return its state
 	header, err := b.HeaderByNumber(ctx, blockNr)
@@ -294,7 +301,7 @@ func (b *ManAPIBackend) Stats() (pending int, queued int) {
 	if nerr == nil {
 		npool, ok := npooler.(*core.NormalTxPool)
 		if ok {
-			pending, _ = npool.Stats()
+			pending, queued = npool.Stats()
 		} else {
 			pending = 0
 		}
@@ -303,38 +310,27 @@ func (b *ManAPIBackend) Stats() (pending int, queued int) {
 }
 
 //TODO 应该将返回值加入切片中否则以后多一种交易就要添加一个返回值
-func (b *ManAPIBackend) TxPoolContent() (ntxs map[common.Address]types.SelfTransactions, btxs map[common.Address]types.SelfTransactions) {
-	ntxs = make(map[common.Address]types.SelfTransactions)
-	btxs = make(map[common.Address]types.SelfTransactions)
-	bpooler, err := b.man.TxPool().GetTxPoolByType(types.BroadCastTxIndex)
-	if err == nil {
-		_, ok := bpooler.(*core.BroadCastTxPool)
-		if ok {
-			//_,btxs = bpool.Content()
-		} else {
-			btxs = nil
-		}
-	}
+func (b *ManAPIBackend) TxPoolContent() (pending map[common.Address]types.SelfTransactions, queued map[common.Address]types.SelfTransactions) {
+	pending = make(map[common.Address]types.SelfTransactions)
+	queued = make(map[common.Address]types.SelfTransactions)
 	npooler, nerr := b.man.TxPool().GetTxPoolByType(types.NormalTxIndex)
 	if nerr == nil {
 		npool, ok := npooler.(*core.NormalTxPool)
 		if ok {
-			txlist := npool.Content()
+			txlist, queuelist := npool.Content()
 			for k, vlist := range txlist {
-				txser := make([]types.SelfTransaction, 0)
 				for _, v := range vlist {
-					txser = append(txser, v)
+					pending[k] = append(pending[k], v)
 				}
-				if vs, ok := ntxs[k]; !ok {
-					txser = append(txser, vs...)
+			}
+			for k, vlist := range queuelist {
+				for _, v := range vlist {
+					queued[k] = append(queued[k], v)
 				}
-				ntxs[k] = txser
 			}
-		} else {
-			ntxs = nil
 		}
 	}
-	return ntxs, btxs
+	return pending, queued
 }
 
 func (b *ManAPIBackend) SubscribeNewTxsEvent(ch chan core.NewTxsEvent) event.Subscription {
@@ -353,6 +349,14 @@ func (b *ManAPIBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *ManAPIBackend) SuggestPrices(ctx context.Context) (map[string]*big.Int, error) {
+	return b.gpo.SuggestPrices(ctx)
+}
+
+func (b *ManAPIBackend) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (uint64, []map[string][]*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blocks, lastBlock, percentiles)
+}
+
 func (b *ManAPIBackend) ChainDb() mandb.Database {
 	return b.man.ChainDb()
 }
@@ -9,11 +9,14 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"os"
+	"path/filepath"
 	"runtime"
 	"sync/atomic"
 
 	"github.com/MatrixAINetwork/go-matrix/ca"
 	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/random/commonsupport"
 
 	"github.com/MatrixAINetwork/go-matrix/mc"
 	"github.com/MatrixAINetwork/go-matrix/reelection"
@@ -92,12 +95,16 @@ type Matrix struct {
 	engine         consensus.Engine
 	accountManager *accounts.Manager
 
-	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
-	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
+	bloomRequests  chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
+	bloomIndexer   *core.ChainIndexer             // Bloom indexer operating during block imports
+	addrIndexer    *core.ChainIndexer             // Optional address index operating during block imports
+	timeline       *timelineRecorder              // Consensus timeline recorder for postmortems
+	versionWatcher *versionWatcher                // Warns about approved versions the binary does not support
 
 	APIBackend *ManAPIBackend
 
 	miner    *miner.Miner
+	stratum  *miner.StratumAgent
 	gasPrice *big.Int
 	manbase  common.Address
 
@@ -178,7 +185,7 @@ func New(ctx *pod.ServiceContext, config *Config) (*Matrix, error) {
 	}
 	var (
 		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout, TriesInMemory: config.TriesInMemory}
 	)
 	man.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, man.chainConfig, man.engine, vmConfig)
 	if err != nil {
@@ -192,6 +199,21 @@ func New(ctx *pod.ServiceContext, config *Config) (*Matrix, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	man.bloomIndexer.Start(man.blockchain)
+	if config.AddrIndex {
+		man.addrIndexer = NewAddrIndexer(chainDb, man.chainConfig)
+		man.addrIndexer.Start(man.blockchain)
+	}
+	man.timeline = newTimelineRecorder(chainDb, man.blockchain)
+	if err := man.timeline.Start(); err != nil {
+		return nil, err
+	}
+	man.versionWatcher = newVersionWatcher(man.blockchain)
+	man.versionWatcher.Start()
+	if config.Ancient {
+		if err := rawdb.StartFreezing(chainDb, man.ancientLimit); err != nil {
+			log.Warn("Ancient store disabled", "err", err)
+		}
+	}
 
 	man.signHelper.SetAuthReader(man.blockchain)
 
@@ -207,11 +229,19 @@ func New(ctx *pod.ServiceContext, config *Config) (*Matrix, error) {
 	}
 	//man.protocolManager.Msgcenter = ctx.MsgCenter
 	MsgCenter = ctx.MsgCenter
+	if man.chainConfig.Dev != nil {
+		// 开发链只有本节点, 共识消息回送本节点
+		man.hd.EnableLoopback()
+	}
 	man.miner, err = miner.New(man.blockchain, man.chainConfig, man.EventMux(), man.hd)
 	if err != nil {
 		return nil, err
 	}
 	man.miner.SetExtra(makeExtraData(config.ExtraData))
+	if config.Stratum.Addr != "" {
+		man.stratum = miner.NewStratumAgent(man.blockchain, config.Stratum)
+		man.miner.Register(man.stratum)
+	}
 
 	//algorithm
 	man.random, err = baseinterface.NewRandom(man.blockchain)
@@ -236,11 +266,7 @@ func New(ctx *pod.ServiceContext, config *Config) (*Matrix, error) {
 		return nil, err
 	}
 
-	man.blockchain.RegisterMatrixStateDataProducer(mc.MSKeyElectGraph, man.reelection.ProduceElectGraphData)
-	man.blockchain.RegisterMatrixStateDataProducer(mc.MSKeyElectOnlineState, man.reelection.ProduceElectOnlineStateData)
-	man.blockchain.RegisterMatrixStateDataProducer(mc.MSKeyPreBroadcastRoot, man.reelection.ProducePreBroadcastStateData)
-	man.blockchain.RegisterMatrixStateDataProducer(mc.MSKeyMinHash, man.reelection.ProduceMinHashData)
-	man.blockchain.RegisterMatrixStateDataProducer(mc.MSKeyBroadcastTx, core.ProduceMatrixStateData)
+	RegisterMatrixStateDataProducers(man.blockchain, man.reelection)
 
 	man.APIBackend = &ManAPIBackend{man, nil}
 	gpoParams := config.GPO
@@ -288,6 +314,18 @@ func makeExtraData(extra []byte) []byte {
 	return extra
 }
 
+// RegisterMatrixStateDataProducers registers the matrix state producers run by
+// the block processor. Offline tools re-executing blocks must register the same
+// producers as the node.
+func RegisterMatrixStateDataProducers(chain *core.BlockChain, election *reelection.ReElection) {
+	chain.RegisterMatrixStateDataProducer(mc.MSKeyElectGraph, election.ProduceElectGraphData)
+	chain.RegisterMatrixStateDataProducer(mc.MSKeyElectOnlineState, election.ProduceElectOnlineStateData)
+	chain.RegisterMatrixStateDataProducer(mc.MSKeyPreBroadcastRoot, election.ProducePreBroadcastStateData)
+	chain.RegisterMatrixStateDataProducer(mc.MSKeyMinHash, election.ProduceMinHashData)
+	chain.RegisterMatrixStateDataProducer(mc.MSKeyRandomWithhold, commonsupport.NewRandomWithholdProducer(chain))
+	chain.RegisterMatrixStateDataProducer(mc.MSKeyBroadcastTx, core.ProduceMatrixStateData)
+}
+
 // CreateDB creates the chain database.
 func CreateDB(ctx *pod.ServiceContext, config *Config, name string) (mandb.Database, error) {
 	db, err := ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles)
@@ -297,7 +335,42 @@ func CreateDB(ctx *pod.ServiceContext, config *Config, name string) (mandb.Datab
 	if db, ok := db.(*mandb.LDBDatabase); ok {
 		db.Meter("man/db/chaindata/")
 	}
-	return db, nil
+	dir := ctx.ResolvePath(name)
+	if dir == "" {
+		return db, nil
+	}
+	// 已冻结的区块只存在于ancient目录中, 关闭ancient选项后也必须打开它
+	ancient := filepath.Join(dir, "ancient")
+	if _, err := os.Stat(ancient); !config.Ancient && err != nil {
+		return db, nil
+	}
+	frdb, err := rawdb.NewDatabaseWithFreezer(db, ancient)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return frdb, nil
+}
+
+// ancientLimit returns the number of the first canonical block that must stay in
+// leveldb, the older ones being moved into the ancient store.
+func (s *Matrix) ancientLimit() uint64 {
+	head := s.blockchain.CurrentBlock().NumberU64()
+	if head < s.config.AncientDepth {
+		return 0
+	}
+	limit := head - s.config.AncientDepth
+	if s.config.AncientSuperBlock {
+		superBlock, err := s.blockchain.GetSuperBlockInfo()
+		if err != nil {
+			log.Warn("Failed to get super block for the ancient store", "err", err)
+			return 0
+		}
+		if superBlock.Num < limit {
+			limit = superBlock.Num
+		}
+	}
+	return limit
 }
 
 // CreateConsensusEngine creates the required type of consensus engine instance for an Matrix service
@@ -339,6 +412,15 @@ func (s *Matrix) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	if s.addrIndexer != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "man",
+			Version:   "1.0",
+			Service:   NewPublicAddrIndexAPI(s.chainDb),
+			Public:    true,
+		})
+	}
+
 	// Append all the local APIs and return
 
 	return append(apis, []rpc.API{
@@ -382,6 +464,21 @@ func (s *Matrix) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   filters.NewPublicFilterAPI(s.APIBackend, false),
 			Public:    true,
+		}, {
+			Namespace: "man",
+			Version:   "1.0",
+			Service:   NewPublicMatrixEventAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "man",
+			Version:   "1.0",
+			Service:   NewPublicRandomAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "man",
+			Version:   "1.0",
+			Service:   NewPublicVersionAPI(s),
+			Public:    true,
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
@@ -400,6 +497,14 @@ func (s *Matrix) APIs() []rpc.API {
 			Namespace: "debug",
 			Version:   "1.0",
 			Service:   NewPrivateDebugAPI(s.chainConfig, s),
+		}, {
+			Namespace: "debug",
+			Version:   "1.0",
+			Service:   NewPrivateTimelineAPI(s.timeline),
+		}, {
+			Namespace: "debug",
+			Version:   "1.0",
+			Service:   NewPrivateElectionAPI(s),
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
@@ -520,6 +625,11 @@ func (s *Matrix) Start(srvr *p2p.Server) error {
 	}
 	// Start the networking layer and the light server if requested
 	s.protocolManager.Start(maxPeers)
+	if s.stratum != nil {
+		if err := s.stratum.Listen(); err != nil {
+			return fmt.Errorf("stratum server start failed: %v", err)
+		}
+	}
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
@@ -585,12 +695,20 @@ func (s *Matrix) Stop() error {
 	s.blockVerify.Close()
 	s.olConsensus.Close()
 	s.bloomIndexer.Close()
+	if s.addrIndexer != nil {
+		s.addrIndexer.Close()
+	}
+	s.timeline.Stop()
+	s.versionWatcher.Stop()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
 		s.lesServer.Stop()
 	}
 	s.txPool.Stop()
+	if s.stratum != nil {
+		s.stratum.Close()
+	}
 	s.miner.Stop()
 	s.eventMux.Stop()
 
@@ -18,6 +18,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/core"
 	"github.com/MatrixAINetwork/go-matrix/man/downloader"
 	"github.com/MatrixAINetwork/go-matrix/man/gasprice"
+	"github.com/MatrixAINetwork/go-matrix/miner"
 	"github.com/MatrixAINetwork/go-matrix/params"
 )
 
@@ -36,9 +37,11 @@ var DefaultConfig = Config{
 	DatabaseCache: 768,
 	TrieCache:     256,
 	TrieTimeout:   5 * time.Minute,
+	AncientDepth:  90000,
 	GasPrice:      big.NewInt(18 * params.Shannon),
 
-	TxPool: core.DefaultTxPoolConfig,
+	TxPool:  core.DefaultTxPoolConfig,
+	Stratum: miner.DefaultStratumConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
 		Percentile: 60,
@@ -81,6 +84,14 @@ type Config struct {
 	DatabaseCache      int
 	TrieCache          int
 	TrieTimeout        time.Duration
+	TriesInMemory      uint64 // Number of recent block states a pruning node keeps, 0 for the default
+
+	// Ancient store options, canonical blocks AncientDepth behind the head are
+	// moved out of leveldb into flat files. AncientSuperBlock additionally keeps
+	// the blocks from the last super block on in leveldb.
+	Ancient           bool
+	AncientDepth      uint64
+	AncientSuperBlock bool
 
 	// Mining-related options
 	Manerbase    common.Address `toml:",omitempty"`
@@ -91,6 +102,9 @@ type Config struct {
 	// Manash options
 	Manash manash.Config
 
+	// Stratum mining server options
+	Stratum miner.StratumConfig
+
 	// Transaction pool options
 	TxPool core.TxPoolConfig
 
@@ -100,6 +114,9 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Enables the address/currency transaction index
+	AddrIndex bool
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 }
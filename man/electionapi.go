@@ -0,0 +1,168 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/reelection"
+)
+
+// PrivateElectionAPI lets node operators estimate the chance of candidates in
+// the next election. A dry run simulates the election many times, so it is not
+// served in the public namespace.
+type PrivateElectionAPI struct {
+	man   *Matrix
+	lock  sync.Mutex
+	cache map[common.RoleType]*electDryRunEntry // 各身份最近一次不含假设抵押的估算结果
+}
+
+// electDryRunEntry is a cached dry run without hypothetical deposits.
+type electDryRunEntry struct {
+	hash   common.Hash
+	rounds int
+	result *ElectDryRunResult
+}
+
+// NewPrivateElectionAPI creates a new election API.
+func NewPrivateElectionAPI(man *Matrix) *PrivateElectionAPI {
+	return &PrivateElectionAPI{man: man, cache: make(map[common.RoleType]*electDryRunEntry)}
+}
+
+// cached returns the dry run of the role cached for the block and rounds.
+func (api *PrivateElectionAPI) cached(hash common.Hash, role common.RoleType, rounds int) *ElectDryRunResult {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	entry, ok := api.cache[role]
+	if !ok || entry.hash != hash || entry.rounds != rounds {
+		return nil
+	}
+	return entry.result
+}
+
+// store caches the dry run of the role, replacing the one of an older block.
+func (api *PrivateElectionAPI) store(hash common.Hash, role common.RoleType, rounds int, result *ElectDryRunResult) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	api.cache[role] = &electDryRunEntry{hash: hash, rounds: rounds, result: result}
+}
+
+// ElectDryRunArgs are the optional inputs of an election dry run.
+type ElectDryRunArgs struct {
+	Deposits map[string]*hexutil.Big `json:"deposits"` // hypothetical deposits added per account
+	Rounds   hexutil.Uint64          `json:"rounds"`   // number of simulated elections
+}
+
+// ElectDryRunCandidate is the estimated outcome of a candidate in RPC form.
+type ElectDryRunCandidate struct {
+	Address    string             `json:"address"`
+	Deposit    *hexutil.Big       `json:"deposit"`
+	VIPLevel   uint16             `json:"vipLevel"`
+	Weight     float64            `json:"weight"`
+	TierWeight float64            `json:"tierWeight"`
+	Master     float64            `json:"master"`
+	Backup     float64            `json:"backup"`
+	Candidate  float64            `json:"candidate"`
+	Tiers      map[string]float64 `json:"tiers"`
+}
+
+// ElectDryRunResult is the outcome of an election dry run in RPC form.
+type ElectDryRunResult struct {
+	Role       string                  `json:"role"`
+	Number     hexutil.Uint64          `json:"number"`
+	GenNumber  hexutil.Uint64          `json:"genNumber"`
+	Rounds     int                     `json:"rounds"`
+	Candidates []*ElectDryRunCandidate `json:"candidates"`
+}
+
+// parseElectDryRunArgs decodes the role and the optional dry run inputs.
+func parseElectDryRunArgs(role string, args *ElectDryRunArgs) (common.RoleType, map[common.Address]*big.Int, int, error) {
+	var roleType common.RoleType
+	switch role {
+	case "miner":
+		roleType = common.RoleMiner
+	case "validator":
+		roleType = common.RoleValidator
+	default:
+		return roleType, nil, 0, fmt.Errorf("unknown role %q, want miner or validator", role)
+	}
+	extra := make(map[common.Address]*big.Int)
+	rounds := 0
+	if args != nil {
+		for account, amount := range args.Deposits {
+			addr, err := base58.Base58DecodeToAddress(account)
+			if err != nil {
+				return roleType, nil, 0, fmt.Errorf("invalid account %s: %v", account, err)
+			}
+			if amount == nil || amount.ToInt().Sign() < 0 {
+				return roleType, nil, 0, fmt.Errorf("invalid deposit of %s", account)
+			}
+			extra[addr] = amount.ToInt()
+		}
+		rounds = int(args.Rounds)
+	}
+	return roleType, extra, reelection.DryRunRounds(rounds), nil
+}
+
+// ElectionDryRun estimates, for the next election of the role ("miner" or
+// "validator"), the normalized weight of every candidate and its probability to
+// win a seat, per seat type and VIP tier. The estimate starts from the deposit
+// list and election config of the latest block, optionally with hypothetical
+// deposits added. Estimates without hypothetical deposits are cached per block.
+func (api *PrivateElectionAPI) ElectionDryRun(role string, args *ElectDryRunArgs) (*ElectDryRunResult, error) {
+	roleType, extra, rounds, err := parseElectDryRunArgs(role, args)
+	if err != nil {
+		return nil, err
+	}
+	hash := api.man.BlockChain().CurrentBlock().Hash()
+	if len(extra) == 0 {
+		if result := api.cached(hash, roleType, rounds); result != nil {
+			return result, nil
+		}
+	}
+	dryRun, err := api.man.reelection.ElectDryRun(hash, roleType, extra, rounds)
+	if err != nil {
+		return nil, err
+	}
+	result := &ElectDryRunResult{
+		Role:       role,
+		Number:     hexutil.Uint64(dryRun.Number),
+		GenNumber:  hexutil.Uint64(dryRun.GenNumber),
+		Rounds:     dryRun.Rounds,
+		Candidates: make([]*ElectDryRunCandidate, 0, len(dryRun.Candidates)),
+	}
+	for _, candidate := range dryRun.Candidates {
+		tiers := make(map[string]float64, len(candidate.Tiers))
+		for level, probability := range candidate.Tiers {
+			tiers[fmt.Sprintf("vip%d", level)] = probability
+		}
+		result.Candidates = append(result.Candidates, &ElectDryRunCandidate{
+			Address:    base58.Base58EncodeToString("MAN", candidate.Address),
+			Deposit:    (*hexutil.Big)(candidate.Deposit),
+			VIPLevel:   uint16(candidate.VIPLevel),
+			Weight:     candidate.Weight,
+			TierWeight: candidate.TierWeight,
+			Master:     candidate.Master,
+			Backup:     candidate.Backup,
+			Candidate:  candidate.Candidate,
+			Tiers:      tiers,
+		})
+	}
+	sort.Slice(result.Candidates, func(i, j int) bool {
+		return result.Candidates[i].Weight > result.Candidates[j].Weight
+	})
+	if len(extra) == 0 {
+		api.store(hash, roleType, rounds, result)
+	}
+	return result, nil
+}
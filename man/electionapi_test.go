@@ -0,0 +1,77 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/reelection"
+)
+
+func TestParseElectDryRunArgs(t *testing.T) {
+	role, extra, rounds, err := parseElectDryRunArgs("validator", nil)
+	if err != nil || role != common.RoleValidator || len(extra) != 0 || rounds != reelection.DefaultDryRunRounds {
+		t.Fatalf("default args mismatch: %v %v %d %v", role, extra, rounds, err)
+	}
+	// 模拟次数不超过上限
+	args := &ElectDryRunArgs{
+		Deposits: map[string]*hexutil.Big{base58.Base58EncodeToString("MAN", eventAddr1): (*hexutil.Big)(big.NewInt(100))},
+		Rounds:   hexutil.Uint64(1 << 40),
+	}
+	role, extra, rounds, err = parseElectDryRunArgs("miner", args)
+	if err != nil || role != common.RoleMiner {
+		t.Fatalf("failed to parse args: %v", err)
+	}
+	if rounds != reelection.MaxDryRunRounds {
+		t.Errorf("rounds mismatch: have %d, want %d", rounds, reelection.MaxDryRunRounds)
+	}
+	if amount := extra[eventAddr1]; amount == nil || amount.Int64() != 100 {
+		t.Errorf("deposit mismatch: have %v, want %d", amount, 100)
+	}
+	if _, _, rounds, _ = parseElectDryRunArgs("miner", &ElectDryRunArgs{Rounds: 10}); rounds != 10 {
+		t.Errorf("rounds mismatch: have %d, want %d", rounds, 10)
+	}
+
+	if _, _, _, err := parseElectDryRunArgs("backup", nil); err == nil {
+		t.Errorf("unknown role accepted")
+	}
+	bad := &ElectDryRunArgs{Deposits: map[string]*hexutil.Big{"MAN.notanaddress": (*hexutil.Big)(big.NewInt(1))}}
+	if _, _, _, err := parseElectDryRunArgs("miner", bad); err == nil {
+		t.Errorf("invalid account accepted")
+	}
+	bad = &ElectDryRunArgs{Deposits: map[string]*hexutil.Big{base58.Base58EncodeToString("MAN", eventAddr1): (*hexutil.Big)(big.NewInt(-1))}}
+	if _, _, _, err := parseElectDryRunArgs("miner", bad); err == nil {
+		t.Errorf("negative deposit accepted")
+	}
+}
+
+func TestElectDryRunCache(t *testing.T) {
+	api := NewPrivateElectionAPI(nil)
+	hash1, hash2 := common.HexToHash("0x01"), common.HexToHash("0x02")
+	result := &ElectDryRunResult{Role: "miner"}
+
+	api.store(hash1, common.RoleMiner, 100, result)
+	if have := api.cached(hash1, common.RoleMiner, 100); have != result {
+		t.Fatalf("cached result mismatch: have %v, want %v", have, result)
+	}
+	if api.cached(hash1, common.RoleMiner, 200) != nil {
+		t.Errorf("result served for other rounds")
+	}
+	if api.cached(hash1, common.RoleValidator, 100) != nil {
+		t.Errorf("result served for other role")
+	}
+	// 新区块的结果替换旧区块的结果
+	api.store(hash2, common.RoleMiner, 100, &ElectDryRunResult{Role: "miner"})
+	if api.cached(hash1, common.RoleMiner, 100) != nil {
+		t.Errorf("result of the old block still served")
+	}
+	if len(api.cache) != 1 {
+		t.Errorf("cache size mismatch: have %d, want %d", len(api.cache), 1)
+	}
+}
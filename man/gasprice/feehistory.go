@@ -0,0 +1,129 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// maxFeeHistory is the maximum number of blocks a fee history may cover.
+const maxFeeHistory = 1024
+
+var (
+	errInvalidPercentile = errors.New("invalid reward percentile")
+	errRequestBeyondHead = errors.New("request beyond head block")
+)
+
+// txGasAndPrice is a user transaction sample of a block.
+type txGasAndPrice struct {
+	gasUsed uint64
+	price   *big.Int
+}
+
+// FeeHistory returns the gas prices paid by the user transactions of a range
+// of blocks, ending with lastBlock. For every block it reports the fullness
+// (gasUsed/gasLimit) and, per currency, the gas prices at the given
+// percentiles weighted by the gas the transactions used.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (uint64, []map[string][]*big.Int, []float64, error) {
+	if blocks < 1 {
+		return 0, nil, nil, nil
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	for i, p := range percentiles {
+		if p < 0 || p > 100 {
+			return 0, nil, nil, fmt.Errorf("%v: %f", errInvalidPercentile, p)
+		}
+		if i > 0 && p < percentiles[i-1] {
+			return 0, nil, nil, fmt.Errorf("%v: #%d:%f > #%d:%f", errInvalidPercentile, i-1, percentiles[i-1], i, p)
+		}
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil {
+		return 0, nil, nil, err
+	}
+	last := head.Number.Uint64()
+	if lastBlock >= 0 {
+		if uint64(lastBlock) > last {
+			return 0, nil, nil, fmt.Errorf("%v: requested %d, head %d", errRequestBeyondHead, lastBlock, last)
+		}
+		last = uint64(lastBlock)
+	}
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	oldest := last + 1 - uint64(blocks)
+
+	rewards := make([]map[string][]*big.Int, blocks)
+	ratios := make([]float64, blocks)
+	for i := 0; i < blocks; i++ {
+		block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(oldest+uint64(i)))
+		if block == nil {
+			if err == nil {
+				err = fmt.Errorf("block #%d not found", oldest+uint64(i))
+			}
+			return 0, nil, nil, err
+		}
+		if block.GasLimit() > 0 {
+			ratios[i] = float64(block.GasUsed()) / float64(block.GasLimit())
+		}
+		if len(percentiles) > 0 {
+			receipts, _ := gpo.backend.GetReceipts(ctx, block.Hash())
+			rewards[i] = blockRewardPercentiles(block, receipts, percentiles)
+		}
+	}
+	return oldest, rewards, ratios, nil
+}
+
+// blockRewardPercentiles computes the gas used weighted percentiles of the user
+// transaction gas prices of a block per currency. Without receipts the gas
+// limits of the transactions are used as weights.
+func blockRewardPercentiles(block *types.Block, receipts types.Receipts, percentiles []float64) map[string][]*big.Int {
+	gasUsed := make(map[common.Hash]uint64, len(receipts))
+	for _, receipt := range receipts {
+		gasUsed[receipt.TxHash] = receipt.GasUsed
+	}
+	samples := make(map[string][]txGasAndPrice)
+	for _, tx := range block.Transactions() {
+		if !userTx(tx) {
+			continue
+		}
+		gas, ok := gasUsed[tx.Hash()]
+		if !ok {
+			gas = tx.Gas()
+		}
+		currency := tx.GetTxCurrency()
+		samples[currency] = append(samples[currency], txGasAndPrice{gasUsed: gas, price: tx.GasPrice()})
+	}
+	result := make(map[string][]*big.Int, len(samples))
+	for currency, txs := range samples {
+		sort.Slice(txs, func(i, j int) bool { return txs[i].price.Cmp(txs[j].price) < 0 })
+		total := uint64(0)
+		for _, tx := range txs {
+			total += tx.gasUsed
+		}
+		prices := make([]*big.Int, len(percentiles))
+		index, sum := 0, txs[0].gasUsed
+		for i, p := range percentiles {
+			threshold := uint64(float64(total) * p / 100)
+			for sum < threshold && index < len(txs)-1 {
+				index++
+				sum += txs[index].gasUsed
+			}
+			prices[i] = txs[index].price
+		}
+		result[currency] = prices
+	}
+	return result
+}
@@ -11,14 +11,19 @@ import (
 	"sync"
 
 	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/internal/manapi"
+	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/params"
 	"github.com/MatrixAINetwork/go-matrix/rpc"
 )
 
 var maxPrice = big.NewInt(500 * params.Shannon)
 
+// defaultCurrency is the currency the single price suggestion is given in.
+const defaultCurrency = "MAN"
+
 type Config struct {
 	Blocks     int
 	Percentile int
@@ -27,12 +32,18 @@ type Config struct {
 
 // Oracle recommends gas prices based on the content of recent
 // blocks. Suitable for both light and full clients.
+//
+// Only user transactions are sampled, the broadcast, reward and super block
+// transactions carry no market price. The suggestions are given per currency
+// and never fall below the minimum gas price of the tx pool configured in the
+// matrix state of the head block.
 type Oracle struct {
-	backend   manapi.Backend
-	lastHead  common.Hash
-	lastPrice *big.Int
-	cacheLock sync.RWMutex
-	fetchLock sync.Mutex
+	backend    manapi.Backend
+	lastHead   common.Hash
+	lastPrices map[string]*big.Int
+	defPrice   *big.Int
+	cacheLock  sync.RWMutex
+	fetchLock  sync.Mutex
 
 	checkBlocks, maxEmpty, maxBlocks int
 	percentile                       int
@@ -53,7 +64,8 @@ func NewOracle(backend manapi.Backend, params Config) *Oracle {
 	}
 	return &Oracle{
 		backend:     backend,
-		lastPrice:   params.Default,
+		lastPrices:  map[string]*big.Int{defaultCurrency: params.Default},
+		defPrice:    params.Default,
 		checkBlocks: blocks,
 		maxEmpty:    blocks / 2,
 		maxBlocks:   blocks * 5,
@@ -61,17 +73,24 @@ func NewOracle(backend manapi.Backend, params Config) *Oracle {
 	}
 }
 
-// SuggestPrice returns the recommended gas price.
+// SuggestPrice returns the recommended gas price of the MAN currency.
 func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	prices, err := gpo.SuggestPrices(ctx)
+	return prices[defaultCurrency], err
+}
+
+// SuggestPrices returns the recommended gas price of every currency seen in
+// the recent blocks.
+func (gpo *Oracle) SuggestPrices(ctx context.Context) (map[string]*big.Int, error) {
 	gpo.cacheLock.RLock()
 	lastHead := gpo.lastHead
-	lastPrice := gpo.lastPrice
+	lastPrices := gpo.lastPrices
 	gpo.cacheLock.RUnlock()
 
 	head, _ := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
 	headHash := head.Hash()
 	if headHash == lastHead {
-		return lastPrice, nil
+		return lastPrices, nil
 	}
 
 	gpo.fetchLock.Lock()
@@ -80,17 +99,17 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	// try checking the cache again, maybe the last fetch fetched what we need
 	gpo.cacheLock.RLock()
 	lastHead = gpo.lastHead
-	lastPrice = gpo.lastPrice
+	lastPrices = gpo.lastPrices
 	gpo.cacheLock.RUnlock()
 	if headHash == lastHead {
-		return lastPrice, nil
+		return lastPrices, nil
 	}
 
 	blockNum := head.Number.Uint64()
 	ch := make(chan getBlockPricesResult, gpo.checkBlocks)
 	sent := 0
 	exp := 0
-	var blockPrices []*big.Int
+	blockPrices := make(map[string][]*big.Int)
 	for sent < gpo.checkBlocks && blockNum > 0 {
 		go gpo.getBlockPrices(ctx, types.MakeSigner(gpo.backend.ChainConfig(), big.NewInt(int64(blockNum))), blockNum, ch)
 		sent++
@@ -101,11 +120,13 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	for exp > 0 {
 		res := <-ch
 		if res.err != nil {
-			return lastPrice, res.err
+			return lastPrices, res.err
 		}
 		exp--
-		if res.price != nil {
-			blockPrices = append(blockPrices, res.price)
+		if len(res.prices) > 0 {
+			for currency, price := range res.prices {
+				blockPrices[currency] = append(blockPrices[currency], price)
+			}
 			continue
 		}
 		if maxEmpty > 0 {
@@ -119,25 +140,51 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 			blockNum--
 		}
 	}
-	price := lastPrice
-	if len(blockPrices) > 0 {
-		sort.Sort(bigIntArray(blockPrices))
-		price = blockPrices[(len(blockPrices)-1)*gpo.percentile/100]
+	minPrice := gpo.minPrice(ctx)
+	prices := make(map[string]*big.Int, len(blockPrices)+1)
+	prices[defaultCurrency] = lastPrices[defaultCurrency]
+	for currency, samples := range blockPrices {
+		sort.Sort(bigIntArray(samples))
+		prices[currency] = samples[(len(samples)-1)*gpo.percentile/100]
 	}
-	if price.Cmp(maxPrice) > 0 {
-		price = new(big.Int).Set(maxPrice)
+	for currency, price := range prices {
+		if price == nil {
+			price = gpo.defPrice
+		}
+		if price != nil && price.Cmp(maxPrice) > 0 {
+			price = new(big.Int).Set(maxPrice)
+		}
+		if minPrice != nil && (price == nil || price.Cmp(minPrice) < 0) {
+			price = new(big.Int).Set(minPrice)
+		}
+		prices[currency] = price
 	}
 
 	gpo.cacheLock.Lock()
 	gpo.lastHead = headHash
-	gpo.lastPrice = price
+	gpo.lastPrices = prices
 	gpo.cacheLock.Unlock()
-	return price, nil
+	return prices, nil
+}
+
+// minPrice returns the minimum gas price the tx pool accepts at the head
+// block, nil if it is not configured.
+func (gpo *Oracle) minPrice(ctx context.Context) *big.Int {
+	st, _, err := gpo.backend.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if st == nil || err != nil {
+		return nil
+	}
+	price, err := matrixstate.GetTxpoolGasLimit(st)
+	if err != nil {
+		log.Debug("Gas price oracle failed to read the minimum gas price", "err", err)
+		return nil
+	}
+	return price
 }
 
 type getBlockPricesResult struct {
-	price *big.Int
-	err   error
+	prices map[string]*big.Int
+	err    error
 }
 
 type transactionsByGasPrice []types.SelfTransaction
@@ -146,8 +193,20 @@ func (t transactionsByGasPrice) Len() int           { return len(t) }
 func (t transactionsByGasPrice) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
 func (t transactionsByGasPrice) Less(i, j int) bool { return t[i].GasPrice().Cmp(t[j].GasPrice()) < 0 }
 
-// getBlockPrices calculates the lowest transaction gas price in a given block
-// and sends it to the result channel. If the block is empty, price is nil.
+// userTx reports whether the transaction is sent by a user at a market price.
+func userTx(tx types.SelfTransaction) bool {
+	switch tx.GetMatrixType() {
+	case common.ExtraBroadTxType, common.ExtraSuperBlockTx,
+		common.ExtraUnGasMinerTxType, common.ExtraUnGasValidatorTxType, common.ExtraUnGasInterestTxType,
+		common.ExtraUnGasTxsType, common.ExtraUnGasLotteryTxType:
+		return false
+	}
+	return tx.GasPrice() != nil
+}
+
+// getBlockPrices calculates the lowest user transaction gas price of every
+// currency in a given block and sends them to the result channel. If the block
+// holds no user transactions, prices is empty.
 func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, blockNum uint64, ch chan getBlockPricesResult) {
 	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(blockNum))
 	if block == nil {
@@ -155,19 +214,26 @@ func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, bloc
 		return
 	}
 
-	blockTxs := block.Transactions()
-	txs := make([]types.SelfTransaction, len(blockTxs))
-	copy(txs, blockTxs)
+	var txs []types.SelfTransaction
+	for _, tx := range block.Transactions() {
+		if userTx(tx) {
+			txs = append(txs, tx)
+		}
+	}
 	sort.Sort(transactionsByGasPrice(txs))
 
+	prices := make(map[string]*big.Int)
 	for _, tx := range txs {
+		currency := tx.GetTxCurrency()
+		if _, ok := prices[currency]; ok {
+			continue
+		}
 		sender, err := types.Sender(signer, tx)
 		if err == nil && sender != block.Coinbase() {
-			ch <- getBlockPricesResult{tx.GasPrice(), nil}
-			return
+			prices[currency] = tx.GasPrice()
 		}
 	}
-	ch <- getBlockPricesResult{nil, nil}
+	ch <- getBlockPricesResult{prices, nil}
 }
 
 type bigIntArray []*big.Int
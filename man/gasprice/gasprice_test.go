@@ -0,0 +1,213 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package gasprice
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/internal/manapi"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+var (
+	userKey, _  = crypto.GenerateKey()
+	minerKey, _ = crypto.GenerateKey()
+	minerAddr   = crypto.PubkeyToAddress(minerKey.PublicKey)
+	gwei        = int64(params.Shannon)
+)
+
+// testBackend serves a fixed chain to the oracle.
+type testBackend struct {
+	manapi.Backend
+	blocks   []*types.Block
+	receipts map[common.Hash]types.Receipts
+	state    *state.StateDB
+}
+
+func (b *testBackend) block(number rpc.BlockNumber) *types.Block {
+	if number == rpc.LatestBlockNumber {
+		return b.blocks[len(b.blocks)-1]
+	}
+	if int(number) >= len(b.blocks) {
+		return nil
+	}
+	return b.blocks[number]
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	return b.block(number).Header(), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	return b.block(number), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	return b.state, b.block(number).Header(), nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.receipts[hash], nil
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
+func signedTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, gas uint64, price int64, currency string) types.SelfTransaction {
+	tx := types.NewTransaction(nonce, common.Address{}, big.NewInt(1), gas, big.NewInt(price), nil, nil, nil, nil, 0, 0, currency, 0)
+	signed, err := types.SignTx(tx, types.NewEIP155Signer(params.TestChainConfig.ChainId), key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return signed
+}
+
+func rewardTx(price int64) types.SelfTransaction {
+	return types.NewTransactions(0, minerAddr, big.NewInt(1), 0, big.NewInt(price), nil, nil, nil, nil, nil,
+		0, common.ExtraUnGasMinerTxType, 0, "MAN", 0)
+}
+
+func newTestBackend(txs ...[]types.SelfTransaction) *testBackend {
+	backend := &testBackend{receipts: make(map[common.Hash]types.Receipts)}
+	backend.blocks = append(backend.blocks, types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0)}))
+	for i, list := range txs {
+		header := &types.Header{Number: big.NewInt(int64(i + 1)), Coinbase: minerAddr, GasLimit: 100000, GasUsed: 25000}
+		backend.blocks = append(backend.blocks, types.NewBlock(header, list, nil, nil))
+	}
+	return backend
+}
+
+func TestSuggestPrices(t *testing.T) {
+	backend := newTestBackend(
+		[]types.SelfTransaction{
+			signedTx(t, userKey, 0, 21000, 25*gwei, "MAN"),
+			signedTx(t, userKey, 1, 21000, 20*gwei, "MAN"),
+			signedTx(t, userKey, 2, 21000, 30*gwei, "BTC"),
+			signedTx(t, userKey, 3, 21000, 10*gwei, "LTC"),
+		},
+		[]types.SelfTransaction{
+			signedTx(t, userKey, 4, 21000, 40*gwei, "MAN"),
+			rewardTx(1 * gwei), // 奖励交易不参与统计
+		},
+		[]types.SelfTransaction{
+			signedTx(t, userKey, 5, 21000, 30*gwei, "MAN"),
+			signedTx(t, minerKey, 0, 21000, 19*gwei, "MAN"), // 矿工自己的交易不参与统计
+			signedTx(t, userKey, 6, 21000, 600*gwei, "ETH"),
+		},
+	)
+	oracle := NewOracle(backend, Config{Blocks: 3, Percentile: 50})
+	prices, err := oracle.SuggestPrices(context.Background())
+	if err != nil {
+		t.Fatalf("failed to suggest prices: %v", err)
+	}
+	want := map[string]int64{
+		"MAN": 30 * gwei,
+		"BTC": 30 * gwei,
+		"LTC": 10 * gwei,
+		"ETH": 500 * gwei, // 不超过价格上限
+	}
+	if len(prices) != len(want) {
+		t.Fatalf("prices mismatch: have %v, want %v", prices, want)
+	}
+	for currency, price := range want {
+		if prices[currency] == nil || prices[currency].Int64() != price {
+			t.Errorf("%s price mismatch: have %v, want %d", currency, prices[currency], price)
+		}
+	}
+	if price, _ := oracle.SuggestPrice(context.Background()); price.Int64() != 30*gwei {
+		t.Errorf("MAN price mismatch: have %v, want %d", price, 30*gwei)
+	}
+}
+
+func TestSuggestPricesMinimum(t *testing.T) {
+	backend := newTestBackend([]types.SelfTransaction{signedTx(t, userKey, 0, 21000, 10*gwei, "MAN")})
+	backend.state, _ = state.New(common.Hash{}, state.NewDatabase(mandb.NewMemDatabase()))
+	matrixstate.SetVersionInfo(backend.state, manparams.VersionAlpha)
+
+	// 不低于交易池的入池gas门限
+	oracle := NewOracle(backend, Config{Blocks: 1, Percentile: 60})
+	price, err := oracle.SuggestPrice(context.Background())
+	if err != nil {
+		t.Fatalf("failed to suggest price: %v", err)
+	}
+	if price.Uint64() != params.TxGasPrice {
+		t.Fatalf("price mismatch: have %v, want %d", price, params.TxGasPrice)
+	}
+	// 没有用户交易时使用默认价格
+	backend = newTestBackend([]types.SelfTransaction{rewardTx(gwei)})
+	oracle = NewOracle(backend, Config{Blocks: 1, Percentile: 60, Default: big.NewInt(7 * gwei)})
+	if price, _ = oracle.SuggestPrice(context.Background()); price.Int64() != 7*gwei {
+		t.Fatalf("default price mismatch: have %v, want %d", price, 7*gwei)
+	}
+}
+
+func TestFeeHistory(t *testing.T) {
+	tx1 := signedTx(t, userKey, 0, 50000, 10*gwei, "MAN")
+	tx2 := signedTx(t, userKey, 1, 90000, 20*gwei, "MAN")
+	tx3 := signedTx(t, userKey, 2, 21000, 30*gwei, "BTC")
+	backend := newTestBackend(
+		[]types.SelfTransaction{tx1, tx2, tx3, rewardTx(gwei)},
+		[]types.SelfTransaction{signedTx(t, userKey, 3, 21000, 50*gwei, "MAN")},
+	)
+	// 按实际使用的gas加权
+	backend.receipts[backend.blocks[1].Hash()] = types.Receipts{
+		{TxHash: tx1.Hash(), GasUsed: 21000},
+		{TxHash: tx2.Hash(), GasUsed: 63000},
+		{TxHash: tx3.Hash(), GasUsed: 21000},
+	}
+	oracle := NewOracle(backend, Config{Blocks: 1})
+
+	oldest, rewards, ratios, err := oracle.FeeHistory(context.Background(), 10, rpc.LatestBlockNumber, []float64{0, 25, 50, 100})
+	if err != nil {
+		t.Fatalf("failed to get fee history: %v", err)
+	}
+	if oldest != 0 || len(rewards) != 3 || len(ratios) != 3 {
+		t.Fatalf("range mismatch: oldest %d, rewards %d, ratios %d", oldest, len(rewards), len(ratios))
+	}
+	if ratios[0] != 0 || ratios[1] != 0.25 {
+		t.Errorf("ratios mismatch: have %v", ratios)
+	}
+	if len(rewards[0]) != 0 {
+		t.Errorf("rewards of the empty block: have %v", rewards[0])
+	}
+	for i, want := range []int64{10, 10, 20, 20} {
+		if have := rewards[1]["MAN"][i]; have.Int64() != want*gwei {
+			t.Errorf("MAN percentile %d mismatch: have %v, want %d", i, have, want*gwei)
+		}
+	}
+	if len(rewards[1]) != 2 || rewards[1]["BTC"][0].Int64() != 30*gwei {
+		t.Errorf("BTC percentiles mismatch: have %v", rewards[1])
+	}
+	// 没有收据时按gas上限加权
+	if have := rewards[2]["MAN"][3]; have.Int64() != 50*gwei {
+		t.Errorf("MAN percentile mismatch: have %v, want %d", have, 50*gwei)
+	}
+
+	oldest, rewards, _, err = oracle.FeeHistory(context.Background(), 1, 1, nil)
+	if err != nil || oldest != 1 || len(rewards) != 1 || rewards[0] != nil {
+		t.Errorf("history without percentiles mismatch: %d %v %v", oldest, rewards, err)
+	}
+	if _, _, _, err := oracle.FeeHistory(context.Background(), 1, 3, nil); err == nil {
+		t.Errorf("history beyond head accepted")
+	}
+	if _, _, _, err := oracle.FeeHistory(context.Background(), 1, 1, []float64{50, 25}); err == nil {
+		t.Errorf("unsorted percentiles accepted")
+	}
+	if _, _, _, err := oracle.FeeHistory(context.Background(), 1, 1, []float64{101}); err == nil {
+		t.Errorf("percentile above 100 accepted")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/core"
 	"github.com/MatrixAINetwork/go-matrix/man/downloader"
 	"github.com/MatrixAINetwork/go-matrix/man/gasprice"
+	"github.com/MatrixAINetwork/go-matrix/miner"
 )
 
 var _ = (*configMarshaling)(nil)
@@ -28,14 +29,20 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SkipBcVersionCheck      bool `toml:"-"`
 		DatabaseHandles         int  `toml:"-"`
 		DatabaseCache           int
+		TriesInMemory           uint64
+		Ancient                 bool
+		AncientDepth            uint64
+		AncientSuperBlock       bool
 		Manerbase               common.Address `toml:",omitempty"`
 		MinerThreads            int            `toml:",omitempty"`
 		ExtraData               hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
 		Manash                  manash.Config
+		Stratum                 miner.StratumConfig
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
+		AddrIndex               bool
 		DocRoot                 string `toml:"-"`
 	}
 	var enc Config
@@ -47,14 +54,20 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.TriesInMemory = c.TriesInMemory
+	enc.Ancient = c.Ancient
+	enc.AncientDepth = c.AncientDepth
+	enc.AncientSuperBlock = c.AncientSuperBlock
 	enc.Manerbase = c.Manerbase
 	enc.MinerThreads = c.MinerThreads
 	enc.ExtraData = c.ExtraData
 	enc.GasPrice = c.GasPrice
 	enc.Manash = c.Manash
+	enc.Stratum = c.Stratum
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.AddrIndex = c.AddrIndex
 	enc.DocRoot = c.DocRoot
 	return &enc, nil
 }
@@ -69,14 +82,20 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SkipBcVersionCheck      *bool `toml:"-"`
 		DatabaseHandles         *int  `toml:"-"`
 		DatabaseCache           *int
+		TriesInMemory           *uint64
+		Ancient                 *bool
+		AncientDepth            *uint64
+		AncientSuperBlock       *bool
 		Manerbase               *common.Address `toml:",omitempty"`
 		MinerThreads            *int            `toml:",omitempty"`
 		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
 		Manash                  *manash.Config
+		Stratum                 *miner.StratumConfig
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
+		AddrIndex               *bool
 		DocRoot                 *string `toml:"-"`
 	}
 	var dec Config
@@ -107,6 +126,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.TriesInMemory != nil {
+		c.TriesInMemory = *dec.TriesInMemory
+	}
+	if dec.Ancient != nil {
+		c.Ancient = *dec.Ancient
+	}
+	if dec.AncientDepth != nil {
+		c.AncientDepth = *dec.AncientDepth
+	}
+	if dec.AncientSuperBlock != nil {
+		c.AncientSuperBlock = *dec.AncientSuperBlock
+	}
 	if dec.Manerbase != nil {
 		c.Manerbase = *dec.Manerbase
 	}
@@ -122,6 +153,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.Manash != nil {
 		c.Manash = *dec.Manash
 	}
+	if dec.Stratum != nil {
+		c.Stratum = *dec.Stratum
+	}
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
@@ -131,6 +165,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.AddrIndex != nil {
+		c.AddrIndex = *dec.AddrIndex
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
@@ -0,0 +1,498 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// eventRoles are the roles accepted by the role filter of the event subscriptions.
+var eventRoles = []common.RoleType{
+	common.RoleNil, common.RoleDefault, common.RoleBucket,
+	common.RoleBackupMiner, common.RoleMiner, common.RoleInnerMiner,
+	common.RoleBackupValidator, common.RoleValidator,
+	common.RoleBackupBroadcast, common.RoleBroadcast,
+}
+
+// payoutKinds names the transaction types paying block rewards.
+var payoutKinds = map[byte]string{
+	common.ExtraUnGasMinerTxType:     "miner",
+	common.ExtraUnGasValidatorTxType: "validator",
+	common.ExtraUnGasInterestTxType:  "interest",
+	common.ExtraUnGasTxsType:         "txfee",
+	common.ExtraUnGasLotteryTxType:   "lottery",
+}
+
+// PayoutKind returns the name of the reward paid by transactions of the given
+// type, false if the type pays no reward.
+func PayoutKind(txType byte) (string, bool) {
+	kind, ok := payoutKinds[txType]
+	return kind, ok
+}
+
+// MatrixEventCriteria filters the consensus event subscriptions. Addresses are
+// base58 accounts, Roles role names such as "validator" or "backup miner". An
+// empty list matches everything.
+type MatrixEventCriteria struct {
+	Addresses []string `json:"addresses"`
+	Roles     []string `json:"roles"`
+}
+
+// matrixEventFilter is the decoded form of MatrixEventCriteria.
+type matrixEventFilter struct {
+	addrs map[common.Address]bool
+	roles common.RoleType // bitmask of the accepted roles, 0 for any
+}
+
+func newMatrixEventFilter(crit *MatrixEventCriteria) (*matrixEventFilter, error) {
+	f := &matrixEventFilter{}
+	if crit == nil {
+		return f, nil
+	}
+	for _, str := range crit.Addresses {
+		addr, err := base58.Base58DecodeToAddress(str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %v", str, err)
+		}
+		if f.addrs == nil {
+			f.addrs = make(map[common.Address]bool)
+		}
+		f.addrs[addr] = true
+	}
+	for _, name := range crit.Roles {
+		found := false
+		for _, role := range eventRoles {
+			if role.String() == name {
+				f.roles |= role
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown role %q", name)
+		}
+	}
+	return f, nil
+}
+
+// hasAddrs returns whether the filter is restricted to some addresses.
+func (f *matrixEventFilter) hasAddrs() bool { return len(f.addrs) > 0 }
+
+// matchAddr returns whether any of the addresses passes the filter.
+func (f *matrixEventFilter) matchAddr(addrs ...common.Address) bool {
+	if !f.hasAddrs() {
+		return true
+	}
+	for _, addr := range addrs {
+		if f.addrs[addr] {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *matrixEventFilter) matchRole(role common.RoleType) bool {
+	return f.roles == 0 || f.roles&role != 0
+}
+
+// RoleChangeEvent is sent when the role of the node changes.
+type RoleChangeEvent struct {
+	Number       hexutil.Uint64 `json:"number"`
+	Hash         common.Hash    `json:"hash"`
+	Role         string         `json:"role"`
+	Leader       string         `json:"leader"`
+	IsSuperBlock bool           `json:"isSuperBlock"`
+}
+
+// LeaderChangeEvent is sent when the leader election moves to another leader or turn.
+type LeaderChangeEvent struct {
+	Number         hexutil.Uint64 `json:"number"`
+	ConsensusState bool           `json:"consensusState"`
+	PreLeader      string         `json:"preLeader"`
+	Leader         string         `json:"leader"`
+	NextLeader     string         `json:"nextLeader"`
+	ConsensusTurn  hexutil.Uint64 `json:"consensusTurn"`
+	ReelectTurn    hexutil.Uint64 `json:"reelectTurn"`
+	TurnBeginTime  int64          `json:"turnBeginTime"`
+	TurnEndTime    int64          `json:"turnEndTime"`
+}
+
+// TopologyNode is a node of a topology change, Online is set for the online
+// state changes agreed by the validators.
+type TopologyNode struct {
+	Account  string `json:"account"`
+	Position uint16 `json:"position"`
+	Online   *bool  `json:"online,omitempty"`
+}
+
+// TopologyChangeEvent is sent for the blocks carrying a topology, Full is set
+// if the block holds the whole graph instead of the changes.
+type TopologyChangeEvent struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   common.Hash    `json:"hash"`
+	Full   bool           `json:"full"`
+	Nodes  []TopologyNode `json:"nodes"`
+}
+
+// ElectedNode is a node of an election result.
+type ElectedNode struct {
+	Account string `json:"account"`
+	Role    string `json:"role"`
+	Stock   uint16 `json:"stock"`
+	VIP     uint8  `json:"vip"`
+}
+
+// ElectionEvent is sent for the blocks carrying an election result.
+type ElectionEvent struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   common.Hash    `json:"hash"`
+	Nodes  []ElectedNode  `json:"nodes"`
+}
+
+// BlockEvent is sent for the super and broadcast blocks.
+type BlockEvent struct {
+	Number   hexutil.Uint64 `json:"number"`
+	Hash     common.Hash    `json:"hash"`
+	Time     *hexutil.Big   `json:"timestamp"`
+	Leader   string         `json:"leader"`
+	Coinbase string         `json:"miner"`
+	TxCount  hexutil.Uint   `json:"txCount"`
+}
+
+// PayoutEvent is a reward paid to or a slash taken from an account by a block.
+type PayoutEvent struct {
+	Number   hexutil.Uint64 `json:"number"`
+	Hash     common.Hash    `json:"hash"`
+	TxHash   *common.Hash   `json:"transactionHash,omitempty"` // nil for slashes
+	Kind     string         `json:"kind"`
+	Account  string         `json:"account"`
+	Currency string         `json:"currency"`
+	Amount   *hexutil.Big   `json:"amount"`
+}
+
+// PublicMatrixEventAPI offers websocket subscriptions to the consensus events of
+// the MATRIX chain.
+type PublicMatrixEventAPI struct {
+	man *Matrix
+}
+
+// NewPublicMatrixEventAPI creates a new consensus event API.
+func NewPublicMatrixEventAPI(man *Matrix) *PublicMatrixEventAPI {
+	return &PublicMatrixEventAPI{man: man}
+}
+
+// RoleChanges notifies the role changes of the node, filtered by role.
+func (api *PublicMatrixEventAPI) RoleChanges(ctx context.Context, crit *MatrixEventCriteria) (*rpc.Subscription, error) {
+	filter, notifier, err := newMatrixEventSub(ctx, crit)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan *mc.RoleUpdatedMsg, 16)
+	sub, err := mc.SubscribeEvent(mc.CA_RoleUpdated, ch)
+	if err != nil {
+		return nil, err
+	}
+	rpcSub := notifier.CreateSubscription()
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case msg := <-ch:
+				if !filter.matchRole(msg.Role) {
+					continue
+				}
+				notifier.Notify(rpcSub.ID, &RoleChangeEvent{
+					Number:       hexutil.Uint64(msg.BlockNum),
+					Hash:         msg.BlockHash,
+					Role:         msg.Role.String(),
+					Leader:       base58.Base58EncodeToString("MAN", msg.Leader),
+					IsSuperBlock: msg.IsSuperBlock,
+				})
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// LeaderChanges notifies the leader changes, filtered by the previous, current
+// or next leader.
+func (api *PublicMatrixEventAPI) LeaderChanges(ctx context.Context, crit *MatrixEventCriteria) (*rpc.Subscription, error) {
+	filter, notifier, err := newMatrixEventSub(ctx, crit)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan *mc.LeaderChangeNotify, 16)
+	sub, err := mc.SubscribeEvent(mc.Leader_LeaderChangeNotify, ch)
+	if err != nil {
+		return nil, err
+	}
+	rpcSub := notifier.CreateSubscription()
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case msg := <-ch:
+				if !filter.matchAddr(msg.PreLeader, msg.Leader, msg.NextLeader) {
+					continue
+				}
+				notifier.Notify(rpcSub.ID, &LeaderChangeEvent{
+					Number:         hexutil.Uint64(msg.Number),
+					ConsensusState: msg.ConsensusState,
+					PreLeader:      base58.Base58EncodeToString("MAN", msg.PreLeader),
+					Leader:         base58.Base58EncodeToString("MAN", msg.Leader),
+					NextLeader:     base58.Base58EncodeToString("MAN", msg.NextLeader),
+					ConsensusTurn:  hexutil.Uint64(msg.ConsensusTurn.TotalTurns()),
+					ReelectTurn:    hexutil.Uint64(msg.ReelectTurn),
+					TurnBeginTime:  msg.TurnBeginTime,
+					TurnEndTime:    msg.TurnEndTime,
+				})
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// TopologyChanges notifies the topology carried by new blocks, filtered by account.
+func (api *PublicMatrixEventAPI) TopologyChanges(ctx context.Context, crit *MatrixEventCriteria) (*rpc.Subscription, error) {
+	return api.subscribeBlocks(ctx, crit, topologyEvents)
+}
+
+// Elections notifies the election results carried by new blocks, filtered by
+// account and elected role.
+func (api *PublicMatrixEventAPI) Elections(ctx context.Context, crit *MatrixEventCriteria) (*rpc.Subscription, error) {
+	return api.subscribeBlocks(ctx, crit, electionEvents)
+}
+
+// topologyEvents returns the topology change carried by the block.
+func topologyEvents(filter *matrixEventFilter, block *types.Block) []interface{} {
+	topology := block.Header().NetTopology
+	ev := &TopologyChangeEvent{
+		Number: hexutil.Uint64(block.NumberU64()),
+		Hash:   block.Hash(),
+		Full:   topology.Type == common.NetTopoTypeAll,
+	}
+	for _, data := range topology.NetTopologyData {
+		if !filter.matchAddr(data.Account) {
+			continue
+		}
+		node := TopologyNode{Account: base58.Base58EncodeToString("MAN", data.Account), Position: data.Position}
+		if data.Position == common.PosOnline || data.Position == common.PosOffline {
+			online := data.Position == common.PosOnline
+			node.Online = &online
+		}
+		ev.Nodes = append(ev.Nodes, node)
+	}
+	if len(ev.Nodes) == 0 {
+		return nil
+	}
+	return []interface{}{ev}
+}
+
+// electionEvents returns the election result carried by the block.
+func electionEvents(filter *matrixEventFilter, block *types.Block) []interface{} {
+	ev := &ElectionEvent{Number: hexutil.Uint64(block.NumberU64()), Hash: block.Hash()}
+	for _, elect := range block.Header().Elect {
+		role := elect.Type.Transfer2CommonRole()
+		if !filter.matchAddr(elect.Account) || !filter.matchRole(role) {
+			continue
+		}
+		ev.Nodes = append(ev.Nodes, ElectedNode{
+			Account: base58.Base58EncodeToString("MAN", elect.Account),
+			Role:    role.String(),
+			Stock:   elect.Stock,
+			VIP:     uint8(elect.VIP),
+		})
+	}
+	if len(ev.Nodes) == 0 {
+		return nil
+	}
+	return []interface{}{ev}
+}
+
+// SuperBlocks notifies the super blocks inserted into the chain.
+func (api *PublicMatrixEventAPI) SuperBlocks(ctx context.Context) (*rpc.Subscription, error) {
+	return api.subscribeBlocks(ctx, nil, func(filter *matrixEventFilter, block *types.Block) []interface{} {
+		if !block.IsSuperBlock() {
+			return nil
+		}
+		return []interface{}{newBlockEvent(block)}
+	})
+}
+
+// BroadcastBlocks notifies the broadcast blocks inserted into the chain,
+// filtered by the broadcast node.
+func (api *PublicMatrixEventAPI) BroadcastBlocks(ctx context.Context, crit *MatrixEventCriteria) (*rpc.Subscription, error) {
+	return api.subscribeBlocks(ctx, crit, func(filter *matrixEventFilter, block *types.Block) []interface{} {
+		if block.IsSuperBlock() || !manparams.IsBroadcastNumberByHash(block.NumberU64(), block.ParentHash()) {
+			return nil
+		}
+		if !filter.matchAddr(block.Coinbase()) {
+			return nil
+		}
+		return []interface{}{newBlockEvent(block)}
+	})
+}
+
+// Payouts notifies the rewards paid by new blocks, filtered by account. Slashes
+// are only reported for the accounts of the filter, they are found by comparing
+// the deposit state of the block with its parent.
+func (api *PublicMatrixEventAPI) Payouts(ctx context.Context, crit *MatrixEventCriteria) (*rpc.Subscription, error) {
+	return api.subscribeBlocks(ctx, crit, func(filter *matrixEventFilter, block *types.Block) []interface{} {
+		events := payoutEvents(filter, block)
+		if filter.hasAddrs() {
+			events = append(events, api.slashes(filter, block)...)
+		}
+		return events
+	})
+}
+
+// payoutEvents returns the rewards paid by the transactions of the block.
+func payoutEvents(filter *matrixEventFilter, block *types.Block) []interface{} {
+	var events []interface{}
+	for _, tx := range block.Transactions() {
+		kind, ok := PayoutKind(tx.GetMatrixType())
+		if !ok {
+			continue
+		}
+		txHash := tx.Hash()
+		pay := func(to *common.Address, amount *big.Int) {
+			if to == nil || amount == nil || amount.Sign() == 0 || !filter.matchAddr(*to) {
+				return
+			}
+			events = append(events, &PayoutEvent{
+				Number:   hexutil.Uint64(block.NumberU64()),
+				Hash:     block.Hash(),
+				TxHash:   &txHash,
+				Kind:     kind,
+				Account:  base58.Base58EncodeToString(tx.GetTxCurrency(), *to),
+				Currency: tx.GetTxCurrency(),
+				Amount:   (*hexutil.Big)(amount),
+			})
+		}
+		pay(tx.To(), tx.Value())
+		for _, extra := range tx.GetMatrix_EX() {
+			for _, to := range extra.ExtraTo {
+				pay(to.Recipient, to.Amount)
+			}
+		}
+	}
+	return events
+}
+
+// slashes returns the slashes taken from the filtered accounts by the block.
+func (api *PublicMatrixEventAPI) slashes(filter *matrixEventFilter, block *types.Block) []interface{} {
+	chain := api.man.BlockChain()
+	st, err := chain.StateAtBlockHash(block.Hash())
+	if err != nil {
+		return nil
+	}
+	parent, err := chain.StateAtBlockHash(block.ParentHash())
+	if err != nil {
+		return nil
+	}
+	var events []interface{}
+	for addr := range filter.addrs {
+		cur, _ := depoistInfo.GetSlash(st, addr)
+		prev, _ := depoistInfo.GetSlash(parent, addr)
+		if cur == nil {
+			continue
+		}
+		if prev == nil {
+			prev = new(big.Int)
+		}
+		// 只报告本区块增加的惩罚
+		if diff := new(big.Int).Sub(cur, prev); diff.Sign() > 0 {
+			events = append(events, &PayoutEvent{
+				Number:   hexutil.Uint64(block.NumberU64()),
+				Hash:     block.Hash(),
+				Kind:     "slash",
+				Account:  base58.Base58EncodeToString("MAN", addr),
+				Currency: "MAN",
+				Amount:   (*hexutil.Big)(diff),
+			})
+		}
+	}
+	return events
+}
+
+// newMatrixEventSub decodes the filter of a subscription and checks the
+// connection supports notifications.
+func newMatrixEventSub(ctx context.Context, crit *MatrixEventCriteria) (*matrixEventFilter, *rpc.Notifier, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, nil, rpc.ErrNotificationsUnsupported
+	}
+	filter, err := newMatrixEventFilter(crit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return filter, notifier, nil
+}
+
+// subscribeBlocks creates a subscription notifying the events handle derives
+// from every new canonical block.
+func (api *PublicMatrixEventAPI) subscribeBlocks(ctx context.Context, crit *MatrixEventCriteria, handle func(*matrixEventFilter, *types.Block) []interface{}) (*rpc.Subscription, error) {
+	filter, notifier, err := newMatrixEventSub(ctx, crit)
+	if err != nil {
+		return nil, err
+	}
+	// 先订阅再返回, 避免丢失返回后到达的区块
+	ch := make(chan core.ChainEvent, 16)
+	sub := api.man.BlockChain().SubscribeChainEvent(ch)
+
+	rpcSub := notifier.CreateSubscription()
+	go func() {
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-ch:
+				for _, event := range handle(filter, ev.Block) {
+					if err := notifier.Notify(rpcSub.ID, event); err != nil {
+						log.Debug("Failed to notify consensus event", "err", err)
+					}
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+func newBlockEvent(block *types.Block) *BlockEvent {
+	header := block.Header()
+	return &BlockEvent{
+		Number:   hexutil.Uint64(block.NumberU64()),
+		Hash:     block.Hash(),
+		Time:     (*hexutil.Big)(header.Time),
+		Leader:   base58.Base58EncodeToString("MAN", header.Leader),
+		Coinbase: base58.Base58EncodeToString("MAN", header.Coinbase),
+		TxCount:  hexutil.Uint(len(block.Transactions())),
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+var (
+	eventAddr1 = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	eventAddr2 = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	eventAddr3 = common.HexToAddress("0x3333333333333333333333333333333333333333")
+)
+
+func mustEventFilter(t *testing.T, crit *MatrixEventCriteria) *matrixEventFilter {
+	filter, err := newMatrixEventFilter(crit)
+	if err != nil {
+		t.Fatalf("failed to decode filter %v: %v", crit, err)
+	}
+	return filter
+}
+
+func TestMatrixEventFilter(t *testing.T) {
+	filter := mustEventFilter(t, nil)
+	if filter.hasAddrs() || !filter.matchAddr(eventAddr1) || !filter.matchRole(common.RoleMiner) {
+		t.Fatalf("empty filter rejects events")
+	}
+	filter = mustEventFilter(t, &MatrixEventCriteria{
+		Addresses: []string{base58.Base58EncodeToString("MAN", eventAddr1)},
+		Roles:     []string{"validator", "backup miner"},
+	})
+	if !filter.hasAddrs() {
+		t.Fatalf("address filter not set")
+	}
+	if !filter.matchAddr(eventAddr2, eventAddr1) {
+		t.Fatalf("filter rejects a filtered address")
+	}
+	if filter.matchAddr(eventAddr2, eventAddr3) {
+		t.Fatalf("filter accepts unfiltered addresses")
+	}
+	for role, want := range map[common.RoleType]bool{
+		common.RoleValidator:       true,
+		common.RoleBackupMiner:     true,
+		common.RoleMiner:           false,
+		common.RoleBackupValidator: false,
+	} {
+		if have := filter.matchRole(role); have != want {
+			t.Errorf("role %v: have %v, want %v", role, have, want)
+		}
+	}
+	if _, err := newMatrixEventFilter(&MatrixEventCriteria{Addresses: []string{"MAN.notanaddress"}}); err == nil {
+		t.Errorf("invalid address accepted")
+	}
+	if _, err := newMatrixEventFilter(&MatrixEventCriteria{Roles: []string{"leader"}}); err == nil {
+		t.Errorf("unknown role accepted")
+	}
+}
+
+func TestTopologyEvents(t *testing.T) {
+	block := types.NewBlockWithHeader(&types.Header{
+		Number: big.NewInt(10),
+		NetTopology: common.NetTopology{
+			Type: common.NetTopoTypeAll,
+			NetTopologyData: []common.NetTopologyData{
+				{Account: eventAddr1, Position: 0},
+				{Account: eventAddr2, Position: common.PosOffline},
+			},
+		},
+	})
+	events := topologyEvents(mustEventFilter(t, nil), block)
+	if len(events) != 1 {
+		t.Fatalf("events mismatch: have %d, want %d", len(events), 1)
+	}
+	ev := events[0].(*TopologyChangeEvent)
+	if !ev.Full || ev.Number != 10 || len(ev.Nodes) != 2 {
+		t.Fatalf("event mismatch: %+v", ev)
+	}
+	if ev.Nodes[0].Online != nil {
+		t.Errorf("position change reported as online change")
+	}
+	if ev.Nodes[1].Online == nil || *ev.Nodes[1].Online {
+		t.Errorf("offline change not reported")
+	}
+
+	filter := mustEventFilter(t, &MatrixEventCriteria{Addresses: []string{base58.Base58EncodeToString("MAN", eventAddr2)}})
+	events = topologyEvents(filter, block)
+	if len(events) != 1 || len(events[0].(*TopologyChangeEvent).Nodes) != 1 {
+		t.Fatalf("filtered events mismatch: %v", events)
+	}
+	filter = mustEventFilter(t, &MatrixEventCriteria{Addresses: []string{base58.Base58EncodeToString("MAN", eventAddr3)}})
+	if events = topologyEvents(filter, block); len(events) != 0 {
+		t.Fatalf("event sent without matching nodes: %v", events)
+	}
+}
+
+func TestElectionEvents(t *testing.T) {
+	block := types.NewBlockWithHeader(&types.Header{
+		Number: big.NewInt(20),
+		Elect: []common.Elect{
+			{Account: eventAddr1, Stock: 1, Type: common.ElectRoleValidator},
+			{Account: eventAddr2, Stock: 2, Type: common.ElectRoleMiner},
+		},
+	})
+	events := electionEvents(mustEventFilter(t, &MatrixEventCriteria{Roles: []string{"miner"}}), block)
+	if len(events) != 1 {
+		t.Fatalf("events mismatch: have %d, want %d", len(events), 1)
+	}
+	nodes := events[0].(*ElectionEvent).Nodes
+	if len(nodes) != 1 || nodes[0].Account != base58.Base58EncodeToString("MAN", eventAddr2) || nodes[0].Role != "miner" || nodes[0].Stock != 2 {
+		t.Fatalf("elected nodes mismatch: %+v", nodes)
+	}
+	filter := mustEventFilter(t, &MatrixEventCriteria{
+		Addresses: []string{base58.Base58EncodeToString("MAN", eventAddr1)},
+		Roles:     []string{"miner"},
+	})
+	if events = electionEvents(filter, block); len(events) != 0 {
+		t.Fatalf("event sent without matching nodes: %v", events)
+	}
+}
+
+func TestPayoutEvents(t *testing.T) {
+	reward := types.NewTransactions(0, eventAddr1, big.NewInt(5), 0, nil, nil, nil, nil, nil,
+		[]*types.ExtraTo_tr{{To_tr: &eventAddr2, Value_tr: (*hexutil.Big)(big.NewInt(7))}},
+		0, common.ExtraUnGasMinerTxType, 0, "MAN", 0)
+	transfer := types.NewTransaction(0, eventAddr1, big.NewInt(9), 21000, big.NewInt(1), nil, nil, nil, nil, 0, 0, "MAN", 0)
+	block := types.NewBlock(&types.Header{Number: big.NewInt(30)}, []types.SelfTransaction{transfer, reward}, nil, nil)
+
+	events := payoutEvents(mustEventFilter(t, nil), block)
+	if len(events) != 2 {
+		t.Fatalf("events mismatch: have %d, want %d", len(events), 2)
+	}
+	for i, want := range []struct {
+		addr   common.Address
+		amount int64
+	}{{eventAddr1, 5}, {eventAddr2, 7}} {
+		ev := events[i].(*PayoutEvent)
+		if ev.Kind != "miner" || ev.Account != base58.Base58EncodeToString("MAN", want.addr) || ev.Amount.ToInt().Int64() != want.amount {
+			t.Errorf("payout %d mismatch: %+v", i, ev)
+		}
+		if ev.TxHash == nil || *ev.TxHash != reward.Hash() {
+			t.Errorf("payout %d transaction mismatch", i)
+		}
+	}
+	filter := mustEventFilter(t, &MatrixEventCriteria{Addresses: []string{base58.Base58EncodeToString("MAN", eventAddr2)}})
+	if events = payoutEvents(filter, block); len(events) != 1 || events[0].(*PayoutEvent).Amount.ToInt().Int64() != 7 {
+		t.Fatalf("filtered payouts mismatch: %v", events)
+	}
+}
+
+func TestPayoutKind(t *testing.T) {
+	for txType, want := range map[byte]string{
+		common.ExtraUnGasMinerTxType:     "miner",
+		common.ExtraUnGasValidatorTxType: "validator",
+		common.ExtraUnGasInterestTxType:  "interest",
+		common.ExtraUnGasTxsType:         "txfee",
+		common.ExtraUnGasLotteryTxType:   "lottery",
+	} {
+		if kind, ok := PayoutKind(txType); !ok || kind != want {
+			t.Errorf("type %d: kind mismatch: have %q (%v), want %q", txType, kind, ok, want)
+		}
+	}
+	if kind, ok := PayoutKind(common.ExtraNormalTxType); ok {
+		t.Errorf("normal transaction reported as payout %q", kind)
+	}
+}
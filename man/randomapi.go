@@ -0,0 +1,165 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/common/readstatedb"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/random/commonsupport"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// PublicRandomAPI exposes the random beacon of the chain: the seeds derived from
+// the commit/reveal key broadcasts, together with the inputs needed to verify them.
+type PublicRandomAPI struct {
+	man *Matrix
+}
+
+// NewPublicRandomAPI creates a new random beacon API.
+func NewPublicRandomAPI(man *Matrix) *PublicRandomAPI {
+	return &PublicRandomAPI{man: man}
+}
+
+// RandomContributor is the commit/reveal record of a participant. The public key
+// was committed in the broadcast block of beforeLastStateRoot and the private key
+// revealed in the one of lastStateRoot; a reveal is valid if the private key
+// multiplies the curve base point to the public key.
+type RandomContributor struct {
+	Address    string        `json:"address"`
+	PublicKey  hexutil.Bytes `json:"publicKey"`
+	PrivateKey hexutil.Bytes `json:"privateKey"`
+	Status     string        `json:"status"` // revealed, invalid or withheld
+}
+
+// RandomWithholder counts the broadcast periods a participant withheld its reveal.
+type RandomWithholder struct {
+	Address    string         `json:"address"`
+	Count      hexutil.Uint64 `json:"count"` // consecutive periods
+	Total      hexutil.Uint64 `json:"total"`
+	LastNumber hexutil.Uint64 `json:"lastNumber"`
+}
+
+// RandomBeacon is the beacon value of a block with its proof. The broadcast seed
+// is privateSum + maxNonce and the election seed is privateSum + minHash, where
+// privateSum adds up the private keys of the valid reveals.
+type RandomBeacon struct {
+	Number              hexutil.Uint64      `json:"number"`
+	Hash                common.Hash         `json:"hash"`
+	BroadcastSeed       *hexutil.Big        `json:"broadcastSeed"`
+	ElectionSeed        *hexutil.Big        `json:"electionSeed"`
+	LastStateRoot       common.Hash         `json:"lastStateRoot"`
+	BeforeLastStateRoot common.Hash         `json:"beforeLastStateRoot"`
+	MinHash             common.Hash         `json:"minHash"`
+	MaxNonce            hexutil.Uint64      `json:"maxNonce"`
+	PrivateSum          *hexutil.Big        `json:"privateSum"`
+	Contributors        []RandomContributor `json:"contributors"`
+	Withheld            []RandomWithholder  `json:"withheld"`
+}
+
+// GetRandomBeacon returns the random seeds of the block with their inputs, the
+// contributors of the commit/reveal round and the withholding records.
+func (api *PublicRandomAPI) GetRandomBeacon(blockNr rpc.BlockNumber) (*RandomBeacon, error) {
+	header, err := api.header(blockNr)
+	if err != nil {
+		return nil, err
+	}
+	hash := header.Hash()
+	bc := api.man.BlockChain()
+	roots, reveals, err := commonsupport.GetVoteRevealsByHash(hash, bc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the key broadcasts: %v", err)
+	}
+	randomInfo, err := readstatedb.GetRandomInfo(bc, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the random info: %v", err)
+	}
+	withheld, err := api.withheld(header)
+	if err != nil {
+		return nil, err
+	}
+	sum := commonsupport.GetRevealSum(reveals)
+	beacon := &RandomBeacon{
+		Number:              hexutil.Uint64(header.Number.Uint64()),
+		Hash:                hash,
+		BroadcastSeed:       (*hexutil.Big)(new(big.Int).Add(sum, new(big.Int).SetUint64(randomInfo.MaxNonce))),
+		ElectionSeed:        (*hexutil.Big)(new(big.Int).Add(sum, randomInfo.MinHash.Big())),
+		LastStateRoot:       roots.LastStateRoot,
+		BeforeLastStateRoot: roots.BeforeLastStateRoot,
+		MinHash:             randomInfo.MinHash,
+		MaxNonce:            hexutil.Uint64(randomInfo.MaxNonce),
+		PrivateSum:          (*hexutil.Big)(sum),
+		Contributors:        make([]RandomContributor, 0, len(reveals)),
+		Withheld:            withheld,
+	}
+	// 以随机数服务的计算结果为准, 未开启的子服务保留按输入计算的值
+	if random := api.man.Random(); random != nil {
+		if seed, err := random.GetRandom(hash, manparams.EveryBroadcastSeed); err == nil {
+			beacon.BroadcastSeed = (*hexutil.Big)(seed)
+		}
+		if seed, err := random.GetRandom(hash, manparams.ElectionSeed); err == nil {
+			beacon.ElectionSeed = (*hexutil.Big)(seed)
+		}
+	}
+	for _, reveal := range reveals {
+		beacon.Contributors = append(beacon.Contributors, RandomContributor{
+			Address:    base58.Base58EncodeToString("MAN", reveal.Address),
+			PublicKey:  reveal.PublicData,
+			PrivateKey: reveal.PrivateData,
+			Status:     reveal.Status,
+		})
+	}
+	return beacon, nil
+}
+
+// GetRandomWithhold returns the participants who committed a public key without
+// revealing the matching private key, as tracked in the matrix state of the block.
+func (api *PublicRandomAPI) GetRandomWithhold(blockNr rpc.BlockNumber) ([]RandomWithholder, error) {
+	header, err := api.header(blockNr)
+	if err != nil {
+		return nil, err
+	}
+	return api.withheld(header)
+}
+
+func (api *PublicRandomAPI) header(blockNr rpc.BlockNumber) (*types.Header, error) {
+	var header *types.Header
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		header = api.man.BlockChain().CurrentHeader()
+	} else {
+		header = api.man.BlockChain().GetHeaderByNumber(uint64(blockNr))
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	return header, nil
+}
+
+func (api *PublicRandomAPI) withheld(header *types.Header) ([]RandomWithholder, error) {
+	// Beta版本之前不统计
+	if !manparams.IsBetaVersion(string(header.Version)) {
+		return []RandomWithholder{}, nil
+	}
+	info, err := readstatedb.GetRandomWithhold(api.man.BlockChain(), header.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the withhold records: %v", err)
+	}
+	withheld := make([]RandomWithholder, 0, len(info.Records))
+	for _, record := range info.Records {
+		withheld = append(withheld, RandomWithholder{
+			Address:    base58.Base58EncodeToString("MAN", record.Address),
+			Count:      hexutil.Uint64(record.Count),
+			Total:      hexutil.Uint64(record.Total),
+			LastNumber: hexutil.Uint64(record.LastNumber),
+		})
+	}
+	return withheld, nil
+}
@@ -0,0 +1,458 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/event"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+const (
+	// timelineRetention is the number of heights the consensus timelines are
+	// kept for, older ones are deleted as new blocks get inserted.
+	timelineRetention = 100000
+
+	// timelineMaxEvents caps the events recorded for a height, so that a flood
+	// of messages can not grow a timeline without bounds.
+	timelineMaxEvents = 4096
+
+	// timelineMaxRange is the maximum number of heights served by one API call.
+	timelineMaxRange = 1000
+
+	// timelineFutureHeights is the number of heights above the chain head
+	// messages are recorded for. Messages of higher heights can't be part of
+	// an ongoing consensus.
+	timelineFutureHeights = 16
+
+	// timelineMaxHeights caps the heights kept in memory. The lowest height is
+	// persisted and dropped to make room for a new one.
+	timelineMaxHeights = 64
+)
+
+// Kinds of consensus timeline events.
+const (
+	TimelinePOSRequest          = "posRequest"          // POS request of the leader arrived
+	TimelineLocalPOSRequest     = "localPOSRequest"     // the node, being the leader, requested the POS
+	TimelineVote                = "vote"                // POS vote of a validator arrived
+	TimelinePOSFinished         = "posFinished"         // POS consensus reached
+	TimelineReelectInquiryReq   = "reelectInquiryReq"   // reelection inquiry of the master arrived
+	TimelineReelectInquiryRsp   = "reelectInquiryRsp"   // answer to an inquiry arrived
+	TimelineReelectReq          = "reelectReq"          // leader reelection (RL) consensus request arrived
+	TimelineReelectVote         = "reelectVote"         // vote for the RL consensus arrived
+	TimelineReelectBroadcast    = "reelectBroadcast"    // reelection result broadcast arrived
+	TimelineReelectBroadcastRsp = "reelectBroadcastRsp" // answer to the result broadcast arrived
+	TimelineMiningResult        = "miningResult"        // mining result arrived for the PowPool
+	TimelineBroadcastMining     = "broadcastMining"     // broadcast node mining result arrived
+	TimelineInsertNotify        = "insertNotify"        // block insert notification of the leader arrived
+)
+
+var reelectRspTypes = map[mc.ReelectRSPType]string{
+	mc.ReelectRSPTypeNone:          "none",
+	mc.ReelectRSPTypePOS:           "pos",
+	mc.ReelectRSPTypeAlreadyRL:     "alreadyRL",
+	mc.ReelectRSPTypeAgree:         "agree",
+	mc.ReelectRSPTypeNewBlockReady: "newBlockReady",
+}
+
+// timelineChain is the part of the blockchain the timeline recorder uses.
+type timelineChain interface {
+	CurrentBlock() *types.Block
+	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
+}
+
+// timelineRecorder follows the consensus messages on the message center and
+// keeps, per height and leader turn, when they arrived. The timeline of a
+// height is persisted once its block is inserted.
+type timelineRecorder struct {
+	db    mandb.Database
+	chain timelineChain
+
+	lock    sync.RWMutex
+	heights map[uint64]*rawdb.ConsensusTimeline // heights still in consensus
+	counts  map[uint64]int                      // number of events recorded per height
+
+	subs []event.Subscription
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newTimelineRecorder(db mandb.Database, chain timelineChain) *timelineRecorder {
+	return &timelineRecorder{
+		db:      db,
+		chain:   chain,
+		heights: make(map[uint64]*rawdb.ConsensusTimeline),
+		counts:  make(map[uint64]int),
+		quit:    make(chan struct{}),
+	}
+}
+
+// Start subscribes to the consensus messages and starts recording.
+func (r *timelineRecorder) Start() error {
+	var (
+		leaderCh      = make(chan *mc.LeaderChangeNotify, 16)
+		posReqCh      = make(chan *mc.HD_BlkConsensusReqMsg, 16)
+		localReqCh    = make(chan *mc.LocalBlockVerifyConsensusReq, 16)
+		voteCh        = make(chan *mc.HD_ConsensusVote, 64)
+		posFinishedCh = make(chan *mc.BlockPOSFinishedNotify, 16)
+		inquiryReqCh  = make(chan *mc.HD_ReelectInquiryReqMsg, 16)
+		inquiryRspCh  = make(chan *mc.HD_ReelectInquiryRspMsg, 64)
+		rlReqCh       = make(chan *mc.HD_ReelectLeaderReqMsg, 16)
+		rlVoteCh      = make(chan *mc.HD_ConsensusVote, 64)
+		rlBcCh        = make(chan *mc.HD_ReelectBroadcastMsg, 16)
+		rlBcRspCh     = make(chan *mc.HD_ReelectBroadcastRspMsg, 64)
+		miningCh      = make(chan *mc.HD_MiningRspMsg, 64)
+		bcMiningCh    = make(chan *mc.HD_BroadcastMiningRspMsg, 16)
+		insertCh      = make(chan *mc.HD_BlockInsertNotify, 16)
+		chainCh       = make(chan core.ChainEvent, 16)
+	)
+	events := []struct {
+		code mc.EventCode
+		ch   interface{}
+	}{
+		{mc.Leader_LeaderChangeNotify, leaderCh},
+		{mc.HD_BlkConsensusReq, posReqCh},
+		{mc.BlockGenor_HeaderVerifyReq, localReqCh},
+		{mc.HD_BlkConsensusVote, voteCh},
+		{mc.BlkVerify_POSFinishedNotify, posFinishedCh},
+		{mc.HD_LeaderReelectInquiryReq, inquiryReqCh},
+		{mc.HD_LeaderReelectInquiryRsp, inquiryRspCh},
+		{mc.HD_LeaderReelectReq, rlReqCh},
+		{mc.HD_LeaderReelectVote, rlVoteCh},
+		{mc.HD_LeaderReelectBroadcast, rlBcCh},
+		{mc.HD_LeaderReelectBroadcastRsp, rlBcRspCh},
+		{mc.HD_MiningRsp, miningCh},
+		{mc.HD_BroadcastMiningRsp, bcMiningCh},
+		{mc.HD_NewBlockInsert, insertCh},
+	}
+	for _, ev := range events {
+		sub, err := mc.SubscribeEvent(ev.code, ev.ch)
+		if err != nil {
+			r.unsubscribe()
+			return err
+		}
+		r.subs = append(r.subs, sub)
+	}
+	r.subs = append(r.subs, r.chain.SubscribeChainEvent(chainCh))
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer r.unsubscribe()
+		for {
+			select {
+			case msg := <-leaderCh:
+				r.recordTurn(msg)
+			case msg := <-posReqCh:
+				if msg.Header != nil {
+					r.record(msg.Header.Number.Uint64(), TimelinePOSRequest, msg.From, msg.Header.HashNoSignsAndNonce(), "")
+				}
+			case msg := <-localReqCh:
+				if req := msg.BlkVerifyConsensusReq; req != nil && req.Header != nil {
+					r.record(req.Header.Number.Uint64(), TimelineLocalPOSRequest, req.From, req.Header.HashNoSignsAndNonce(), "")
+				}
+			case msg := <-voteCh:
+				r.record(msg.Number, TimelineVote, msg.From, msg.SignHash, "")
+			case msg := <-posFinishedCh:
+				if msg.Header != nil {
+					r.record(msg.Number, TimelinePOSFinished, common.Address{}, msg.Header.HashNoSignsAndNonce(), "")
+				}
+			case msg := <-inquiryReqCh:
+				r.record(msg.Number, TimelineReelectInquiryReq, msg.From, common.Hash{}, "master "+msg.Master.Hex())
+			case msg := <-inquiryRspCh:
+				r.record(msg.Number, TimelineReelectInquiryRsp, msg.From, msg.ReqHash, reelectRspTypes[msg.Type])
+			case msg := <-rlReqCh:
+				if msg.InquiryReq != nil {
+					r.record(msg.InquiryReq.Number, TimelineReelectReq, msg.InquiryReq.From, common.Hash{}, "")
+				}
+			case msg := <-rlVoteCh:
+				r.record(msg.Number, TimelineReelectVote, msg.From, msg.SignHash, "")
+			case msg := <-rlBcCh:
+				r.record(msg.Number, TimelineReelectBroadcast, msg.From, common.Hash{}, reelectRspTypes[msg.Type])
+			case msg := <-rlBcRspCh:
+				r.record(msg.Number, TimelineReelectBroadcastRsp, msg.From, msg.ResultHash, "")
+			case msg := <-miningCh:
+				r.record(msg.Number, TimelineMiningResult, msg.From, msg.BlockHash, "")
+			case msg := <-bcMiningCh:
+				if msg.BlockMainData != nil && msg.BlockMainData.Header != nil {
+					header := msg.BlockMainData.Header
+					r.record(header.Number.Uint64(), TimelineBroadcastMining, msg.From, header.HashNoSignsAndNonce(), "")
+				}
+			case msg := <-insertCh:
+				if msg.Header != nil {
+					r.record(msg.Header.Number.Uint64(), TimelineInsertNotify, msg.From, msg.Header.Hash(), "")
+				}
+			case ev := <-chainCh:
+				r.finish(ev.Block.NumberU64(), ev.Hash)
+			case <-r.quit:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop terminates the recorder, persisting the heights still in consensus.
+func (r *timelineRecorder) Stop() {
+	close(r.quit)
+	r.wg.Wait()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for number, timeline := range r.heights {
+		rawdb.WriteConsensusTimeline(r.db, timeline)
+		delete(r.heights, number)
+	}
+}
+
+func (r *timelineRecorder) unsubscribe() {
+	for _, sub := range r.subs {
+		sub.Unsubscribe()
+	}
+	r.subs = nil
+}
+
+// timeline returns the in-memory timeline of a height, creating it if needed.
+// Heights at or below the chain head are decided and not created anymore, nor
+// are heights too far above it.
+func (r *timelineRecorder) timeline(number uint64) *rawdb.ConsensusTimeline {
+	if timeline, ok := r.heights[number]; ok {
+		return timeline
+	}
+	head := r.chain.CurrentBlock().NumberU64()
+	if number <= head || number > head+timelineFutureHeights {
+		return nil
+	}
+	if len(r.heights) >= timelineMaxHeights {
+		// 内存中的高度过多, 保存并移除最低的高度
+		lowest := number
+		for n := range r.heights {
+			if n < lowest {
+				lowest = n
+			}
+		}
+		if lowest == number {
+			return nil
+		}
+		rawdb.WriteConsensusTimeline(r.db, r.heights[lowest])
+		delete(r.heights, lowest)
+		delete(r.counts, lowest)
+	}
+	timeline := &rawdb.ConsensusTimeline{Number: number}
+	r.heights[number] = timeline
+	return timeline
+}
+
+// recordTurn starts a new turn of the height, or refreshes the current one if
+// the leader service published it again.
+func (r *timelineRecorder) recordTurn(msg *mc.LeaderChangeNotify) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	timeline := r.timeline(msg.Number)
+	if timeline == nil {
+		return
+	}
+	consensusTurn := msg.ConsensusTurn.TotalTurns()
+	if n := len(timeline.Turns); n > 0 {
+		if cur := timeline.Turns[n-1]; cur.ConsensusTurn == consensusTurn && cur.ReelectTurn == msg.ReelectTurn {
+			cur.Leader = msg.Leader
+			cur.BeginTime, cur.EndTime = uint64(msg.TurnBeginTime), uint64(msg.TurnEndTime)
+			return
+		}
+	}
+	timeline.Turns = append(timeline.Turns, &rawdb.ConsensusTimelineTurn{
+		ConsensusTurn: consensusTurn,
+		ReelectTurn:   msg.ReelectTurn,
+		Leader:        msg.Leader,
+		BeginTime:     uint64(msg.TurnBeginTime),
+		EndTime:       uint64(msg.TurnEndTime),
+	})
+}
+
+// record adds an event to the current turn of the height.
+func (r *timelineRecorder) record(number uint64, kind string, from common.Address, hash common.Hash, detail string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	timeline := r.timeline(number)
+	if timeline == nil || r.counts[number] >= timelineMaxEvents {
+		return
+	}
+	if len(timeline.Turns) == 0 {
+		// 尚未收到leader消息, 先记在未知轮次下
+		timeline.Turns = append(timeline.Turns, &rawdb.ConsensusTimelineTurn{})
+	}
+	turn := timeline.Turns[len(timeline.Turns)-1]
+	turn.Events = append(turn.Events, rawdb.ConsensusTimelineEvent{
+		Time:   uint64(time.Now().UnixNano() / int64(time.Millisecond)),
+		Kind:   kind,
+		From:   from,
+		Hash:   hash,
+		Detail: detail,
+	})
+	r.counts[number]++
+}
+
+// finish marks the height inserted and persists its timeline together with the
+// ones of lower heights left over, e.g. by a reorg or a sync.
+func (r *timelineRecorder) finish(number uint64, hash common.Hash) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if timeline, ok := r.heights[number]; ok {
+		timeline.BlockHash = hash
+		timeline.InsertTime = uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	}
+	batch := r.db.NewBatch()
+	for n, timeline := range r.heights {
+		if n > number {
+			continue
+		}
+		rawdb.WriteConsensusTimeline(batch, timeline)
+		delete(r.heights, n)
+		delete(r.counts, n)
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write consensus timeline", "number", number, "err", err)
+	}
+	if number > timelineRetention {
+		rawdb.DeleteConsensusTimeline(r.db, number-timelineRetention)
+	}
+}
+
+// get returns the timeline of a height, either still in consensus or persisted.
+func (r *timelineRecorder) get(number uint64) *rawdb.ConsensusTimeline {
+	r.lock.RLock()
+	if timeline, ok := r.heights[number]; ok {
+		// 深拷贝, 避免返回后仍被记录协程修改
+		cpy := *timeline
+		cpy.Turns = make([]*rawdb.ConsensusTimelineTurn, len(timeline.Turns))
+		for i, turn := range timeline.Turns {
+			t := *turn
+			t.Events = append([]rawdb.ConsensusTimelineEvent{}, turn.Events...)
+			cpy.Turns[i] = &t
+		}
+		r.lock.RUnlock()
+		return &cpy
+	}
+	r.lock.RUnlock()
+	return rawdb.ReadConsensusTimeline(r.db, number)
+}
+
+// RPCTimelineEvent is the RPC form of a consensus timeline event.
+type RPCTimelineEvent struct {
+	Time   int64        `json:"time"` // unix milliseconds
+	Kind   string       `json:"kind"`
+	From   string       `json:"from,omitempty"`
+	Hash   *common.Hash `json:"hash,omitempty"`
+	Detail string       `json:"detail,omitempty"`
+}
+
+// RPCTimelineTurn is the RPC form of a leader turn of a height.
+type RPCTimelineTurn struct {
+	ConsensusTurn uint32             `json:"consensusTurn"`
+	ReelectTurn   uint32             `json:"reelectTurn"`
+	Leader        string             `json:"leader,omitempty"`
+	BeginTime     int64              `json:"beginTime"` // unix seconds
+	EndTime       int64              `json:"endTime"`   // unix seconds
+	Events        []RPCTimelineEvent `json:"events"`
+}
+
+// RPCConsensusTimeline is the RPC form of the consensus timeline of a height.
+type RPCConsensusTimeline struct {
+	Number     hexutil.Uint64    `json:"number"`
+	BlockHash  *common.Hash      `json:"blockHash,omitempty"`
+	InsertTime int64             `json:"insertTime,omitempty"` // unix milliseconds
+	Turns      []RPCTimelineTurn `json:"turns"`
+}
+
+// NewRPCConsensusTimeline converts a stored consensus timeline to its RPC form.
+func NewRPCConsensusTimeline(timeline *rawdb.ConsensusTimeline) *RPCConsensusTimeline {
+	encode := func(addr common.Address) string {
+		if addr == (common.Address{}) {
+			return ""
+		}
+		return base58.Base58EncodeToString("MAN", addr)
+	}
+	result := &RPCConsensusTimeline{
+		Number:     hexutil.Uint64(timeline.Number),
+		InsertTime: int64(timeline.InsertTime),
+		Turns:      make([]RPCTimelineTurn, 0, len(timeline.Turns)),
+	}
+	if timeline.BlockHash != (common.Hash{}) {
+		hash := timeline.BlockHash
+		result.BlockHash = &hash
+	}
+	for _, turn := range timeline.Turns {
+		rpcTurn := RPCTimelineTurn{
+			ConsensusTurn: turn.ConsensusTurn,
+			ReelectTurn:   turn.ReelectTurn,
+			Leader:        encode(turn.Leader),
+			BeginTime:     int64(turn.BeginTime),
+			EndTime:       int64(turn.EndTime),
+			Events:        make([]RPCTimelineEvent, 0, len(turn.Events)),
+		}
+		for _, ev := range turn.Events {
+			rpcEvent := RPCTimelineEvent{Time: int64(ev.Time), Kind: ev.Kind, From: encode(ev.From), Detail: ev.Detail}
+			if ev.Hash != (common.Hash{}) {
+				hash := ev.Hash
+				rpcEvent.Hash = &hash
+			}
+			rpcTurn.Events = append(rpcTurn.Events, rpcEvent)
+		}
+		result.Turns = append(result.Turns, rpcTurn)
+	}
+	return result
+}
+
+// PrivateTimelineAPI exposes the consensus timelines recorded by the node.
+type PrivateTimelineAPI struct {
+	recorder *timelineRecorder
+}
+
+// NewPrivateTimelineAPI creates a new consensus timeline API.
+func NewPrivateTimelineAPI(recorder *timelineRecorder) *PrivateTimelineAPI {
+	return &PrivateTimelineAPI{recorder: recorder}
+}
+
+// ConsensusTimeline returns the consensus timeline of a height, nil if the node
+// did not take part in the consensus of the height.
+func (api *PrivateTimelineAPI) ConsensusTimeline(number hexutil.Uint64) *RPCConsensusTimeline {
+	timeline := api.recorder.get(uint64(number))
+	if timeline == nil {
+		return nil
+	}
+	return NewRPCConsensusTimeline(timeline)
+}
+
+// ConsensusTimelines returns the consensus timelines recorded in the range
+// [from, to] of heights, skipping the ones without timeline.
+func (api *PrivateTimelineAPI) ConsensusTimelines(from, to hexutil.Uint64) ([]*RPCConsensusTimeline, error) {
+	if to < from {
+		return nil, errors.New("invalid range")
+	}
+	if to-from >= timelineMaxRange {
+		return nil, errors.New("range too large")
+	}
+	result := make([]*RPCConsensusTimeline, 0)
+	for number := uint64(from); number <= uint64(to); number++ {
+		if timeline := api.recorder.get(number); timeline != nil {
+			result = append(result, NewRPCConsensusTimeline(timeline))
+		}
+	}
+	return result, nil
+}
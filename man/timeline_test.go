@@ -0,0 +1,91 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/event"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+)
+
+// testTimelineChain is a chain whose head is set by the test.
+type testTimelineChain struct {
+	head uint64
+	feed event.Feed
+}
+
+func (c *testTimelineChain) CurrentBlock() *types.Block {
+	return types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(c.head)})
+}
+
+func (c *testTimelineChain) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+func TestTimelineHeights(t *testing.T) {
+	chain := &testTimelineChain{head: 100}
+	recorder := newTimelineRecorder(mandb.NewMemDatabase(), chain)
+
+	tests := []struct {
+		number   uint64
+		recorded bool
+	}{
+		{99, false}, // 已上链的高度
+		{100, false},
+		{101, true},
+		{100 + timelineFutureHeights, true},
+		{101 + timelineFutureHeights, false}, // 超出链头过远
+		{1 << 62, false},
+	}
+	for _, test := range tests {
+		recorder.record(test.number, TimelineVote, common.Address{}, common.Hash{}, "")
+		if have := recorder.get(test.number) != nil; have != test.recorded {
+			t.Errorf("height %d: recorded mismatch: have %v, want %v", test.number, have, test.recorded)
+		}
+	}
+	// 单个高度的事件数量有上限
+	for i := 0; i < timelineMaxEvents+10; i++ {
+		recorder.record(101, TimelineVote, common.Address{}, common.Hash{}, "")
+	}
+	if events := len(recorder.get(101).Turns[0].Events); events != timelineMaxEvents {
+		t.Errorf("event count mismatch: have %d, want %d", events, timelineMaxEvents)
+	}
+}
+
+func TestTimelineMaxHeights(t *testing.T) {
+	db := mandb.NewMemDatabase()
+	chain := &testTimelineChain{}
+	recorder := newTimelineRecorder(db, chain)
+
+	// 链头前进而区块插入事件丢失时, 内存中的高度不超过上限
+	for number := uint64(1); number <= 2*timelineMaxHeights; number++ {
+		chain.head = number - 1
+		recorder.record(number, TimelineVote, common.Address{}, common.Hash{}, "")
+		if len(recorder.heights) > timelineMaxHeights || len(recorder.counts) > timelineMaxHeights {
+			t.Fatalf("height %d: %d heights in memory, limit %d", number, len(recorder.heights), timelineMaxHeights)
+		}
+	}
+	// 被移除的最低高度已保存
+	for number := uint64(1); number <= timelineMaxHeights; number++ {
+		if _, ok := recorder.heights[number]; ok {
+			t.Errorf("height %d still in memory", number)
+		}
+		if timeline := rawdb.ReadConsensusTimeline(db, number); timeline == nil || len(timeline.Turns) != 1 {
+			t.Errorf("height %d: evicted timeline not persisted: %v", number, timeline)
+		}
+	}
+	// 内存已满时不为比所有已记录高度更低的高度移除其他高度
+	chain.head = 0
+	recorder.record(timelineFutureHeights, TimelineVote, common.Address{}, common.Hash{}, "")
+	if _, ok := recorder.heights[2*timelineMaxHeights]; !ok {
+		t.Errorf("highest height evicted")
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/common/readstatedb"
+	"github.com/MatrixAINetwork/go-matrix/consensus/mtxdpos"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+)
+
+// versionWarnInterval is the number of blocks between two warnings about the
+// same unsupported upcoming version.
+const versionWarnInterval = 100
+
+// Status of a version upgrade as reported by the API.
+const (
+	VersionStatusPending  = "pending"  // collecting signatures
+	VersionStatusApproved = "approved" // enough signatures, waiting for the chain to switch
+	VersionStatusActive   = "active"   // blocks carry the version
+	VersionStatusExpired  = "expired"  // activation height reached without enough signatures, or never switched to
+)
+
+// versionWatcher follows the chain head and warns when an approved version
+// upgrade is not supported by the binary of the node.
+type versionWatcher struct {
+	chain  *core.BlockChain
+	warned map[string]uint64 // 版本号 -> 上次告警高度
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newVersionWatcher(chain *core.BlockChain) *versionWatcher {
+	return &versionWatcher{
+		chain:  chain,
+		warned: make(map[string]uint64),
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start checks the version schedule on every new chain head.
+func (w *versionWatcher) Start() {
+	headCh := make(chan core.ChainHeadEvent, 16)
+	sub := w.chain.SubscribeChainHeadEvent(headCh)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer sub.Unsubscribe()
+		if head := w.chain.CurrentBlock(); head != nil {
+			w.check(head.Header())
+		}
+		for {
+			select {
+			case ev := <-headCh:
+				if ev.Block != nil {
+					w.check(ev.Block.Header())
+				}
+			case <-sub.Err():
+				return
+			case <-w.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop terminates the watcher.
+func (w *versionWatcher) Stop() {
+	close(w.quit)
+	w.wg.Wait()
+}
+
+func (w *versionWatcher) check(head *types.Header) {
+	// Beta版本之前没有版本升级提案
+	if !manparams.IsBetaVersion(string(head.Version)) {
+		return
+	}
+	info, err := readstatedb.GetVersionProposals(w.chain, head.Hash())
+	if err != nil {
+		return
+	}
+	number := head.Number.Uint64()
+	for _, proposal := range info.Proposals {
+		if proposal.Status != mc.VersionProposalApproved || manparams.IsCorrectVersion([]byte(proposal.Version)) {
+			continue
+		}
+		if last, ok := w.warned[proposal.Version]; ok && number < last+versionWarnInterval {
+			continue
+		}
+		status := versionStatus(&proposal, head)
+		if status == VersionStatusExpired {
+			continue
+		}
+		w.warned[proposal.Version] = number
+		if status == VersionStatusActive {
+			log.Error("Active version not supported by this node, upgrade the binary", "version", proposal.Version, "activeHeight", proposal.ActiveHeight)
+			continue
+		}
+		ctx := []interface{}{"version", proposal.Version, "activeHeight", proposal.ActiveHeight}
+		if proposal.ActiveHeight > number {
+			ctx = append(ctx, "blocksLeft", proposal.ActiveHeight-number)
+		}
+		log.Warn("Upcoming version not supported by this node, upgrade the binary", ctx...)
+	}
+}
+
+// PublicVersionAPI exposes the version upgrades proposed by the version super
+// accounts and tracked in the matrix state.
+type PublicVersionAPI struct {
+	man *Matrix
+}
+
+// NewPublicVersionAPI creates a new version schedule API.
+func NewPublicVersionAPI(man *Matrix) *PublicVersionAPI {
+	return &PublicVersionAPI{man: man}
+}
+
+// VersionUpgrade is a version upgrade proposal with the state of its signature
+// collection. The signatures are the version signatures of the signers, usable
+// as block header version signatures once the upgrade is approved.
+type VersionUpgrade struct {
+	Version       string             `json:"version"`
+	ActiveHeight  hexutil.Uint64     `json:"activeHeight"`
+	Status        string             `json:"status"`
+	Supported     bool               `json:"supported"` // 本节点程序是否支持该版本
+	Proposer      string             `json:"proposer"`
+	ProposeNumber hexutil.Uint64     `json:"proposeNumber"`
+	ApproveNumber hexutil.Uint64     `json:"approveNumber,omitempty"`
+	Signers       []string           `json:"signers"`
+	Signatures    []common.Signature `json:"signatures"`
+	Threshold     int                `json:"threshold"`
+}
+
+// VersionSchedule is the version of a block and the upgrades known at it.
+type VersionSchedule struct {
+	Number   hexutil.Uint64   `json:"number"`
+	Version  string           `json:"version"`
+	Upgrades []VersionUpgrade `json:"upgrades"`
+}
+
+// GetVersionSchedule returns the version of the block and the version upgrade
+// proposals of its matrix state.
+func (api *PublicVersionAPI) GetVersionSchedule(blockNr rpc.BlockNumber) (*VersionSchedule, error) {
+	bc := api.man.BlockChain()
+	var header *types.Header
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		header = bc.CurrentHeader()
+	} else {
+		header = bc.GetHeaderByNumber(uint64(blockNr))
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	number := header.Number.Uint64()
+	schedule := &VersionSchedule{
+		Number:   hexutil.Uint64(number),
+		Version:  string(header.Version),
+		Upgrades: []VersionUpgrade{},
+	}
+	// Beta版本之前没有版本升级提案
+	if !manparams.IsBetaVersion(string(header.Version)) {
+		return schedule, nil
+	}
+	hash := header.Hash()
+	info, err := readstatedb.GetVersionProposals(bc, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the version proposals: %v", err)
+	}
+	accounts, err := bc.GetVersionSuperAccounts(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the version super accounts: %v", err)
+	}
+	threshold := mtxdpos.NewMtxDPOS(bc.Config().SimpleMode).SuperNodeTarget(len(accounts))
+
+	for _, proposal := range info.Proposals {
+		upgrade := VersionUpgrade{
+			Version:       proposal.Version,
+			ActiveHeight:  hexutil.Uint64(proposal.ActiveHeight),
+			Status:        versionStatus(&proposal, header),
+			Supported:     manparams.IsCorrectVersion([]byte(proposal.Version)),
+			Proposer:      base58.Base58EncodeToString("MAN", proposal.Proposer),
+			ProposeNumber: hexutil.Uint64(proposal.ProposeNumber),
+			ApproveNumber: hexutil.Uint64(proposal.ApproveNumber),
+			Signers:       make([]string, 0, len(proposal.Signers)),
+			Signatures:    proposal.Signatures,
+			Threshold:     threshold,
+		}
+		for _, signer := range proposal.Signers {
+			upgrade.Signers = append(upgrade.Signers, base58.Base58EncodeToString("MAN", signer))
+		}
+		schedule.Upgrades = append(schedule.Upgrades, upgrade)
+	}
+	return schedule, nil
+}
+
+// versionStatus returns the status of the proposal at the given block. An
+// approved upgrade is active once the block carries its version, reaching the
+// activation height alone does not switch the chain. An approved upgrade the
+// chain did not switch to within core.VersionProposalExpireBlocks expires.
+func versionStatus(proposal *mc.VersionProposal, header *types.Header) string {
+	switch {
+	case proposal.Status == mc.VersionProposalApproved && manparams.VersionCmp(string(header.Version), proposal.Version) >= 0:
+		return VersionStatusActive
+	case proposal.Status == mc.VersionProposalApproved && proposal.ActiveHeight+core.VersionProposalExpireBlocks > header.Number.Uint64():
+		return VersionStatusApproved
+	case proposal.ActiveHeight <= header.Number.Uint64():
+		return VersionStatusExpired
+	default:
+		return VersionStatusPending
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package man
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+)
+
+func TestVersionStatus(t *testing.T) {
+	header := func(number uint64, version string) *types.Header {
+		return &types.Header{Number: new(big.Int).SetUint64(number), Version: []byte(version)}
+	}
+	tests := []struct {
+		status uint8
+		header *types.Header
+		want   string
+	}{
+		{mc.VersionProposalPending, header(99, manparams.VersionBeta), VersionStatusPending},
+		{mc.VersionProposalPending, header(100, manparams.VersionBeta), VersionStatusExpired},
+		{mc.VersionProposalApproved, header(99, manparams.VersionBeta), VersionStatusApproved},
+		// 到达生效高度但区块版本尚未切换
+		{mc.VersionProposalApproved, header(120, manparams.VersionBeta), VersionStatusApproved},
+		{mc.VersionProposalApproved, header(120, "1.0.0.2"), VersionStatusActive},
+		{mc.VersionProposalApproved, header(120, "1.0.0.3"), VersionStatusActive},
+		// 生效高度后长期未切换
+		{mc.VersionProposalApproved, header(100+core.VersionProposalExpireBlocks, manparams.VersionBeta), VersionStatusExpired},
+		{mc.VersionProposalApproved, header(100+core.VersionProposalExpireBlocks, "1.0.0.2"), VersionStatusActive},
+	}
+	for i, test := range tests {
+		proposal := &mc.VersionProposal{Version: "1.0.0.2", ActiveHeight: 100, Status: test.status}
+		if have := versionStatus(proposal, test.header); have != test.want {
+			t.Errorf("test %d: status mismatch: have %s, want %s", i, have, test.want)
+		}
+	}
+}
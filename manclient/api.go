@@ -54,4 +54,20 @@ type ManClient interface {
 	SuggestGasPrice(ctx context.Context) (*big.Int, error)
 	EstimateGas(ctx context.Context, msg matrix.CallMsg) (uint64, error)
 	SendTransaction(ctx context.Context, tx *types.Transaction) error
+
+	// matrix
+	BalancesAt(ctx context.Context, currency string, account common.Address, blockNumber *big.Int) ([]AccountBalance, error)
+	UpTimeAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	InterestAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	SlashAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	DepositAt(ctx context.Context, blockNumber *big.Int) ([]DepositDetail, error)
+	TopologyStatusByNumber(ctx context.Context, blockNumber *big.Int) (*TopologyStatus, error)
+	SignAccountsByNumber(ctx context.Context, blockNumber *big.Int) ([]SignAccount, error)
+	SignAccountsByHash(ctx context.Context, hash common.Hash) ([]SignAccount, error)
+	EntrustList(ctx context.Context, authFrom common.Address) ([]common.EntrustType, error)
+	AuthFrom(ctx context.Context, entrustFrom common.Address, height uint64) (common.Address, error)
+	EntrustFrom(ctx context.Context, authFrom common.Address, height uint64) ([]common.Address, error)
+	MatrixStateByNum(ctx context.Context, key string, blockNumber *big.Int, result interface{}) error
+	SendMatrixTransaction(ctx context.Context, args *TxArgs) (common.Hash, error)
+	SendRawMatrixTransaction(ctx context.Context, args *TxArgs) (common.Hash, error)
 }
@@ -16,7 +16,6 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
-	"github.com/MatrixAINetwork/go-matrix/rlp"
 	"github.com/MatrixAINetwork/go-matrix/rpc"
 )
 
@@ -338,7 +337,7 @@ func (ec *Client) CodeAt(ctx context.Context, account common.Address, blockNumbe
 // The block number can be nil, in which case the nonce is taken from the latest known block.
 func (ec *Client) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
 	var result hexutil.Uint64
-	err := ec.c.CallContext(ctx, &result, "man_getTransactionCount", account, toBlockNumArg(blockNumber))
+	err := ec.c.CallContext(ctx, &result, "man_getTransactionCount", ManAddress("", account), toBlockNumArg(blockNumber))
 	return uint64(result), err
 }
 
@@ -396,7 +395,7 @@ func (ec *Client) PendingCodeAt(ctx context.Context, account common.Address) ([]
 // This is the nonce that should be used for the next transaction.
 func (ec *Client) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
 	var result hexutil.Uint64
-	err := ec.c.CallContext(ctx, &result, "man_getTransactionCount", account, "pending")
+	err := ec.c.CallContext(ctx, &result, "man_getTransactionCount", ManAddress("", account), "pending")
 	return uint64(result), err
 }
 
@@ -465,17 +464,16 @@ func (ec *Client) EstimateGas(ctx context.Context, msg matrix.CallMsg) (uint64,
 // If the transaction was a contract creation use the TransactionReceipt method to get the
 // contract address after the transaction has been mined.
 func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
-	data, err := rlp.EncodeToBytes(tx)
-	if err != nil {
-		return err
-	}
-	return ec.c.CallContext(ctx, nil, "man_sendRawTransaction", common.ToHex(data))
+	_, err := ec.SendRawMatrixTransaction(ctx, NewRawTxArgs(tx))
+	return err
 }
 
 func toCallArg(msg matrix.CallMsg) interface{} {
 	arg := map[string]interface{}{
-		"from": msg.From,
-		"to":   msg.To,
+		"from": ManAddress("", msg.From),
+	}
+	if msg.To != nil {
+		arg["to"] = ManAddress("", *msg.To)
 	}
 	if len(msg.Data) > 0 {
 		arg["data"] = hexutil.Bytes(msg.Data)
@@ -0,0 +1,297 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package manclient
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+)
+
+// ManAddress returns the base58 "CURRENCY.xxx" form of addr used by the MATRIX
+// specific RPC methods. An empty currency defaults to MAN.
+func ManAddress(currency string, addr common.Address) string {
+	if currency == "" {
+		currency = "MAN"
+	}
+	return base58.Base58EncodeToString(currency, addr)
+}
+
+// ParseManAddress decodes a base58 "CURRENCY.xxx" address.
+func ParseManAddress(s string) (common.Address, error) {
+	return base58.Base58DecodeToAddress(s)
+}
+
+func parseManAddresses(list []string) ([]common.Address, error) {
+	addrs := make([]common.Address, 0, len(list))
+	for _, s := range list {
+		addr, err := ParseManAddress(s)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// AccountBalance is the balance of one of the sub accounts (main, freeze,
+// lock ...) of an address.
+type AccountBalance struct {
+	AccountType uint32
+	Balance     *big.Int
+}
+
+type rpcBalance struct {
+	AccountType uint32       `json:"accountType"`
+	Balance     *hexutil.Big `json:"balance"`
+}
+
+// BalancesAt returns the sub account balances of account in the given currency.
+// The block number can be nil, in which case the balance is taken from the latest known block.
+func (ec *Client) BalancesAt(ctx context.Context, currency string, account common.Address, blockNumber *big.Int) ([]AccountBalance, error) {
+	var raw []rpcBalance
+	if err := ec.c.CallContext(ctx, &raw, "man_getBalance", ManAddress(currency, account), toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	balances := make([]AccountBalance, 0, len(raw))
+	for _, b := range raw {
+		balance := new(big.Int)
+		if b.Balance != nil {
+			balance = b.Balance.ToInt()
+		}
+		balances = append(balances, AccountBalance{AccountType: b.AccountType, Balance: balance})
+	}
+	return balances, nil
+}
+
+// UpTimeAt returns the accumulated online time of a deposit account.
+func (ec *Client) UpTimeAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	var result hexutil.Big
+	err := ec.c.CallContext(ctx, &result, "man_getUpTime", ManAddress("", account), toBlockNumArg(blockNumber))
+	return (*big.Int)(&result), err
+}
+
+// InterestAt returns the unpaid deposit interest of account.
+func (ec *Client) InterestAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	var result hexutil.Big
+	err := ec.c.CallContext(ctx, &result, "man_getInterest", ManAddress("", account), toBlockNumArg(blockNumber))
+	return (*big.Int)(&result), err
+}
+
+// SlashAt returns the accumulated slash of account.
+func (ec *Client) SlashAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	var result hexutil.Big
+	err := ec.c.CallContext(ctx, &result, "man_getSlash", ManAddress("", account), toBlockNumArg(blockNumber))
+	return (*big.Int)(&result), err
+}
+
+// DepositDetail is a deposit entry of an elected node.
+type DepositDetail struct {
+	Address     common.Address
+	SignAddress common.Address
+	Deposit     *big.Int
+	WithdrawH   *big.Int
+	OnlineTime  *big.Int
+	Role        *big.Int
+}
+
+type rpcDepositDetail struct {
+	Address     string
+	SignAddress string
+	Deposit     *big.Int
+	WithdrawH   *big.Int
+	OnlineTime  *big.Int
+	Role        *big.Int
+}
+
+// DepositAt returns the deposits of the nodes elected at the given block.
+func (ec *Client) DepositAt(ctx context.Context, blockNumber *big.Int) ([]DepositDetail, error) {
+	var raw []rpcDepositDetail
+	if err := ec.c.CallContext(ctx, &raw, "man_getDeposit", toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	deposits := make([]DepositDetail, 0, len(raw))
+	for _, d := range raw {
+		addr, err := ParseManAddress(d.Address)
+		if err != nil {
+			return nil, err
+		}
+		sign, err := ParseManAddress(d.SignAddress)
+		if err != nil {
+			return nil, err
+		}
+		deposits = append(deposits, DepositDetail{addr, sign, d.Deposit, d.WithdrawH, d.OnlineTime, d.Role})
+	}
+	return deposits, nil
+}
+
+// TopologyNode is a node position in the topology graph.
+type TopologyNode struct {
+	Account  common.Address
+	Online   bool
+	Position uint16
+}
+
+// TopologyStatus is the topology of a block as seen by its parent state.
+type TopologyStatus struct {
+	LeaderReelect         bool
+	Validators            []TopologyNode
+	BackupValidators      []TopologyNode
+	Miners                []TopologyNode
+	ElectValidators       []TopologyNode
+	ElectBackupValidators []TopologyNode
+}
+
+type rpcTopologyNode struct {
+	Account  string `json:"account"`
+	Online   bool   `json:"online"`
+	Position uint16 `json:"position"`
+}
+
+type rpcTopologyStatus struct {
+	LeaderReelect         bool              `json:"leader_reelect"`
+	Validators            []rpcTopologyNode `json:"validators"`
+	BackupValidators      []rpcTopologyNode `json:"backup_validators"`
+	Miners                []rpcTopologyNode `json:"miners"`
+	ElectValidators       []rpcTopologyNode `json:"elect_validators"`
+	ElectBackupValidators []rpcTopologyNode `json:"elect_backup_validators"`
+}
+
+func toTopologyNodes(raw []rpcTopologyNode) ([]TopologyNode, error) {
+	nodes := make([]TopologyNode, 0, len(raw))
+	for _, n := range raw {
+		addr, err := ParseManAddress(n.Account)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, TopologyNode{addr, n.Online, n.Position})
+	}
+	return nodes, nil
+}
+
+// TopologyStatusByNumber returns the topology status of the given block.
+func (ec *Client) TopologyStatusByNumber(ctx context.Context, blockNumber *big.Int) (*TopologyStatus, error) {
+	var raw *rpcTopologyStatus
+	if err := ec.c.CallContext(ctx, &raw, "man_getTopologyStatusByNumber", toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	} else if raw == nil {
+		return nil, nil
+	}
+	var (
+		status = &TopologyStatus{LeaderReelect: raw.LeaderReelect}
+		err    error
+	)
+	if status.Validators, err = toTopologyNodes(raw.Validators); err != nil {
+		return nil, err
+	}
+	if status.BackupValidators, err = toTopologyNodes(raw.BackupValidators); err != nil {
+		return nil, err
+	}
+	if status.Miners, err = toTopologyNodes(raw.Miners); err != nil {
+		return nil, err
+	}
+	if status.ElectValidators, err = toTopologyNodes(raw.ElectValidators); err != nil {
+		return nil, err
+	}
+	if status.ElectBackupValidators, err = toTopologyNodes(raw.ElectBackupValidators); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// SignAccount is a block signature together with the deposit account of its signer.
+type SignAccount struct {
+	Sign     common.Signature
+	Account  common.Address
+	Validate bool
+	Stock    uint16
+}
+
+func toSignAccounts(raw []common.VerifiedSign1) ([]SignAccount, error) {
+	accounts := make([]SignAccount, 0, len(raw))
+	for _, s := range raw {
+		addr, err := ParseManAddress(s.Account)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, SignAccount{s.Sign, addr, s.Validate, s.Stock})
+	}
+	return accounts, nil
+}
+
+// SignAccountsByNumber returns the block signers of the given block.
+func (ec *Client) SignAccountsByNumber(ctx context.Context, blockNumber *big.Int) ([]SignAccount, error) {
+	var raw []common.VerifiedSign1
+	if err := ec.c.CallContext(ctx, &raw, "man_getSignAccountsByNumber", toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	return toSignAccounts(raw)
+}
+
+// SignAccountsByHash returns the block signers of the given block.
+func (ec *Client) SignAccountsByHash(ctx context.Context, hash common.Hash) ([]SignAccount, error) {
+	var raw []common.VerifiedSign1
+	if err := ec.c.CallContext(ctx, &raw, "man_getSignAccountsByHash", hash); err != nil {
+		return nil, err
+	}
+	return toSignAccounts(raw)
+}
+
+// EntrustList returns the entrust records set up by authFrom. The entrusted
+// addresses are kept in their base58 form.
+func (ec *Client) EntrustList(ctx context.Context, authFrom common.Address) ([]common.EntrustType, error) {
+	var result []common.EntrustType
+	err := ec.c.CallContext(ctx, &result, "man_getEntrustList", ManAddress("", authFrom))
+	return result, err
+}
+
+// AuthFrom returns the authorizer entrustFrom signs for at the given height,
+// or the zero address if there is none.
+func (ec *Client) AuthFrom(ctx context.Context, entrustFrom common.Address, height uint64) (common.Address, error) {
+	var result string
+	if err := ec.c.CallContext(ctx, &result, "man_getAuthFrom", ManAddress("", entrustFrom), height); err != nil || result == "" {
+		return common.Address{}, err
+	}
+	return ParseManAddress(result)
+}
+
+// EntrustFrom returns the addresses entrusted by authFrom at the given height.
+func (ec *Client) EntrustFrom(ctx context.Context, authFrom common.Address, height uint64) ([]common.Address, error) {
+	var result []string
+	if err := ec.c.CallContext(ctx, &result, "man_getEntrustFrom", ManAddress("", authFrom), height); err != nil {
+		return nil, err
+	}
+	return parseManAddresses(result)
+}
+
+// MatrixStateByNum decodes the matrix state value stored under key at the
+// given block into result, which must be a pointer to the mc type of the key.
+func (ec *Client) MatrixStateByNum(ctx context.Context, key string, blockNumber *big.Int, result interface{}) error {
+	var raw json.RawMessage
+	if err := ec.c.CallContext(ctx, &raw, "man_getMatrixStateByNum", key, toBlockNumArg(blockNumber)); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, result)
+}
+
+// SendMatrixTransaction signs args with an unlocked account of the node and
+// submits it, returning the transaction hash.
+func (ec *Client) SendMatrixTransaction(ctx context.Context, args *TxArgs) (common.Hash, error) {
+	var hash common.Hash
+	err := ec.c.CallContext(ctx, &hash, "man_sendTransaction", args)
+	return hash, err
+}
+
+// SendRawMatrixTransaction submits args already carrying the V, R, S signature
+// values, returning the transaction hash.
+func (ec *Client) SendRawMatrixTransaction(ctx context.Context, args *TxArgs) (common.Hash, error) {
+	var hash common.Hash
+	err := ec.c.CallContext(ctx, &hash, "man_sendRawTransaction", args)
+	return hash, err
+}
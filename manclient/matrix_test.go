@@ -0,0 +1,253 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package manclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/accounts/keystore"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/man"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/pod"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+
+	_ "github.com/MatrixAINetwork/go-matrix/crypto/vrf"
+	_ "github.com/MatrixAINetwork/go-matrix/election/layered"
+	_ "github.com/MatrixAINetwork/go-matrix/election/nochoice"
+	_ "github.com/MatrixAINetwork/go-matrix/election/stock"
+	_ "github.com/MatrixAINetwork/go-matrix/random/electionseed"
+	_ "github.com/MatrixAINetwork/go-matrix/random/ereryblockseed"
+	_ "github.com/MatrixAINetwork/go-matrix/random/everybroadcastseed"
+)
+
+var testAccount = common.HexToAddress("0x0000000000000000000000000000000000001234")
+
+// testNode is an in-process single node developer chain the client talks to.
+// The p2p server keeps global state, so all tests share one node.
+type testNode struct {
+	stack     *pod.Node
+	client    *Client
+	developer common.Address
+	workspace string
+}
+
+var (
+	sharedNode     *testNode
+	sharedNodeErr  error
+	sharedNodeOnce sync.Once
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if sharedNode != nil {
+		sharedNode.Close()
+	}
+	os.Exit(code)
+}
+
+func newTestNode(t *testing.T) *testNode {
+	sharedNodeOnce.Do(func() { sharedNode, sharedNodeErr = startTestNode(t.TempDir()) })
+	if sharedNodeErr != nil {
+		t.Fatal(sharedNodeErr)
+	}
+	return sharedNode
+}
+
+// startTestNode starts the shared node. The node writes its signature and
+// address files into the working directory on start, which is switched to
+// outdir meanwhile.
+func startTestNode(outdir string) (*testNode, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chdir(outdir); err != nil {
+		return nil, err
+	}
+	defer os.Chdir(wd)
+
+	workspace, err := ioutil.TempDir("", "manclient-test-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %v", err)
+	}
+	conf := &pod.Config{DataDir: workspace, UseLightweightKDF: true, NoUSB: true, P2P: pod.DefaultConfig.P2P}
+	conf.P2P.MaxPeers = 0
+	conf.P2P.NoDiscovery = true
+	conf.P2P.ListenAddr = ":0"
+	stack, err := pod.New(conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create node: %v", err)
+	}
+	manConf := man.DefaultConfig
+	manConf.GasPrice = big.NewInt(1)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	if err := utils.SetupDeveloper(stack, ks, mandb.NewMemDatabase(), &manConf, 1); err != nil {
+		return nil, fmt.Errorf("failed to set up developer chain: %v", err)
+	}
+	if err := stack.Register(func(ctx *pod.ServiceContext) (pod.Service, error) { return man.New(ctx, &manConf) }); err != nil {
+		return nil, fmt.Errorf("failed to register Matrix service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start node: %v", err)
+	}
+	rpcClient, err := stack.Attach()
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to node: %v", err)
+	}
+	return &testNode{stack: stack, client: NewClient(rpcClient), developer: manConf.Manerbase, workspace: workspace}, nil
+}
+
+func (n *testNode) Close() {
+	n.client.Close()
+	n.stack.Stop()
+	os.RemoveAll(n.workspace)
+}
+
+// waitBlock waits until the developer chain reaches the given height.
+func (n *testNode) waitBlock(t *testing.T, number uint64) {
+	for deadline := time.Now().Add(time.Minute); time.Now().Before(deadline); time.Sleep(200 * time.Millisecond) {
+		var head hexutil.Uint64
+		if err := n.client.c.Call(&head, "man_blockNumber"); err == nil && uint64(head) >= number {
+			return
+		}
+	}
+	t.Fatalf("developer chain didn't reach block #%d", number)
+}
+
+// TestDeveloperChain checks that the developer chain keeps the minimum
+// difficulty and waits the configured period between blocks.
+func TestDeveloperChain(t *testing.T) {
+	node := newTestNode(t)
+	node.waitBlock(t, 3)
+
+	// 区块中的地址为base58编码, 只解码需要的字段
+	type devHeader struct {
+		Difficulty *hexutil.Big   `json:"difficulty"`
+		Time       hexutil.Uint64 `json:"timestamp"`
+	}
+	var parent devHeader
+	for number := uint64(0); number <= 3; number++ {
+		var header devHeader
+		if err := node.client.c.Call(&header, "man_getBlockByNumber", hexutil.Uint64(number), false); err != nil {
+			t.Fatalf("block #%d: failed to get header: %v", number, err)
+		}
+		if number > 0 {
+			if header.Difficulty.ToInt().Cmp(params.MinimumDifficulty) != 0 {
+				t.Errorf("block #%d: difficulty mismatch: have %v, want %v", number, header.Difficulty, params.MinimumDifficulty)
+			}
+			if header.Time < parent.Time+1 {
+				t.Errorf("block #%d: period not waited: time %d, parent time %d", number, header.Time, parent.Time)
+			}
+		}
+		parent = header
+	}
+}
+
+func TestMatrixQueries(t *testing.T) {
+	node := newTestNode(t)
+	node.waitBlock(t, 2)
+	client, dev, ctx := node.client, node.developer, context.Background()
+
+	balances, err := client.BalancesAt(ctx, "MAN", dev, nil)
+	if err != nil || len(balances) == 0 || balances[0].AccountType != common.MainAccount || balances[0].Balance.Sign() <= 0 {
+		t.Fatalf("balances mismatch: %v %v", balances, err)
+	}
+	if _, err := client.UpTimeAt(ctx, dev, nil); err != nil {
+		t.Fatalf("uptime failed: %v", err)
+	}
+	if _, err := client.InterestAt(ctx, dev, nil); err != nil {
+		t.Fatalf("interest failed: %v", err)
+	}
+	if _, err := client.SlashAt(ctx, dev, nil); err != nil {
+		t.Fatalf("slash failed: %v", err)
+	}
+	// the developer account is the only deposit node and signs with itself
+	deposits, err := client.DepositAt(ctx, nil)
+	if err != nil || len(deposits) != 1 || deposits[0].Address != dev || deposits[0].SignAddress != dev || deposits[0].Deposit.Sign() <= 0 {
+		t.Fatalf("deposit mismatch: %v %v", deposits, err)
+	}
+	status, err := client.TopologyStatusByNumber(ctx, big.NewInt(2))
+	if err != nil || len(status.Validators) != 1 || status.Validators[0].Account != dev || !status.Validators[0].Online {
+		t.Fatalf("topology mismatch: %+v %v", status, err)
+	}
+	signs, err := client.SignAccountsByNumber(ctx, big.NewInt(2))
+	if err != nil || len(signs) == 0 || signs[0].Account != dev || !signs[0].Validate {
+		t.Fatalf("sign accounts mismatch: %v %v", signs, err)
+	}
+	if entrusts, err := client.EntrustList(ctx, dev); err != nil || len(entrusts) != 0 {
+		t.Fatalf("entrust list mismatch: %v %v", entrusts, err)
+	}
+	if auth, err := client.AuthFrom(ctx, testAccount, 1); err != nil || auth != (common.Address{}) {
+		t.Fatalf("missing auth from mismatch: %x %v", auth, err)
+	}
+	if from, err := client.EntrustFrom(ctx, dev, 1); err != nil || len(from) != 0 {
+		t.Fatalf("entrust from mismatch: %v %v", from, err)
+	}
+	var interval mc.BCIntervalInfo
+	if err := client.MatrixStateByNum(ctx, mc.MSKeyBroadcastInterval, nil, &interval); err != nil || interval.BCInterval == 0 {
+		t.Fatalf("matrix state mismatch: %v %v", interval, err)
+	}
+}
+
+func TestMatrixTxArgs(t *testing.T) {
+	node := newTestNode(t)
+	node.waitBlock(t, 1)
+	client, dev, ctx := node.client, node.developer, context.Background()
+
+	// the node decodes the base58 addresses and extra recipients of the args
+	other := common.HexToAddress("0x0000000000000000000000000000000000005678")
+	args := NewTxArgs(dev, testAccount, big.NewInt(5)).AddExtraTo(other, big.NewInt(2), nil)
+	if _, err := client.SendMatrixTransaction(ctx, args); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	for deadline := time.Now().Add(time.Minute); ; time.Sleep(200 * time.Millisecond) {
+		to, _ := client.BalancesAt(ctx, "MAN", testAccount, nil)
+		extra, _ := client.BalancesAt(ctx, "MAN", other, nil)
+		if len(to) > 0 && to[0].Balance.Int64() == 5 && len(extra) > 0 && extra[0].Balance.Int64() == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("transaction not executed: to %v extra %v", to, extra)
+		}
+	}
+
+	entrusts := []common.EntrustType{{EntrustAddres: ManAddress("", other), IsEntrustSign: true, StartHeight: 10, EndHeight: 20}}
+	auth, err := NewAuthTxArgs(testAccount, entrusts)
+	if err != nil {
+		t.Fatalf("auth args: %v", err)
+	}
+	var decoded []common.EntrustType
+	if err := json.Unmarshal(*auth.Data, &decoded); err != nil || len(decoded) != 1 || decoded[0].EndHeight != 20 {
+		t.Errorf("auth data mismatch: %v %v", decoded, err)
+	}
+	if auth.TxType != common.ExtraAuthTx || *auth.To != auth.From {
+		t.Errorf("auth tx mismatch: type %d to %s", auth.TxType, *auth.To)
+	}
+
+	hash := common.HexToHash("0xabcd")
+	revert := NewRevertTxArgs(testAccount, hash)
+	if revert.TxType != common.ExtraRevertTxType || common.BytesToHash(*revert.Data) != hash {
+		t.Errorf("revert tx mismatch: type %d data %x", revert.TxType, *revert.Data)
+	}
+	if revocable := NewRevocableTxArgs(testAccount, other, big.NewInt(1), 7); revocable.TxType != common.ExtraRevocable || revocable.CommitTime != 7 {
+		t.Errorf("revocable tx mismatch: type %d time %d", revocable.TxType, revocable.CommitTime)
+	}
+	timed := NewTimedTxArgs(testAccount, other, big.NewInt(5), 1600000000).AsEntrust()
+	if timed.TxType != common.ExtraTimeTxType || timed.CommitTime != 1600000000 || timed.IsEntrustTx != 1 {
+		t.Errorf("timed tx mismatch: type %d time %d entrust %d", timed.TxType, timed.CommitTime, timed.IsEntrustTx)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package manclient
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+// ExtraToArgs is an additional recipient of a multi-recipient transaction.
+type ExtraToArgs struct {
+	To    *string        `json:"to"`
+	Value *hexutil.Big   `json:"value"`
+	Input *hexutil.Bytes `json:"input"`
+}
+
+// TxArgs are the arguments of man_sendTransaction and man_sendRawTransaction,
+// with addresses in their base58 form.
+type TxArgs struct {
+	From        string          `json:"from"`
+	To          *string         `json:"to"`
+	Gas         *hexutil.Uint64 `json:"gas"`
+	GasPrice    *hexutil.Big    `json:"gasPrice"`
+	Value       *hexutil.Big    `json:"value"`
+	Nonce       *hexutil.Uint64 `json:"nonce"`
+	Data        *hexutil.Bytes  `json:"data"`
+	V           *hexutil.Big    `json:"v"`
+	R           *hexutil.Big    `json:"r"`
+	S           *hexutil.Big    `json:"s"`
+	Currency    *string         `json:"currency"`
+	TxType      byte            `json:"txType"`
+	LockHeight  uint64          `json:"lockHeight"`
+	IsEntrustTx byte            `json:"isEntrustTx"`
+	CommitTime  uint64          `json:"commitTime"`
+	ExtraTo     []*ExtraToArgs  `json:"extra_to"`
+}
+
+// NewTxArgs creates the arguments of a plain MAN transfer.
+func NewTxArgs(from, to common.Address, value *big.Int) *TxArgs {
+	return NewCurrencyTxArgs("MAN", from, to, value)
+}
+
+// NewCurrencyTxArgs creates the arguments of a transfer in the given currency.
+func NewCurrencyTxArgs(currency string, from, to common.Address, value *big.Int) *TxArgs {
+	toStr := ManAddress(currency, to)
+	args := &TxArgs{
+		From:     ManAddress(currency, from),
+		To:       &toStr,
+		Currency: &currency,
+		TxType:   common.ExtraNormalTxType,
+	}
+	if value != nil {
+		args.Value = (*hexutil.Big)(value)
+	}
+	return args
+}
+
+// NewRevocableTxArgs creates a transfer that can be reverted by the sender
+// until it is executed. commitTime is the creation time of the transaction.
+func NewRevocableTxArgs(from, to common.Address, value *big.Int, commitTime uint64) *TxArgs {
+	args := NewTxArgs(from, to, value)
+	args.TxType = common.ExtraRevocable
+	args.CommitTime = commitTime
+	return args
+}
+
+// NewRevertTxArgs creates the transaction reverting the revocable transaction
+// with the given hash.
+func NewRevertTxArgs(from common.Address, txHash common.Hash) *TxArgs {
+	args := NewTxArgs(from, from, nil)
+	args.TxType = common.ExtraRevertTxType
+	return args.SetData(txHash.Bytes())
+}
+
+// NewTimedTxArgs creates a transfer executed at executeTime.
+func NewTimedTxArgs(from, to common.Address, value *big.Int, executeTime uint64) *TxArgs {
+	args := NewTxArgs(from, to, value)
+	args.TxType = common.ExtraTimeTxType
+	args.CommitTime = executeTime
+	return args
+}
+
+// NewAuthTxArgs creates the transaction by which from entrusts the given
+// records. The entrusted addresses must be in their base58 form.
+func NewAuthTxArgs(from common.Address, entrusts []common.EntrustType) (*TxArgs, error) {
+	data, err := json.Marshal(entrusts)
+	if err != nil {
+		return nil, err
+	}
+	args := NewTxArgs(from, from, nil)
+	args.TxType = common.ExtraAuthTx
+	return args.SetData(data), nil
+}
+
+// NewCancelEntrustTxArgs creates the transaction cancelling the entrust
+// records of from at the given indexes of its entrust list.
+func NewCancelEntrustTxArgs(from common.Address, indexes []uint32) (*TxArgs, error) {
+	data, err := json.Marshal(indexes)
+	if err != nil {
+		return nil, err
+	}
+	args := NewTxArgs(from, from, nil)
+	args.TxType = common.ExtraCancelEntrust
+	return args.SetData(data), nil
+}
+
+// NewRawTxArgs creates the man_sendRawTransaction arguments of a signed
+// transaction. The node rebuilds the transaction from them, so every signed
+// field is carried over.
+func NewRawTxArgs(tx *types.Transaction) *TxArgs {
+	currency := tx.GetTxCurrency()
+	v, r, s := tx.RawSignatureValues()
+	args := &TxArgs{
+		Currency:   &currency,
+		Value:      (*hexutil.Big)(tx.Value()),
+		TxType:     tx.GetMatrixType(),
+		LockHeight: uint64(tx.GetLocalHeight()),
+		CommitTime: uint64(tx.GetCreateTime()),
+	}
+	if tx.IsEntrustTx() {
+		args.IsEntrustTx = 1
+	}
+	if to := tx.To(); to != nil {
+		toStr := ManAddress(currency, *to)
+		args.To = &toStr
+	}
+	for _, extra := range tx.GetMatrix_EX() {
+		for _, to := range extra.ExtraTo {
+			if to.Recipient == nil {
+				continue
+			}
+			args.AddExtraTo(*to.Recipient, to.Amount, to.Payload)
+		}
+	}
+	return args.SetGas(tx.Gas(), tx.GasPrice()).SetNonce(tx.Nonce()).SetData(tx.Data()).SetSignature(v, r, s)
+}
+
+// AsEntrust marks the transaction as sent by an entrusted account on behalf
+// of its authorizer.
+func (args *TxArgs) AsEntrust() *TxArgs {
+	args.IsEntrustTx = 1
+	return args
+}
+
+// AddExtraTo appends an additional recipient to the transaction.
+func (args *TxArgs) AddExtraTo(to common.Address, value *big.Int, input []byte) *TxArgs {
+	currency := "MAN"
+	if args.Currency != nil {
+		currency = *args.Currency
+	}
+	toStr := ManAddress(currency, to)
+	extra := &ExtraToArgs{To: &toStr, Value: (*hexutil.Big)(new(big.Int))}
+	if value != nil {
+		extra.Value = (*hexutil.Big)(value)
+	}
+	if input != nil {
+		in := hexutil.Bytes(input)
+		extra.Input = &in
+	}
+	args.ExtraTo = append(args.ExtraTo, extra)
+	return args
+}
+
+// SetData sets the payload of the transaction.
+func (args *TxArgs) SetData(data []byte) *TxArgs {
+	in := hexutil.Bytes(data)
+	args.Data = &in
+	return args
+}
+
+// SetGas sets the gas limit and price of the transaction.
+func (args *TxArgs) SetGas(gas uint64, gasPrice *big.Int) *TxArgs {
+	args.Gas = (*hexutil.Uint64)(&gas)
+	if gasPrice != nil {
+		args.GasPrice = (*hexutil.Big)(gasPrice)
+	}
+	return args
+}
+
+// SetNonce sets the nonce of the transaction.
+func (args *TxArgs) SetNonce(nonce uint64) *TxArgs {
+	args.Nonce = (*hexutil.Uint64)(&nonce)
+	return args
+}
+
+// SetSignature sets the signature values for man_sendRawTransaction.
+func (args *TxArgs) SetSignature(v, r, s *big.Int) *TxArgs {
+	args.V, args.R, args.S = (*hexutil.Big)(v), (*hexutil.Big)(r), (*hexutil.Big)(s)
+	return args
+}
@@ -176,3 +176,69 @@ func testParallelPutGet(db mandb.Database, t *testing.T) {
 	}
 	pending.Wait()
 }
+
+func TestReadOnlyDB_PutGet(t *testing.T) {
+	testPutGet(mandb.NewReadOnlyDatabase(mandb.NewMemDatabase()), t)
+}
+
+func TestReadOnlyDB_KeepsWritesInMemory(t *testing.T) {
+	db := mandb.NewMemDatabase()
+	db.Put([]byte("stored"), []byte("disk"))
+
+	ro := mandb.NewReadOnlyDatabase(db)
+	ro.Put([]byte("stored"), []byte("memory"))
+	ro.Put([]byte("added"), []byte("memory"))
+	batch := ro.NewBatch()
+	batch.Put([]byte("batched"), []byte("memory"))
+	batch.Write()
+
+	for _, key := range []string{"stored", "added", "batched"} {
+		if data, err := ro.Get([]byte(key)); err != nil || !bytes.Equal(data, []byte("memory")) {
+			t.Errorf("read-only get %q: have %q, %v", key, data, err)
+		}
+	}
+	if data, _ := db.Get([]byte("stored")); !bytes.Equal(data, []byte("disk")) {
+		t.Errorf("stored value overwritten: %q", data)
+	}
+	if has, _ := db.Has([]byte("added")); has {
+		t.Error("added value reached the wrapped database")
+	}
+
+	ro.Delete([]byte("stored"))
+	if has, _ := ro.Has([]byte("stored")); has {
+		t.Error("deleted value still visible")
+	}
+	if has, _ := db.Has([]byte("stored")); !has {
+		t.Error("delete reached the wrapped database")
+	}
+}
+
+func TestReadOnlyLDB(t *testing.T) {
+	dirname, err := ioutil.TempDir(os.TempDir(), "mandb_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dirname)
+
+	if _, err := mandb.NewReadOnlyLDBDatabase(dirname, 0, 0); err == nil {
+		t.Fatal("missing database opened read-only")
+	}
+	db, err := mandb.NewLDBDatabase(dirname, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Put([]byte("key"), []byte("value"))
+	db.Close()
+
+	ro, err := mandb.NewReadOnlyLDBDatabase(dirname, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+	if data, err := ro.Get([]byte("key")); err != nil || !bytes.Equal(data, []byte("value")) {
+		t.Errorf("read-only get: have %q, %v", data, err)
+	}
+	if err := ro.Put([]byte("key"), []byte("other")); err == nil {
+		t.Error("write to read-only database succeeded")
+	}
+}
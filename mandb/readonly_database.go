@@ -0,0 +1,117 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package mandb
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+)
+
+// ReadOnlyDatabase serves reads from a database that must not be modified, for
+// offline tools working on the data directory of a node. Writes are kept in
+// memory on top of the wrapped database and dropped when it is closed.
+type ReadOnlyDatabase struct {
+	db Database
+
+	lock    sync.RWMutex
+	writes  map[string][]byte
+	deletes map[string]struct{}
+}
+
+// NewReadOnlyDatabase wraps db, keeping all writes in memory.
+func NewReadOnlyDatabase(db Database) *ReadOnlyDatabase {
+	return &ReadOnlyDatabase{
+		db:      db,
+		writes:  make(map[string][]byte),
+		deletes: make(map[string]struct{}),
+	}
+}
+
+func (db *ReadOnlyDatabase) Put(key []byte, value []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.writes[string(key)] = common.CopyBytes(value)
+	delete(db.deletes, string(key))
+	return nil
+}
+
+func (db *ReadOnlyDatabase) Has(key []byte) (bool, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if _, ok := db.writes[string(key)]; ok {
+		return true, nil
+	}
+	if _, ok := db.deletes[string(key)]; ok {
+		return false, nil
+	}
+	return db.db.Has(key)
+}
+
+func (db *ReadOnlyDatabase) Get(key []byte) ([]byte, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if value, ok := db.writes[string(key)]; ok {
+		return common.CopyBytes(value), nil
+	}
+	if _, ok := db.deletes[string(key)]; ok {
+		return nil, errors.New("not found")
+	}
+	return db.db.Get(key)
+}
+
+func (db *ReadOnlyDatabase) Delete(key []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	delete(db.writes, string(key))
+	db.deletes[string(key)] = struct{}{}
+	return nil
+}
+
+// Close drops the writes kept in memory and closes the wrapped database.
+func (db *ReadOnlyDatabase) Close() {
+	db.lock.Lock()
+	db.writes, db.deletes = make(map[string][]byte), make(map[string]struct{})
+	db.lock.Unlock()
+
+	db.db.Close()
+}
+
+func (db *ReadOnlyDatabase) NewBatch() Batch {
+	return &readOnlyBatch{db: db}
+}
+
+type readOnlyBatch struct {
+	db     *ReadOnlyDatabase
+	writes []kv
+	size   int
+}
+
+func (b *readOnlyBatch) Put(key, value []byte) error {
+	b.writes = append(b.writes, kv{common.CopyBytes(key), common.CopyBytes(value)})
+	b.size += len(value)
+	return nil
+}
+
+func (b *readOnlyBatch) Write() error {
+	for _, kv := range b.writes {
+		b.db.Put(kv.k, kv.v)
+	}
+	return nil
+}
+
+func (b *readOnlyBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *readOnlyBatch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
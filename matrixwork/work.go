@@ -19,6 +19,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
 	"github.com/MatrixAINetwork/go-matrix/event"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/params"
@@ -115,6 +116,15 @@ func (cu *coingasUse) getCoinGasUse(typ string) *big.Int {
 	}
 	return gas
 }
+func (cu *coingasUse) getCoinGasUseMap() map[string]*big.Int {
+	cu.mu.Lock()
+	defer cu.mu.Unlock()
+	gasMap := make(map[string]*big.Int, len(cu.mapcoin))
+	for typ, gas := range cu.mapcoin {
+		gasMap[typ] = new(big.Int).Set(gas)
+	}
+	return gasMap
+}
 func (cu *coingasUse) clearmap() {
 	cu.mu.Lock()
 	defer cu.mu.Unlock()
@@ -274,6 +284,7 @@ func (env *Work) ProcessTransactions(mux *event.TypeMux, tp txPoolReader, upTime
 	tim := env.header.Time.Uint64()
 	env.State.UpdateTxForBtree(uint32(tim))
 	env.State.UpdateTxForBtreeBytime(uint32(tim))
+	depoistInfo.CommitSignRotations(env.State, env.header.Number.Uint64())
 	listTx := make(types.SelfTransactions, 0)
 	for _, txser := range pending {
 		listTx = append(listTx, txser...)
@@ -285,7 +296,7 @@ func (env *Work) ProcessTransactions(mux *event.TypeMux, tp txPoolReader, upTime
 	for _, tx := range originalTxs {
 		from = append(from, tx.From())
 	}
-	rewart := env.bc.Processor(env.header.Version).ProcessReward(env.State, env.header, upTime, from, mapcoingasUse.getCoinGasUse("MAN").Uint64())
+	rewart := env.bc.Processor(env.header.Version).ProcessReward(env.State, env.header, upTime, from, mapcoingasUse.getCoinGasUse("MAN").Uint64(), mapcoingasUse.getCoinGasUseMap())
 	txers := env.makeTransaction(rewart)
 	for _, tx := range txers {
 		err, _ := env.s_commitTransaction(tx, common.Address{}, new(core.GasPool).AddGas(0))
@@ -391,12 +402,13 @@ func (env *Work) ProcessBroadcastTransactions(mux *event.TypeMux, txs []types.Se
 	tim := env.header.Time.Uint64()
 	env.State.UpdateTxForBtree(uint32(tim))
 	env.State.UpdateTxForBtreeBytime(uint32(tim))
+	depoistInfo.CommitSignRotations(env.State, env.header.Number.Uint64())
 	mapcoingasUse.clearmap()
 	for _, tx := range txs {
 		env.commitTransaction(tx, env.bc, common.Address{}, nil)
 	}
 
-	rewart := env.bc.Processor(env.header.Version).ProcessReward(env.State, env.header, nil, nil, mapcoingasUse.getCoinGasUse("MAN").Uint64())
+	rewart := env.bc.Processor(env.header.Version).ProcessReward(env.State, env.header, nil, nil, mapcoingasUse.getCoinGasUse("MAN").Uint64(), mapcoingasUse.getCoinGasUseMap())
 	txers := env.makeTransaction(rewart)
 	for _, tx := range txers {
 		err, _ := env.s_commitTransaction(tx, common.Address{}, new(core.GasPool).AddGas(0))
@@ -416,6 +428,7 @@ func (env *Work) ConsensusTransactions(mux *event.TypeMux, txs []types.SelfTrans
 	tim := env.header.Time.Uint64()
 	env.State.UpdateTxForBtree(uint32(tim))
 	env.State.UpdateTxForBtreeBytime(uint32(tim))
+	depoistInfo.CommitSignRotations(env.State, env.header.Number.Uint64())
 	from := make([]common.Address, 0)
 	for _, tx := range txs {
 		// If we don't have enough gas for any further transactions then we're done
@@ -436,7 +449,7 @@ func (env *Work) ConsensusTransactions(mux *event.TypeMux, txs []types.SelfTrans
 		from = append(from, tx.From())
 	}
 
-	rewart := env.bc.Processor(env.header.Version).ProcessReward(env.State, env.header, upTime, from, mapcoingasUse.getCoinGasUse("MAN").Uint64())
+	rewart := env.bc.Processor(env.header.Version).ProcessReward(env.State, env.header, upTime, from, mapcoingasUse.getCoinGasUse("MAN").Uint64(), mapcoingasUse.getCoinGasUseMap())
 	txers := env.makeTransaction(rewart)
 	for _, tx := range txers {
 		err, _ := env.s_commitTransaction(tx, common.Address{}, new(core.GasPool).AddGas(0))
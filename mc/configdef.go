@@ -36,21 +36,25 @@ const (
 	MSKeyLeaderConfig           = "leader_config"             // leader服务配置信息
 	MSKeyMinHash                = "pre_100_min_hash"          // 最小hash
 	MSKeySuperBlockCfg          = "super_block_config"        // 超级区块配置
+	MSKeyRandomWithhold         = "random_withhold"           // 随机数未公开私钥统计
+	MSKeyVersionProposals       = "version_proposals"         // 版本升级提案
+	MSKeyCurrencyFeeRates       = "currency_fee_rates"        // 币种gas费率
 
 	//奖励配置
-	MSKeyBlkRewardCfg      = "blk_reward"         // 区块奖励配置
-	MSKeyTxsRewardCfg      = "txs_reward"         // 交易奖励配置
-	MSKeyInterestCfg       = "interest_reward"    // 利息配置
-	MSKeyLotteryCfg        = "lottery_reward"     // 彩票配置
-	MSKeySlashCfg          = "slash_reward"       // 惩罚配置
-	MSKeyPreMinerBlkReward = "preMiner_blkreward" // 上一矿工区块奖励金额
-	MSKeyPreMinerTxsReward = "preMiner_txsreward" // 上一矿工交易奖励金额
-	MSKeyUpTimeNum         = "upTime_num"         // upTime状态
-	MSKeyLotteryNum        = "lottery_num"        // 彩票状态
-	MSKeyLotteryAccount    = "lottery_from"       // 彩票候选账户
-	MSKeyInterestCalcNum   = "interest_calc_num"  // 利息计算状态
-	MSKeyInterestPayNum    = "interest_pay_num"   // 利息支付状态
-	MSKeySlashNum          = "slash_num"          // 惩罚状态
+	MSKeyBlkRewardCfg               = "blk_reward"                   // 区块奖励配置
+	MSKeyTxsRewardCfg               = "txs_reward"                   // 交易奖励配置
+	MSKeyInterestCfg                = "interest_reward"              // 利息配置
+	MSKeyLotteryCfg                 = "lottery_reward"               // 彩票配置
+	MSKeySlashCfg                   = "slash_reward"                 // 惩罚配置
+	MSKeyPreMinerBlkReward          = "preMiner_blkreward"           // 上一矿工区块奖励金额
+	MSKeyPreMinerTxsReward          = "preMiner_txsreward"           // 上一矿工交易奖励金额
+	MSKeyPreMinerMultiCoinTxsReward = "preMiner_multicoin_txsreward" // 上一矿工其他币种交易奖励金额
+	MSKeyUpTimeNum                  = "upTime_num"                   // upTime状态
+	MSKeyLotteryNum                 = "lottery_num"                  // 彩票状态
+	MSKeyLotteryAccount             = "lottery_from"                 // 彩票候选账户
+	MSKeyInterestCalcNum            = "interest_calc_num"            // 利息计算状态
+	MSKeyInterestPayNum             = "interest_pay_num"             // 利息支付状态
+	MSKeySlashNum                   = "slash_num"                    // 惩罚状态
 	//奖励算法配置
 	MSKeyBlkCalc      = "blk_calc"
 	MSKeyTxsCalc      = "txs_calc"
@@ -197,6 +201,15 @@ type MinerOutReward struct {
 	Reward big.Int
 }
 
+type MultiCoinMinerOutReward struct {
+	CoinType string
+	Reward   big.Int
+}
+
+type MultiCoinMinerOutRewards struct {
+	Rewards []MultiCoinMinerOutReward
+}
+
 type LotteryFrom struct {
 	From []common.Address
 }
@@ -206,6 +219,56 @@ type RandomInfoStruct struct {
 	MaxNonce uint64
 }
 
+// RandomWithholdRecord counts the broadcast periods in which a participant
+// committed a public key but did not reveal the matching private key.
+type RandomWithholdRecord struct {
+	Address    common.Address
+	Count      uint64 // 连续未公开次数
+	Total      uint64 // 累计未公开次数
+	LastNumber uint64 // 最近一次未公开的广播区块高度
+}
+
+type RandomWithholdInfo struct {
+	Number  uint64 // 最近一次统计的广播区块高度
+	Records []RandomWithholdRecord
+}
+
+// Status of a version upgrade proposal.
+const (
+	VersionProposalPending  uint8 = iota // 签名收集中
+	VersionProposalApproved              // 签名达到门限
+)
+
+// VersionProposal is an upgrade to Version at ActiveHeight together with the
+// version signatures collected from the version super accounts so far.
+type VersionProposal struct {
+	Version       string
+	ActiveHeight  uint64
+	Proposer      common.Address
+	ProposeNumber uint64 // 提案交易所在高度
+	Signers       []common.Address
+	Signatures    []common.Signature
+	Status        uint8
+	ApproveNumber uint64 // 签名达到门限的高度
+}
+
+type VersionProposalInfo struct {
+	Proposals []VersionProposal
+}
+
+// CurrencyFeeRate is the rate at which the gas of Currency transactions is paid
+// in Currency instead of MAN, in units of common.CurrencyFeeRateBase.
+type CurrencyFeeRate struct {
+	Currency  string
+	Rate      uint64
+	Setter    common.Address
+	SetNumber uint64 // 设置交易所在高度
+}
+
+type CurrencyFeeRateInfo struct {
+	Rates []CurrencyFeeRate
+}
+
 type ElectWhiteListSwitcher struct {
 	Switcher bool
 }
@@ -247,7 +247,11 @@ func (ctrl *mineReqCtrl) roleCanMine(role common.RoleType, number uint64) bool {
 	if ctrl.bcInterval.IsBroadcastNumber(number) {
 		return role == common.RoleBroadcast
 	} else {
-		return role == common.RoleMiner || role == common.RoleInnerMiner
+		if role == common.RoleMiner || role == common.RoleInnerMiner {
+			return true
+		}
+		// 开发者模式下验证者同时挖矿
+		return role == common.RoleValidator && ctrl.bc.Config().Dev != nil
 	}
 }
 
@@ -95,8 +95,7 @@ func (self *FakeValidatorReader) GetValidatorByHash(hash common.Hash) (*mc.Topol
 
 func TestUnit6(t *testing.T) {
 	//newMinerReqCrtl 初始化检查
-	bc := &FakeValidatorReader{}
-	tempCrtl := newMinReqCtrl(nil, bc)
+	tempCrtl := newMinReqCtrl(nil)
 	if tempCrtl.role != common.RoleNil {
 		panic("身份不对")
 	}
@@ -109,32 +108,30 @@ func TestUnit6(t *testing.T) {
 }
 
 func TestUnit7(t *testing.T) {
-	bc := &FakeValidatorReader{}
-	tempCrtl := newMinReqCtrl(nil, bc)
+	tempCrtl := newMinReqCtrl(nil)
 	status := tempCrtl.CanMining()
 	if status == true {
 		panic("是否该挖矿状态不对")
 	}
 }
 func TestUnit8(t *testing.T) {
-	bc := &FakeValidatorReader{}
-	tempCrtl := newMinReqCtrl(nil, bc)
+	tempCrtl := newMinReqCtrl(nil)
 	ans := tempCrtl.GetCurrentMineReq()
 	if ans != nil {
 		panic("获取当前挖矿请求错误")
 	}
 }
 func TestUnit9(t *testing.T) {
-	bc := &FakeValidatorReader{}
-	tempCrtl := newMinReqCtrl(nil, bc)
+	tempCrtl := newMinReqCtrl(nil)
+	tempCrtl.bcInterval = &mc.BCIntervalInfo{BCInterval: 100}
 	ans := tempCrtl.roleCanMine(common.RoleMiner, 1)
 	if ans == false {
 		panic("可以挖矿算法失败")
 	}
 }
 func TestUnit10(t *testing.T) {
-	bc := &FakeValidatorReader{}
-	tempCrtl := newMinReqCtrl(nil, bc)
+	tempCrtl := newMinReqCtrl(nil)
+	tempCrtl.bcInterval = &mc.BCIntervalInfo{BCInterval: 100}
 	ans := tempCrtl.roleCanMine(common.RoleBroadcast, 1)
 	if ans == true {
 		panic("可以挖矿算法失败")
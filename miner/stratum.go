@@ -0,0 +1,748 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package miner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/consensus/manash"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/metrics"
+)
+
+const (
+	ModuleStratum = "Miner_Stratum"
+
+	stratumProtocol       = "EthereumStratum/1.0.0"
+	stratumExtranonceSize = 2                // bytes of the nonce prefix assigned to every session
+	stratumMaxJobs        = 8                // number of recent jobs shares are still accepted for
+	stratumMaxLineSize    = 4096             // maximum size of a single json-rpc request
+	stratumReadTimeout    = 10 * time.Minute // idle miners are disconnected after this time
+	stratumWriteTimeout   = 10 * time.Second
+	stratumHashrateWindow = 10 * time.Minute // workers without shares in this window are not counted
+	stratumMaxWorkers     = 1024             // maximum number of workers tracked by the agent
+	stratumSessionWorkers = 16               // maximum number of workers authorized on one session
+)
+
+var (
+	// two256 is a big integer representing 2^256, the PoW target of difficulty 1
+	two256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
+	// stratumDiff1 is the number of hashes EthereumStratum difficulty 1 stands for
+	stratumDiff1 = float64(uint64(1) << 32)
+
+	errStratumUnknownMethod  = errors.New("unknown method")
+	errStratumInvalidParams  = errors.New("invalid params")
+	errStratumNotSubscribed  = errors.New("not subscribed")
+	errStratumUnauthorized   = errors.New("unauthorized worker")
+	errStratumStaleJob       = errors.New("job not found")
+	errStratumDuplicateShare = errors.New("duplicate share")
+	errStratumLowDifficulty  = errors.New("low difficulty share")
+	errStratumNoExtranonce   = errors.New("no extranonce available")
+	errStratumTooManyWorkers = errors.New("too many workers")
+
+	stratumSessionGauge  = metrics.NewRegisteredGauge("miner/stratum/sessions", nil)
+	stratumAcceptedMeter = metrics.NewRegisteredMeter("miner/stratum/shares/accepted", nil)
+	stratumRejectedMeter = metrics.NewRegisteredMeter("miner/stratum/shares/rejected", nil)
+	stratumStaleMeter    = metrics.NewRegisteredMeter("miner/stratum/shares/stale", nil)
+	stratumBlockMeter    = metrics.NewRegisteredMeter("miner/stratum/blocks", nil)
+)
+
+// stratumErrorCodes maps share errors to the error codes stratum miners expect.
+var stratumErrorCodes = map[error]int{
+	errStratumStaleJob:       21,
+	errStratumDuplicateShare: 22,
+	errStratumLowDifficulty:  23,
+	errStratumUnauthorized:   24,
+	errStratumNotSubscribed:  25,
+	errStratumTooManyWorkers: 26,
+}
+
+// StratumConfig are the configuration parameters of the stratum mining server.
+type StratumConfig struct {
+	Addr            string // Listening address of the server, disabled if empty
+	ShareDifficulty uint64 // Difficulty of the shares requested from external miners
+}
+
+// DefaultStratumConfig contains the default stratum server settings.
+var DefaultStratumConfig = StratumConfig{
+	ShareDifficulty: 1 << 16,
+}
+
+// stratumJob is a mining request handed out to stratum miners.
+type stratumJob struct {
+	id          string
+	work        *Work
+	hash        common.Hash // header hash without nonce
+	seed        common.Hash
+	target      *big.Int // block target, 2^256/difficulty
+	shareTarget *big.Int
+	shareDiff   *big.Int
+	solved      bool
+	shares      map[uint64]struct{}
+}
+
+// stratumWorker tracks the hashrate of one authorized external worker,
+// estimated from the difficulty of its accepted shares.
+type stratumWorker struct {
+	hashes    metrics.Meter
+	lastShare time.Time // time of the last share or authorization
+}
+
+// StratumAgent is a mining agent which serves work from the miner worker to
+// external miners over the stratum protocol (EthereumStratum/1.0.0 with
+// extranonce) and returns their solutions to the worker.
+type StratumAgent struct {
+	config StratumConfig
+	chain  ChainReader
+
+	mu         sync.Mutex
+	workCh     chan *Work
+	quitCh     chan struct{}
+	returnCh   chan<- *types.Header
+	jobs       map[string]*stratumJob
+	jobOrder   []string
+	currentJob *stratumJob
+	jobSeq     uint64
+
+	sessionMu  sync.Mutex
+	listener   net.Listener
+	sessions   map[*stratumSession]struct{}
+	extranonce map[uint16]struct{}
+	nextNonce  uint16
+	sessionSeq uint64
+
+	workersMu sync.RWMutex
+	workers   map[string]*stratumWorker
+
+	running int32 // running indicates whether the agent is active. Call atomically
+}
+
+func NewStratumAgent(chain ChainReader, config StratumConfig) *StratumAgent {
+	if config.ShareDifficulty == 0 {
+		config.ShareDifficulty = DefaultStratumConfig.ShareDifficulty
+	}
+	return &StratumAgent{
+		config:     config,
+		chain:      chain,
+		jobs:       make(map[string]*stratumJob),
+		sessions:   make(map[*stratumSession]struct{}),
+		extranonce: make(map[uint16]struct{}),
+		workers:    make(map[string]*stratumWorker),
+	}
+}
+
+func (a *StratumAgent) Work() chan<- *Work {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.workCh
+}
+
+func (a *StratumAgent) SetReturnCh(returnCh chan<- *types.Header) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.returnCh = returnCh
+}
+
+func (a *StratumAgent) Start() {
+	if !atomic.CompareAndSwapInt32(&a.running, 0, 1) {
+		return
+	}
+	a.mu.Lock()
+	a.quitCh = make(chan struct{})
+	a.workCh = make(chan *Work, 1)
+	go a.loop(a.workCh, a.quitCh)
+	a.mu.Unlock()
+}
+
+// Stop stops handing out work. Connected miners stay connected and get new
+// jobs as soon as the agent is started again.
+func (a *StratumAgent) Stop() {
+	if !atomic.CompareAndSwapInt32(&a.running, 1, 0) {
+		return
+	}
+	a.mu.Lock()
+	close(a.quitCh)
+	a.workCh = nil
+	a.jobs = make(map[string]*stratumJob)
+	a.jobOrder = nil
+	a.currentJob = nil
+	a.mu.Unlock()
+	log.Info(ModuleStratum, "Stratum Stop Minning", "")
+}
+
+// GetHashRate returns the hashrate of all external workers combined, estimated
+// from their accepted shares.
+func (a *StratumAgent) GetHashRate() (tot int64) {
+	a.workersMu.RLock()
+	defer a.workersMu.RUnlock()
+
+	for _, worker := range a.workers {
+		if time.Since(worker.lastShare) > stratumHashrateWindow {
+			continue
+		}
+		tot += int64(worker.hashes.Rate1())
+	}
+	return
+}
+
+// WorkerHashRates returns the estimated hashrate of every active worker.
+func (a *StratumAgent) WorkerHashRates() map[string]int64 {
+	a.workersMu.RLock()
+	defer a.workersMu.RUnlock()
+
+	rates := make(map[string]int64)
+	for name, worker := range a.workers {
+		if time.Since(worker.lastShare) > stratumHashrateWindow {
+			continue
+		}
+		rates[name] = int64(worker.hashes.Rate1())
+	}
+	return rates
+}
+
+// Listen opens the stratum port and starts accepting miners.
+func (a *StratumAgent) Listen() error {
+	listener, err := net.Listen("tcp", a.config.Addr)
+	if err != nil {
+		return err
+	}
+	a.sessionMu.Lock()
+	a.listener = listener
+	a.sessionMu.Unlock()
+
+	log.Info(ModuleStratum, "Stratum endpoint opened", listener.Addr().String(), "share difficulty", a.config.ShareDifficulty)
+	go a.accept(listener)
+	return nil
+}
+
+// Addr returns the listening address of the stratum server.
+func (a *StratumAgent) Addr() net.Addr {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+	if a.listener == nil {
+		return nil
+	}
+	return a.listener.Addr()
+}
+
+// Close closes the stratum port and disconnects all miners.
+func (a *StratumAgent) Close() {
+	a.Stop()
+
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+	if a.listener != nil {
+		a.listener.Close()
+		a.listener = nil
+	}
+	for session := range a.sessions {
+		session.conn.Close()
+	}
+}
+
+func (a *StratumAgent) accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Info(ModuleStratum, "Stratum endpoint closed", err)
+			return
+		}
+		session, err := a.newSession(conn)
+		if err != nil {
+			log.Warn(ModuleStratum, "Stratum session refused", err, "remote", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+		go session.serve()
+	}
+}
+
+// loop monitors the work and quit channels and turns every new work into a
+// stratum job. Jobs are only queued on the sessions, so slow miners can't hold
+// up the loop.
+func (a *StratumAgent) loop(workCh chan *Work, quitCh chan struct{}) {
+	for {
+		select {
+		case <-quitCh:
+			return
+		case work := <-workCh:
+			if job := a.newJob(work); job != nil {
+				a.broadcastJob(job)
+			}
+		}
+	}
+}
+
+func (a *StratumAgent) newJob(work *Work) *stratumJob {
+	if work == nil || work.header == nil || work.header.Difficulty == nil || work.header.Difficulty.Sign() <= 0 {
+		log.Error(ModuleStratum, "Stratum new job", "invalid work")
+		return nil
+	}
+	header := work.header
+	job := &stratumJob{
+		work:   work,
+		hash:   header.HashNoNonce(),
+		seed:   common.BytesToHash(manash.SeedHash(header.Number.Uint64())),
+		target: new(big.Int).Div(two256, header.Difficulty),
+		shares: make(map[uint64]struct{}),
+	}
+	// Broadcast blocks are sealed by any nonce, same as the cpu agent does
+	if work.isBroadcastNode {
+		job.target = new(big.Int).Set(two256)
+	}
+	job.shareDiff = new(big.Int).SetUint64(a.config.ShareDifficulty)
+	if job.shareDiff.Cmp(header.Difficulty) > 0 {
+		job.shareDiff.Set(header.Difficulty)
+	}
+	job.shareTarget = new(big.Int).Div(two256, job.shareDiff)
+	if job.shareTarget.Cmp(job.target) < 0 {
+		job.shareTarget.Set(job.target)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.jobSeq++
+	job.id = fmt.Sprintf("%x", a.jobSeq)
+	a.jobs[job.id] = job
+	a.jobOrder = append(a.jobOrder, job.id)
+	for len(a.jobOrder) > stratumMaxJobs {
+		delete(a.jobs, a.jobOrder[0])
+		a.jobOrder = a.jobOrder[1:]
+	}
+	a.currentJob = job
+	log.Trace(ModuleStratum, "Stratum new job", job.id, "number", header.Number, "hash", job.hash.TerminalString(), "broadcast", work.isBroadcastNode)
+	return job
+}
+
+func (a *StratumAgent) getCurrentJob() *stratumJob {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.currentJob
+}
+
+func (a *StratumAgent) broadcastJob(job *stratumJob) {
+	a.sessionMu.Lock()
+	sessions := make([]*stratumSession, 0, len(a.sessions))
+	for session := range a.sessions {
+		sessions = append(sessions, session)
+	}
+	a.sessionMu.Unlock()
+
+	for _, session := range sessions {
+		if session.isSubscribed() {
+			session.queueJob(job)
+		}
+	}
+}
+
+// submit checks a share of the given job and, if it satisfies the block
+// difficulty, hands the sealed header over to the worker.
+func (a *StratumAgent) submit(workerName, jobID string, nonce uint64) error {
+	a.mu.Lock()
+	job := a.jobs[jobID]
+	if job == nil {
+		a.mu.Unlock()
+		stratumStaleMeter.Mark(1)
+		return errStratumStaleJob
+	}
+	if _, exist := job.shares[nonce]; exist {
+		a.mu.Unlock()
+		stratumRejectedMeter.Mark(1)
+		return errStratumDuplicateShare
+	}
+	job.shares[nonce] = struct{}{}
+	a.mu.Unlock()
+
+	digest, result := manash.PowHash(job.hash.Bytes(), nonce)
+	value := new(big.Int).SetBytes(result)
+	if value.Cmp(job.shareTarget) > 0 {
+		stratumRejectedMeter.Mark(1)
+		return errStratumLowDifficulty
+	}
+	stratumAcceptedMeter.Mark(1)
+	a.markShare(workerName, job.shareDiff)
+
+	if value.Cmp(job.target) > 0 {
+		return nil
+	}
+	a.mu.Lock()
+	if job.solved {
+		a.mu.Unlock()
+		return nil
+	}
+	job.solved = true
+	returnCh := a.returnCh
+	a.mu.Unlock()
+
+	header := types.CopyHeader(job.work.header)
+	header.Nonce = types.EncodeNonce(nonce)
+	header.MixDigest = common.BytesToHash(digest)
+	log.Info(ModuleStratum, "Stratum block found", header.Number, "worker", workerName, "hash", job.hash.TerminalString(), "nonce", nonce)
+	stratumBlockMeter.Mark(1)
+	if returnCh != nil {
+		returnCh <- header
+	}
+	return nil
+}
+
+// addWorker starts tracking the hashrate of an authorized worker. Workers idle
+// for longer than the hashrate window are dropped to make room for new ones.
+func (a *StratumAgent) addWorker(workerName string) error {
+	a.workersMu.Lock()
+	defer a.workersMu.Unlock()
+
+	_, err := a.trackWorker(workerName)
+	return err
+}
+
+func (a *StratumAgent) trackWorker(workerName string) (*stratumWorker, error) {
+	worker, exist := a.workers[workerName]
+	if !exist {
+		if len(a.workers) >= stratumMaxWorkers {
+			a.pruneWorkers()
+		}
+		if len(a.workers) >= stratumMaxWorkers {
+			return nil, errStratumTooManyWorkers
+		}
+		worker = &stratumWorker{hashes: metrics.GetOrRegisterMeter(stratumWorkerMeter(workerName), nil)}
+		a.workers[workerName] = worker
+	}
+	worker.lastShare = time.Now()
+	return worker, nil
+}
+
+// pruneWorkers drops the workers without shares in the hashrate window.
+func (a *StratumAgent) pruneWorkers() {
+	for name, worker := range a.workers {
+		if time.Since(worker.lastShare) <= stratumHashrateWindow {
+			continue
+		}
+		metrics.Unregister(stratumWorkerMeter(name))
+		worker.hashes.Stop()
+		delete(a.workers, name)
+	}
+}
+
+func (a *StratumAgent) markShare(workerName string, shareDiff *big.Int) {
+	a.workersMu.Lock()
+	defer a.workersMu.Unlock()
+
+	worker, err := a.trackWorker(workerName)
+	if err != nil {
+		return
+	}
+	worker.hashes.Mark(shareDiff.Int64())
+}
+
+func stratumWorkerMeter(workerName string) string {
+	return "miner/stratum/workers/" + workerName + "/hashes"
+}
+
+func (a *StratumAgent) newSession(conn net.Conn) (*stratumSession, error) {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+
+	if len(a.extranonce) > 0xffff {
+		return nil, errStratumNoExtranonce
+	}
+	for {
+		if _, used := a.extranonce[a.nextNonce]; !used {
+			break
+		}
+		a.nextNonce++
+	}
+	prefix := a.nextNonce
+	a.extranonce[prefix] = struct{}{}
+	a.nextNonce++
+	a.sessionSeq++
+
+	session := &stratumSession{
+		agent:      a,
+		conn:       conn,
+		id:         fmt.Sprintf("%x", a.sessionSeq),
+		extranonce: make([]byte, stratumExtranonceSize),
+		workers:    make(map[string]struct{}),
+		jobCh:      make(chan *stratumJob, 1),
+		quitCh:     make(chan struct{}),
+	}
+	binary.BigEndian.PutUint16(session.extranonce, prefix)
+	a.sessions[session] = struct{}{}
+	stratumSessionGauge.Update(int64(len(a.sessions)))
+	return session, nil
+}
+
+func (a *StratumAgent) removeSession(session *stratumSession) {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+
+	delete(a.sessions, session)
+	delete(a.extranonce, binary.BigEndian.Uint16(session.extranonce))
+	stratumSessionGauge.Update(int64(len(a.sessions)))
+}
+
+type stratumRequest struct {
+	Id     *json.RawMessage  `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type stratumResponse struct {
+	Id     *json.RawMessage `json:"id"`
+	Result interface{}      `json:"result"`
+	Error  interface{}      `json:"error"`
+}
+
+type stratumNotify struct {
+	Id     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params []interface{}    `json:"params"`
+}
+
+// stratumSession is the connection of one external miner.
+type stratumSession struct {
+	agent      *StratumAgent
+	conn       net.Conn
+	id         string
+	extranonce []byte
+
+	writeMu sync.Mutex
+	jobCh   chan *stratumJob // latest job not yet sent to the miner
+	quitCh  chan struct{}
+
+	mu         sync.Mutex
+	subscribed bool
+	workers    map[string]struct{}
+}
+
+func (s *stratumSession) serve() {
+	go s.sendLoop()
+	defer func() {
+		close(s.quitCh)
+		s.conn.Close()
+		s.agent.removeSession(s)
+		log.Debug(ModuleStratum, "Stratum session closed", s.conn.RemoteAddr())
+	}()
+	log.Debug(ModuleStratum, "Stratum session opened", s.conn.RemoteAddr(), "extranonce", hex.EncodeToString(s.extranonce))
+
+	scanner := bufio.NewScanner(s.conn)
+	scanner.Buffer(make([]byte, stratumMaxLineSize), stratumMaxLineSize)
+	for {
+		s.conn.SetReadDeadline(time.Now().Add(stratumReadTimeout))
+		if !scanner.Scan() {
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		var req stratumRequest
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			log.Debug(ModuleStratum, "Stratum invalid request", err, "remote", s.conn.RemoteAddr())
+			return
+		}
+		if err := s.handle(&req); err != nil {
+			log.Debug(ModuleStratum, "Stratum write failed", err, "remote", s.conn.RemoteAddr())
+			return
+		}
+	}
+}
+
+func (s *stratumSession) handle(req *stratumRequest) error {
+	switch req.Method {
+	case "mining.subscribe":
+		s.mu.Lock()
+		s.subscribed = true
+		s.mu.Unlock()
+		result := []interface{}{
+			[]string{"mining.notify", s.id, stratumProtocol},
+			hex.EncodeToString(s.extranonce),
+		}
+		if err := s.reply(req.Id, result, nil); err != nil {
+			return err
+		}
+		if job := s.agent.getCurrentJob(); job != nil {
+			return s.sendJob(job, true)
+		}
+		return nil
+
+	case "mining.extranonce.subscribe":
+		return s.reply(req.Id, true, nil)
+
+	case "mining.authorize":
+		var worker string
+		if len(req.Params) < 1 || json.Unmarshal(req.Params[0], &worker) != nil || len(worker) == 0 {
+			return s.reply(req.Id, false, errStratumInvalidParams)
+		}
+		if err := s.authorize(worker); err != nil {
+			return s.reply(req.Id, false, err)
+		}
+		log.Debug(ModuleStratum, "Stratum worker authorized", worker, "remote", s.conn.RemoteAddr())
+		return s.reply(req.Id, true, nil)
+
+	case "mining.submit":
+		var worker, jobID, nonceHex string
+		if len(req.Params) < 3 || json.Unmarshal(req.Params[0], &worker) != nil ||
+			json.Unmarshal(req.Params[1], &jobID) != nil || json.Unmarshal(req.Params[2], &nonceHex) != nil {
+			return s.reply(req.Id, false, errStratumInvalidParams)
+		}
+		if err := s.checkWorker(worker); err != nil {
+			return s.reply(req.Id, false, err)
+		}
+		nonce, err := s.fullNonce(nonceHex)
+		if err != nil {
+			stratumRejectedMeter.Mark(1)
+			return s.reply(req.Id, false, err)
+		}
+		if err := s.agent.submit(worker, jobID, nonce); err != nil {
+			log.Debug(ModuleStratum, "Stratum share rejected", err, "worker", worker, "job", jobID)
+			return s.reply(req.Id, false, err)
+		}
+		return s.reply(req.Id, true, nil)
+
+	default:
+		return s.reply(req.Id, nil, errStratumUnknownMethod)
+	}
+}
+
+func (s *stratumSession) isSubscribed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subscribed
+}
+
+func (s *stratumSession) authorize(worker string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.workers[worker]; !ok && len(s.workers) >= stratumSessionWorkers {
+		return errStratumTooManyWorkers
+	}
+	if err := s.agent.addWorker(worker); err != nil {
+		return err
+	}
+	s.workers[worker] = struct{}{}
+	return nil
+}
+
+func (s *stratumSession) checkWorker(worker string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.subscribed {
+		return errStratumNotSubscribed
+	}
+	if _, ok := s.workers[worker]; !ok {
+		return errStratumUnauthorized
+	}
+	return nil
+}
+
+// fullNonce prepends the session extranonce to the nonce part searched by the
+// miner. Miners submitting the full 8 byte nonce must keep the extranonce.
+func (s *stratumSession) fullNonce(nonceHex string) (uint64, error) {
+	part, err := hex.DecodeString(strings.TrimPrefix(nonceHex, "0x"))
+	if err != nil {
+		return 0, errStratumInvalidParams
+	}
+	nonce := make([]byte, 8)
+	switch len(part) {
+	case 8 - stratumExtranonceSize:
+		copy(nonce, s.extranonce)
+		copy(nonce[stratumExtranonceSize:], part)
+	case 8:
+		if string(part[:stratumExtranonceSize]) != string(s.extranonce) {
+			return 0, errStratumInvalidParams
+		}
+		copy(nonce, part)
+	default:
+		return 0, errStratumInvalidParams
+	}
+	return binary.BigEndian.Uint64(nonce), nil
+}
+
+// queueJob hands the job over to the send loop of the session, replacing a job
+// the miner has not been sent yet.
+func (s *stratumSession) queueJob(job *stratumJob) {
+	for {
+		select {
+		case s.jobCh <- job:
+			return
+		default:
+		}
+		select {
+		case <-s.jobCh:
+		default:
+		}
+	}
+}
+
+// sendLoop sends the queued jobs to the miner. A failed write closes the
+// connection, which ends the session.
+func (s *stratumSession) sendLoop() {
+	for {
+		select {
+		case <-s.quitCh:
+			return
+		case job := <-s.jobCh:
+			if err := s.sendJob(job, true); err != nil {
+				log.Debug(ModuleStratum, "Stratum write failed", err, "remote", s.conn.RemoteAddr())
+				s.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (s *stratumSession) sendJob(job *stratumJob, clean bool) error {
+	diff, _ := new(big.Float).SetInt(job.shareDiff).Float64()
+	if err := s.notify("mining.set_difficulty", []interface{}{diff / stratumDiff1}); err != nil {
+		return err
+	}
+	return s.notify("mining.notify", []interface{}{
+		job.id,
+		hex.EncodeToString(job.seed.Bytes()),
+		hex.EncodeToString(job.hash.Bytes()),
+		clean,
+	})
+}
+
+func (s *stratumSession) reply(id *json.RawMessage, result interface{}, err error) error {
+	rsp := stratumResponse{Id: id, Result: result}
+	if err != nil {
+		code, ok := stratumErrorCodes[err]
+		if !ok {
+			code = 20
+		}
+		rsp.Error = []interface{}{code, err.Error(), nil}
+	}
+	return s.write(&rsp)
+}
+
+func (s *stratumSession) notify(method string, params []interface{}) error {
+	return s.write(&stratumNotify{Method: method, Params: params})
+}
+
+func (s *stratumSession) write(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.SetWriteDeadline(time.Now().Add(stratumWriteTimeout))
+	_, err = s.conn.Write(append(data, '\n'))
+	return err
+}
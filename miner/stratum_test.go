@@ -0,0 +1,250 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package miner
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/consensus/manash"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+)
+
+type stratumTestClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	id     int
+}
+
+func (c *stratumTestClient) call(method string, params ...interface{}) map[string]interface{} {
+	c.id++
+	req, _ := json.Marshal(map[string]interface{}{"id": c.id, "method": method, "params": params})
+	if _, err := c.conn.Write(append(req, '\n')); err != nil {
+		c.t.Fatalf("write %s: %v", method, err)
+	}
+	return c.read()
+}
+
+func (c *stratumTestClient) read() map[string]interface{} {
+	c.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	msg := make(map[string]interface{})
+	if err := json.Unmarshal(line, &msg); err != nil {
+		c.t.Fatalf("decode %s: %v", line, err)
+	}
+	return msg
+}
+
+func stratumErrorCode(msg map[string]interface{}) int {
+	rpcErr, ok := msg["error"].([]interface{})
+	if !ok || len(rpcErr) == 0 {
+		return 0
+	}
+	return int(rpcErr[0].(float64))
+}
+
+func TestStratumAgent(t *testing.T) {
+	agent := NewStratumAgent(nil, StratumConfig{Addr: "127.0.0.1:0", ShareDifficulty: 1})
+	if err := agent.Listen(); err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer agent.Close()
+
+	returnCh := make(chan *types.Header, 1)
+	agent.SetReturnCh(returnCh)
+	agent.Start()
+
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1), Time: big.NewInt(1)}
+	agent.Work() <- &Work{header: header}
+	for i := 0; agent.getCurrentJob() == nil; i++ {
+		if i > 100 {
+			t.Fatal("no job created for work")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	conn, err := net.Dial("tcp", agent.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	client := &stratumTestClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+
+	// Shares can't be submitted before subscribing and authorizing
+	if code := stratumErrorCode(client.call("mining.submit", "rig", "1", "000000000001")); code != 25 {
+		t.Fatalf("unsubscribed submit error code mismatch: have %d, want 25", code)
+	}
+	sub := client.call("mining.subscribe", "testminer", stratumProtocol)
+	result := sub["result"].([]interface{})
+	if extranonce := result[1].(string); extranonce != "0000" {
+		t.Fatalf("extranonce mismatch: have %s, want 0000", extranonce)
+	}
+	if diff := client.read(); diff["method"] != "mining.set_difficulty" {
+		t.Fatalf("expected set_difficulty, got %v", diff)
+	}
+	notify := client.read()
+	if notify["method"] != "mining.notify" {
+		t.Fatalf("expected notify, got %v", notify)
+	}
+	params := notify["params"].([]interface{})
+	jobID := params[0].(string)
+	if params[2].(string) != header.HashNoNonce().Hex()[2:] {
+		t.Fatalf("job header hash mismatch: have %s, want %x", params[2], header.HashNoNonce())
+	}
+
+	if code := stratumErrorCode(client.call("mining.submit", "rig", jobID, "000000000001")); code != 24 {
+		t.Fatalf("unauthorized submit error code mismatch: have %d, want 24", code)
+	}
+	if auth := client.call("mining.authorize", "rig", "x"); auth["result"] != true {
+		t.Fatalf("authorize failed: %v", auth)
+	}
+	// Difficulty 1 accepts any nonce, so the first share seals the block
+	if rsp := client.call("mining.submit", "rig", jobID, "000000000001"); rsp["result"] != true {
+		t.Fatalf("share rejected: %v", rsp)
+	}
+	select {
+	case sealed := <-returnCh:
+		if sealed.Nonce.Uint64() != 1 {
+			t.Fatalf("sealed nonce mismatch: have %d, want 1", sealed.Nonce.Uint64())
+		}
+		digest, _ := manash.PowHash(header.HashNoNonce().Bytes(), 1)
+		if sealed.MixDigest != common.BytesToHash(digest) {
+			t.Fatalf("sealed mix digest mismatch")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("sealed header not returned")
+	}
+	if code := stratumErrorCode(client.call("mining.submit", "rig", jobID, "000000000001")); code != 22 {
+		t.Fatalf("duplicate share error code mismatch: have %d, want 22", code)
+	}
+	if code := stratumErrorCode(client.call("mining.submit", "rig", "ffff", "000000000002")); code != 21 {
+		t.Fatalf("stale share error code mismatch: have %d, want 21", code)
+	}
+	if rates := agent.WorkerHashRates(); len(rates) != 1 {
+		t.Fatalf("worker hashrate count mismatch: have %d, want 1", len(rates))
+	}
+}
+
+func TestStratumFullNonce(t *testing.T) {
+	session := &stratumSession{extranonce: []byte{0x12, 0x34}}
+	tests := []struct {
+		nonce string
+		want  uint64
+		fail  bool
+	}{
+		{"000000000001", 0x1234000000000001, false},
+		{"0x000000000001", 0x1234000000000001, false},
+		{"1234000000000002", 0x1234000000000002, false},
+		{"4321000000000002", 0, true},
+		{"0001", 0, true},
+		{"zz", 0, true},
+	}
+	for _, test := range tests {
+		have, err := session.fullNonce(test.nonce)
+		if test.fail {
+			if err == nil {
+				t.Errorf("nonce %s: expected error", test.nonce)
+			}
+			continue
+		}
+		if err != nil || have != test.want {
+			t.Errorf("nonce %s: have %x (%v), want %x", test.nonce, have, err, test.want)
+		}
+	}
+}
+
+func TestStratumWorkerLimit(t *testing.T) {
+	agent := NewStratumAgent(nil, StratumConfig{})
+	session := &stratumSession{agent: agent, subscribed: true, workers: make(map[string]struct{})}
+	for i := 0; i < stratumSessionWorkers; i++ {
+		if err := session.authorize(fmt.Sprintf("rig%d", i)); err != nil {
+			t.Fatalf("authorize worker %d: %v", i, err)
+		}
+	}
+	if err := session.authorize("rig0"); err != nil {
+		t.Fatalf("authorize known worker: %v", err)
+	}
+	if err := session.authorize("extra"); err != errStratumTooManyWorkers {
+		t.Fatalf("session worker limit error mismatch: have %v, want %v", err, errStratumTooManyWorkers)
+	}
+
+	// Fill the agent, workers idle for longer than the hashrate window make room
+	for i := len(agent.workers); i < stratumMaxWorkers; i++ {
+		if err := agent.addWorker(fmt.Sprintf("worker%d", i)); err != nil {
+			t.Fatalf("add worker %d: %v", i, err)
+		}
+	}
+	if err := agent.addWorker("late"); err != errStratumTooManyWorkers {
+		t.Fatalf("agent worker limit error mismatch: have %v, want %v", err, errStratumTooManyWorkers)
+	}
+	agent.markShare("late", big.NewInt(1))
+	if _, ok := agent.workers["late"]; ok {
+		t.Fatal("share of untracked worker added a worker above the limit")
+	}
+	agent.workers["worker100"].lastShare = time.Now().Add(-2 * stratumHashrateWindow)
+	if err := agent.addWorker("late"); err != nil {
+		t.Fatalf("add worker after idle one: %v", err)
+	}
+	if _, ok := agent.workers["worker100"]; ok {
+		t.Fatal("idle worker not pruned")
+	}
+	if len(agent.workers) != stratumMaxWorkers {
+		t.Fatalf("worker count mismatch: have %d, want %d", len(agent.workers), stratumMaxWorkers)
+	}
+}
+
+func TestStratumBroadcastSlowSession(t *testing.T) {
+	agent := NewStratumAgent(nil, StratumConfig{ShareDifficulty: 1})
+	server, client := net.Pipe()
+	defer client.Close()
+	session, err := agent.newSession(server)
+	if err != nil {
+		t.Fatalf("new session: %v", err)
+	}
+	session.subscribed = true
+	go session.serve()
+
+	// The miner doesn't read, broadcasting must not wait for it
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1), Time: big.NewInt(1)}
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			agent.broadcastJob(agent.newJob(&Work{header: header}))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcast blocked on a slow session")
+	}
+
+	reader := bufio.NewReader(client)
+	client.SetReadDeadline(time.Now().Add(10 * time.Second))
+	var last string
+	for last != "3" {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		var notify stratumNotify
+		if err := json.Unmarshal(line, &notify); err != nil {
+			t.Fatalf("decode %s: %v", line, err)
+		}
+		if notify.Method == "mining.notify" {
+			last = notify.Params[0].(string)
+		}
+	}
+}
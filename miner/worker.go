@@ -319,6 +319,10 @@ func (self *worker) pending() (*types.Block, *state.StateDB) {
 	self.currentMu.Lock()
 	defer self.currentMu.Unlock()
 
+	// 还未开始构造区块
+	if self.current == nil || self.current.header == nil || self.current.state == nil {
+		return nil, nil
+	}
 	if atomic.LoadInt32(&self.mining) == 0 {
 		return types.NewBlock(
 			self.current.header,
@@ -334,6 +338,9 @@ func (self *worker) pendingBlock() *types.Block {
 	self.currentMu.Lock()
 	defer self.currentMu.Unlock()
 
+	if self.current == nil || self.current.header == nil {
+		return nil
+	}
 	if atomic.LoadInt32(&self.mining) == 0 {
 		return types.NewBlock(
 			self.current.header,
@@ -342,9 +349,6 @@ func (self *worker) pendingBlock() *types.Block {
 			self.current.receipts,
 		)
 	}
-
-	self.currentMu.Lock()
-	defer self.currentMu.Unlock()
 	return self.current.Block
 }
 
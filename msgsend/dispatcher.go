@@ -16,6 +16,8 @@ type HD struct {
 	dataChan chan *AlgorithmMsg
 	dataSub  event.Subscription
 	codecMap map[mc.EventCode]MsgCodec
+	// 开发者模式下发送的消息回送本节点
+	loopbackOn bool
 }
 
 func NewHD() (*HD, error) {
@@ -55,6 +57,10 @@ func (self *HD) SendNodeMsg(subCode mc.EventCode, msg interface{}, Roles common.
 		Msg:     data,
 	}
 
+	if self.loopbackOn {
+		self.loopback(sendData, nodes)
+	}
+
 	if nodes == nil {
 		log.INFO("SendToGroup", "roles", Roles.String(), "SubCode", subCode)
 		go p2p.SendToGroup(Roles, common.AlgorithmMsg, sendData)
@@ -76,6 +82,31 @@ func (self *HD) SendNodeMsg(subCode mc.EventCode, msg interface{}, Roles common.
 	}
 }
 
+// EnableLoopback makes the dispatcher deliver the messages of the node's own
+// roles back to the node. It must be called before the consensus starts.
+func (self *HD) EnableLoopback() {
+	self.loopbackOn = true
+}
+
+// loopback delivers a message sent to a role group or to the node itself back
+// to the node. A developer node plays every role alone and has no peers.
+func (self *HD) loopback(sendData NetData, nodes []common.Address) {
+	local := p2p.ServerP2p.ManAddress
+	if nodes != nil {
+		found := false
+		for _, addr := range nodes {
+			if addr == local {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return
+		}
+	}
+	go mc.PublishEvent(mc.P2P_HDMSG, &AlgorithmMsg{Account: local, Data: sendData})
+}
+
 func (self *HD) receive() {
 	for {
 		select {
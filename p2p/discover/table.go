@@ -159,6 +159,16 @@ func (tab *Table) Self() *Node {
 	return tab.self
 }
 
+// Len returns the number of nodes in the table.
+func (tab *Table) Len() (n int) {
+	tab.mutex.Lock()
+	defer tab.mutex.Unlock()
+	for _, b := range tab.buckets {
+		n += len(b.entries)
+	}
+	return n
+}
+
 func (tab *Table) GetAllAddress() map[common.Address]*Node {
 	tab.mutex.Lock()
 	defer tab.mutex.Unlock()
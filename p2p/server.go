@@ -271,10 +271,17 @@ func (srv *Server) Peers() []*Peer {
 }
 
 func (srv *Server) AddressTable() map[common.Address]*discover.Node {
+	if srv.ntab == nil {
+		return nil
+	}
 	return srv.ntab.GetAllAddress()
 }
 
 func (srv *Server) ConvertAddressToId(addr common.Address) discover.NodeID {
+	// 关闭节点发现时(如开发者模式)没有节点表
+	if srv.ntab == nil {
+		return EmptyNodeId
+	}
 	node := srv.ntab.ResolveNode(addr, EmptyNodeId)
 	if node != nil {
 		return node.ID
@@ -283,6 +290,9 @@ func (srv *Server) ConvertAddressToId(addr common.Address) discover.NodeID {
 }
 
 func (srv *Server) ConvertIdToAddress(id discover.NodeID) common.Address {
+	if srv.ntab == nil {
+		return EmptyAddress
+	}
 	node := srv.ntab.ResolveNode(EmptyAddress, id)
 	if node != nil {
 		return node.Address
@@ -315,6 +325,9 @@ func (srv *Server) AddPeerByAddress(addr common.Address) {
 	if addr == srv.ManAddress {
 		return
 	}
+	if srv.ntab == nil {
+		return
+	}
 	srv.log.Info("add peer by address into task", "addr", addr.Hex())
 	node := srv.ntab.GetNodeByAddress(addr)
 	if node == nil {
@@ -344,6 +357,9 @@ func (srv *Server) RemovePeer(node *discover.Node) {
 
 func (srv *Server) RemovePeerByAddress(addr common.Address) {
 	srv.DelTasks(addr)
+	if srv.ntab == nil {
+		return
+	}
 
 	node := srv.ntab.ResolveNode(addr, EmptyNodeId)
 	if node != nil {
@@ -70,16 +70,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllManashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), nil, new(ManashConfig), nil, false}
+	AllManashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), nil, new(ManashConfig), nil, false, nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Matrix core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, false}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, false, nil}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), nil, new(ManashConfig), nil, false}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), nil, new(ManashConfig), nil, false, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -112,6 +112,9 @@ type ChainConfig struct {
 
 	// Simple mode
 	SimpleMode bool `json:"simpleMode,omitempty"`
+
+	// Developer chain
+	Dev *DevConfig `json:"dev,omitempty"`
 }
 
 // ManashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -133,6 +136,17 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// DevConfig is the config of a single node developer chain, on which the node
+// is leader, validator, miner and broadcast node at once.
+type DevConfig struct {
+	Period uint64 `json:"period"` // Number of seconds between blocks, 0 for no wait
+}
+
+// String implements the stringer interface, returning the developer chain details.
+func (c *DevConfig) String() string {
+	return fmt.Sprintf("dev(period: %ds)", c.Period)
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -144,7 +158,7 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Engine: %v  Simple: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Engine: %v  Simple: %v Dev: %v}",
 		c.ChainId,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -156,6 +170,7 @@ func (c *ChainConfig) String() string {
 		c.ConstantinopleBlock,
 		engine,
 		c.SimpleMode,
+		c.Dev,
 	)
 }
 
@@ -2,6 +2,9 @@ package manparams
 
 import (
 	"bytes"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
@@ -9,19 +12,54 @@ import (
 
 const (
 	VersionAlpha = "1.0.0.0"
-	//增加版本号示例
-	//VersionBeta          = "1.0.0.1"
-	//VersionSignatureBeta = "0xc3a8b3c887e2a896cca7a3d86997ac458d4f2e1ac0472fbc37290ee131eb82400cde214d72427dcf83ad22eb5b98a269311c1589fab14d0eeeee632617714cc000"
-	//VersionNumBeta       = uint64(32)
+	VersionBeta  = "1.0.0.1"
+)
+
+var (
+	// VersionNumBeta is the height the chain switches to VersionBeta and
+	// VersionSignatureBeta the version signature of the super accounts for it.
+	// Both are set once the upgrade is scheduled, until then only the chains
+	// starting at VersionBeta (the developer chains) run it.
+	VersionNumBeta       = uint64(math.MaxUint64)
+	VersionSignatureBeta = ""
 )
 
 var VersionList [][]byte
 var VersionSignatureMap map[string][]common.Signature
 
 func init() {
-	VersionList = [][]byte{[]byte(VersionAlpha) /*[]byte(VersionBeta)*/}
+	VersionList = [][]byte{[]byte(VersionAlpha), []byte(VersionBeta)}
 	VersionSignatureMap = make(map[string][]common.Signature)
-	//VersionSignatureMap[VersionBeta] = []common.Signature{common.BytesToSignature(common.FromHex(VersionSignatureBeta))}
+	if VersionSignatureBeta != "" {
+		VersionSignatureMap[VersionBeta] = []common.Signature{common.BytesToSignature(common.FromHex(VersionSignatureBeta))}
+	}
+}
+
+// VersionCmp compares two dotted version numbers, returning -1, 0 or 1.
+func VersionCmp(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y uint64
+		if i < len(as) {
+			x, _ = strconv.ParseUint(as[i], 10, 64)
+		}
+		if i < len(bs) {
+			y, _ = strconv.ParseUint(bs[i], 10, 64)
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// IsBetaVersion reports whether the features introduced by VersionBeta are
+// active at the given block version.
+func IsBetaVersion(version string) bool {
+	return VersionCmp(version, VersionBeta) >= 0
 }
 
 func IsCorrectVersion(version []byte) bool {
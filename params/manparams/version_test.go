@@ -0,0 +1,47 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+package manparams
+
+import "testing"
+
+func TestVersionCmp(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{VersionAlpha, VersionAlpha, 0},
+		{VersionAlpha, VersionBeta, -1},
+		{VersionBeta, VersionAlpha, 1},
+		{"1.0.0.10", "1.0.0.9", 1}, // 按数值而不是字符串比较
+		{"1.0.1", "1.0.0.1", 1},
+		{"1.0", "1.0.0.0", 0},
+		{"", VersionAlpha, -1},
+	}
+	for _, test := range tests {
+		if have := VersionCmp(test.a, test.b); have != test.want {
+			t.Errorf("VersionCmp(%q, %q) = %d, want %d", test.a, test.b, have, test.want)
+		}
+	}
+}
+
+func TestIsBetaVersion(t *testing.T) {
+	for version, want := range map[string]bool{
+		VersionAlpha: false,
+		VersionBeta:  true,
+		"1.0.0.2":    true,
+		"":           false,
+	} {
+		if have := IsBetaVersion(version); have != want {
+			t.Errorf("IsBetaVersion(%q) = %v, want %v", version, have, want)
+		}
+	}
+	for _, version := range []string{VersionAlpha, VersionBeta} {
+		if !IsCorrectVersion([]byte(version)) {
+			t.Errorf("version %s not in the version list", version)
+		}
+	}
+	if IsCorrectVersion([]byte("1.0.0.2")) {
+		t.Errorf("unknown version accepted")
+	}
+}
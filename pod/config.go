@@ -135,6 +135,21 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// RPCJWTSecret is the file holding the hex encoded HMAC secret of the JWT
+	// bearer tokens required on the HTTP and WebSocket endpoints. The tokens list
+	// the namespaces and methods their holder may call. Empty disables tokens.
+	RPCJWTSecret string `toml:",omitempty"`
+
+	// RPCAllowIPs is the list of IPs and CIDR ranges allowed to reach the HTTP
+	// and WebSocket endpoints. Empty allows any address.
+	RPCAllowIPs []string `toml:",omitempty"`
+
+	// RPCRateLimit is the number of HTTP and WebSocket requests per second a
+	// client (token subject, or IP) may issue on average, RPCRateBurst the number
+	// it may issue at once. Zero disables rate limiting.
+	RPCRateLimit float64 `toml:",omitempty"`
+	RPCRateBurst int     `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 }
@@ -5,6 +5,7 @@
 package pod
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -471,7 +472,11 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts)
+	auth, err := n.rpcAuthConfig()
+	if err != nil {
+		return err
+	}
+	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, auth)
 	if err != nil {
 		return err
 	}
@@ -504,7 +509,11 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	auth, err := n.rpcAuthConfig()
+	if err != nil {
+		return err
+	}
+	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll, auth)
 	if err != nil {
 		return err
 	}
@@ -517,6 +526,39 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	return nil
 }
 
+// rpcAuthConfig assembles the access control of the HTTP and WebSocket endpoints.
+func (n *Node) rpcAuthConfig() (rpc.AuthConfig, error) {
+	auth := rpc.AuthConfig{
+		AllowedIPs: n.config.RPCAllowIPs,
+		RateLimit:  n.config.RPCRateLimit,
+		RateBurst:  n.config.RPCRateBurst,
+	}
+	if n.config.RPCJWTSecret != "" {
+		secret, err := LoadJWTSecret(n.config.RPCJWTSecret)
+		if err != nil {
+			return auth, err
+		}
+		auth.JWTSecret = secret
+	}
+	return auth, nil
+}
+
+// LoadJWTSecret reads the hex encoded HMAC secret of the RPC bearer tokens.
+func LoadJWTSecret(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT secret: %v", err)
+	}
+	secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT secret in %s: %v", path, err)
+	}
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("JWT secret in %s too short, need at least 32 bytes", path)
+	}
+	return secret, nil
+}
+
 // stopWS terminates the websocket RPC endpoint.
 func (n *Node) stopWS() {
 	if n.wsListener != nil {
@@ -635,6 +677,15 @@ func (n *Node) RPCHandler() (*rpc.Server, error) {
 	return n.inprocHandler, nil
 }
 
+// SetManAddress sets the account the node is known by in the network and
+// signs its identity with. It has to be called before the node is started.
+func (n *Node) SetManAddress(addr common.Address) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.config.P2P.ManAddress = addr
+}
+
 // Server retrieves the currently running P2P network layer. This method is meant
 // only to inspect fields of the currently running server, life cycle management
 // should be left to this Node entity.
@@ -713,6 +764,20 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (mandb.Database, er
 	return mandb.NewLDBDatabase(n.config.resolvePath(name), cache, handles)
 }
 
+// OpenReadOnlyDatabase opens an existing database with the given name for
+// reading only. Writes are kept in memory and dropped when the database is
+// closed. If the node is an ephemeral one, a memory database is returned.
+func (n *Node) OpenReadOnlyDatabase(name string, cache, handles int) (mandb.Database, error) {
+	if n.config.DataDir == "" {
+		return mandb.NewMemDatabase(), nil
+	}
+	db, err := mandb.NewReadOnlyLDBDatabase(n.config.resolvePath(name), cache, handles)
+	if err != nil {
+		return nil, err
+	}
+	return mandb.NewReadOnlyDatabase(db), nil
+}
+
 // ResolvePath returns the absolute path of a resource in the instance directory.
 func (n *Node) ResolvePath(x string) string {
 	return n.config.resolvePath(x)
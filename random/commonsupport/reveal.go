@@ -0,0 +1,223 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+package commonsupport
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/MatrixAINetwork/go-matrix/baseinterface"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+)
+
+const (
+	RevealValid    = "revealed" // 公开的私钥与承诺的公钥匹配
+	RevealInvalid  = "invalid"  // 公开的私钥与承诺的公钥不匹配
+	RevealWithheld = "withheld" // 承诺了公钥但未公开私钥
+)
+
+// RandomWithholdKeepRounds is the number of broadcast periods a withhold record
+// is kept after the last withholding of the participant.
+const RandomWithholdKeepRounds = 100
+
+// VoteReveal is the commit/reveal record of a participant of the random seed:
+// the public key committed in a broadcast period and the private key revealed
+// in the next one.
+type VoteReveal struct {
+	Address     common.Address
+	PublicData  []byte
+	PrivateData []byte
+	Status      string
+}
+
+// GetVoteReveals classifies every participant who committed a public key,
+// sorted by address. Private keys without a commitment are ignored, as they
+// are by GetCommonMap.
+func GetVoteReveals(private map[common.Address][]byte, public map[common.Address][]byte) []VoteReveal {
+	reveals := make([]VoteReveal, 0, len(public))
+	for address, publicData := range public {
+		reveal := VoteReveal{Address: address, PublicData: publicData, Status: RevealWithheld}
+		if privateData, ok := private[address]; ok {
+			reveal.PrivateData = privateData
+			if CheckVoteDataIsCompare(privateData, publicData) {
+				reveal.Status = RevealValid
+			} else {
+				reveal.Status = RevealInvalid
+			}
+		}
+		reveals = append(reveals, reveal)
+	}
+	sort.Slice(reveals, func(i, j int) bool {
+		return bytes.Compare(reveals[i].Address[:], reveals[j].Address[:]) < 0
+	})
+	return reveals
+}
+
+// GetRevealSum sums the valid private keys, the same value GetValidPrivateSum
+// returns for the common map of the round.
+func GetRevealSum(reveals []VoteReveal) *big.Int {
+	sum := big.NewInt(0)
+	for _, reveal := range reveals {
+		if reveal.Status == RevealValid {
+			sum.Add(sum, common.BytesToHash(reveal.PrivateData).Big())
+		}
+	}
+	return sum
+}
+
+// GetVoteRevealsByHash returns the broadcast roots the seeds of the block are
+// derived from, together with the commit/reveal records stored under them.
+func GetVoteRevealsByHash(hash common.Hash, bc baseinterface.ChainReader) (*mc.PreBroadStateRoot, []VoteReveal, error) {
+	st, err := bc.StateAtBlockHash(hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	roots, err := matrixstate.GetPreBroadcastRoot(st)
+	if err != nil {
+		return nil, nil, err
+	}
+	if roots == nil {
+		return nil, nil, errors.New("pre broadcast root is nil")
+	}
+	private, err := getBroadcastKeys(bc, roots.LastStateRoot, mc.Privatekey)
+	if err != nil {
+		return nil, nil, err
+	}
+	public, err := getBroadcastKeys(bc, roots.BeforeLastStateRoot, mc.Publickey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return roots, GetVoteReveals(private, public), nil
+}
+
+// getBroadcastKeys reads the key transactions of a broadcast block, an empty
+// root or an absent key type yields an empty map.
+func getBroadcastKeys(bc core.ChainReader, root common.Hash, txType string) (map[common.Address][]byte, error) {
+	if root == (common.Hash{}) {
+		return nil, nil
+	}
+	st, err := bc.StateAt(root)
+	if err != nil {
+		return nil, err
+	}
+	txs, err := matrixstate.GetBroadcastTxs(st)
+	if err != nil {
+		return nil, err
+	}
+	return txs.FindKey(txType), nil
+}
+
+// NewRandomWithholdProducer returns the matrix state producer tracking the
+// participants who committed a public key but withheld the private key. It runs
+// in the block after each broadcast block, comparing the commitments of the
+// previous broadcast block with the reveals of the new one. Withheld and invalid
+// reveals count as withholding, a valid reveal resets the consecutive count.
+// The statistics start with VersionBeta.
+func NewRandomWithholdProducer(bc baseinterface.ChainReader) core.ProduceMatrixStateDataFn {
+	return func(block *types.Block, readFn core.PreStateReadFn) (interface{}, error) {
+		// 父区块的版本不支持该状态时不统计
+		version, err := readFn(mc.MSKeyVersionInfo)
+		if err != nil {
+			return nil, err
+		}
+		if preVersion, _ := version.(string); !manparams.IsBetaVersion(preVersion) {
+			return nil, nil
+		}
+		bciData, err := readFn(mc.MSKeyBroadcastInterval)
+		if err != nil {
+			log.Error(ModeleRandomCommon, "统计未公开私钥阶段,获取广播周期失败 err", err)
+			return nil, err
+		}
+		bcInterval, ok := bciData.(*mc.BCIntervalInfo)
+		if !ok || bcInterval == nil {
+			return nil, errors.New("broadcast interval reflect failed")
+		}
+		height := block.NumberU64()
+		if height == 0 || !bcInterval.IsBroadcastNumber(height-1) {
+			return nil, nil
+		}
+		// 读取到的是更新前的值, LastStateRoot仍是上一个广播区块
+		rootData, err := readFn(mc.MSKeyPreBroadcastRoot)
+		if err != nil {
+			log.Error(ModeleRandomCommon, "统计未公开私钥阶段,获取广播区块root失败 err", err)
+			return nil, err
+		}
+		roots, ok := rootData.(*mc.PreBroadStateRoot)
+		if !ok || roots == nil {
+			return nil, errors.New("pre broadcast root reflect failed")
+		}
+		header := bc.GetHeaderByHash(block.ParentHash())
+		if header == nil {
+			return nil, errors.New("broadcast block header is nil")
+		}
+		public, err := getBroadcastKeys(bc, roots.LastStateRoot, mc.Publickey)
+		if err != nil {
+			log.Error(ModeleRandomCommon, "统计未公开私钥阶段,获取公钥失败 err", err)
+			return nil, err
+		}
+		if len(public) == 0 {
+			return nil, nil
+		}
+		private, err := getBroadcastKeys(bc, header.Root, mc.Privatekey)
+		if err != nil {
+			log.Error(ModeleRandomCommon, "统计未公开私钥阶段,获取私钥失败 err", err)
+			return nil, err
+		}
+		data, err := readFn(mc.MSKeyRandomWithhold)
+		if err != nil {
+			log.Error(ModeleRandomCommon, "统计未公开私钥阶段,获取统计信息失败 err", err)
+			return nil, err
+		}
+		info, ok := data.(*mc.RandomWithholdInfo)
+		if !ok || info == nil {
+			info = new(mc.RandomWithholdInfo)
+		}
+		keep := bcInterval.GetBroadcastInterval() * RandomWithholdKeepRounds
+		return UpdateRandomWithhold(info, GetVoteReveals(private, public), height-1, keep), nil
+	}
+}
+
+// UpdateRandomWithhold applies the reveals of the broadcast block at number to
+// the withhold records, dropping the records of the participants who did not
+// withhold in the last keep blocks.
+func UpdateRandomWithhold(info *mc.RandomWithholdInfo, reveals []VoteReveal, number uint64, keep uint64) *mc.RandomWithholdInfo {
+	records := make(map[common.Address]mc.RandomWithholdRecord, len(info.Records))
+	for _, record := range info.Records {
+		records[record.Address] = record
+	}
+	for _, reveal := range reveals {
+		record, exist := records[reveal.Address]
+		if reveal.Status == RevealValid {
+			if exist {
+				record.Count = 0
+				records[reveal.Address] = record
+			}
+			continue
+		}
+		record.Address = reveal.Address
+		record.Count++
+		record.Total++
+		record.LastNumber = number
+		records[reveal.Address] = record
+	}
+	updated := &mc.RandomWithholdInfo{Number: number, Records: make([]mc.RandomWithholdRecord, 0, len(records))}
+	for _, record := range records {
+		if record.LastNumber+keep <= number {
+			continue
+		}
+		updated.Records = append(updated.Records, record)
+	}
+	sort.Slice(updated.Records, func(i, j int) bool {
+		return bytes.Compare(updated.Records[i].Address[:], updated.Records[j].Address[:]) < 0
+	})
+	return updated
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+package commonsupport
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+)
+
+var (
+	revealAddr1 = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	revealAddr2 = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	revealAddr3 = common.HexToAddress("0x3333333333333333333333333333333333333333")
+)
+
+func TestGetVoteReveals(t *testing.T) {
+	private1, public1, _ := GetVoteData()
+	private2, public2, _ := GetVoteData()
+	_, public3, _ := GetVoteData()
+
+	public := map[common.Address][]byte{revealAddr1: public1, revealAddr2: public2, revealAddr3: public3}
+	private := map[common.Address][]byte{
+		revealAddr1:                   private1.Bytes(),
+		revealAddr2:                   private1.Bytes(), // 与承诺的公钥不匹配
+		common.HexToAddress("0x4444"): private2.Bytes(),
+	}
+	reveals := GetVoteReveals(private, public)
+	if len(reveals) != 3 {
+		t.Fatalf("reveals mismatch: have %d, want %d", len(reveals), 3)
+	}
+	want := []struct {
+		addr   common.Address
+		status string
+	}{{revealAddr1, RevealValid}, {revealAddr2, RevealInvalid}, {revealAddr3, RevealWithheld}}
+	for i, w := range want {
+		if reveals[i].Address != w.addr || reveals[i].Status != w.status {
+			t.Errorf("reveal %d mismatch: have %x %s, want %x %s", i, reveals[i].Address, reveals[i].Status, w.addr, w.status)
+		}
+	}
+	if sum := GetRevealSum(reveals); sum.Cmp(private1) != 0 {
+		t.Errorf("reveal sum mismatch: have %v, want %v", sum, private1)
+	}
+	if sum := GetValidPrivateSum(GetCommonMap(private, public)); sum.Cmp(GetRevealSum(reveals)) != 0 {
+		t.Errorf("reveal sum differs from the common map sum: have %v, want %v", GetRevealSum(reveals), sum)
+	}
+}
+
+func TestUpdateRandomWithhold(t *testing.T) {
+	info := &mc.RandomWithholdInfo{}
+	withheld := []VoteReveal{{Address: revealAddr1, Status: RevealWithheld}, {Address: revealAddr2, Status: RevealInvalid}}
+
+	info = UpdateRandomWithhold(info, withheld, 100, 1000)
+	info = UpdateRandomWithhold(info, withheld, 200, 1000)
+	if len(info.Records) != 2 || info.Number != 200 {
+		t.Fatalf("records mismatch: %+v", info)
+	}
+	for _, record := range info.Records {
+		if record.Count != 2 || record.Total != 2 || record.LastNumber != 200 {
+			t.Errorf("record mismatch: %+v", record)
+		}
+	}
+	// 公开私钥后清零连续次数, 累计次数保留
+	info = UpdateRandomWithhold(info, []VoteReveal{{Address: revealAddr1, Status: RevealValid}, {Address: revealAddr3, Status: RevealValid}}, 300, 1000)
+	if len(info.Records) != 2 {
+		t.Fatalf("valid reveal without a record created one: %+v", info.Records)
+	}
+	if record := info.Records[0]; record.Address != revealAddr1 || record.Count != 0 || record.Total != 2 || record.LastNumber != 200 {
+		t.Errorf("record of the revealed participant mismatch: %+v", record)
+	}
+	if record := info.Records[1]; record.Address != revealAddr2 || record.Count != 2 {
+		t.Errorf("record of the absent participant changed: %+v", record)
+	}
+	// 超过保留区间没有再次扣留的记录被删除
+	info = UpdateRandomWithhold(info, []VoteReveal{{Address: revealAddr2, Status: RevealWithheld}}, 1200, 1000)
+	if len(info.Records) != 1 || info.Records[0].Address != revealAddr2 || info.Records[0].Count != 3 {
+		t.Fatalf("expired records not pruned: %+v", info.Records)
+	}
+	if info = UpdateRandomWithhold(info, nil, 2200, 1000); len(info.Records) != 0 {
+		t.Fatalf("expired records not pruned: %+v", info.Records)
+	}
+}
+
+func TestRandomWithholdProducerVersion(t *testing.T) {
+	producer := NewRandomWithholdProducer(nil)
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(101)})
+	bcInterval := &mc.BCIntervalInfo{BCInterval: 100}
+
+	var read []string
+	readFn := func(version string) func(key string) (interface{}, error) {
+		return func(key string) (interface{}, error) {
+			read = append(read, key)
+			switch key {
+			case mc.MSKeyVersionInfo:
+				return version, nil
+			case mc.MSKeyBroadcastInterval:
+				return bcInterval, nil
+			}
+			return nil, nil
+		}
+	}
+	// Alpha版本不读取统计状态
+	data, err := producer(block, readFn(manparams.VersionAlpha))
+	if data != nil || err != nil {
+		t.Fatalf("alpha producer result: have %v %v, want nil", data, err)
+	}
+	if len(read) != 1 {
+		t.Fatalf("alpha producer read the state: %v", read)
+	}
+	// Beta版本只在广播区块后统计
+	read = nil
+	block = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(150)})
+	if data, err = producer(block, readFn(manparams.VersionBeta)); data != nil || err != nil {
+		t.Fatalf("beta producer result off the broadcast block: have %v %v, want nil", data, err)
+	}
+	if len(read) != 2 {
+		t.Fatalf("beta producer reads mismatch: %v", read)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+package reelection
+
+import (
+	"errors"
+	"math/big"
+	"math/rand"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/election/support"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+)
+
+const (
+	DefaultDryRunRounds = 1000 // 默认蒙特卡洛模拟次数
+	MaxDryRunRounds     = 2000 // 最大蒙特卡洛模拟次数
+)
+
+// DryRunRounds returns the number of simulated elections actually run for the
+// requested rounds.
+func DryRunRounds(rounds int) int {
+	if rounds <= 0 {
+		return DefaultDryRunRounds
+	}
+	if rounds > MaxDryRunRounds {
+		return MaxDryRunRounds
+	}
+	return rounds
+}
+
+// ElectDryRunCandidate is the estimated election outcome of a candidate.
+type ElectDryRunCandidate struct {
+	Address    common.Address
+	Deposit    *big.Int
+	VIPLevel   common.VIPRoleType
+	Weight     float64                        // 在全部候选人中归一化的权重
+	TierWeight float64                        // 在同等级及以上候选人中归一化的权重
+	Master     float64                        // 当选主节点(验证者主节点或矿工)的概率
+	Backup     float64                        // 当选备份验证者的概率
+	Candidate  float64                        // 成为候选验证者的概率
+	Tiers      map[common.VIPRoleType]float64 // 在各VIP等级当选主节点或备份节点的概率
+}
+
+// ElectDryRunResult is the outcome of an election dry run.
+type ElectDryRunResult struct {
+	Role       common.RoleType
+	Number     uint64 // 估算所基于的区块高度
+	GenNumber  uint64 // 下一次选举生成的高度
+	Rounds     int
+	Candidates []*ElectDryRunCandidate
+}
+
+// ElectDryRun estimates the outcome of the next election of the role from the
+// deposit list and the election config at the block. The deposits in extra are
+// added on top of the list, new accounts join it as candidates. The seat
+// probabilities are estimated by running the election plug with random seeds.
+func (self *ReElection) ElectDryRun(hash common.Hash, role common.RoleType, extra map[common.Address]*big.Int, rounds int) (*ElectDryRunResult, error) {
+	if role != common.RoleMiner && role != common.RoleValidator {
+		return nil, errors.New("选举身份错误")
+	}
+	rounds = DryRunRounds(rounds)
+	height, err := self.GetNumberByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	genData, err := self.GetElectGenTimes(hash)
+	if err != nil {
+		return nil, err
+	}
+	bcInterval, err := self.GetBroadcastIntervalByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	genTime := uint64(genData.MinerGen)
+	if role == common.RoleValidator {
+		genTime = uint64(genData.ValidatorGen)
+	}
+	deposits, err := GetAllElectedByHash(hash, role)
+	if err != nil {
+		return nil, err
+	}
+	deposits = addDryRunDeposits(deposits, extra)
+
+	elect, err := self.GetElectPlug(hash)
+	if err != nil {
+		return nil, err
+	}
+	electConf, err := self.GetElectConfig(hash)
+	if err != nil {
+		return nil, err
+	}
+	vipList, err := self.GetViPList(hash)
+	if err != nil {
+		return nil, err
+	}
+	produceBlackList, err := self.addBlockProduceBlackList(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ElectDryRunResult{
+		Role:      role,
+		Number:    height,
+		GenNumber: bcInterval.GetNextReElectionNumber(height) - genTime,
+		Rounds:    rounds,
+	}
+	if role == common.RoleMiner {
+		vipList = nil
+	}
+	candidates := dryRunWeights(vipList, deposits, *electConf, role)
+
+	// 种子由区块hash决定, 同一区块的估算结果可重现
+	rng := rand.New(rand.NewSource(hash.Big().Int64()))
+	for i := 0; i < rounds; i++ {
+		seed := big.NewInt(rng.Int63())
+		if role == common.RoleMiner {
+			rsp := elect.MinerTopGen(&mc.MasterMinerReElectionReqMsg{SeqNum: height, RandSeed: seed, MinerList: deposits, ElectConfig: *electConf})
+			countDryRunSeats(candidates, rsp.MasterMiner, func(c *ElectDryRunCandidate) *float64 { return &c.Master })
+			continue
+		}
+		rsp := elect.ValidatorTopGen(&mc.MasterValidatorReElectionReqMsg{SeqNum: height, RandSeed: seed, ValidatorList: deposits, ElectConfig: *electConf, VIPList: vipList, BlockProduceBlackList: *produceBlackList})
+		countDryRunSeats(candidates, rsp.MasterValidator, func(c *ElectDryRunCandidate) *float64 { return &c.Master })
+		countDryRunSeats(candidates, rsp.BackUpValidator, func(c *ElectDryRunCandidate) *float64 { return &c.Backup })
+		countDryRunSeats(candidates, rsp.CandidateValidator, func(c *ElectDryRunCandidate) *float64 { return &c.Candidate })
+	}
+	for _, candidate := range candidates {
+		candidate.Master /= float64(rounds)
+		candidate.Backup /= float64(rounds)
+		candidate.Candidate /= float64(rounds)
+		for level := range candidate.Tiers {
+			candidate.Tiers[level] /= float64(rounds)
+		}
+		result.Candidates = append(result.Candidates, candidate)
+	}
+	log.Debug(Module, "选举模拟完成 身份", role, "高度", height, "候选人数", len(result.Candidates), "次数", rounds)
+	return result, nil
+}
+
+// addDryRunDeposits returns a copy of the deposit list with the hypothetical
+// deposits added.
+func addDryRunDeposits(deposits []vm.DepositDetail, extra map[common.Address]*big.Int) []vm.DepositDetail {
+	list := make([]vm.DepositDetail, 0, len(deposits)+len(extra))
+	added := make(map[common.Address]bool)
+	for _, detail := range deposits {
+		if amount, ok := extra[detail.Address]; ok && amount != nil {
+			deposit := new(big.Int).Set(amount)
+			if detail.Deposit != nil {
+				deposit.Add(deposit, detail.Deposit)
+			}
+			detail.Deposit = deposit
+			added[detail.Address] = true
+		}
+		list = append(list, detail)
+	}
+	for addr, amount := range extra {
+		if added[addr] || amount == nil {
+			continue
+		}
+		list = append(list, vm.DepositDetail{
+			Address:     addr,
+			SignAddress: addr,
+			Deposit:     new(big.Int).Set(amount),
+			WithdrawH:   big.NewInt(0),
+			OnlineTime:  big.NewInt(0),
+		})
+	}
+	return list
+}
+
+// dryRunWeights computes the normalized weights of the candidates eligible for
+// the election, the same value function the election plugs sample with.
+func dryRunWeights(vipList []mc.VIPConfig, deposits []vm.DepositDetail, electConf mc.ElectConfigInfo_All, role common.RoleType) map[common.Address]*ElectDryRunCandidate {
+	vipEle := support.NewElelection(vipList, deposits, electConf, big.NewInt(0), 0, role)
+	if electConf.WhiteListSwitcher {
+		vipEle.ProcessWhiteNode()
+	}
+	vipEle.ProcessBlackNode()
+
+	nodes := vipEle.GetLastNode()
+	total := 0.0
+	for _, value := range support.CalcValue(nodes, role) {
+		total += value.Value
+	}
+	tierTotal := make(map[common.VIPRoleType]float64)
+	candidates := make(map[common.Address]*ElectDryRunCandidate, len(nodes))
+	for _, node := range nodes {
+		level := node.VIPLevel()
+		if _, ok := tierTotal[level]; !ok {
+			for _, value := range support.CalcValue(vipEle.GetNodeByLevel(level), role) {
+				tierTotal[level] += value.Value
+			}
+		}
+		value := support.CalcValue([]support.Node{node}, role)[0].Value
+		candidate := &ElectDryRunCandidate{
+			Address:  node.Address,
+			Deposit:  node.Deposit,
+			VIPLevel: level,
+			Tiers:    make(map[common.VIPRoleType]float64),
+		}
+		if total > 0 {
+			candidate.Weight = value / total
+		}
+		if tierTotal[level] > 0 {
+			candidate.TierWeight = value / tierTotal[level]
+		}
+		candidates[node.Address] = candidate
+	}
+	return candidates
+}
+
+func countDryRunSeats(candidates map[common.Address]*ElectDryRunCandidate, elected []mc.ElectNodeInfo, counter func(*ElectDryRunCandidate) *float64) {
+	for _, node := range elected {
+		candidate, ok := candidates[node.Account]
+		if !ok {
+			continue
+		}
+		*counter(candidate)++
+		if node.Type != common.RoleCandidateValidator {
+			candidate.Tiers[node.VIPLevel]++
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+package reelection
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/baseinterface"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	_ "github.com/MatrixAINetwork/go-matrix/election/layered"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+)
+
+func dryRunDeposit(addr common.Address, man int64) vm.DepositDetail {
+	return vm.DepositDetail{
+		Address:     addr,
+		SignAddress: addr,
+		Deposit:     new(big.Int).Mul(big.NewInt(man), common.ManValue),
+		WithdrawH:   big.NewInt(0),
+		OnlineTime:  big.NewInt(0),
+	}
+}
+
+// Tests that the seat probabilities estimated from seeded elections of a single
+// miner seat follow the normalized weights of the candidates.
+func TestDryRunSeats(t *testing.T) {
+	deposits := []vm.DepositDetail{
+		dryRunDeposit(common.HexToAddress("0x01"), 10000),
+		dryRunDeposit(common.HexToAddress("0x02"), 20000),
+		dryRunDeposit(common.HexToAddress("0x03"), 70000),
+	}
+	electConf := mc.ElectConfigInfo_All{MinerNum: 1, ElectPlug: manparams.ElectPlug_layerd}
+
+	candidates := dryRunWeights(nil, deposits, electConf, common.RoleMiner)
+	if len(candidates) != len(deposits) {
+		t.Fatalf("candidate count mismatch: have %d, want %d", len(candidates), len(deposits))
+	}
+	total := 0.0
+	for _, deposit := range deposits {
+		candidate := candidates[deposit.Address]
+		if candidate == nil {
+			t.Fatalf("candidate %x missing", deposit.Address)
+		}
+		if candidate.Weight != candidate.TierWeight {
+			t.Errorf("candidate %x: tier weight %f differs from weight %f in a single tier", deposit.Address, candidate.TierWeight, candidate.Weight)
+		}
+		total += candidate.Weight
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Fatalf("weights sum to %f, want 1", total)
+	}
+
+	elect := baseinterface.NewElect(manparams.ElectPlug_layerd)
+	rounds := 2000
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < rounds; i++ {
+		rsp := elect.MinerTopGen(&mc.MasterMinerReElectionReqMsg{SeqNum: 100, RandSeed: big.NewInt(rng.Int63()), MinerList: deposits, ElectConfig: electConf})
+		countDryRunSeats(candidates, rsp.MasterMiner, func(c *ElectDryRunCandidate) *float64 { return &c.Master })
+	}
+	seats := 0.0
+	for _, deposit := range deposits {
+		candidate := candidates[deposit.Address]
+		seats += candidate.Master
+		if candidate.Tiers[common.VIP_Nil] != candidate.Master {
+			t.Errorf("candidate %x: tier seats %f differ from seats %f", deposit.Address, candidate.Tiers[common.VIP_Nil], candidate.Master)
+		}
+		if p := candidate.Master / float64(rounds); math.Abs(p-candidate.Weight) > 0.03 {
+			t.Errorf("candidate %x: seat probability %f, want %f", deposit.Address, p, candidate.Weight)
+		}
+	}
+	if seats != float64(rounds) {
+		t.Errorf("seats mismatch: have %f, want %d", seats, rounds)
+	}
+}
+
+// Tests that seats of unknown accounts are ignored and candidate validator seats
+// are not counted in the VIP tiers.
+func TestCountDryRunSeats(t *testing.T) {
+	known := common.HexToAddress("0x01")
+	candidates := map[common.Address]*ElectDryRunCandidate{
+		known: {Address: known, Tiers: make(map[common.VIPRoleType]float64)},
+	}
+	master := func(c *ElectDryRunCandidate) *float64 { return &c.Master }
+	candidate := func(c *ElectDryRunCandidate) *float64 { return &c.Candidate }
+
+	countDryRunSeats(candidates, []mc.ElectNodeInfo{
+		{Account: known, Type: common.RoleValidator, VIPLevel: common.VIP_1},
+		{Account: common.HexToAddress("0x02"), Type: common.RoleValidator},
+	}, master)
+	countDryRunSeats(candidates, []mc.ElectNodeInfo{{Account: known, Type: common.RoleCandidateValidator, VIPLevel: common.VIP_1}}, candidate)
+
+	if c := candidates[known]; c.Master != 1 || c.Candidate != 1 || c.Tiers[common.VIP_1] != 1 || len(candidates) != 1 {
+		t.Errorf("seats mismatch: %+v", c)
+	}
+}
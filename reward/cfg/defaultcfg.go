@@ -85,6 +85,7 @@ type ChainReader interface {
 type SetRewardsExec interface {
 	SetLeaderRewards(reward *big.Int, Leader common.Address, num uint64) map[common.Address]*big.Int
 	SetMinerOutRewards(reward *big.Int, state util.StateDB, chain util.ChainReader, num uint64, parentHash common.Hash, innerMiners []common.Address, rewardType uint8) map[common.Address]*big.Int
+	GetMinerOutRewards(preReward *big.Int, state util.StateDB, chain util.ChainReader, num uint64, parentHash common.Hash, innerMiners []common.Address) map[common.Address]*big.Int
 	GetSelectedRewards(reward *big.Int, state util.StateDB, chain util.ChainReader, roleType common.RoleType, number uint64, rate uint64, topology *mc.TopologyGraph, elect *mc.ElectGraph) map[common.Address]*big.Int //todo 金额
 }
 type DefaultSetRewards struct {
@@ -114,6 +115,10 @@ func (str *DefaultSetRewards) SetMinerOutRewards(reward *big.Int, state util.Sta
 
 	return str.miner.SetMinerOutRewards(reward, state, num, parentHash, chain, innerMiners, rewardType)
 }
+func (str *DefaultSetRewards) GetMinerOutRewards(preReward *big.Int, state util.StateDB, chain util.ChainReader, num uint64, parentHash common.Hash, innerMiners []common.Address) map[common.Address]*big.Int {
+
+	return str.miner.GetMinerOutRewards(preReward, state, num, parentHash, chain, innerMiners)
+}
 
 func New(RewardMount *mc.BlkRewardCfg, SetReward SetRewardsExec) *RewardCfg {
 	//默认配置
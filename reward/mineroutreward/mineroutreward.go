@@ -114,6 +114,29 @@ func (mr *MinerOutReward) SetMinerOutRewards(curReward *big.Int, state util.Stat
 	return rewards
 }
 
+// GetMinerOutRewards pays preReward, the reward deferred by the miner of the
+// parent block, without reading or updating the reward kept in the state.
+func (mr *MinerOutReward) GetMinerOutRewards(preReward *big.Int, state util.StateDB, num uint64, parentHash common.Hash, reader util.ChainReader, innerMiners []common.Address) map[common.Address]*big.Int {
+	bcInterval, err := matrixstate.GetBroadcastInterval(state)
+	if err != nil {
+		log.Error(PackageName, "获取广播周期失败", err)
+		return nil
+	}
+	if bcInterval.IsBroadcastNumber(num) {
+		log.WARN(PackageName, "广播区块不发钱：", num)
+		return nil
+	}
+
+	coinBase, err := mr.canSetMinerOutRewards(num, preReward, reader, bcInterval, parentHash, innerMiners)
+	if nil != err {
+		return nil
+	}
+
+	rewards := make(map[common.Address]*big.Int)
+	util.SetAccountRewards(rewards, coinBase, preReward)
+	return rewards
+}
+
 func (mr *MinerOutReward) canSetMinerOutRewards(num uint64, reward *big.Int, reader util.ChainReader, bcInterval *mc.BCIntervalInfo, parentHash common.Hash, innerMiners []common.Address) (common.Address, error) {
 	if num < 2 {
 		log.Debug(PackageName, "高度为小于2 不发放奖励：", "")
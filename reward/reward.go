@@ -12,6 +12,7 @@ import (
 // gas分段计价 第二笔gas，0x80001垫付，写入创世配置文件，初始金额，网络组判断  ，多币种和子链需要考虑，配置超级节点上链。
 type Reward interface {
 	CalcNodesRewards(blockReward *big.Int, Leader common.Address, num uint64, parentHash common.Hash) map[common.Address]*big.Int
+	CalcCurrencyNodesRewards(blockReward *big.Int, preMinerReward *big.Int, Leader common.Address, num uint64, parentHash common.Hash) (map[common.Address]*big.Int, *big.Int)
 	CalcValidatorRewards(Leader common.Address, num uint64) map[common.Address]*big.Int
 	CalcMinerRewards(num uint64, parentHash common.Hash) map[common.Address]*big.Int
 	CalcMinerRateMount(blockReward *big.Int) (*big.Int, *big.Int, *big.Int)
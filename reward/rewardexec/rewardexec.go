@@ -107,10 +107,13 @@ func (br *BlockReward) getValidatorRewards(blockReward *big.Int, Leader common.A
 }
 
 func (br *BlockReward) getMinerRewards(blockReward *big.Int, num uint64, rewardType uint8, parentHash common.Hash) map[common.Address]*big.Int {
-	rewards := make(map[common.Address]*big.Int, 0)
-
 	minerOutAmount, electedMount, FoundationsMount := br.CalcMinerRateMount(blockReward)
 	minerOutReward := br.rewardCfg.SetReward.SetMinerOutRewards(minerOutAmount, br.st, br.chain, num, parentHash, br.innerMinerAccounts, rewardType)
+	return br.mergeMinerRewards(minerOutReward, electedMount, FoundationsMount, num)
+}
+
+func (br *BlockReward) mergeMinerRewards(minerOutReward map[common.Address]*big.Int, electedMount *big.Int, FoundationsMount *big.Int, num uint64) map[common.Address]*big.Int {
+	rewards := make(map[common.Address]*big.Int, 0)
 	electReward := br.rewardCfg.SetReward.GetSelectedRewards(electedMount, br.st, br.chain, common.RoleMiner|common.RoleBackupMiner, num, br.rewardCfg.RewardMount.RewardRate.BackupRewardRate, br.topology, br.elect)
 	foundationReward := br.calcFoundationRewards(FoundationsMount, num)
 	util.MergeReward(rewards, minerOutReward)
@@ -192,6 +195,40 @@ func (br *BlockReward) CalcNodesRewards(blockReward *big.Int, Leader common.Addr
 	return rewards
 }
 
+// CalcCurrencyNodesRewards splits the tx fees of a currency other than MAN the
+// same way as CalcNodesRewards. The reward of the block miner is paid one block
+// later as well: preMinerReward is the reward deferred by the parent block, and
+// the reward deferred by this block is returned instead of being kept in the
+// state.
+func (br *BlockReward) CalcCurrencyNodesRewards(blockReward *big.Int, preMinerReward *big.Int, Leader common.Address, num uint64, parentHash common.Hash) (map[common.Address]*big.Int, *big.Int) {
+
+	if nil == br.rewardCfg {
+		log.Error(PackageName, "奖励配置为空", "")
+		return nil, preMinerReward
+	}
+
+	if br.bcInterval.IsBroadcastNumber(num) {
+		log.WARN(PackageName, "广播周期不处理", "")
+		return nil, preMinerReward
+	}
+
+	rewards := make(map[common.Address]*big.Int, 0)
+	minersBlkReward := util.CalcRateReward(blockReward, br.rewardCfg.MinersRate)
+	minerOutAmount, electedMount, FoundationsMount := br.CalcMinerRateMount(minersBlkReward)
+	minerOutReward := br.rewardCfg.SetReward.GetMinerOutRewards(preMinerReward, br.st, br.chain, num, parentHash, br.innerMinerAccounts)
+	minerRewards := br.mergeMinerRewards(minerOutReward, electedMount, FoundationsMount, num)
+	if blockReward.Cmp(big.NewInt(0)) <= 0 {
+		return minerRewards, minerOutAmount
+	}
+
+	validatorsBlkReward := util.CalcRateReward(blockReward, br.rewardCfg.ValidatorsRate)
+	validatorReward := br.getValidatorRewards(validatorsBlkReward, Leader, num)
+
+	util.MergeReward(rewards, validatorReward)
+	util.MergeReward(rewards, minerRewards)
+	return rewards, minerOutAmount
+}
+
 func (br *BlockReward) GetRewardCfg() *cfg.RewardCfg {
 
 	return br.rewardCfg
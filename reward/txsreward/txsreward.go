@@ -1,6 +1,10 @@
 package txsreward
 
 import (
+	"math/big"
+	"sort"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
 	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 	"github.com/MatrixAINetwork/go-matrix/log"
 	"github.com/MatrixAINetwork/go-matrix/mc"
@@ -71,3 +75,47 @@ func New(chain util.ChainReader, st util.StateDB, preSt util.StateDB) reward.Rew
 	cfg.MinersRate = TC.MinersRate
 	return rewardexec.New(chain, cfg, st, interval, foundationAccount, innerMinerAccounts, currentTop, originElectNodes)
 }
+
+// CalcCurrencyRewards distributes the tx fees of every currency other than MAN
+// the same way as the MAN tx fees and returns one reward per currency, sorted by
+// currency. The reward of the block miner is paid one block later: preRewards
+// are the rewards deferred by the parent block, and the rewards deferred by this
+// block are returned with the reward list.
+func CalcCurrencyRewards(txsReward reward.Reward, fees map[string]*big.Int, preRewards *mc.MultiCoinMinerOutRewards, Leader common.Address, num uint64, parentHash common.Hash) ([]common.RewarTx, *mc.MultiCoinMinerOutRewards) {
+	preMap := make(map[string]*big.Int)
+	for _, pre := range preRewards.Rewards {
+		preMap[pre.CoinType] = new(big.Int).Set(&pre.Reward)
+	}
+	currencies := make([]string, 0, len(fees)+len(preMap))
+	for coin := range fees {
+		if _, ok := preMap[coin]; !ok && coin != "MAN" {
+			currencies = append(currencies, coin)
+		}
+	}
+	for coin := range preMap {
+		currencies = append(currencies, coin)
+	}
+	sort.Strings(currencies)
+
+	rewardList := make([]common.RewarTx, 0, len(currencies))
+	newPreRewards := &mc.MultiCoinMinerOutRewards{Rewards: make([]mc.MultiCoinMinerOutReward, 0, len(currencies))}
+	for _, coin := range currencies {
+		pre, ok := preMap[coin]
+		if !ok {
+			pre = big.NewInt(0)
+		}
+		fee, ok := fees[coin]
+		if !ok {
+			fee = big.NewInt(0)
+		}
+		rewards, minerReward := txsReward.CalcCurrencyNodesRewards(fee, pre, Leader, num, parentHash)
+		if 0 != len(rewards) {
+			rewardList = append(rewardList, common.RewarTx{CoinType: coin, Fromaddr: common.TxGasRewardAddress, To_Amont: rewards, RewardTyp: common.RewardTxsType})
+		}
+		if minerReward.Sign() > 0 {
+			newPreRewards.Rewards = append(newPreRewards.Rewards, mc.MultiCoinMinerOutReward{CoinType: coin, Reward: *minerReward})
+		}
+	}
+	log.INFO(PackageName, "多币种交易费奖励", fees)
+	return rewardList, newPreRewards
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// maxRateLimiters is the number of client rate limiters kept before the idle
+// ones are dropped.
+const maxRateLimiters = 4096
+
+var (
+	errMissingToken  = errors.New("missing bearer token")
+	errTokenNoExpiry = errors.New("bearer token has no expiry")
+	errIPNotAllowed  = errors.New("client address not allowed")
+)
+
+// AuthConfig configures the access control of the HTTP and WebSocket endpoints.
+// The zero value allows everything.
+type AuthConfig struct {
+	JWTSecret  []byte   // HMAC key of the required bearer tokens, tokens aren't required if empty
+	AllowedIPs []string // IPs or CIDR ranges allowed to connect, any address if empty
+	RateLimit  float64  // sustained requests per second per client, unlimited if zero
+	RateBurst  int      // requests a client may issue at once on top of the rate
+}
+
+// Enabled returns whether the config restricts anything.
+func (cfg *AuthConfig) Enabled() bool {
+	return len(cfg.JWTSecret) > 0 || len(cfg.AllowedIPs) > 0 || cfg.RateLimit > 0
+}
+
+// AuthClaims are the claims of the JWT bearer tokens. Namespaces lists the API
+// namespaces and Methods the single methods ("namespace_method") the token may
+// call, "*" allowing everything. Tokens must carry an expiry ("exp").
+type AuthClaims struct {
+	Namespaces []string `json:"namespaces,omitempty"`
+	Methods    []string `json:"methods,omitempty"`
+	jwt.StandardClaims
+}
+
+// allows returns whether the claims permit calling the given method.
+func (c *AuthClaims) allows(namespace, method string) bool {
+	for _, ns := range c.Namespaces {
+		if ns == "*" || ns == namespace {
+			return true
+		}
+	}
+	name := namespace + serviceMethodSeparator + method
+	for _, m := range c.Methods {
+		if m == "*" || m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NewAuthToken creates a bearer token with the given claims signed by secret.
+func NewAuthToken(secret []byte, claims *AuthClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// request is rejected by the access control of the endpoint
+type unauthorizedError struct{ message string }
+
+func (e *unauthorizedError) ErrorCode() int { return -32001 }
+
+func (e *unauthorizedError) Error() string { return e.message }
+
+// client exceeded its request rate
+type rateLimitError struct{}
+
+func (e *rateLimitError) ErrorCode() int { return -32005 }
+
+func (e *rateLimitError) Error() string { return "request rate limit exceeded" }
+
+// authInfoKey is the context key of the authInfo of a connection.
+type authInfoKey struct{}
+
+// authInfo is the identity a connection was authenticated with.
+type authInfo struct {
+	client string      // token subject, or the remote IP for anonymous clients
+	claims *AuthClaims // nil if tokens aren't required
+}
+
+// rateLimiter is a token bucket refilled at the configured rate.
+type rateLimiter struct {
+	tokens float64
+	last   time.Time
+}
+
+// authenticator enforces an AuthConfig on the requests of a Server.
+type authenticator struct {
+	secret []byte
+	nets   []*net.IPNet
+	rate   float64
+	burst  float64
+
+	lock     sync.Mutex
+	limiters map[string]*rateLimiter
+}
+
+func newAuthenticator(cfg AuthConfig) (*authenticator, error) {
+	a := &authenticator{
+		secret:   cfg.JWTSecret,
+		rate:     cfg.RateLimit,
+		burst:    float64(cfg.RateBurst),
+		limiters: make(map[string]*rateLimiter),
+	}
+	if a.burst < 1 {
+		a.burst = 1
+	}
+	for _, allowed := range cfg.AllowedIPs {
+		if !strings.Contains(allowed, "/") {
+			ip := net.ParseIP(allowed)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid allowed IP %q", allowed)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			a.nets = append(a.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipnet, err := net.ParseCIDR(allowed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed IP range %q: %v", allowed, err)
+		}
+		a.nets = append(a.nets, ipnet)
+	}
+	return a, nil
+}
+
+// authenticate checks the client address and bearer token of an HTTP request,
+// returning the identity of the client or the HTTP status to reject it with.
+func (a *authenticator) authenticate(r *http.Request) (*authInfo, int, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if len(a.nets) > 0 {
+		ip, allowed := net.ParseIP(host), false
+		for _, ipnet := range a.nets {
+			if ip != nil && ipnet.Contains(ip) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, http.StatusForbidden, errIPNotAllowed
+		}
+	}
+	info := &authInfo{client: host}
+	if len(a.secret) == 0 {
+		return info, 0, nil
+	}
+	// 浏览器的websocket无法设置header, 只在websocket握手时允许通过token参数传递
+	var token string
+	if isWebsocketUpgrade(r) {
+		token = r.URL.Query().Get("token")
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if token == "" {
+		return nil, http.StatusUnauthorized, errMissingToken
+	}
+	claims := new(AuthClaims)
+	_, err = jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return a.secret, nil
+	})
+	if err != nil {
+		return nil, http.StatusUnauthorized, fmt.Errorf("invalid bearer token: %v", err)
+	}
+	if claims.ExpiresAt == 0 {
+		return nil, http.StatusUnauthorized, errTokenNoExpiry
+	}
+	if claims.Subject != "" {
+		info.client = claims.Subject
+	}
+	info.claims = claims
+	return info, 0, nil
+}
+
+// isWebsocketUpgrade returns whether the request opens a WebSocket connection.
+func isWebsocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// authorize checks a single call of an authenticated client against the claims
+// of its token and its request rate.
+func (a *authenticator) authorize(info *authInfo, namespace, method string) Error {
+	// 取消订阅没有namespace, 只能取消本连接自己的订阅
+	if info.claims != nil && namespace != "" && namespace != MetadataApi && !info.claims.allows(namespace, method) {
+		return &unauthorizedError{fmt.Sprintf("method %s%s%s not allowed by token", namespace, serviceMethodSeparator, method)}
+	}
+	if a.rate > 0 && !a.take(info.client) {
+		return &rateLimitError{}
+	}
+	return nil
+}
+
+// take consumes a request token of the client, returning false if it has none.
+func (a *authenticator) take(client string) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := time.Now()
+	limiter := a.limiters[client]
+	if limiter == nil {
+		if len(a.limiters) >= maxRateLimiters {
+			a.dropIdle(now)
+		}
+		limiter = &rateLimiter{tokens: a.burst, last: now}
+		a.limiters[client] = limiter
+	}
+	limiter.tokens += now.Sub(limiter.last).Seconds() * a.rate
+	if limiter.tokens > a.burst {
+		limiter.tokens = a.burst
+	}
+	limiter.last = now
+	if limiter.tokens < 1 {
+		return false
+	}
+	limiter.tokens--
+	return true
+}
+
+// dropIdle removes the limiters that refilled completely, they would be
+// recreated in the same state.
+func (a *authenticator) dropIdle(now time.Time) {
+	for client, limiter := range a.limiters {
+		if limiter.tokens+now.Sub(limiter.last).Seconds()*a.rate >= a.burst {
+			delete(a.limiters, client)
+		}
+	}
+}
+
+// SetAuth enables access control on the HTTP and WebSocket requests served by
+// the server. IPC and in-process connections are not restricted.
+func (s *Server) SetAuth(cfg AuthConfig) error {
+	if !cfg.Enabled() {
+		s.auth = nil
+		return nil
+	}
+	auth, err := newAuthenticator(cfg)
+	if err != nil {
+		return err
+	}
+	s.auth = auth
+	return nil
+}
+
+// authorizeRequest checks a request against the identity its connection was
+// authenticated with, if any.
+func (s *Server) authorizeRequest(ctx context.Context, req *serverRequest) Error {
+	info, ok := ctx.Value(authInfoKey{}).(*authInfo)
+	if !ok || s.auth == nil {
+		return nil
+	}
+	method := ""
+	if req.callb != nil {
+		method = formatName(req.callb.method.Name)
+	}
+	return s.auth.authorize(info, req.svcname, method)
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var testAuthSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func newAuthTestServer(t *testing.T, cfg AuthConfig) *Server {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("other", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.SetAuth(cfg); err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+// authCall sends a JSON-RPC call to the server, returning the HTTP status and body.
+func authCall(server *Server, remote, token, method string) (int, string) {
+	body := `{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":[]}`
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/", strings.NewReader(body))
+	req.Header.Set("content-type", contentType)
+	req.RemoteAddr = remote
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestAuthTokens(t *testing.T) {
+	server := newAuthTestServer(t, AuthConfig{JWTSecret: testAuthSecret})
+
+	valid := jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	token, err := NewAuthToken(testAuthSecret, &AuthClaims{Namespaces: []string{"test"}, Methods: []string{"other_rets"}, StandardClaims: valid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := authCall(server, "1.2.3.4:5", "", "test_rets"); code != http.StatusUnauthorized {
+		t.Errorf("missing token: status %d", code)
+	}
+	if code, body := authCall(server, "1.2.3.4:5", token, "test_rets"); code != http.StatusOK || !strings.Contains(body, `"result"`) {
+		t.Errorf("allowed namespace: status %d body %s", code, body)
+	}
+	if _, body := authCall(server, "1.2.3.4:5", token, "other_rets"); !strings.Contains(body, `"result"`) {
+		t.Errorf("allowed method: body %s", body)
+	}
+	if _, body := authCall(server, "1.2.3.4:5", token, "other_noArgsRets"); !strings.Contains(body, "-32001") {
+		t.Errorf("denied method: body %s", body)
+	}
+	if _, body := authCall(server, "1.2.3.4:5", token, "rpc_modules"); !strings.Contains(body, `"result"`) {
+		t.Errorf("metadata: body %s", body)
+	}
+
+	forged, _ := NewAuthToken([]byte("another secret of enough length!"), &AuthClaims{Namespaces: []string{"*"}, StandardClaims: valid})
+	if code, _ := authCall(server, "1.2.3.4:5", forged, "test_rets"); code != http.StatusUnauthorized {
+		t.Errorf("forged token: status %d", code)
+	}
+	expired, _ := NewAuthToken(testAuthSecret, &AuthClaims{
+		Namespaces:     []string{"*"},
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(-time.Minute).Unix()},
+	})
+	if code, _ := authCall(server, "1.2.3.4:5", expired, "test_rets"); code != http.StatusUnauthorized {
+		t.Errorf("expired token: status %d", code)
+	}
+	// 没有过期时间的token永久有效, 不予接受
+	unlimited, _ := NewAuthToken(testAuthSecret, &AuthClaims{Namespaces: []string{"*"}})
+	if code, _ := authCall(server, "1.2.3.4:5", unlimited, "test_rets"); code != http.StatusUnauthorized {
+		t.Errorf("token without expiry: status %d", code)
+	}
+}
+
+func TestAuthQueryToken(t *testing.T) {
+	auth, err := newAuthenticator(AuthConfig{JWTSecret: testAuthSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := NewAuthToken(testAuthSecret, &AuthClaims{
+		Namespaces:     []string{"*"},
+		StandardClaims: jwt.StandardClaims{Subject: "browser", ExpiresAt: time.Now().Add(time.Hour).Unix()},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/?token="+token, nil)
+	req.RemoteAddr = "1.2.3.4:5"
+	if _, code, err := auth.authenticate(req); code != http.StatusUnauthorized || err != errMissingToken {
+		t.Errorf("query token on plain HTTP: status %d, err %v", code, err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	if info, _, err := auth.authenticate(req); err != nil || info.client != "browser" {
+		t.Errorf("query token on WebSocket upgrade: info %v, err %v", info, err)
+	}
+}
+
+func TestAuthAllowedIPs(t *testing.T) {
+	server := newAuthTestServer(t, AuthConfig{AllowedIPs: []string{"10.0.0.0/8", "192.168.1.7"}})
+
+	for remote, want := range map[string]int{
+		"10.1.2.3:100":    http.StatusOK,
+		"192.168.1.7:100": http.StatusOK,
+		"192.168.1.8:100": http.StatusForbidden,
+		"[::1]:100":       http.StatusForbidden,
+	} {
+		if code, _ := authCall(server, remote, "", "test_rets"); code != want {
+			t.Errorf("%s: status %d, want %d", remote, code, want)
+		}
+	}
+	if err := NewServer().SetAuth(AuthConfig{AllowedIPs: []string{"10.0.0"}}); err == nil {
+		t.Error("invalid address accepted")
+	}
+}
+
+func TestAuthRateLimit(t *testing.T) {
+	server := newAuthTestServer(t, AuthConfig{RateLimit: 0.001, RateBurst: 3})
+
+	for i := 0; i < 3; i++ {
+		if _, body := authCall(server, "1.2.3.4:5", "", "test_rets"); !strings.Contains(body, `"result"`) {
+			t.Fatalf("call %d: body %s", i, body)
+		}
+	}
+	if _, body := authCall(server, "1.2.3.4:5", "", "test_rets"); !strings.Contains(body, "-32005") {
+		t.Errorf("limited call: body %s", body)
+	}
+	// 限流按客户端计算
+	if _, body := authCall(server, "5.6.7.8:5", "", "test_rets"); !strings.Contains(body, `"result"`) {
+		t.Errorf("other client: body %s", body)
+	}
+}
@@ -170,6 +170,14 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// untilEOF and writes the response to w and order the server to process a
 	// single request.
 	ctx := context.Background()
+	if srv.auth != nil {
+		info, code, err := srv.auth.authenticate(r)
+		if err != nil {
+			http.Error(w, err.Error(), code)
+			return
+		}
+		ctx = context.WithValue(ctx, authInfoKey{}, info)
+	}
 	ctx = context.WithValue(ctx, "remote", r.RemoteAddr)
 	ctx = context.WithValue(ctx, "scheme", r.Proto)
 	ctx = context.WithValue(ctx, "local", r.Host)
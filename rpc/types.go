@@ -62,6 +62,8 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	auth *authenticator // access control of HTTP and WebSocket requests, nil if disabled
 }
 
 // rpcRequest represents a raw incoming RPC request
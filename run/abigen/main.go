@@ -0,0 +1,140 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+// abigen generates Go (or Java) contract bindings from an ABI, a bytecode and
+// a type name, or from Solidity sources compiled with solc. The generated
+// bindings send MATRIX transactions, see bind.TransactOpts.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/MatrixAINetwork/go-matrix/accounts/abi/bind"
+	"github.com/MatrixAINetwork/go-matrix/common/compiler"
+)
+
+var (
+	abiFlag = flag.String("abi", "", "Path to the MATRIX contract ABI json to bind, - for STDIN")
+	binFlag = flag.String("bin", "", "Path to the MATRIX contract bytecode (generate deploy method)")
+	typFlag = flag.String("type", "", "Struct name for the binding (default = package name)")
+
+	solFlag  = flag.String("sol", "", "Path to the MATRIX contract Solidity source to build and bind")
+	solcFlag = flag.String("solc", "solc", "Solidity compiler to use if source builds are requested")
+	excFlag  = flag.String("exc", "", "Comma separated types to exclude from binding")
+
+	pkgFlag  = flag.String("pkg", "", "Package name to generate the binding into")
+	outFlag  = flag.String("out", "", "Output file for the generated binding (default = stdout)")
+	langFlag = flag.String("lang", "go", "Destination language for the bindings (go, java)")
+)
+
+func main() {
+	// Parse and ensure all needed inputs are specified
+	flag.Parse()
+
+	if *abiFlag == "" && *solFlag == "" {
+		fmt.Printf("No contract ABI (--abi) or Solidity source (--sol) specified\n")
+		os.Exit(-1)
+	} else if *abiFlag != "" && *solFlag != "" {
+		fmt.Printf("Contract ABI (--abi) and Solidity source (--sol) flags are mutually exclusive\n")
+		os.Exit(-1)
+	}
+	if *pkgFlag == "" {
+		fmt.Printf("No destination package specified (--pkg)\n")
+		os.Exit(-1)
+	}
+	var lang bind.Lang
+	switch *langFlag {
+	case "go":
+		lang = bind.LangGo
+	case "java":
+		lang = bind.LangJava
+	default:
+		fmt.Printf("Unsupported destination language \"%s\" (--lang)\n", *langFlag)
+		os.Exit(-1)
+	}
+	// If the entire solidity code was specified, build and bind based on that
+	var (
+		abis  []string
+		bins  []string
+		types []string
+	)
+	if *solFlag != "" {
+		// Generate the list of types to exclude from binding
+		exclude := make(map[string]bool)
+		for _, kind := range strings.Split(*excFlag, ",") {
+			exclude[strings.ToLower(kind)] = true
+		}
+		contracts, err := compiler.CompileSolidity(*solcFlag, *solFlag)
+		if err != nil {
+			fmt.Printf("Failed to build Solidity contract: %v\n", err)
+			os.Exit(-1)
+		}
+		// Gather all non-excluded contract for binding
+		for name, contract := range contracts {
+			// solc 输出的合约名带有源文件前缀, 去掉后再判断
+			nameParts := strings.Split(name, ":")
+			typeName := nameParts[len(nameParts)-1]
+			if exclude[strings.ToLower(typeName)] {
+				continue
+			}
+			abi, err := json.Marshal(contract.Info.AbiDefinition)
+			if err != nil {
+				fmt.Printf("Failed to parse ABIs from compiler output: %v\n", err)
+				os.Exit(-1)
+			}
+			abis = append(abis, string(abi))
+			bins = append(bins, contract.Code)
+			types = append(types, typeName)
+		}
+	} else {
+		// Otherwise load up the ABI, optional bytecode and type name from the parameters
+		var abi []byte
+		var err error
+		if *abiFlag == "-" {
+			abi, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			abi, err = ioutil.ReadFile(*abiFlag)
+		}
+		if err != nil {
+			fmt.Printf("Failed to read input ABI: %v\n", err)
+			os.Exit(-1)
+		}
+		abis = append(abis, string(abi))
+
+		bin := []byte{}
+		if *binFlag != "" {
+			if bin, err = ioutil.ReadFile(*binFlag); err != nil {
+				fmt.Printf("Failed to read input bytecode: %v\n", err)
+				os.Exit(-1)
+			}
+		}
+		bins = append(bins, strings.TrimSpace(string(bin)))
+
+		kind := *typFlag
+		if kind == "" {
+			kind = *pkgFlag
+		}
+		types = append(types, kind)
+	}
+	// Generate the contract binding
+	code, err := bind.Bind(types, abis, bins, *pkgFlag, lang)
+	if err != nil {
+		fmt.Printf("Failed to generate ABI binding: %v\n", err)
+		os.Exit(-1)
+	}
+	// Either flush it out to a file or display on the standard output
+	if *outFlag == "" {
+		fmt.Printf("%s\n", code)
+		return
+	}
+	if err := ioutil.WriteFile(*outFlag, []byte(code), 0600); err != nil {
+		fmt.Printf("Failed to write ABI binding: %v\n", err)
+		os.Exit(-1)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+// bootnode runs a bootstrap node for the MATRIX Discovery Protocol. It only
+// takes part in the node discovery, without running a chain.
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/metrics"
+	"github.com/MatrixAINetwork/go-matrix/metrics/exp"
+	"github.com/MatrixAINetwork/go-matrix/p2p/discover"
+	"github.com/MatrixAINetwork/go-matrix/p2p/enr"
+	"github.com/MatrixAINetwork/go-matrix/p2p/nat"
+	"github.com/MatrixAINetwork/go-matrix/p2p/netutil"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+)
+
+var (
+	tableNodesGauge = metrics.NewRegisteredGauge("bootnode/table/nodes", nil)
+	tableIPsGauge   = metrics.NewRegisteredGauge("bootnode/table/ips", nil)
+)
+
+func main() {
+	var (
+		listenAddr  = flag.String("addr", ":50505", "listen address")
+		genKey      = flag.String("genkey", "", "generate a node key")
+		writeAddr   = flag.Bool("writeaddress", false, "write out the node's pubkey hash and quit")
+		writeENR    = flag.Bool("writeenr", false, "write out the node's signed ENR and quit")
+		nodeKeyFile = flag.String("nodekey", "", "private key filename")
+		nodeKeyHex  = flag.String("nodekeyhex", "", "private key as hex (for testing)")
+		nodeDB      = flag.String("nodedb", "", "node database path (default = in memory)")
+		natdesc     = flag.String("nat", "none", "port mapping mechanism (any|none|upnp|pmp|extip:<IP>)")
+		netrestrict = flag.String("netrestrict", "", "restrict network communication to the given IP networks (CIDR masks)")
+		networkID   = flag.Uint64("networkid", 1, "network identifier, must match the one of the nodes")
+		bootnodes   = flag.String("bootnodes", "", "comma separated enode URLs of other bootnodes to join")
+		enableStats = flag.Bool(metrics.MetricsEnabledFlag, false, "enable metrics collection and periodic network size reports")
+		statsAddr   = flag.String("metrics.addr", "", "HTTP listen address serving the metrics at /debug/metrics (requires -metrics)")
+		statsPeriod = flag.Duration("metrics.interval", time.Minute, "interval of the network size reports")
+		verbosity   = flag.Int("verbosity", int(log.LvlInfo), "log verbosity (0-9)")
+		vmodule     = flag.String("vmodule", "", "log verbosity pattern")
+
+		nodeKey *ecdsa.PrivateKey
+		err     error
+	)
+	flag.Parse()
+
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(*verbosity))
+	glogger.Vmodule(*vmodule)
+	log.Root().SetHandler(glogger)
+
+	natm, err := nat.Parse(*natdesc)
+	if err != nil {
+		utils.Fatalf("-nat: %v", err)
+	}
+	switch {
+	case *genKey != "":
+		nodeKey, err = crypto.GenerateKey()
+		if err != nil {
+			utils.Fatalf("could not generate key: %v", err)
+		}
+		if err = crypto.SaveECDSA(*genKey, nodeKey); err != nil {
+			utils.Fatalf("%v", err)
+		}
+		return
+	case *nodeKeyFile == "" && *nodeKeyHex == "":
+		utils.Fatalf("Use -nodekey or -nodekeyhex to specify a private key")
+	case *nodeKeyFile != "" && *nodeKeyHex != "":
+		utils.Fatalf("Options -nodekey and -nodekeyhex are mutually exclusive")
+	case *nodeKeyFile != "":
+		if nodeKey, err = crypto.LoadECDSA(*nodeKeyFile); err != nil {
+			utils.Fatalf("-nodekey: %v", err)
+		}
+	case *nodeKeyHex != "":
+		if nodeKey, err = crypto.HexToECDSA(*nodeKeyHex); err != nil {
+			utils.Fatalf("-nodekeyhex: %v", err)
+		}
+	}
+
+	if *writeAddr {
+		fmt.Printf("%v\n", discover.PubkeyID(&nodeKey.PublicKey))
+		os.Exit(0)
+	}
+
+	var restrictList *netutil.Netlist
+	if *netrestrict != "" {
+		restrictList, err = netutil.ParseNetlist(*netrestrict)
+		if err != nil {
+			utils.Fatalf("-netrestrict: %v", err)
+		}
+	}
+	var bootstrap []*discover.Node
+	if *bootnodes != "" {
+		for _, url := range strings.Split(*bootnodes, ",") {
+			node, err := discover.ParseNode(strings.TrimSpace(url))
+			if err != nil {
+				utils.Fatalf("-bootnodes: invalid enode %q: %v", url, err)
+			}
+			bootstrap = append(bootstrap, node)
+		}
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", *listenAddr)
+	if err != nil {
+		utils.Fatalf("-ResolveUDPAddr: %v", err)
+	}
+	if *writeENR {
+		record, err := signedRecord(nodeKey, addr)
+		if err != nil {
+			utils.Fatalf("could not create ENR: %v", err)
+		}
+		fmt.Println(record)
+		os.Exit(0)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		utils.Fatalf("-ListenUDP: %v", err)
+	}
+
+	realaddr := conn.LocalAddr().(*net.UDPAddr)
+	if natm != nil {
+		if !realaddr.IP.IsLoopback() {
+			go nat.Map(natm, nil, "udp", realaddr.Port, realaddr.Port, "matrix discovery")
+		}
+		// TODO: react to external IP changes over time.
+		if ext, err := natm.ExternalIP(); err == nil {
+			realaddr = &net.UDPAddr{IP: ext, Port: realaddr.Port}
+		}
+	}
+
+	cfg := discover.Config{
+		PrivateKey:   nodeKey,
+		AnnounceAddr: realaddr,
+		NodeDBPath:   *nodeDB,
+		NetRestrict:  restrictList,
+		Bootnodes:    bootstrap,
+		NetWorkId:    *networkID,
+	}
+	tab, err := discover.ListenUDP(conn, cfg)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	record, err := signedRecord(nodeKey, realaddr)
+	if err != nil {
+		utils.Fatalf("could not create ENR: %v", err)
+	}
+	fmt.Println("enode:", tab.Self())
+	fmt.Println("enr:  ", record)
+
+	if *enableStats {
+		if *statsAddr != "" {
+			exp.Exp(metrics.DefaultRegistry)
+			log.Info("Starting metrics server", "addr", fmt.Sprintf("http://%s/debug/metrics", *statsAddr))
+			go func() {
+				if err := http.ListenAndServe(*statsAddr, nil); err != nil {
+					log.Error("Failure in running metrics server", "err", err)
+				}
+			}()
+		}
+		go reportNetworkSize(tab, *statsPeriod)
+	}
+	select {}
+}
+
+// signedRecord returns the textual form of the node record (EIP-778) announcing
+// the given discovery (UDP) address, signed with the node key.
+func signedRecord(key *ecdsa.PrivateKey, addr *net.UDPAddr) (string, error) {
+	var r enr.Record
+	if ip := addr.IP; ip != nil && !ip.IsUnspecified() {
+		r.Set(enr.IP(ip))
+	}
+	r.Set(enr.UDP(addr.Port))
+	if err := enr.SignV4(&r, key); err != nil {
+		return "", err
+	}
+	enc, err := rlp.EncodeToBytes(&r)
+	if err != nil {
+		return "", err
+	}
+	return "enr:" + base64.RawURLEncoding.EncodeToString(enc), nil
+}
+
+// reportNetworkSize periodically logs the number of nodes and distinct IPs in
+// the discovery table and updates the corresponding gauges.
+func reportNetworkSize(tab *discover.Table, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	buf := make([]*discover.Node, 1024)
+	for range ticker.C {
+		nodes := buf[:tab.ReadRandomNodes(buf)]
+		ips := make(map[string]struct{}, len(nodes))
+		for _, n := range nodes {
+			ips[n.IP.String()] = struct{}{}
+		}
+		tableNodesGauge.Update(int64(tab.Len()))
+		tableIPsGauge.Update(int64(len(ips)))
+		log.Info("Discovery table", "nodes", tab.Len(), "ips", len(ips))
+	}
+}
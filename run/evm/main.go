@@ -0,0 +1,127 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+// evm executes EVM code snippets and JSON state tests against an in-memory
+// state, using the MATRIX chain rules and precompiled contracts.
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var gitCommit = "" // Git SHA1 commit hash of the release (set via linker flags)
+
+var (
+	app = utils.NewApp(gitCommit, "the evm command line interface")
+
+	DebugFlag = cli.BoolFlag{
+		Name:  "debug",
+		Usage: "output full trace logs",
+	}
+	JSONFlag = cli.BoolFlag{
+		Name:  "json",
+		Usage: "output trace logs and results in machine readable format (json)",
+	}
+	TracerFlag = cli.StringFlag{
+		Name:  "tracer",
+		Usage: "name of a built-in JavaScript tracer (e.g. callTracer) or JavaScript tracer code",
+	}
+	DisableMemoryFlag = cli.BoolFlag{
+		Name:  "nomemory",
+		Usage: "disable memory output",
+	}
+	DisableStackFlag = cli.BoolFlag{
+		Name:  "nostack",
+		Usage: "disable stack output",
+	}
+	VerbosityFlag = cli.IntFlag{
+		Name:  "verbosity",
+		Usage: "sets the verbosity level",
+	}
+	CodeFlag = cli.StringFlag{
+		Name:  "code",
+		Usage: "EVM code",
+	}
+	CodeFileFlag = cli.StringFlag{
+		Name:  "codefile",
+		Usage: "File containing EVM code. If '-' is specified, code is read from stdin ",
+	}
+	GasFlag = cli.Uint64Flag{
+		Name:  "gas",
+		Usage: "gas limit for the evm",
+		Value: 10000000000,
+	}
+	PriceFlag = utils.BigFlag{
+		Name:  "price",
+		Usage: "price set for the evm",
+		Value: new(big.Int),
+	}
+	ValueFlag = utils.BigFlag{
+		Name:  "value",
+		Usage: "value set for the evm",
+		Value: new(big.Int),
+	}
+	InputFlag = cli.StringFlag{
+		Name:  "input",
+		Usage: "input for the EVM",
+	}
+	CreateFlag = cli.BoolFlag{
+		Name:  "create",
+		Usage: "indicates the action should be create rather than call",
+	}
+	PreStateFlag = cli.StringFlag{
+		Name:  "prestate",
+		Usage: "JSON file with the accounts (same format as the genesis alloc) to start from",
+	}
+	SenderFlag = cli.StringFlag{
+		Name:  "sender",
+		Usage: "The transaction origin (hex or MAN base58 address)",
+	}
+	ReceiverFlag = cli.StringFlag{
+		Name:  "receiver",
+		Usage: "The transaction receiver (execution context, hex or MAN base58 address)",
+	}
+	DumpFlag = cli.BoolFlag{
+		Name:  "dump",
+		Usage: "dumps the state after the run",
+	}
+)
+
+func init() {
+	app.Flags = []cli.Flag{
+		CreateFlag,
+		DebugFlag,
+		JSONFlag,
+		TracerFlag,
+		VerbosityFlag,
+		CodeFlag,
+		CodeFileFlag,
+		GasFlag,
+		PriceFlag,
+		ValueFlag,
+		InputFlag,
+		PreStateFlag,
+		SenderFlag,
+		ReceiverFlag,
+		DisableMemoryFlag,
+		DisableStackFlag,
+		DumpFlag,
+	}
+	app.Commands = []cli.Command{
+		runCommand,
+		stateTestCommand,
+	}
+}
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
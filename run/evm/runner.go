@@ -0,0 +1,255 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/common/math"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/core/vm/runtime"
+	"github.com/MatrixAINetwork/go-matrix/log"
+	"github.com/MatrixAINetwork/go-matrix/man/tracers"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	tests "github.com/MatrixAINetwork/go-matrix/systest"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var runCommand = cli.Command{
+	Action:    runCmd,
+	Name:      "run",
+	Usage:     "run arbitrary evm binary",
+	ArgsUsage: "<code>",
+	Description: `
+The run command executes the given EVM code, passed with --code, --codefile or
+as argument, on an in-memory state. The state starts with the accounts of
+--prestate and the alpha matrix state, the chain rules are the ones of the
+MATRIX main network.
+
+After the run the return data, the gas used and the state diff against the
+prestate are reported; --json reports them as a JSON object following the
+trace.`,
+}
+
+// execResult is the outcome of an evm run reported with --json.
+type execResult struct {
+	Output  hexutil.Bytes       `json:"output"`
+	GasUsed math.HexOrDecimal64 `json:"gasUsed"`
+	Address *common.Address     `json:"address,omitempty"` // 创建的合约地址
+	Error   string              `json:"error,omitempty"`
+	Trace   json.RawMessage     `json:"trace,omitempty"` // JavaScript tracer的结果
+	Diffs   []state.DumpDiff    `json:"diffs"`
+}
+
+// initLogger sets up the logging verbosity of the command.
+func initLogger(ctx *cli.Context) {
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(ctx.GlobalInt(VerbosityFlag.Name)))
+	log.Root().SetHandler(glogger)
+}
+
+// makeTracer creates the tracer selected by the flags: a JavaScript tracer
+// (--tracer), a JSON logger streaming to stdout (--json) or a struct logger
+// whose trace is printed after the run (--debug). It returns nil if none was
+// requested.
+func makeTracer(ctx *cli.Context) (vm.Tracer, error) {
+	logconfig := &vm.LogConfig{
+		DisableMemory: ctx.GlobalBool(DisableMemoryFlag.Name),
+		DisableStack:  ctx.GlobalBool(DisableStackFlag.Name),
+		Debug:         ctx.GlobalBool(DebugFlag.Name),
+	}
+	switch {
+	case ctx.GlobalIsSet(TracerFlag.Name):
+		return tracers.New(ctx.GlobalString(TracerFlag.Name))
+	case ctx.GlobalBool(JSONFlag.Name):
+		return vm.NewJSONLogger(logconfig, os.Stdout), nil
+	case ctx.GlobalBool(DebugFlag.Name):
+		return vm.NewStructLogger(logconfig), nil
+	}
+	return nil, nil
+}
+
+// parseAddress 解析十六进制地址或MAN base58地址
+func parseAddress(s string) (common.Address, error) {
+	if common.IsHexAddress(s) {
+		return common.HexToAddress(s), nil
+	}
+	return base58.Base58DecodeToAddress(s)
+}
+
+// readPreState reads the accounts of the --prestate file.
+func readPreState(ctx *cli.Context) (core.GenesisAlloc, error) {
+	alloc := make(core.GenesisAlloc)
+	file := ctx.GlobalString(PreStateFlag.Name)
+	if file == "" {
+		return alloc, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &alloc); err != nil {
+		return nil, fmt.Errorf("invalid prestate %s: %v", file, err)
+	}
+	return alloc, nil
+}
+
+// readCode reads the hex encoded code from --codefile, --code or the first argument.
+func readCode(ctx *cli.Context) ([]byte, error) {
+	var hexcode []byte
+	switch {
+	case ctx.GlobalString(CodeFileFlag.Name) == "-":
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("could not load code from stdin: %v", err)
+		}
+		hexcode = data
+	case ctx.GlobalString(CodeFileFlag.Name) != "":
+		data, err := ioutil.ReadFile(ctx.GlobalString(CodeFileFlag.Name))
+		if err != nil {
+			return nil, fmt.Errorf("could not load code from file: %v", err)
+		}
+		hexcode = data
+	case ctx.GlobalString(CodeFlag.Name) != "":
+		hexcode = []byte(ctx.GlobalString(CodeFlag.Name))
+	case len(ctx.Args()) > 0:
+		hexcode = []byte(ctx.Args().First())
+	}
+	return common.FromHex(strings.TrimSpace(string(bytes.TrimRight(hexcode, "\n")))), nil
+}
+
+func runCmd(ctx *cli.Context) error {
+	initLogger(ctx)
+
+	tracer, err := makeTracer(ctx)
+	if err != nil {
+		utils.Fatalf("Failed to create tracer: %v", err)
+	}
+	genesis, err := core.DefaultGenesis("")
+	if err != nil {
+		utils.Fatalf("Failed to load the default genesis: %v", err)
+	}
+	alloc, err := readPreState(ctx)
+	if err != nil {
+		utils.Fatalf("Failed to read prestate: %v", err)
+	}
+	code, err := readCode(ctx)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	var (
+		sender   = common.BytesToAddress([]byte("sender"))
+		receiver = common.BytesToAddress([]byte("receiver"))
+	)
+	if ctx.GlobalString(SenderFlag.Name) != "" {
+		if sender, err = parseAddress(ctx.GlobalString(SenderFlag.Name)); err != nil {
+			utils.Fatalf("Invalid sender: %v", err)
+		}
+	}
+	if ctx.GlobalString(ReceiverFlag.Name) != "" {
+		if receiver, err = parseAddress(ctx.GlobalString(ReceiverFlag.Name)); err != nil {
+			utils.Fatalf("Invalid receiver: %v", err)
+		}
+	}
+	// 转账前检查的是主账户余额, 发送者须持有主账户
+	if _, ok := alloc[sender]; !ok {
+		alloc[sender] = core.GenesisAccount{Balance: new(big.Int)}
+	}
+	statedb := tests.MakePreState(mandb.NewMemDatabase(), alloc)
+	pre := statedb.RawDump()
+
+	initialGas := ctx.GlobalUint64(GasFlag.Name)
+	runtimeConfig := runtime.Config{
+		ChainConfig: genesis.Config,
+		Origin:      sender,
+		State:       statedb,
+		GasLimit:    initialGas,
+		GasPrice:    utils.GlobalBig(ctx, PriceFlag.Name),
+		Value:       utils.GlobalBig(ctx, ValueFlag.Name),
+		EVMConfig: vm.Config{
+			Tracer: tracer,
+			Debug:  tracer != nil,
+		},
+	}
+	var (
+		ret         []byte
+		leftOverGas uint64
+		created     *common.Address
+		input       = common.FromHex(ctx.GlobalString(InputFlag.Name))
+	)
+	if ctx.GlobalBool(CreateFlag.Name) {
+		var address common.Address
+		ret, address, leftOverGas, err = runtime.Create(append(code, input...), &runtimeConfig)
+		created = &address
+	} else {
+		if len(code) > 0 {
+			statedb.SetCode(receiver, code)
+		}
+		ret, leftOverGas, err = runtime.Call(receiver, input, &runtimeConfig)
+	}
+	result := execResult{
+		Output:  ret,
+		GasUsed: math.HexOrDecimal64(initialGas - leftOverGas),
+		Address: created,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if jst, ok := tracer.(*tracers.Tracer); ok {
+		if result.Trace, err = jst.GetResult(); err != nil {
+			utils.Fatalf("Failed to retrieve trace result: %v", err)
+		}
+	}
+	statedb.Commit(genesis.Config.IsEIP158(runtimeConfig.BlockNumber))
+	post := statedb.RawDump()
+	result.Diffs = state.DiffDump(pre, post)
+
+	if ctx.GlobalBool(JSONFlag.Name) {
+		json.NewEncoder(os.Stdout).Encode(result)
+	} else {
+		if logger, ok := tracer.(*vm.StructLogger); ok {
+			fmt.Fprintln(os.Stderr, "#### TRACE ####")
+			vm.WriteTrace(os.Stderr, logger.StructLogs())
+			fmt.Fprintln(os.Stderr, "#### LOGS ####")
+			vm.WriteLogs(os.Stderr, statedb.Logs())
+		}
+		printResult(result)
+	}
+	if ctx.GlobalBool(DumpFlag.Name) {
+		fmt.Println(string(statedb.Dump()))
+	}
+	return nil
+}
+
+// printResult prints the outcome of a run in human readable form.
+func printResult(result execResult) {
+	fmt.Printf("output: %s\n", result.Output)
+	if result.Error != "" {
+		fmt.Printf("error: %s\n", result.Error)
+	}
+	fmt.Printf("gas used: %d\n", uint64(result.GasUsed))
+	if result.Address != nil {
+		fmt.Printf("contract: %s\n", base58.Base58EncodeToString("MAN", *result.Address))
+	}
+	if result.Trace != nil {
+		fmt.Printf("trace: %s\n", result.Trace)
+	}
+	fmt.Println("state diff:")
+	for _, diff := range result.Diffs {
+		fmt.Printf("  %s\n", diff)
+	}
+}
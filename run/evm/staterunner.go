@@ -0,0 +1,100 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/vm"
+	"github.com/MatrixAINetwork/go-matrix/man/tracers"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	tests "github.com/MatrixAINetwork/go-matrix/systest"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var stateTestCommand = cli.Command{
+	Action:    stateTestCmd,
+	Name:      "statetest",
+	Usage:     "executes the given state tests",
+	ArgsUsage: "<file>",
+	Description: `
+The statetest command runs every subtest of the JSON state test file as a MATRIX
+transaction paid in MAN and prints the results as JSON, together with the state
+diff of each subtest against its prestate. --dump adds the post state.`,
+}
+
+// StatetestResult contains the execution status after running a state test, any
+// error that might have occurred and a dump of the final state if requested.
+type StatetestResult struct {
+	Name  string           `json:"name"`
+	Pass  bool             `json:"pass"`
+	Fork  string           `json:"fork"`
+	Error string           `json:"error,omitempty"`
+	Trace json.RawMessage  `json:"trace,omitempty"` // JavaScript tracer的结果
+	Diffs []state.DumpDiff `json:"diffs,omitempty"`
+	State *state.Dump      `json:"state,omitempty"`
+}
+
+func stateTestCmd(ctx *cli.Context) error {
+	if len(ctx.Args().First()) == 0 {
+		return errors.New("path-to-test argument required")
+	}
+	initLogger(ctx)
+
+	// Load the test content from the input file
+	src, err := ioutil.ReadFile(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	var stateTests map[string]tests.StateTest
+	if err = json.Unmarshal(src, &stateTests); err != nil {
+		return err
+	}
+	// Iterate over all the tests, run them and aggregate the results
+	results := make([]StatetestResult, 0, len(stateTests))
+	for key, test := range stateTests {
+		pre := tests.MakePreState(mandb.NewMemDatabase(), test.PreState()).RawDump()
+		for _, st := range test.Subtests() {
+			// 每个子测试使用新的tracer
+			tracer, err := makeTracer(ctx)
+			if err != nil {
+				utils.Fatalf("Failed to create tracer: %v", err)
+			}
+			cfg := vm.Config{Tracer: tracer, Debug: tracer != nil}
+
+			// Run the test and aggregate the result
+			result := StatetestResult{Name: key, Fork: st.Fork, Pass: true}
+			statedb, err := test.Run(st, cfg)
+			if err != nil {
+				result.Pass, result.Error = false, err.Error()
+			}
+			if statedb != nil {
+				post := statedb.RawDump()
+				result.Diffs = state.DiffDump(pre, post)
+				if ctx.GlobalBool(DumpFlag.Name) {
+					result.State = &post
+				}
+			}
+			switch tracer := tracer.(type) {
+			case *tracers.Tracer:
+				if result.Trace, err = tracer.GetResult(); err != nil {
+					utils.Fatalf("Failed to retrieve trace result: %v", err)
+				}
+			case *vm.StructLogger:
+				vm.WriteTrace(os.Stderr, tracer.StructLogs())
+			}
+			results = append(results, result)
+		}
+	}
+	out, _ := json.MarshalIndent(results, "", "  ")
+	fmt.Println(string(out))
+	return nil
+}
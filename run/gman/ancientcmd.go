@@ -0,0 +1,136 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var ancientCommand = cli.Command{
+	Name:     "ancient",
+	Usage:    "Inspect and repair the ancient block store",
+	Category: "BLOCKCHAIN COMMANDS",
+	Description: `
+The ancient store keeps the canonical blocks older than --ancient.depth in
+append-only flat files next to the chain database. These commands work on a
+stopped node.`,
+	Subcommands: []cli.Command{
+		{
+			Name:   "inspect",
+			Usage:  "Print the content of the ancient store",
+			Action: utils.MigrateFlags(ancientInspect),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.LightModeFlag,
+			},
+			Description: `
+Prints the number of frozen blocks and the size of every table of the ancient
+store, along with the range of blocks still held by the chain database.`,
+		},
+		{
+			Name:   "repair",
+			Usage:  "Check the ancient store and drop corrupt blocks",
+			Action: utils.MigrateFlags(ancientRepair),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.LightModeFlag,
+			},
+			Description: `
+Checks that every frozen block decodes and links to its parent, truncating the
+ancient store at the first one that doesn't. Blocks dropped that way are lost
+and have to be synced again.`,
+		},
+	},
+}
+
+// openAncientDir opens the chain database, which locks the data directory, and
+// returns it along with the ancient store directory.
+func openAncientDir(ctx *cli.Context) (mandb.Database, string) {
+	stack, _ := makeConfigNode(ctx)
+
+	name := "chaindata"
+	if ctx.GlobalBool(utils.LightModeFlag.Name) {
+		name = "lightchaindata"
+	}
+	dir := utils.MakeAncientDir(stack, name)
+	if dir == "" {
+		utils.Fatalf("The ancient store needs a data directory")
+	}
+	if _, err := os.Stat(dir); err != nil {
+		utils.Fatalf("No ancient store at %s: %v", dir, err)
+	}
+	db, err := stack.OpenDatabase(name, 16, 16)
+	if err != nil {
+		utils.Fatalf("Could not open database: %v", err)
+	}
+	return db, dir
+}
+
+func ancientInspect(ctx *cli.Context) error {
+	db, dir := openAncientDir(ctx)
+	defer db.Close()
+
+	frozen, stats, err := rawdb.InspectFreezer(dir)
+	if err != nil {
+		utils.Fatalf("Failed to inspect ancient store: %v", err)
+	}
+	fmt.Printf("Ancient store: %s\n", dir)
+	fmt.Printf("Frozen blocks: %d\n", frozen)
+	var total common.StorageSize
+	for _, stat := range stats {
+		fmt.Printf("  %-10s %10d items %12s\n", stat.Name, stat.Items, stat.Size)
+		total += stat.Size
+	}
+	fmt.Printf("  %-10s %29s\n", "total", total)
+
+	if head := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadBlockHash(db)); head != nil {
+		fmt.Printf("Chain head: %d\n", *head)
+		if gap := findAncientGap(db, frozen, *head); gap != nil {
+			fmt.Printf("Blocks from %d on are missing from both stores\n", *gap)
+		}
+	}
+	return nil
+}
+
+func ancientRepair(ctx *cli.Context) error {
+	db, dir := openAncientDir(ctx)
+	defer db.Close()
+
+	before, _, err := rawdb.InspectFreezer(dir)
+	if err != nil {
+		utils.Fatalf("Failed to open ancient store: %v", err)
+	}
+	after, err := rawdb.RepairFreezer(dir)
+	if err != nil {
+		utils.Fatalf("Failed to repair ancient store: %v", err)
+	}
+	if after == before {
+		fmt.Printf("Ancient store is consistent, %d blocks frozen\n", after)
+		return nil
+	}
+	fmt.Printf("Dropped %d corrupt blocks, %d blocks left frozen\n", before-after, after)
+	if head := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadBlockHash(db)); head != nil {
+		if gap := findAncientGap(db, after, *head); gap != nil {
+			fmt.Printf("Blocks from %d on are missing, rewind the chain below them to resync\n", *gap)
+		}
+	}
+	return nil
+}
+
+// findAncientGap returns the first block after the frozen ones if it is missing
+// from the chain database although the head is past it.
+func findAncientGap(db mandb.Database, frozen, head uint64) *uint64 {
+	if frozen <= head && rawdb.ReadCanonicalHash(db, frozen) == (common.Hash{}) {
+		return &frozen
+	}
+	return nil
+}
@@ -228,6 +228,12 @@ func ReadDecryptPassword(inputTimes int, ctx *cli.Context) ([]byte, error) {
 		h.Write([]byte(password))
 		return h.Sum(nil), nil
 	}
+	// 密码文件的第一行为口令
+	if passwords := utils.MakePasswordList(ctx); len(passwords) > 0 {
+		h := sha256.New()
+		h.Write([]byte(passwords[0]))
+		return h.Sum(nil), nil
+	}
 	var passphrase string
 	var err error
 	InputCount := 0
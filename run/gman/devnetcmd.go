@@ -0,0 +1,320 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MatrixAINetwork/go-matrix/accounts/keystore"
+	"github.com/MatrixAINetwork/go-matrix/base58"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/crypto/aes"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/p2p/discover"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	devnetValidatorsFlag = cli.IntFlag{
+		Name:  "validators",
+		Usage: "Number of validator nodes",
+		Value: 3,
+	}
+	devnetMinersFlag = cli.IntFlag{
+		Name:  "miners",
+		Usage: "Number of miner nodes",
+		Value: 1,
+	}
+	devnetBroadcastsFlag = cli.IntFlag{
+		Name:  "broadcasts",
+		Usage: "Number of broadcast nodes",
+		Value: 1,
+	}
+	devnetChainIDFlag = cli.Uint64Flag{
+		Name:  "chainid",
+		Usage: "Chain id and network id of the devnet",
+		Value: 1338,
+	}
+	devnetPortFlag = cli.IntFlag{
+		Name:  "baseport",
+		Usage: "P2P listening port of the first node, the following nodes count up",
+		Value: 40000,
+	}
+	devnetRPCPortFlag = cli.IntFlag{
+		Name:  "baserpcport",
+		Usage: "HTTP-RPC port of the first node, the following nodes count up",
+		Value: 48000,
+	}
+	devnetPassphraseFlag = cli.StringFlag{
+		Name:  "passphrase",
+		Usage: "Password of the generated keystores and entrust files",
+		Value: "Matrix@devnet1",
+	}
+	devnetCommand = cli.Command{
+		Action:    utils.MigrateFlags(makeDevnet),
+		Name:      "devnet",
+		Usage:     "Generate a local multi-node network",
+		ArgsUsage: "<dir>",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Flags: []cli.Flag{
+			devnetValidatorsFlag,
+			devnetMinersFlag,
+			devnetBroadcastsFlag,
+			devnetChainIDFlag,
+			devnetPortFlag,
+			devnetRPCPortFlag,
+			devnetPassphraseFlag,
+		},
+		Description: `
+The devnet command generates everything needed to run a private MATRIX network
+on localhost into a new directory:
+
+  MANGenesis.json    genesis with the nodes elected, their deposits in place and
+                     the version signed by the faucet
+  faucet/keystore    pre-funded account, also foundation and super account
+  <role><n>/         data directory of each node: keystore, nodekey, entrust
+                     file, man.json and static-nodes.json listing all other nodes
+  password           the passphrase, read by the nodes with --password
+  start.sh, stop.sh  initialise and launch, respectively stop, all nodes
+
+All keystores and entrust files are encrypted with --passphrase. The genesis
+time is the generation time; start the network soon after generating it.`,
+	}
+)
+
+// devnetNode 本地测试网中的一个节点
+type devnetNode struct {
+	name    string
+	role    common.RoleType
+	key     *ecdsa.PrivateKey // 账户私钥
+	nodeKey *ecdsa.PrivateKey // p2p节点私钥
+	port    int
+	rpcPort int
+}
+
+func (n *devnetNode) address() common.Address {
+	return crypto.PubkeyToAddress(n.key.PublicKey)
+}
+
+func (n *devnetNode) manAddress() string {
+	return base58.Base58EncodeToString("MAN", n.address())
+}
+
+func (n *devnetNode) enode() string {
+	id := discover.PubkeyID(&n.nodeKey.PublicKey)
+	return discover.NewNode(id, net.ParseIP("127.0.0.1"), uint16(n.port), uint16(n.port)).String()
+}
+
+func makeDevnet(ctx *cli.Context) error {
+	dir := ctx.Args().First()
+	if dir == "" {
+		utils.Fatalf("The devnet directory is required")
+	}
+	if _, err := os.Stat(dir); err == nil {
+		utils.Fatalf("Directory %s already exists", dir)
+	}
+	passphrase := ctx.String(devnetPassphraseFlag.Name)
+	if passphrase == "" || strings.ContainsAny(passphrase, "\r\n") {
+		utils.Fatalf("The passphrase must not be empty nor contain line breaks")
+	}
+	counts := []struct {
+		role  common.RoleType
+		name  string
+		count int
+	}{
+		{common.RoleValidator, "validator", ctx.Int(devnetValidatorsFlag.Name)},
+		{common.RoleMiner, "miner", ctx.Int(devnetMinersFlag.Name)},
+		{common.RoleBroadcast, "broadcast", ctx.Int(devnetBroadcastsFlag.Name)},
+	}
+
+	var (
+		nodes                          []*devnetNode
+		validators, miners, broadcasts []common.Address
+		port, rpcPort                  = ctx.Int(devnetPortFlag.Name), ctx.Int(devnetRPCPortFlag.Name)
+	)
+	for _, c := range counts {
+		if c.count < 1 {
+			utils.Fatalf("The devnet needs at least one %s", c.name)
+		}
+		for i := 0; i < c.count; i++ {
+			node := &devnetNode{name: fmt.Sprintf("%s%d", c.name, i), role: c.role, port: port, rpcPort: rpcPort}
+			node.key = mustGenerateKey()
+			node.nodeKey = mustGenerateKey()
+			nodes = append(nodes, node)
+			port, rpcPort = port+1, rpcPort+1
+
+			switch c.role {
+			case common.RoleValidator:
+				validators = append(validators, node.address())
+			case common.RoleMiner:
+				miners = append(miners, node.address())
+			case common.RoleBroadcast:
+				broadcasts = append(broadcasts, node.address())
+			}
+		}
+	}
+	faucetKey := mustGenerateKey()
+	faucet := crypto.PubkeyToAddress(faucetKey.PublicKey)
+
+	chainID := ctx.Uint64(devnetChainIDFlag.Name)
+	genesis := core.DevnetGenesisBlock(chainID, validators, miners, broadcasts, faucet)
+	// 水龙头账户是唯一的版本超级账户, 创世区块的版本号由它签名
+	versionSign, err := crypto.SignWithValidate(common.BytesToHash([]byte(genesis.Version)).Bytes(), true, faucetKey)
+	if err != nil {
+		utils.Fatalf("Failed to sign genesis version: %v", err)
+	}
+	genesis.VersionSignatures = []common.Signature{common.BytesToSignature(versionSign)}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		utils.Fatalf("Failed to create devnet directory: %v", err)
+	}
+	out, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode genesis: %v", err)
+	}
+	writeDevnetFile(filepath.Join(dir, "MANGenesis.json"), out, 0644)
+	writeDevnetKey(filepath.Join(dir, "faucet"), faucetKey, passphrase)
+	for _, node := range nodes {
+		writeDevnetNode(filepath.Join(dir, node.name), node, nodes, passphrase)
+	}
+	// 口令只写入密码文件, 不出现在节点的命令行中
+	writeDevnetFile(filepath.Join(dir, "password"), []byte(passphrase+"\n"), 0600)
+	writeDevnetFile(filepath.Join(dir, "start.sh"), devnetStartScript(nodes, chainID), 0755)
+	writeDevnetFile(filepath.Join(dir, "stop.sh"), devnetStopScript(nodes), 0755)
+
+	fmt.Printf("Devnet generated in %s, chain id %d\n\n", dir, chainID)
+	fmt.Printf("%-12s %-36s %-6s %s\n", "NODE", "ADDRESS", "PORT", "RPC")
+	for _, node := range nodes {
+		fmt.Printf("%-12s %-36s %-6d http://127.0.0.1:%d\n", node.name, node.manAddress(), node.port, node.rpcPort)
+	}
+	fmt.Printf("%-12s %s\n\n", "faucet", base58.Base58EncodeToString("MAN", faucet))
+	fmt.Printf("Start the nodes with %s (set GMAN if gman is not in PATH).\n", filepath.Join(dir, "start.sh"))
+	return nil
+}
+
+// writeDevnetNode writes the data directory of a node: its account and node
+// key, the entrust file for signing and the other nodes as boot and static
+// nodes.
+func writeDevnetNode(dir string, node *devnetNode, nodes []*devnetNode, passphrase string) {
+	writeDevnetKey(dir, node.key, passphrase)
+
+	instance := filepath.Join(dir, clientIdentifier)
+	if err := os.MkdirAll(instance, 0700); err != nil {
+		utils.Fatalf("Failed to create %s: %v", instance, err)
+	}
+	if err := crypto.SaveECDSA(filepath.Join(instance, "nodekey"), node.nodeKey); err != nil {
+		utils.Fatalf("Failed to save node key: %v", err)
+	}
+	peers := make([]string, 0, len(nodes)-1)
+	for _, peer := range nodes {
+		if peer != node {
+			peers = append(peers, peer.enode())
+		}
+	}
+	static, _ := json.MarshalIndent(peers, "", "  ")
+	writeDevnetFile(filepath.Join(instance, "static-nodes.json"), static, 0644)
+	config, _ := json.MarshalIndent(struct{ BootNode []string }{peers}, "", "  ")
+	writeDevnetFile(filepath.Join(dir, "man.json"), config, 0644)
+
+	// 委托文件的加密方式与aes命令相同, 启动时从--password文件读取口令
+	entrustData, _ := json.Marshal([]mc.EntrustInfo{{Address: node.manAddress(), Password: passphrase}})
+	aesKey := sha256.Sum256([]byte(passphrase))
+	entrust, err := aes.AesEncrypt(entrustData, aesKey[:])
+	if err != nil {
+		utils.Fatalf("Failed to encrypt entrust file: %v", err)
+	}
+	writeDevnetFile(filepath.Join(dir, "entrust.json"), []byte(base64.StdEncoding.EncodeToString(entrust)), 0600)
+}
+
+// writeDevnetKey stores key in the keystore of the given data directory.
+func writeDevnetKey(dir string, key *ecdsa.PrivateKey, passphrase string) {
+	ks := keystore.NewKeyStore(filepath.Join(dir, "keystore"), keystore.LightScryptN, keystore.LightScryptP)
+	if _, err := ks.ImportECDSA(key, passphrase); err != nil {
+		utils.Fatalf("Failed to store key: %v", err)
+	}
+}
+
+func devnetStartScript(nodes []*devnetNode, chainID uint64) []byte {
+	var script bytes.Buffer
+	script.WriteString(`#!/bin/sh
+# Starts the devnet generated by "gman devnet". Set GMAN to the gman binary if
+# it is not in PATH. Every node runs in its own directory and logs to gman.log.
+GMAN=${GMAN:-gman}
+case "$GMAN" in
+	/*) ;;
+	*/*) GMAN="$(pwd)/$GMAN" ;;
+esac
+cd "$(dirname "$0")" || exit 1
+
+start() {
+	node=$1
+	shift
+	if [ ! -d "$node/gman/chaindata" ]; then
+		(cd "$node" && "$GMAN" --datadir . init ../MANGenesis.json >init.log 2>&1) || {
+			echo "Failed to initialise $node, see $node/init.log"
+			exit 1
+		}
+	fi
+	(cd "$node" && exec "$GMAN" --datadir . "$@" >gman.log 2>&1) &
+	echo $! >"$node/gman.pid"
+	echo "Started $node, pid $!"
+}
+
+`)
+	for _, node := range nodes {
+		fmt.Fprintf(&script, "start %s --networkid %d --port %d --nat none --rpc --rpcaddr 127.0.0.1 --rpcport %d --rpcapi man,net,web3,personal,admin --manAddress %s --entrust entrust.json --password ../password",
+			node.name, chainID, node.port, node.rpcPort, node.manAddress())
+		if node.role != common.RoleValidator {
+			// 矿工和广播节点需要挖矿
+			script.WriteString(" --mine")
+		}
+		script.WriteString("\n")
+	}
+	return script.Bytes()
+}
+
+func devnetStopScript(nodes []*devnetNode) []byte {
+	var script bytes.Buffer
+	script.WriteString("#!/bin/sh\n# Stops the devnet started by start.sh.\ncd \"$(dirname \"$0\")\" || exit 1\n\n")
+	script.WriteString("for node in")
+	for _, node := range nodes {
+		script.WriteString(" " + node.name)
+	}
+	script.WriteString(`; do
+	if [ -f "$node/gman.pid" ]; then
+		kill "$(cat "$node/gman.pid")" 2>/dev/null && echo "Stopped $node"
+		rm -f "$node/gman.pid"
+	fi
+done
+`)
+	return script.Bytes()
+}
+
+func writeDevnetFile(path string, data []byte, perm os.FileMode) {
+	if err := ioutil.WriteFile(path, data, perm); err != nil {
+		utils.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func mustGenerateKey() *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		utils.Fatalf("Failed to generate key: %v", err)
+	}
+	return key
+}
@@ -76,7 +76,7 @@ var (
 		//utils.TxPoolJournalFlag, //Y
 		//utils.TxPoolRejournalFlag,
 		utils.TxPoolPriceLimitFlag,
-		//utils.TxPoolPriceBumpFlag,//Y
+		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
 		utils.TxPoolGlobalSlotsFlag,
 		utils.TxPoolAccountQueueFlag,
@@ -86,6 +86,11 @@ var (
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
 		utils.GCModeFlag,
+		utils.GCModeRecentFlag,
+		utils.AddrIndexFlag,
+		utils.AncientFlag,
+		utils.AncientDepthFlag,
+		utils.AncientSuperBlockFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
@@ -100,6 +105,10 @@ var (
 		utils.GasPriceFlag,
 		utils.MinerThreadsFlag,
 		utils.MiningEnabledFlag,
+		utils.StratumEnabledFlag,
+		utils.StratumListenAddrFlag,
+		utils.StratumPortFlag,
+		utils.StratumDifficultyFlag,
 		utils.TargetGasLimitFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
@@ -107,8 +116,8 @@ var (
 		utils.NetrestrictFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
-		//utils.DeveloperFlag,
-		//utils.DeveloperPeriodFlag,
+		utils.DeveloperFlag,
+		utils.DeveloperPeriodFlag,
 		//utils.TestnetFlag,
 		//utils.RinkebyFlag,
 		utils.VMEnableDebugFlag,
@@ -144,6 +153,10 @@ var (
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.RPCJWTSecretFlag,
+		utils.RPCAllowIPsFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateBurstFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 	}
@@ -164,12 +177,18 @@ func init() {
 		copydbCommand,
 		removedbCommand,
 		dumpCommand,
+		replayCommand,
+		ancientCommand,
+		pruneCommand,
+		timelineCommand,
+		rpcTokenCommand,
 		rollbackCommand,
 		genBlockCommand,
 		importSupBlockCommand,
 		genBlockRootsCommand,
 		signCommand,
 		signSuperBlockCommand,
+		superBlockCommand,
 		signVersionCommand,
 		// See monitorcmd.go:
 		monitorCommand,
@@ -186,6 +205,8 @@ func init() {
 		versionCommand,
 		bugCommand,
 		licenseCommand,
+		// See devnetcmd.go:
+		devnetCommand,
 		// See config.go
 		dumpConfigCommand,
 		CommitCommand,
@@ -347,6 +368,10 @@ func startNode(ctx *cli.Context, stack *pod.Node) {
 	}
 }
 func Init_Config_PATH(ctx *cli.Context) {
+	if ctx.GlobalBool(utils.DeveloperFlag.Name) {
+		// 开发者模式不连接其他节点, 无需bootnode
+		return
+	}
 	log.INFO("开始读取配置文件", "", "")
 	config_dir := utils.MakeDataDir(ctx)
 	if config_dir == "" {
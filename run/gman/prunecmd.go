@@ -0,0 +1,67 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+	"github.com/MatrixAINetwork/go-matrix/mandb"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var pruneCommand = cli.Command{
+	Action:    utils.MigrateFlags(pruneState),
+	Name:      "prune",
+	Usage:     "Delete the historical state no longer needed by the node",
+	ArgsUsage: " ",
+	Category:  "BLOCKCHAIN COMMANDS",
+	Flags: []cli.Flag{
+		utils.DataDirFlag,
+		utils.CacheFlag,
+		utils.LightModeFlag,
+		utils.GCModeRecentFlag,
+		utils.NoCompactionFlag,
+	},
+	Description: `
+The prune command works on a stopped node. It keeps the states of the last
+--gcmode.recent canonical blocks, of the genesis and super blocks, and of the
+broadcast blocks and the blocks two below them that the consensus reads again,
+and deletes every other state trie node and contract code. Matrix data and the
+revocable/time btree nodes are stored in the state trie and are kept along with
+the states referring to them. Afterwards the node can run with --gcmode=full.`,
+}
+
+func pruneState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	recent := ctx.GlobalUint64(utils.GCModeRecentFlag.Name)
+	if recent == 0 {
+		utils.Fatalf("--%s must be positive", utils.GCModeRecentFlag.Name)
+	}
+	stats, err := core.PruneState(db, recent)
+	if err != nil {
+		utils.Fatalf("Prune failed: %v", err)
+	}
+	fmt.Printf("Kept %d state roots, %d nodes\n", stats.Roots, stats.Nodes)
+	fmt.Printf("Deleted %d nodes (%v) in %v\n", stats.Deleted, stats.Size, stats.Elapsed)
+
+	if ctx.GlobalIsSet(utils.NoCompactionFlag.Name) {
+		return nil
+	}
+	start := time.Now()
+	fmt.Println("Compacting entire database...")
+	if err = rawdb.KeyValueStore(db).(*mandb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
+		utils.Fatalf("Compaction failed: %v", err)
+	}
+	fmt.Printf("Compaction done in %v.\n", time.Since(start))
+	return nil
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/MatrixAINetwork/go-matrix/baseinterface"
+	"github.com/MatrixAINetwork/go-matrix/ca"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/core/types"
+	"github.com/MatrixAINetwork/go-matrix/depoistInfo"
+	"github.com/MatrixAINetwork/go-matrix/man"
+	"github.com/MatrixAINetwork/go-matrix/mc"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
+	"github.com/MatrixAINetwork/go-matrix/reelection"
+	"github.com/MatrixAINetwork/go-matrix/rlp"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	replayBadBlockFlag = cli.StringFlag{
+		Name:  "badblock",
+		Usage: "File holding a bad block RLP (hex or binary, e.g. the rlp field of debug.getBadBlocks)",
+	}
+	replayJSONFlag = cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print the replay results and state diffs as JSON",
+	}
+	replayCommand = cli.Command{
+		Action:    utils.MigrateFlags(replayChain),
+		Name:      "replay",
+		Usage:     "Re-execute blocks offline and diff the resulting state",
+		ArgsUsage: "<from> [<to>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.LightModeFlag,
+			replayBadBlockFlag,
+			replayJSONFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The replay command opens the data directory read-only and re-executes the
+given block range on top of the locally stored parent states, running the versioned processor including matrix state
+producers, rewards and uptime. Nothing is written back to the database.
+
+For every block the replayed state root is compared with the header root, and
+on mismatch a per-account and per-matrix-state-key diff against the stored
+state is printed.
+
+With --badblock the block is read from an RLP file instead, typically the rlp
+field returned by debug.getBadBlocks, and replayed on its local parent. The
+state of a bad block is not stored, so no diff can be printed for it.
+
+Consensus online results are node local and not available offline, so blocks
+carrying topology changes may be reported as mismatching.`,
+	}
+)
+
+// replayTopNode 离线重放时没有在线共识结果
+type replayTopNode struct{}
+
+func (replayTopNode) GetConsensusOnlineResults() []*mc.HD_OnlineConsensusVoteResultMsg {
+	return nil
+}
+
+// replayBackend 为depoistInfo提供离线的状态读取
+type replayBackend struct {
+	chain *core.BlockChain
+}
+
+func (b *replayBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	var header *types.Header
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		header = b.chain.CurrentBlock().Header()
+	} else {
+		header = b.chain.GetHeaderByNumber(uint64(blockNr))
+	}
+	if header == nil {
+		return nil, nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	st, err := b.chain.StateAt(header.Root)
+	return st, header, err
+}
+
+func (b *replayBackend) StateAndHeaderByHash(ctx context.Context, hash common.Hash) (*state.StateDB, *types.Header, error) {
+	header := b.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, nil, fmt.Errorf("block %s not found", hash.TerminalString())
+	}
+	st, err := b.chain.StateAt(header.Root)
+	return st, header, err
+}
+
+// makeReplayChain opens the chain read-only and wires the services the block
+// processor depends on, mirroring man.New without starting any network component.
+func makeReplayChain(ctx *cli.Context) *core.BlockChain {
+	stack, _ := makeConfigNode(ctx)
+	chain, _ := utils.MakeReadOnlyChain(ctx, stack)
+
+	ca.SetTopologyReader(chain.GetTopologyStore())
+	depoistInfo.NewDepositInfo(&replayBackend{chain: chain})
+
+	random, err := baseinterface.NewRandom(chain)
+	if err != nil {
+		utils.Fatalf("Failed to create random service: %v", err)
+	}
+	chain.Processor([]byte(manparams.VersionAlpha)).SetRandom(random)
+	election, err := reelection.New(chain, random, replayTopNode{})
+	if err != nil {
+		utils.Fatalf("Failed to create election service: %v", err)
+	}
+	man.RegisterMatrixStateDataProducers(chain, election)
+	return chain
+}
+
+// replayOutput 单个区块的重放输出
+type replayOutput struct {
+	Number     uint64           `json:"number"`
+	Hash       common.Hash      `json:"hash"`
+	Root       common.Hash      `json:"root"`
+	ReplayRoot common.Hash      `json:"replayRoot"`
+	Match      bool             `json:"match"`
+	Error      string           `json:"error,omitempty"`
+	DiffBase   common.Hash      `json:"diffBase"` // 差异对比的状态根,坏块为父区块的状态根
+	Diffs      []state.DumpDiff `json:"diffs,omitempty"`
+}
+
+func replayChain(ctx *cli.Context) error {
+	var blocks []*types.Block
+	chain := makeReplayChain(ctx)
+	defer chain.Stop()
+
+	if path := ctx.String(replayBadBlockFlag.Name); path != "" {
+		block, err := readBadBlock(path)
+		if err != nil {
+			utils.Fatalf("Failed to read bad block: %v", err)
+		}
+		blocks = append(blocks, block)
+	} else {
+		if len(ctx.Args()) < 1 || len(ctx.Args()) > 2 {
+			utils.Fatalf("This command requires an argument.")
+		}
+		from, err := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+		if err != nil {
+			utils.Fatalf("Invalid start block number: %v", err)
+		}
+		to := from
+		if len(ctx.Args()) == 2 {
+			if to, err = strconv.ParseUint(ctx.Args().Get(1), 10, 64); err != nil {
+				utils.Fatalf("Invalid end block number: %v", err)
+			}
+		}
+		if from == 0 || to < from {
+			utils.Fatalf("Invalid block range %d-%d", from, to)
+		}
+		for number := from; number <= to; number++ {
+			block := chain.GetBlockByNumber(number)
+			if block == nil {
+				utils.Fatalf("Block #%d not found", number)
+			}
+			blocks = append(blocks, block)
+		}
+	}
+
+	mismatches := 0
+	for _, block := range blocks {
+		out := replayBlock(chain, block)
+		if !out.Match {
+			mismatches++
+		}
+		if ctx.Bool(replayJSONFlag.Name) {
+			data, _ := json.MarshalIndent(out, "", "    ")
+			fmt.Println(string(data))
+			continue
+		}
+		printReplayOutput(out)
+	}
+	if mismatches > 0 {
+		return fmt.Errorf("%d of %d blocks mismatched", mismatches, len(blocks))
+	}
+	return nil
+}
+
+func replayBlock(chain *core.BlockChain, block *types.Block) *replayOutput {
+	out := &replayOutput{Number: block.NumberU64(), Hash: block.Hash(), Root: block.Root()}
+	result, err := chain.ReplayBlock(block)
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	out.ReplayRoot = result.ReplayRoot
+	out.Match = result.RootMatch() && result.ValidateErr == nil
+	if result.ValidateErr != nil {
+		out.Error = result.ValidateErr.Error()
+	}
+	if !result.RootMatch() {
+		if out.Diffs, out.DiffBase, err = chain.DiffReplay(result); err != nil {
+			out.Error = fmt.Sprintf("diff failed: %v", err)
+		}
+	}
+	return out
+}
+
+func printReplayOutput(out *replayOutput) {
+	status := "OK"
+	if !out.Match {
+		status = "MISMATCH"
+	}
+	fmt.Printf("block #%d %s %s root=%x replay=%x\n", out.Number, out.Hash.TerminalString(), status, out.Root, out.ReplayRoot)
+	if out.Error != "" {
+		fmt.Printf("    error: %s\n", out.Error)
+	}
+	if len(out.Diffs) > 0 && out.DiffBase != out.Root {
+		fmt.Printf("    state %x not stored, diff against the parent state %x\n", out.Root, out.DiffBase)
+	}
+	for _, diff := range out.Diffs {
+		fmt.Printf("    %s\n", diff)
+	}
+}
+
+// readBadBlock 读取十六进制或二进制编码的区块RLP
+func readBadBlock(path string) (*types.Block, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if text := strings.Trim(string(bytes.TrimSpace(data)), "\""); strings.HasPrefix(text, "0x") {
+		if data, err = hexutil.Decode(text); err != nil {
+			return nil, err
+		}
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(data, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
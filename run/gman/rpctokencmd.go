@@ -0,0 +1,100 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MatrixAINetwork/go-matrix/pod"
+	"github.com/MatrixAINetwork/go-matrix/rpc"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	"github.com/dgrijalva/jwt-go"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	rpcTokenNamespacesFlag = cli.StringFlag{
+		Name:  "namespaces",
+		Usage: `Comma separated list of API namespaces the token may call ("*" for all)`,
+	}
+	rpcTokenMethodsFlag = cli.StringFlag{
+		Name:  "methods",
+		Usage: "Comma separated list of single methods (namespace_method) the token may call",
+	}
+	rpcTokenSubjectFlag = cli.StringFlag{
+		Name:  "subject",
+		Usage: "Client name carried by the token, rate limits are applied per subject",
+	}
+	rpcTokenExpiryFlag = cli.DurationFlag{
+		Name:  "expiry",
+		Usage: "Validity of the token, the node rejects tokens that never expire",
+		Value: 30 * 24 * time.Hour,
+	}
+	rpcTokenCommand = cli.Command{
+		Action:    utils.MigrateFlags(rpcToken),
+		Name:      "rpctoken",
+		Usage:     "Issue a JWT bearer token for the HTTP-RPC and WS-RPC interfaces",
+		ArgsUsage: " ",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Flags: []cli.Flag{
+			utils.RPCJWTSecretFlag,
+			rpcTokenNamespacesFlag,
+			rpcTokenMethodsFlag,
+			rpcTokenSubjectFlag,
+			rpcTokenExpiryFlag,
+		},
+		Description: `
+Signs a token with the secret given to the node by --rpcjwtsecret. The secret
+file holds at least 32 hex encoded random bytes, e.g. from "openssl rand -hex 32".
+Clients send the token in an "Authorization: Bearer <token>" header, or in the
+token query parameter of the WebSocket URL.`,
+	}
+)
+
+func rpcToken(ctx *cli.Context) error {
+	path := ctx.String(utils.RPCJWTSecretFlag.Name)
+	if path == "" {
+		utils.Fatalf("The secret file is required (--%s)", utils.RPCJWTSecretFlag.Name)
+	}
+	secret, err := pod.LoadJWTSecret(path)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	claims := &rpc.AuthClaims{
+		Namespaces: splitList(ctx.String(rpcTokenNamespacesFlag.Name)),
+		Methods:    splitList(ctx.String(rpcTokenMethodsFlag.Name)),
+		StandardClaims: jwt.StandardClaims{
+			Subject:  ctx.String(rpcTokenSubjectFlag.Name),
+			IssuedAt: time.Now().Unix(),
+		},
+	}
+	if len(claims.Namespaces) == 0 && len(claims.Methods) == 0 {
+		utils.Fatalf("The token must allow some namespaces or methods")
+	}
+	expiry := ctx.Duration(rpcTokenExpiryFlag.Name)
+	if expiry <= 0 {
+		utils.Fatalf("The token must expire (--%s)", rpcTokenExpiryFlag.Name)
+	}
+	claims.ExpiresAt = time.Now().Add(expiry).Unix()
+	token, err := rpc.NewAuthToken(secret, claims)
+	if err != nil {
+		utils.Fatalf("Failed to sign token: %v", err)
+	}
+	fmt.Println(token)
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty entries.
+func splitList(input string) []string {
+	var list []string
+	for _, item := range strings.Split(input, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,318 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/MatrixAINetwork/go-matrix/accounts/keystore"
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/consensus/mtxdpos"
+	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/state"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var superBlockCommand = cli.Command{
+	Name:     "superblock",
+	Usage:    "Propose, sign and merge super blocks signed by several super block accounts",
+	Category: "BLOCKCHAIN COMMANDS",
+	Description: `
+A super block needs the signatures of the super block accounts before it can be
+imported with 'gman importSuperBlock'. The proposer creates a package out of the
+super block genesis file, every super account holder signs the package offline
+with its key file, and the signed packages are finally merged into the super
+block file once enough signatures are collected.`,
+	Subcommands: []cli.Command{
+		{
+			Name:      "propose",
+			Usage:     "Create the unsigned package of a super block",
+			ArgsUsage: "<genesisPath> <packageFile>",
+			Action:    utils.MigrateFlags(proposeSuperBlock),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+			},
+			Description: `
+Generates the super block of the genesis file on top of its parent block of the
+local chain and writes the package to sign: the super block with its state and
+transactions roots, the hash to sign, the super block accounts and the number of
+signatures needed.`,
+		},
+		{
+			Name:      "sign",
+			Usage:     "Sign a super block package with a key file",
+			ArgsUsage: "<packageFile> <keyFile>",
+			Action:    utils.MigrateFlags(signSuperBlockPackage),
+			Flags: []cli.Flag{
+				utils.PasswordFileFlag,
+			},
+			Description: `
+Checks the hash of the package, signs it with the key of the key file and
+appends the signature to the package. The chain data is not needed, the key
+must belong to one of the super block accounts of the package.`,
+		},
+		{
+			Name:      "merge",
+			Usage:     "Merge signed packages into the importable super block file",
+			ArgsUsage: "<outFile> <packageFile> [packageFile...]",
+			Action:    utils.MigrateFlags(mergeSuperBlock),
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+			},
+			Description: `
+Collects the signatures of the given packages of the same super block, keeps one
+valid signature per super block account of the local chain and writes the super
+block file once the signature threshold is met.`,
+		},
+	},
+}
+
+// superBlockPackage is a super block waiting for the signatures of the super
+// block accounts.
+type superBlockPackage struct {
+	Genesis   *core.Genesis    `json:"genesis"`
+	Hash      common.Hash      `json:"hash"`      // 待签名的区块哈希
+	Accounts  []common.Address `json:"accounts"`  // 超级区块账户
+	Threshold int              `json:"threshold"` // 所需签名数量
+}
+
+func readSuperBlockPackage(path string) *superBlockPackage {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		utils.Fatalf("Failed to read package %s: %v", path, err)
+	}
+	pkg := new(superBlockPackage)
+	if err := json.Unmarshal(data, pkg); err != nil {
+		utils.Fatalf("Invalid package %s: %v", path, err)
+	}
+	if pkg.Genesis == nil {
+		utils.Fatalf("Invalid package %s: no super block", path)
+	}
+	hash, err := pkg.Genesis.SuperBlockSignHash()
+	if err != nil {
+		utils.Fatalf("Invalid package %s: %v", path, err)
+	}
+	if hash != pkg.Hash {
+		utils.Fatalf("Invalid package %s: hash mismatch, have %s, want %s", path, hash.Hex(), pkg.Hash.Hex())
+	}
+	return pkg
+}
+
+func writeJSONFile(path string, v interface{}) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode %s: %v", path, err)
+	}
+	if err := ioutil.WriteFile(path, out, 0644); err != nil {
+		utils.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// superBlockSigners returns the valid signatures of hash made by the given
+// accounts, one per account, along with the signing accounts.
+func superBlockSigners(hash common.Hash, signatures []common.Signature, accounts []common.Address) ([]common.Signature, []common.Address) {
+	allowed := make(map[common.Address]bool, len(accounts))
+	for _, account := range accounts {
+		allowed[account] = true
+	}
+	var (
+		signs   []common.Signature
+		signers []common.Address
+		seen    = make(map[common.Address]bool)
+	)
+	for _, sign := range signatures {
+		signer, _, err := crypto.VerifySignWithValidate(hash.Bytes(), common.CopyBytes(sign.Bytes()))
+		if err != nil {
+			fmt.Println("Skipping invalid signature:", err)
+			continue
+		}
+		if !allowed[signer] {
+			fmt.Println("Skipping signature of", signer.Hex(), "which is not a super block account")
+			continue
+		}
+		if seen[signer] {
+			continue
+		}
+		seen[signer] = true
+		signs = append(signs, sign)
+		signers = append(signers, signer)
+	}
+	return signs, signers
+}
+
+func proposeSuperBlock(ctx *cli.Context) error {
+	if len(ctx.Args()) < 2 {
+		utils.Fatalf("This command requires 2 arguments.")
+	}
+	genesisPath, packagePath := ctx.Args().Get(0), ctx.Args().Get(1)
+	data, err := ioutil.ReadFile(genesisPath)
+	if err != nil {
+		utils.Fatalf("Failed to read genesis file: %v", err)
+	}
+	genesis := new(core.Genesis)
+	if err := json.Unmarshal(data, genesis); err != nil {
+		utils.Fatalf("invalid genesis file: %v", err)
+	}
+	genesis.Signatures = make([]common.Signature, 0)
+
+	stack, _ := makeConfigNode(ctx)
+	chain, chainDB := utils.MakeChain(ctx, stack)
+	if chain == nil {
+		utils.Fatalf("make chain err")
+	}
+	defer chain.Stop()
+
+	parent := chain.GetHeaderByHash(genesis.ParentHash)
+	if parent == nil {
+		utils.Fatalf("Unknown parent block %s", genesis.ParentHash.Hex())
+	}
+	if parent.Number.Uint64()+1 != genesis.Number {
+		utils.Fatalf("Super block number %d does not follow parent block %d", genesis.Number, parent.Number.Uint64())
+	}
+	superBlock := genesis.GenSuperBlock(parent, state.NewDatabase(chainDB), chain.Config())
+	if superBlock == nil {
+		utils.Fatalf("genesis super block err")
+	}
+	if !superBlock.IsSuperBlock() {
+		utils.Fatalf("Genesis file is not a super block, leader %s", superBlock.Header().Leader.Hex())
+	}
+	genesis.Root = superBlock.Root()
+	genesis.TxHash = superBlock.TxHash()
+
+	hash, err := genesis.SuperBlockSignHash()
+	if err != nil {
+		utils.Fatalf("Failed to hash super block: %v", err)
+	}
+	if hash != superBlock.HashNoSigns() {
+		utils.Fatalf("Super block hash mismatch: have %s, want %s", hash.Hex(), superBlock.HashNoSigns().Hex())
+	}
+	accounts, err := chain.GetBlockSuperAccounts(chain.GetCurrentHash())
+	if err != nil || len(accounts) == 0 {
+		utils.Fatalf("Failed to get the super block accounts: %v", err)
+	}
+	pkg := &superBlockPackage{
+		Genesis:   genesis,
+		Hash:      hash,
+		Accounts:  accounts,
+		Threshold: mtxdpos.NewMtxDPOS(chain.Config().SimpleMode).SuperNodeTarget(len(accounts)),
+	}
+	writeJSONFile(packagePath, pkg)
+	fmt.Println("Super block hash:", hash.Hex())
+	fmt.Printf("Exported super block package to %s, %d of %d super block accounts must sign\n", packagePath, pkg.Threshold, len(accounts))
+	return nil
+}
+
+func signSuperBlockPackage(ctx *cli.Context) error {
+	if len(ctx.Args()) < 2 {
+		utils.Fatalf("This command requires 2 arguments.")
+	}
+	packagePath, keyPath := ctx.Args().Get(0), ctx.Args().Get(1)
+	pkg := readSuperBlockPackage(packagePath)
+
+	keyjson, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		utils.Fatalf("Failed to read key file: %v", err)
+	}
+	passphrase := getPassPhrase("Passphrase of the super block account", false, 0, utils.MakePasswordList(ctx))
+	key, err := keystore.DecryptKey(keyjson, passphrase)
+	if err != nil {
+		utils.Fatalf("Failed to decrypt key: %v", err)
+	}
+	_, signers := superBlockSigners(pkg.Hash, pkg.Genesis.Signatures, pkg.Accounts)
+	if containsAddress(signers, key.Address) {
+		fmt.Println("Package already signed by", key.Address.Hex())
+		return nil
+	}
+	if !containsAddress(pkg.Accounts, key.Address) {
+		utils.Fatalf("Account %s is not a super block account", key.Address.Hex())
+	}
+	signBytes, err := crypto.Sign(pkg.Hash.Bytes(), key.PrivateKey)
+	if err != nil {
+		utils.Fatalf("Failed to sign super block: %v", err)
+	}
+	pkg.Genesis.Signatures = append(pkg.Genesis.Signatures, common.BytesToSignature(signBytes))
+	writeJSONFile(packagePath, pkg)
+	fmt.Printf("Signed super block %s with %s (%d/%d signatures)\n", pkg.Hash.Hex(), key.Address.Hex(), len(signers)+1, pkg.Threshold)
+	return nil
+}
+
+func containsAddress(accounts []common.Address, addr common.Address) bool {
+	for _, account := range accounts {
+		if account == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func mergeSuperBlock(ctx *cli.Context) error {
+	if len(ctx.Args()) < 2 {
+		utils.Fatalf("This command requires an output file and at least one package.")
+	}
+	outPath := ctx.Args().First()
+
+	var (
+		genesis    *core.Genesis
+		hash       common.Hash
+		signatures []common.Signature
+	)
+	for _, path := range ctx.Args().Tail() {
+		pkg := readSuperBlockPackage(path)
+		if genesis == nil {
+			genesis, hash = pkg.Genesis, pkg.Hash
+		} else if pkg.Hash != hash {
+			utils.Fatalf("Package %s is for super block %s, want %s", path, pkg.Hash.Hex(), hash.Hex())
+		}
+		signatures = append(signatures, pkg.Genesis.Signatures...)
+	}
+
+	stack, _ := makeConfigNode(ctx)
+	chain, chainDB := utils.MakeChain(ctx, stack)
+	if chain == nil {
+		utils.Fatalf("make chain err")
+	}
+	defer chain.Stop()
+
+	// 签名门限以本地链的超级区块账户为准, 与导入时的检查一致
+	accounts, err := chain.GetBlockSuperAccounts(chain.GetCurrentHash())
+	if err != nil || len(accounts) == 0 {
+		utils.Fatalf("Failed to get the super block accounts: %v", err)
+	}
+	threshold := mtxdpos.NewMtxDPOS(chain.Config().SimpleMode).SuperNodeTarget(len(accounts))
+	signs, signers := superBlockSigners(hash, signatures, accounts)
+	if len(signs) < threshold {
+		for _, account := range accounts {
+			if !containsAddress(signers, account) {
+				fmt.Println("Missing signature of", account.Hex())
+			}
+		}
+		utils.Fatalf("Not enough signatures: have %d, need %d of %d super block accounts", len(signs), threshold, len(accounts))
+	}
+	genesis.Signatures = signs
+
+	parent := chain.GetHeaderByHash(genesis.ParentHash)
+	if parent == nil {
+		utils.Fatalf("Unknown parent block %s", genesis.ParentHash.Hex())
+	}
+	root, txHash := genesis.Root, genesis.TxHash
+	superBlock := genesis.GenSuperBlock(parent, state.NewDatabase(chainDB), chain.Config())
+	if superBlock == nil {
+		utils.Fatalf("genesis super block err")
+	}
+	if superBlock.Root() != root || superBlock.TxHash() != txHash {
+		utils.Fatalf("Super block does not match the local chain: root %s, transactions root %s", superBlock.Root().Hex(), superBlock.TxHash().Hex())
+	}
+	header := superBlock.Header()
+	if err := chain.DPOSEngine(header.Version).VerifyBlock(chain, header); err != nil {
+		utils.Fatalf("Super block verification failed: %v", err)
+	}
+	writeJSONFile(outPath, genesis)
+	fmt.Printf("Exported super block %s with %d/%d signatures to %s\n", hash.Hex(), len(signs), threshold, outPath)
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"reflect"
+	"testing"
+
+	"github.com/MatrixAINetwork/go-matrix/common"
+	"github.com/MatrixAINetwork/go-matrix/crypto"
+)
+
+func TestSuperBlockSigners(t *testing.T) {
+	hash := common.HexToHash("0x0102")
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	outsider, _ := crypto.GenerateKey()
+	addr1 := crypto.PubkeyToAddress(key1.PublicKey)
+	addr2 := crypto.PubkeyToAddress(key2.PublicKey)
+
+	sign := func(hash common.Hash, key *ecdsa.PrivateKey) common.Signature {
+		sig, err := crypto.Sign(hash.Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		return common.BytesToSignature(sig)
+	}
+	sig1 := sign(hash, key1)
+	sig2 := sign(hash, key2)
+	signatures := []common.Signature{
+		sig1,
+		{0x01},                     // 无效签名
+		sign(hash, outsider),       // 非超级区块账户
+		sign(common.Hash{1}, key2), // 其他区块的签名
+		sig1,                       // 重复签名
+		sig2,
+	}
+	accounts := []common.Address{addr1, addr2}
+
+	signs, signers := superBlockSigners(hash, signatures, accounts)
+	if want := []common.Signature{sig1, sig2}; !reflect.DeepEqual(signs, want) {
+		t.Errorf("signatures mismatch: have %x, want %x", signs, want)
+	}
+	if want := []common.Address{addr1, addr2}; !reflect.DeepEqual(signers, want) {
+		t.Errorf("signers mismatch: have %x, want %x", signers, want)
+	}
+	// 校验签名不修改输入
+	if signatures[0] != sig1 {
+		t.Errorf("signature modified: have %x, want %x", signatures[0], sig1)
+	}
+	if signs, signers := superBlockSigners(hash, signatures, nil); len(signs) != 0 || len(signers) != 0 {
+		t.Errorf("signatures accepted without super block accounts: %x", signers)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2018 The MATRIX Authors
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or or http://www.opensource.org/licenses/mit-license.php
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
+	"github.com/MatrixAINetwork/go-matrix/man"
+	"github.com/MatrixAINetwork/go-matrix/run/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var timelineCommand = cli.Command{
+	Action:    utils.MigrateFlags(exportTimeline),
+	Name:      "timeline",
+	Usage:     "Export the recorded consensus timelines",
+	ArgsUsage: "<first> <last> [<filename>]",
+	Category:  "BLOCKCHAIN COMMANDS",
+	Flags: []cli.Flag{
+		utils.DataDirFlag,
+		utils.CacheFlag,
+		utils.LightModeFlag,
+	},
+	Description: `
+The timeline command works on a stopped node. It writes the consensus timelines
+the node recorded for the heights first to last, one JSON object per line, to
+the file or to stdout if no file is given. A timeline lists, per leader turn,
+when the POS request, the votes, the reelection messages, the mining results
+and the insert notification arrived, and when the block got inserted.`,
+}
+
+func exportTimeline(ctx *cli.Context) error {
+	if len(ctx.Args()) < 2 {
+		utils.Fatalf("This command requires the first and last block numbers.")
+	}
+	first, ferr := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+	last, lerr := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+	if ferr != nil || lerr != nil {
+		utils.Fatalf("Export error in parsing parameters: block number not an integer")
+	}
+	if last < first {
+		utils.Fatalf("Export error: last block number below the first one")
+	}
+	stack, _ := makeConfigNode(ctx)
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	var out io.Writer = os.Stdout
+	if len(ctx.Args()) > 2 {
+		fh, err := os.OpenFile(ctx.Args().Get(2), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+		if err != nil {
+			utils.Fatalf("Export error: %v", err)
+		}
+		defer fh.Close()
+		out = fh
+	}
+	writer := bufio.NewWriter(out)
+	defer writer.Flush()
+
+	enc := json.NewEncoder(writer)
+	count := 0
+	for number := first; number <= last; number++ {
+		timeline := rawdb.ReadConsensusTimeline(db, number)
+		if timeline == nil {
+			continue
+		}
+		if err := enc.Encode(man.NewRPCConsensusTimeline(timeline)); err != nil {
+			utils.Fatalf("Export error: %v", err)
+		}
+		count++
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d consensus timelines\n", count)
+	return nil
+}
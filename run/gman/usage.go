@@ -64,6 +64,11 @@ var AppHelpFlagGroups = []flagGroup{
 			//utils.RinkebyFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
+			utils.GCModeRecentFlag,
+			utils.AddrIndexFlag,
+			utils.AncientFlag,
+			utils.AncientDepthFlag,
+			utils.AncientSuperBlockFlag,
 			utils.ManStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
@@ -113,7 +118,7 @@ var AppHelpFlagGroups = []flagGroup{
 			//utils.TxPoolJournalFlag,//Y
 			//utils.TxPoolRejournalFlag,
 			utils.TxPoolPriceLimitFlag,
-			//utils.TxPoolPriceBumpFlag,//Y
+			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
 			utils.TxPoolGlobalSlotsFlag,
 			utils.TxPoolAccountQueueFlag,
@@ -149,6 +154,10 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.RPCJWTSecretFlag,
+			utils.RPCAllowIPsFlag,
+			utils.RPCRateLimitFlag,
+			utils.RPCRateBurstFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
@@ -180,6 +189,10 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.MiningEnabledFlag,
 			utils.MinerThreadsFlag,
+			utils.StratumEnabledFlag,
+			utils.StratumListenAddrFlag,
+			utils.StratumPortFlag,
+			utils.StratumDifficultyFlag,
 			utils.ManerbaseFlag,
 			utils.TargetGasLimitFlag,
 			utils.GasPriceFlag,
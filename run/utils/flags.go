@@ -9,6 +9,7 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -28,6 +29,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/consensus/manash"
 	"github.com/MatrixAINetwork/go-matrix/console"
 	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/rawdb"
 	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/vm"
 	"github.com/MatrixAINetwork/go-matrix/crypto"
@@ -39,11 +41,13 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/mandb"
 	"github.com/MatrixAINetwork/go-matrix/manstats"
 	"github.com/MatrixAINetwork/go-matrix/metrics"
+	"github.com/MatrixAINetwork/go-matrix/miner"
 	"github.com/MatrixAINetwork/go-matrix/p2p"
 	"github.com/MatrixAINetwork/go-matrix/p2p/discover"
 	"github.com/MatrixAINetwork/go-matrix/p2p/nat"
 	"github.com/MatrixAINetwork/go-matrix/p2p/netutil"
 	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/enstrust"
 	"github.com/MatrixAINetwork/go-matrix/pod"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -130,11 +134,11 @@ var (
 	}
 	DeveloperFlag = cli.BoolFlag{
 		Name:  "dev",
-		Usage: "Ephemeral proof-of-authority network with a pre-funded developer account, mining enabled",
+		Usage: "Ephemeral single-node network with a pre-funded developer account playing every consensus role, mining enabled",
 	}
 	DeveloperPeriodFlag = cli.IntFlag{
 		Name:  "dev.period",
-		Usage: "Block period to use in developer mode (0 = mine only if transaction pending)",
+		Usage: "Block period in seconds to use in developer mode (0 = as fast as the consensus allows)",
 	}
 	IdentityFlag = cli.StringFlag{
 		Name:  "identity",
@@ -165,9 +169,31 @@ var (
 	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
-		Usage: `Blockchain garbage collection mode ("full", "archive")`,
+		Usage: `Blockchain garbage collection mode ("full", "archive"), "full" keeps the recent states and the ones consensus reads`,
 		Value: "archive",
 	}
+	GCModeRecentFlag = cli.Uint64Flag{
+		Name:  "gcmode.recent",
+		Usage: `Number of recent block states kept in "full" garbage collection mode`,
+		Value: 128,
+	}
+	AddrIndexFlag = cli.BoolFlag{
+		Name:  "addrindex",
+		Usage: "Maintain a per address and currency transaction index (man_getAddressTransactions)",
+	}
+	AncientFlag = cli.BoolFlag{
+		Name:  "ancient",
+		Usage: "Move old canonical blocks out of leveldb into the append-only ancient store",
+	}
+	AncientDepthFlag = cli.Uint64Flag{
+		Name:  "ancient.depth",
+		Usage: "Number of recent blocks kept in leveldb when the ancient store is enabled",
+		Value: man.DefaultConfig.AncientDepth,
+	}
+	AncientSuperBlockFlag = cli.BoolFlag{
+		Name:  "ancient.superblock",
+		Usage: "Never move blocks from the last super block on into the ancient store",
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
@@ -260,11 +286,11 @@ var (
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
 		Value: man.DefaultConfig.TxPool.PriceLimit,
 	}
-	//TxPoolPriceBumpFlag = cli.Uint64Flag{ //Y
-	//	Name:  "txpool.pricebump",
-	//	Usage: "Price bump percentage to replace an already existing transaction",
-	//	Value: eth.DefaultConfig.TxPool.PriceBump,
-	//}
+	TxPoolPriceBumpFlag = cli.Uint64Flag{
+		Name:  "txpool.pricebump",
+		Usage: "Price bump percentage to replace an already existing transaction",
+		Value: man.DefaultConfig.TxPool.PriceBump,
+	}
 	TxPoolAccountSlotsFlag = cli.Uint64Flag{
 		Name:  "txpool.accountslots",
 		Usage: "Minimum number of executable transaction slots guaranteed per account",
@@ -321,6 +347,25 @@ var (
 		Usage: "Number of CPU threads to use for mining",
 		Value: runtime.NumCPU(),
 	}
+	StratumEnabledFlag = cli.BoolFlag{
+		Name:  "stratum",
+		Usage: "Enable the stratum mining server for external miners",
+	}
+	StratumListenAddrFlag = cli.StringFlag{
+		Name:  "stratumaddr",
+		Usage: "Stratum mining server listening interface",
+		Value: "0.0.0.0",
+	}
+	StratumPortFlag = cli.IntFlag{
+		Name:  "stratumport",
+		Usage: "Stratum mining server listening port",
+		Value: 8008,
+	}
+	StratumDifficultyFlag = cli.Uint64Flag{
+		Name:  "stratumdiff",
+		Usage: "Difficulty of the shares requested from stratum miners",
+		Value: miner.DefaultStratumConfig.ShareDifficulty,
+	}
 	TargetGasLimitFlag = cli.Uint64Flag{
 		Name:  "targetgaslimit",
 		Usage: "Target gas limit sets the artificial target gas floor for the blocks to mine",
@@ -433,6 +478,25 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpcjwtsecret",
+		Usage: "File holding the hex encoded secret of the JWT bearer tokens required on the HTTP-RPC and WS-RPC interfaces",
+		Value: "",
+	}
+	RPCAllowIPsFlag = cli.StringFlag{
+		Name:  "rpcallowip",
+		Usage: "Comma separated list of IPs and CIDR ranges allowed to reach the HTTP-RPC and WS-RPC interfaces",
+		Value: "",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpcratelimit",
+		Usage: "Requests per second a HTTP-RPC or WS-RPC client may issue on average (0 = unlimited)",
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpcrateburst",
+		Usage: "Requests a HTTP-RPC or WS-RPC client may issue at once on top of --rpcratelimit",
+		Value: 100,
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -758,6 +822,24 @@ func setWS(ctx *cli.Context, cfg *pod.Config) {
 	}
 }
 
+// setRPCAuth applies the access control of the HTTP and WebSocket RPC
+// interfaces from the command line flags.
+func setRPCAuth(ctx *cli.Context, cfg *pod.Config) {
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.RPCJWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAllowIPsFlag.Name) {
+		cfg.RPCAllowIPs = splitAndTrim(ctx.GlobalString(RPCAllowIPsFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCRateLimit = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+		cfg.RPCRateBurst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateBurstFlag.Name) {
+		cfg.RPCRateBurst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *pod.Config) {
@@ -1044,6 +1126,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *pod.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCAuth(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	switch {
@@ -1096,9 +1179,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
-	//if ctx.GlobalIsSet(TxPoolPriceBumpFlag.Name) {//Y
-	//	cfg.PriceBump = ctx.GlobalUint64(TxPoolPriceBumpFlag.Name)
-	//}
+	if ctx.GlobalIsSet(TxPoolPriceBumpFlag.Name) {
+		cfg.PriceBump = ctx.GlobalUint64(TxPoolPriceBumpFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolAccountSlotsFlag.Name) {
 		cfg.AccountSlots = ctx.GlobalUint64(TxPoolAccountSlotsFlag.Name)
 	}
@@ -1216,6 +1299,9 @@ func SetManConfig(ctx *cli.Context, stack *pod.Node, cfg *man.Config) {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
 	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+	if ctx.GlobalIsSet(GCModeRecentFlag.Name) {
+		cfg.TriesInMemory = ctx.GlobalUint64(GCModeRecentFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
@@ -1223,6 +1309,24 @@ func SetManConfig(ctx *cli.Context, stack *pod.Node, cfg *man.Config) {
 	if ctx.GlobalIsSet(MinerThreadsFlag.Name) {
 		cfg.MinerThreads = ctx.GlobalInt(MinerThreadsFlag.Name)
 	}
+	if ctx.GlobalBool(StratumEnabledFlag.Name) {
+		cfg.Stratum.Addr = fmt.Sprintf("%s:%d", ctx.GlobalString(StratumListenAddrFlag.Name), ctx.GlobalInt(StratumPortFlag.Name))
+	}
+	if ctx.GlobalIsSet(StratumDifficultyFlag.Name) {
+		cfg.Stratum.ShareDifficulty = ctx.GlobalUint64(StratumDifficultyFlag.Name)
+	}
+	if ctx.GlobalIsSet(AddrIndexFlag.Name) {
+		cfg.AddrIndex = ctx.GlobalBool(AddrIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.Ancient = ctx.GlobalBool(AncientFlag.Name)
+	}
+	if ctx.GlobalIsSet(AncientDepthFlag.Name) {
+		cfg.AncientDepth = ctx.GlobalUint64(AncientDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(AncientSuperBlockFlag.Name) {
+		cfg.AncientSuperBlock = ctx.GlobalBool(AncientSuperBlockFlag.Name)
+	}
 	if ctx.GlobalIsSet(DocRootFlag.Name) {
 		cfg.DocRoot = ctx.GlobalString(DocRootFlag.Name)
 	}
@@ -1249,36 +1353,77 @@ func SetManConfig(ctx *cli.Context, stack *pod.Node, cfg *man.Config) {
 			cfg.NetworkId = 4
 		}
 		cfg.Genesis = core.DefaultRinkebyGenesisBlock()
-	case ctx.GlobalBool(DeveloperFlag.Name):
-		// Create new developer account or reuse existing one
-		var (
-			developer accounts.Account
-			err       error
-		)
-		if accs := ks.Accounts(); len(accs) > 0 {
-			developer = ks.Accounts()[0]
-		} else {
-			developer, err = ks.NewAccount("")
-			if err != nil {
-				Fatalf("Failed to create developer account: %v", err)
-			}
-		}
-		if err := ks.Unlock(developer, ""); err != nil {
-			Fatalf("Failed to unlock developer account: %v", err)
-		}
-		log.Info("Using developer account", "address", developer.Address)
-
-		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), developer.Address)
-		if !ctx.GlobalIsSet(GasPriceFlag.Name) {
-			cfg.GasPrice = big.NewInt(1)
-		}
 	}*/
+	if ctx.GlobalBool(DeveloperFlag.Name) {
+		setDeveloper(ctx, stack, ks, cfg)
+	}
 	// TODO(fjl): move trie cache generations into config
 	if gen := ctx.GlobalInt(TrieCacheGenFlag.Name); gen > 0 {
 		state.MaxTrieCacheGen = uint16(gen)
 	}
 }
 
+// setDeveloper configures the single-node developer chain from the command
+// line flags.
+func setDeveloper(ctx *cli.Context, stack *pod.Node, ks *keystore.KeyStore, cfg *man.Config) {
+	period := ctx.GlobalInt(DeveloperPeriodFlag.Name)
+	if period < 0 {
+		Fatalf("Option %q: must not be negative", DeveloperPeriodFlag.Name)
+	}
+	if !ctx.GlobalIsSet(GasPriceFlag.Name) {
+		cfg.GasPrice = big.NewInt(1)
+	}
+	chaindb := MakeChainDatabase(ctx, stack)
+	err := SetupDeveloper(stack, ks, chaindb, cfg, uint64(period))
+	chaindb.Close()
+	if err != nil {
+		Fatalf("%v", err)
+	}
+}
+
+// SetupDeveloper configures the single-node developer chain. The first keystore
+// account, created if there is none, is the faucet and plays every consensus
+// role; it is unlocked with an empty password and entrusted for signing. A
+// developer chain already stored in chaindb is continued.
+func SetupDeveloper(stack *pod.Node, ks *keystore.KeyStore, chaindb mandb.Database, cfg *man.Config, period uint64) error {
+	var (
+		developer accounts.Account
+		err       error
+	)
+	if accs := ks.Accounts(); len(accs) > 0 {
+		developer = accs[0]
+	} else {
+		developer, err = ks.NewAccount("")
+		if err != nil {
+			return fmt.Errorf("failed to create developer account: %v", err)
+		}
+	}
+	if err := ks.Unlock(developer, ""); err != nil {
+		return fmt.Errorf("failed to unlock developer account: %v", err)
+	}
+	if err := entrust.EntrustAccountValue.SetEntrustValue(map[common.Address]string{developer.Address: ""}); err != nil {
+		return fmt.Errorf("failed to entrust developer account: %v", err)
+	}
+	log.Info("Using developer account", "address", base58.Base58EncodeToString("MAN", developer.Address))
+
+	stack.SetManAddress(developer.Address)
+	cfg.Manerbase = developer.Address
+
+	// 数据目录中已有开发链时沿用其创世区块
+	if rawdb.ReadCanonicalHash(chaindb, 0) != (common.Hash{}) {
+		log.Info("Continuing the existing developer chain")
+		return nil
+	}
+	cfg.Genesis = core.DeveloperGenesisBlock(period, developer.Address)
+	// 开发者账户是唯一的版本超级账户, 创世区块的版本号由它签名
+	versionSign, err := ks.SignHashValidateWithPass(developer, "", common.BytesToHash([]byte(cfg.Genesis.Version)).Bytes(), true)
+	if err != nil {
+		return fmt.Errorf("failed to sign developer genesis version: %v", err)
+	}
+	cfg.Genesis.VersionSignatures = []common.Signature{common.BytesToSignature(versionSign)}
+	return nil
+}
+
 // SetDashboardConfig applies dashboard related command line flags to the config.
 func SetDashboardConfig(ctx *cli.Context, cfg *dashboard.Config) {
 	cfg.Host = ctx.GlobalString(DashboardAddrFlag.Name)
@@ -1301,7 +1446,11 @@ func RegisterManService(stack *pod.Node, cfg *man.Config) {
 // RegisterDashboardService adds a dashboard to the stack.
 func RegisterDashboardService(stack *pod.Node, cfg *dashboard.Config, commit string) {
 	stack.Register(func(ctx *pod.ServiceContext) (pod.Service, error) {
-		return dashboard.New(cfg, commit)
+		// 轻节点没有man服务, 仪表盘只显示系统信息
+		var manServ *man.Matrix
+		ctx.Service(&manServ)
+
+		return dashboard.New(cfg, commit, manServ)
 	})
 }
 
@@ -1327,21 +1476,59 @@ func SetupNetwork(ctx *cli.Context) {
 
 // MakeChainDatabase open an LevelDB using the flags passed to the client and will hard crash if it fails.
 func MakeChainDatabase(ctx *cli.Context, stack *pod.Node) mandb.Database {
+	return makeChainDatabase(ctx, stack, false)
+}
+
+// MakeReadOnlyChainDatabase opens the existing chain database for reading only.
+// Writes are kept in memory and never reach the data directory.
+func MakeReadOnlyChainDatabase(ctx *cli.Context, stack *pod.Node) mandb.Database {
+	return makeChainDatabase(ctx, stack, true)
+}
+
+func makeChainDatabase(ctx *cli.Context, stack *pod.Node, readonly bool) mandb.Database {
 	var (
 		cache   = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 		handles = makeDatabaseHandles()
+		chainDb mandb.Database
+		err     error
 	)
 	name := "chaindata"
 	if ctx.GlobalBool(LightModeFlag.Name) {
 		name = "lightchaindata"
 	}
-	chainDb, err := stack.OpenDatabase(name, cache, handles)
+	if readonly {
+		chainDb, err = stack.OpenReadOnlyDatabase(name, cache, handles)
+	} else {
+		chainDb, err = stack.OpenDatabase(name, cache, handles)
+	}
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
+	// 节点启用过ancient store时, 离线命令也要能读到已冻结的区块
+	if dir := MakeAncientDir(stack, name); dir != "" {
+		if _, err := os.Stat(dir); err == nil {
+			if readonly {
+				chainDb, err = rawdb.NewDatabaseWithReadOnlyFreezer(chainDb, dir)
+			} else {
+				chainDb, err = rawdb.NewDatabaseWithFreezer(chainDb, dir)
+			}
+			if err != nil {
+				Fatalf("Could not open ancient database: %v", err)
+			}
+		}
+	}
 	return chainDb
 }
 
+// MakeAncientDir returns the ancient store directory of the named chain database,
+// or an empty string for ephemeral nodes.
+func MakeAncientDir(stack *pod.Node, name string) string {
+	if dir := stack.ResolvePath(name); dir != "" {
+		return filepath.Join(dir, "ancient")
+	}
+	return ""
+}
+
 func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	var genesis *core.Genesis
 	switch {
@@ -1357,9 +1544,18 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 
 // MakeChain creates a chain manager from set command line flags.
 func MakeChain(ctx *cli.Context, stack *pod.Node) (chain *core.BlockChain, chainDb mandb.Database) {
-	var err error
 	chainDb = MakeChainDatabase(ctx, stack)
+	return makeChain(ctx, stack, chainDb), chainDb
+}
+
+// MakeReadOnlyChain creates a chain on top of the read-only chain database, for
+// offline tools that must leave the data directory untouched.
+func MakeReadOnlyChain(ctx *cli.Context, stack *pod.Node) (chain *core.BlockChain, chainDb mandb.Database) {
+	chainDb = MakeReadOnlyChainDatabase(ctx, stack)
+	return makeChain(ctx, stack, chainDb), chainDb
+}
 
+func makeChain(ctx *cli.Context, stack *pod.Node, chainDb mandb.Database) *core.BlockChain {
 	config, _, err := core.SetupGenesisBlock(chainDb, MakeGenesis(ctx))
 	if err != nil {
 		Fatalf("%v", err)
@@ -1387,17 +1583,18 @@ func MakeChain(ctx *cli.Context, stack *pod.Node) (chain *core.BlockChain, chain
 		Disabled:      ctx.GlobalString(GCModeFlag.Name) == "archive",
 		TrieNodeLimit: man.DefaultConfig.TrieCache,
 		TrieTimeLimit: man.DefaultConfig.TrieTimeout,
+		TriesInMemory: ctx.GlobalUint64(GCModeRecentFlag.Name),
 	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
 
-	chain, err = core.NewBlockChain(chainDb, cache, config, engine, vmcfg)
+	chain, err := core.NewBlockChain(chainDb, cache, config, engine, vmcfg)
 	if err != nil {
 		Fatalf("Can't create BlockChain: %v", err)
 	}
-	return chain, chainDb
+	return chain
 }
 
 // MakeConsolePreloads retrieves the absolute paths for the console JavaScript
@@ -15,6 +15,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/common/hexutil"
 	"github.com/MatrixAINetwork/go-matrix/common/math"
 	"github.com/MatrixAINetwork/go-matrix/core"
+	"github.com/MatrixAINetwork/go-matrix/core/matrixstate"
 	"github.com/MatrixAINetwork/go-matrix/core/state"
 	"github.com/MatrixAINetwork/go-matrix/core/types"
 	"github.com/MatrixAINetwork/go-matrix/core/vm"
@@ -22,6 +23,7 @@ import (
 	"github.com/MatrixAINetwork/go-matrix/crypto/sha3"
 	"github.com/MatrixAINetwork/go-matrix/mandb"
 	"github.com/MatrixAINetwork/go-matrix/params"
+	"github.com/MatrixAINetwork/go-matrix/params/manparams"
 	"github.com/MatrixAINetwork/go-matrix/rlp"
 )
 
@@ -107,13 +109,18 @@ func (t *StateTest) Subtests() []StateSubtest {
 	return sub
 }
 
+// PreState returns the accounts the test starts from.
+func (t *StateTest) PreState() core.GenesisAlloc {
+	return t.json.Pre
+}
+
 // Run executes a specific subtest.
 func (t *StateTest) Run(subtest StateSubtest, vmconfig vm.Config) (*state.StateDB, error) {
 	config, ok := Forks[subtest.Fork]
 	if !ok {
 		return nil, UnsupportedForkError{subtest.Fork}
 	}
-	block := t.genesis(config).ToBlock(nil)
+	header := t.header()
 	statedb := MakePreState(mandb.NewMemDatabase(), t.json.Pre)
 
 	post := t.json.Post[subtest.Fork][subtest.Index]
@@ -121,12 +128,12 @@ func (t *StateTest) Run(subtest StateSubtest, vmconfig vm.Config) (*state.StateD
 	if err != nil {
 		return nil, err
 	}
-	context := core.NewEVMContext(msg.From(), msg.GasPrice(), block.Header(), nil, &t.json.Env.Coinbase)
+	context := core.NewEVMContext(msg.From(), msg.GasPrice(), header, nil, &t.json.Env.Coinbase)
 	context.GetHash = vmTestBlockHash
 	evm := vm.NewEVM(context, statedb, config, vmconfig)
 
 	gaspool := new(core.GasPool)
-	gaspool.AddGas(block.GasLimit())
+	gaspool.AddGas(header.GasLimit)
 	snapshot := statedb.Snapshot()
 	if _, _, _, err := core.ApplyMessage(evm, msg, gaspool); err != nil {
 		statedb.RevertToSnapshot(snapshot)
@@ -134,7 +141,7 @@ func (t *StateTest) Run(subtest StateSubtest, vmconfig vm.Config) (*state.StateD
 	if logs := rlpHash(statedb.Logs()); logs != common.Hash(post.Logs) {
 		return statedb, fmt.Errorf("post state logs hash mismatch: got %x, want %x", logs, post.Logs)
 	}
-	root, _ := statedb.Commit(config.IsEIP158(block.Number()))
+	root, _ := statedb.Commit(config.IsEIP158(header.Number))
 	if root != common.Hash(post.Root) {
 		return statedb, fmt.Errorf("post state root mismatch: got %x, want %x", root, post.Root)
 	}
@@ -145,36 +152,39 @@ func (t *StateTest) gasLimit(subtest StateSubtest) uint64 {
 	return t.json.Tx.GasLimit[t.json.Post[subtest.Fork][subtest.Index].Indexes.Gas]
 }
 
+// MakePreState creates the state holding the given accounts, with their balance
+// on the main account. The matrix state carries the alpha version, so that the
+// MATRIX gas rules (e.g. the txpool gas price) apply to messages run on it.
 func MakePreState(db mandb.Database, accounts core.GenesisAlloc) *state.StateDB {
 	sdb := state.NewDatabase(db)
 	statedb, _ := state.New(common.Hash{}, sdb)
 	for addr, a := range accounts {
 		statedb.SetCode(addr, a.Code)
 		statedb.SetNonce(addr, a.Nonce)
-		statedb.SetBalance(addr, a.Balance)
+		statedb.SetBalance(common.MainAccount, addr, a.Balance)
 		for k, v := range a.Storage {
 			statedb.SetState(addr, k, v)
 		}
 	}
+	matrixstate.SetVersionInfo(statedb, manparams.VersionAlpha)
 	// Commit and re-open to start with a clean state.
 	root, _ := statedb.Commit(false)
 	statedb, _ = state.New(root, sdb)
 	return statedb
 }
 
-func (t *StateTest) genesis(config *params.ChainConfig) *core.Genesis {
-	return &core.Genesis{
-		Config:     config,
+// header returns the header of the block the test transaction is executed in.
+func (t *StateTest) header() *types.Header {
+	return &types.Header{
 		Coinbase:   t.json.Env.Coinbase,
 		Difficulty: t.json.Env.Difficulty,
 		GasLimit:   t.json.Env.GasLimit,
-		Number:     t.json.Env.Number,
-		Timestamp:  t.json.Env.Timestamp,
-		Alloc:      t.json.Pre,
+		Number:     new(big.Int).SetUint64(t.json.Env.Number),
+		Time:       new(big.Int).SetUint64(t.json.Env.Timestamp),
 	}
 }
 
-func (tx *stTransaction) toMessage(ps stPostState) (core.Message, error) {
+func (tx *stTransaction) toMessage(ps stPostState) (*types.Transaction, error) {
 	// Derive sender from private key if present.
 	var from common.Address
 	if len(tx.PrivateKey) > 0 {
@@ -187,10 +197,11 @@ func (tx *stTransaction) toMessage(ps stPostState) (core.Message, error) {
 	// Parse recipient if present.
 	var to *common.Address
 	if tx.To != "" {
-		to = new(common.Address)
-		if err := to.UnmarshalText([]byte(tx.To)); err != nil {
-			return nil, fmt.Errorf("invalid to address: %v", err)
+		if !common.IsHexAddress(tx.To) {
+			return nil, fmt.Errorf("invalid to address %q", tx.To)
 		}
+		to = new(common.Address)
+		*to = common.HexToAddress(tx.To)
 	}
 
 	// Get values specific to this post state.
@@ -220,7 +231,15 @@ func (tx *stTransaction) toMessage(ps stPostState) (core.Message, error) {
 		return nil, fmt.Errorf("invalid tx data %q", dataHex)
 	}
 
-	msg := types.NewMessage(from, to, tx.Nonce, value, gasLimit, tx.GasPrice, data, true)
+	// 以MAN币种的普通交易执行, 发送者由私钥推导; 状态中的nonce带有最高位标志
+	nonce := tx.Nonce | params.NonceAddOne
+	var msg *types.Transaction
+	if to == nil {
+		msg = types.NewContractCreation(nonce, value, gasLimit, tx.GasPrice, data, nil, nil, nil, 0, 0, "MAN", 0)
+	} else {
+		msg = types.NewTransaction(nonce, *to, value, gasLimit, tx.GasPrice, data, nil, nil, nil, 0, 0, "MAN", 0)
+	}
+	msg.SetFromLoad(from)
 	return msg, nil
 }
 